@@ -0,0 +1,5 @@
+// Package quotebotv1 は QuoteBot の gRPC API（quotebot.proto）から生成したコードです。
+// 他のサービスはこのパッケージのクライアントで、実行中のボットを操作できます
+package quotebotv1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative api/quotebot/v1/quotebot.proto
@@ -0,0 +1,968 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: api/quotebot/v1/quotebot.proto
+
+package quotebotv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Quote は名言です
+type Quote struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id は名言のIDです。省略した場合は本文と著者から作ったIDになります
+	Id            string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Text          string   `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Author        string   `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	Tags          []string `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	Weight        float64  `protobuf:"fixed64,5,opt,name=weight,proto3" json:"weight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Quote) Reset() {
+	*x = Quote{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quote) ProtoMessage() {}
+
+func (x *Quote) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quote.ProtoReflect.Descriptor instead.
+func (*Quote) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Quote) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Quote) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Quote) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Quote) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Quote) GetWeight() float64 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+// PostResult は1回の投稿の結果です
+type PostResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=at,proto3" json:"at,omitempty"`
+	RequestId     string                 `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Trigger       string                 `protobuf:"bytes,3,opt,name=trigger,proto3" json:"trigger,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	Uri           string                 `protobuf:"bytes,5,opt,name=uri,proto3" json:"uri,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	DryRun        bool                   `protobuf:"varint,7,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	Variant       string                 `protobuf:"bytes,8,opt,name=variant,proto3" json:"variant,omitempty"`
+	QuoteId       string                 `protobuf:"bytes,9,opt,name=quote_id,json=quoteId,proto3" json:"quote_id,omitempty"`
+	ApprovalId    string                 `protobuf:"bytes,10,opt,name=approval_id,json=approvalId,proto3" json:"approval_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostResult) Reset() {
+	*x = PostResult{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostResult) ProtoMessage() {}
+
+func (x *PostResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostResult.ProtoReflect.Descriptor instead.
+func (*PostResult) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PostResult) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *PostResult) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *PostResult) GetTrigger() string {
+	if x != nil {
+		return x.Trigger
+	}
+	return ""
+}
+
+func (x *PostResult) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *PostResult) GetUri() string {
+	if x != nil {
+		return x.Uri
+	}
+	return ""
+}
+
+func (x *PostResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *PostResult) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *PostResult) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *PostResult) GetQuoteId() string {
+	if x != nil {
+		return x.QuoteId
+	}
+	return ""
+}
+
+func (x *PostResult) GetApprovalId() string {
+	if x != nil {
+		return x.ApprovalId
+	}
+	return ""
+}
+
+type PostNowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostNowRequest) Reset() {
+	*x = PostNowRequest{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostNowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostNowRequest) ProtoMessage() {}
+
+func (x *PostNowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostNowRequest.ProtoReflect.Descriptor instead.
+func (*PostNowRequest) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{2}
+}
+
+type PostNowResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *PostResult            `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostNowResponse) Reset() {
+	*x = PostNowResponse{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostNowResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostNowResponse) ProtoMessage() {}
+
+func (x *PostNowResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostNowResponse.ProtoReflect.Descriptor instead.
+func (*PostNowResponse) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PostNowResponse) GetResult() *PostResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type AddQuoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quote         *Quote                 `protobuf:"bytes,1,opt,name=quote,proto3" json:"quote,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddQuoteRequest) Reset() {
+	*x = AddQuoteRequest{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddQuoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddQuoteRequest) ProtoMessage() {}
+
+func (x *AddQuoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddQuoteRequest.ProtoReflect.Descriptor instead.
+func (*AddQuoteRequest) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AddQuoteRequest) GetQuote() *Quote {
+	if x != nil {
+		return x.Quote
+	}
+	return nil
+}
+
+type AddQuoteResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// quote は追加した名言で、id を省略した場合は本文から作ったIDが入ります
+	Quote         *Quote `protobuf:"bytes,1,opt,name=quote,proto3" json:"quote,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddQuoteResponse) Reset() {
+	*x = AddQuoteResponse{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddQuoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddQuoteResponse) ProtoMessage() {}
+
+func (x *AddQuoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddQuoteResponse.ProtoReflect.Descriptor instead.
+func (*AddQuoteResponse) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AddQuoteResponse) GetQuote() *Quote {
+	if x != nil {
+		return x.Quote
+	}
+	return nil
+}
+
+type ListQuotesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// tag を指定すると、そのタグが付いた名言だけを返します
+	Tag           string `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListQuotesRequest) Reset() {
+	*x = ListQuotesRequest{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListQuotesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListQuotesRequest) ProtoMessage() {}
+
+func (x *ListQuotesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListQuotesRequest.ProtoReflect.Descriptor instead.
+func (*ListQuotesRequest) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListQuotesRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type ListQuotesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quotes        []*Quote               `protobuf:"bytes,1,rep,name=quotes,proto3" json:"quotes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListQuotesResponse) Reset() {
+	*x = ListQuotesResponse{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListQuotesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListQuotesResponse) ProtoMessage() {}
+
+func (x *ListQuotesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListQuotesResponse.ProtoReflect.Descriptor instead.
+func (*ListQuotesResponse) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListQuotesResponse) GetQuotes() []*Quote {
+	if x != nil {
+		return x.Quotes
+	}
+	return nil
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{8}
+}
+
+// ErrorEntry は直近のエラーです
+type ErrorEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=at,proto3" json:"at,omitempty"`
+	RequestId     string                 `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorEntry) Reset() {
+	*x = ErrorEntry{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorEntry) ProtoMessage() {}
+
+func (x *ErrorEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorEntry.ProtoReflect.Descriptor instead.
+func (*ErrorEntry) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ErrorEntry) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *ErrorEntry) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ErrorEntry) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetStatusResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Paused         bool                   `protobuf:"varint,1,opt,name=paused,proto3" json:"paused,omitempty"`
+	DryRun         bool                   `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	StartedAt      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	NextPostAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=next_post_at,json=nextPostAt,proto3" json:"next_post_at,omitempty"`
+	LastPost       *PostResult            `protobuf:"bytes,5,opt,name=last_post,json=lastPost,proto3" json:"last_post,omitempty"`
+	PoolSize       int32                  `protobuf:"varint,6,opt,name=pool_size,json=poolSize,proto3" json:"pool_size,omitempty"`
+	TokenExpiresAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=token_expires_at,json=tokenExpiresAt,proto3" json:"token_expires_at,omitempty"`
+	RecentErrors   []*ErrorEntry          `protobuf:"bytes,8,rep,name=recent_errors,json=recentErrors,proto3" json:"recent_errors,omitempty"`
+	// events は起動してからのイベントの数を種類ごとに数えたものです
+	Events        map[string]int64 `protobuf:"bytes,9,rep,name=events,proto3" json:"events,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetStatusResponse) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *GetStatusResponse) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *GetStatusResponse) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *GetStatusResponse) GetNextPostAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NextPostAt
+	}
+	return nil
+}
+
+func (x *GetStatusResponse) GetLastPost() *PostResult {
+	if x != nil {
+		return x.LastPost
+	}
+	return nil
+}
+
+func (x *GetStatusResponse) GetPoolSize() int32 {
+	if x != nil {
+		return x.PoolSize
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetTokenExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TokenExpiresAt
+	}
+	return nil
+}
+
+func (x *GetStatusResponse) GetRecentErrors() []*ErrorEntry {
+	if x != nil {
+		return x.RecentErrors
+	}
+	return nil
+}
+
+func (x *GetStatusResponse) GetEvents() map[string]int64 {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{11}
+}
+
+// Event はボットで起きたことです
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// type はイベントの種類です（quote_selected, post_succeeded, post_failed, post_held,
+	// post_dry_run, token_refreshed, token_refresh_failed）
+	Type string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	At   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=at,proto3" json:"at,omitempty"`
+	// post は投稿の結果のイベント（post_*）の結果です
+	Post          *PostResult `protobuf:"bytes,3,opt,name=post,proto3" json:"post,omitempty"`
+	RequestId     string      `protobuf:"bytes,4,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	QuoteId       string      `protobuf:"bytes,5,opt,name=quote_id,json=quoteId,proto3" json:"quote_id,omitempty"`
+	Error         string      `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_api_quotebot_v1_quotebot_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_api_quotebot_v1_quotebot_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *Event) GetPost() *PostResult {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+func (x *Event) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *Event) GetQuoteId() string {
+	if x != nil {
+		return x.QuoteId
+	}
+	return ""
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_api_quotebot_v1_quotebot_proto protoreflect.FileDescriptor
+
+const file_api_quotebot_v1_quotebot_proto_rawDesc = "" +
+	"\n" +
+	"\x1eapi/quotebot/v1/quotebot.proto\x12\vquotebot.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"o\n" +
+	"\x05Quote\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x16\n" +
+	"\x06author\x18\x03 \x01(\tR\x06author\x12\x12\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\x12\x16\n" +
+	"\x06weight\x18\x05 \x01(\x01R\x06weight\"\x9c\x02\n" +
+	"\n" +
+	"PostResult\x12*\n" +
+	"\x02at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12\x18\n" +
+	"\atrigger\x18\x03 \x01(\tR\atrigger\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12\x10\n" +
+	"\x03uri\x18\x05 \x01(\tR\x03uri\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x17\n" +
+	"\adry_run\x18\a \x01(\bR\x06dryRun\x12\x18\n" +
+	"\avariant\x18\b \x01(\tR\avariant\x12\x19\n" +
+	"\bquote_id\x18\t \x01(\tR\aquoteId\x12\x1f\n" +
+	"\vapproval_id\x18\n" +
+	" \x01(\tR\n" +
+	"approvalId\"\x10\n" +
+	"\x0ePostNowRequest\"B\n" +
+	"\x0fPostNowResponse\x12/\n" +
+	"\x06result\x18\x01 \x01(\v2\x17.quotebot.v1.PostResultR\x06result\";\n" +
+	"\x0fAddQuoteRequest\x12(\n" +
+	"\x05quote\x18\x01 \x01(\v2\x12.quotebot.v1.QuoteR\x05quote\"<\n" +
+	"\x10AddQuoteResponse\x12(\n" +
+	"\x05quote\x18\x01 \x01(\v2\x12.quotebot.v1.QuoteR\x05quote\"%\n" +
+	"\x11ListQuotesRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\"@\n" +
+	"\x12ListQuotesResponse\x12*\n" +
+	"\x06quotes\x18\x01 \x03(\v2\x12.quotebot.v1.QuoteR\x06quotes\"\x12\n" +
+	"\x10GetStatusRequest\"q\n" +
+	"\n" +
+	"ErrorEntry\x12*\n" +
+	"\x02at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"\x93\x04\n" +
+	"\x11GetStatusResponse\x12\x16\n" +
+	"\x06paused\x18\x01 \x01(\bR\x06paused\x12\x17\n" +
+	"\adry_run\x18\x02 \x01(\bR\x06dryRun\x129\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12<\n" +
+	"\fnext_post_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"nextPostAt\x124\n" +
+	"\tlast_post\x18\x05 \x01(\v2\x17.quotebot.v1.PostResultR\blastPost\x12\x1b\n" +
+	"\tpool_size\x18\x06 \x01(\x05R\bpoolSize\x12D\n" +
+	"\x10token_expires_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x0etokenExpiresAt\x12<\n" +
+	"\rrecent_errors\x18\b \x03(\v2\x17.quotebot.v1.ErrorEntryR\frecentErrors\x12B\n" +
+	"\x06events\x18\t \x03(\v2*.quotebot.v1.GetStatusResponse.EventsEntryR\x06events\x1a9\n" +
+	"\vEventsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x15\n" +
+	"\x13StreamEventsRequest\"\xc4\x01\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12*\n" +
+	"\x02at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\x12+\n" +
+	"\x04post\x18\x03 \x01(\v2\x17.quotebot.v1.PostResultR\x04post\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x04 \x01(\tR\trequestId\x12\x19\n" +
+	"\bquote_id\x18\x05 \x01(\tR\aquoteId\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error2\x83\x03\n" +
+	"\x0fQuoteBotService\x12D\n" +
+	"\aPostNow\x12\x1b.quotebot.v1.PostNowRequest\x1a\x1c.quotebot.v1.PostNowResponse\x12G\n" +
+	"\bAddQuote\x12\x1c.quotebot.v1.AddQuoteRequest\x1a\x1d.quotebot.v1.AddQuoteResponse\x12M\n" +
+	"\n" +
+	"ListQuotes\x12\x1e.quotebot.v1.ListQuotesRequest\x1a\x1f.quotebot.v1.ListQuotesResponse\x12J\n" +
+	"\tGetStatus\x12\x1d.quotebot.v1.GetStatusRequest\x1a\x1e.quotebot.v1.GetStatusResponse\x12F\n" +
+	"\fStreamEvents\x12 .quotebot.v1.StreamEventsRequest\x1a\x12.quotebot.v1.Event0\x01B@Z>github.com/littleironwaltz/quotebot/api/quotebot/v1;quotebotv1b\x06proto3"
+
+var (
+	file_api_quotebot_v1_quotebot_proto_rawDescOnce sync.Once
+	file_api_quotebot_v1_quotebot_proto_rawDescData []byte
+)
+
+func file_api_quotebot_v1_quotebot_proto_rawDescGZIP() []byte {
+	file_api_quotebot_v1_quotebot_proto_rawDescOnce.Do(func() {
+		file_api_quotebot_v1_quotebot_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_quotebot_v1_quotebot_proto_rawDesc), len(file_api_quotebot_v1_quotebot_proto_rawDesc)))
+	})
+	return file_api_quotebot_v1_quotebot_proto_rawDescData
+}
+
+var file_api_quotebot_v1_quotebot_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_api_quotebot_v1_quotebot_proto_goTypes = []any{
+	(*Quote)(nil),                 // 0: quotebot.v1.Quote
+	(*PostResult)(nil),            // 1: quotebot.v1.PostResult
+	(*PostNowRequest)(nil),        // 2: quotebot.v1.PostNowRequest
+	(*PostNowResponse)(nil),       // 3: quotebot.v1.PostNowResponse
+	(*AddQuoteRequest)(nil),       // 4: quotebot.v1.AddQuoteRequest
+	(*AddQuoteResponse)(nil),      // 5: quotebot.v1.AddQuoteResponse
+	(*ListQuotesRequest)(nil),     // 6: quotebot.v1.ListQuotesRequest
+	(*ListQuotesResponse)(nil),    // 7: quotebot.v1.ListQuotesResponse
+	(*GetStatusRequest)(nil),      // 8: quotebot.v1.GetStatusRequest
+	(*ErrorEntry)(nil),            // 9: quotebot.v1.ErrorEntry
+	(*GetStatusResponse)(nil),     // 10: quotebot.v1.GetStatusResponse
+	(*StreamEventsRequest)(nil),   // 11: quotebot.v1.StreamEventsRequest
+	(*Event)(nil),                 // 12: quotebot.v1.Event
+	nil,                           // 13: quotebot.v1.GetStatusResponse.EventsEntry
+	(*timestamppb.Timestamp)(nil), // 14: google.protobuf.Timestamp
+}
+var file_api_quotebot_v1_quotebot_proto_depIdxs = []int32{
+	14, // 0: quotebot.v1.PostResult.at:type_name -> google.protobuf.Timestamp
+	1,  // 1: quotebot.v1.PostNowResponse.result:type_name -> quotebot.v1.PostResult
+	0,  // 2: quotebot.v1.AddQuoteRequest.quote:type_name -> quotebot.v1.Quote
+	0,  // 3: quotebot.v1.AddQuoteResponse.quote:type_name -> quotebot.v1.Quote
+	0,  // 4: quotebot.v1.ListQuotesResponse.quotes:type_name -> quotebot.v1.Quote
+	14, // 5: quotebot.v1.ErrorEntry.at:type_name -> google.protobuf.Timestamp
+	14, // 6: quotebot.v1.GetStatusResponse.started_at:type_name -> google.protobuf.Timestamp
+	14, // 7: quotebot.v1.GetStatusResponse.next_post_at:type_name -> google.protobuf.Timestamp
+	1,  // 8: quotebot.v1.GetStatusResponse.last_post:type_name -> quotebot.v1.PostResult
+	14, // 9: quotebot.v1.GetStatusResponse.token_expires_at:type_name -> google.protobuf.Timestamp
+	9,  // 10: quotebot.v1.GetStatusResponse.recent_errors:type_name -> quotebot.v1.ErrorEntry
+	13, // 11: quotebot.v1.GetStatusResponse.events:type_name -> quotebot.v1.GetStatusResponse.EventsEntry
+	14, // 12: quotebot.v1.Event.at:type_name -> google.protobuf.Timestamp
+	1,  // 13: quotebot.v1.Event.post:type_name -> quotebot.v1.PostResult
+	2,  // 14: quotebot.v1.QuoteBotService.PostNow:input_type -> quotebot.v1.PostNowRequest
+	4,  // 15: quotebot.v1.QuoteBotService.AddQuote:input_type -> quotebot.v1.AddQuoteRequest
+	6,  // 16: quotebot.v1.QuoteBotService.ListQuotes:input_type -> quotebot.v1.ListQuotesRequest
+	8,  // 17: quotebot.v1.QuoteBotService.GetStatus:input_type -> quotebot.v1.GetStatusRequest
+	11, // 18: quotebot.v1.QuoteBotService.StreamEvents:input_type -> quotebot.v1.StreamEventsRequest
+	3,  // 19: quotebot.v1.QuoteBotService.PostNow:output_type -> quotebot.v1.PostNowResponse
+	5,  // 20: quotebot.v1.QuoteBotService.AddQuote:output_type -> quotebot.v1.AddQuoteResponse
+	7,  // 21: quotebot.v1.QuoteBotService.ListQuotes:output_type -> quotebot.v1.ListQuotesResponse
+	10, // 22: quotebot.v1.QuoteBotService.GetStatus:output_type -> quotebot.v1.GetStatusResponse
+	12, // 23: quotebot.v1.QuoteBotService.StreamEvents:output_type -> quotebot.v1.Event
+	19, // [19:24] is the sub-list for method output_type
+	14, // [14:19] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_api_quotebot_v1_quotebot_proto_init() }
+func file_api_quotebot_v1_quotebot_proto_init() {
+	if File_api_quotebot_v1_quotebot_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_quotebot_v1_quotebot_proto_rawDesc), len(file_api_quotebot_v1_quotebot_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_quotebot_v1_quotebot_proto_goTypes,
+		DependencyIndexes: file_api_quotebot_v1_quotebot_proto_depIdxs,
+		MessageInfos:      file_api_quotebot_v1_quotebot_proto_msgTypes,
+	}.Build()
+	File_api_quotebot_v1_quotebot_proto = out.File
+	file_api_quotebot_v1_quotebot_proto_goTypes = nil
+	file_api_quotebot_v1_quotebot_proto_depIdxs = nil
+}
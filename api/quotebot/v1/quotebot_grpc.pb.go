@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/quotebot/v1/quotebot.proto
+
+package quotebotv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	QuoteBotService_PostNow_FullMethodName      = "/quotebot.v1.QuoteBotService/PostNow"
+	QuoteBotService_AddQuote_FullMethodName     = "/quotebot.v1.QuoteBotService/AddQuote"
+	QuoteBotService_ListQuotes_FullMethodName   = "/quotebot.v1.QuoteBotService/ListQuotes"
+	QuoteBotService_GetStatus_FullMethodName    = "/quotebot.v1.QuoteBotService/GetStatus"
+	QuoteBotService_StreamEvents_FullMethodName = "/quotebot.v1.QuoteBotService/StreamEvents"
+)
+
+// QuoteBotServiceClient is the client API for QuoteBotService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// QuoteBotService は実行中のボットを操作します。管理APIと同じく ADMIN_TOKEN で認証します
+// （メタデータ authorization: Bearer <ADMIN_TOKEN>）
+type QuoteBotServiceClient interface {
+	// PostNow はすぐに1件投稿します（一時停止中でも投稿します）
+	PostNow(ctx context.Context, in *PostNowRequest, opts ...grpc.CallOption) (*PostNowResponse, error)
+	// AddQuote は名言ファイルに名言を追加し、読み込み直します
+	AddQuote(ctx context.Context, in *AddQuoteRequest, opts ...grpc.CallOption) (*AddQuoteResponse, error)
+	// ListQuotes は読み込んでいる名言を返します
+	ListQuotes(ctx context.Context, in *ListQuotesRequest, opts ...grpc.CallOption) (*ListQuotesResponse, error)
+	// GetStatus はボットの状態を返します
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	// StreamEvents は接続している間、ボットのイベントを送り続けます。受け取りが遅れたイベントは捨てます
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type quoteBotServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQuoteBotServiceClient(cc grpc.ClientConnInterface) QuoteBotServiceClient {
+	return &quoteBotServiceClient{cc}
+}
+
+func (c *quoteBotServiceClient) PostNow(ctx context.Context, in *PostNowRequest, opts ...grpc.CallOption) (*PostNowResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PostNowResponse)
+	err := c.cc.Invoke(ctx, QuoteBotService_PostNow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quoteBotServiceClient) AddQuote(ctx context.Context, in *AddQuoteRequest, opts ...grpc.CallOption) (*AddQuoteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddQuoteResponse)
+	err := c.cc.Invoke(ctx, QuoteBotService_AddQuote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quoteBotServiceClient) ListQuotes(ctx context.Context, in *ListQuotesRequest, opts ...grpc.CallOption) (*ListQuotesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListQuotesResponse)
+	err := c.cc.Invoke(ctx, QuoteBotService_ListQuotes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quoteBotServiceClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, QuoteBotService_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quoteBotServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &QuoteBotService_ServiceDesc.Streams[0], QuoteBotService_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type QuoteBotService_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+// QuoteBotServiceServer is the server API for QuoteBotService service.
+// All implementations must embed UnimplementedQuoteBotServiceServer
+// for forward compatibility.
+//
+// QuoteBotService は実行中のボットを操作します。管理APIと同じく ADMIN_TOKEN で認証します
+// （メタデータ authorization: Bearer <ADMIN_TOKEN>）
+type QuoteBotServiceServer interface {
+	// PostNow はすぐに1件投稿します（一時停止中でも投稿します）
+	PostNow(context.Context, *PostNowRequest) (*PostNowResponse, error)
+	// AddQuote は名言ファイルに名言を追加し、読み込み直します
+	AddQuote(context.Context, *AddQuoteRequest) (*AddQuoteResponse, error)
+	// ListQuotes は読み込んでいる名言を返します
+	ListQuotes(context.Context, *ListQuotesRequest) (*ListQuotesResponse, error)
+	// GetStatus はボットの状態を返します
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	// StreamEvents は接続している間、ボットのイベントを送り続けます。受け取りが遅れたイベントは捨てます
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedQuoteBotServiceServer()
+}
+
+// UnimplementedQuoteBotServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedQuoteBotServiceServer struct{}
+
+func (UnimplementedQuoteBotServiceServer) PostNow(context.Context, *PostNowRequest) (*PostNowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostNow not implemented")
+}
+func (UnimplementedQuoteBotServiceServer) AddQuote(context.Context, *AddQuoteRequest) (*AddQuoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddQuote not implemented")
+}
+func (UnimplementedQuoteBotServiceServer) ListQuotes(context.Context, *ListQuotesRequest) (*ListQuotesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListQuotes not implemented")
+}
+func (UnimplementedQuoteBotServiceServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedQuoteBotServiceServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedQuoteBotServiceServer) mustEmbedUnimplementedQuoteBotServiceServer() {}
+func (UnimplementedQuoteBotServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeQuoteBotServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QuoteBotServiceServer will
+// result in compilation errors.
+type UnsafeQuoteBotServiceServer interface {
+	mustEmbedUnimplementedQuoteBotServiceServer()
+}
+
+func RegisterQuoteBotServiceServer(s grpc.ServiceRegistrar, srv QuoteBotServiceServer) {
+	// If the following call pancis, it indicates UnimplementedQuoteBotServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&QuoteBotService_ServiceDesc, srv)
+}
+
+func _QuoteBotService_PostNow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostNowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuoteBotServiceServer).PostNow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuoteBotService_PostNow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuoteBotServiceServer).PostNow(ctx, req.(*PostNowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuoteBotService_AddQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddQuoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuoteBotServiceServer).AddQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuoteBotService_AddQuote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuoteBotServiceServer).AddQuote(ctx, req.(*AddQuoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuoteBotService_ListQuotes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQuotesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuoteBotServiceServer).ListQuotes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuoteBotService_ListQuotes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuoteBotServiceServer).ListQuotes(ctx, req.(*ListQuotesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuoteBotService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuoteBotServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuoteBotService_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuoteBotServiceServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuoteBotService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QuoteBotServiceServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type QuoteBotService_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+// QuoteBotService_ServiceDesc is the grpc.ServiceDesc for QuoteBotService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var QuoteBotService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "quotebot.v1.QuoteBotService",
+	HandlerType: (*QuoteBotServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PostNow",
+			Handler:    _QuoteBotService_PostNow_Handler,
+		},
+		{
+			MethodName: "AddQuote",
+			Handler:    _QuoteBotService_AddQuote_Handler,
+		},
+		{
+			MethodName: "ListQuotes",
+			Handler:    _QuoteBotService_ListQuotes_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _QuoteBotService_GetStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _QuoteBotService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/quotebot/v1/quotebot.proto",
+}
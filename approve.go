@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/interface/admin"
+)
+
+// runApprove は実行中のボットの管理APIで、承認待ちの投稿を表示・承認・却下します
+func runApprove(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("approve", flag.ContinueOnError)
+	flags.SetOutput(out)
+	reject := flags.Bool("reject", false, "投稿せずに破棄する")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if cfg.AdminToken == "" {
+		return fmt.Errorf("承認には ADMIN_TOKEN が必要です")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.PostTimeout+cfg.HTTPTimeout)
+	defer cancel()
+	client := admin.NewClient(cfg)
+
+	switch {
+	case flags.NArg() == 0 && !*reject:
+		items, err := client.Approvals(ctx)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Fprintln(out, "承認待ちの投稿はありません")
+			return nil
+		}
+		now := time.Now().In(cfg.DisplayLocation())
+		for _, item := range items {
+			fmt.Fprintf(out, "%s 期限: %s 名言: %s\n", item.ID, formatTime(item.ExpiresAt, now), item.Quote.StableID())
+			for _, line := range strings.Split(item.Text, "\n") {
+				fmt.Fprintf(out, "  %s\n", line)
+			}
+		}
+		return nil
+	case flags.NArg() != 1:
+		return fmt.Errorf("使い方: quotebot approve [--reject] [ID]")
+	case *reject:
+		if err := client.Reject(ctx, flags.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "却下しました: %s\n", flags.Arg(0))
+		return nil
+	default:
+		result, err := client.Approve(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "投稿しました: %s\n", result.URI)
+		return nil
+	}
+}
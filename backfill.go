@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/metrics"
+	"github.com/littleironwaltz/quotebot/internal/outbox"
+	"github.com/littleironwaltz/quotebot/internal/state"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// minBackfillGap は backfill の投稿間隔の下限です。新しいアカウントから短い間隔で続けて投稿すると、
+// PDSのレート制限やスパムの判定にかかりやすくなります
+const minBackfillGap = time.Minute
+
+// progressRecorder は backfill の投稿の結果を表示し、数えます。投稿履歴にも記録します
+type progressRecorder struct {
+	next  history.Recorder // 任意
+	out   io.Writer
+	total int
+	since time.Time // これより前の投稿（前回の実行で送信中だった投稿）は数えない
+
+	mu       sync.Mutex
+	posted   int
+	failures int
+}
+
+func (r *progressRecorder) Record(entry history.Entry) error {
+	r.mu.Lock()
+	switch {
+	case entry.Timestamp.Before(r.since):
+	case entry.Result == history.ResultSuccess:
+		r.posted++
+		fmt.Fprintf(r.out, "[%d/%d] 投稿しました: %s\n", r.posted+r.failures, r.total, entry.URI)
+	case entry.Result == history.ResultFailure:
+		r.failures++
+		fmt.Fprintf(r.out, "[%d/%d] 投稿に失敗しました: %s\n", r.posted+r.failures, r.total, entry.Error)
+	}
+	r.mu.Unlock()
+
+	if r.next == nil {
+		return nil
+	}
+	return r.next.Record(entry)
+}
+
+// runBackfill は新しいアカウントに名言を --count 件、--gap の間隔で投稿します。
+// 定期投稿と同じ Bot を投稿間隔だけ変えて動かすため、整形、投稿履歴、再試行とレート制限の扱いは定期投稿と同じです
+func runBackfill(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	flags.SetOutput(out)
+	count := flags.Int("count", 0, "投稿する件数")
+	gap := flags.Duration("gap", 10*time.Minute, "投稿の間隔")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return fmt.Errorf("使い方: quotebot backfill --count N [--gap 10m]")
+	}
+	if *gap < minBackfillGap {
+		return fmt.Errorf("--gap は%v以上で指定してください: %v", minBackfillGap, *gap)
+	}
+	if cfg.ApprovalRequired {
+		return fmt.Errorf("APPROVAL_REQUIRED では承認を経ずに投稿する backfill は使えません")
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	// 投稿間隔だけを変えた設定で、定期投稿と同じように Bot を動かす
+	backfillCfg := *cfg
+	backfillCfg.PostInterval = *gap
+
+	selector, err := usecase.NewSelector(cfg.QuoteSelector)
+	if err != nil {
+		return err
+	}
+	quotes := usecase.NewQuoteUseCase(repository.NewQuoteRepository(cfg), usecase.WithRand(usecase.NewRand(cfg.RandomSeed)), usecase.WithSelector(selector))
+	if err := quotes.Initialize(); err != nil {
+		return err
+	}
+	poster, err := newPoster(cfg, metrics.Discard)
+	if err != nil {
+		return err
+	}
+	defer poster.Shutdown()
+
+	opts, err := app.ContentOptions(cfg)
+	if err != nil {
+		return err
+	}
+	progress := &progressRecorder{out: out, total: *count, since: time.Now()}
+	stateStore, err := state.Open(cfg)
+	if err != nil {
+		return err
+	}
+	if closer, ok := stateStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+	historyRecorder, err := history.NewRecorder(cfg, stateStore)
+	if err != nil {
+		return err
+	}
+	if historyRecorder != nil {
+		if closer, ok := historyRecorder.(io.Closer); ok {
+			defer closer.Close()
+		}
+		progress.next = historyRecorder
+	}
+	opts = append(opts, app.WithHistory(progress), app.WithMaxPosts(*count))
+	if stateStore != nil {
+		opts = append(opts, app.WithOutbox(outbox.New(stateStore)))
+	}
+
+	// シグナルを受信したら、実行中の投稿を終えてから止める
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(out, "%d件を%v間隔で投稿します（終了予定: %s）\n",
+		*count, *gap, time.Now().In(cfg.DisplayLocation()).Add(time.Duration(*count-1)**gap).Format(time.RFC3339))
+	bot := app.NewBot(&backfillCfg, quotes, poster, opts...)
+	bot.Run(ctx)
+	if err := bot.Shutdown(context.Background()); err != nil {
+		return err
+	}
+
+	if cfg.DryRun {
+		fmt.Fprintln(out, "DRY_RUN のため投稿しませんでした（本文はログに出力されています）")
+		return nil
+	}
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+	fmt.Fprintf(out, "%d件を投稿しました（失敗: %d件）\n", progress.posted, progress.failures)
+	if progress.failures > 0 {
+		return fmt.Errorf("%d件の投稿に失敗しました", progress.failures)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"sync"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+)
+
+// botInstance は bots の1つのボットです
+type botInstance struct {
+	name string
+	cfg  *config.Config
+	app  *app.App
+}
+
+// runBots は設定ファイルの bots のボットを1つのプロセスで動かし、シグナルを受信するまで待ちます。
+// ボットごとにアカウント・名言・投稿の予定・投稿先を設定でき、互いに独立して動きます。
+// 設定や初期化に失敗したボット、実行中に停止したボットがあっても、ほかのボットは動き続けます
+func runBots(names []string, opts []config.Option) {
+	// 設定はボットごとに読み込む。ログの出力先はプロセスで1つなので、最初のボットの設定を使う
+	var bots []*botInstance
+	failed := map[string]error{}
+	for _, name := range names {
+		cfg, err := config.New(botOptions(opts, name)...)
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+		bots = append(bots, &botInstance{name: name, cfg: cfg})
+	}
+	if len(bots) == 0 {
+		log.Fatalf("起動できるボットがありません: %v", redact.Error(failed[names[0]]))
+	}
+	if err := logging.Setup(bots[0].cfg); err != nil {
+		log.Fatalf("ログ設定の読み込みに失敗しました: %v", err)
+	}
+	logger := logging.Module("main")
+	for _, name := range names {
+		if err, ok := failed[name]; ok {
+			logger.Error("ボットの設定に問題があるため起動しません", "bot", name, "error", redact.Error(err))
+		}
+	}
+
+	cfgs := make(map[string]*config.Config, len(bots))
+	for _, b := range bots {
+		cfgs[b.name] = b.cfg
+	}
+	if err := config.CheckBots(cfgs); err != nil {
+		fatal(logger, "設定に問題があります", err)
+	}
+	if bots[0].cfg.UpdateCheck {
+		go checkForUpdate(logger, bots[0].cfg)
+	}
+
+	var running []*botInstance
+	for _, b := range bots {
+		application, err := newApplication(b.cfg, botOptions(opts, b.name))
+		if err != nil {
+			logger.Error("ボットの初期化に失敗したため起動しません", "bot", b.name, "error", redact.Error(err))
+			continue
+		}
+		b.app = application
+		running = append(running, b)
+	}
+	if len(running) == 0 {
+		fatal(logger, "アプリケーションの初期化に失敗しました", errors.New("起動できるボットがありません"))
+	}
+	logger.Info("ボットを起動します", "bots", len(running), "skipped", len(names)-len(running))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnSignal(logger, bots[0].cfg.ShutdownTimeout, cancel)
+	stopReload := reloadOnHangup(ctx, func() {
+		for _, b := range running {
+			if _, err := b.app.ReloadConfig(); err != nil {
+				logger.Error("設定の再読み込みに失敗しました", "bot", b.name, "error", redact.Error(err))
+			}
+		}
+	})
+	defer stopReload()
+	defer reopenLogOnSignal(ctx, logger)()
+
+	var wg sync.WaitGroup
+	for _, b := range running {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.run(ctx, logger)
+		}()
+	}
+	wg.Wait()
+	logger.Info("シャットダウンが完了しました")
+}
+
+// run はボットを ctx がキャンセルされるまで動かし、実行中の投稿の完了を SHUTDOWN_TIMEOUT まで待ちます。
+// ボットが停止したりパニックを起こしたりしても、ほかのボットには影響しません。
+// ボットがバックグラウンドで起動するゴルーチン（スケジューラ、トークンの更新、メンションの確認など）は
+// safego で起動しているため、そこでのパニックはログに出力してその処理だけを再開します
+func (b *botInstance) run(ctx context.Context, logger *slog.Logger) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("ボットが異常終了しました", "bot", b.name, "panic", fmt.Sprint(r))
+		}
+	}()
+
+	if err := b.app.Run(ctx); err != nil {
+		logger.Error("ボットが停止しました", "bot", b.name, "error", redact.Error(err))
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), b.cfg.ShutdownTimeout)
+	defer shutdownCancel()
+	if err := b.app.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("シャットダウン中にエラーが発生しました", "bot", b.name, "error", redact.Error(err))
+	}
+}
+
+// botOptions は opts に name のボットを選ぶオプションを加えたものを返します
+func botOptions(opts []config.Option, name string) []config.Option {
+	return append(append([]config.Option{}, opts...), config.WithBot(name))
+}
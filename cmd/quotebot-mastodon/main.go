@@ -0,0 +1,111 @@
+// quotebot-mastodon は Mastodon に投稿する投稿プラグインです。
+// PUBLISHER_PLUGIN にこのコマンドのパスを設定すると、quotebot は Bluesky の代わりに Mastodon に投稿します。
+// 投稿プラグインを作るときの参考実装でもあります（プロトコルは pkg/publisher を参照）
+//
+// 設定は環境変数で指定します:
+//
+//	MASTODON_SERVER        投稿するサーバーのURL（例: https://mastodon.social）
+//	MASTODON_ACCESS_TOKEN  write:statuses のスコープを持つアクセストークン
+//	MASTODON_VISIBILITY    投稿の公開範囲（public, unlisted, private。デフォルトは public）
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/pkg/publisher"
+)
+
+// mastodon は Mastodon の API で投稿します
+type mastodon struct {
+	server     string
+	token      string
+	visibility string
+	client     *http.Client
+}
+
+func (m *mastodon) Info() publisher.Info {
+	return publisher.Info{
+		Platform:  domain.MastodonCapabilities.Platform,
+		MaxLength: domain.MastodonCapabilities.MaxLength,
+		Unit:      string(domain.MastodonCapabilities.Unit),
+	}
+}
+
+// Publish は本文を投稿します。quotebot のリクエストIDを Idempotency-Key に使うため、
+// 同じ投稿を再試行してもサーバーで重複しません
+func (m *mastodon) Publish(ctx context.Context, text, requestID string) (*publisher.Post, error) {
+	form := url.Values{"status": {text}, "visibility": {m.visibility}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.server+"/api/v1/statuses", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	if requestID != "" {
+		req.Header.Set("Idempotency-Key", requestID)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(body, &apiErr)
+		return nil, fmt.Errorf("Mastodonへの投稿に失敗しました（HTTP %d）: %s", resp.StatusCode, apiErr.Error)
+	}
+
+	var status struct {
+		ID  string `json:"id"`
+		URI string `json:"uri"`
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("Mastodonの応答を解析できませんでした: %w", err)
+	}
+	// 投稿履歴やエンゲージメントの表示ではブラウザで開けるURLを使う
+	uri := status.URL
+	if uri == "" {
+		uri = status.URI
+	}
+	return &publisher.Post{URI: uri, ID: status.ID, URL: status.URL}, nil
+}
+
+func main() {
+	// 標準出力はプロトコルに使うため、ログは標準エラー出力に書く（quotebot のログに転送されます）
+	log.SetOutput(os.Stderr)
+	log.SetFlags(0)
+
+	m := &mastodon{
+		server:     strings.TrimRight(os.Getenv("MASTODON_SERVER"), "/"),
+		token:      os.Getenv("MASTODON_ACCESS_TOKEN"),
+		visibility: os.Getenv("MASTODON_VISIBILITY"),
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+	if m.server == "" || m.token == "" {
+		log.Fatal("MASTODON_SERVER と MASTODON_ACCESS_TOKEN を設定してください")
+	}
+	if m.visibility == "" {
+		m.visibility = "public"
+	}
+	if err := publisher.Serve(m); err != nil {
+		log.Fatalf("プロトコルのエラーです: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// botsKey は設定ファイルで1つのプロセスで動かすボットをまとめるキーです
+const botsKey = "BOTS"
+
+// WithBot は設定ファイルの bots から使用するボットを選びます（--bot フラグ用、複数のボットを動かす場合）。
+// ボットの値は設定ファイルの共通の値とプロファイルの値より優先されます
+func WithBot(name string) Option {
+	return func(o *loadOptions) {
+		o.bot = name
+	}
+}
+
+// BotNames は設定ファイルの bots に定義されているボットの名前を、名前順に返します。
+// 設定ファイルを使っていない場合や bots がない場合は空です
+func BotNames(opts ...Option) ([]string, error) {
+	o := newLoadOptions(opts)
+	if o.file == "" {
+		return nil, nil
+	}
+	fc, err := readConfigFile(o.file, o.profile, "")
+	if err != nil {
+		return nil, err
+	}
+	return fc.bots, nil
+}
+
+// Bot は使用しているボットの名前を返します。bots のボットを選んでいない場合は空です
+func (c *Config) Bot() string {
+	if c == nil || c.sources == nil {
+		return ""
+	}
+	return c.sources.bot
+}
+
+// splitBots は設定ファイルの内容から bots を取り除き、定義されているボットの名前と name のボットの設定を返します。
+// name が空の場合、ボットの設定は nil です
+func splitBots(raw map[string]interface{}, name string) ([]string, map[string]interface{}, error) {
+	var bots map[string]interface{}
+	for k, v := range raw {
+		if strings.ToUpper(k) != botsKey {
+			continue
+		}
+		delete(raw, k)
+		if v == nil {
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("bots にはボットの名前ごとの設定を指定してください")
+		}
+		bots = m
+	}
+
+	names := make([]string, 0, len(bots))
+	for k := range bots {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	if name == "" {
+		return names, nil, nil
+	}
+
+	v, ok := bots[name]
+	if !ok {
+		if len(names) == 0 {
+			return nil, nil, fmt.Errorf("ボット %s が見つかりません（bots が定義されていません）", name)
+		}
+		return nil, nil, fmt.Errorf("ボット %s が見つかりません（定義されているボット: %s）", name, strings.Join(names, ", "))
+	}
+	if v == nil {
+		return names, map[string]interface{}{}, nil
+	}
+	bot, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("ボット %s の設定の形式が正しくありません", name)
+	}
+	return names, bot, nil
+}
+
+// botExclusiveKeys は複数のボットで同じ値を使えない設定です。
+// 同じファイルに書き込んだり同じポートで待ち受けたりすると、ボット同士が干渉します
+var botExclusiveKeys = []string{"TOKEN_FILE", "HISTORY_FILE", "STATE_FILE", "ADMIN_ADDR", "GRPC_ADDR"}
+
+// CheckBots は複数のボットの設定が互いに干渉しないかを確認します。
+// トークン・履歴・状態のファイルや管理API・gRPCのアドレスが重複している場合にエラーを返します
+func CheckBots(cfgs map[string]*Config) error {
+	names := make([]string, 0, len(cfgs))
+	for name := range cfgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []Problem
+	for _, key := range botExclusiveKeys {
+		owners := map[string]string{}
+		for _, name := range names {
+			value := cfgs[name].exclusiveValue(key)
+			if value == "" {
+				continue
+			}
+			if owner, ok := owners[value]; ok {
+				problems = append(problems, Problem{
+					Key:        key,
+					Message:    fmt.Sprintf("ボット %s と %s で同じ値（%s）が設定されています", owner, name, value),
+					Suggestion: "ボットごとに別の値を bots の中で指定してください",
+				})
+				continue
+			}
+			owners[value] = name
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// exclusiveValue は botExclusiveKeys の設定の値を返します。使っていない機能のアドレスは空です
+func (c *Config) exclusiveValue(key string) string {
+	switch key {
+	case "TOKEN_FILE":
+		return c.TokenFile
+	case "HISTORY_FILE":
+		return c.HistoryFile
+	case "STATE_FILE":
+		return c.StateFile
+	case "ADMIN_ADDR":
+		if c.AdminEnabled {
+			return c.AdminAddr
+		}
+	case "GRPC_ADDR":
+		if c.GRPCEnabled {
+			return c.GRPCAddr
+		}
+	}
+	return ""
+}
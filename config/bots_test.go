@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const botsTestConfig = `pds_url: https://bsky.social
+post_interval: 1h
+profiles:
+  dev:
+    pds_url: http://localhost:2583
+bots:
+  morning:
+    did: did:plc:morning
+    access_jwt: morning-access
+    refresh_jwt: morning-refresh
+    state_file: /var/lib/quotebot/morning.json
+  evening:
+    did: did:plc:evening
+    access_jwt: evening-access
+    refresh_jwt: evening-refresh
+    post_interval: 3h
+    pds_url: https://pds.example.com
+`
+
+func TestNew_Bot(t *testing.T) {
+	tests := []struct {
+		name    string
+		bot     string
+		profile string
+		envVars map[string]string
+		check   func(t *testing.T, cfg *Config)
+		wantErr bool
+	}{
+		{
+			name: "success case: bot settings override the base settings",
+			bot:  "evening",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DID != "did:plc:evening" || cfg.PostInterval.Hours() != 3 || cfg.Bot() != "evening" {
+					t.Errorf("DID = %v, PostInterval = %v, Bot() = %q", cfg.DID, cfg.PostInterval, cfg.Bot())
+				}
+				if got := settingSource(cfg, "DID"); got != SourceBot {
+					t.Errorf("DID source = %v, want %v", got, SourceBot)
+				}
+			},
+		},
+		{
+			name: "success case: settings missing in the bot come from the base settings",
+			bot:  "morning",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.PostInterval.Hours() != 1 || cfg.PDSURL != "https://bsky.social" {
+					t.Errorf("PostInterval = %v, PDSURL = %v", cfg.PostInterval, cfg.PDSURL)
+				}
+				if got := settingSource(cfg, "POST_INTERVAL"); got != SourceFile {
+					t.Errorf("POST_INTERVAL source = %v, want %v", got, SourceFile)
+				}
+			},
+		},
+		{
+			name:    "success case: bot settings take precedence over the profile",
+			bot:     "evening",
+			profile: ProfileDev,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.PDSURL != "https://pds.example.com" || !cfg.DryRun {
+					t.Errorf("PDSURL = %v, DryRun = %v", cfg.PDSURL, cfg.DryRun)
+				}
+			},
+		},
+		{
+			name:    "success case: env vars take precedence over the bot",
+			bot:     "evening",
+			envVars: map[string]string{"POST_INTERVAL": "2h"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.PostInterval.Hours() != 2 || settingSource(cfg, "POST_INTERVAL") != SourceEnv {
+					t.Errorf("PostInterval = %v, source = %v", cfg.PostInterval, settingSource(cfg, "POST_INTERVAL"))
+				}
+			},
+		},
+		{
+			name:    "error case: unknown bot",
+			bot:     "noon",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+			path := writeBotsTestConfig(t)
+			opts := []Option{WithFile(path), WithBot(tt.bot)}
+			if tt.profile != "" {
+				opts = append(opts, WithProfile(tt.profile))
+			}
+
+			got, err := New(opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			tt.check(t, got)
+		})
+	}
+}
+
+func TestBotNames(t *testing.T) {
+	os.Clearenv()
+	path := writeBotsTestConfig(t)
+
+	got, err := BotNames(WithFile(path))
+	if err != nil {
+		t.Fatalf("BotNames() error = %v", err)
+	}
+	if want := []string{"evening", "morning"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("BotNames() = %v, want %v", got, want)
+	}
+
+	if got, err := BotNames(); err != nil || len(got) != 0 {
+		t.Errorf("BotNames() without a config file = %v, %v, want none", got, err)
+	}
+}
+
+func TestCheckBots(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfgs     map[string]*Config
+		wantKeys []string
+	}{
+		{
+			name: "success case: separate files and addresses",
+			cfgs: map[string]*Config{
+				"a": {StateFile: "a.json", AdminEnabled: true, AdminAddr: ":8686"},
+				"b": {StateFile: "b.json", AdminEnabled: true, AdminAddr: ":8688"},
+			},
+		},
+		{
+			name: "success case: the same address is fine while the server is disabled",
+			cfgs: map[string]*Config{
+				"a": {GRPCAddr: "127.0.0.1:8687"},
+				"b": {GRPCAddr: "127.0.0.1:8687", GRPCEnabled: true},
+			},
+		},
+		{
+			name: "error case: shared state file and admin address",
+			cfgs: map[string]*Config{
+				"a": {StateFile: "state.json", AdminEnabled: true, AdminAddr: ":8686"},
+				"b": {StateFile: "state.json", AdminEnabled: true, AdminAddr: ":8686"},
+				"c": {StateFile: "c.json"},
+			},
+			wantKeys: []string{"STATE_FILE", "ADMIN_ADDR"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckBots(tt.cfgs)
+			var keys []string
+			if err != nil {
+				for _, p := range err.(*ValidationError).Problems {
+					keys = append(keys, p.Key)
+				}
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("CheckBots() problems = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+// writeBotsTestConfig は bots を定義した設定ファイルを作成します
+func writeBotsTestConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(botsTestConfig), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
@@ -2,32 +2,538 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
+// 認証方式
+const (
+	// AuthModeSession はアプリパスワードやJWTによる従来のセッション認証です
+	AuthModeSession = "session"
+	// AuthModeOAuth はDPoPでバインドされたトークンを使うatproto OAuthです
+	AuthModeOAuth = "oauth"
+)
+
+// TLSの暗号スイートの選択
+const (
+	// TLSCipherSuitesRestricted はECDHEとAEADに限定した暗号スイートのみを使用します
+	TLSCipherSuitesRestricted = "restricted"
+	// TLSCipherSuitesDefault はGoの標準の暗号スイートを使用します（社内PKIなど互換性が必要な場合）
+	TLSCipherSuitesDefault = "default"
+)
+
+// 状態と投稿履歴の保存先
+const (
+	// StateBackendFile は状態を STATE_FILE に、投稿履歴を HISTORY_FILE に保存します
+	StateBackendFile = "file"
+	// StateBackendSQLite は状態と投稿履歴を STATE_DSN の SQLite データベースに保存します
+	StateBackendSQLite = "sqlite"
+	// StateBackendPostgres は状態と投稿履歴を STATE_DSN の PostgreSQL データベースに保存します
+	StateBackendPostgres = "postgres"
+)
+
+// リーダー選出の方法
+const (
+	// LeaderElectionBackendKubernetes は Kubernetes の Lease を取り合います
+	LeaderElectionBackendKubernetes = "kubernetes"
+	// LeaderElectionBackendPostgres は STATE_DSN の PostgreSQL のアドバイザリロックを取り合います
+	LeaderElectionBackendPostgres = "postgres"
+)
+
+// メトリクスの送信先
+const (
+	// MetricsBackendNone はメトリクスを送りません
+	MetricsBackendNone = "none"
+	// MetricsBackendStatsd は statsd のエージェントに送ります
+	MetricsBackendStatsd = "statsd"
+	// MetricsBackendDogStatsD は Datadog の DogStatsD のエージェントにタグ付きで送ります
+	MetricsBackendDogStatsD = "dogstatsd"
+)
+
 // Config はアプリケーション全体の設定を保持します
 type Config struct {
 	PDSURL               string        `envconfig:"PDS_URL" default:"https://bsky.social"`
 	Collection           string        `envconfig:"COLLECTION" default:"app.bsky.feed.post"`
 	QuotesFile           string        `envconfig:"QUOTES_FILE" default:"quotes.json"`
-	AccessJWT            string        `envconfig:"ACCESS_JWT" required:"true"`
-	RefreshJWT           string        `envconfig:"REFRESH_JWT" required:"true"`
+	AccessJWT            string        `envconfig:"ACCESS_JWT"`
+	RefreshJWT           string        `envconfig:"REFRESH_JWT"`
 	DID                  string        `envconfig:"DID" required:"true"`
+	Handle               string        `envconfig:"HANDLE"`
+	AppPassword          string        `envconfig:"APP_PASSWORD"`
+	AuthMode             string        `envconfig:"AUTH_MODE" default:"session"`
+	OAuthClientID        string        `envconfig:"OAUTH_CLIENT_ID"`
+	OAuthRedirectURI     string        `envconfig:"OAUTH_REDIRECT_URI" default:"http://127.0.0.1:8085/callback"`
+	OAuthScope           string        `envconfig:"OAUTH_SCOPE" default:"atproto transition:generic"`
+	CredentialsBackend   string        `envconfig:"CREDENTIALS_BACKEND" default:"env"`
+	KeyringService       string        `envconfig:"KEYRING_SERVICE" default:"quotebot"`
+	VaultAddr            string        `envconfig:"VAULT_ADDR"`
+	VaultToken           string        `envconfig:"VAULT_TOKEN"`
+	VaultNamespace       string        `envconfig:"VAULT_NAMESPACE"`
+	VaultMount           string        `envconfig:"VAULT_KV_MOUNT" default:"secret"`
+	VaultSecretPath      string        `envconfig:"VAULT_SECRET_PATH" default:"quotebot"`
 	PostInterval         time.Duration `envconfig:"POST_INTERVAL" default:"1h"`
 	HTTPTimeout          time.Duration `envconfig:"HTTP_TIMEOUT" default:"10s"`
+	PostTimeout          time.Duration `envconfig:"POST_TIMEOUT" default:"2m"`
+	ShutdownTimeout      time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"30s"`
+	RefreshTimeout       time.Duration `envconfig:"HTTP_TIMEOUT_REFRESH_SESSION"`
+	CreateRecordTimeout  time.Duration `envconfig:"HTTP_TIMEOUT_CREATE_RECORD"`
+	UploadBlobTimeout    time.Duration `envconfig:"HTTP_TIMEOUT_UPLOAD_BLOB" default:"60s"`
+	HTTPMaxResponseBytes int64         `envconfig:"HTTP_MAX_RESPONSE_BYTES" default:"4194304"`
+	BlobMaxBytes         int64         `envconfig:"BLOB_MAX_BYTES" default:"1000000"`
 	TokenRefreshInterval time.Duration `envconfig:"TOKEN_REFRESH_INTERVAL" default:"45m"`
+	TokenRefreshMargin   time.Duration `envconfig:"TOKEN_REFRESH_MARGIN" default:"5m"`
 	MaxRetries           int           `envconfig:"MAX_RETRIES" default:"3"`
 	RetryBackoff         time.Duration `envconfig:"RETRY_BACKOFF" default:"5s"`
+	RetryJitter          string        `envconfig:"RETRY_JITTER" default:"full"`
+	RetryBudget          int           `envconfig:"RETRY_BUDGET" default:"20"`
+	RetryBudgetWindow    time.Duration `envconfig:"RETRY_BUDGET_WINDOW" default:"1h"`
+	UserAgent            string        `envconfig:"USER_AGENT"`
+	UpdateCheck          bool          `envconfig:"UPDATE_CHECK" default:"false"`
+	HTTPLogRequests      bool          `envconfig:"HTTP_LOG_REQUESTS" default:"false"`
+	HTTPLogSampleRate    float64       `envconfig:"HTTP_LOG_SAMPLE_RATE" default:"1"`
+	HTTPLogPerMinute     int           `envconfig:"HTTP_LOG_PER_MINUTE" default:"30"`
+	LogFormat            string        `envconfig:"LOG_FORMAT" default:"text"`
+	LogLevel             string        `envconfig:"LOG_LEVEL" default:"info"`
+	LogLang              string        `envconfig:"LOG_LANG" default:"ja"`
+	HTTPUnixSocket       string        `envconfig:"HTTP_UNIX_SOCKET"`
+	HTTPLocalAddr        string        `envconfig:"HTTP_LOCAL_ADDR"`
+	TLSCAFile            string        `envconfig:"TLS_CA_FILE"`
+	TLSClientCertFile    string        `envconfig:"TLS_CLIENT_CERT_FILE"`
+	TLSClientKeyFile     string        `envconfig:"TLS_CLIENT_KEY_FILE"`
+	TLSCipherSuites      string        `envconfig:"TLS_CIPHER_SUITES" default:"restricted"`
+	TLSMinVersion        string        `envconfig:"TLS_MIN_VERSION" default:"1.2"`
+	AdminEnabled         bool          `envconfig:"ADMIN_ENABLED" default:"false"`
+	AdminAddr            string        `envconfig:"ADMIN_ADDR" default:"127.0.0.1:8686"`
+	AdminToken           string        `envconfig:"ADMIN_TOKEN"`
+	HistoryFile          string        `envconfig:"HISTORY_FILE"`
+	HistoryMaxSizeMB     int           `envconfig:"HISTORY_MAX_SIZE_MB" default:"10"`
+	HistoryMaxBackups    int           `envconfig:"HISTORY_MAX_BACKUPS" default:"5"`
+	AlertThreshold       int           `envconfig:"ALERT_THRESHOLD" default:"3"`
+	AlertWebhookURL      string        `envconfig:"ALERT_WEBHOOK_URL"`
+	AlertDiscordURL      string        `envconfig:"ALERT_DISCORD_WEBHOOK_URL"`
+	AlertSMTPAddr        string        `envconfig:"ALERT_SMTP_ADDR"`
+	AlertSMTPUsername    string        `envconfig:"ALERT_SMTP_USERNAME"`
+	AlertSMTPPassword    string        `envconfig:"ALERT_SMTP_PASSWORD"`
+	AlertEmailFrom       string        `envconfig:"ALERT_EMAIL_FROM"`
+	AlertEmailTo         []string      `envconfig:"ALERT_EMAIL_TO"`
+	TokenFile            string        `envconfig:"TOKEN_FILE"`
+	TokenFilePassphrase  string        `envconfig:"TOKEN_FILE_PASSPHRASE"`
+
+	TokenEncryptionKey          string   `envconfig:"TOKEN_ENCRYPTION_KEY"`
+	TokenEncryptionPassphrase   string   `envconfig:"TOKEN_ENCRYPTION_PASSPHRASE"`
+	TokenEncryptionPreviousKeys []string `envconfig:"TOKEN_ENCRYPTION_PREVIOUS_KEYS"`
+
+	// PostTemplate は投稿本文のテンプレート（text/template）です。空の場合は「本文\n- 著者」です
+	PostTemplate string `envconfig:"POST_TEMPLATE"`
+	// PostTemplateBluesky はBlueskyへの投稿に使うテンプレートです。空の場合は PostTemplate を使います
+	PostTemplateBluesky string `envconfig:"POST_TEMPLATE_BLUESKY"`
+	// PostLang はアカウントの言語（BCP 47。例: en）です。名言にこの言語の翻訳があれば翻訳を投稿します
+	PostLang string `envconfig:"POST_LANG"`
+	// TranslationMode は翻訳の投稿の仕方です（replace は翻訳だけを投稿、thread は原文を投稿して翻訳を返信）
+	TranslationMode string `envconfig:"TRANSLATION_MODE" default:"replace"`
+
+	// AttributionSeparator は著者の前の区切りです（hyphen, em-dash, horizontal-bar, by または任意の文字）
+	AttributionSeparator string `envconfig:"ATTRIBUTION_SEPARATOR" default:"hyphen"`
+	// SmartQuotes は引用符の表記です（keep, curly, straight）
+	SmartQuotes string `envconfig:"SMART_QUOTES" default:"keep"`
+	// Ellipsis は三点リーダーの表記です（keep, unicode, ascii）
+	Ellipsis string `envconfig:"ELLIPSIS" default:"keep"`
+
+	// Decoration は投稿に付けるデフォルトの絵文字などの装飾です
+	Decoration string `envconfig:"DECORATION"`
+	// DecorationWeekdays は曜日ごとの装飾です（例: mon:☕,fri:🎉）
+	DecorationWeekdays map[string]string `envconfig:"DECORATION_WEEKDAYS"`
+	// DecorationDates は日付の範囲ごとの装飾です（例: 12-01/12-25:🎄,01-01/01-07:🎍）
+	DecorationDates map[string]string `envconfig:"DECORATION_DATES"`
+	// DecorationPosition は装飾の位置です（prefix, suffix, both, template）
+	DecorationPosition string `envconfig:"DECORATION_POSITION" default:"prefix"`
+
+	// Hashtags は投稿の末尾に付けるハッシュタグです（# は省略できます）
+	Hashtags []string `envconfig:"HASHTAGS"`
+	// HashtagMode は Hashtags の付け方です（fixed: すべて付ける、rotate: 投稿ごとに順番に付ける）
+	HashtagMode string `envconfig:"HASHTAG_MODE" default:"fixed"`
+	// HashtagsPerPost は rotate で1回の投稿に付けるハッシュタグの数です
+	HashtagsPerPost int `envconfig:"HASHTAGS_PER_POST" default:"1"`
+	// HashtagMap は名言のタグから付けるハッシュタグへの対応です（例: philosophy:哲学,life:人生）
+	HashtagMap map[string]string `envconfig:"HASHTAG_MAP"`
+
+	// FormatVariants はA/Bテストで比べる投稿形式です（設定ファイルの variants）
+	FormatVariants []FormatVariant `ignored:"true"`
+
+	// QuoteSelector は定期投稿で名言を選ぶ方法です（random, shuffle, weighted）
+	QuoteSelector string `envconfig:"QUOTE_SELECTOR" default:"random"`
+	// RandomSeed は名言を選ぶ乱数のシードです。0以外を指定すると選ばれる順番を再現できます（0は実行ごとに変わる）
+	RandomSeed int64 `envconfig:"RANDOM_SEED" default:"0"`
+
+	// DenyWords は投稿しない語句です（大文字と小文字を区別しない）。本文・著者・タグを確認します
+	DenyWords []string `envconfig:"DENY_WORDS"`
+	// DenyPatterns は投稿しない語句の正規表現です
+	DenyPatterns []string `envconfig:"DENY_PATTERNS"`
+	// DenyAction は禁止語を含む名言の扱いです（skip は選び直す、flag は投稿して履歴に印を付ける）
+	DenyAction string `envconfig:"DENY_ACTION" default:"skip"`
+
+	// StateFile は再起動しても残す状態（承認待ちの投稿など）を保存するファイルです
+	StateFile string `envconfig:"STATE_FILE"`
+	// ApprovalRequired は投稿を承認待ちに入れ、管理APIまたは quotebot approve で承認されてから投稿します
+	ApprovalRequired bool `envconfig:"APPROVAL_REQUIRED" default:"false"`
+	// ApprovalTTL は承認待ちの投稿を破棄するまでの時間です
+	ApprovalTTL time.Duration `envconfig:"APPROVAL_TTL" default:"24h"`
+
+	// GRPCEnabled は実行中のボットを操作する gRPC API（api/quotebot/v1）を起動します。認証には ADMIN_TOKEN を使います
+	GRPCEnabled bool `envconfig:"GRPC_ENABLED" default:"false"`
+	// GRPCAddr は gRPC API の待ち受けアドレスです
+	GRPCAddr string `envconfig:"GRPC_ADDR" default:"127.0.0.1:8687"`
+
+	// ProfileUpdateEnabled は投稿するアカウントのプロフィール（自己紹介と固定する投稿）を定期的に更新します
+	ProfileUpdateEnabled bool `envconfig:"PROFILE_UPDATE_ENABLED" default:"false"`
+	// ProfileDescription は自己紹介のテンプレートです（空の場合は次の投稿の時刻を知らせるデフォルト）
+	ProfileDescription string `envconfig:"PROFILE_DESCRIPTION"`
+	// ProfilePinCount はプロフィールに固定する投稿に並べる最近の名言の数です（0は固定する投稿を作らない）
+	ProfilePinCount int `envconfig:"PROFILE_PIN_COUNT" default:"5"`
+	// ProfilePinInterval は固定する投稿を作り直す間隔です
+	ProfilePinInterval time.Duration `envconfig:"PROFILE_PIN_INTERVAL" default:"24h"`
+	// ProfilePinTitle は固定する投稿の1行目です
+	ProfilePinTitle string `envconfig:"PROFILE_PIN_TITLE" default:"最近の名言"`
+	// QuoteRequestsEnabled は「#quote <トピック>」と書いてメンションした投稿に、トピックのタグが付いた名言で返信します
+	QuoteRequestsEnabled bool `envconfig:"QUOTE_REQUESTS_ENABLED" default:"false"`
+	// QuoteRequestHashtag は名言のリクエストに使うハッシュタグです（# は付けない）
+	QuoteRequestHashtag string `envconfig:"QUOTE_REQUEST_HASHTAG" default:"quote"`
+	// QuoteRequestCooldown は同じユーザーのリクエストに続けて返信しない時間です
+	QuoteRequestCooldown time.Duration `envconfig:"QUOTE_REQUEST_COOLDOWN" default:"1h"`
+	// QuoteRequestPollInterval は新しいメンションを確認する間隔です
+	QuoteRequestPollInterval time.Duration `envconfig:"QUOTE_REQUEST_POLL_INTERVAL" default:"1m"`
+	// QuoteRequestBatchSize は1回の確認で扱う最大のメンション数です。残りは次の確認で扱います
+	QuoteRequestBatchSize int `envconfig:"QUOTE_REQUEST_BATCH_SIZE" default:"20"`
+
+	// PublisherPlugin は Bluesky の代わりに投稿に使う投稿プラグインの実行ファイルです（pkg/publisher のプロトコル）
+	PublisherPlugin string `envconfig:"PUBLISHER_PLUGIN"`
+	// PublisherPluginArgs は投稿プラグインに渡す引数です
+	PublisherPluginArgs []string `envconfig:"PUBLISHER_PLUGIN_ARGS"`
+	// CrossPostPlugins は投稿先に投稿できた後に、同じ本文を投稿するほかの投稿先の投稿プラグインです。
+	// カンマ区切りで、それぞれ実行ファイルと空白区切りの引数を指定します（例: ./quotebot-mastodon,/usr/local/bin/my-plugin arg1 arg2）
+	CrossPostPlugins []string `envconfig:"CROSS_POST_PLUGINS"`
+	// CrossPostConcurrency はほかの投稿先に同時に投稿する数です。0はすべての投稿先に同時に投稿します
+	CrossPostConcurrency int `envconfig:"CROSS_POST_CONCURRENCY" default:"4"`
+	// CrossPostTimeout はほかの投稿先ごとの投稿の期限です。遅い投稿先があってもほかの投稿先を待たせません。0は POST_TIMEOUT だけを使います
+	CrossPostTimeout time.Duration `envconfig:"CROSS_POST_TIMEOUT" default:"30s"`
+
+	// DryRun は投稿せずに本文をログに出力します（QUOTEBOT_PROFILE=dev のデフォルト）
+	DryRun bool `envconfig:"DRY_RUN" default:"false"`
+
+	// RequireSecretFiles は秘密情報を _FILE 経由でのみ受け付けます（平文の環境変数を禁止する運用向け）
+	RequireSecretFiles bool `envconfig:"REQUIRE_SECRET_FILES" default:"false"`
+
+	// LogModuleLevels はモジュールごとのログレベルです（例: http:debug,token:warn）
+	LogModuleLevels map[string]string `envconfig:"LOG_MODULE_LEVELS"`
+
+	// DisplayTimezone は status などで人に見せる時刻のタイムゾーンです（例: Asia/Tokyo）。空の場合はマシンのタイムゾーンを使います
+	DisplayTimezone string `envconfig:"DISPLAY_TIMEZONE"`
+
+	// LeaderElection は複数のレプリカのうち、リーダーに選ばれたものだけが投稿するようにします（LEADER_ELECTION_BACKEND）
+	LeaderElection bool `envconfig:"LEADER_ELECTION" default:"false"`
+	// LeaderElectionLease はリーダー選出に使う Lease の名前です（bots のボットでは -<ボットの名前> が付きます）
+	LeaderElectionLease string `envconfig:"LEADER_ELECTION_LEASE" default:"quotebot"`
+	// LeaderElectionNamespace は Lease の名前空間です。空の場合は Pod の名前空間を使います
+	LeaderElectionNamespace string `envconfig:"LEADER_ELECTION_NAMESPACE"`
+	// LeaderElectionIdentity はこのレプリカの名前です。空の場合はホスト名（Pod の名前）を使います
+	LeaderElectionIdentity string `envconfig:"LEADER_ELECTION_IDENTITY"`
+	// LeaderElectionLeaseDuration はリーダーがリースを更新しなくなってから、ほかのレプリカが引き継ぐまでの時間です
+	LeaderElectionLeaseDuration time.Duration `envconfig:"LEADER_ELECTION_LEASE_DURATION" default:"15s"`
+	// LeaderElectionBackend はリーダー選出の方法です（kubernetes, postgres）。
+	// postgres は Kubernetes の外で、STATE_BACKEND=postgres のデータベースのアドバイザリロックでリーダーを選びます
+	LeaderElectionBackend string `envconfig:"LEADER_ELECTION_BACKEND" default:"kubernetes"`
+
+	// OccasionsFile は記念日などの特別な日に投稿する名言の予定（JSON）です
+	OccasionsFile string `envconfig:"OCCASIONS_FILE"`
+	// OccasionTime は特別な日の投稿を行う時刻（HH:MM、DISPLAY_TIMEZONE のタイムゾーン）です
+	OccasionTime string `envconfig:"OCCASION_TIME" default:"09:00"`
+
+	// SuggestionsEnabled はボットの投稿への「suggest: 名言 — 著者」の返信を、名言の提案として受け付けます
+	SuggestionsEnabled bool `envconfig:"SUGGESTIONS_ENABLED" default:"false"`
+	// SuggestionPrefix は名言の提案の返信の先頭に付ける語です
+	SuggestionPrefix string `envconfig:"SUGGESTION_PREFIX" default:"suggest:"`
+	// SuggestionPollInterval は新しい返信を確認する間隔です
+	SuggestionPollInterval time.Duration `envconfig:"SUGGESTION_POLL_INTERVAL" default:"5m"`
+
+	// DenyDIDs はメンションへの返信や名言の提案で相手にしないユーザーのDIDです。
+	// ボットのアカウントがミュート・ブロックしているユーザーは指定しなくても相手にしません
+	DenyDIDs []string `envconfig:"DENY_DIDS"`
+
+	// AdminReadToken は状態の参照だけができる、管理APIとgRPC APIの読み取り専用のトークンです。
+	// 投稿や一時停止などの操作には ADMIN_TOKEN が必要です
+	AdminReadToken string `envconfig:"ADMIN_READ_TOKEN"`
+
+	// PublicStatsEnabled は認証なしで見られる統計ページ（投稿した数、名言の件数、今月いちばん「いいね」された名言）を
+	// 管理APIとは別のアドレスで公開します。投稿の数は HISTORY_FILE から数えます
+	PublicStatsEnabled bool `envconfig:"PUBLIC_STATS_ENABLED" default:"false"`
+	// PublicStatsAddr は統計ページの待ち受けアドレスです
+	PublicStatsAddr string `envconfig:"PUBLIC_STATS_ADDR" default:"127.0.0.1:8688"`
+	// PublicStatsCacheTTL は集計した統計を使い回す時間です。アクセスのたびに Bluesky に問い合わせないようにします
+	PublicStatsCacheTTL time.Duration `envconfig:"PUBLIC_STATS_CACHE_TTL" default:"10m"`
+
+	// PostBackoffThreshold は定期投稿がこの回数続けて失敗すると、失敗するたびに投稿間隔を2倍に延ばします。
+	// 投稿に成功すると元の間隔に戻します。0の場合は延ばしません
+	PostBackoffThreshold int `envconfig:"POST_BACKOFF_THRESHOLD" default:"3"`
+	// PostBackoffMax は延ばした投稿間隔の上限です
+	PostBackoffMax time.Duration `envconfig:"POST_BACKOFF_MAX" default:"24h"`
+
+	// RateLimitReserve は投稿先が ratelimit-remaining ヘッダーで返したリクエストの残りがこの数以下になると、
+	// ratelimit-reset まで次のリクエストを待たせます
+	RateLimitReserve int `envconfig:"RATE_LIMIT_RESERVE" default:"2"`
+	// RateLimitMaxWait はレート制限のリセットを待つ最長の時間です。これより先のリセットは待たずに送信します。0は待ちません
+	RateLimitMaxWait time.Duration `envconfig:"RATE_LIMIT_MAX_WAIT" default:"1m"`
+	// MaxRetriesRefresh はトークンのリフレッシュ（refreshSession）の最大再試行回数です。-1は MAX_RETRIES と同じです
+	MaxRetriesRefresh int `envconfig:"MAX_RETRIES_REFRESH_SESSION" default:"-1"`
+	// MaxRetriesCreateRecord は投稿（createRecord）の最大再試行回数です。-1は MAX_RETRIES と同じです
+	MaxRetriesCreateRecord int `envconfig:"MAX_RETRIES_CREATE_RECORD" default:"-1"`
+	// RetryCreateRecordServerErrors は投稿を5xxやタイムアウトでも再試行します。
+	// 投稿先が記録を書き込んでからエラーを返した場合に二重投稿になるため、デフォルトでは429と接続前の失敗だけを再試行します
+	RetryCreateRecordServerErrors bool `envconfig:"RETRY_CREATE_RECORD_SERVER_ERRORS" default:"false"`
+
+	// StateBackend は状態（送信中の投稿、承認待ちの投稿など）と投稿履歴の保存先です（file, sqlite, postgres）
+	StateBackend string `envconfig:"STATE_BACKEND" default:"file"`
+	// StateDSN は STATE_BACKEND が sqlite または postgres のときのデータベースの接続先です
+	StateDSN string `envconfig:"STATE_DSN"`
+	// HistoryMaxEntries は STATE_BACKEND が sqlite または postgres のときに残す投稿履歴の件数です（0 は削除しない）
+	HistoryMaxEntries int `envconfig:"HISTORY_MAX_ENTRIES" default:"100000"`
+
+	// MetricsBackend はメトリクスの送信先です（none, statsd, dogstatsd）
+	MetricsBackend string `envconfig:"METRICS_BACKEND" default:"none"`
+	// MetricsStatsdAddr は statsd または DogStatsD のエージェントのアドレス（UDP）です
+	MetricsStatsdAddr string `envconfig:"METRICS_STATSD_ADDR" default:"127.0.0.1:8125"`
+	// MetricsPrefix はメトリクスの名前の前に付ける文字列です
+	MetricsPrefix string `envconfig:"METRICS_PREFIX" default:"quotebot."`
+	// MetricsTags はすべてのメトリクスに付けるタグです（dogstatsd のみ。例: env:prod,service:quotebot）
+	MetricsTags []string `envconfig:"METRICS_TAGS"`
+
+	// LogFile はログを書き込むファイルです。空の場合は標準エラー出力に書き込みます。
+	// SIGUSR1 を受信するとファイルを開き直します（logrotate の create 方式向け）
+	LogFile string `envconfig:"LOG_FILE"`
+	// LogMaxSizeMB はログファイルをローテーションするサイズです（MB、0 でローテーションしない）
+	LogMaxSizeMB int `envconfig:"LOG_MAX_SIZE_MB" default:"100"`
+	// LogRotateInterval はログファイルをローテーションする間隔です（0 で時間ではローテーションしない）
+	LogRotateInterval time.Duration `envconfig:"LOG_ROTATE_INTERVAL"`
+	// LogMaxBackups は保持するローテーション済みのログファイルの数です
+	LogMaxBackups int `envconfig:"LOG_MAX_BACKUPS" default:"5"`
+
+	// HeartbeatEnabled はその日に名言を投稿していなければ、稼働していることを知らせる投稿を1日1回行います
+	HeartbeatEnabled bool `envconfig:"HEARTBEAT_ENABLED" default:"false"`
+	// HeartbeatTime は稼働を知らせる投稿を行う時刻（HH:MM、DISPLAY_TIMEZONE のタイムゾーン）です
+	HeartbeatTime string `envconfig:"HEARTBEAT_TIME" default:"21:00"`
+	// HeartbeatMessage は稼働を知らせる投稿の本文のテンプレートです（空の場合はデフォルト）
+	HeartbeatMessage string `envconfig:"HEARTBEAT_MESSAGE"`
+	// HeartbeatKeepPrevious は前に稼働を知らせた投稿を削除せずに残します
+	HeartbeatKeepPrevious bool `envconfig:"HEARTBEAT_KEEP_PREVIOUS" default:"false"`
+
+	// DeadLetterEnabled は投稿先に送って失敗した投稿を状態に残し、quotebot dlq で送り直せるようにします
+	DeadLetterEnabled bool `envconfig:"DEAD_LETTER_ENABLED" default:"false"`
+	// DeadLetterMax は残す失敗した投稿の数の上限です。超えると古い投稿から捨てます
+	DeadLetterMax int `envconfig:"DEAD_LETTER_MAX" default:"100"`
+	// StateEncryptionEnabled は状態のうち個人情報を含む値（名言の提案、名言のリクエスト）を
+	// TOKEN_ENCRYPTION_KEY の鍵で暗号化して保存します
+	StateEncryptionEnabled bool `envconfig:"STATE_ENCRYPTION_ENABLED" default:"false"`
+
+	// LinkCardCacheDir はリンクカード（リンク先の Open Graph 情報）のキャッシュを保存するディレクトリです。
+	// 空の場合はメモリにだけ保存し、再起動すると取得し直します
+	LinkCardCacheDir string `envconfig:"LINK_CARD_CACHE_DIR"`
+	// LinkCardCacheTTL は取得したリンクカードを使い回す時間です
+	LinkCardCacheTTL time.Duration `envconfig:"LINK_CARD_CACHE_TTL" default:"24h"`
+	// LinkCardCacheSize はメモリに保存するリンクカードの数の上限です。超えると最も長く使っていないものから捨てます
+	LinkCardCacheSize int `envconfig:"LINK_CARD_CACHE_SIZE" default:"1000"`
+	// LinkCardMaxBytes はリンクカードを取得するときに読むリンク先のページの最大サイズです
+	LinkCardMaxBytes int64 `envconfig:"LINK_CARD_MAX_BYTES" default:"524288"`
+
+	// sources は各設定値の取得元です（New で読み込んだ場合のみ）
+	sources *sourceTracker
+}
+
+// DatabaseState は状態と投稿履歴をデータベースに保存するかを返します
+func (c *Config) DatabaseState() bool {
+	return c.StateBackend == StateBackendSQLite || c.StateBackend == StateBackendPostgres
+}
+
+// HasState は状態の保存先があるかを返します
+func (c *Config) HasState() bool {
+	return c.StateFile != "" || c.DatabaseState()
 }
 
+// HasHistory は投稿履歴の保存先があるかを返します
+func (c *Config) HasHistory() bool {
+	return c.HistoryFile != "" || c.DatabaseState()
+}
+
+// CrossPostCommands は CROSS_POST_PLUGINS の投稿プラグインごとに、実行ファイルと引数を返します。空の指定は除きます
+func (c *Config) CrossPostCommands() [][]string {
+	var commands [][]string
+	for _, plugin := range c.CrossPostPlugins {
+		if fields := strings.Fields(plugin); len(fields) > 0 {
+			commands = append(commands, fields)
+		}
+	}
+	return commands
+}
+
+// DisplayLocation は DISPLAY_TIMEZONE のタイムゾーンを返します。未設定または不正な場合はマシンのタイムゾーンです
+func (c *Config) DisplayLocation() *time.Location {
+	if c.DisplayTimezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.DisplayTimezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Option は設定の読み込み方法を変更します
+type Option func(*loadOptions)
+
+type loadOptions struct {
+	file          string
+	fileSource    Source
+	profile       string
+	profileSource Source
+	bot           string
+}
+
+// WithFile は設定ファイル（YAMLまたはTOML）を読み込みます（--config フラグ用）。
+// 指定しない場合は QUOTEBOT_CONFIG 環境変数のパスを使います
+func WithFile(path string) Option {
+	return func(o *loadOptions) {
+		o.file = path
+		o.fileSource = SourceFlag
+	}
+}
+
+// newLoadOptions は環境変数のデフォルトに opts を適用した読み込みオプションを返します
+func newLoadOptions(opts []Option) loadOptions {
+	o := loadOptions{
+		file:          os.Getenv(ConfigFileEnv),
+		fileSource:    SourceEnv,
+		profile:       os.Getenv(ProfileEnv),
+		profileSource: SourceEnv,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// loadMutex は設定の読み込みを1つずつ行うためのロックです
+var loadMutex sync.Mutex
+
 // New は新しい設定インスタンスを作成します。
-// 環境変数から自動的に設定を読み込み、必須フィールドが欠けている場合はエラーを返します
-func New() (*Config, error) {
-	var cfg Config
+// 設定ファイルと環境変数から設定を読み込み（環境変数が優先）、必須フィールドが欠けている場合はエラーを返します
+func New(opts ...Option) (*Config, error) {
+	return load(true, opts)
+}
+
+// NewForLogin は `quotebot login` 用の設定インスタンスを作成します。
+// トークンはログインで取得するため、JWTやアプリパスワードが未設定でもエラーにしません
+func NewForLogin(opts ...Option) (*Config, error) {
+	return load(false, opts)
+}
+
+// load は設定を読み込みます。requireTokens が false の場合は認証情報の有無を確認しません
+func load(requireTokens bool, opts []Option) (*Config, error) {
+	// 設定ファイルの値は環境変数を経由して反映するため、複数のボットの設定を同時には読み込まない
+	loadMutex.Lock()
+	defer loadMutex.Unlock()
+
+	o := newLoadOptions(opts)
+	// プロファイル名を間違えて本番の設定で起動しないよう、プロファイルは設定ファイルに定義されている必要がある
+	if o.profile != "" && o.file == "" {
+		return nil, fmt.Errorf("%s を使用するには設定ファイル（--config または %s）が必要です", ProfileEnv, ConfigFileEnv)
+	}
+	if o.bot != "" && o.file == "" {
+		return nil, fmt.Errorf("--bot を使用するには設定ファイル（--config または %s）が必要です", ConfigFileEnv)
+	}
+
+	// 設定ファイルの値は、環境変数で設定されていない項目にだけ使う
+	tracker := &sourceTracker{}
+	var fileValues, profileValues, botValues map[string]string
+	var variants []FormatVariant
+	if o.file != "" {
+		fc, err := readConfigFile(o.file, o.profile, o.bot)
+		if err != nil {
+			return nil, err
+		}
+		fileValues, profileValues, botValues, variants = fc.values, fc.profileValues, fc.botValues, fc.variants
+		tracker.configFile, tracker.configFileSource = o.file, o.fileSource
+		tracker.profile, tracker.profileSource = o.profile, o.profileSource
+		tracker.bot = o.bot
+	}
+	tracker.recordInitial(fileValues, profileValues, botValues)
+	if fileValues != nil {
+		restore, err := applyConfigFile(fileValues)
+		if err != nil {
+			return nil, err
+		}
+		defer restore()
+	}
+
+	cfg := Config{FormatVariants: variants, sources: tracker}
 	if err := envconfig.Process("", &cfg); err != nil {
 		return nil, fmt.Errorf("環境変数の処理に失敗しました: %w", err)
 	}
+
+	// 秘密情報をファイルから読み込む（ACCESS_JWT_FILE など）
+	before := cfg
+	if err := loadSecretFiles(&cfg); err != nil {
+		return nil, err
+	}
+	tracker.recordFilled(before, &cfg, SourceSecretFile)
+	if len(before.TokenEncryptionPreviousKeys) == 0 && len(cfg.TokenEncryptionPreviousKeys) > 0 {
+		tracker.sources["TOKEN_ENCRYPTION_PREVIOUS_KEYS"] = SourceSecretFile
+	}
+
+	// 環境変数で指定されなかった認証情報をキーリングやVaultから補完
+	store, err := NewCredentialStore(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		before := cfg
+		if err := loadCredentials(&cfg, store); err != nil {
+			return nil, err
+		}
+		tracker.recordFilled(before, &cfg, SourceCredentialStore)
+	}
+
+	// 問題は1つずつではなく、まとめて報告する
+	problems := append(cfg.problems(), cfg.credentialProblems(store != nil, requireTokens)...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
 	return &cfg, nil
 }
+
+// credentialProblems は認証方式に必要な認証情報やトークンの保存先が設定されているかを確認します
+func (c *Config) credentialProblems(hasStore, requireTokens bool) []Problem {
+	var problems []Problem
+	if c.TokenFile != "" && c.TokenFilePassphrase == "" {
+		problems = append(problems, Problem{Key: "TOKEN_FILE_PASSPHRASE", Message: "TOKEN_FILE を使用するには TOKEN_FILE_PASSPHRASE が必要です"})
+	}
+
+	switch c.AuthMode {
+	case AuthModeOAuth:
+		// OAuthのトークンは `quotebot oauth-login` で取得し、トークンストアに保存されます
+		if c.TokenFile == "" && !hasStore {
+			problems = append(problems, Problem{Key: "AUTH_MODE", Message: "AUTH_MODE=oauth には TOKEN_FILE または CREDENTIALS_BACKEND（keyring/vault）の設定が必要です"})
+		}
+	case AuthModeSession:
+		// トークンファイルやアプリパスワードを使う場合、JWTは起動時に取得できるため任意
+		hasTokens := c.AccessJWT != "" && c.RefreshJWT != ""
+		hasAppPassword := c.Handle != "" && c.AppPassword != ""
+		// 投稿プラグインで投稿する場合はBlueskyにログインしない
+		if requireTokens && c.PublisherPlugin == "" && !hasTokens && !hasAppPassword && c.TokenFile == "" {
+			problems = append(problems, Problem{Key: "ACCESS_JWT", Message: "認証情報が設定されていません",
+				Suggestion: "ACCESS_JWT と REFRESH_JWT を設定するか、TOKEN_FILE または HANDLE と APP_PASSWORD を指定してください。`quotebot login` でトークンを保存することもできます"})
+		}
+	}
+	return problems
+}
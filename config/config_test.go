@@ -60,6 +60,94 @@ func TestNew(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "success case: token file instead of JWT env vars",
+			envVars: map[string]string{
+				"DID":                   "test-did",
+				"TOKEN_FILE":            "/var/lib/quotebot/tokens.json",
+				"TOKEN_FILE_PASSPHRASE": "passphrase",
+			},
+			want: &Config{
+				PDSURL:       "https://bsky.social",
+				Collection:   "app.bsky.feed.post",
+				QuotesFile:   "quotes.json",
+				DID:          "test-did",
+				PostInterval: time.Hour,
+				HTTPTimeout:  10 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "error case: token file without passphrase",
+			envVars: map[string]string{
+				"DID":        "test-did",
+				"TOKEN_FILE": "/var/lib/quotebot/tokens.json",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "success case: oauth mode with token file",
+			envVars: map[string]string{
+				"DID":                   "test-did",
+				"AUTH_MODE":             "oauth",
+				"TOKEN_FILE":            "/var/lib/quotebot/tokens.json",
+				"TOKEN_FILE_PASSPHRASE": "passphrase",
+			},
+			want: &Config{
+				PDSURL:       "https://bsky.social",
+				Collection:   "app.bsky.feed.post",
+				QuotesFile:   "quotes.json",
+				DID:          "test-did",
+				PostInterval: time.Hour,
+				HTTPTimeout:  10 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "error case: oauth mode without token storage",
+			envVars: map[string]string{
+				"ACCESS_JWT":  "test-access-token",
+				"REFRESH_JWT": "test-refresh-token",
+				"DID":         "test-did",
+				"AUTH_MODE":   "oauth",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "error case: unknown auth mode",
+			envVars: map[string]string{
+				"ACCESS_JWT":  "test-access-token",
+				"REFRESH_JWT": "test-refresh-token",
+				"DID":         "test-did",
+				"AUTH_MODE":   "password",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "error case: admin API without token",
+			envVars: map[string]string{
+				"ACCESS_JWT":    "test-access-token",
+				"REFRESH_JWT":   "test-refresh-token",
+				"DID":           "test-did",
+				"ADMIN_ENABLED": "true",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "error case: alert email without SMTP server",
+			envVars: map[string]string{
+				"ACCESS_JWT":     "test-access-token",
+				"REFRESH_JWT":    "test-refresh-token",
+				"DID":            "test-did",
+				"ALERT_EMAIL_TO": "ops@example.com",
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "error case: invalid time format",
 			envVars: map[string]string{
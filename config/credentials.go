@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// 認証情報のバックエンド
+const (
+	CredentialsBackendEnv     = "env"
+	CredentialsBackendKeyring = "keyring"
+	CredentialsBackendVault   = "vault"
+)
+
+// キーリングに保存する認証情報のキー
+const (
+	CredentialAccessJWT           = "access_jwt"
+	CredentialRefreshJWT          = "refresh_jwt"
+	CredentialAppPassword         = "app_password"
+	CredentialTokenFilePassphrase = "token_file_passphrase"
+	CredentialTokenEncryptionKey  = "token_encryption_key"
+	CredentialOAuthSession        = "oauth_session"
+)
+
+// ErrCredentialNotFound は認証情報がバックエンドに存在しない場合に返されます
+var ErrCredentialNotFound = errors.New("認証情報が見つかりません")
+
+// CredentialStore は認証情報の読み書きを行うインターフェースです。
+// キーリングやVaultなど、外部のシークレットプロバイダーを抽象化します
+type CredentialStore interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// KeyringStore はOSのキーチェーン（macOS Keychain, Secret Service, Windows資格情報マネージャー）に
+// 認証情報を保存します
+type KeyringStore struct {
+	service string
+	account string
+}
+
+// NewKeyringStore は新しいKeyringStoreインスタンスを作成します。
+// accountはDIDなど、同じサービス内で複数アカウントを区別するための値です
+func NewKeyringStore(service, account string) *KeyringStore {
+	return &KeyringStore{
+		service: service,
+		account: account,
+	}
+}
+
+// Get はキーリングから認証情報を読み込みます
+func (s *KeyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(s.service, s.user(key))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrCredentialNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("キーリングからの読み込みに失敗しました（%s）: %w", key, err)
+	}
+	return value, nil
+}
+
+// Set はキーリングに認証情報を書き込みます
+func (s *KeyringStore) Set(key, value string) error {
+	if err := keyring.Set(s.service, s.user(key), value); err != nil {
+		return fmt.Errorf("キーリングへの書き込みに失敗しました（%s）: %w", key, err)
+	}
+	return nil
+}
+
+// Delete はキーリングから認証情報を削除します
+func (s *KeyringStore) Delete(key string) error {
+	err := keyring.Delete(s.service, s.user(key))
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("キーリングからの削除に失敗しました（%s）: %w", key, err)
+	}
+	return nil
+}
+
+// user はキーリングのユーザー名を組み立てます
+func (s *KeyringStore) user(key string) string {
+	if s.account == "" {
+		return key
+	}
+	return s.account + "/" + key
+}
+
+// NewCredentialStore は設定に応じたCredentialStoreを作成します。
+// 環境変数バックエンドの場合はnilを返します
+func NewCredentialStore(cfg *Config) (CredentialStore, error) {
+	switch cfg.CredentialsBackend {
+	case "", CredentialsBackendEnv:
+		return nil, nil
+	case CredentialsBackendKeyring:
+		return NewKeyringStore(cfg.KeyringService, cfg.DID), nil
+	case CredentialsBackendVault:
+		if cfg.VaultAddr == "" || cfg.VaultToken == "" {
+			return nil, fmt.Errorf("CREDENTIALS_BACKEND=vault には VAULT_ADDR と VAULT_TOKEN が必要です")
+		}
+		return NewVaultStore(cfg.VaultAddr, cfg.VaultToken, cfg.VaultNamespace, cfg.VaultMount, cfg.VaultSecretPath, cfg.HTTPTimeout), nil
+	default:
+		return nil, fmt.Errorf("不明な CREDENTIALS_BACKEND です: %s", cfg.CredentialsBackend)
+	}
+}
+
+// secretField は <環境変数名>_FILE でファイルから読み込める秘密情報です
+type secretField struct {
+	env   string
+	value *string
+}
+
+// secretFields は秘密情報の環境変数と、その値を格納する cfg のフィールドの一覧を返します
+func secretFields(cfg *Config) []secretField {
+	return []secretField{
+		{"ACCESS_JWT", &cfg.AccessJWT},
+		{"REFRESH_JWT", &cfg.RefreshJWT},
+		{"APP_PASSWORD", &cfg.AppPassword},
+		{"TOKEN_FILE_PASSPHRASE", &cfg.TokenFilePassphrase},
+		{"VAULT_TOKEN", &cfg.VaultToken},
+		{"TOKEN_ENCRYPTION_KEY", &cfg.TokenEncryptionKey},
+		{"TOKEN_ENCRYPTION_PASSPHRASE", &cfg.TokenEncryptionPassphrase},
+		{"ADMIN_TOKEN", &cfg.AdminToken},
+		{"ADMIN_READ_TOKEN", &cfg.AdminReadToken},
+		{"ALERT_SMTP_PASSWORD", &cfg.AlertSMTPPassword},
+		// WebhookのURLにはトークンが含まれる
+		{"ALERT_WEBHOOK_URL", &cfg.AlertWebhookURL},
+		{"ALERT_DISCORD_WEBHOOK_URL", &cfg.AlertDiscordURL},
+		// 接続先にはデータベースのパスワードが含まれる
+		{"STATE_DSN", &cfg.StateDSN},
+	}
+}
+
+// isSecretEnv は name が _FILE で読み込める秘密情報の環境変数かを返します
+func isSecretEnv(name string) bool {
+	if name == "TOKEN_ENCRYPTION_PREVIOUS_KEYS" {
+		return true
+	}
+	for _, f := range secretFields(&Config{}) {
+		if f.env == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSecretFiles は <環境変数名>_FILE で指定されたファイルから秘密情報を読み込みます。
+// KubernetesやDockerのシークレットをファイルとしてマウントする場合に使用します。
+// REQUIRE_SECRET_FILES が有効な場合は、秘密情報を環境変数に直接設定するとエラーになります
+func loadSecretFiles(cfg *Config) error {
+	for _, f := range secretFields(cfg) {
+		if cfg.RequireSecretFiles && *f.value != "" {
+			return fmt.Errorf("REQUIRE_SECRET_FILES が有効なため、%s は %s_FILE で指定してください", f.env, f.env)
+		}
+
+		// 環境変数が直接設定されている場合はそちらを優先
+		path := os.Getenv(f.env + "_FILE")
+		if path == "" || *f.value != "" {
+			continue
+		}
+
+		content, err := readSecretFile(f.env, path)
+		if err != nil {
+			return err
+		}
+		*f.value = content
+	}
+
+	// 鍵のローテーション中の古い鍵は、1行に1つずつ（またはカンマ区切りで）記述する
+	if cfg.RequireSecretFiles && len(cfg.TokenEncryptionPreviousKeys) > 0 {
+		return fmt.Errorf("REQUIRE_SECRET_FILES が有効なため、TOKEN_ENCRYPTION_PREVIOUS_KEYS は TOKEN_ENCRYPTION_PREVIOUS_KEYS_FILE で指定してください")
+	}
+	if path := os.Getenv("TOKEN_ENCRYPTION_PREVIOUS_KEYS_FILE"); path != "" && len(cfg.TokenEncryptionPreviousKeys) == 0 {
+		content, err := readSecretFile("TOKEN_ENCRYPTION_PREVIOUS_KEYS", path)
+		if err != nil {
+			return err
+		}
+		cfg.TokenEncryptionPreviousKeys = strings.FieldsFunc(content, func(r rune) bool {
+			return r == '\n' || r == '\r' || r == ','
+		})
+		for i, key := range cfg.TokenEncryptionPreviousKeys {
+			cfg.TokenEncryptionPreviousKeys[i] = strings.TrimSpace(key)
+		}
+	}
+	return nil
+}
+
+// readSecretFile は秘密情報のファイルを読み込み、前後の空白と改行を取り除いて返します
+func readSecretFile(env, path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE の読み込みに失敗しました: %w", env, err)
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "" {
+		return "", fmt.Errorf("%s_FILE が空です: %s", env, path)
+	}
+	return value, nil
+}
+
+// loadCredentials は環境変数で設定されていない認証情報をCredentialStoreから補完します
+func loadCredentials(cfg *Config, store CredentialStore) error {
+	fields := []struct {
+		key   string
+		value *string
+	}{
+		{CredentialAccessJWT, &cfg.AccessJWT},
+		{CredentialRefreshJWT, &cfg.RefreshJWT},
+		{CredentialAppPassword, &cfg.AppPassword},
+		{CredentialTokenFilePassphrase, &cfg.TokenFilePassphrase},
+		{CredentialTokenEncryptionKey, &cfg.TokenEncryptionKey},
+	}
+
+	for _, f := range fields {
+		// 環境変数が優先されます
+		if *f.value != "" {
+			continue
+		}
+
+		value, err := store.Get(f.key)
+		if errors.Is(err, ErrCredentialNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		*f.value = value
+	}
+	return nil
+}
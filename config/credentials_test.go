@@ -0,0 +1,245 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestNew_KeyringBackend(t *testing.T) {
+	keyring.MockInit()
+
+	store := NewKeyringStore("quotebot", "did:plc:test")
+	if err := store.Set(CredentialAccessJWT, "keyring-access-token"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set(CredentialRefreshJWT, "keyring-refresh-token"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		envVars        map[string]string
+		wantAccessJWT  string
+		wantRefreshJWT string
+		wantErr        bool
+	}{
+		{
+			name: "success case: tokens loaded from keyring",
+			envVars: map[string]string{
+				"DID":                 "did:plc:test",
+				"CREDENTIALS_BACKEND": "keyring",
+			},
+			wantAccessJWT:  "keyring-access-token",
+			wantRefreshJWT: "keyring-refresh-token",
+		},
+		{
+			name: "success case: env vars take precedence over keyring",
+			envVars: map[string]string{
+				"DID":                 "did:plc:test",
+				"CREDENTIALS_BACKEND": "keyring",
+				"ACCESS_JWT":          "env-access-token",
+			},
+			wantAccessJWT:  "env-access-token",
+			wantRefreshJWT: "keyring-refresh-token",
+		},
+		{
+			name: "error case: nothing stored for another account",
+			envVars: map[string]string{
+				"DID":                 "did:plc:other",
+				"CREDENTIALS_BACKEND": "keyring",
+			},
+			wantErr: true,
+		},
+		{
+			name: "error case: unknown backend",
+			envVars: map[string]string{
+				"DID":                 "did:plc:test",
+				"CREDENTIALS_BACKEND": "unknown",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			got, err := New()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.AccessJWT != tt.wantAccessJWT {
+				t.Errorf("AccessJWT = %v, want %v", got.AccessJWT, tt.wantAccessJWT)
+			}
+			if got.RefreshJWT != tt.wantRefreshJWT {
+				t.Errorf("RefreshJWT = %v, want %v", got.RefreshJWT, tt.wantRefreshJWT)
+			}
+		})
+	}
+}
+
+func TestNewForLogin(t *testing.T) {
+	keyring.MockInit()
+
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr bool
+	}{
+		{
+			name: "success case: empty keyring before the first login",
+			envVars: map[string]string{
+				"DID":                 "did:plc:test",
+				"CREDENTIALS_BACKEND": "keyring",
+			},
+			wantErr: false,
+		},
+		{
+			name: "success case: no credentials at all",
+			envVars: map[string]string{
+				"DID": "did:plc:test",
+			},
+			wantErr: false,
+		},
+		{
+			name: "error case: token file without passphrase",
+			envVars: map[string]string{
+				"DID":        "did:plc:test",
+				"TOKEN_FILE": "/tmp/tokens.json",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			_, err := NewForLogin()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewForLogin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew_SecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		return path
+	}
+	accessFile := writeSecret("access-jwt", "file-access-token\n")
+	refreshFile := writeSecret("refresh-jwt", "file-refresh-token\n")
+	webhookFile := writeSecret("webhook-url", "https://hooks.example.com/secret-token\n")
+	previousKeysFile := writeSecret("previous-keys", "old-key-1\nold-key-2\n")
+	emptyFile := writeSecret("empty", "\n")
+
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		check   func(t *testing.T, cfg *Config)
+		wantErr bool
+	}{
+		{
+			name: "success case: tokens, webhook URL and previous keys from files",
+			envVars: map[string]string{
+				"DID":                                 "did:plc:test",
+				"ACCESS_JWT_FILE":                     accessFile,
+				"REFRESH_JWT_FILE":                    refreshFile,
+				"ALERT_WEBHOOK_URL_FILE":              webhookFile,
+				"TOKEN_ENCRYPTION_PREVIOUS_KEYS_FILE": previousKeysFile,
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.AccessJWT != "file-access-token" || cfg.RefreshJWT != "file-refresh-token" {
+					t.Errorf("tokens = %v/%v", cfg.AccessJWT, cfg.RefreshJWT)
+				}
+				if cfg.AlertWebhookURL != "https://hooks.example.com/secret-token" {
+					t.Errorf("AlertWebhookURL = %v", cfg.AlertWebhookURL)
+				}
+				if want := []string{"old-key-1", "old-key-2"}; !reflect.DeepEqual(cfg.TokenEncryptionPreviousKeys, want) {
+					t.Errorf("TokenEncryptionPreviousKeys = %v, want %v", cfg.TokenEncryptionPreviousKeys, want)
+				}
+			},
+		},
+		{
+			name: "success case: REQUIRE_SECRET_FILES with secrets from files",
+			envVars: map[string]string{
+				"DID":                  "did:plc:test",
+				"REQUIRE_SECRET_FILES": "true",
+				"ACCESS_JWT_FILE":      accessFile,
+				"REFRESH_JWT_FILE":     refreshFile,
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.AccessJWT != "file-access-token" {
+					t.Errorf("AccessJWT = %v", cfg.AccessJWT)
+				}
+			},
+		},
+		{
+			name: "error case: REQUIRE_SECRET_FILES with a plain env secret",
+			envVars: map[string]string{
+				"DID":                  "did:plc:test",
+				"REQUIRE_SECRET_FILES": "true",
+				"ACCESS_JWT":           "plain-access-token",
+				"REFRESH_JWT_FILE":     refreshFile,
+			},
+			wantErr: true,
+		},
+		{
+			name: "error case: empty secret file",
+			envVars: map[string]string{
+				"DID":              "did:plc:test",
+				"ACCESS_JWT_FILE":  emptyFile,
+				"REFRESH_JWT_FILE": refreshFile,
+			},
+			wantErr: true,
+		},
+		{
+			name: "error case: missing secret file",
+			envVars: map[string]string{
+				"DID":              "did:plc:test",
+				"ACCESS_JWT_FILE":  filepath.Join(dir, "missing"),
+				"REFRESH_JWT_FILE": refreshFile,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			got, err := New()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			tt.check(t, got)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv は設定ファイルのパスを指定する環境変数です（--config が優先されます）
+const ConfigFileEnv = "QUOTEBOT_CONFIG"
+
+// fileConfig は設定ファイルから読み込んだ内容です
+type fileConfig struct {
+	// values は環境変数名をキーとした値です（プロファイルの値で上書き済み）
+	values map[string]string
+	// profileValues はプロファイルから取った値です（プロファイルごとのデフォルト値を含む）
+	profileValues map[string]string
+	// variants は投稿形式のバリエーションです
+	variants []FormatVariant
+	// bots は bots に定義されているボットの名前です
+	bots []string
+	// botValues は選んだボットの設定から取った値です
+	botValues map[string]string
+}
+
+// readConfigFile は設定ファイル（YAMLまたはTOML）を読み込み、環境変数名をキーとした値に変換します。
+// キーには環境変数名を大文字・小文字を問わずに使えます。入れ子のテーブルは _ で連結し
+// （alert: {threshold: 3} は ALERT_THRESHOLD）、リストはカンマ区切りの値として扱います。
+// profile を指定した場合は、profiles の中のそのプロファイルの値で上書きします。
+// bot を指定した場合は、さらに bots の中のそのボットの値で上書きします
+func readConfigFile(path, profile, bot string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	raw := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("設定ファイルの形式が不明です（.yaml, .yml, .toml のいずれか）: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイル %s の解析に失敗しました: %w", path, err)
+	}
+
+	rawProfile, err := splitProfiles(raw, profile)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイル %s: %w", path, err)
+	}
+	fc := &fileConfig{values: map[string]string{}}
+	bots, rawBot, err := splitBots(raw, bot)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイル %s: %w", path, err)
+	}
+	fc.bots = bots
+	if fc.variants, err = splitVariants(raw); err != nil {
+		return nil, fmt.Errorf("設定ファイル %s: %w", path, err)
+	}
+
+	fields := envFields()
+	if err := flattenConfig(raw, "", fields, fc.values); err != nil {
+		return nil, fmt.Errorf("設定ファイル %s: %w", path, err)
+	}
+	if rawProfile != nil {
+		if err := fc.applyProfile(path, profile, rawProfile, fields); err != nil {
+			return nil, err
+		}
+	}
+	if rawBot != nil {
+		fc.botValues = map[string]string{}
+		if err := flattenConfig(rawBot, "", fields, fc.botValues); err != nil {
+			return nil, fmt.Errorf("設定ファイル %s のボット %s: %w", path, bot, err)
+		}
+		for k, v := range fc.botValues {
+			fc.values[k] = v
+		}
+	}
+	return fc, nil
+}
+
+// applyProfile はプロファイルの値（プロファイルごとのデフォルト値を含む）で設定ファイルの値を上書きします
+func (fc *fileConfig) applyProfile(path, profile string, rawProfile map[string]interface{}, fields map[string]reflect.Kind) error {
+	fc.profileValues = map[string]string{}
+	if err := flattenConfig(rawProfile, "", fields, fc.profileValues); err != nil {
+		return fmt.Errorf("設定ファイル %s のプロファイル %s: %w", path, profile, err)
+	}
+	for k, v := range profileDefaults[profile] {
+		if _, ok := fc.profileValues[k]; !ok {
+			fc.profileValues[k] = v
+		}
+	}
+	for k, v := range fc.profileValues {
+		fc.values[k] = v
+	}
+	return nil
+}
+
+// flattenConfig は設定ファイルの値を環境変数名と envconfig が解釈できる文字列に変換します
+func flattenConfig(raw map[string]interface{}, prefix string, fields map[string]reflect.Kind, values map[string]string) error {
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		name := prefix + strings.ToUpper(k)
+		kind, known := fields[name]
+
+		switch v := raw[k].(type) {
+		case nil:
+			// 値が空の項目は未設定として扱い、デフォルト値を使う
+			continue
+		case map[string]interface{}:
+			if kind == reflect.Map {
+				entries, err := joinMap(name, v)
+				if err != nil {
+					return err
+				}
+				values[name] = entries
+				continue
+			}
+			if err := flattenConfig(v, name+"_", fields, values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// _FILE の付いた秘密情報のファイルパスも指定できる
+		if !known && !(strings.HasSuffix(name, "_FILE") && isSecretEnv(strings.TrimSuffix(name, "_FILE"))) {
+			return fmt.Errorf("不明な設定項目です: %s", name)
+		}
+
+		switch v := raw[k].(type) {
+		case []interface{}:
+			if known && kind != reflect.Slice {
+				return fmt.Errorf("%s にリストは指定できません", name)
+			}
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				s, err := scalarString(name, item)
+				if err != nil {
+					return err
+				}
+				items = append(items, s)
+			}
+			values[name] = strings.Join(items, ",")
+		default:
+			s, err := scalarString(name, v)
+			if err != nil {
+				return err
+			}
+			values[name] = s
+		}
+	}
+	return nil
+}
+
+// joinMap はマップの値を envconfig の key:value,key:value 形式に変換します
+func joinMap(name string, m map[string]interface{}) (string, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, 0, len(m))
+	for _, k := range keys {
+		s, err := scalarString(name, m[k])
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, k+":"+s)
+	}
+	return strings.Join(entries, ","), nil
+}
+
+// scalarString は文字列・数値・真偽値を文字列に変換します
+func scalarString(name string, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return "", fmt.Errorf("%s の値の形式が正しくありません", name)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// envFields は Config の環境変数名とフィールドの種類の一覧を返します
+func envFields() map[string]reflect.Kind {
+	fields := map[string]reflect.Kind{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("envconfig"); name != "" {
+			fields[name] = t.Field(i).Type.Kind()
+		}
+	}
+	return fields
+}
+
+// applyConfigFile は環境変数で設定されていない項目だけを設定ファイルの値で補います（環境変数が優先）。
+// 戻り値の関数は、補った環境変数を元に戻します
+func applyConfigFile(values map[string]string) (restore func(), err error) {
+	var applied []string
+	restore = func() {
+		for _, name := range applied {
+			os.Unsetenv(name)
+		}
+	}
+	for name, value := range values {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			restore()
+			return nil, fmt.Errorf("%s の設定に失敗しました: %w", name, err)
+		}
+		applied = append(applied, name)
+	}
+	return restore, nil
+}
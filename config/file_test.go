@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNew_ConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		envVars  map[string]string
+		check    func(t *testing.T, cfg *Config)
+		wantErr  bool
+	}{
+		{
+			name:     "success case: yaml with nested tables, lists and maps",
+			fileName: "config.yaml",
+			content: `did: did:plc:file
+access_jwt: file-access-token
+refresh_jwt: file-refresh-token
+post_interval: 30m
+max_retries: 5
+alert:
+  threshold: 2
+  smtp_addr: smtp.example.com:587
+  email_from: bot@example.com
+  email_to:
+    - ops@example.com
+    - oncall@example.com
+log_module_levels:
+  http: debug
+  token: warn
+`,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DID != "did:plc:file" || cfg.PostInterval != 30*time.Minute || cfg.MaxRetries != 5 {
+					t.Errorf("DID = %v, PostInterval = %v, MaxRetries = %v", cfg.DID, cfg.PostInterval, cfg.MaxRetries)
+				}
+				if cfg.AlertThreshold != 2 {
+					t.Errorf("AlertThreshold = %v, want 2", cfg.AlertThreshold)
+				}
+				if want := []string{"ops@example.com", "oncall@example.com"}; !reflect.DeepEqual(cfg.AlertEmailTo, want) {
+					t.Errorf("AlertEmailTo = %v, want %v", cfg.AlertEmailTo, want)
+				}
+				if want := map[string]string{"http": "debug", "token": "warn"}; !reflect.DeepEqual(cfg.LogModuleLevels, want) {
+					t.Errorf("LogModuleLevels = %v, want %v", cfg.LogModuleLevels, want)
+				}
+				// 設定ファイルにない項目はデフォルト値
+				if cfg.PDSURL != "https://bsky.social" {
+					t.Errorf("PDSURL = %v, want default", cfg.PDSURL)
+				}
+			},
+		},
+		{
+			name:     "success case: toml with upper case keys",
+			fileName: "config.toml",
+			content: `DID = "did:plc:file"
+ACCESS_JWT = "file-access-token"
+REFRESH_JWT = "file-refresh-token"
+HTTP_LOG_REQUESTS = true
+
+[admin]
+addr = "127.0.0.1:9999"
+`,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DID != "did:plc:file" || !cfg.HTTPLogRequests || cfg.AdminAddr != "127.0.0.1:9999" {
+					t.Errorf("DID = %v, HTTPLogRequests = %v, AdminAddr = %v", cfg.DID, cfg.HTTPLogRequests, cfg.AdminAddr)
+				}
+			},
+		},
+		{
+			name:     "success case: env vars take precedence over the file",
+			fileName: "config.yaml",
+			content: `did: did:plc:file
+access_jwt: file-access-token
+refresh_jwt: file-refresh-token
+post_interval: 30m
+`,
+			envVars: map[string]string{
+				"DID":           "did:plc:env",
+				"POST_INTERVAL": "2h",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DID != "did:plc:env" || cfg.PostInterval != 2*time.Hour {
+					t.Errorf("DID = %v, PostInterval = %v", cfg.DID, cfg.PostInterval)
+				}
+				if cfg.AccessJWT != "file-access-token" {
+					t.Errorf("AccessJWT = %v, want value from the file", cfg.AccessJWT)
+				}
+			},
+		},
+		{
+			name:     "error case: unknown key",
+			fileName: "config.yaml",
+			content: `did: did:plc:file
+post_intervall: 30m
+`,
+			wantErr: true,
+		},
+		{
+			name:     "error case: list for a scalar setting",
+			fileName: "config.yaml",
+			content: `did: [did:plc:a, did:plc:b]
+`,
+			wantErr: true,
+		},
+		{
+			name:     "error case: unsupported extension",
+			fileName: "config.json",
+			content:  `{"did": "did:plc:file"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "error case: invalid value",
+			fileName: "config.yaml",
+			content: `did: did:plc:file
+access_jwt: file-access-token
+refresh_jwt: file-refresh-token
+post_interval: soon
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			got, err := New(WithFile(path))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			tt.check(t, got)
+
+			// 設定ファイルの値は環境変数に残らない
+			if _, ok := os.LookupEnv("ACCESS_JWT"); ok {
+				t.Errorf("ACCESS_JWT remained in the environment")
+			}
+		})
+	}
+}
+
+func TestNew_ConfigFileEnv(t *testing.T) {
+	os.Clearenv()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := "did: did:plc:file\naccess_jwt: a\nrefresh_jwt: r\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	os.Setenv(ConfigFileEnv, path)
+
+	got, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got.DID != "did:plc:file" {
+		t.Errorf("DID = %v, want did:plc:file", got.DID)
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProfileEnv は使用するプロファイルを指定する環境変数です（--profile が優先されます）
+const ProfileEnv = "QUOTEBOT_PROFILE"
+
+// ProfileDev は開発用のプロファイル名です。DRY_RUN を指定しない場合は投稿しません
+const ProfileDev = "dev"
+
+// profilesKey は設定ファイルでプロファイルをまとめるキーです
+const profilesKey = "PROFILES"
+
+// profileDefaults はプロファイルごとのデフォルト値です。設定ファイルと環境変数の値が優先されます
+var profileDefaults = map[string]map[string]string{
+	// テスト用の名言を本番のアカウントに投稿してしまわないよう、開発中は投稿しない
+	ProfileDev: {"DRY_RUN": "true"},
+}
+
+// WithProfile は設定ファイルの profiles から使用するプロファイルを選びます（--profile フラグ用）。
+// 指定しない場合は QUOTEBOT_PROFILE 環境変数のプロファイルを使います
+func WithProfile(name string) Option {
+	return func(o *loadOptions) {
+		o.profile = name
+		o.profileSource = SourceFlag
+	}
+}
+
+// splitProfiles は設定ファイルの内容から profiles を取り除き、name のプロファイルを返します。
+// name が空の場合は nil を返します
+func splitProfiles(raw map[string]interface{}, name string) (map[string]interface{}, error) {
+	var profiles map[string]interface{}
+	for k, v := range raw {
+		if strings.ToUpper(k) != profilesKey {
+			continue
+		}
+		delete(raw, k)
+		if v == nil {
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("profiles にはプロファイル名ごとの設定を指定してください")
+		}
+		profiles = m
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(profiles))
+	for k, v := range profiles {
+		if k != name {
+			names = append(names, k)
+			continue
+		}
+		if v == nil {
+			// 設定のないプロファイルはデフォルト値だけを使う
+			return map[string]interface{}{}, nil
+		}
+		profile, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("プロファイル %s の設定の形式が正しくありません", name)
+		}
+		return profile, nil
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("プロファイル %s が見つかりません（定義されているプロファイル: %s）", name, strings.Join(names, ", "))
+}
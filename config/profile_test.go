@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const profileTestConfig = `did: did:plc:prod
+access_jwt: prod-access-token
+refresh_jwt: prod-refresh-token
+pds_url: https://bsky.social
+profiles:
+  dev:
+    did: did:plc:test
+    pds_url: http://localhost:2583
+  staging:
+    did: did:plc:staging
+    dry_run: false
+  prod:
+`
+
+func TestNew_Profile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		envVars map[string]string
+		check   func(t *testing.T, cfg *Config)
+		wantErr bool
+	}{
+		{
+			name: "success case: no profile uses the base settings",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DID != "did:plc:prod" || cfg.DryRun || cfg.Profile() != "" {
+					t.Errorf("DID = %v, DryRun = %v, Profile() = %q", cfg.DID, cfg.DryRun, cfg.Profile())
+				}
+			},
+		},
+		{
+			name:    "success case: dev overrides the base settings and defaults to dry run",
+			profile: ProfileDev,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DID != "did:plc:test" || cfg.PDSURL != "http://localhost:2583" {
+					t.Errorf("DID = %v, PDSURL = %v", cfg.DID, cfg.PDSURL)
+				}
+				// プロファイルにない項目は共通の設定を使う
+				if cfg.AccessJWT != "prod-access-token" {
+					t.Errorf("AccessJWT = %v, want value from the base settings", cfg.AccessJWT)
+				}
+				if !cfg.DryRun {
+					t.Errorf("DryRun = false, want true")
+				}
+				if got := settingSource(cfg, "DID"); got != SourceProfile {
+					t.Errorf("DID source = %v, want %v", got, SourceProfile)
+				}
+				if got := settingSource(cfg, "ACCESS_JWT"); got != SourceFile {
+					t.Errorf("ACCESS_JWT source = %v, want %v", got, SourceFile)
+				}
+			},
+		},
+		{
+			name:    "success case: env vars take precedence over the profile",
+			profile: ProfileDev,
+			envVars: map[string]string{"DRY_RUN": "false"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DryRun || settingSource(cfg, "DRY_RUN") != SourceEnv {
+					t.Errorf("DryRun = %v, source = %v", cfg.DryRun, settingSource(cfg, "DRY_RUN"))
+				}
+			},
+		},
+		{
+			name:    "success case: empty profile",
+			profile: "prod",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DID != "did:plc:prod" || cfg.DryRun || cfg.Profile() != "prod" {
+					t.Errorf("DID = %v, DryRun = %v, Profile() = %q", cfg.DID, cfg.DryRun, cfg.Profile())
+				}
+			},
+		},
+		{
+			name:    "error case: unknown profile",
+			profile: "production",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+			}
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(profileTestConfig), 0600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			if tt.profile != "" {
+				os.Setenv(ProfileEnv, tt.profile)
+			}
+
+			got, err := New(WithFile(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			tt.check(t, got)
+		})
+	}
+}
+
+func TestNew_ProfileWithoutFile(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DID", "did:plc:env")
+	os.Setenv("ACCESS_JWT", "a")
+	os.Setenv("REFRESH_JWT", "r")
+
+	if _, err := New(WithProfile(ProfileDev)); err == nil {
+		t.Errorf("New() error = nil, want error for a profile without a config file")
+	}
+}
+
+// settingSource は Settings() から key の取得元を返します
+func settingSource(cfg *Config, key string) Source {
+	for _, s := range cfg.Settings() {
+		if s.Key == key {
+			return s.Source
+		}
+	}
+	return ""
+}
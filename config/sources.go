@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/internal/redact"
+)
+
+// Source は設定値の取得元です
+type Source string
+
+// 設定値の取得元
+const (
+	SourceDefault         Source = "default"
+	SourceEnv             Source = "env"
+	SourceFile            Source = "file"
+	SourceProfile         Source = "profile" // 設定ファイルの profiles
+	SourceBot             Source = "bot"     // 設定ファイルの bots
+	SourceFlag            Source = "flag"
+	SourceSecretFile      Source = "secret-file"      // ACCESS_JWT_FILE などのファイル
+	SourceCredentialStore Source = "credential-store" // キーリングまたはVault
+)
+
+// Setting は `quotebot config show` で表示する1つの設定です
+type Setting struct {
+	Key    string
+	Value  string // 秘密情報はマスクされます
+	Source Source
+}
+
+// sourceTracker は設定の読み込み中に、各設定値の取得元を記録します
+type sourceTracker struct {
+	configFile       string
+	configFileSource Source
+	profile          string
+	profileSource    Source
+	bot              string
+	sources          map[string]Source
+}
+
+// recordInitial は環境変数と設定ファイルの値を反映する前に、各設定の取得元を記録します
+func (t *sourceTracker) recordInitial(fileValues, profileValues, botValues map[string]string) {
+	t.sources = map[string]Source{}
+	for name := range envFields() {
+		if _, ok := os.LookupEnv(name); ok {
+			t.sources[name] = SourceEnv
+		} else if _, ok := botValues[name]; ok {
+			t.sources[name] = SourceBot
+		} else if _, ok := profileValues[name]; ok {
+			t.sources[name] = SourceProfile
+		} else if _, ok := fileValues[name]; ok {
+			t.sources[name] = SourceFile
+		} else {
+			t.sources[name] = SourceDefault
+		}
+	}
+}
+
+// recordFilled は before で空だった秘密情報のうち、cfg で値が入ったものの取得元を source にします
+func (t *sourceTracker) recordFilled(before Config, cfg *Config, source Source) {
+	after := secretFields(cfg)
+	for i, f := range secretFields(&before) {
+		if *f.value == "" && *after[i].value != "" {
+			t.sources[f.env] = source
+		}
+	}
+}
+
+// Settings は実際に使われる設定値と、その取得元の一覧を返します。
+// 秘密情報の値はマスクされるため、そのまま表示やログ出力ができます
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	if c.sources != nil && c.sources.configFile != "" {
+		settings = append(settings, Setting{Key: ConfigFileEnv, Value: c.sources.configFile, Source: c.sources.configFileSource})
+	}
+	if c.sources != nil && c.sources.profile != "" {
+		settings = append(settings, Setting{Key: ProfileEnv, Value: c.sources.profile, Source: c.sources.profileSource})
+	}
+
+	v := reflect.ValueOf(*c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("envconfig")
+		if name == "" {
+			continue
+		}
+		value := formatValue(v.Field(i))
+		if value != "" && isSecretEnv(name) {
+			value = redact.Placeholder
+		}
+		var source Source
+		if c.sources != nil {
+			source = c.sources.sources[name]
+		}
+		settings = append(settings, Setting{Key: name, Value: value, Source: source})
+	}
+	return settings
+}
+
+// Profile は使用しているプロファイル名を返します。プロファイルを使っていない場合は空です
+func (c *Config) Profile() string {
+	if c.sources == nil {
+		return ""
+	}
+	return c.sources.profile
+}
+
+// formatValue は設定値を環境変数と同じ形式の文字列にします
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			entries = append(entries, fmt.Sprintf("%v:%v", key.Interface(), v.MapIndex(key).Interface()))
+		}
+		sort.Strings(entries)
+		return strings.Join(entries, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
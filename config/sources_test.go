@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/littleironwaltz/quotebot/internal/redact"
+)
+
+func TestConfig_Settings(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	content := "pds_url: https://pds.example.com\npost_interval: 30m\n"
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	accessFile := filepath.Join(dir, "access-jwt")
+	if err := os.WriteFile(accessFile, []byte("file-access-token"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	os.Clearenv()
+	os.Setenv("DID", "did:plc:test")
+	os.Setenv("ACCESS_JWT_FILE", accessFile)
+	os.Setenv("REFRESH_JWT", "env-refresh-token")
+	os.Setenv("POST_INTERVAL", "2h")
+
+	cfg, err := New(WithFile(configFile))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	settings := map[string]Setting{}
+	for _, s := range cfg.Settings() {
+		settings[s.Key] = s
+	}
+
+	tests := []struct {
+		key        string
+		wantValue  string
+		wantSource Source
+	}{
+		{key: ConfigFileEnv, wantValue: configFile, wantSource: SourceFlag},
+		{key: "PDS_URL", wantValue: "https://pds.example.com", wantSource: SourceFile},
+		{key: "POST_INTERVAL", wantValue: "2h0m0s", wantSource: SourceEnv},
+		{key: "HTTP_TIMEOUT", wantValue: "10s", wantSource: SourceDefault},
+		{key: "ACCESS_JWT", wantValue: redact.Placeholder, wantSource: SourceSecretFile},
+		{key: "REFRESH_JWT", wantValue: redact.Placeholder, wantSource: SourceEnv},
+		{key: "APP_PASSWORD", wantValue: "", wantSource: SourceDefault},
+	}
+	for _, tt := range tests {
+		got, ok := settings[tt.key]
+		if !ok {
+			t.Errorf("Settings() has no %s", tt.key)
+			continue
+		}
+		if got.Value != tt.wantValue || got.Source != tt.wantSource {
+			t.Errorf("Settings()[%s] = %q from %s, want %q from %s", tt.key, got.Value, got.Source, tt.wantValue, tt.wantSource)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func validConfig(t *testing.T) Config {
+	quotesFile := filepath.Join(t.TempDir(), "quotes.json")
+	if err := os.WriteFile(quotesFile, []byte("[]"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return Config{
+		PDSURL:               "https://bsky.social",
+		QuotesFile:           quotesFile,
+		DID:                  "did:plc:test",
+		AuthMode:             AuthModeSession,
+		PostInterval:         time.Hour,
+		HTTPTimeout:          10 * time.Second,
+		PostTimeout:          2 * time.Minute,
+		ShutdownTimeout:      30 * time.Second,
+		TokenRefreshInterval: 45 * time.Minute,
+		MaxRetries:           3,
+		RetryBudget:          20,
+		RetryBudgetWindow:    time.Hour,
+		TLSCipherSuites:      TLSCipherSuitesRestricted,
+		HistoryMaxSizeMB:     10,
+		AlertThreshold:       3,
+		HashtagMode:          "fixed",
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *Config)
+		wantKeys []string
+	}{
+		{
+			name:   "success case: valid config",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name: "success case: retry budget disabled without window",
+			modify: func(cfg *Config) {
+				cfg.RetryBudget = 0
+				cfg.RetryBudgetWindow = 0
+			},
+		},
+		{
+			name: "error case: all problems are reported at once",
+			modify: func(cfg *Config) {
+				cfg.PDSURL = "bsky.social"
+				cfg.PostInterval = 0
+				cfg.MaxRetries = 100
+				cfg.QuotesFile = filepath.Join(t.TempDir(), "missing.json")
+			},
+			wantKeys: []string{"PDS_URL", "POST_INTERVAL", "MAX_RETRIES", "QUOTES_FILE"},
+		},
+		{
+			name: "error case: PDS URL with unsupported scheme",
+			modify: func(cfg *Config) {
+				cfg.PDSURL = "ftp://bsky.social"
+			},
+			wantKeys: []string{"PDS_URL"},
+		},
+		{
+			name: "error case: negative durations and retries",
+			modify: func(cfg *Config) {
+				cfg.RetryBackoff = -time.Second
+				cfg.MaxRetries = -1
+			},
+			wantKeys: []string{"RETRY_BACKOFF", "MAX_RETRIES"},
+		},
+		{
+			name: "error case: negative size limits",
+			modify: func(cfg *Config) {
+				cfg.HTTPMaxResponseBytes = -1
+				cfg.BlobMaxBytes = -1
+			},
+			wantKeys: []string{"HTTP_MAX_RESPONSE_BYTES", "BLOB_MAX_BYTES"},
+		},
+		{
+			name: "error case: request log sampling out of range",
+			modify: func(cfg *Config) {
+				cfg.HTTPLogSampleRate = 1.5
+				cfg.HTTPLogPerMinute = -1
+			},
+			wantKeys: []string{"HTTP_LOG_SAMPLE_RATE", "HTTP_LOG_PER_MINUTE"},
+		},
+		{
+			name: "success case: display timezone",
+			modify: func(cfg *Config) {
+				cfg.DisplayTimezone = "Asia/Tokyo"
+			},
+		},
+		{
+			name: "error case: unknown display timezone",
+			modify: func(cfg *Config) {
+				cfg.DisplayTimezone = "Mars/Olympus_Mons"
+			},
+			wantKeys: []string{"DISPLAY_TIMEZONE"},
+		},
+		{
+			name: "error case: invalid leader election lease",
+			modify: func(cfg *Config) {
+				cfg.LeaderElection = true
+				cfg.LeaderElectionLease = "QuoteBot_Lease"
+				cfg.LeaderElectionLeaseDuration = time.Second
+			},
+			wantKeys: []string{"LEADER_ELECTION_LEASE", "LEADER_ELECTION_LEASE_DURATION"},
+		},
+		{
+			name: "success case: leader election with a postgres advisory lock",
+			modify: func(cfg *Config) {
+				cfg.LeaderElection = true
+				cfg.LeaderElectionBackend = LeaderElectionBackendPostgres
+				cfg.LeaderElectionLease = "QuoteBot_Lock"
+				cfg.LeaderElectionLeaseDuration = 15 * time.Second
+				cfg.StateBackend = StateBackendPostgres
+				cfg.StateDSN = "postgres://quotebot@db/quotebot"
+			},
+		},
+		{
+			name: "error case: postgres leader election without the postgres state backend",
+			modify: func(cfg *Config) {
+				cfg.LeaderElection = true
+				cfg.LeaderElectionBackend = LeaderElectionBackendPostgres
+				cfg.LeaderElectionLeaseDuration = 15 * time.Second
+			},
+			wantKeys: []string{"LEADER_ELECTION_BACKEND"},
+		},
+		{
+			name: "error case: missing occasions file and invalid occasion time",
+			modify: func(cfg *Config) {
+				cfg.OccasionsFile = "missing-occasions.json"
+				cfg.OccasionTime = "9am"
+			},
+			wantKeys: []string{"OCCASION_TIME", "OCCASIONS_FILE"},
+		},
+		{
+			name: "error case: quotes file is a directory",
+			modify: func(cfg *Config) {
+				cfg.QuotesFile = t.TempDir()
+			},
+			wantKeys: []string{"QUOTES_FILE"},
+		},
+		{
+			name: "error case: invalid webhook URL and unknown enums",
+			modify: func(cfg *Config) {
+				cfg.AlertWebhookURL = "hooks.example.com/token"
+				cfg.AuthMode = "password"
+				cfg.TLSCipherSuites = "weak"
+			},
+			wantKeys: []string{"AUTH_MODE", "TLS_CIPHER_SUITES", "ALERT_WEBHOOK_URL"},
+		},
+		{
+			name: "error case: post template that does not parse",
+			modify: func(cfg *Config) {
+				cfg.PostTemplate = "{{.Text}}\n- {{.Author}}"
+				cfg.PostTemplateBluesky = "{{.Text"
+			},
+			wantKeys: []string{"POST_TEMPLATE_BLUESKY"},
+		},
+		{
+			name: "error case: hashtags",
+			modify: func(cfg *Config) {
+				cfg.HashtagMode = "rotate"
+				cfg.HashtagsPerPost = 0
+				cfg.Hashtags = []string{"#quote", "two words"}
+				cfg.HashtagMap = map[string]string{"year": "#2024"}
+			},
+			wantKeys: []string{"HASHTAGS_PER_POST", "HASHTAGS", "HASHTAG_MAP"},
+		},
+		{
+			name: "error case: approval without state file or admin API",
+			modify: func(cfg *Config) {
+				cfg.ApprovalRequired = true
+				cfg.ApprovalTTL = time.Hour
+			},
+			wantKeys: []string{"STATE_FILE", "ADMIN_ENABLED"},
+		},
+		{
+			name: "error case: deny-list",
+			modify: func(cfg *Config) {
+				cfg.DenyPatterns = []string{"(unclosed"}
+				cfg.DenyAction = "drop"
+			},
+			wantKeys: []string{"DENY_PATTERNS", "DENY_ACTION"},
+		},
+		{
+			name: "error case: gRPC API without a token",
+			modify: func(cfg *Config) {
+				cfg.GRPCEnabled = true
+				cfg.AdminToken = ""
+			},
+			wantKeys: []string{"ADMIN_TOKEN"},
+		},
+		{
+			name: "error case: read-only admin token same as the admin token",
+			modify: func(cfg *Config) {
+				cfg.AdminToken = "secret"
+				cfg.AdminReadToken = "secret"
+			},
+			wantKeys: []string{"ADMIN_READ_TOKEN"},
+		},
+		{
+			name: "error case: public stats page",
+			modify: func(cfg *Config) {
+				cfg.PublicStatsEnabled = true
+				cfg.PublicStatsAddr = cfg.AdminAddr
+				cfg.AdminEnabled = true
+				cfg.AdminToken = "secret"
+				cfg.PublicStatsCacheTTL = time.Second
+			},
+			wantKeys: []string{"HISTORY_FILE", "PUBLIC_STATS_ADDR", "PUBLIC_STATS_CACHE_TTL"},
+		},
+		{
+			name: "error case: negative rate limit settings",
+			modify: func(cfg *Config) {
+				cfg.RateLimitReserve = -1
+				cfg.RateLimitMaxWait = -time.Second
+			},
+			wantKeys: []string{"RATE_LIMIT_MAX_WAIT", "RATE_LIMIT_RESERVE"},
+		},
+		{
+			name: "error case: per-request retry counts",
+			modify: func(cfg *Config) {
+				cfg.MaxRetriesRefresh = -1
+				cfg.MaxRetriesCreateRecord = -2
+			},
+			wantKeys: []string{"MAX_RETRIES_CREATE_RECORD"},
+		},
+		{
+			name: "success case: approvals stored in a database",
+			modify: func(cfg *Config) {
+				cfg.StateBackend = StateBackendSQLite
+				cfg.StateDSN = "file:quotebot.db"
+				cfg.ApprovalRequired = true
+				cfg.ApprovalTTL = time.Hour
+				cfg.AdminEnabled = true
+				cfg.AdminToken = "token"
+			},
+		},
+		{
+			name: "error case: state backend",
+			modify: func(cfg *Config) {
+				cfg.StateBackend = StateBackendPostgres
+			},
+			wantKeys: []string{"STATE_DSN"},
+		},
+		{
+			name: "error case: database history retention",
+			modify: func(cfg *Config) {
+				cfg.StateBackend = StateBackendSQLite
+				cfg.StateDSN = "file:quotebot.db"
+				cfg.HistoryMaxEntries = -1
+			},
+			wantKeys: []string{"HISTORY_MAX_ENTRIES"},
+		},
+		{
+			name: "error case: unknown state backend",
+			modify: func(cfg *Config) {
+				cfg.StateBackend = "redis"
+			},
+			wantKeys: []string{"STATE_BACKEND"},
+		},
+		{
+			name: "error case: metrics backend",
+			modify: func(cfg *Config) {
+				cfg.MetricsBackend = MetricsBackendDogStatsD
+				cfg.MetricsStatsdAddr = "localhost"
+			},
+			wantKeys: []string{"METRICS_STATSD_ADDR"},
+		},
+		{
+			name: "error case: unknown metrics backend",
+			modify: func(cfg *Config) {
+				cfg.MetricsBackend = "prometheus"
+			},
+			wantKeys: []string{"METRICS_BACKEND"},
+		},
+		{
+			name: "error case: negative log rotation",
+			modify: func(cfg *Config) {
+				cfg.LogFile = "quotebot.log"
+				cfg.LogMaxSizeMB = -1
+				cfg.LogRotateInterval = -time.Hour
+				cfg.LogMaxBackups = -1
+			},
+			wantKeys: []string{"LOG_MAX_SIZE_MB", "LOG_ROTATE_INTERVAL", "LOG_MAX_BACKUPS"},
+		},
+		{
+			name: "error case: negative link card cache settings",
+			modify: func(cfg *Config) {
+				cfg.LinkCardCacheTTL = -time.Hour
+				cfg.LinkCardCacheSize = -1
+				cfg.LinkCardMaxBytes = -1
+			},
+			wantKeys: []string{"LINK_CARD_CACHE_TTL", "LINK_CARD_CACHE_SIZE", "LINK_CARD_MAX_BYTES"},
+		},
+		{
+			name: "error case: negative cross-post concurrency and timeout",
+			modify: func(cfg *Config) {
+				cfg.CrossPostConcurrency = -1
+				cfg.CrossPostTimeout = -time.Second
+			},
+			wantKeys: []string{"CROSS_POST_CONCURRENCY", "CROSS_POST_TIMEOUT"},
+		},
+		{
+			name: "error case: heartbeat time and message",
+			modify: func(cfg *Config) {
+				cfg.HeartbeatEnabled = true
+				cfg.HeartbeatTime = "9pm"
+				cfg.HeartbeatMessage = "{{.Uptime"
+			},
+			wantKeys: []string{"HEARTBEAT_TIME", "HEARTBEAT_MESSAGE"},
+		},
+		{
+			name: "error case: dead letters without state",
+			modify: func(cfg *Config) {
+				cfg.DeadLetterEnabled = true
+				cfg.DeadLetterMax = 0
+			},
+			wantKeys: []string{"STATE_FILE", "DEAD_LETTER_MAX"},
+		},
+		{
+			name: "error case: state encryption without a persistent key",
+			modify: func(cfg *Config) {
+				cfg.StateEncryptionEnabled = true
+			},
+			wantKeys: []string{"STATE_ENCRYPTION_ENABLED"},
+		},
+		{
+			name: "error case: post backoff",
+			modify: func(cfg *Config) {
+				cfg.PostBackoffThreshold = 3
+				cfg.PostBackoffMax = time.Minute
+			},
+			wantKeys: []string{"POST_BACKOFF_MAX"},
+		},
+		{
+			name: "error case: profile updates",
+			modify: func(cfg *Config) {
+				cfg.ProfileUpdateEnabled = true
+				cfg.ProfileDescription = "{{.NextPostAt"
+				cfg.ProfilePinCount = 5
+				cfg.ProfilePinInterval = 0
+			},
+			wantKeys: []string{"PROFILE_PIN_INTERVAL", "PROFILE_DESCRIPTION"},
+		},
+		{
+			name: "error case: translation thread without a language",
+			modify: func(cfg *Config) {
+				cfg.TranslationMode = "thread"
+			},
+			wantKeys: []string{"POST_LANG"},
+		},
+		{
+			name: "error case: invalid language and translation mode",
+			modify: func(cfg *Config) {
+				cfg.PostLang = "english"
+				cfg.TranslationMode = "both"
+			},
+			wantKeys: []string{"POST_LANG", "TRANSLATION_MODE"},
+		},
+		{
+			name: "error case: quote requests",
+			modify: func(cfg *Config) {
+				cfg.QuoteRequestsEnabled = true
+				cfg.StateFile = ""
+				cfg.QuoteRequestHashtag = "#quote"
+				cfg.QuoteRequestCooldown = time.Hour
+				cfg.QuoteRequestPollInterval = time.Second
+				cfg.QuoteRequestBatchSize = 0
+			},
+			wantKeys: []string{"STATE_FILE", "QUOTE_REQUEST_HASHTAG", "QUOTE_REQUEST_POLL_INTERVAL", "QUOTE_REQUEST_BATCH_SIZE"},
+		},
+		{
+			name: "error case: deny DIDs with a handle",
+			modify: func(cfg *Config) {
+				cfg.DenyDIDs = []string{"did:plc:spammer", "spammer.bsky.social"}
+			},
+			wantKeys: []string{"DENY_DIDS"},
+		},
+		{
+			name: "error case: suggestions",
+			modify: func(cfg *Config) {
+				cfg.SuggestionsEnabled = true
+				cfg.StateFile = ""
+				cfg.SuggestionPrefix = " "
+				cfg.SuggestionPollInterval = time.Second
+			},
+			wantKeys: []string{"STATE_FILE", "SUGGESTION_PREFIX", "SUGGESTION_POLL_INTERVAL"},
+		},
+		{
+			name: "error case: publisher plugin that does not exist",
+			modify: func(cfg *Config) {
+				cfg.PublisherPlugin = filepath.Join(t.TempDir(), "quotebot-missing")
+			},
+			wantKeys: []string{"PUBLISHER_PLUGIN"},
+		},
+		{
+			name: "error case: cross-post plugin that does not exist",
+			modify: func(cfg *Config) {
+				cfg.CrossPostPlugins = []string{filepath.Join(t.TempDir(), "quotebot-missing") + " --instance example.social"}
+			},
+			wantKeys: []string{"CROSS_POST_PLUGINS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %v, want *ValidationError", err)
+			}
+			got := map[string]bool{}
+			for _, p := range validationErr.Problems {
+				got[p.Key] = true
+			}
+			if len(validationErr.Problems) != len(tt.wantKeys) {
+				t.Errorf("Validate() problems = %v, want keys %v", validationErr.Problems, tt.wantKeys)
+			}
+			for _, key := range tt.wantKeys {
+				if !got[key] {
+					t.Errorf("Validate() problems = %v, want a problem for %s", validationErr.Problems, key)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_ReportsAllProblems(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DID", "did:plc:test")
+	os.Setenv("PDS_URL", "bsky.social")
+	os.Setenv("MAX_RETRIES", "50")
+	os.Setenv("ADMIN_ENABLED", "true")
+
+	_, err := New()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("New() error = %v, want *ValidationError", err)
+	}
+	// PDS_URL, MAX_RETRIES, ADMIN_TOKEN と認証情報の4件
+	if len(validationErr.Problems) != 4 {
+		t.Errorf("New() problems = %v, want 4", validationErr.Problems)
+	}
+}
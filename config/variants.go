@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// variantsKey は設定ファイルで投稿形式のバリエーションを並べるキーです
+const variantsKey = "VARIANTS"
+
+// FormatVariant はA/Bテストで比べる投稿形式の1つです。設定ファイルの variants でのみ指定できます
+type FormatVariant struct {
+	// Name は投稿履歴とレポートに記録する名前です
+	Name string
+	// Weight は選ばれる割合の重みです（0の場合は選ばれません）
+	Weight int
+	// Template は POST_TEMPLATE と POST_TEMPLATE_BLUESKY の代わりに使うテンプレートです（空の場合は変えません）
+	Template string
+	// Hashtags は HASHTAGS と HASHTAG_MAP のハッシュタグを付けるかです
+	Hashtags bool
+}
+
+// splitVariants は設定ファイルの内容から variants を取り除き、投稿形式のバリエーションとして返します
+func splitVariants(raw map[string]interface{}) ([]FormatVariant, error) {
+	var items []interface{}
+	for k, v := range raw {
+		if strings.ToUpper(k) != variantsKey {
+			continue
+		}
+		delete(raw, k)
+		if v == nil {
+			continue
+		}
+		switch list := v.(type) {
+		case []interface{}:
+			items = list
+		case []map[string]interface{}:
+			// TOMLの [[variants]]
+			for _, item := range list {
+				items = append(items, item)
+			}
+		default:
+			return nil, fmt.Errorf("variants には投稿形式のリストを指定してください")
+		}
+	}
+
+	var variants []FormatVariant
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("variants の%d番目の形式が正しくありません", i+1)
+		}
+		// 重みを省略した場合は均等、ハッシュタグは付ける
+		v := FormatVariant{Weight: 1, Hashtags: true}
+		for key, value := range m {
+			s, err := scalarString("VARIANTS", value)
+			if err != nil {
+				return nil, err
+			}
+			switch strings.ToLower(key) {
+			case "name":
+				v.Name = s
+			case "template":
+				v.Template = s
+			case "weight":
+				if v.Weight, err = strconv.Atoi(s); err != nil {
+					return nil, fmt.Errorf("variants の%d番目の weight は整数で指定してください: %s", i+1, s)
+				}
+			case "hashtags":
+				if v.Hashtags, err = strconv.ParseBool(s); err != nil {
+					return nil, fmt.Errorf("variants の%d番目の hashtags は true または false で指定してください: %s", i+1, s)
+				}
+			default:
+				return nil, fmt.Errorf("variants の%d番目に不明な項目があります: %s", i+1, key)
+			}
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+// variantProblems は投稿形式のバリエーションを確認します
+func (c *Config) variantProblems() []Problem {
+	if len(c.FormatVariants) == 0 {
+		return nil
+	}
+
+	var problems []Problem
+	add := func(message string) {
+		problems = append(problems, Problem{Key: "variants", Message: message})
+	}
+	seen := map[string]bool{}
+	total := 0
+	for i, v := range c.FormatVariants {
+		switch {
+		case v.Name == "":
+			add(fmt.Sprintf("%d番目の形式に name がありません", i+1))
+		case seen[v.Name]:
+			add(fmt.Sprintf("name が重複しています: %s", v.Name))
+		}
+		seen[v.Name] = true
+		if v.Weight < 0 {
+			add(fmt.Sprintf("%s の weight は0以上で指定してください: %d", v.Name, v.Weight))
+		}
+		total += max(v.Weight, 0)
+		if _, err := template.New(v.Name).Parse(v.Template); err != nil {
+			add(fmt.Sprintf("%s のテンプレートの解析に失敗しました: %v", v.Name, err))
+		}
+	}
+	if total == 0 {
+		add("weight が1以上の形式が1つもありません")
+	}
+	return problems
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNew_FormatVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		want     []FormatVariant
+		wantErr  bool
+	}{
+		{
+			name:     "success case: yaml list",
+			fileName: "config.yaml",
+			content: `did: did:plc:file
+access_jwt: a
+refresh_jwt: r
+variants:
+  - name: plain
+    hashtags: false
+  - name: quoted
+    weight: 3
+    template: "「{{.Text}}」"
+`,
+			want: []FormatVariant{
+				{Name: "plain", Weight: 1, Hashtags: false},
+				{Name: "quoted", Weight: 3, Template: "「{{.Text}}」", Hashtags: true},
+			},
+		},
+		{
+			name:     "success case: toml array of tables",
+			fileName: "config.toml",
+			content: `did = "did:plc:file"
+access_jwt = "a"
+refresh_jwt = "r"
+
+[[variants]]
+name = "plain"
+hashtags = false
+
+[[variants]]
+name = "tagged"
+`,
+			want: []FormatVariant{
+				{Name: "plain", Weight: 1, Hashtags: false},
+				{Name: "tagged", Weight: 1, Hashtags: true},
+			},
+		},
+		{
+			name:     "error case: unknown field",
+			fileName: "config.yaml",
+			content: `did: did:plc:file
+access_jwt: a
+refresh_jwt: r
+variants:
+  - name: plain
+    colour: red
+`,
+			wantErr: true,
+		},
+		{
+			name:     "error case: duplicate names and no weight",
+			fileName: "config.yaml",
+			content: `did: did:plc:file
+access_jwt: a
+refresh_jwt: r
+variants:
+  - name: a
+    weight: 0
+  - name: a
+    weight: 0
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			got, err := New(WithFile(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.FormatVariants, tt.want) {
+				t.Errorf("FormatVariants = %+v, want %+v", got.FormatVariants, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxVaultResponseBytes はVaultのレスポンスを読み込む上限です
+const maxVaultResponseBytes = 1 << 20
+
+// VaultStore はHashiCorp VaultのKVシークレットエンジン（v2）に認証情報を保存します
+type VaultStore struct {
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	path      string
+	client    *http.Client
+}
+
+// NewVaultStore は新しいVaultStoreインスタンスを作成します
+func NewVaultStore(addr, token, namespace, mount, path string, timeout time.Duration) *VaultStore {
+	return &VaultStore{
+		addr:      strings.TrimRight(addr, "/"),
+		token:     token,
+		namespace: namespace,
+		mount:     strings.Trim(mount, "/"),
+		path:      strings.Trim(path, "/"),
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Get はVaultのシークレットから指定されたキーの値を読み込みます
+func (s *VaultStore) Get(key string) (string, error) {
+	data, err := s.read()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := data[key]
+	if !ok || value == nil {
+		return "", ErrCredentialNotFound
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("Vaultの値が文字列ではありません（%s）", key)
+	}
+	return str, nil
+}
+
+// Set はVaultのシークレットの指定されたキーを更新します。他のキーは保持されます
+func (s *VaultStore) Set(key, value string) error {
+	return s.patch(map[string]interface{}{key: value})
+}
+
+// Delete はVaultのシークレットから指定されたキーを削除します
+func (s *VaultStore) Delete(key string) error {
+	return s.patch(map[string]interface{}{key: nil})
+}
+
+// read はシークレットの最新バージョンを取得します
+func (s *VaultStore) read() (map[string]interface{}, error) {
+	resp, err := s.do(http.MethodGet, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]interface{}{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, vaultError(resp)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxVaultResponseBytes)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("Vaultのレスポンスのデコードに失敗しました: %w", err)
+	}
+	return body.Data.Data, nil
+}
+
+// patch はJSON Merge Patchでシークレットを部分更新します
+func (s *VaultStore) patch(data map[string]interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return fmt.Errorf("Vaultへのリクエストのエンコードに失敗しました: %w", err)
+	}
+
+	resp, err := s.do(http.MethodPatch, payload, "application/merge-patch+json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// シークレットがまだ存在しない場合、PATCHは404になるため新規作成する
+	if resp.StatusCode == http.StatusNotFound {
+		resp, err = s.do(http.MethodPost, payload, "application/json")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return vaultError(resp)
+	}
+	return nil
+}
+
+// do はVaultのKV v2データエンドポイントにリクエストを送信します
+func (s *VaultStore) do(method string, body []byte, contentType string) (*http.Response, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", s.addr, s.mount, s.path)
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if s.client.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), s.client.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("Vaultへのリクエストの作成に失敗しました: %w", err)
+	}
+
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("Vaultへのリクエストに失敗しました: %w", err)
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// vaultError はVaultのエラーレスポンスをエラーに変換します
+func vaultError(resp *http.Response) error {
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body)
+	if len(body.Errors) > 0 {
+		return fmt.Errorf("Vaultがエラーを返しました（status %d）: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+	return fmt.Errorf("Vaultがエラーを返しました（status %d）", resp.StatusCode)
+}
+
+// cancelOnClose はレスポンスボディを閉じる際にリクエストのコンテキストをキャンセルします
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
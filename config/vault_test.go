@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeVault はKV v2エンジンの最低限の動作を模倣するテストサーバーを作成します
+func newFakeVault(t *testing.T, token string, initial map[string]interface{}) *httptest.Server {
+	var mu sync.Mutex
+	data := initial
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/quotebot" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			if data == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": data},
+			})
+		case http.MethodPatch, http.MethodPost:
+			if r.Method == http.MethodPatch && data == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if data == nil {
+				data = map[string]interface{}{}
+			}
+			for k, v := range body.Data {
+				if v == nil {
+					delete(data, k)
+				} else {
+					data[k] = v
+				}
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+}
+
+func TestVaultStore(t *testing.T) {
+	server := newFakeVault(t, "vault-token", nil)
+	defer server.Close()
+
+	store := NewVaultStore(server.URL, "vault-token", "", "secret", "quotebot", time.Second)
+
+	// シークレットが存在しない場合
+	if _, err := store.Get(CredentialAccessJWT); err != ErrCredentialNotFound {
+		t.Fatalf("Get() error = %v, want %v", err, ErrCredentialNotFound)
+	}
+
+	// 書き込み（新規作成）と部分更新
+	if err := store.Set(CredentialAccessJWT, "vault-access-token"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set(CredentialRefreshJWT, "vault-refresh-token"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	got, err := store.Get(CredentialAccessJWT)
+	if err != nil || got != "vault-access-token" {
+		t.Errorf("Get() = %v, %v, want vault-access-token", got, err)
+	}
+
+	// 削除
+	if err := store.Delete(CredentialAccessJWT); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(CredentialAccessJWT); err != ErrCredentialNotFound {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrCredentialNotFound)
+	}
+	if got, _ := store.Get(CredentialRefreshJWT); got != "vault-refresh-token" {
+		t.Errorf("Delete() removed other keys: refresh_jwt = %v", got)
+	}
+
+	// 無効なトークン
+	invalid := NewVaultStore(server.URL, "wrong-token", "", "secret", "quotebot", time.Second)
+	if _, err := invalid.Get(CredentialAccessJWT); err == nil {
+		t.Errorf("Get() with invalid token error = nil, want error")
+	}
+}
+
+func TestNew_VaultBackend(t *testing.T) {
+	server := newFakeVault(t, "vault-token", map[string]interface{}{
+		CredentialAccessJWT:  "vault-access-token",
+		CredentialRefreshJWT: "vault-refresh-token",
+	})
+	defer server.Close()
+
+	// VAULT_TOKEN は _FILE 経由で渡す
+	tokenFile := filepath.Join(t.TempDir(), "vault-token")
+	if err := os.WriteFile(tokenFile, []byte("vault-token\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	os.Clearenv()
+	os.Setenv("DID", "did:plc:test")
+	os.Setenv("CREDENTIALS_BACKEND", "vault")
+	os.Setenv("VAULT_ADDR", server.URL)
+	os.Setenv("VAULT_TOKEN_FILE", tokenFile)
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if cfg.AccessJWT != "vault-access-token" || cfg.RefreshJWT != "vault-refresh-token" {
+		t.Errorf("tokens = %v/%v, want vault-access-token/vault-refresh-token", cfg.AccessJWT, cfg.RefreshJWT)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// runConfig は `quotebot config` のサブコマンドを実行します
+func runConfig(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) != 1 || args[0] != "show" {
+		return fmt.Errorf("使い方: quotebot config show")
+	}
+	return showConfig(cfg, out)
+}
+
+// showConfig は設定ファイルと環境変数を反映した実際の設定値と、その取得元を表示します。
+// 秘密情報はマスクして表示します
+func showConfig(cfg *config.Config, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, s := range cfg.Settings() {
+		value := s.Value
+		if value == "" {
+			value = "-"
+		}
+		source := string(s.Source)
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, value, source)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/interface/admin"
+)
+
+// runDLQ は実行中のボットの管理APIで、投稿先に送って失敗した投稿を表示・送り直し・破棄します
+func runDLQ(cfg *config.Config, args []string, out io.Writer) error {
+	usage := fmt.Errorf("使い方: quotebot dlq [list | retry [--all] [ID...] | discard ID...]")
+	command := "list"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet("dlq "+command, flag.ContinueOnError)
+	flags.SetOutput(out)
+	all := flags.Bool("all", false, "残っているすべての投稿を古い順に送り直す（retry のみ）")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if cfg.AdminToken == "" {
+		return fmt.Errorf("失敗した投稿の確認には ADMIN_TOKEN が必要です")
+	}
+
+	client := admin.NewClient(cfg)
+	switch command {
+	case "list":
+		if flags.NArg() != 0 || *all {
+			return usage
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+		defer cancel()
+		items, err := client.DeadLetters(ctx)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Fprintln(out, "失敗した投稿はありません")
+			return nil
+		}
+		now := time.Now().In(cfg.DisplayLocation())
+		for _, item := range items {
+			fmt.Fprintf(out, "%s 失敗: %s 名言: %s\n", item.ID, formatTime(item.FailedAt, now), item.Quote.StableID())
+			fmt.Fprintf(out, "  エラー: %s\n", item.Error)
+			for _, line := range strings.Split(item.Text, "\n") {
+				fmt.Fprintf(out, "  %s\n", line)
+			}
+		}
+		return nil
+
+	case "retry":
+		ids := flags.Args()
+		switch {
+		case *all && len(ids) > 0, !*all && len(ids) == 0:
+			return usage
+		case *all:
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+			defer cancel()
+			items, err := client.DeadLetters(ctx)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				ids = append(ids, item.ID)
+			}
+		}
+		// 1件ずつ送り直し、失敗しても残りを続ける。失敗した投稿は新しいIDで残る
+		var errs []error
+		for _, id := range ids {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.PostTimeout+cfg.HTTPTimeout)
+			result, err := client.RetryDeadLetter(ctx, id)
+			cancel()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", id, err))
+				continue
+			}
+			fmt.Fprintf(out, "投稿しました: %s %s\n", id, result.URI)
+		}
+		return errors.Join(errs...)
+
+	case "discard":
+		if flags.NArg() == 0 || *all {
+			return usage
+		}
+		for _, id := range flags.Args() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+			err := client.DiscardDeadLetter(ctx, id)
+			cancel()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "破棄しました: %s\n", id)
+		}
+		return nil
+
+	default:
+		return usage
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/state"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// runHistory は `quotebot history` のサブコマンドを実行します
+func runHistory(cfg *config.Config, args []string, out io.Writer) error {
+	const usage = "使い方: quotebot history sync [--dry-run] [--since 2024-01-01]"
+	if len(args) == 0 || args[0] != "sync" {
+		return fmt.Errorf(usage)
+	}
+	flags := flag.NewFlagSet("history sync", flag.ContinueOnError)
+	flags.SetOutput(out)
+	dryRun := flags.Bool("dry-run", false, "投稿履歴に書き込まずに、追加するエントリを表示する")
+	sinceFlag := flags.String("since", "", "これより前の投稿は読まない（168h のような期間、または 2024-01-01 のような日付）")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	since, err := parseSince(*sinceFlag, time.Now().In(cfg.DisplayLocation()))
+	if err != nil {
+		return err
+	}
+	if !cfg.HasHistory() {
+		return fmt.Errorf("投稿履歴の同期には HISTORY_FILE が必要です")
+	}
+	return syncHistory(cfg, since, *dryRun, out)
+}
+
+// syncHistory はアカウントの投稿をさかのぼって名言と照合し、投稿履歴にない投稿を投稿履歴に追加します。
+// 投稿履歴を記録する前から使っているアカウントでも、report で過去の投稿を集計できるようになります
+func syncHistory(cfg *config.Config, since time.Time, dryRun bool, out io.Writer) error {
+	quotes := usecase.NewQuoteUseCase(repository.NewQuoteRepository(cfg))
+	if err := quotes.Reload(); err != nil {
+		return err
+	}
+	stateStore, err := state.Open(cfg)
+	if err != nil {
+		return err
+	}
+	if closer, ok := stateStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+	existing, err := history.Read(cfg, stateStore)
+	if err != nil {
+		return err
+	}
+	blueskyRepo, err := repository.NewBlueskyRepository(cfg)
+	if err != nil {
+		return fmt.Errorf("Blueskyリポジトリの初期化に失敗しました: %w", err)
+	}
+	defer blueskyRepo.Shutdown()
+
+	var posts []domain.PublishedPost
+	cursor := ""
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*cfg.HTTPTimeout)
+		page, next, err := blueskyRepo.AuthorPosts(ctx, cursor)
+		cancel()
+		if err != nil {
+			return err
+		}
+		done := next == ""
+		for _, post := range page {
+			if !since.IsZero() && post.CreatedAt.Before(since) {
+				done = true
+				continue
+			}
+			posts = append(posts, post)
+		}
+		fmt.Fprintf(out, "投稿を読み込んでいます: %d件\n", len(posts))
+		if done {
+			break
+		}
+		cursor = next
+	}
+
+	entries, unmatched := history.Reconstruct(posts, quotes.Quotes(), existing)
+	now := time.Now().In(cfg.DisplayLocation())
+	for _, entry := range entries {
+		fmt.Fprintf(out, "%s %s %s\n", formatTime(entry.Timestamp, now), entry.Quote.ID, excerpt(entry.Quote.Text, 30))
+	}
+	recorded := len(posts) - len(entries) - len(unmatched)
+	if dryRun {
+		fmt.Fprintf(out, "%d件を追加します（記録済み: %d件、名言と一致しない投稿: %d件）\n", len(entries), recorded, len(unmatched))
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(out, "追加する投稿はありません（記録済み: %d件、名言と一致しない投稿: %d件）\n", recorded, len(unmatched))
+		return nil
+	}
+
+	recorder, err := history.NewRecorder(cfg, stateStore)
+	if err != nil {
+		return err
+	}
+	if closer, ok := recorder.(io.Closer); ok {
+		defer closer.Close()
+	}
+	for _, entry := range entries {
+		if err := recorder.Record(entry); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "%d件を投稿履歴に追加しました（記録済み: %d件、名言と一致しない投稿: %d件）\n", len(entries), recorded, len(unmatched))
+	return nil
+}
+
+// readHistory は設定した保存先（HISTORY_FILE または STATE_BACKEND のデータベース）から投稿履歴を読み込みます
+func readHistory(cfg *config.Config) ([]history.Entry, error) {
+	stateStore, err := state.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := stateStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return history.Read(cfg, stateStore)
+}
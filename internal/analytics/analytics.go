@@ -0,0 +1,146 @@
+// Package analytics は投稿履歴と投稿への反応を集計します
+package analytics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/history"
+)
+
+// EngagementSource は投稿のURIから反応の数を取得します
+type EngagementSource interface {
+	Engagement(ctx context.Context, uris []string) (map[string]domain.Engagement, error)
+}
+
+// VariantSummary は投稿形式ごとの反応の集計です
+type VariantSummary struct {
+	Variant string
+	Posts   int // 集計した投稿の数（削除された投稿は含みません）
+	domain.Engagement
+}
+
+// Average は1投稿あたりの反応の平均です
+func (s VariantSummary) Average() float64 {
+	if s.Posts == 0 {
+		return 0
+	}
+	return float64(s.Total()) / float64(s.Posts)
+}
+
+// QuoteSummary は名言ごとの反応の集計です
+type QuoteSummary struct {
+	QuoteID string
+	Text    string // 最後に投稿したときの本文
+	Author  string
+	Posts   int // 集計した投稿の数（削除された投稿は含みません）
+	domain.Engagement
+}
+
+// Average は1投稿あたりの反応の平均です
+func (s QuoteSummary) Average() float64 {
+	if s.Posts == 0 {
+		return 0
+	}
+	return float64(s.Total()) / float64(s.Posts)
+}
+
+// SummarizeVariants は since 以降に成功した投稿のうち、投稿形式を記録したものを形式ごとに集計します。
+// since がゼロ値の場合はすべての投稿を集計します。結果は形式の名前順です
+func SummarizeVariants(ctx context.Context, entries []history.Entry, since time.Time, source EngagementSource) ([]VariantSummary, error) {
+	groups, err := summarize(ctx, entries, since, source, func(entry history.Entry) string { return entry.Variant })
+	if err != nil {
+		return nil, err
+	}
+	result := make([]VariantSummary, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, VariantSummary{Variant: g.key, Posts: g.posts, Engagement: g.engagement})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Variant < result[j].Variant })
+	return result, nil
+}
+
+// SummarizeQuotes は since 以降に成功した投稿のうち、名言のIDを記録したものを名言ごとに集計します。
+// 結果は1投稿あたりの反応が多い順です
+func SummarizeQuotes(ctx context.Context, entries []history.Entry, since time.Time, source EngagementSource) ([]QuoteSummary, error) {
+	groups, err := summarize(ctx, entries, since, source, func(entry history.Entry) string { return entry.Quote.ID })
+	if err != nil {
+		return nil, err
+	}
+	result := make([]QuoteSummary, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, QuoteSummary{QuoteID: g.key, Text: g.last.Quote.Text, Author: g.last.Quote.Author, Posts: g.posts, Engagement: g.engagement})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if a, b := result[i].Average(), result[j].Average(); a != b {
+			return a > b
+		}
+		return result[i].QuoteID < result[j].QuoteID
+	})
+	return result, nil
+}
+
+// group は key ごとに集計した投稿です
+type group struct {
+	key        string
+	posts      int
+	engagement domain.Engagement
+	last       history.Entry // 最後に集計した投稿
+}
+
+// summarize は since 以降に成功した投稿を key ごとに集計します。key が空の投稿は含みません。
+// 反応は Bluesky から取得するため、Bluesky に投稿していない投稿も含みません
+func summarize(ctx context.Context, entries []history.Entry, since time.Time, source EngagementSource, key func(history.Entry) string) ([]*group, error) {
+	posts := map[string]history.Entry{} // URI -> 投稿
+	var uris []string
+	for _, entry := range entries {
+		if entry.Result != history.ResultSuccess || key(entry) == "" {
+			continue
+		}
+		if !since.IsZero() && entry.Timestamp.Before(since) {
+			continue
+		}
+		post, ok := entry.PostOn(domain.PlatformBluesky)
+		if !ok || post.URI == "" {
+			continue
+		}
+		if _, ok := posts[post.URI]; !ok {
+			uris = append(uris, post.URI)
+		}
+		posts[post.URI] = entry
+	}
+	if len(uris) == 0 {
+		return nil, nil
+	}
+
+	engagement, err := source.Engagement(ctx, uris)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*group{}
+	var result []*group
+	for _, uri := range uris {
+		e, ok := engagement[uri]
+		if !ok {
+			continue
+		}
+		entry := posts[uri]
+		name := key(entry)
+		g, ok := groups[name]
+		if !ok {
+			g = &group{key: name}
+			groups[name] = g
+			result = append(result, g)
+		}
+		g.posts++
+		g.engagement.Likes += e.Likes
+		g.engagement.Reposts += e.Reposts
+		g.engagement.Replies += e.Replies
+		g.engagement.Quotes += e.Quotes
+		g.last = entry
+	}
+	return result, nil
+}
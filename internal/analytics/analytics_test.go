@@ -0,0 +1,142 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/history"
+)
+
+// fakeEngagement は決まった反応の数を返します
+type fakeEngagement struct {
+	engagement map[string]domain.Engagement
+	err        error
+	uris       []string
+}
+
+func (f *fakeEngagement) Engagement(ctx context.Context, uris []string) (map[string]domain.Engagement, error) {
+	f.uris = uris
+	return f.engagement, f.err
+}
+
+func TestSummarizeVariants(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []history.Entry{
+		{Timestamp: base, Result: history.ResultSuccess, URI: "at://1", Variant: "plain"},
+		{Timestamp: base.Add(time.Hour), Result: history.ResultSuccess, URI: "at://2", Variant: "emoji"},
+		{Timestamp: base.Add(2 * time.Hour), Result: history.ResultSuccess, URI: "at://3", Variant: "plain"},
+		{Timestamp: base.Add(3 * time.Hour), Result: history.ResultFailure, Variant: "plain"},
+		{Timestamp: base.Add(4 * time.Hour), Result: history.ResultSuccess, URI: "at://4"},
+		{Timestamp: base.Add(5 * time.Hour), Result: history.ResultSuccess, URI: "at://deleted", Variant: "emoji"},
+		{Timestamp: base.Add(6 * time.Hour), Result: history.ResultSuccess, Platform: domain.PlatformMastodon, URI: "https://example.social/@quotes/1", Variant: "plain"},
+	}
+	engagement := map[string]domain.Engagement{
+		"at://1": {Likes: 3, Reposts: 1},
+		"at://2": {Likes: 10, Replies: 2, Quotes: 1},
+		"at://3": {Likes: 1},
+		"at://4": {Likes: 100},
+	}
+
+	tests := []struct {
+		name     string
+		since    time.Time
+		err      error
+		want     []VariantSummary
+		wantURIs int
+		wantErr  bool
+	}{
+		{
+			name: "正常系: 形式ごとに集計し、失敗・形式なし・削除された投稿と Bluesky 以外の投稿は含まない",
+			want: []VariantSummary{
+				{Variant: "emoji", Posts: 1, Engagement: domain.Engagement{Likes: 10, Replies: 2, Quotes: 1}},
+				{Variant: "plain", Posts: 2, Engagement: domain.Engagement{Likes: 4, Reposts: 1}},
+			},
+			wantURIs: 4,
+		},
+		{
+			name:  "正常系: since より前の投稿は含まない",
+			since: base.Add(90 * time.Minute),
+			want: []VariantSummary{
+				{Variant: "plain", Posts: 1, Engagement: domain.Engagement{Likes: 1}},
+			},
+			wantURIs: 2,
+		},
+		{
+			name:    "異常系: 反応の取得に失敗",
+			err:     errors.New("unavailable"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeEngagement{engagement: engagement, err: tt.err}
+			got, err := SummarizeVariants(context.Background(), entries, tt.since, source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SummarizeVariants() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(source.uris) != tt.wantURIs {
+				t.Errorf("Engagement() called with %v, want %d URIs", source.uris, tt.wantURIs)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("SummarizeVariants() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("SummarizeVariants()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVariantSummary_Average(t *testing.T) {
+	s := VariantSummary{Posts: 2, Engagement: domain.Engagement{Likes: 3, Reposts: 2}}
+	if got := s.Average(); got != 2.5 {
+		t.Errorf("Average() = %v, want 2.5", got)
+	}
+	if got := (VariantSummary{}).Average(); got != 0 {
+		t.Errorf("Average() = %v, want 0", got)
+	}
+}
+
+func TestSummarizeQuotes(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []history.Entry{
+		{Timestamp: base, Result: history.ResultSuccess, URI: "at://1", Quote: history.Quote{ID: "q1", Text: "古い本文", Author: "著者1"}},
+		{Timestamp: base.Add(time.Hour), Result: history.ResultSuccess, URI: "at://2", Quote: history.Quote{ID: "q2", Text: "名言2", Author: "著者2"}},
+		{Timestamp: base.Add(2 * time.Hour), Result: history.ResultSuccess, URI: "at://3", Quote: history.Quote{ID: "q1", Text: "名言1", Author: "著者1"}},
+		{Timestamp: base.Add(3 * time.Hour), Result: history.ResultSuccess, URI: "at://4", Quote: history.Quote{Text: "IDなし"}},
+		{Timestamp: base.Add(4 * time.Hour), Result: history.ResultBlocked, Quote: history.Quote{ID: "q3"}},
+	}
+	source := &fakeEngagement{engagement: map[string]domain.Engagement{
+		"at://1": {Likes: 2},
+		"at://2": {Likes: 1, Reposts: 1},
+		"at://3": {Likes: 4},
+		"at://4": {Likes: 100},
+	}}
+
+	got, err := SummarizeQuotes(context.Background(), entries, time.Time{}, source)
+	if err != nil {
+		t.Fatalf("SummarizeQuotes() error = %v", err)
+	}
+	// 1投稿あたりの反応が多い順で、本文は最後に投稿したときのもの
+	want := []QuoteSummary{
+		{QuoteID: "q1", Text: "名言1", Author: "著者1", Posts: 2, Engagement: domain.Engagement{Likes: 6}},
+		{QuoteID: "q2", Text: "名言2", Author: "著者2", Posts: 1, Engagement: domain.Engagement{Likes: 1, Reposts: 1}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("SummarizeQuotes() = %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("SummarizeQuotes()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/approval"
+	"github.com/littleironwaltz/quotebot/internal/deadletter"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/metrics"
+	"github.com/littleironwaltz/quotebot/internal/notify"
+	"github.com/littleironwaltz/quotebot/internal/outbox"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// Server は App の実行中だけ動かすサーバー（管理APIなど）です
+type Server interface {
+	Start() error
+	Shutdown(ctx context.Context) error
+}
+
+// Leader は複数のレプリカで動かすときのリーダー選出です。リーダーに選ばれている間だけ Bot が定期投稿します
+type Leader interface {
+	Server
+	// IsLeader はこのレプリカがリーダーかを返します
+	IsLeader() bool
+}
+
+// LeaderConfirmer は投稿の直前にリーダーであることを確かめ直せるリーダー選出です。
+// Leader が実装していれば定期投稿の直前に呼び、確かめられなければ投稿しません。
+// リーダーが入れ替わる間に、前のリーダーと新しいリーダーが同じ予定を投稿しないようにします
+type LeaderConfirmer interface {
+	ConfirmLeader(ctx context.Context) bool
+}
+
+// RefreshObservable はトークンのリフレッシュの結果を通知できる投稿先です。
+// Poster が実装していれば、リフレッシュの結果もイベントとして発行し、失敗を通知の対象にします
+type RefreshObservable interface {
+	OnTokenRefresh(observer func(error))
+}
+
+// SessionObservable はセッションの状態（ログインし直しが必要かなど）の変化を通知できる投稿先です。
+// Poster が実装していれば、状態の変化をイベントとして発行し、ログインし直しが必要になったらすぐに通知します
+type SessionObservable interface {
+	OnSessionStateChange(observer func(from, to string, err error))
+}
+
+// Dependencies は App が使う依存関係です。main では実際のリポジトリを、テストではモックを渡します
+type Dependencies struct {
+	Quotes QuoteSource
+	Poster Poster
+	// CrossPosters は任意です。投稿先に投稿できた後に、同じ本文をほかの投稿先にも投稿します。
+	// Shutdown() を実装していればシャットダウン時に呼び出します
+	CrossPosters []CrossPoster
+	// History は任意です。io.Closer を実装していればシャットダウン時に閉じます
+	History history.Recorder
+	// Notifier は任意です。設定すると失敗が続いたときに通知します
+	Notifier notify.Notifier
+	// NewAdminServer は任意です。Bot を操作する管理APIを作成します
+	NewAdminServer func(app *App) Server
+	// NewGRPCServer は任意です。他のサービスから Bot を操作する gRPC API を作成します
+	NewGRPCServer func(app *App) Server
+	// NewServices は任意です。Bot のイベントなどを使って App の実行中だけ動くサービスを作成します。
+	// 作成に失敗した場合は error を返すと New が失敗します
+	NewServices []func(app *App) (Server, error)
+	// LoadConfig は任意です。設定すると ReloadConfig で設定を読み込み直せます
+	LoadConfig func() (*config.Config, error)
+	// QuotesFile は任意です。設定すると再読み込みで名言ファイルのパスを変更できます
+	QuotesFile QuotesFileSetter
+	// Hooks は任意です。投稿のパイプラインの段階の前後に呼ぶフックを登録します
+	Hooks *usecase.Hooks
+	// Approvals は任意です。設定すると投稿を承認待ちに入れ、承認されてから投稿します
+	Approvals *approval.Queue
+	// DeadLetters は任意です。設定すると投稿先に送って失敗した投稿を残し、後で送り直せるようにします
+	DeadLetters *deadletter.Queue
+	// Outbox は任意です。設定すると送信する前の投稿を保存し、停止した後に二重に投稿しないようにします
+	Outbox *outbox.Outbox
+	// Leader は任意です。設定するとリーダーに選ばれたレプリカだけが定期投稿します
+	Leader Leader
+	// Metrics は任意です。設定するとイベントの数と投稿の段階ごとの時間を送ります。
+	// io.Closer を実装していればシャットダウン時に閉じます
+	Metrics metrics.Sink
+}
+
+// App は投稿ボットとその周辺のサービスをまとめて起動・停止します
+type App struct {
+	deps     Dependencies
+	bot      *Bot
+	monitor  *notify.Monitor
+	admin    Server
+	grpc     Server
+	services []Server
+	logger   *slog.Logger
+	done     chan struct{} // Run で作成され、Bot.Run が終了すると閉じられます
+
+	reloadMu sync.Mutex     // ReloadConfig を直列化します
+	cfg      *config.Config // 現在反映されている設定。ReloadConfig で置き換えられます
+}
+
+// New は依存関係を組み立てて App を作成します
+func New(cfg *config.Config, deps Dependencies) (*App, error) {
+	if deps.Quotes == nil || deps.Poster == nil {
+		return nil, fmt.Errorf("名言の取得元と投稿先は必須です")
+	}
+
+	a := &App{deps: deps, cfg: cfg, logger: logging.ModuleFor(cfg, "main")}
+
+	opts, err := ContentOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := NewCalendar(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if calendar != nil {
+		opts = append(opts, WithCalendar(calendar))
+	}
+	if deps.History != nil {
+		opts = append(opts, WithHistory(deps.History))
+	}
+	if deps.Hooks != nil {
+		opts = append(opts, WithHooks(deps.Hooks))
+	}
+	if deps.Approvals != nil {
+		opts = append(opts, WithApprovals(deps.Approvals))
+	}
+	if deps.DeadLetters != nil {
+		opts = append(opts, WithDeadLetters(deps.DeadLetters))
+	}
+	if deps.Outbox != nil {
+		opts = append(opts, WithOutbox(deps.Outbox))
+	}
+	if deps.Leader != nil {
+		opts = append(opts, WithLeader(deps.Leader))
+	}
+	if len(deps.CrossPosters) > 0 {
+		opts = append(opts, WithCrossPosters(deps.CrossPosters...))
+	}
+	// 投稿の結果やトークンのリフレッシュは Bus に発行し、通知や管理APIのストリームはそれを購読する
+	bus := events.NewBus()
+	opts = append(opts, WithEvents(bus))
+	if deps.Metrics != nil {
+		opts = append(opts, WithMetrics(deps.Metrics))
+		bus.Handle(func(event events.Event) {
+			tags := []string{"type:" + string(event.Type)}
+			if event.Trigger != "" {
+				tags = append(tags, "trigger:"+event.Trigger)
+			}
+			if event.ErrorClass != "" {
+				tags = append(tags, "error_class:"+event.ErrorClass)
+			}
+			deps.Metrics.Count("events", 1, tags...)
+		})
+	}
+	if observable, ok := deps.Poster.(RefreshObservable); ok {
+		observable.OnTokenRefresh(func(err error) {
+			if err != nil {
+				bus.Publish(events.Event{
+					Type:       events.TokenRefreshFailed,
+					Error:      redact.String(err.Error()),
+					ErrorClass: string(domain.ClassifyError(err)),
+				})
+				return
+			}
+			bus.Publish(events.Event{Type: events.TokenRefreshed})
+		})
+	}
+	if observable, ok := deps.Poster.(SessionObservable); ok {
+		observable.OnSessionStateChange(func(from, to string, err error) {
+			event := events.Event{Type: events.SessionStateChanged, SessionState: to}
+			if err != nil {
+				event.Error = redact.String(err.Error())
+				event.ErrorClass = string(domain.ClassifyError(err))
+			}
+			bus.Publish(event)
+		})
+	}
+	if deps.Notifier != nil {
+		a.monitor = notify.NewMonitor(cfg, deps.Notifier)
+		opts = append(opts, WithMonitor(a.monitor))
+	}
+
+	a.bot = NewBot(cfg, deps.Quotes, deps.Poster, opts...)
+	if deps.NewAdminServer != nil {
+		a.admin = deps.NewAdminServer(a)
+	}
+	if deps.NewGRPCServer != nil {
+		a.grpc = deps.NewGRPCServer(a)
+	}
+	for _, newService := range deps.NewServices {
+		service, err := newService(a)
+		if err != nil {
+			return nil, err
+		}
+		a.services = append(a.services, service)
+	}
+	return a, nil
+}
+
+// Bot は App が動かしている Bot を返します
+func (a *App) Bot() *Bot {
+	return a.bot
+}
+
+// Run はリーダー選出、管理API、gRPC API、その他のサービスと定期投稿を開始し、ctx がキャンセルされるまで待ちます。
+// 実行中の投稿の完了を待って後片付けをするには、戻った後に Shutdown を呼び出してください
+func (a *App) Run(ctx context.Context) error {
+	if a.deps.Leader != nil {
+		if err := a.deps.Leader.Start(); err != nil {
+			return fmt.Errorf("リーダー選出の開始に失敗しました: %w", err)
+		}
+	}
+	if a.admin != nil {
+		if err := a.admin.Start(); err != nil {
+			return fmt.Errorf("管理APIの起動に失敗しました: %w", err)
+		}
+	}
+	if a.grpc != nil {
+		if err := a.grpc.Start(); err != nil {
+			return fmt.Errorf("gRPC APIの起動に失敗しました: %w", err)
+		}
+	}
+	for _, service := range a.services {
+		if err := service.Start(); err != nil {
+			return fmt.Errorf("サービスの起動に失敗しました: %w", err)
+		}
+	}
+
+	a.done = make(chan struct{})
+	safego.Go(a.logger, "scheduler", func() {
+		defer close(a.done)
+		// パニックした場合は、初回投稿をせずに定期投稿を再開する
+		initial := true
+		safego.Loop(a.logger, "scheduler", ctx.Done(), func() {
+			first := initial
+			initial = false
+			a.bot.run(ctx, first)
+		})
+	})
+
+	<-ctx.Done()
+	return nil
+}
+
+// Shutdown は管理API、gRPC APIとその他のサービスを停止し、実行中の投稿の完了を ctx の期限まで待ってから、
+// リース、送信中の通知、投稿履歴、投稿先を順に後片付けします。途中で失敗しても残りの後片付けは続けます
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	if a.admin != nil {
+		if err := a.admin.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("管理APIの停止に失敗しました: %w", err))
+		}
+	}
+	if a.grpc != nil {
+		if err := a.grpc.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("gRPC APIの停止に失敗しました: %w", err))
+		}
+	}
+	for _, service := range a.services {
+		if err := service.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("サービスの停止に失敗しました: %w", err))
+		}
+	}
+	if err := a.bot.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("実行中の投稿を中断しました: %w", err))
+	}
+	if a.done != nil {
+		<-a.done
+	}
+	// 実行中の投稿が終わってからリースを手放し、ほかのレプリカに引き継ぐ
+	if a.deps.Leader != nil {
+		if err := a.deps.Leader.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("リーダー選出の停止に失敗しました: %w", err))
+		}
+	}
+
+	if a.monitor != nil {
+		a.monitor.Wait()
+	}
+	if closer, ok := a.deps.History.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("投稿履歴のクローズに失敗しました: %w", err))
+		}
+	}
+	if closer, ok := a.deps.Metrics.(io.Closer); ok {
+		closer.Close()
+	}
+	// バックグラウンドのトークンリフレッシュなどを停止する
+	if shutdowner, ok := a.deps.Poster.(interface{ Shutdown() }); ok {
+		shutdowner.Shutdown()
+	}
+	for _, poster := range a.deps.CrossPosters {
+		if shutdowner, ok := poster.(interface{ Shutdown() }); ok {
+			shutdowner.Shutdown()
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/notify"
+)
+
+// fakeServer は起動と停止を記録します
+type fakeServer struct {
+	startErr error
+	started  bool
+	stopped  bool
+}
+
+func (s *fakeServer) Start() error {
+	s.started = true
+	return s.startErr
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.stopped = true
+	return nil
+}
+
+// closingRecorder はクローズされたかを記録します
+type closingRecorder struct {
+	mockRecorder
+	closed bool
+}
+
+func (r *closingRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+// lifecyclePoster はシャットダウンとリフレッシュの通知先の登録を記録します
+type lifecyclePoster struct {
+	mockPoster
+	observer func(error)
+	shutdown bool
+}
+
+func (p *lifecyclePoster) OnTokenRefresh(observer func(error)) {
+	p.observer = observer
+}
+
+func (p *lifecyclePoster) Shutdown() {
+	p.shutdown = true
+}
+
+// nopNotifier は通知を破棄します
+type nopNotifier struct{}
+
+func (nopNotifier) Notify(ctx context.Context, alert notify.Alert) error {
+	return nil
+}
+
+func newTestConfig() *config.Config {
+	return &config.Config{PostInterval: time.Hour, PostTimeout: time.Second, AlertThreshold: 3, HTTPTimeout: time.Second}
+}
+
+func TestNew(t *testing.T) {
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+
+	tests := []struct {
+		name    string
+		deps    Dependencies
+		wantErr bool
+	}{
+		{
+			name:    "正常系: 必須の依存関係のみ",
+			deps:    Dependencies{Quotes: quotes, Poster: &mockPoster{}},
+			wantErr: false,
+		},
+		{
+			name:    "異常系: 投稿先がない",
+			deps:    Dependencies{Quotes: quotes},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 名言の取得元がない",
+			deps:    Dependencies{Poster: &mockPoster{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(newTestConfig(), tt.deps)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApp_RunAndShutdown(t *testing.T) {
+	poster := &lifecyclePoster{}
+	recorder := &closingRecorder{}
+	server := &fakeServer{}
+	service := &fakeServer{}
+	var adminBot *Bot
+
+	app, err := New(newTestConfig(), Dependencies{
+		Quotes:   &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}},
+		Poster:   poster,
+		History:  recorder,
+		Notifier: nopNotifier{},
+		NewAdminServer: func(a *App) Server {
+			adminBot = a.Bot()
+			return server
+		},
+		NewServices: []func(a *App) (Server, error){
+			func(a *App) (Server, error) { return service, nil },
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if adminBot != app.Bot() {
+		t.Errorf("NewAdminServer() received %p, want %p", adminBot, app.Bot())
+	}
+	// トークンのリフレッシュの結果を監視する
+	if poster.observer == nil {
+		t.Errorf("OnTokenRefresh() was not called")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- app.Run(ctx)
+	}()
+
+	// 初回投稿を待ってから停止する
+	deadline := time.Now().Add(time.Second)
+	for poster.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if poster.count() != 1 || len(recorder.entries) != 1 {
+		t.Errorf("posts = %d, history entries = %d, want 1 each", poster.count(), len(recorder.entries))
+	}
+	if !server.started || !server.stopped {
+		t.Errorf("admin server started = %v, stopped = %v", server.started, server.stopped)
+	}
+	if !service.started || !service.stopped {
+		t.Errorf("service started = %v, stopped = %v", service.started, service.stopped)
+	}
+	if !recorder.closed || !poster.shutdown {
+		t.Errorf("history closed = %v, poster shut down = %v", recorder.closed, poster.shutdown)
+	}
+}
+
+// panickingQuoteSource は最初の1回だけパニックする名言の取得元です
+type panickingQuoteSource struct {
+	mockQuoteSource
+	panicked atomic.Bool
+}
+
+func (m *panickingQuoteSource) PostRandomQuote(ctx context.Context) (*domain.Quote, error) {
+	if m.panicked.CompareAndSwap(false, true) {
+		panic("boom")
+	}
+	return m.mockQuoteSource.PostRandomQuote(ctx)
+}
+
+func TestApp_RunRestartsScheduler(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PostInterval = 10 * time.Millisecond
+	poster := &mockPoster{}
+	recorder := &mockRecorder{}
+	app, err := New(cfg, Dependencies{
+		Quotes:  &panickingQuoteSource{mockQuoteSource: mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}},
+		Poster:  poster,
+		History: recorder,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- app.Run(ctx)
+	}()
+
+	// 初回投稿でパニックしても、定期投稿を再開する
+	deadline := time.Now().Add(5 * time.Second)
+	for poster.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if poster.count() == 0 {
+		t.Fatal("posts = 0, want the scheduler restarted")
+	}
+	for _, entry := range recorder.entries {
+		if entry.Trigger != TriggerScheduled {
+			t.Errorf("entry.Trigger = %q, want %q without posting the initial post again", entry.Trigger, TriggerScheduled)
+		}
+	}
+}
+
+func TestApp_RunAdminStartError(t *testing.T) {
+	poster := &mockPoster{}
+	app, err := New(newTestConfig(), Dependencies{
+		Quotes: &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}},
+		Poster: poster,
+		NewAdminServer: func(a *App) Server {
+			return &fakeServer{startErr: errors.New("address already in use")}
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := app.Run(context.Background()); err == nil {
+		t.Errorf("Run() error = nil, want error")
+	}
+	// 起動に失敗した場合は投稿しない
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if poster.count() != 0 {
+		t.Errorf("posts = %d, want 0", poster.count())
+	}
+}
@@ -0,0 +1,928 @@
+// Package app はボット全体の起動と停止、投稿のスケジュールと実行時の制御（一時停止、即時投稿、状態の取得）を担当します
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/approval"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/deadletter"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/metrics"
+	"github.com/littleironwaltz/quotebot/internal/notify"
+	"github.com/littleironwaltz/quotebot/internal/outbox"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// 投稿のきっかけ
+const (
+	TriggerInitial   = "initial"
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+	TriggerApproval  = "approval" // 承認待ちの投稿が承認された
+	TriggerOccasion  = "occasion" // 特別な日の投稿（OCCASIONS_FILE）
+	TriggerRetry     = "retry"    // 失敗した投稿を送り直した（RetryDeadLetter）
+)
+
+// maxRecentErrors は状態に保持する直近のエラーの件数です
+const maxRecentErrors = 10
+
+// maxRecentPosts は状態に保持する直近の投稿の件数です
+const maxRecentPosts = 10
+
+// ErrShuttingDown はシャットダウンの開始後に投稿しようとした場合のエラーです
+var ErrShuttingDown = errors.New("シャットダウン中のため投稿できません")
+
+// QuoteSource は投稿する名言の取得元です
+type QuoteSource interface {
+	PostRandomQuote(ctx context.Context) (*domain.Quote, error)
+	Reload() error
+	Count() int
+}
+
+// QuoteFinder はIDで名言を探せる名言の取得元です。QuoteSource が実装していれば、特別な日の投稿で名言のIDを使えます
+type QuoteFinder interface {
+	QuoteByID(id string) (*domain.Quote, error)
+}
+
+// Poster は投稿先です
+type Poster = usecase.PostRepository
+
+// CapabilityProvider は投稿先のプラットフォームの制約を返します。
+// Poster が実装していれば、そのプラットフォームのテンプレートと長さの上限で整形します（実装していなければBluesky）
+type CapabilityProvider interface {
+	Capabilities() domain.Capabilities
+}
+
+// TokenExpirer は投稿先のアクセストークンの有効期限を返します。Poster が実装していれば状態に含めます
+type TokenExpirer interface {
+	AccessTokenExpiry() (time.Time, bool)
+}
+
+// SessionReporter は投稿先のセッションの状態（repository.SessionState）を返します。Poster が実装していれば状態に含めます
+type SessionReporter interface {
+	SessionState() string
+}
+
+// RateLimitReporter は投稿先が最後に返したエンドポイントごとのレート制限の残りを返します。Poster が実装していれば状態に含めます
+type RateLimitReporter interface {
+	RateLimits() map[string]repository.RateLimit
+}
+
+// PostResult は1回の投稿の結果です
+type PostResult struct {
+	At        time.Time `json:"at"`
+	RequestID string    `json:"requestId"`
+	Trigger   string    `json:"trigger"`
+	Text      string    `json:"text,omitempty"`
+	URI       string    `json:"uri,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	DryRun    bool      `json:"dryRun,omitempty"`
+	// PostID は投稿先によらない投稿のID（domain.NewPostKey）です。投稿履歴と Outbox で同じIDを使います
+	PostID string `json:"postId,omitempty"`
+	// URL は投稿を表示するページです。投稿先が返さない場合は空です
+	URL     string `json:"url,omitempty"`
+	Variant string `json:"variant,omitempty"`
+	// QuoteID は投稿した名言のID（domain.Quote.StableID）です
+	QuoteID string `json:"quoteId,omitempty"`
+	// ApprovalID は投稿せずに承認待ちに入れた場合のIDです
+	ApprovalID string `json:"approvalId,omitempty"`
+	// ErrorClass は失敗の分類（domain.ErrorClass）です。分類できない失敗では空です
+	ErrorClass string `json:"errorClass,omitempty"`
+	// DeadLetterID は失敗した投稿を送り直せるよう残した場合のIDです
+	DeadLetterID string `json:"deadLetterId,omitempty"`
+
+	flags      []string       // 投稿履歴に記録する印
+	crossPosts []history.Post // ほかの投稿先への投稿（CROSS_POST_PLUGINS）
+}
+
+// ErrorEntry は直近のエラーの記録です
+type ErrorEntry struct {
+	At        time.Time `json:"at"`
+	RequestID string    `json:"requestId,omitempty"`
+	Message   string    `json:"message"`
+	Class     string    `json:"class,omitempty"` // domain.ErrorClass
+}
+
+// Status はボットの現在の状態です
+type Status struct {
+	Bot            string       `json:"bot,omitempty"` // bots で複数のボットを動かしている場合のボットの名前
+	Paused         bool         `json:"paused"`
+	Standby        bool         `json:"standby,omitempty"` // リーダーではないため定期投稿しないレプリカ
+	DryRun         bool         `json:"dryRun"`
+	StartedAt      time.Time    `json:"startedAt"`
+	NextPostAt     time.Time    `json:"nextPostAt"`
+	LastPost       *PostResult  `json:"lastPost,omitempty"`
+	PoolSize       int          `json:"poolSize"`
+	TokenExpiresAt *time.Time   `json:"tokenExpiresAt,omitempty"`
+	SessionState   string       `json:"sessionState,omitempty"`
+	RecentErrors   []ErrorEntry `json:"recentErrors"`
+	// RecentPosts は直近の投稿の結果です（古い順）。失敗や承認待ちに入れた投稿も含みます
+	RecentPosts []PostResult `json:"recentPosts,omitempty"`
+	// Events は起動してからのイベントの数を種類ごとに数えたものです
+	Events map[events.Type]int `json:"events,omitempty"`
+	// Stages は起動してからの投稿のパイプラインの段階ごとの時間の分布です。publish は投稿先ごとに publish:<投稿先> です
+	Stages map[string]metrics.HistogramSnapshot `json:"stages,omitempty"`
+	// Failures は定期投稿が続けて失敗している回数です。POST_BACKOFF_THRESHOLD 以上の間は投稿間隔を延ばしています
+	Failures int `json:"consecutiveFailures,omitempty"`
+	// Halted は投稿先のアカウントが停止されたため定期投稿を止めている場合の、そのエラーです。再開するまで Paused です
+	Halted string `json:"halted,omitempty"`
+	// RateLimits は投稿先が最後に返したレート制限の残りです。キーはホストとパスです
+	RateLimits map[string]repository.RateLimit `json:"rateLimits,omitempty"`
+}
+
+// Bot は一定間隔で名言を投稿し、実行中の制御を受け付けます
+type Bot struct {
+	quotes      QuoteSource
+	poster      Poster
+	crossRepos  []CrossPoster    // 任意。投稿先に投稿できた後に、同じ本文を投稿するほかの投稿先
+	history     history.Recorder // 任意。投稿の試行を監査ログに記録します
+	monitor     *notify.Monitor  // 任意。投稿が続けて失敗したときに通知します
+	dryRun      bool             // DRY_RUN。投稿せずに本文をログに出力します
+	formatter   *domain.Formatter
+	variants    *domain.Variants  // 任意。A/Bテストで投稿ごとに投稿形式を選びます
+	hooks       *usecase.Hooks    // 任意。投稿のパイプラインの段階の前後に呼ぶフック
+	denyList    *domain.DenyList  // 任意。投稿してはいけない語句
+	denyAction  string            // 禁止語を含む名言の扱い（skip, flag）
+	lang        string            // 任意。翻訳を投稿する言語（WithTranslation）
+	langMode    string            // 翻訳の投稿の仕方（replace, thread）
+	approvals   *approval.Queue   // 任意。投稿せずに承認待ちに入れます
+	outbox      *outbox.Outbox    // 任意。送信する前の投稿を保存し、再起動したときに投稿済みかを確かめます
+	deadLetters *deadletter.Queue // 任意。失敗した投稿を残し、後で送り直せるようにします
+	maxPosts    int               // 0より大きい場合は、この件数を投稿すると Run を終了します
+	leader      Leader            // 任意。リーダーに選ばれている間だけ定期投稿します
+	scheduler   Scheduler         // 任意。設定しない場合は postInterval ごとに投稿します
+	calendar    *domain.Calendar  // 任意。特別な日に、定期投稿とは別に名言を投稿します
+	events      *events.Bus       // 投稿の結果などのイベントを購読者に配ります
+	counter     events.Counter    // イベントの種類ごとの数
+	clock       clock.Clock       // 投稿の予定と記録の時刻。テストでは偽の時計に差し替えます
+	caps        domain.Capabilities
+	name        string // bots で複数のボットを動かしている場合のボットの名前
+	logger      *slog.Logger
+
+	postMu   sync.Mutex         // 投稿を直列化します
+	inflight sync.WaitGroup     // 実行中の投稿
+	abortCtx context.Context    // Shutdown の期限を過ぎるとキャンセルされ、実行中の投稿を中断します
+	abort    context.CancelFunc // abortCtx をキャンセルします
+
+	reschedule chan struct{} // 投稿間隔が変更されたことを Run に知らせます
+
+	backoffAfter int           // 定期投稿がこの回数続けて失敗すると投稿間隔を延ばします（0は延ばしません）
+	backoffMax   time.Duration // 延ばした投稿間隔の上限
+
+	crossWorkers int           // ほかの投稿先に同時に投稿する数（0はすべての投稿先に同時に投稿します）
+	crossTimeout time.Duration // ほかの投稿先ごとの投稿の期限（0は投稿の期限だけを使います）
+
+	stages *metrics.Histograms // 投稿のパイプラインの段階ごとの時間（mu で保護する必要はありません）
+	sink   metrics.Sink        // 段階ごとの時間を送る外部の集計サービス（METRICS_BACKEND）
+
+	mu           sync.Mutex // 以下のフィールドを保護します
+	closed       bool
+	paused       bool
+	postInterval time.Duration // 設定の再読み込みで変更されます
+	postTimeout  time.Duration
+	startedAt    time.Time
+	nextPostAt   time.Time
+	lastPost     *PostResult
+	recentPosts  []PostResult
+	recentErrors []ErrorEntry
+	failures     int    // 定期投稿が続けて失敗した回数
+	halted       string // 投稿先のアカウントが停止されたため一時停止した場合の、そのエラー
+}
+
+// Option はBotの任意の依存関係を設定します
+type Option func(*Bot)
+
+// WithHistory は投稿の試行を記録する Recorder を設定します
+func WithHistory(recorder history.Recorder) Option {
+	return func(b *Bot) {
+		b.history = recorder
+	}
+}
+
+// WithFormatter は投稿本文を整形する Formatter を設定します。設定しない場合はデフォルトのテンプレートを使います
+func WithFormatter(formatter *domain.Formatter) Option {
+	return func(b *Bot) {
+		b.formatter = formatter
+	}
+}
+
+// WithHooks は投稿のパイプライン（select → format → validate → publish → record）の段階の前後に呼ぶフックを設定します
+func WithHooks(hooks *usecase.Hooks) Option {
+	return func(b *Bot) {
+		b.hooks = hooks
+	}
+}
+
+// WithDenyList は禁止語を含む名言を、action が skip の場合は投稿せずに別の名言を選び直し、
+// flag の場合は投稿して投稿履歴に印を付けます。名言を選んだ後のフックとして登録します
+func WithDenyList(denyList *domain.DenyList, action string) Option {
+	return func(b *Bot) {
+		b.denyList = denyList
+		b.denyAction = action
+	}
+}
+
+// WithApprovals は投稿せずに承認待ちに入れ、Approve で承認された投稿だけを投稿します
+func WithApprovals(queue *approval.Queue) Option {
+	return func(b *Bot) {
+		b.approvals = queue
+	}
+}
+
+// WithMaxPosts は Run が初回投稿を含めて n 件の投稿を試みた後に終了するようにします（backfill など）
+func WithMaxPosts(n int) Option {
+	return func(b *Bot) {
+		b.maxPosts = n
+	}
+}
+
+// WithVariants は投稿ごとに重みに応じて投稿形式を選び、選んだ形式の名前を投稿履歴に記録します
+func WithVariants(variants *domain.Variants) Option {
+	return func(b *Bot) {
+		b.variants = variants
+	}
+}
+
+// WithLeader はリーダー選出でリーダーに選ばれている間だけ定期投稿するようにします。
+// リーダーでない間も、管理APIなどからの投稿や承認された投稿は受け付けます
+func WithLeader(leader Leader) Option {
+	return func(b *Bot) {
+		b.leader = leader
+	}
+}
+
+// WithMonitor は投稿の結果を監視し、続けて失敗したときに通知する Monitor を設定します
+func WithMonitor(monitor *notify.Monitor) Option {
+	return func(b *Bot) {
+		b.monitor = monitor
+	}
+}
+
+// WithMetrics は投稿のパイプラインの段階ごとの時間を sink にも送ります
+func WithMetrics(sink metrics.Sink) Option {
+	return func(b *Bot) {
+		b.sink = sink
+	}
+}
+
+// WithClock は投稿の予定や記録に実際の時計の代わりに c を使います（テストでの clock.Fake など）
+func WithClock(c clock.Clock) Option {
+	return func(b *Bot) {
+		b.clock = c
+	}
+}
+
+// NewBot は新しいBotインスタンスを作成します
+func NewBot(cfg *config.Config, quotes QuoteSource, poster Poster, opts ...Option) *Bot {
+	b := &Bot{
+		quotes:       quotes,
+		poster:       poster,
+		dryRun:       cfg.DryRun,
+		formatter:    domain.DefaultFormatter(),
+		caps:         domain.BlueskyCapabilities,
+		name:         cfg.Bot(),
+		logger:       logging.ModuleFor(cfg, "main"),
+		reschedule:   make(chan struct{}, 1),
+		postInterval: cfg.PostInterval,
+		postTimeout:  cfg.PostTimeout,
+		backoffAfter: cfg.PostBackoffThreshold,
+		backoffMax:   cfg.PostBackoffMax,
+		crossWorkers: cfg.CrossPostConcurrency,
+		crossTimeout: cfg.CrossPostTimeout,
+		clock:        clock.Real,
+		stages:       metrics.NewHistograms(metrics.DefaultDurationBuckets),
+		sink:         metrics.Discard,
+	}
+	if provider, ok := poster.(CapabilityProvider); ok {
+		b.caps = provider.Capabilities()
+	}
+	b.abortCtx, b.abort = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.startedAt = b.clock.Now()
+	if b.denyList != nil && !b.denyList.Empty() {
+		if b.hooks == nil {
+			b.hooks = usecase.NewHooks()
+		}
+		b.hooks.After(usecase.StageSelect, b.filterDenied)
+	}
+	if b.lang != "" {
+		if b.hooks == nil {
+			b.hooks = usecase.NewHooks()
+		}
+		// 禁止語で選び直した後の名言を翻訳する
+		b.hooks.After(usecase.StageSelect, b.localize)
+		if b.langMode == domain.TranslationModeThread {
+			b.hooks.After(usecase.StagePublish, b.replyTranslation)
+		}
+	}
+	if b.events == nil {
+		b.events = events.NewBus()
+	}
+	b.subscribeEvents()
+	return b
+}
+
+// Run は初回投稿を行った後、POST_INTERVAL ごと（WithScheduler を設定した場合はその時刻）に投稿します。
+// WithCalendar を設定した場合は、特別な日の決まった時刻にその日の名言も投稿します。
+// ctx がキャンセルされると新しい投稿を行わずに終了します。実行中の投稿は完了を待ちます
+func (b *Bot) Run(ctx context.Context) {
+	b.run(ctx, true)
+}
+
+// run は Run の本体です。initial が false の場合は、パニックした定期投稿を再開するため初回投稿を行いません
+func (b *Bot) run(ctx context.Context, initial bool) {
+	lastTick := b.clock.Now()
+	next := b.schedule(lastTick)
+	timer := b.clock.NewTimer(max(clock.Until(b.clock, next), 0))
+	defer timer.Stop()
+	b.setNextPostAt(next)
+
+	// 特別な日の投稿は定期投稿とは別のタイマーで行う。予定がなければ発火しない
+	var occasionAt time.Time
+	var occasionTimer clock.Timer
+	var occasionC <-chan time.Time
+	if b.calendar != nil {
+		occasionAt = b.calendar.Next(lastTick)
+		occasionTimer = b.clock.NewTimer(max(clock.Until(b.clock, occasionAt), 0))
+		defer occasionTimer.Stop()
+		occasionC = occasionTimer.C()
+	}
+
+	b.logger.Info("QuoteBotが起動しました", "post_interval", b.interval(), "next_post_at", next)
+
+	posts := 0
+	if initial {
+		// 前回の実行で送信中のまま止まった投稿を、初回投稿の前に確かめる
+		b.reconcileOutbox(ctx)
+
+		// 初回投稿
+		// トークンは有効期限に合わせてTokenManagerが自動的にリフレッシュします
+		switch {
+		case b.standbyBeforePost(ctx):
+			b.logger.Info("リーダーではないため投稿をスキップしました")
+		case b.replacedByOccasion():
+			b.logger.Info("特別な日のため定期投稿をスキップしました")
+		default:
+			b.backOff(b.post(ctx, TriggerInitial, nil, nil), lastTick, timer)
+		}
+		posts = 1
+		if b.maxPosts > 0 && posts >= b.maxPosts {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.reschedule:
+			// 直前の投稿予定時刻から新しい間隔を数え直す。すでに過ぎていればすぐに投稿する
+			next := b.schedule(lastTick)
+			timer.Stop()
+			timer.Reset(max(clock.Until(b.clock, next), 0))
+			b.setNextPostAt(next)
+		case <-timer.C():
+			// キャンセルと同時にタイマーが発火した場合は投稿しない
+			if ctx.Err() != nil {
+				return
+			}
+			lastTick = b.clock.Now()
+			next := b.schedule(lastTick)
+			timer.Reset(max(clock.Until(b.clock, next), 0))
+			b.setNextPostAt(next)
+			if b.Paused() {
+				b.logger.Info("一時停止中のため投稿をスキップしました")
+				continue
+			}
+			if b.standbyBeforePost(ctx) {
+				b.logger.Info("リーダーではないため投稿をスキップしました")
+				continue
+			}
+			if b.replacedByOccasion() {
+				b.logger.Info("特別な日のため定期投稿をスキップしました")
+				continue
+			}
+			b.backOff(b.post(ctx, TriggerScheduled, nil, nil), lastTick, timer)
+			if posts++; b.maxPosts > 0 && posts >= b.maxPosts {
+				return
+			}
+		case <-occasionC:
+			if ctx.Err() != nil {
+				return
+			}
+			day := occasionAt
+			occasionAt = b.calendar.Next(day)
+			occasionTimer.Reset(max(clock.Until(b.clock, occasionAt), 0))
+			if b.Paused() {
+				b.logger.Info("一時停止中のため投稿をスキップしました")
+				continue
+			}
+			if b.standbyBeforePost(ctx) {
+				b.logger.Info("リーダーではないため投稿をスキップしました")
+				continue
+			}
+			b.postOccasions(ctx, day)
+		}
+	}
+}
+
+// SetSchedule は投稿間隔と投稿のタイムアウトを変更します。
+// 実行中の Run は直前の投稿予定時刻から新しい間隔で次の投稿を予定し直します
+func (b *Bot) SetSchedule(interval, timeout time.Duration) {
+	b.mu.Lock()
+	changed := b.postInterval != interval
+	b.postInterval = interval
+	b.postTimeout = timeout
+	b.mu.Unlock()
+
+	if changed {
+		select {
+		case b.reschedule <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// interval は現在の投稿間隔を返します
+func (b *Bot) interval() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.postInterval
+}
+
+// PostNow は一時停止中かどうかに関係なく、すぐに投稿します
+func (b *Bot) PostNow(ctx context.Context) (*PostResult, error) {
+	result := b.post(ctx, TriggerManual, nil, nil)
+	if result.Error != "" {
+		return result, fmt.Errorf("%s", result.Error)
+	}
+	return result, nil
+}
+
+// Approvals は承認待ちの投稿を古い順に返します。承認待ちを使わない場合は空です
+func (b *Bot) Approvals() ([]approval.Item, error) {
+	if b.approvals == nil {
+		return nil, nil
+	}
+	b.expireApprovals()
+	return b.approvals.List()
+}
+
+// Approve は承認待ちの投稿を承認し、承認待ちに入れたときの本文をそのまま投稿します
+func (b *Bot) Approve(ctx context.Context, id string) (*PostResult, error) {
+	if b.approvals == nil {
+		return nil, approval.ErrNotFound
+	}
+	item, err := b.approvals.Take(id)
+	if err != nil {
+		return nil, err
+	}
+	b.logger.Info("承認待ちの投稿が承認されました", "approval_id", item.ID)
+	result := b.post(ctx, TriggerApproval, &item, nil)
+	if result.Error != "" {
+		return result, fmt.Errorf("%s", result.Error)
+	}
+	return result, nil
+}
+
+// Reject は承認待ちの投稿を投稿せずに破棄します
+func (b *Bot) Reject(id string) error {
+	if b.approvals == nil {
+		return approval.ErrNotFound
+	}
+	item, err := b.approvals.Take(id)
+	if err != nil {
+		return err
+	}
+	b.logger.Info("承認待ちの投稿が却下されました", "approval_id", item.ID)
+	return nil
+}
+
+// expireApprovals は期限までに承認されなかった投稿を破棄します
+func (b *Bot) expireApprovals() {
+	expired, err := b.approvals.Expire()
+	if err != nil {
+		b.logger.Warn("承認待ちの投稿の読み込みに失敗しました", "error", err)
+		return
+	}
+	for _, item := range expired {
+		b.logger.Info("期限までに承認されなかった投稿を破棄しました", "approval_id", item.ID, "expires_at", item.ExpiresAt)
+	}
+}
+
+// Shutdown は新しい投稿を受け付けないようにし、実行中の投稿の完了を待ちます。
+// ctx の期限までに完了しない場合は実行中の投稿を中断し、ctx のエラーを返します
+func (b *Bot) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		b.abort()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Pause は定期投稿を一時停止します
+func (b *Bot) Pause() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.paused {
+		b.logger.Info("定期投稿を一時停止しました")
+	}
+	b.paused = true
+}
+
+// Resume は一時停止した定期投稿を再開します。アカウントの停止で止めた場合も再開します
+func (b *Bot) Resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.paused {
+		b.logger.Info("定期投稿を再開しました")
+	}
+	b.paused = false
+	b.halted = ""
+}
+
+// halt は投稿先のアカウントが停止されたため、運用者が Resume するまで定期投稿を止めます
+func (b *Bot) halt(reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.halted != "" {
+		return
+	}
+	b.logger.Error("投稿先のアカウントが停止されているため定期投稿を止めました", "error", reason)
+	b.paused = true
+	b.halted = reason
+}
+
+// Paused は定期投稿が一時停止中かどうかを返します
+func (b *Bot) Paused() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.paused
+}
+
+// ReloadQuotes は名言ファイルを読み込み直し、読み込んだ件数を返します
+func (b *Bot) ReloadQuotes() (int, error) {
+	if err := b.quotes.Reload(); err != nil {
+		b.recordError("", err)
+		return 0, err
+	}
+	count := b.quotes.Count()
+	b.logger.Info("名言を再読み込みしました", "count", count)
+	return count, nil
+}
+
+// standby はリーダー選出でリーダーに選ばれていないため、定期投稿しないかを返します
+func (b *Bot) standby() bool {
+	return b.leader != nil && !b.leader.IsLeader()
+}
+
+// standbyBeforePost は standby と同じですが、リーダー選出が LeaderConfirmer を実装していれば
+// リーダーであることを確かめ直します。定期投稿の直前に呼びます
+func (b *Bot) standbyBeforePost(ctx context.Context) bool {
+	if confirmer, ok := b.leader.(LeaderConfirmer); ok {
+		return !confirmer.ConfirmLeader(ctx)
+	}
+	return b.standby()
+}
+
+// Status は現在の状態を返します
+func (b *Bot) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		Bot:          b.name,
+		Paused:       b.paused,
+		Standby:      b.standby(),
+		DryRun:       b.dryRun,
+		StartedAt:    b.startedAt,
+		NextPostAt:   b.nextPostAt,
+		PoolSize:     b.quotes.Count(),
+		RecentErrors: append([]ErrorEntry{}, b.recentErrors...),
+		RecentPosts:  append([]PostResult(nil), b.recentPosts...),
+		Events:       b.counter.Counts(),
+		Stages:       b.stages.Snapshot(),
+		Failures:     b.failures,
+		Halted:       b.halted,
+	}
+	if b.lastPost != nil {
+		lastPost := *b.lastPost
+		status.LastPost = &lastPost
+	}
+	if expirer, ok := b.poster.(TokenExpirer); ok {
+		if expiry, ok := expirer.AccessTokenExpiry(); ok {
+			status.TokenExpiresAt = &expiry
+		}
+	}
+	if reporter, ok := b.poster.(SessionReporter); ok {
+		status.SessionState = reporter.SessionState()
+	}
+	if reporter, ok := b.poster.(RateLimitReporter); ok {
+		status.RateLimits = reporter.RateLimits()
+	}
+	return status
+}
+
+// post は名言を1件選んで投稿し、結果を記録します。approved を指定した場合は選ばずにその投稿を投稿し、
+// selected を指定した場合は選ばずにその名言を投稿します
+func (b *Bot) post(ctx context.Context, trigger string, approved *approval.Item, selected *domain.Quote) *PostResult {
+	if !b.beginPost() {
+		return &PostResult{At: b.clock.Now(), Trigger: trigger, Error: ErrShuttingDown.Error()}
+	}
+	defer b.inflight.Done()
+
+	b.postMu.Lock()
+	defer b.postMu.Unlock()
+
+	// シャットダウンのシグナルで投稿が途中で止まらないよう、ctx のキャンセルは引き継ぎません。
+	// 投稿全体はPOST_TIMEOUTで打ち切られ、Shutdown の期限を過ぎた場合は中断されます
+	requestID := repository.NewRequestID()
+	b.mu.Lock()
+	timeout := b.postTimeout
+	b.mu.Unlock()
+	reqCtx, cancel := context.WithTimeout(repository.WithRequestID(context.WithoutCancel(ctx), requestID), timeout)
+	defer cancel()
+	stop := context.AfterFunc(b.abortCtx, cancel)
+	defer stop()
+
+	result := &PostResult{At: b.clock.Now(), RequestID: requestID, Trigger: trigger}
+	result.PostID = domain.NewPostKey(result.At, requestID)
+	err := b.runPipeline(reqCtx, result, approved, selected)
+	if err != nil {
+		b.recordError(requestID, err)
+	}
+
+	b.mu.Lock()
+	b.lastPost = result
+	b.recentPosts = append(b.recentPosts, *result)
+	if len(b.recentPosts) > maxRecentPosts {
+		b.recentPosts = b.recentPosts[len(b.recentPosts)-maxRecentPosts:]
+	}
+	b.mu.Unlock()
+
+	event := resultEvent(result)
+	if err != nil {
+		event.Type = events.PostFailed
+		event.Error = redact.String(err.Error())
+		event.ErrorClass = string(domain.ClassifyError(err))
+	}
+	b.events.Publish(event)
+	return result
+}
+
+// beginPost は実行中の投稿として登録します。シャットダウンの開始後は false を返します
+func (b *Bot) beginPost() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	b.inflight.Add(1)
+	return true
+}
+
+// runPipeline は名言を選んで（selected を指定した場合はその名言を）投稿し、結果を result と投稿履歴に記録します。
+// 承認待ちを使う場合は、approved を指定したときだけ投稿し、それ以外は承認待ちに入れます
+func (b *Bot) runPipeline(ctx context.Context, result *PostResult, approved *approval.Item, selected *domain.Quote) error {
+	pc := &usecase.PostContext{
+		RequestID:    result.RequestID,
+		Trigger:      result.Trigger,
+		Formatter:    b.formatter,
+		Capabilities: b.caps,
+		DryRun:       b.dryRun,
+	}
+	outboxRepo := b.newOutboxRepository(pc, result)
+	threadRepo := b.newThreadRepository(pc, result)
+	pipeline := usecase.Pipeline{
+		Select: b.quotes.PostRandomQuote,
+		Repo:   b.poster,
+		Hooks:  b.hooks,
+		Record: func(ctx context.Context, pc *usecase.PostContext) {
+			// 投稿履歴に記録するまでは、停止しても再起動したときに確かめられるよう Outbox に残す
+			defer outboxRepo.done(b)
+			result.Text = pc.Text
+			result.flags = pc.Flags
+			if pc.Quote != nil {
+				result.QuoteID = pc.Quote.StableID()
+			}
+			switch {
+			case pc.Err != nil:
+				result.Error = redact.String(pc.Err.Error())
+				result.ErrorClass = string(domain.ClassifyError(pc.Err))
+				b.addDeadLetter(pc, result)
+			case pc.DryRun:
+				result.DryRun = true
+			case pc.Held:
+				// 承認されたときに記録する
+				return
+			default:
+				result.URI, result.URL = pc.Ref.URI, pc.Ref.URL
+			}
+			// 投稿していないため、DRY_RUN では投稿履歴に記録しない
+			if !pc.DryRun {
+				b.recordHistory(result, pc.Quote, pc.Ref)
+			}
+		},
+	}
+	if threadRepo != nil {
+		pipeline.Repo = threadRepo
+		if outboxRepo != nil {
+			outboxRepo.repo = threadRepo
+		}
+	}
+	if outboxRepo != nil {
+		pipeline.Repo = outboxRepo
+	}
+	if crossRepo := b.newCrossPostRepository(pipeline.Repo, result); crossRepo != nil {
+		pipeline.Repo = crossRepo
+	}
+	if selected != nil {
+		pipeline.Select = func(ctx context.Context) (*domain.Quote, error) { return selected, nil }
+	}
+	switch {
+	case approved != nil:
+		quote := approved.Quote
+		pipeline.Select = func(ctx context.Context) (*domain.Quote, error) { return &quote, nil }
+		pc.Text = approved.Text
+		result.Variant = approved.Variant
+	case b.variants != nil:
+		variant := b.variants.Pick()
+		pc.Formatter = variant.Formatter
+		result.Variant = variant.Name
+	}
+	if approved == nil && b.approvals != nil {
+		pipeline.Hold = func(ctx context.Context, pc *usecase.PostContext) error {
+			b.expireApprovals()
+			item, err := b.approvals.Add(*pc.Quote, pc.Text, result.Variant)
+			if err != nil {
+				return err
+			}
+			result.ApprovalID = item.ID
+			return nil
+		}
+	}
+	err := pipeline.Run(ctx, pc)
+	b.observeStages(pc)
+	for _, hookErr := range pc.HookErrors {
+		b.logger.Warn("投稿のフックでエラーが発生しました", "request_id", result.RequestID, "error", redact.Error(hookErr))
+	}
+	return err
+}
+
+// observeStages は投稿のパイプラインの段階ごとの時間を数えます。
+// publish は投稿先によって時間が大きく違うため、投稿先ごとに分けます
+func (b *Bot) observeStages(pc *usecase.PostContext) {
+	for stage, d := range pc.Durations {
+		label := string(stage)
+		if stage == usecase.StagePublish {
+			label += ":" + pc.Capabilities.Platform
+		}
+		b.stages.Observe(label, d)
+		b.sink.Timing("post.stage", d, "stage:"+label)
+	}
+}
+
+// maxDenyRetries は禁止語を含む名言を選び直す回数の上限です
+const maxDenyRetries = 10
+
+// filterDenied は選んだ名言が禁止語を含む場合に、denyAction に従って選び直すか印を付けます。
+// 選び直した名言は投稿履歴に blocked として記録します
+func (b *Bot) filterDenied(ctx context.Context, pc *usecase.PostContext) error {
+	for retries := 0; ; retries++ {
+		term, ok := b.denyList.MatchQuote(pc.Quote)
+		if !ok {
+			return nil
+		}
+		if b.denyAction == domain.DenyActionFlag {
+			b.logger.Warn("禁止語を含む名言を投稿します", "request_id", pc.RequestID, "term", term)
+			pc.Flags = append(pc.Flags, "deny:"+term)
+			return nil
+		}
+
+		switch {
+		case pc.Text != "":
+			// 承認された本文は名言を選び直すと変わってしまう
+			return fmt.Errorf("承認された名言が禁止語を含みます: %s", term)
+		case pc.Trigger == TriggerOccasion:
+			// 特別な日の名言はほかの名言に置き換えない
+			return fmt.Errorf("特別な日の名言が禁止語を含みます: %s", term)
+		}
+		b.logger.Warn("禁止語を含む名言をスキップしました", "request_id", pc.RequestID, "term", term)
+		b.recordBlocked(pc, term)
+		if retries >= maxDenyRetries {
+			return fmt.Errorf("禁止語を含まない名言を選べませんでした（%d回）", retries+1)
+		}
+		quote, err := b.quotes.PostRandomQuote(ctx)
+		if err != nil {
+			return err
+		}
+		pc.Quote = quote
+	}
+}
+
+// recordBlocked は禁止語を含むため投稿しなかった名言を投稿履歴に記録します
+func (b *Bot) recordBlocked(pc *usecase.PostContext, term string) {
+	if b.history == nil || pc.DryRun {
+		return
+	}
+	entry := history.Entry{
+		Timestamp: b.clock.Now(),
+		RequestID: pc.RequestID,
+		Trigger:   pc.Trigger,
+		Platform:  pc.Capabilities.Platform,
+		Quote:     history.NewQuote(pc.Quote),
+		Result:    history.ResultBlocked,
+		Error:     "禁止語を含むため投稿しませんでした: " + term,
+	}
+	if err := b.history.Record(entry); err != nil {
+		b.logger.Warn("投稿履歴の記録に失敗しました", "request_id", pc.RequestID, "error", err)
+	}
+}
+
+// recordHistory は投稿の試行を監査ログに記録します。名言を選べなかった場合も失敗として記録します
+func (b *Bot) recordHistory(result *PostResult, quote *domain.Quote, ref *domain.PostRef) {
+	if b.history == nil {
+		return
+	}
+
+	entry := history.Entry{
+		Timestamp: result.At,
+		RequestID: result.RequestID,
+		Trigger:   result.Trigger,
+		Platform:  b.caps.Platform,
+		Text:      result.Text,
+		Result:    history.ResultSuccess,
+		Error:     result.Error,
+		Variant:   result.Variant,
+		Flags:     result.flags,
+		PostID:    result.PostID,
+	}
+	if quote != nil {
+		entry.Quote = history.NewQuote(quote)
+	}
+	if ref != nil {
+		entry.Platform = ref.Platform
+		entry.URI = ref.URI
+		entry.CID = ref.CID
+		entry.Posts = []history.Post{history.NewPost(ref)}
+	}
+	// ほかの投稿先への投稿も同じレコードにまとめ、投稿先ごとの投稿をたどれるようにする
+	entry.Posts = append(entry.Posts, result.crossPosts...)
+	if result.Error != "" {
+		entry.Result = history.ResultFailure
+	}
+
+	if err := b.history.Record(entry); err != nil {
+		b.logger.Warn("投稿履歴の記録に失敗しました", "request_id", result.RequestID, "error", err)
+	}
+}
+
+// setNextPostAt は次回の定期投稿の予定時刻を記録します
+func (b *Bot) setNextPostAt(at time.Time) {
+	b.mu.Lock()
+	b.nextPostAt = at
+	b.mu.Unlock()
+}
+
+// recordError は直近のエラーとして記録します
+func (b *Bot) recordError(requestID string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.recentErrors = append(b.recentErrors, ErrorEntry{
+		At:        b.clock.Now(),
+		RequestID: requestID,
+		Message:   redact.String(err.Error()),
+		Class:     string(domain.ClassifyError(err)),
+	})
+	if len(b.recentErrors) > maxRecentErrors {
+		b.recentErrors = b.recentErrors[len(b.recentErrors)-maxRecentErrors:]
+	}
+}
@@ -0,0 +1,1469 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/approval"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/deadletter"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/outbox"
+	"github.com/littleironwaltz/quotebot/internal/state"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// mockQuoteSource はテスト用の名言の取得元です
+type mockQuoteSource struct {
+	quotes    []domain.Quote
+	reloadErr error
+}
+
+func (m *mockQuoteSource) PostRandomQuote(ctx context.Context) (*domain.Quote, error) {
+	if len(m.quotes) == 0 {
+		return nil, errors.New("利用可能な名言がありません")
+	}
+	return &m.quotes[0], nil
+}
+
+func (m *mockQuoteSource) Reload() error {
+	return m.reloadErr
+}
+
+func (m *mockQuoteSource) Count() int {
+	return len(m.quotes)
+}
+
+func (m *mockQuoteSource) QuoteByID(id string) (*domain.Quote, error) {
+	for i := range m.quotes {
+		if m.quotes[i].ID == id {
+			return &m.quotes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("IDが %s の名言が見つかりません", id)
+}
+
+// mockPoster は投稿されたメッセージを記録します
+type mockPoster struct {
+	mu       sync.Mutex
+	messages []string
+	err      error
+}
+
+func (m *mockPoster) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.messages = append(m.messages, message)
+	return &domain.PostRef{
+		Platform: domain.PlatformBluesky,
+		URI:      fmt.Sprintf("at://did:plc:test/app.bsky.feed.post/%d", len(m.messages)),
+		CID:      "bafytest",
+	}, nil
+}
+
+// expiringPoster はアクセストークンの有効期限を返す投稿先です
+type expiringPoster struct {
+	mockPoster
+	expiry time.Time
+}
+
+func (m *expiringPoster) AccessTokenExpiry() (time.Time, bool) {
+	return m.expiry, true
+}
+
+func (m *mockPoster) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.messages)
+}
+
+func newTestBot(poster *mockPoster, interval time.Duration, opts ...Option) *Bot {
+	cfg := &config.Config{PostInterval: interval, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	return NewBot(cfg, quotes, poster, opts...)
+}
+
+// waitForPosts は投稿先に n 件の投稿が届くまで待ちます
+func waitForPosts(t *testing.T, poster *mockPoster, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for poster.count() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("posts = %d, want %d", poster.count(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBot_PostNow(t *testing.T) {
+	tests := []struct {
+		name       string
+		postErr    error
+		wantErr    bool
+		wantErrors int
+	}{
+		{
+			name:       "正常系: 投稿に成功",
+			postErr:    nil,
+			wantErr:    false,
+			wantErrors: 0,
+		},
+		{
+			name:       "異常系: 投稿に失敗するとエラーが記録される",
+			postErr:    errors.New("failed with Bearer secret-token"),
+			wantErr:    true,
+			wantErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot := newTestBot(&mockPoster{err: tt.postErr}, time.Hour)
+
+			result, err := bot.PostNow(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PostNow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Trigger != TriggerManual || result.RequestID == "" || result.Text != "テスト名言\n- 著者" {
+				t.Errorf("PostNow() = %+v", result)
+			}
+
+			status := bot.Status()
+			if status.LastPost == nil || status.LastPost.RequestID != result.RequestID {
+				t.Errorf("Status().LastPost = %+v, want %+v", status.LastPost, result)
+			}
+			if len(status.RecentErrors) != tt.wantErrors {
+				t.Errorf("len(Status().RecentErrors) = %d, want %d", len(status.RecentErrors), tt.wantErrors)
+			}
+			// エラーメッセージの認証情報はマスクされる
+			for _, entry := range status.RecentErrors {
+				if entry.Message != "failed with Bearer [REDACTED]" {
+					t.Errorf("RecentErrors message = %q", entry.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestBot_RecentErrorsLimit(t *testing.T) {
+	bot := newTestBot(&mockPoster{err: errors.New("boom")}, time.Hour)
+	for i := 0; i < maxRecentErrors+5; i++ {
+		bot.PostNow(context.Background())
+	}
+	if got := len(bot.Status().RecentErrors); got != maxRecentErrors {
+		t.Errorf("len(RecentErrors) = %d, want %d", got, maxRecentErrors)
+	}
+}
+
+func TestBot_Run(t *testing.T) {
+	const interval = time.Hour
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	poster := &mockPoster{}
+	bot := newTestBot(poster, interval, WithClock(fake))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		bot.Run(ctx)
+		close(done)
+	}()
+
+	// 初回投稿と定期投稿
+	waitForPosts(t, poster, 1)
+	fake.Advance(interval)
+	waitForPosts(t, poster, 2)
+	if got, want := bot.Status().NextPostAt, start.Add(2*interval); !got.Equal(want) {
+		t.Errorf("Status().NextPostAt = %v, want %v", got, want)
+	}
+	if got := bot.Status().StartedAt; !got.Equal(start) {
+		t.Errorf("Status().StartedAt = %v, want %v", got, start)
+	}
+
+	// 一時停止中は投稿されない。次の予定を入れ直すまで待ってから数える
+	bot.Pause()
+	fake.Advance(interval)
+	fake.BlockUntil(1)
+	if poster.count() != 2 {
+		t.Errorf("posts while paused = %d, want 2", poster.count())
+	}
+	if !bot.Status().Paused {
+		t.Errorf("Status().Paused = false, want true")
+	}
+
+	// 再開すると投稿が続く
+	bot.Resume()
+	fake.Advance(interval)
+	waitForPosts(t, poster, 3)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+}
+
+func TestBot_FailureBackoff(t *testing.T) {
+	const interval = time.Hour
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	poster := &mockPoster{err: errors.New("account suspended")}
+	cfg := &config.Config{PostInterval: interval, PostTimeout: time.Second, PostBackoffThreshold: 2, PostBackoffMax: 3 * time.Hour}
+	bot := NewBot(cfg, &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}, poster, WithClock(fake))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bot.Run(ctx)
+
+	// waitForNextPost は次の投稿予定が want になるまで待ちます
+	waitForNextPost := func(want time.Time) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for !bot.Status().NextPostAt.Equal(want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("NextPostAt = %v, want %v", bot.Status().NextPostAt, want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// 1回目の失敗では間隔は変わらない
+	waitForNextPost(start.Add(interval))
+	// 2回続けて失敗すると間隔を2倍に延ばす
+	fake.Advance(interval)
+	waitForNextPost(start.Add(3 * interval))
+	if got := bot.Status().Failures; got != 2 {
+		t.Errorf("Status().Failures = %d, want 2", got)
+	}
+	// さらに失敗しても POST_BACKOFF_MAX までしか延ばさない
+	fake.Advance(2 * interval)
+	waitForNextPost(start.Add(6 * interval))
+
+	// 成功すると元の間隔に戻す
+	poster.mu.Lock()
+	poster.err = nil
+	poster.mu.Unlock()
+	fake.Advance(3 * interval)
+	waitForNextPost(start.Add(7 * interval))
+	status := bot.Status()
+	if status.Failures != 0 || status.Events[events.PostBackoff] != 2 {
+		t.Errorf("Status() failures = %d, post_backoff events = %d, want 0 and 2", status.Failures, status.Events[events.PostBackoff])
+	}
+}
+
+func TestBot_HaltOnSuspension(t *testing.T) {
+	poster := &mockPoster{err: fmt.Errorf("failed to post message: %w", domain.ErrAccountSuspended)}
+	bot := newTestBot(poster, time.Hour)
+
+	bot.PostNow(context.Background())
+	status := bot.Status()
+	if !status.Paused || status.Halted == "" {
+		t.Fatalf("Status() paused = %v, halted = %q, want halted after the account was suspended", status.Paused, status.Halted)
+	}
+
+	// セッションの状態の変化でも止まり、再開すると解除される
+	bot.Resume()
+	if status := bot.Status(); status.Paused || status.Halted != "" {
+		t.Fatalf("Status() after Resume() paused = %v, halted = %q", status.Paused, status.Halted)
+	}
+	bot.Events().Publish(events.Event{Type: events.SessionStateChanged, SessionState: "suspended"})
+	if status := bot.Status(); !status.Paused || status.Halted != domain.ErrAccountSuspended.Error() {
+		t.Errorf("Status() paused = %v, halted = %q, want halted on the suspended session", status.Paused, status.Halted)
+	}
+}
+
+// fakeLeader はリーダーかどうかをテストから切り替えられるリーダー選出です
+type fakeLeader struct {
+	leading atomic.Bool
+}
+
+func (l *fakeLeader) Start() error                       { return nil }
+func (l *fakeLeader) Shutdown(ctx context.Context) error { return nil }
+func (l *fakeLeader) IsLeader() bool                     { return l.leading.Load() }
+
+func TestBot_Leader(t *testing.T) {
+	const interval = time.Hour
+	fake := clock.NewFake(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	poster := &mockPoster{}
+	leader := &fakeLeader{}
+	bot := newTestBot(poster, interval, WithClock(fake), WithLeader(leader))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bot.Run(ctx)
+
+	// リーダーでない間は初回投稿も定期投稿もしない
+	fake.BlockUntil(1)
+	fake.Advance(interval)
+	fake.BlockUntil(1)
+	if poster.count() != 0 {
+		t.Errorf("posts while standing by = %d, want 0", poster.count())
+	}
+	if !bot.Status().Standby {
+		t.Errorf("Status().Standby = false, want true")
+	}
+
+	// リーダーになると次の予定から投稿する
+	leader.leading.Store(true)
+	fake.Advance(interval)
+	waitForPosts(t, poster, 1)
+	if bot.Status().Standby {
+		t.Errorf("Status().Standby = true, want false")
+	}
+}
+
+// confirmingLeader は投稿の直前に確かめ直した結果をテストから切り替えられるリーダー選出です
+type confirmingLeader struct {
+	fakeLeader
+	confirmed atomic.Bool
+}
+
+func (l *confirmingLeader) ConfirmLeader(ctx context.Context) bool { return l.confirmed.Load() }
+
+func TestBot_LeaderConfirm(t *testing.T) {
+	const interval = time.Hour
+	fake := clock.NewFake(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	poster := &mockPoster{}
+	leader := &confirmingLeader{}
+	leader.leading.Store(true)
+	bot := newTestBot(poster, interval, WithClock(fake), WithLeader(leader))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bot.Run(ctx)
+
+	// リーダーのつもりでも、投稿の直前に確かめられなければ投稿しない
+	fake.BlockUntil(1)
+	fake.Advance(interval)
+	fake.BlockUntil(1)
+	if poster.count() != 0 {
+		t.Errorf("posts without confirming the leadership = %d, want 0", poster.count())
+	}
+
+	// 確かめられると投稿する
+	leader.confirmed.Store(true)
+	fake.Advance(interval)
+	waitForPosts(t, poster, 1)
+}
+
+func TestBot_Calendar(t *testing.T) {
+	const interval = 24 * time.Hour
+	fake := clock.NewFake(time.Date(2024, 3, 13, 8, 30, 0, 0, time.UTC))
+	calendar, err := domain.NewCalendar([]domain.Occasion{
+		{Date: "03-14", Text: "パイの日", Replace: true},
+		{Date: "03-14", QuoteID: "einstein"},
+		{Date: "03-14", QuoteID: "missing"},
+	}, "09:00", time.UTC)
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+	poster := &mockPoster{}
+	cfg := &config.Config{PostInterval: interval, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{
+		{Text: "テスト名言", Author: "著者"},
+		{ID: "einstein", Text: "想像力は知識より重要だ", Author: "アインシュタイン"},
+	}}
+	bot := NewBot(cfg, quotes, poster, WithClock(fake), WithCalendar(calendar))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bot.Run(ctx)
+
+	waitForPosts(t, poster, 1)
+	fake.BlockUntil(2)
+
+	// 正常系: replace の特別な日は定期投稿を行わない
+	fake.Advance(interval)
+	fake.BlockUntil(2)
+	if poster.count() != 1 {
+		t.Errorf("posts on a replaced day = %d, want 1", poster.count())
+	}
+
+	// 正常系: 特別な日の時刻に、その日の名言を予定に書いた順に投稿する。見つからない名言は飛ばす
+	fake.Advance(30 * time.Minute)
+	waitForPosts(t, poster, 3)
+	poster.mu.Lock()
+	messages := append([]string(nil), poster.messages...)
+	poster.mu.Unlock()
+	if !strings.Contains(messages[1], "パイの日") || !strings.Contains(messages[2], "想像力は知識より重要だ") {
+		t.Errorf("messages = %q, want the occasions of 03-14", messages)
+	}
+	if last := bot.Status().LastPost; last == nil || last.Trigger != TriggerOccasion {
+		t.Errorf("Status().LastPost = %+v, want an occasion post", last)
+	}
+	fake.BlockUntil(2)
+	if errs := bot.Status().RecentErrors; len(errs) != 1 {
+		t.Errorf("RecentErrors = %+v, want the missing quote", errs)
+	}
+}
+
+func TestBot_ReloadQuotes(t *testing.T) {
+	tests := []struct {
+		name      string
+		reloadErr error
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name:      "正常系: 再読み込みした件数を返す",
+			wantCount: 1,
+			wantErr:   false,
+		},
+		{
+			name:      "異常系: 再読み込みの失敗はエラーとして記録される",
+			reloadErr: errors.New("名言ファイルのオープンに失敗しました"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot := newTestBot(&mockPoster{}, time.Hour)
+			bot.quotes.(*mockQuoteSource).reloadErr = tt.reloadErr
+
+			count, err := bot.ReloadQuotes()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReloadQuotes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if count != tt.wantCount {
+				t.Errorf("ReloadQuotes() = %d, want %d", count, tt.wantCount)
+			}
+			if tt.wantErr && len(bot.Status().RecentErrors) != 1 {
+				t.Errorf("RecentErrors = %v, want 1 entry", bot.Status().RecentErrors)
+			}
+		})
+	}
+}
+
+func TestBot_StatusTokenExpiry(t *testing.T) {
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+
+	// 有効期限を返せない投稿先では含まれない
+	if got := NewBot(cfg, quotes, &mockPoster{}).Status().TokenExpiresAt; got != nil {
+		t.Errorf("Status().TokenExpiresAt = %v, want nil", got)
+	}
+
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	got := NewBot(cfg, quotes, &expiringPoster{expiry: expiry}).Status().TokenExpiresAt
+	if got == nil || !got.Equal(expiry) {
+		t.Errorf("Status().TokenExpiresAt = %v, want %v", got, expiry)
+	}
+}
+
+func TestBot_StatusStages(t *testing.T) {
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, &mockPoster{})
+	if stages := bot.Status().Stages; stages != nil {
+		t.Fatalf("Status().Stages = %v, want nil before any post", stages)
+	}
+
+	bot.PostNow(context.Background())
+	bot.PostNow(context.Background())
+	stages := bot.Status().Stages
+	for _, label := range []string{"select", "format", "validate", "publish:bluesky", "record"} {
+		if stages[label].Count != 2 {
+			t.Errorf("Status().Stages[%s].Count = %d, want 2", label, stages[label].Count)
+		}
+	}
+	if _, ok := stages["publish"]; ok {
+		t.Errorf("Status().Stages has publish without the platform")
+	}
+}
+
+func TestBot_StatusRecentPosts(t *testing.T) {
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, &mockPoster{})
+	var last *PostResult
+	for i := 0; i < maxRecentPosts+2; i++ {
+		last, _ = bot.PostNow(context.Background())
+	}
+
+	posts := bot.Status().RecentPosts
+	if len(posts) != maxRecentPosts || posts[len(posts)-1].RequestID != last.RequestID {
+		t.Errorf("Status().RecentPosts = %d posts ending with %+v, want the latest %d", len(posts), posts[len(posts)-1], maxRecentPosts)
+	}
+}
+
+// mockRecorder は記録された履歴を保持します
+type mockRecorder struct {
+	entries []history.Entry
+	err     error
+}
+
+func (m *mockRecorder) Record(entry history.Entry) error {
+	m.entries = append(m.entries, entry)
+	return m.err
+}
+
+func TestBot_History(t *testing.T) {
+	tests := []struct {
+		name       string
+		quotes     []domain.Quote
+		postErr    error
+		recordErr  error
+		wantResult string
+		wantURI    string
+		wantQuote  history.Quote
+		wantError  string
+	}{
+		{
+			name:       "正常系: 成功した投稿のURIが記録される",
+			quotes:     []domain.Quote{{Text: "テスト名言", Author: "著者"}},
+			wantResult: history.ResultSuccess,
+			wantURI:    "at://did:plc:test/app.bsky.feed.post/1",
+			wantQuote:  history.Quote{ID: domain.ContentID("テスト名言", "著者"), Text: "テスト名言", Author: "著者"},
+		},
+		{
+			name:       "異常系: 失敗した投稿はマスクしたエラーとともに記録される",
+			quotes:     []domain.Quote{{Text: "テスト名言", Author: "著者"}},
+			postErr:    errors.New("failed with Bearer secret-token"),
+			wantResult: history.ResultFailure,
+			wantQuote:  history.Quote{ID: domain.ContentID("テスト名言", "著者"), Text: "テスト名言", Author: "著者"},
+			wantError:  "failed with Bearer [REDACTED]",
+		},
+		{
+			name:       "異常系: 名言を選べなかった場合も記録される",
+			wantResult: history.ResultFailure,
+			wantError:  "利用可能な名言がありません",
+		},
+		{
+			name:       "異常系: 履歴の記録に失敗しても投稿は成功する",
+			quotes:     []domain.Quote{{Text: "テスト名言", Author: "著者"}},
+			recordErr:  errors.New("disk full"),
+			wantResult: history.ResultSuccess,
+			wantURI:    "at://did:plc:test/app.bsky.feed.post/1",
+			wantQuote:  history.Quote{ID: domain.ContentID("テスト名言", "著者"), Text: "テスト名言", Author: "著者"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+			recorder := &mockRecorder{err: tt.recordErr}
+			bot := NewBot(cfg, &mockQuoteSource{quotes: tt.quotes}, &mockPoster{err: tt.postErr}, WithHistory(recorder))
+
+			result, err := bot.PostNow(context.Background())
+			if (err != nil) != (tt.wantError != "") {
+				t.Errorf("PostNow() error = %v, wantErr %v", err, tt.wantError != "")
+			}
+			if result.URI != tt.wantURI {
+				t.Errorf("PostNow().URI = %q, want %q", result.URI, tt.wantURI)
+			}
+			if result.QuoteID != tt.wantQuote.ID {
+				t.Errorf("PostNow().QuoteID = %q, want %q", result.QuoteID, tt.wantQuote.ID)
+			}
+
+			if len(recorder.entries) != 1 {
+				t.Fatalf("len(entries) = %d, want 1", len(recorder.entries))
+			}
+			entry := recorder.entries[0]
+			if entry.Result != tt.wantResult || entry.URI != tt.wantURI || entry.Quote != tt.wantQuote || entry.Error != tt.wantError {
+				t.Errorf("entry = %+v", entry)
+			}
+			if entry.RequestID != result.RequestID || entry.Trigger != TriggerManual || entry.Platform != domain.PlatformBluesky {
+				t.Errorf("entry = %+v, want request ID %s", entry, result.RequestID)
+			}
+			if entry.PostID == "" || entry.PostID != result.PostID {
+				t.Errorf("entry.PostID = %q, want %q", entry.PostID, result.PostID)
+			}
+			if wantPosts := tt.wantURI != ""; wantPosts != (len(entry.Posts) == 1 && entry.Posts[0].URI == tt.wantURI) {
+				t.Errorf("entry.Posts = %+v, want the post %q", entry.Posts, tt.wantURI)
+			}
+		})
+	}
+}
+
+// blockingPoster は release が閉じられるか ctx がキャンセルされるまで投稿を完了しません
+type blockingPoster struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingPoster) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	close(m.started)
+	select {
+	case <-m.release:
+		return &domain.PostRef{Platform: domain.PlatformBluesky, URI: "at://did:plc:test/app.bsky.feed.post/1"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestBot_Shutdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		release   bool
+		wantErr   bool
+		wantPosts bool
+	}{
+		{
+			name:      "正常系: 実行中の投稿の完了を待つ",
+			release:   true,
+			wantErr:   false,
+			wantPosts: true,
+		},
+		{
+			name:      "異常系: 期限を過ぎると実行中の投稿を中断する",
+			release:   false,
+			wantErr:   true,
+			wantPosts: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Minute}
+			quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+			poster := &blockingPoster{started: make(chan struct{}), release: make(chan struct{})}
+			bot := NewBot(cfg, quotes, poster)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				bot.Run(ctx)
+				close(done)
+			}()
+
+			// 初回投稿の実行中にシャットダウンを開始する
+			<-poster.started
+			cancel()
+			if tt.release {
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					close(poster.release)
+				}()
+			}
+
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer shutdownCancel()
+			if err := bot.Shutdown(shutdownCtx); (err != nil) != tt.wantErr {
+				t.Errorf("Shutdown() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("Run() did not return after Shutdown")
+			}
+
+			lastPost := bot.Status().LastPost
+			if lastPost == nil || (lastPost.Error == "") != tt.wantPosts {
+				t.Errorf("Status().LastPost = %+v, want success %v", lastPost, tt.wantPosts)
+			}
+
+			// シャットダウン後は投稿を受け付けない
+			if _, err := bot.PostNow(context.Background()); err == nil || err.Error() != ErrShuttingDown.Error() {
+				t.Errorf("PostNow() after Shutdown error = %v, want %v", err, ErrShuttingDown)
+			}
+		})
+	}
+}
+
+func TestBot_SetSchedule(t *testing.T) {
+	poster := &mockPoster{}
+	bot := newTestBot(poster, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		bot.Run(ctx)
+		close(done)
+	}()
+
+	// 初回投稿の後は1時間待つが、間隔を短くするとすぐに次の投稿が行われる
+	time.Sleep(20 * time.Millisecond)
+	bot.SetSchedule(20*time.Millisecond, time.Second)
+	time.Sleep(70 * time.Millisecond)
+	if poster.count() < 3 {
+		t.Errorf("posts = %d, want at least 3", poster.count())
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+}
+
+func TestBot_DryRun(t *testing.T) {
+	poster := &mockPoster{}
+	recorder := &mockRecorder{}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second, DryRun: true}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, poster, WithHistory(recorder))
+
+	result, err := bot.PostNow(context.Background())
+	if err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+	if !result.DryRun || result.Text == "" || result.URI != "" {
+		t.Errorf("PostNow() = %+v, want a dry run result with text", result)
+	}
+	if poster.count() != 0 {
+		t.Errorf("posts = %d, want 0", poster.count())
+	}
+	if len(recorder.entries) != 0 {
+		t.Errorf("history entries = %d, want 0", len(recorder.entries))
+	}
+	if !bot.Status().DryRun {
+		t.Errorf("Status().DryRun = false, want true")
+	}
+}
+
+// mastodonPoster はMastodonの制約を返す投稿先です
+type mastodonPoster struct {
+	mockPoster
+}
+
+func (p *mastodonPoster) Capabilities() domain.Capabilities {
+	return domain.MastodonCapabilities
+}
+
+func TestBot_FormatForPlatform(t *testing.T) {
+	formatter, err := domain.NewFormatter("{{.Text}}", map[string]string{domain.PlatformMastodon: "{{.Text}} — {{.Author}}"})
+	if err != nil {
+		t.Fatalf("NewFormatter() error = %v", err)
+	}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	poster := &mastodonPoster{}
+	bot := NewBot(cfg, quotes, poster, WithFormatter(formatter))
+
+	result, err := bot.PostNow(context.Background())
+	if err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+	if want := "テスト名言 — 著者"; result.Text != want {
+		t.Errorf("Text = %q, want %q", result.Text, want)
+	}
+}
+
+// crossPoster はほかの投稿先（CROSS_POST_PLUGINS）の代わりに、caps の投稿先として投稿を記録します
+type crossPoster struct {
+	mockPoster
+	caps domain.Capabilities
+}
+
+func (p *crossPoster) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	_, err := p.mockPoster.Publish(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.PostRef{Platform: p.caps.Platform, ID: "42", URI: "https://example.social/@quotes/42"}, nil
+}
+
+func (p *crossPoster) Capabilities() domain.Capabilities {
+	return p.caps
+}
+
+func TestBot_CrossPost(t *testing.T) {
+	tests := []struct {
+		name      string
+		postErr   error
+		crossErr  error
+		maxLength int
+		wantPosts []history.Post
+		wantCross int
+	}{
+		{
+			name: "正常系: 投稿先ごとの投稿を1つのレコードに記録する",
+			wantPosts: []history.Post{
+				{Platform: domain.PlatformBluesky, URI: "at://did:plc:test/app.bsky.feed.post/1", CID: "bafytest"},
+				{Platform: domain.PlatformMastodon, ID: "42", URI: "https://example.social/@quotes/42"},
+			},
+			wantCross: 1,
+		},
+		{
+			name:     "異常系: ほかの投稿先に投稿できなくても投稿は成功し、エラーを記録する",
+			crossErr: errors.New("503 Service Unavailable"),
+			wantPosts: []history.Post{
+				{Platform: domain.PlatformBluesky, URI: "at://did:plc:test/app.bsky.feed.post/1", CID: "bafytest"},
+				{Platform: domain.PlatformMastodon, Error: "503 Service Unavailable"},
+			},
+		},
+		{
+			name:      "異常系: ほかの投稿先の上限を超える本文は投稿しない",
+			maxLength: 3,
+			wantPosts: []history.Post{
+				{Platform: domain.PlatformBluesky, URI: "at://did:plc:test/app.bsky.feed.post/1", CID: "bafytest"},
+				{Platform: domain.PlatformMastodon, Error: "投稿の本文が長すぎます（10文字、上限は3文字）"},
+			},
+		},
+		{
+			name:    "異常系: 投稿先に投稿できなければほかの投稿先には投稿しない",
+			postErr: errors.New("503 Service Unavailable"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caps := domain.MastodonCapabilities
+			if tt.maxLength > 0 {
+				caps.MaxLength = tt.maxLength
+			}
+			cross := &crossPoster{mockPoster: mockPoster{err: tt.crossErr}, caps: caps}
+			recorder := &mockRecorder{}
+			bot := newTestBot(&mockPoster{err: tt.postErr}, time.Hour, WithHistory(recorder), WithCrossPosters(cross))
+
+			if _, err := bot.PostNow(context.Background()); (err != nil) != (tt.postErr != nil) {
+				t.Fatalf("PostNow() error = %v, wantErr %v", err, tt.postErr != nil)
+			}
+			if cross.count() != tt.wantCross {
+				t.Errorf("cross posts = %d, want %d", cross.count(), tt.wantCross)
+			}
+			if len(recorder.entries) != 1 {
+				t.Fatalf("len(entries) = %d, want 1", len(recorder.entries))
+			}
+			if got := recorder.entries[0].Posts; !reflect.DeepEqual(got, tt.wantPosts) {
+				t.Errorf("entry.Posts = %+v, want %+v", got, tt.wantPosts)
+			}
+		})
+	}
+}
+
+// slowCrossPoster は delay だけかけて投稿するほかの投稿先で、同時に投稿している数の最大を記録します
+type slowCrossPoster struct {
+	platform  string
+	delay     time.Duration
+	active    *atomic.Int32
+	maxActive *atomic.Int32
+}
+
+func (p *slowCrossPoster) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		max := p.maxActive.Load()
+		if n <= max || p.maxActive.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(p.delay):
+		return &domain.PostRef{Platform: p.platform, URI: "https://" + p.platform + ".example/1"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *slowCrossPoster) Capabilities() domain.Capabilities {
+	return domain.Capabilities{Platform: p.platform}
+}
+
+func TestBot_CrossPostConcurrency(t *testing.T) {
+	var active, maxActive atomic.Int32
+	newPoster := func(platform string, delay time.Duration) CrossPoster {
+		return &slowCrossPoster{platform: platform, delay: delay, active: &active, maxActive: &maxActive}
+	}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: 5 * time.Second, CrossPostConcurrency: 2, CrossPostTimeout: 200 * time.Millisecond}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, &mockPoster{}, WithCrossPosters(
+		newPoster("a", 50*time.Millisecond),
+		newPoster("hang", time.Hour),
+		newPoster("b", 50*time.Millisecond),
+		newPoster("c", 50*time.Millisecond),
+		newPoster("stuck", time.Hour),
+	))
+
+	start := time.Now()
+	posts, err := bot.crossPost(context.Background(), "テスト名言")
+	elapsed := time.Since(start)
+
+	// 正常系: 同時に投稿するのは CROSS_POST_CONCURRENCY まで
+	if got := maxActive.Load(); got != 2 {
+		t.Errorf("max concurrent posts = %d, want 2", got)
+	}
+	// 正常系: 応答しない投稿先は CROSS_POST_TIMEOUT で打ち切り、ほかの投稿先を待たせない
+	if elapsed > 2*time.Second {
+		t.Errorf("crossPost() took %v, want the slow targets to time out", elapsed)
+	}
+	var failed []string
+	for i, post := range posts {
+		if post.Error != "" {
+			failed = append(failed, post.Platform)
+		} else if post.URI == "" {
+			t.Errorf("posts[%d] = %+v, want a URI", i, post)
+		}
+	}
+	if want := []string{"hang", "stuck"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed targets = %v, want %v", failed, want)
+	}
+	// 異常系: 失敗した投稿先のエラーをまとめて返す
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "hang:") || !strings.Contains(err.Error(), "stuck:") {
+		t.Errorf("crossPost() error = %v, want both timeouts", err)
+	}
+}
+
+func TestBot_Variants(t *testing.T) {
+	newFormatter := func(text string) *domain.Formatter {
+		f, err := domain.NewFormatter(text, nil)
+		if err != nil {
+			t.Fatalf("NewFormatter() error = %v", err)
+		}
+		return f
+	}
+	// 重みが0の形式は選ばれない
+	variants, err := domain.NewVariants([]domain.Variant{
+		{Name: "plain", Weight: 0, Formatter: newFormatter("{{.Text}}")},
+		{Name: "quoted", Weight: 1, Formatter: newFormatter("「{{.Text}}」")},
+	})
+	if err != nil {
+		t.Fatalf("NewVariants() error = %v", err)
+	}
+	recorder := &mockRecorder{}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, &mockPoster{}, WithHistory(recorder), WithVariants(variants))
+
+	result, err := bot.PostNow(context.Background())
+	if err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+	if result.Text != "「テスト名言」" || result.Variant != "quoted" {
+		t.Errorf("PostNow() = %+v, want the quoted variant", result)
+	}
+	if len(recorder.entries) != 1 || recorder.entries[0].Variant != "quoted" {
+		t.Errorf("history entries = %+v, want variant quoted", recorder.entries)
+	}
+}
+
+func TestBot_Hooks(t *testing.T) {
+	hooks := usecase.NewHooks()
+	hooks.Before(usecase.StagePublish, func(ctx context.Context, pc *usecase.PostContext) error {
+		if pc.RequestID == "" || pc.Trigger != TriggerManual {
+			t.Errorf("PostContext = %+v, want request ID and trigger", pc)
+		}
+		return errors.New("禁止語が含まれています")
+	})
+	poster := &mockPoster{}
+	recorder := &mockRecorder{}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, poster, WithHistory(recorder), WithHooks(hooks))
+
+	result, err := bot.PostNow(context.Background())
+	if err == nil || !strings.Contains(result.Error, "禁止語") {
+		t.Errorf("PostNow() = %+v, %v, want the hook error", result, err)
+	}
+	if poster.count() != 0 {
+		t.Errorf("posts = %d, want 0", poster.count())
+	}
+	if len(recorder.entries) != 1 || recorder.entries[0].Result != history.ResultFailure {
+		t.Errorf("history entries = %+v, want one failure", recorder.entries)
+	}
+}
+
+// sequenceQuoteSource は名言を順番に返します
+type sequenceQuoteSource struct {
+	mockQuoteSource
+	next int
+}
+
+func (m *sequenceQuoteSource) PostRandomQuote(ctx context.Context) (*domain.Quote, error) {
+	q := &m.quotes[m.next%len(m.quotes)]
+	m.next++
+	return q, nil
+}
+
+func TestBot_DenyList(t *testing.T) {
+	denyList, err := domain.NewDenyList([]string{"禁止"}, nil)
+	if err != nil {
+		t.Fatalf("NewDenyList() error = %v", err)
+	}
+	quotes := []domain.Quote{{Text: "禁止された名言", Author: "著者"}, {Text: "普通の名言", Author: "著者"}}
+
+	tests := []struct {
+		name        string
+		action      string
+		quotes      []domain.Quote
+		wantErr     bool
+		wantPost    string
+		wantResults []string
+		wantFlags   []string
+	}{
+		{
+			name:        "正常系: skip では別の名言を選び直す",
+			action:      domain.DenyActionSkip,
+			quotes:      quotes,
+			wantPost:    "普通の名言",
+			wantResults: []string{history.ResultBlocked, history.ResultSuccess},
+		},
+		{
+			name:        "正常系: flag では投稿して印を付ける",
+			action:      domain.DenyActionFlag,
+			quotes:      quotes,
+			wantPost:    "禁止された名言",
+			wantResults: []string{history.ResultSuccess},
+			wantFlags:   []string{"deny:禁止"},
+		},
+		{
+			name:    "異常系: すべての名言が禁止語を含む",
+			action:  domain.DenyActionSkip,
+			quotes:  quotes[:1],
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poster := &mockPoster{}
+			recorder := &mockRecorder{}
+			cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+			source := &sequenceQuoteSource{mockQuoteSource: mockQuoteSource{quotes: tt.quotes}}
+			bot := NewBot(cfg, source, poster, WithHistory(recorder), WithDenyList(denyList, tt.action))
+
+			_, err := bot.PostNow(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PostNow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if poster.count() != 0 {
+					t.Errorf("posts = %d, want 0", poster.count())
+				}
+				return
+			}
+			if poster.count() != 1 || !strings.Contains(poster.messages[0], tt.wantPost) {
+				t.Errorf("posts = %v, want %q", poster.messages, tt.wantPost)
+			}
+			if len(recorder.entries) != len(tt.wantResults) {
+				t.Fatalf("history entries = %+v, want results %v", recorder.entries, tt.wantResults)
+			}
+			for i, want := range tt.wantResults {
+				if recorder.entries[i].Result != want {
+					t.Errorf("entries[%d].Result = %s, want %s", i, recorder.entries[i].Result, want)
+				}
+			}
+			last := recorder.entries[len(recorder.entries)-1]
+			if !reflect.DeepEqual(last.Flags, tt.wantFlags) {
+				t.Errorf("Flags = %v, want %v", last.Flags, tt.wantFlags)
+			}
+		})
+	}
+}
+
+func TestBot_Approvals(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	poster := &mockPoster{}
+	recorder := &mockRecorder{}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, poster, WithHistory(recorder), WithApprovals(approval.NewQueue(store, time.Hour)))
+
+	// 投稿せずに承認待ちに入る
+	result, err := bot.PostNow(context.Background())
+	if err != nil || result.ApprovalID == "" {
+		t.Fatalf("PostNow() = %+v, %v, want an approval ID", result, err)
+	}
+	if poster.count() != 0 || len(recorder.entries) != 0 {
+		t.Fatalf("posts = %d, history = %d, want nothing before approval", poster.count(), len(recorder.entries))
+	}
+	items, err := bot.Approvals()
+	if err != nil || len(items) != 1 || items[0].Text != "テスト名言\n- 著者" {
+		t.Fatalf("Approvals() = %+v, %v, want the queued post", items, err)
+	}
+
+	// 承認すると承認待ちに入れた本文を投稿する
+	result, err = bot.Approve(context.Background(), result.ApprovalID)
+	if err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if result.Trigger != TriggerApproval || result.URI == "" || poster.count() != 1 || poster.messages[0] != items[0].Text {
+		t.Errorf("Approve() = %+v, posts = %v", result, poster.messages)
+	}
+	if len(recorder.entries) != 1 || recorder.entries[0].Result != history.ResultSuccess {
+		t.Errorf("history entries = %+v, want one success", recorder.entries)
+	}
+	if _, err := bot.Approve(context.Background(), items[0].ID); !errors.Is(err, approval.ErrNotFound) {
+		t.Errorf("Approve() twice error = %v, want ErrNotFound", err)
+	}
+
+	// 却下した投稿は投稿しない
+	result, _ = bot.PostNow(context.Background())
+	if err := bot.Reject(result.ApprovalID); err != nil {
+		t.Fatalf("Reject() error = %v", err)
+	}
+	if items, _ := bot.Approvals(); len(items) != 0 || poster.count() != 1 {
+		t.Errorf("Approvals() = %+v, posts = %d, want nothing after reject", items, poster.count())
+	}
+}
+
+// idempotentPoster は冪等キーで投稿した投稿を覚えている投稿先です
+type idempotentPoster struct {
+	mockPoster
+	posted map[string]*domain.PostRef
+}
+
+func (m *idempotentPoster) PublishOnce(ctx context.Context, message, key string) (*domain.PostRef, error) {
+	if ref, ok := m.posted[key]; ok {
+		return ref, nil
+	}
+	ref, err := m.Publish(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	m.posted[key] = ref
+	return ref, nil
+}
+
+func (m *idempotentPoster) FindPost(ctx context.Context, key string) (*domain.PostRef, error) {
+	return m.posted[key], nil
+}
+
+func TestBot_Outbox(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	box := outbox.New(store)
+	poster := &idempotentPoster{posted: map[string]*domain.PostRef{}}
+	recorder := &mockRecorder{}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, poster, WithHistory(recorder), WithOutbox(box))
+
+	// 投稿履歴に記録した後は Outbox に残らない
+	if _, err := bot.PostNow(context.Background()); err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+	if len(poster.posted) != 1 {
+		t.Errorf("posted = %d, want 1 post with an idempotency key", len(poster.posted))
+	}
+	if pending, _ := box.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %+v, want empty", pending)
+	}
+
+	// 前回の実行で送信中のまま止まった投稿を確かめる
+	sent := outbox.Entry{Key: "3kabcdefghij2", RequestID: "sent", Trigger: TriggerScheduled, Quote: quotes.quotes[0], Text: "送信済み"}
+	lost := outbox.Entry{Key: "3kabcdefghij3", RequestID: "lost", Trigger: TriggerScheduled, Quote: quotes.quotes[0], Text: "未送信"}
+	poster.posted[sent.Key] = &domain.PostRef{Platform: domain.PlatformBluesky, URI: "at://did:plc:test/app.bsky.feed.post/" + sent.Key}
+	for _, entry := range []outbox.Entry{sent, lost} {
+		if err := box.Begin(entry); err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+	}
+	recorder.entries = nil
+	bot.reconcileOutbox(context.Background())
+
+	if len(recorder.entries) != 2 {
+		t.Fatalf("history entries = %+v, want 2", recorder.entries)
+	}
+	if got := recorder.entries[0]; got.RequestID != "sent" || got.Result != history.ResultSuccess || got.URI == "" {
+		t.Errorf("entries[0] = %+v, want the sent post as a success", got)
+	}
+	if got := recorder.entries[1]; got.RequestID != "lost" || got.Result != history.ResultFailure {
+		t.Errorf("entries[1] = %+v, want the lost post as a failure", got)
+	}
+	if pending, _ := box.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %+v, want empty after reconciling", pending)
+	}
+	if poster.count() != 1 {
+		t.Errorf("posts = %d, want no new posts while reconciling", poster.count())
+	}
+}
+
+func TestBot_DeadLetters(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	poster := &idempotentPoster{posted: map[string]*domain.PostRef{}}
+	recorder := &mockRecorder{}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, poster, WithHistory(recorder), WithOutbox(outbox.New(store)), WithDeadLetters(deadletter.NewQueue(store, 10)))
+
+	// 投稿先に送って失敗した投稿は残る
+	poster.err = errors.New("503 Service Unavailable")
+	result, err := bot.PostNow(context.Background())
+	if err == nil || result.DeadLetterID == "" {
+		t.Fatalf("PostNow() = %+v, %v, want a dead letter ID", result, err)
+	}
+	items, err := bot.DeadLetters()
+	if err != nil || len(items) != 1 || items[0].Text != "テスト名言\n- 著者" || items[0].PostID != result.PostID {
+		t.Fatalf("DeadLetters() = %+v, %v, want the failed post", items, err)
+	}
+
+	// 送る前に失敗した投稿は残らない
+	poster.err = nil
+	empty := NewBot(cfg, &mockQuoteSource{}, poster, WithDeadLetters(deadletter.NewQueue(store, 10)))
+	if result, _ := empty.PostNow(context.Background()); result.DeadLetterID != "" {
+		t.Errorf("PostNow() = %+v, want no dead letter when no quote was selected", result)
+	}
+
+	// 送り直すと失敗したときの本文を投稿し、残っていた投稿は取り除かれる
+	result, err = bot.RetryDeadLetter(context.Background(), items[0].ID)
+	if err != nil {
+		t.Fatalf("RetryDeadLetter() error = %v", err)
+	}
+	if result.Trigger != TriggerRetry || result.URI == "" || poster.count() != 1 || poster.messages[0] != items[0].Text {
+		t.Errorf("RetryDeadLetter() = %+v, posts = %v", result, poster.messages)
+	}
+	if _, err := bot.RetryDeadLetter(context.Background(), items[0].ID); !errors.Is(err, deadletter.ErrNotFound) {
+		t.Errorf("RetryDeadLetter() twice error = %v, want ErrNotFound", err)
+	}
+
+	// 実は投稿できていた投稿は送り直さない
+	poster.err = errors.New("timeout")
+	result, _ = bot.PostNow(context.Background())
+	poster.err = nil
+	poster.posted[result.PostID] = &domain.PostRef{Platform: domain.PlatformBluesky, URI: "at://did:plc:test/app.bsky.feed.post/" + result.PostID}
+	recorder.entries = nil
+	retried, err := bot.RetryDeadLetter(context.Background(), result.DeadLetterID)
+	if err != nil || retried.URI != poster.posted[result.PostID].URI || poster.count() != 1 {
+		t.Errorf("RetryDeadLetter() = %+v, %v, posts = %d, want the existing post", retried, err, poster.count())
+	}
+	if len(recorder.entries) != 1 || recorder.entries[0].Result != history.ResultSuccess {
+		t.Errorf("history entries = %+v, want one success", recorder.entries)
+	}
+
+	// 破棄した投稿は送り直せない
+	poster.err = errors.New("503 Service Unavailable")
+	result, _ = bot.PostNow(context.Background())
+	if err := bot.DiscardDeadLetter(result.DeadLetterID); err != nil {
+		t.Fatalf("DiscardDeadLetter() error = %v", err)
+	}
+	if items, _ := bot.DeadLetters(); len(items) != 0 {
+		t.Errorf("DeadLetters() = %+v, want empty after discarding", items)
+	}
+}
+
+func TestBot_MaxPosts(t *testing.T) {
+	poster := &mockPoster{}
+	cfg := &config.Config{PostInterval: 10 * time.Millisecond, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	bot := NewBot(cfg, quotes, poster, WithMaxPosts(3))
+
+	done := make(chan struct{})
+	go func() {
+		bot.Run(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after the maximum number of posts")
+	}
+	if poster.count() != 3 {
+		t.Errorf("posts = %d, want 3", poster.count())
+	}
+}
+
+func TestBot_Scheduler(t *testing.T) {
+	poster := &mockPoster{}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}}
+	var calls []time.Time
+	scheduler := SchedulerFunc(func(last time.Time) time.Time {
+		calls = append(calls, last)
+		return last.Add(10 * time.Millisecond)
+	})
+	bot := NewBot(cfg, quotes, poster, WithScheduler(scheduler), WithMaxPosts(3))
+
+	done := make(chan struct{})
+	go func() {
+		bot.Run(context.Background())
+		close(done)
+	}()
+	// POST_INTERVAL は1時間だが、Scheduler の時刻で投稿する
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not post on the scheduler's times")
+	}
+	if poster.count() != 3 {
+		t.Errorf("posts = %d, want 3", poster.count())
+	}
+	if len(calls) != 3 {
+		t.Errorf("Next() calls = %d, want 3", len(calls))
+	}
+}
+
+func TestBot_Events(t *testing.T) {
+	tests := []struct {
+		name    string
+		postErr error
+		want    []events.Type
+	}{
+		{
+			name: "正常系: 名言を選んでから投稿に成功する",
+			want: []events.Type{events.QuoteSelected, events.PostSucceeded},
+		},
+		{
+			name:    "異常系: 投稿に失敗する",
+			postErr: errors.New("pds unavailable"),
+			want:    []events.Type{events.QuoteSelected, events.PostFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+			bus := events.NewBus()
+			var got []events.Event
+			bus.Handle(func(e events.Event) { got = append(got, e) })
+			bot := NewBot(cfg, &mockQuoteSource{quotes: []domain.Quote{{ID: "q1", Text: "テスト名言", Author: "著者"}}}, &mockPoster{err: tt.postErr}, WithEvents(bus))
+
+			result, _ := bot.PostNow(context.Background())
+			if len(got) != len(tt.want) {
+				t.Fatalf("events = %+v, want %v", got, tt.want)
+			}
+			for i, e := range got {
+				if e.Type != tt.want[i] || e.RequestID != result.RequestID || e.QuoteID != "q1" {
+					t.Errorf("events[%d] = %+v, want %s for request %s", i, e, tt.want[i], result.RequestID)
+				}
+			}
+			if tt.postErr != nil && got[1].Error == "" {
+				t.Errorf("post_failed event = %+v, want an error", got[1])
+			}
+			if counts := bot.Status().Events; counts[tt.want[1]] != 1 {
+				t.Errorf("Status().Events = %v, want one %s", counts, tt.want[1])
+			}
+		})
+	}
+}
+
+// replyPoster は返信と投稿の言語を記録する投稿先です
+type replyPoster struct {
+	mockPoster
+	langs   [][]string
+	replies []string
+	parents []domain.PostRef
+}
+
+func (m *replyPoster) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	m.langs = append(m.langs, usecase.LangsFromContext(ctx))
+	return m.mockPoster.Publish(ctx, message)
+}
+
+func (m *replyPoster) PublishReply(ctx context.Context, message string, root, parent domain.PostRef) (*domain.PostRef, error) {
+	m.langs = append(m.langs, usecase.LangsFromContext(ctx))
+	m.replies = append(m.replies, message)
+	m.parents = append(m.parents, parent)
+	return &domain.PostRef{URI: "at://did:plc:test/app.bsky.feed.post/reply"}, nil
+}
+
+func TestBot_Translation(t *testing.T) {
+	quote := domain.Quote{ID: "q1", Text: "知は力なり", Author: "ベーコン", Lang: "ja", Translations: map[string]string{"en": "Knowledge is power"}}
+
+	tests := []struct {
+		name        string
+		lang        string
+		mode        string
+		wantPost    string
+		wantReplies []string
+		wantLangs   [][]string
+	}{
+		{
+			name:      "正常系: 翻訳で投稿",
+			lang:      "en-US",
+			mode:      domain.TranslationModeReplace,
+			wantPost:  "Knowledge is power\n- ベーコン",
+			wantLangs: [][]string{{"en-US"}},
+		},
+		{
+			name:        "正常系: 原文を投稿して翻訳を返信",
+			lang:        "en",
+			mode:        domain.TranslationModeThread,
+			wantPost:    "知は力なり\n- ベーコン",
+			wantReplies: []string{"Knowledge is power\n- ベーコン"},
+			wantLangs:   [][]string{{"ja"}, {"en"}},
+		},
+		{
+			name:      "正常系: 翻訳がない言語は原文を投稿",
+			lang:      "fr",
+			mode:      domain.TranslationModeThread,
+			wantPost:  "知は力なり\n- ベーコン",
+			wantLangs: [][]string{{"ja"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poster := &replyPoster{}
+			cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+			bot := NewBot(cfg, &mockQuoteSource{quotes: []domain.Quote{quote}}, poster, WithTranslation(tt.lang, tt.mode))
+
+			result, err := bot.PostNow(context.Background())
+			if err != nil {
+				t.Fatalf("PostNow() error = %v", err)
+			}
+			if len(poster.messages) != 1 || poster.messages[0] != tt.wantPost {
+				t.Errorf("posts = %q, want %q", poster.messages, tt.wantPost)
+			}
+			if !reflect.DeepEqual(poster.replies, tt.wantReplies) {
+				t.Errorf("replies = %q, want %q", poster.replies, tt.wantReplies)
+			}
+			if len(poster.parents) > 0 && poster.parents[0].URI != result.URI {
+				t.Errorf("reply parent = %+v, want the post %s", poster.parents[0], result.URI)
+			}
+			if !reflect.DeepEqual(poster.langs, tt.wantLangs) {
+				t.Errorf("langs = %q, want %q", poster.langs, tt.wantLangs)
+			}
+			// 翻訳して投稿しても名言のIDは変わらない
+			if result.QuoteID != "q1" {
+				t.Errorf("QuoteID = %s, want q1", result.QuoteID)
+			}
+		})
+	}
+}
+
+// threadPoster はスレッドをまとめて投稿する投稿先です
+type threadPoster struct {
+	replyPoster
+	keys    []string
+	threads [][]usecase.ThreadPost
+}
+
+func (m *threadPoster) PublishThread(ctx context.Context, key string, posts []usecase.ThreadPost) ([]*domain.PostRef, error) {
+	m.keys = append(m.keys, key)
+	m.threads = append(m.threads, posts)
+	refs := make([]*domain.PostRef, len(posts))
+	for i := range posts {
+		refs[i] = &domain.PostRef{URI: fmt.Sprintf("at://did:plc:test/app.bsky.feed.post/%s-%d", key, i)}
+	}
+	return refs, nil
+}
+
+func TestBot_TranslationThread(t *testing.T) {
+	quote := domain.Quote{ID: "q1", Text: "知は力なり", Author: "ベーコン", Lang: "ja", Translations: map[string]string{"en": "Knowledge is power"}}
+	poster := &threadPoster{}
+	cfg := &config.Config{PostInterval: time.Hour, PostTimeout: time.Second}
+	bot := NewBot(cfg, &mockQuoteSource{quotes: []domain.Quote{quote}}, poster, WithTranslation("en", domain.TranslationModeThread))
+
+	result, err := bot.PostNow(context.Background())
+	if err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+	// 原文と翻訳の返信を1回でまとめて投稿し、別に返信はしない
+	want := [][]usecase.ThreadPost{{
+		{Text: "知は力なり\n- ベーコン", Langs: []string{"ja"}},
+		{Text: "Knowledge is power\n- ベーコン", Langs: []string{"en"}},
+	}}
+	if !reflect.DeepEqual(poster.threads, want) {
+		t.Errorf("threads = %+v, want %+v", poster.threads, want)
+	}
+	if len(poster.messages) != 0 || len(poster.replies) != 0 {
+		t.Errorf("posts = %q, replies = %q, want none outside the thread", poster.messages, poster.replies)
+	}
+	if len(poster.keys) != 1 || poster.keys[0] != result.PostID || !strings.HasSuffix(result.URI, "-0") {
+		t.Errorf("key = %v, URI = %s, want the post ID as the key and the first post as the result", poster.keys, result.URI)
+	}
+}
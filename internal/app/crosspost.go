@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// CrossPoster は投稿先に加えて同じ本文を投稿する、ほかの投稿先です（CROSS_POST_PLUGINS）
+type CrossPoster interface {
+	Poster
+	CapabilityProvider
+}
+
+// WithCrossPosters は投稿先に投稿できた後に、同じ本文を posters にも投稿します。
+// 投稿先ごとの投稿は、1つの投稿履歴のレコードの posts にまとめて記録します
+func WithCrossPosters(posters ...CrossPoster) Option {
+	return func(b *Bot) {
+		b.crossRepos = posters
+	}
+}
+
+// crossPostRepository は1回の投稿の間だけ使う投稿先で、投稿先に投稿できた後に同じ本文をほかの投稿先にも投稿します。
+// 投稿先には投稿済みのため、ほかの投稿先に投稿できなくても投稿は失敗にしません
+type crossPostRepository struct {
+	repo   usecase.PostRepository
+	result *PostResult
+	bot    *Bot
+}
+
+// newCrossPostRepository はほかの投稿先がある場合に、repo に投稿した後にほかの投稿先にも投稿する投稿先を返します
+func (b *Bot) newCrossPostRepository(repo usecase.PostRepository, result *PostResult) *crossPostRepository {
+	if len(b.crossRepos) == 0 {
+		return nil
+	}
+	return &crossPostRepository{repo: repo, result: result, bot: b}
+}
+
+func (r *crossPostRepository) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	ref, err := r.repo.Publish(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := r.bot.crossPost(ctx, message)
+	if err != nil {
+		r.bot.logger.Warn("ほかの投稿先への投稿に失敗しました", "request_id", repository.RequestIDFromContext(ctx), "error", redact.Error(err))
+	}
+	r.result.crossPosts = posts
+	return ref, nil
+}
+
+// crossPost は message をほかの投稿先に CROSS_POST_CONCURRENCY ずつ同時に投稿し、投稿先ごとの結果を CROSS_POST_PLUGINS の順に返します。
+// 遅い投稿先があってもほかの投稿先を待たせないよう、投稿先ごとに CROSS_POST_TIMEOUT の期限を設けます。
+// 投稿できなかった投稿先があれば、そのエラーをまとめて返します
+func (b *Bot) crossPost(ctx context.Context, message string) ([]history.Post, error) {
+	workers := b.crossWorkers
+	if workers <= 0 || workers > len(b.crossRepos) {
+		workers = len(b.crossRepos)
+	}
+	posts := make([]history.Post, len(b.crossRepos))
+	errs := make([]error, len(b.crossRepos))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, poster := range b.crossRepos {
+		caps := poster.Capabilities()
+		// パニックした場合もエラーとして記録されるよう、先に失敗として埋めておく
+		posts[i] = history.Post{Platform: caps.Platform, Error: "投稿中にパニックしました"}
+		errs[i] = fmt.Errorf("%s: %s", caps.Platform, posts[i].Error)
+
+		sem <- struct{}{}
+		wg.Add(1)
+		safego.Go(b.logger, "crosspost-"+caps.Platform, func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			posts[i], errs[i] = b.crossPostTo(ctx, poster, caps, message)
+		})
+	}
+	wg.Wait()
+	return posts, errors.Join(errs...)
+}
+
+// crossPostTo は message を poster に投稿します。投稿できなかった場合はエラーを入れた結果とそのエラーを返します
+func (b *Bot) crossPostTo(ctx context.Context, poster CrossPoster, caps domain.Capabilities, message string) (history.Post, error) {
+	if b.crossTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.crossTimeout)
+		defer cancel()
+	}
+	ref, err := publishCrossPost(ctx, poster, caps, message)
+	if err != nil {
+		return history.Post{Platform: caps.Platform, Error: redact.String(err.Error())}, fmt.Errorf("%s: %w", caps.Platform, err)
+	}
+	b.logger.Info("ほかの投稿先に投稿しました", "request_id", repository.RequestIDFromContext(ctx), "platform", caps.Platform, "uri", ref.URI)
+	return history.NewPost(ref), nil
+}
+
+// publishCrossPost は message が投稿先の上限に収まるかを確かめてから投稿します
+func publishCrossPost(ctx context.Context, poster CrossPoster, caps domain.Capabilities, message string) (*domain.PostRef, error) {
+	if err := caps.Validate(message); err != nil {
+		return nil, err
+	}
+	ref, err := poster.Publish(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Platform == "" {
+		ref.Platform = caps.Platform
+	}
+	if ref.URI == "" {
+		return nil, fmt.Errorf("%s の投稿先が投稿のURIを返しませんでした", caps.Platform)
+	}
+	return ref, nil
+}
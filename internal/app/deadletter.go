@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/littleironwaltz/quotebot/internal/approval"
+	"github.com/littleironwaltz/quotebot/internal/deadletter"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// WithDeadLetters は投稿先に送って失敗した投稿を queue に残し、RetryDeadLetter で送り直せるようにします
+func WithDeadLetters(queue *deadletter.Queue) Option {
+	return func(b *Bot) {
+		b.deadLetters = queue
+	}
+}
+
+// addDeadLetter は投稿先に送って失敗した投稿を残します。名言を選べなかった、本文が上限を超えたなど、
+// 送る前に失敗した投稿は送り直しても同じ結果になるため残しません
+func (b *Bot) addDeadLetter(pc *usecase.PostContext, result *PostResult) {
+	if b.deadLetters == nil || pc.Quote == nil {
+		return
+	}
+	if _, sent := pc.Durations[usecase.StagePublish]; !sent {
+		return
+	}
+	item, err := b.deadLetters.Add(deadletter.Item{
+		RequestID:  result.RequestID,
+		Trigger:    result.Trigger,
+		Quote:      *pc.Quote,
+		Text:       pc.Text,
+		Variant:    result.Variant,
+		PostID:     result.PostID,
+		Error:      result.Error,
+		ErrorClass: result.ErrorClass,
+		FailedAt:   b.clock.Now(),
+	})
+	if err != nil {
+		b.logger.Warn("失敗した投稿の保存に失敗しました", "request_id", result.RequestID, "error", err)
+		return
+	}
+	result.DeadLetterID = item.ID
+	b.logger.Info("失敗した投稿を送り直せるよう残しました", "request_id", result.RequestID, "dead_letter_id", item.ID)
+}
+
+// DeadLetters は送り直せる失敗した投稿を古い順に返します。残さない場合は空です
+func (b *Bot) DeadLetters() ([]deadletter.Item, error) {
+	if b.deadLetters == nil {
+		return nil, nil
+	}
+	return b.deadLetters.List()
+}
+
+// RetryDeadLetter は失敗した投稿を、失敗したときの本文のまま送り直します。また失敗すると新しいIDで残ります。
+// 投稿先が冪等キーで投稿を探せる場合は、実は投稿できていた投稿を送り直さずに投稿済みとして記録します
+func (b *Bot) RetryDeadLetter(ctx context.Context, id string) (*PostResult, error) {
+	if b.deadLetters == nil {
+		return nil, deadletter.ErrNotFound
+	}
+	items, err := b.deadLetters.List()
+	if err != nil {
+		return nil, err
+	}
+	var item *deadletter.Item
+	for i := range items {
+		if items[i].ID == id {
+			item = &items[i]
+		}
+	}
+	if item == nil {
+		return nil, fmt.Errorf("%w: %s", deadletter.ErrNotFound, id)
+	}
+
+	// 確かめられない場合は二重に投稿しないよう、送り直さずに残す
+	ref, err := b.findPosted(ctx, item.PostID)
+	if err != nil {
+		return nil, fmt.Errorf("失敗した投稿が投稿済みかを確かめられませんでした: %w", err)
+	}
+	if _, err := b.deadLetters.Take(id); err != nil {
+		return nil, err
+	}
+	if ref != nil {
+		result := &PostResult{At: b.clock.Now(), RequestID: item.RequestID, Trigger: TriggerRetry, Text: item.Text, Variant: item.Variant,
+			PostID: item.PostID, QuoteID: item.Quote.StableID(), URI: ref.URI, URL: ref.URL}
+		b.recordHistory(result, &item.Quote, ref)
+		b.logger.Info("失敗した投稿は投稿済みでした", "dead_letter_id", id, "uri", ref.URI)
+		return result, nil
+	}
+
+	b.logger.Info("失敗した投稿を送り直します", "dead_letter_id", id)
+	// 送り直す本文は、承認された投稿と同じように選び直さず整形もし直さない
+	result := b.post(ctx, TriggerRetry, &approval.Item{Quote: item.Quote, Text: item.Text, Variant: item.Variant}, nil)
+	if result.Error != "" {
+		return result, fmt.Errorf("%s", result.Error)
+	}
+	return result, nil
+}
+
+// DiscardDeadLetter は失敗した投稿を送り直さずに破棄します
+func (b *Bot) DiscardDeadLetter(id string) error {
+	if b.deadLetters == nil {
+		return deadletter.ErrNotFound
+	}
+	if _, err := b.deadLetters.Take(id); err != nil {
+		return err
+	}
+	b.logger.Info("失敗した投稿を破棄しました", "dead_letter_id", id)
+	return nil
+}
+
+// findPosted は冪等キーで投稿した投稿を探します。投稿先が探せない場合は nil を返します
+func (b *Bot) findPosted(ctx context.Context, key string) (*domain.PostRef, error) {
+	repo, ok := b.poster.(usecase.IdempotentPostRepository)
+	if !ok || key == "" {
+		return nil, nil
+	}
+	b.mu.Lock()
+	timeout := b.postTimeout
+	b.mu.Unlock()
+	findCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return repo.FindPost(findCtx, key)
+}
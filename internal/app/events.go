@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/notify"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// WithEvents は Bot がイベントを発行する Bus を設定します。指定しない場合は Bot ごとに作成します
+func WithEvents(bus *events.Bus) Option {
+	return func(b *Bot) {
+		b.events = bus
+	}
+}
+
+// Events は Bot がイベントを発行する Bus を返します
+func (b *Bot) Events() *events.Bus {
+	return b.events
+}
+
+// subscribeEvents は Bot が使う購読者を登録します
+func (b *Bot) subscribeEvents() {
+	b.events.Handle(b.counter.Handle)
+	b.events.Handle(b.logEvent)
+	b.events.Handle(b.haltOnSuspension)
+	if b.monitor != nil {
+		b.events.Handle(observeEvent(b.monitor))
+	}
+	// 禁止語で選び直した後の名言を知らせるため、他のフックの後に登録する
+	if b.hooks == nil {
+		b.hooks = usecase.NewHooks()
+	}
+	b.hooks.After(usecase.StageSelect, func(ctx context.Context, pc *usecase.PostContext) error {
+		b.events.Publish(events.Event{Type: events.QuoteSelected, RequestID: pc.RequestID, Trigger: pc.Trigger, QuoteID: pc.Quote.StableID()})
+		return nil
+	})
+}
+
+// resultEvent は投稿の結果のイベントを作ります
+func resultEvent(result *PostResult) events.Event {
+	event := events.Event{
+		Type:       events.PostSucceeded,
+		At:         result.At,
+		RequestID:  result.RequestID,
+		Trigger:    result.Trigger,
+		QuoteID:    result.QuoteID,
+		Text:       result.Text,
+		URI:        result.URI,
+		Variant:    result.Variant,
+		ApprovalID: result.ApprovalID,
+		Error:      result.Error,
+		ErrorClass: result.ErrorClass,
+	}
+	switch {
+	case result.Error != "":
+		event.Type = events.PostFailed
+	case result.ApprovalID != "":
+		event.Type = events.PostHeld
+	case result.DryRun:
+		event.Type = events.PostDryRun
+	}
+	return event
+}
+
+// logEvent は投稿の結果をログに出力します
+func (b *Bot) logEvent(event events.Event) {
+	switch event.Type {
+	case events.PostSucceeded:
+		b.logger.Info("メッセージの投稿に成功しました", "trigger", event.Trigger, "request_id", event.RequestID, "uri", event.URI)
+	case events.PostFailed:
+		b.logger.Error("メッセージの投稿に失敗しました", "trigger", event.Trigger, "request_id", event.RequestID, "error", event.Error, "error_class", event.ErrorClass)
+	case events.PostHeld:
+		b.logger.Info("投稿を承認待ちに入れました", "trigger", event.Trigger, "request_id", event.RequestID, "approval_id", event.ApprovalID)
+	case events.PostDryRun:
+		b.logger.Info("DRY_RUN のため投稿しませんでした", "trigger", event.Trigger, "request_id", event.RequestID, "text", event.Text)
+	case events.PostBackoff:
+		b.logger.Warn("投稿が続けて失敗したため投稿間隔を延ばしました", "failures", event.Failures, "interval", event.Interval, "error_class", event.ErrorClass)
+	}
+}
+
+// haltOnSuspension は投稿先のアカウントが停止されたことが分かると、再試行を続けずに定期投稿を止めます
+func (b *Bot) haltOnSuspension(event events.Event) {
+	switch {
+	case event.ErrorClass == string(domain.ErrorClassAccountSuspended):
+	case event.Type == events.SessionStateChanged && event.SessionState == string(repository.SessionSuspended):
+	default:
+		return
+	}
+	reason := event.Error
+	if reason == "" {
+		reason = domain.ErrAccountSuspended.Error()
+	}
+	b.halt(reason)
+}
+
+// observeEvent は投稿とトークンのリフレッシュの結果を Monitor に渡し、続けて失敗したときに通知します
+func observeEvent(monitor *notify.Monitor) events.Handler {
+	return func(event events.Event) {
+		switch event.Type {
+		case events.PostSucceeded, events.PostHeld, events.PostDryRun:
+			monitor.Observe(notify.SourcePost, nil)
+		case events.PostFailed:
+			monitor.Observe(notify.SourcePost, eventError(event))
+			if event.ErrorClass == string(domain.ErrorClassAccountSuspended) {
+				monitor.Escalate(notify.SourcePost, eventError(event))
+			}
+		case events.PostBackoff:
+			// ALERT_THRESHOLD がより大きくても、投稿間隔を延ばしたことは知らせる
+			monitor.Escalate(notify.SourcePost, eventError(event))
+		case events.TokenRefreshed:
+			monitor.Observe(notify.SourceTokenRefresh, nil)
+		case events.TokenRefreshFailed:
+			monitor.Observe(notify.SourceTokenRefresh, eventError(event))
+		case events.SessionStateChanged:
+			// リフレッシュトークンが使えなくなると、ボットはログインし直すまで投稿できない
+			if event.SessionState == string(repository.SessionReloginRequired) {
+				monitor.Escalate(notify.SourceTokenRefresh, eventError(event))
+			}
+			// アカウントが停止されると、運用者が対処するまで投稿できない
+			if event.SessionState == string(repository.SessionSuspended) {
+				event.ErrorClass = string(domain.ErrorClassAccountSuspended)
+				monitor.Escalate(notify.SourcePost, eventError(event))
+			}
+		}
+	}
+}
+
+// eventError はイベントのエラーを、分類を保ったエラーに戻します
+func eventError(event events.Event) error {
+	return &domain.ClassifiedError{Message: event.Error, Class: domain.ErrorClass(event.ErrorClass)}
+}
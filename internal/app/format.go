@@ -0,0 +1,89 @@
+package app
+
+import (
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// NewFormatter は POST_TEMPLATE と投稿先ごとのテンプレート、表記、装飾、ハッシュタグの設定から Formatter を作成します
+func NewFormatter(cfg *config.Config) (*domain.Formatter, error) {
+	return newFormatter(cfg, config.FormatVariant{Hashtags: true})
+}
+
+// ContentOptions は投稿の内容についての設定（テンプレート、A/Bテストの投稿形式、禁止語、翻訳）から Bot の Option を作成します
+func ContentOptions(cfg *config.Config) ([]Option, error) {
+	formatter, err := NewFormatter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts := []Option{WithFormatter(formatter)}
+	variants, err := NewVariants(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if variants != nil {
+		opts = append(opts, WithVariants(variants))
+	}
+	denyList, err := domain.NewDenyList(cfg.DenyWords, cfg.DenyPatterns)
+	if err != nil {
+		return nil, err
+	}
+	if !denyList.Empty() {
+		opts = append(opts, WithDenyList(denyList, cfg.DenyAction))
+	}
+	if cfg.PostLang != "" {
+		opts = append(opts, WithTranslation(cfg.PostLang, cfg.TranslationMode))
+	}
+	return opts, nil
+}
+
+// NewVariants は設定ファイルの variants から、A/Bテストで比べる投稿形式を作成します。
+// variants がない場合は nil を返します
+func NewVariants(cfg *config.Config) (*domain.Variants, error) {
+	if len(cfg.FormatVariants) == 0 {
+		return nil, nil
+	}
+	variants := make([]domain.Variant, 0, len(cfg.FormatVariants))
+	for _, v := range cfg.FormatVariants {
+		formatter, err := newFormatter(cfg, v)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, domain.Variant{Name: v.Name, Weight: v.Weight, Formatter: formatter})
+	}
+	return domain.NewVariants(variants)
+}
+
+// newFormatter は variant のテンプレートとハッシュタグの有無で設定を変えた Formatter を作成します
+func newFormatter(cfg *config.Config, variant config.FormatVariant) (*domain.Formatter, error) {
+	typography, err := domain.NewTypography(cfg.AttributionSeparator, cfg.SmartQuotes, cfg.Ellipsis)
+	if err != nil {
+		return nil, err
+	}
+	opts := []domain.FormatterOption{domain.WithTypography(typography)}
+	if cfg.Decoration != "" || len(cfg.DecorationWeekdays) > 0 || len(cfg.DecorationDates) > 0 {
+		decorations, err := domain.NewDecorations(cfg.Decoration, cfg.DecorationWeekdays, cfg.DecorationDates, cfg.DecorationPosition)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, domain.WithDecorations(decorations))
+	}
+	if variant.Hashtags && (len(cfg.Hashtags) > 0 || len(cfg.HashtagMap) > 0) {
+		mode := cfg.HashtagMode
+		if mode == "" {
+			mode = domain.HashtagModeFixed
+		}
+		hashtags, err := domain.NewHashtags(mode, cfg.Hashtags, cfg.HashtagsPerPost, cfg.HashtagMap)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, domain.WithHashtags(hashtags))
+	}
+
+	if variant.Template != "" {
+		return domain.NewFormatter(variant.Template, nil, opts...)
+	}
+	return domain.NewFormatter(cfg.PostTemplate, map[string]string{
+		domain.PlatformBluesky: cfg.PostTemplateBluesky,
+	}, opts...)
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/outbox"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// WithOutbox は送信する前の投稿を Outbox に書いてから冪等キーで投稿し、
+// 起動したときに前回の実行で送信中だった投稿が投稿済みかを確かめます。
+// Poster が usecase.IdempotentPostRepository を実装していない場合は使いません
+func WithOutbox(o *outbox.Outbox) Option {
+	return func(b *Bot) {
+		b.outbox = o
+	}
+}
+
+// onceRepository は冪等キーで投稿できる投稿先です
+type onceRepository interface {
+	PublishOnce(ctx context.Context, message, key string) (*domain.PostRef, error)
+}
+
+// outboxRepository は1回の投稿の間だけ使う投稿先で、送信する前に投稿を Outbox に書きます
+type outboxRepository struct {
+	outbox *outbox.Outbox
+	repo   onceRepository // 翻訳をスレッドで投稿する場合は threadRepository
+	pc     *usecase.PostContext
+	result *PostResult
+	clock  clock.Clock
+	key    string // 送信を始めた投稿の冪等キー
+}
+
+// newOutboxRepository は Outbox を使う場合に、この投稿で使う投稿先を返します
+func (b *Bot) newOutboxRepository(pc *usecase.PostContext, result *PostResult) *outboxRepository {
+	if b.outbox == nil {
+		return nil
+	}
+	repo, ok := b.poster.(usecase.IdempotentPostRepository)
+	if !ok {
+		return nil
+	}
+	return &outboxRepository{outbox: b.outbox, repo: repo, pc: pc, result: result, clock: b.clock}
+}
+
+func (r *outboxRepository) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	now := r.clock.Now()
+	entry := outbox.Entry{
+		Key:       r.result.PostID,
+		RequestID: r.pc.RequestID,
+		Trigger:   r.pc.Trigger,
+		Platform:  r.pc.Capabilities.Platform,
+		Quote:     *r.pc.Quote,
+		Text:      message,
+		Variant:   r.result.Variant,
+		CreatedAt: now,
+	}
+	if err := r.outbox.Begin(entry); err != nil {
+		return nil, err
+	}
+	r.key = entry.Key
+	return r.repo.PublishOnce(ctx, message, entry.Key)
+}
+
+// done は投稿の結果を投稿履歴に記録した後に、投稿を Outbox から取り除きます
+func (r *outboxRepository) done(b *Bot) {
+	if r == nil || r.key == "" {
+		return
+	}
+	if err := r.outbox.Done(r.key); err != nil {
+		b.logger.Warn("送信中の投稿の記録の削除に失敗しました", "request_id", r.pc.RequestID, "error", err)
+	}
+}
+
+// reconcileOutbox は前回の実行で送信中のまま止まった投稿が投稿済みかを確かめて投稿履歴に記録し、Outbox から取り除きます。
+// 確かめられなかった投稿は次の起動まで残します
+func (b *Bot) reconcileOutbox(ctx context.Context) {
+	if b.outbox == nil {
+		return
+	}
+	repo, ok := b.poster.(usecase.IdempotentPostRepository)
+	if !ok {
+		return
+	}
+	pending, err := b.outbox.Pending()
+	if err != nil {
+		b.logger.Warn("送信中の投稿の読み込みに失敗しました", "error", err)
+		return
+	}
+
+	b.mu.Lock()
+	timeout := b.postTimeout
+	b.mu.Unlock()
+	for _, entry := range pending {
+		findCtx, cancel := context.WithTimeout(ctx, timeout)
+		ref, err := repo.FindPost(findCtx, entry.Key)
+		cancel()
+		if err != nil {
+			b.logger.Warn("送信中だった投稿を確認できませんでした", "request_id", entry.RequestID, "error", redact.Error(err))
+			continue
+		}
+
+		result := &PostResult{At: entry.CreatedAt, RequestID: entry.RequestID, Trigger: entry.Trigger, Text: entry.Text, Variant: entry.Variant, PostID: entry.Key}
+		if ref != nil {
+			result.URI, result.URL = ref.URI, ref.URL
+			b.logger.Info("前回の実行で投稿済みだった投稿を記録しました", "request_id", entry.RequestID, "uri", ref.URI)
+		} else {
+			result.Error = "投稿の送信中に停止しました"
+			b.logger.Info("前回の実行で送信されなかった投稿を破棄しました", "request_id", entry.RequestID)
+		}
+		b.recordHistory(result, &entry.Quote, ref)
+		if err := b.outbox.Done(entry.Key); err != nil {
+			b.logger.Warn("送信中の投稿の記録の削除に失敗しました", "request_id", entry.RequestID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+)
+
+// reloadableKeys は再起動せずに反映できる設定です。認証情報や接続先などは再起動が必要です
+var reloadableKeys = []string{"POST_INTERVAL", "POST_TIMEOUT", "QUOTES_FILE", "LOG_LEVEL", "LOG_MODULE_LEVELS"}
+
+// ErrReloadUnavailable は設定の読み込み方法が設定されていない場合のエラーです
+var ErrReloadUnavailable = errors.New("設定の再読み込みは利用できません")
+
+// QuotesFileSetter は名言ファイルのパスを変更できる名言の読み込み元です
+type QuotesFileSetter interface {
+	QuotesFile() string
+	SetQuotesFile(path string)
+}
+
+// ReloadResult は設定の再読み込みの結果です
+type ReloadResult struct {
+	// Applied は反映した設定です
+	Applied []string `json:"applied"`
+	// RestartRequired は変更されていたが、反映するには再起動が必要な設定です
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// ReloadConfig は設定を読み込み直し、投稿間隔、投稿のタイムアウト、名言ファイル、ログレベルの変更を
+// 再起動せずに反映します。それ以外の設定の変更は反映せず、ReloadResult.RestartRequired で返します。
+// 反映に失敗した場合は、それまでの設定のまま動作を続けます
+func (a *App) ReloadConfig() (*ReloadResult, error) {
+	if a.deps.LoadConfig == nil {
+		return nil, ErrReloadUnavailable
+	}
+
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	next, err := a.deps.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("設定の読み込みに失敗しました: %w", err)
+	}
+
+	result := &ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	for _, key := range changedKeys(a.cfg, next) {
+		if slices.Contains(reloadableKeys, key) && (key != "QUOTES_FILE" || a.deps.QuotesFile != nil) {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+
+	current := *a.cfg
+	applied := current
+	applied.PostInterval = next.PostInterval
+	applied.PostTimeout = next.PostTimeout
+	applied.LogLevel = next.LogLevel
+	applied.LogModuleLevels = next.LogModuleLevels
+	if a.deps.QuotesFile != nil {
+		applied.QuotesFile = next.QuotesFile
+	}
+
+	if err := logging.SetLevels(applied.LogLevel, applied.LogModuleLevels); err != nil {
+		return nil, fmt.Errorf("ログレベルの変更に失敗しました: %w", err)
+	}
+	if applied.QuotesFile != current.QuotesFile {
+		a.deps.QuotesFile.SetQuotesFile(applied.QuotesFile)
+		if _, err := a.bot.ReloadQuotes(); err != nil {
+			// 読み込めなかった場合は元の名言ファイルとログレベルに戻す
+			a.deps.QuotesFile.SetQuotesFile(current.QuotesFile)
+			logging.SetLevels(current.LogLevel, current.LogModuleLevels)
+			return nil, fmt.Errorf("名言ファイルの読み込みに失敗しました: %w", err)
+		}
+	}
+	a.bot.SetSchedule(applied.PostInterval, applied.PostTimeout)
+	a.cfg = &applied
+
+	a.logger.Info("設定を再読み込みしました", "applied", result.Applied, "post_interval", applied.PostInterval)
+	if len(result.RestartRequired) > 0 {
+		a.logger.Warn("再起動が必要な設定の変更は反映されていません", "keys", result.RestartRequired)
+	}
+	return result, nil
+}
+
+// changedKeys は2つの設定で値が異なる設定の環境変数名を返します。秘密情報は値がマスクされるため、
+// 設定の有無が変わった場合のみ検出します
+func changedKeys(before, after *config.Config) []string {
+	values := map[string]string{}
+	for _, s := range before.Settings() {
+		values[s.Key] = s.Value
+	}
+	var keys []string
+	for _, s := range after.Settings() {
+		if old, ok := values[s.Key]; !ok || old != s.Value {
+			keys = append(keys, s.Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// fakeQuotesFile は名言ファイルのパスを保持します
+type fakeQuotesFile struct {
+	path string
+}
+
+func (f *fakeQuotesFile) QuotesFile() string        { return f.path }
+func (f *fakeQuotesFile) SetQuotesFile(path string) { f.path = path }
+
+func newReloadTestConfig() *config.Config {
+	cfg := newTestConfig()
+	cfg.LogLevel = "info"
+	cfg.QuotesFile = "quotes.json"
+	cfg.PDSURL = "https://bsky.social"
+	return cfg
+}
+
+func TestApp_ReloadConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		change         func(cfg *config.Config)
+		loadErr        error
+		reloadErr      error
+		wantErr        bool
+		wantApplied    []string
+		wantRestart    []string
+		wantInterval   time.Duration
+		wantQuotesFile string
+	}{
+		{
+			name: "正常系: 投稿間隔を反映し、接続先の変更は再起動が必要として返す",
+			change: func(cfg *config.Config) {
+				cfg.PostInterval = 30 * time.Minute
+				cfg.PDSURL = "https://pds.example.com"
+			},
+			wantApplied:    []string{"POST_INTERVAL"},
+			wantRestart:    []string{"PDS_URL"},
+			wantInterval:   30 * time.Minute,
+			wantQuotesFile: "quotes.json",
+		},
+		{
+			name: "正常系: 名言ファイルを切り替える",
+			change: func(cfg *config.Config) {
+				cfg.QuotesFile = "other.json"
+			},
+			wantApplied:    []string{"QUOTES_FILE"},
+			wantRestart:    []string{},
+			wantInterval:   time.Hour,
+			wantQuotesFile: "other.json",
+		},
+		{
+			name: "異常系: 名言ファイルを読み込めない場合は元に戻す",
+			change: func(cfg *config.Config) {
+				cfg.PostInterval = 30 * time.Minute
+				cfg.QuotesFile = "broken.json"
+			},
+			reloadErr:      errors.New("名言データのデコードに失敗しました"),
+			wantErr:        true,
+			wantInterval:   time.Hour,
+			wantQuotesFile: "quotes.json",
+		},
+		{
+			name:           "異常系: 設定の読み込みに失敗",
+			loadErr:        errors.New("設定に1件の問題があります"),
+			wantErr:        true,
+			wantInterval:   time.Hour,
+			wantQuotesFile: "quotes.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes := &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}, reloadErr: tt.reloadErr}
+			quotesFile := &fakeQuotesFile{path: "quotes.json"}
+			app, err := New(newReloadTestConfig(), Dependencies{
+				Quotes: quotes,
+				Poster: &mockPoster{},
+				LoadConfig: func() (*config.Config, error) {
+					if tt.loadErr != nil {
+						return nil, tt.loadErr
+					}
+					cfg := newReloadTestConfig()
+					if tt.change != nil {
+						tt.change(cfg)
+					}
+					return cfg, nil
+				},
+				QuotesFile: quotesFile,
+			})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			result, err := app.ReloadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReloadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				if !slices.Equal(result.Applied, tt.wantApplied) || !slices.Equal(result.RestartRequired, tt.wantRestart) {
+					t.Errorf("ReloadConfig() = %+v, want applied %v, restart %v", result, tt.wantApplied, tt.wantRestart)
+				}
+			}
+			if got := app.Bot().interval(); got != tt.wantInterval {
+				t.Errorf("interval = %v, want %v", got, tt.wantInterval)
+			}
+			if quotesFile.path != tt.wantQuotesFile {
+				t.Errorf("quotes file = %q, want %q", quotesFile.path, tt.wantQuotesFile)
+			}
+		})
+	}
+}
+
+func TestApp_ReloadConfigUnavailable(t *testing.T) {
+	app, err := New(newTestConfig(), Dependencies{
+		Quotes: &mockQuoteSource{quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}}},
+		Poster: &mockPoster{},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := app.ReloadConfig(); !errors.Is(err, ErrReloadUnavailable) {
+		t.Errorf("ReloadConfig() error = %v, want ErrReloadUnavailable", err)
+	}
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+)
+
+// Scheduler は定期投稿の時刻を決めます。設定しない場合は POST_INTERVAL ごとに投稿します
+type Scheduler interface {
+	// Next は last（前回の投稿予定時刻、初回は起動した時刻）の次の投稿予定時刻を返します
+	Next(last time.Time) time.Time
+}
+
+// SchedulerFunc は関数を Scheduler として使います
+type SchedulerFunc func(last time.Time) time.Time
+
+func (f SchedulerFunc) Next(last time.Time) time.Time {
+	return f(last)
+}
+
+// WithScheduler は POST_INTERVAL の代わりに scheduler で定期投稿の時刻を決めます。
+// 設定の再読み込みで POST_INTERVAL を変更しても投稿の時刻は変わりません
+func WithScheduler(scheduler Scheduler) Option {
+	return func(b *Bot) {
+		b.scheduler = scheduler
+	}
+}
+
+// schedule は last の次の定期投稿の予定時刻を返します。定期投稿が続けて失敗している間は間隔を延ばします
+func (b *Bot) schedule(last time.Time) time.Time {
+	next := last.Add(b.interval())
+	if b.scheduler != nil {
+		next = b.scheduler.Next(last)
+	}
+	return last.Add(b.backoff(next.Sub(last)))
+}
+
+// backoff は定期投稿が POST_BACKOFF_THRESHOLD 回続けて失敗している場合に、それを超えた失敗ごとに
+// interval を2倍に延ばした間隔を返します。延ばした間隔は POST_BACKOFF_MAX までです
+func (b *Bot) backoff(interval time.Duration) time.Duration {
+	b.mu.Lock()
+	failures := b.failures
+	b.mu.Unlock()
+	if b.backoffAfter <= 0 || failures < b.backoffAfter {
+		return interval
+	}
+	delayed := interval
+	for i := b.backoffAfter; i <= failures && delayed < b.backoffMax; i++ {
+		delayed *= 2
+	}
+	// もともと上限より長い間隔は短くしない
+	return max(min(delayed, b.backoffMax), interval)
+}
+
+// backOff は定期投稿の結果から続けて失敗した回数を数えます。投稿間隔を延ばした場合や元に戻した場合は、
+// last（この投稿の予定時刻）から数えて次の投稿を予定し直します
+func (b *Bot) backOff(result *PostResult, last time.Time, timer clock.Timer) {
+	if b.backoffAfter <= 0 || result.Error == ErrShuttingDown.Error() {
+		return
+	}
+	b.mu.Lock()
+	before := b.failures
+	if result.Error == "" {
+		b.failures = 0
+	} else {
+		b.failures++
+	}
+	failures := b.failures
+	b.mu.Unlock()
+	if failures < b.backoffAfter && before < b.backoffAfter {
+		return
+	}
+
+	next := b.schedule(last)
+	timer.Stop()
+	timer.Reset(max(clock.Until(b.clock, next), 0))
+	b.setNextPostAt(next)
+	if failures == 0 {
+		b.logger.Info("投稿に成功したため投稿間隔を元に戻しました", "failures", before, "next_post_at", next)
+		return
+	}
+	b.events.Publish(events.Event{
+		Type:       events.PostBackoff,
+		RequestID:  result.RequestID,
+		Trigger:    result.Trigger,
+		Error:      result.Error,
+		ErrorClass: result.ErrorClass,
+		Failures:   failures,
+		Interval:   next.Sub(last).String(),
+	})
+}
+
+// WithCalendar は calendar の特別な日に、その日の名言を定期投稿とは別に投稿します。
+// replace を指定した特別な日は、その日の定期投稿を行いません
+func WithCalendar(calendar *domain.Calendar) Option {
+	return func(b *Bot) {
+		b.calendar = calendar
+	}
+}
+
+// NewCalendar は OCCASIONS_FILE の特別な日の予定を読み込み、OCCASION_TIME に投稿する Calendar を作成します。
+// OCCASIONS_FILE が未設定の場合は nil を返します
+func NewCalendar(cfg *config.Config) (*domain.Calendar, error) {
+	if cfg.OccasionsFile == "" {
+		return nil, nil
+	}
+	occasions, err := repository.LoadOccasions(cfg.OccasionsFile)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewCalendar(occasions, cfg.OccasionTime, cfg.DisplayLocation())
+}
+
+// replacedByOccasion は今日が定期投稿を特別な日の投稿に置き換える日かを返します
+func (b *Bot) replacedByOccasion() bool {
+	return b.calendar != nil && b.calendar.Replaces(b.clock.Now())
+}
+
+// postOccasions は day の特別な日の名言を、予定に書いた順に投稿します。
+// 名言が見つからない場合はエラーとして記録し、残りの名言の投稿を続けます
+func (b *Bot) postOccasions(ctx context.Context, day time.Time) {
+	for _, occasion := range b.calendar.On(day) {
+		quote := occasion.Quote()
+		if quote == nil {
+			var err error
+			if quote, err = b.findQuote(occasion.QuoteID); err != nil {
+				b.logger.Warn("特別な日の名言が見つかりません", "date", occasion.Date, "quote_id", occasion.QuoteID, "error", err)
+				b.recordError("", err)
+				continue
+			}
+		}
+		b.logger.Info("特別な日の名言を投稿します", "date", occasion.Date, "quote_id", quote.StableID())
+		b.post(ctx, TriggerOccasion, nil, quote)
+	}
+}
+
+// findQuote は名言の取得元から id の名言を探します
+func (b *Bot) findQuote(id string) (*domain.Quote, error) {
+	finder, ok := b.quotes.(QuoteFinder)
+	if !ok {
+		return nil, fmt.Errorf("名言の取得元はIDでの検索に対応していません")
+	}
+	return finder.QuoteByID(id)
+}
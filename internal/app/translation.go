@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// WithTranslation は名言の翻訳（Quote.Translations）を投稿します。mode が replace の場合は lang の翻訳がある名言を
+// 翻訳で投稿し、thread の場合は原文を投稿してから lang の翻訳を返信します（投稿先が返信できる場合のみ）。
+// 投稿には本文の言語を付けます
+func WithTranslation(lang, mode string) Option {
+	return func(b *Bot) {
+		b.lang = lang
+		b.langMode = mode
+	}
+}
+
+// localize は選んだ名言を翻訳し、投稿の言語を設定します。名言を選んだ後のフックとして登録します
+func (b *Bot) localize(ctx context.Context, pc *usecase.PostContext) error {
+	if b.langMode != domain.TranslationModeThread {
+		pc.Quote = pc.Quote.Localized(b.lang)
+	}
+	pc.Langs = nil
+	if pc.Quote.Lang != "" {
+		pc.Langs = []string{pc.Quote.Lang}
+	}
+	return nil
+}
+
+// replyTranslation は投稿した名言の翻訳を、投稿への返信として投稿します。投稿した後のフックとして登録します
+func (b *Bot) replyTranslation(ctx context.Context, pc *usecase.PostContext) error {
+	if _, ok := pc.Quote.Translation(b.lang); !ok || pc.Ref == nil {
+		return nil
+	}
+	// スレッドをまとめて投稿できる投稿先には、原文と一緒に投稿済み
+	if _, ok := b.poster.(usecase.ThreadPostRepository); ok {
+		return nil
+	}
+	replier, ok := b.poster.(usecase.ReplyPostRepository)
+	if !ok {
+		return nil
+	}
+	text, err := pc.Formatter.Format(pc.Quote.Localized(b.lang), pc.Capabilities)
+	if err != nil {
+		return fmt.Errorf("翻訳の整形に失敗しました: %w", err)
+	}
+	ref, err := replier.PublishReply(usecase.WithLangs(ctx, []string{b.lang}), text, *pc.Ref, *pc.Ref)
+	if err != nil {
+		return fmt.Errorf("翻訳の返信に失敗しました: %w", err)
+	}
+	b.logger.Info("翻訳を返信しました", "request_id", pc.RequestID, "lang", b.lang, "uri", ref.URI)
+	return nil
+}
+
+// threadRepository は1回の投稿の間だけ使う投稿先で、翻訳をスレッドで投稿する場合に原文と翻訳の返信を
+// 1回のリクエストでまとめて投稿します。原文だけが投稿されて翻訳の返信が抜けることはありません
+type threadRepository struct {
+	repo usecase.ThreadPostRepository
+	pc   *usecase.PostContext
+	lang string
+	key  string // 原文の冪等キー
+	bot  *Bot
+}
+
+// newThreadRepository は翻訳をスレッドで投稿し、投稿先がスレッドをまとめて投稿できる場合に、この投稿で使う投稿先を返します
+func (b *Bot) newThreadRepository(pc *usecase.PostContext, result *PostResult) *threadRepository {
+	if b.langMode != domain.TranslationModeThread {
+		return nil
+	}
+	repo, ok := b.poster.(usecase.ThreadPostRepository)
+	if !ok {
+		return nil
+	}
+	return &threadRepository{repo: repo, pc: pc, lang: b.lang, key: result.PostID, bot: b}
+}
+
+func (r *threadRepository) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	return r.PublishOnce(ctx, message, r.key)
+}
+
+// PublishOnce は原文を key で投稿し、翻訳があればその返信と一緒に投稿します
+func (r *threadRepository) PublishOnce(ctx context.Context, message, key string) (*domain.PostRef, error) {
+	posts := []usecase.ThreadPost{{Text: message, Langs: usecase.LangsFromContext(ctx)}}
+	if _, ok := r.pc.Quote.Translation(r.lang); ok {
+		text, err := r.pc.Formatter.Format(r.pc.Quote.Localized(r.lang), r.pc.Capabilities)
+		if err != nil {
+			return nil, fmt.Errorf("翻訳の整形に失敗しました: %w", err)
+		}
+		posts = append(posts, usecase.ThreadPost{Text: text, Langs: []string{r.lang}})
+	}
+	refs, err := r.repo.PublishThread(ctx, key, posts)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 1 {
+		r.bot.logger.Info("翻訳を返信しました", "request_id", r.pc.RequestID, "lang", r.lang, "uri", refs[1].URI)
+	}
+	return refs[0], nil
+}
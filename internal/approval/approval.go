@@ -0,0 +1,143 @@
+// Package approval は投稿の前に人が確認するための承認待ちのキューを管理します
+package approval
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// stateKey は承認待ちの投稿を保存する状態のキーです
+const stateKey = "approvals"
+
+// ErrNotFound は指定したIDの承認待ちの投稿がない（期限切れを含む）場合のエラーです
+var ErrNotFound = errors.New("承認待ちの投稿が見つかりません")
+
+// Item は承認待ちの投稿です
+type Item struct {
+	ID        string       `json:"id"`
+	Quote     domain.Quote `json:"quote"`
+	Text      string       `json:"text"` // 承認されるとこの本文をそのまま投稿します
+	Variant   string       `json:"variant,omitempty"`
+	CreatedAt time.Time    `json:"createdAt"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+}
+
+// Queue は承認待ちの投稿を状態ファイルに保存します。期限を過ぎた投稿は取り除きます
+type Queue struct {
+	store state.Store
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewQueue は Queue を作成します。ttl を過ぎても承認されなかった投稿は破棄されます
+func NewQueue(store state.Store, ttl time.Duration) *Queue {
+	return &Queue{store: store, ttl: ttl, now: time.Now}
+}
+
+// Add は投稿を承認待ちに追加します
+func (q *Queue) Add(quote domain.Quote, text, variant string) (Item, error) {
+	id, err := newID()
+	if err != nil {
+		return Item{}, err
+	}
+	now := q.now()
+	item := Item{ID: id, Quote: quote, Text: text, Variant: variant, CreatedAt: now, ExpiresAt: now.Add(q.ttl)}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items, err := q.load()
+	if err != nil {
+		return Item{}, err
+	}
+	if err := q.store.Put(stateKey, append(items, item)); err != nil {
+		return Item{}, err
+	}
+	return item, nil
+}
+
+// List は期限内の承認待ちの投稿を、古い順に返します
+func (q *Queue) List() ([]Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.load()
+}
+
+// Take は承認待ちの投稿を取り出します（承認または却下）
+func (q *Queue) Take(id string) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items, err := q.load()
+	if err != nil {
+		return Item{}, err
+	}
+	for i, item := range items {
+		if item.ID != id {
+			continue
+		}
+		rest := append(items[:i:i], items[i+1:]...)
+		if err := q.store.Put(stateKey, rest); err != nil {
+			return Item{}, err
+		}
+		return item, nil
+	}
+	return Item{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Expire は期限を過ぎた投稿を取り除き、取り除いた投稿を返します
+func (q *Queue) Expire() ([]Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var all []Item
+	if _, err := q.store.Get(stateKey, &all); err != nil {
+		return nil, err
+	}
+	items, expired := q.split(all)
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	if err := q.store.Put(stateKey, items); err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// load は期限内の投稿を読み込みます。期限切れの投稿は Expire で保存し直すまで状態ファイルに残ります
+func (q *Queue) load() ([]Item, error) {
+	var all []Item
+	if _, err := q.store.Get(stateKey, &all); err != nil {
+		return nil, err
+	}
+	items, _ := q.split(all)
+	return items, nil
+}
+
+// split は期限内の投稿と期限切れの投稿に分けます
+func (q *Queue) split(all []Item) (items, expired []Item) {
+	now := q.now()
+	for _, item := range all {
+		if now.Before(item.ExpiresAt) {
+			items = append(items, item)
+		} else {
+			expired = append(expired, item)
+		}
+	}
+	return items, expired
+}
+
+// newID は承認待ちの投稿のIDを作成します（CLIで入力しやすい短い16進数）
+func newID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("IDの作成に失敗しました: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
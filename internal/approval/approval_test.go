@@ -0,0 +1,65 @@
+package approval
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+func newTestQueue(t *testing.T) (*Queue, *time.Time) {
+	t.Helper()
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	q := NewQueue(store, time.Hour)
+	q.now = func() time.Time { return now }
+	return q, &now
+}
+
+func TestQueue(t *testing.T) {
+	q, now := newTestQueue(t)
+	first, err := q.Add(domain.Quote{Text: "名言1", Author: "著者"}, "名言1\n- 著者", "")
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	*now = now.Add(30 * time.Minute)
+	second, err := q.Add(domain.Quote{Text: "名言2", Author: "著者"}, "名言2\n- 著者", "short")
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	items, err := q.List()
+	if err != nil || len(items) != 2 || items[0].ID != first.ID || items[1].ID != second.ID {
+		t.Fatalf("List() = %+v, %v, want both items oldest first", items, err)
+	}
+
+	// 1件目の期限を過ぎると一覧から消え、承認できない
+	*now = now.Add(45 * time.Minute)
+	if _, err := q.Take(first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Take(expired) error = %v, want ErrNotFound", err)
+	}
+	expired, err := q.Expire()
+	if err != nil || len(expired) != 1 || expired[0].ID != first.ID {
+		t.Errorf("Expire() = %+v, %v, want the first item", expired, err)
+	}
+
+	item, err := q.Take(second.ID)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if item.Text != "名言2\n- 著者" || item.Variant != "short" {
+		t.Errorf("Take() = %+v, want the second item", item)
+	}
+	if _, err := q.Take(second.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Take() twice error = %v, want ErrNotFound", err)
+	}
+	if items, _ := q.List(); len(items) != 0 {
+		t.Errorf("List() = %+v, want empty", items)
+	}
+}
@@ -0,0 +1,10 @@
+// Package auth checks the bearer tokens of the admin and gRPC APIs (ADMIN_TOKEN, ADMIN_READ_TOKEN).
+package auth
+
+import "crypto/subtle"
+
+// TokenMatches reports whether token equals want, comparing in constant time so that the
+// response time does not reveal how much of the token was right. An unset token never matches.
+func TokenMatches(token, want string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
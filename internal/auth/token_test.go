@@ -0,0 +1,25 @@
+package auth
+
+import "testing"
+
+func TestTokenMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+		match bool
+	}{
+		{name: "正常系: 同じトークン", token: "secret", want: "secret", match: true},
+		{name: "異常系: 違うトークン", token: "secreT", want: "secret"},
+		{name: "異常系: 前方だけ一致するトークン", token: "secret", want: "secret-longer"},
+		{name: "異常系: 設定されていないトークンには空のトークンも一致しない", token: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TokenMatches(tt.token, tt.want); got != tt.match {
+				t.Errorf("TokenMatches(%q, %q) = %v, want %v", tt.token, tt.want, got, tt.match)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when Advance or Set is called.
+// Timers fire synchronously from Advance once their deadline is reached.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond // Signalled when a timer is added or removed
+	now     time.Time
+	timers  []*fakeTimer // Timers that haven't fired or been stopped
+}
+
+// NewFake returns a Fake clock set to now
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer creates a timer that fires when the fake time reaches now+d
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// After returns the channel of a new timer
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Advance moves the fake time forward by d and fires the timers that are due
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the fake time to now and fires the timers that are due.
+// Moving the time backwards doesn't fire anything.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.deadline.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.fire(now)
+	}
+	clear(f.timers[len(pending):])
+	f.timers = pending
+	f.changed.Broadcast()
+}
+
+// BlockUntil waits until at least n timers are waiting to fire. Tests use it to make sure
+// that a goroutine has started waiting before they call Advance.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+// remove drops a timer from the waiting list and reports whether it was there; mu must be held
+func (f *Fake) remove(t *fakeTimer) bool {
+	for i, waiting := range f.timers {
+		if waiting == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.drain()
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.drain()
+	active := f.remove(t)
+	t.deadline = f.now.Add(d)
+	if d <= 0 {
+		t.fire(f.now)
+		return active
+	}
+	f.timers = append(f.timers, t)
+	f.changed.Broadcast()
+	return active
+}
+
+// drain discards a tick that wasn't received, so that like a *time.Timer since Go 1.23
+// no stale time is delivered after Stop or Reset
+func (t *fakeTimer) drain() {
+	select {
+	case <-t.c:
+	default:
+	}
+}
+
+// fire delivers the time unless an earlier tick hasn't been received yet
+func (t *fakeTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
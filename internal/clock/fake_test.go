@@ -0,0 +1,84 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_Timer(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		run       func(f *Fake, timer Timer)
+		wantFired bool
+	}{
+		{
+			name:      "正常系: 期限まで進めると発火する",
+			run:       func(f *Fake, timer Timer) { f.Advance(time.Minute) },
+			wantFired: true,
+		},
+		{
+			name:      "正常系: 期限の前では発火しない",
+			run:       func(f *Fake, timer Timer) { f.Advance(59 * time.Second) },
+			wantFired: false,
+		},
+		{
+			name: "正常系: 止めたタイマーは発火しない",
+			run: func(f *Fake, timer Timer) {
+				timer.Stop()
+				f.Advance(time.Hour)
+			},
+			wantFired: false,
+		},
+		{
+			name: "正常系: Reset は進めた後の時刻から数え直す",
+			run: func(f *Fake, timer Timer) {
+				f.Advance(30 * time.Second)
+				timer.Reset(time.Minute)
+				f.Advance(45 * time.Second)
+			},
+			wantFired: false,
+		},
+		{
+			name: "正常系: Reset は受け取られていない発火を捨てる",
+			run: func(f *Fake, timer Timer) {
+				f.Advance(time.Minute)
+				timer.Reset(time.Minute)
+			},
+			wantFired: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFake(start)
+			timer := f.NewTimer(time.Minute)
+			tt.run(f, timer)
+
+			select {
+			case got := <-timer.C():
+				if !tt.wantFired {
+					t.Errorf("timer fired at %v, want no fire", got)
+				}
+			default:
+				if tt.wantFired {
+					t.Error("timer did not fire")
+				}
+			}
+		})
+	}
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	f := NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	done := make(chan time.Time)
+	go func() {
+		done <- <-f.After(time.Second)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+	if got, want := <-done, f.Now(); !got.Equal(want) {
+		t.Errorf("After() sent %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+// Package deadletter は失敗した投稿を状態ファイルに残し、障害が収まった後に送り直せるようにします
+package deadletter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// stateKey は失敗した投稿を保存する状態のキーです
+const stateKey = "dead_letters"
+
+// ErrNotFound は指定したIDの失敗した投稿がない場合のエラーです
+var ErrNotFound = errors.New("失敗した投稿が見つかりません")
+
+// Item は失敗した投稿です
+type Item struct {
+	ID        string       `json:"id"`
+	RequestID string       `json:"requestId"`
+	Trigger   string       `json:"trigger"`
+	Quote     domain.Quote `json:"quote"`
+	Text      string       `json:"text"` // 送り直すとこの本文をそのまま投稿します
+	Variant   string       `json:"variant,omitempty"`
+	// PostID は失敗した投稿の冪等キーです。送り直す前に、実は投稿できていたかを確かめます
+	PostID     string    `json:"postId,omitempty"`
+	Error      string    `json:"error"`
+	ErrorClass string    `json:"errorClass,omitempty"`
+	FailedAt   time.Time `json:"failedAt"`
+}
+
+// Queue は失敗した投稿を状態ファイルに保存します。上限を超えると古い投稿から捨てます
+type Queue struct {
+	store state.Store
+	max   int
+
+	mu sync.Mutex
+}
+
+// NewQueue は最大 max 件を残す Queue を作成します
+func NewQueue(store state.Store, max int) *Queue {
+	return &Queue{store: store, max: max}
+}
+
+// Add は失敗した投稿を追加し、IDを付けて返します
+func (q *Queue) Add(item Item) (Item, error) {
+	id, err := newID()
+	if err != nil {
+		return Item{}, err
+	}
+	item.ID = id
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items, err := q.load()
+	if err != nil {
+		return Item{}, err
+	}
+	items = append(items, item)
+	if q.max > 0 && len(items) > q.max {
+		items = items[len(items)-q.max:]
+	}
+	if err := q.store.Put(stateKey, items); err != nil {
+		return Item{}, err
+	}
+	return item, nil
+}
+
+// List は失敗した投稿を古い順に返します
+func (q *Queue) List() ([]Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.load()
+}
+
+// Take は失敗した投稿を取り出します（送り直すか破棄する）
+func (q *Queue) Take(id string) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items, err := q.load()
+	if err != nil {
+		return Item{}, err
+	}
+	for i, item := range items {
+		if item.ID != id {
+			continue
+		}
+		rest := append(items[:i:i], items[i+1:]...)
+		if err := q.store.Put(stateKey, rest); err != nil {
+			return Item{}, err
+		}
+		return item, nil
+	}
+	return Item{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+func (q *Queue) load() ([]Item, error) {
+	var items []Item
+	if _, err := q.store.Get(stateKey, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// newID は失敗した投稿のIDを作成します（CLIで入力しやすい短い16進数）
+func newID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("IDの作成に失敗しました: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,57 @@
+package deadletter
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+func TestQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := state.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	q := NewQueue(store, 2)
+	var added []Item
+	for _, text := range []string{"名言1", "名言2", "名言3"} {
+		item, err := q.Add(Item{Quote: domain.Quote{Text: text, Author: "著者"}, Text: text + "\n- 著者", Error: "503"})
+		if err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if item.ID == "" {
+			t.Fatalf("Add() returned no ID")
+		}
+		added = append(added, item)
+	}
+
+	// 上限を超えた古い投稿は捨てる
+	items, err := q.List()
+	if err != nil || len(items) != 2 || items[0].ID != added[1].ID || items[1].ID != added[2].ID {
+		t.Fatalf("List() = %+v, %v, want the two newest items oldest first", items, err)
+	}
+	if _, err := q.Take(added[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Take(dropped) error = %v, want ErrNotFound", err)
+	}
+
+	taken, err := q.Take(added[1].ID)
+	if err != nil || taken.Text != "名言2\n- 著者" {
+		t.Fatalf("Take() = %+v, %v, want the second item", taken, err)
+	}
+	if _, err := q.Take(added[1].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Take(twice) error = %v, want ErrNotFound", err)
+	}
+
+	// 再起動した後も残る
+	store, err = state.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	items, err = NewQueue(store, 2).List()
+	if err != nil || len(items) != 1 || items[0].ID != added[2].ID {
+		t.Errorf("List() after restart = %+v, %v, want the third item", items, err)
+	}
+}
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 装飾の位置
+const (
+	DecorationPrefix   = "prefix"   // 本文の前に付ける
+	DecorationSuffix   = "suffix"   // 本文の後に付ける（ハッシュタグの前）
+	DecorationBoth     = "both"     // 前後に付ける
+	DecorationTemplate = "template" // 自動では付けず、テンプレートの {{.Decoration}} で使う
+)
+
+// weekdays は曜日の名前です
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// monthDay は年を問わない日付です（例: 1225 は12月25日）
+type monthDay int
+
+func newMonthDay(t time.Time) monthDay {
+	return monthDay(int(t.Month())*100 + t.Day())
+}
+
+// dateRange は年をまたいでもよい日付の範囲です（両端を含む）
+type dateRange struct {
+	from, to   monthDay
+	decoration string
+}
+
+func (r dateRange) contains(d monthDay) bool {
+	if r.from <= r.to {
+		return r.from <= d && d <= r.to
+	}
+	// 12-25/01-05 のように年をまたぐ
+	return d >= r.from || d <= r.to
+}
+
+// days は範囲のおおよその日数です。重なる範囲では短いほうを優先します
+func (r dateRange) days() int {
+	days := func(d monthDay) int { return int(d/100)*31 + int(d%100) }
+	n := days(r.to) - days(r.from)
+	if n < 0 {
+		n += 12 * 31
+	}
+	return n
+}
+
+// Decorations は投稿の前後に付ける絵文字などの装飾を、日付と曜日から決めます。
+// 日付の範囲、曜日、デフォルトの順に優先します
+type Decorations struct {
+	fallback string
+	weekdays map[time.Weekday]string
+	ranges   []dateRange
+	position string
+	now      func() time.Time
+}
+
+// NewDecorations は Decorations を作成します。weekdays のキーは曜日（mon, tue, ...）、
+// dates のキーは日付（12-25）または日付の範囲（12-01/12-31、12-25/01-05）です
+func NewDecorations(fallback string, weekdayDecorations, dates map[string]string, position string) (*Decorations, error) {
+	switch position {
+	case "":
+		position = DecorationPrefix
+	case DecorationPrefix, DecorationSuffix, DecorationBoth, DecorationTemplate:
+	default:
+		return nil, fmt.Errorf("不明な装飾の位置です: %s（prefix, suffix, both, template のいずれか）", position)
+	}
+
+	d := &Decorations{fallback: fallback, weekdays: map[time.Weekday]string{}, position: position, now: time.Now}
+	for name, decoration := range weekdayDecorations {
+		weekday, ok := parseWeekday(name)
+		if !ok {
+			return nil, fmt.Errorf("不明な曜日です: %s（mon, tue, wed, thu, fri, sat, sun のいずれか）", name)
+		}
+		d.weekdays[weekday] = decoration
+	}
+
+	keys := make([]string, 0, len(dates))
+	for k := range dates {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		r, err := parseDateRange(key)
+		if err != nil {
+			return nil, err
+		}
+		r.decoration = dates[key]
+		d.ranges = append(d.ranges, r)
+	}
+	return d, nil
+}
+
+// parseWeekday は曜日の略称（mon）または英語の名前（monday）を解析します
+func parseWeekday(name string) (time.Weekday, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if weekday, ok := weekdays[key]; ok {
+		return weekday, true
+	}
+	for _, weekday := range weekdays {
+		if strings.ToLower(weekday.String()) == key {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+// parseDateRange は "12-25" または "12-01/12-31" を解析します
+func parseDateRange(s string) (dateRange, error) {
+	from, to, found := strings.Cut(s, "/")
+	if !found {
+		to = from
+	}
+	parse := func(v string) (monthDay, error) {
+		t, err := time.Parse("01-02", strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("日付は MM-DD の形式で指定してください: %s", s)
+		}
+		return newMonthDay(t), nil
+	}
+	start, err := parse(from)
+	if err != nil {
+		return dateRange{}, err
+	}
+	end, err := parse(to)
+	if err != nil {
+		return dateRange{}, err
+	}
+	return dateRange{from: start, to: end}, nil
+}
+
+// For は t の日付に使う装飾を返します。該当するものがなければデフォルトの装飾です
+func (d *Decorations) For(t time.Time) string {
+	day := newMonthDay(t)
+	best := -1
+	for i, r := range d.ranges {
+		if r.contains(day) && (best < 0 || r.days() < d.ranges[best].days()) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return d.ranges[best].decoration
+	}
+	if decoration, ok := d.weekdays[t.Weekday()]; ok {
+		return decoration
+	}
+	return d.fallback
+}
+
+// apply は設定した位置に装飾を付けます
+func (d *Decorations) apply(text, decoration string) string {
+	if decoration == "" {
+		return text
+	}
+	switch d.position {
+	case DecorationPrefix:
+		return decoration + " " + text
+	case DecorationSuffix:
+		return text + " " + decoration
+	case DecorationBoth:
+		return decoration + " " + text + " " + decoration
+	default:
+		return text
+	}
+}
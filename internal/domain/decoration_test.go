@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecorations_For(t *testing.T) {
+	d, err := NewDecorations("✨",
+		map[string]string{"mon": "☕", "Friday": "🎉"},
+		map[string]string{"12-01/12-31": "❄️", "12-24/12-25": "🎄", "12-31/01-03": "🎍"},
+		DecorationPrefix)
+	if err != nil {
+		t.Fatalf("NewDecorations() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		date time.Time
+		want string
+	}{
+		{name: "デフォルト（水曜日）", date: time.Date(2024, 6, 5, 9, 0, 0, 0, time.UTC), want: "✨"},
+		{name: "曜日（月曜日）", date: time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC), want: "☕"},
+		{name: "曜日（金曜日）", date: time.Date(2024, 6, 7, 9, 0, 0, 0, time.UTC), want: "🎉"},
+		{name: "日付の範囲は曜日より優先（12月2日は月曜日）", date: time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC), want: "❄️"},
+		{name: "重なる範囲は短いほうを優先", date: time.Date(2024, 12, 25, 9, 0, 0, 0, time.UTC), want: "🎄"},
+		{name: "年をまたぐ範囲", date: time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC), want: "🎍"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.For(tt.date); got != tt.want {
+				t.Errorf("For(%v) = %q, want %q", tt.date, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDecorations_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		weekdays map[string]string
+		dates    map[string]string
+		position string
+	}{
+		{name: "不明な曜日", weekdays: map[string]string{"funday": "🎉"}},
+		{name: "日付の形式", dates: map[string]string{"12/25": "🎄"}},
+		{name: "存在しない日付", dates: map[string]string{"13-01": "🎄"}},
+		{name: "不明な位置", position: "middle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDecorations("", tt.weekdays, tt.dates, tt.position); err == nil {
+				t.Errorf("NewDecorations() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestFormatter_FormatWithDecorations(t *testing.T) {
+	hashtags, err := NewHashtags(HashtagModeFixed, []string{"quote"}, 0, nil)
+	if err != nil {
+		t.Fatalf("NewHashtags() error = %v", err)
+	}
+	quote := &Quote{Text: "本文", Author: "著者"}
+	christmas := func() time.Time { return time.Date(2024, 12, 25, 9, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		position string
+		template string
+		want     string
+	}{
+		{name: "前に付ける", position: DecorationPrefix, want: "🎄 本文\n- 著者\n#quote"},
+		{name: "ハッシュタグの前に付ける", position: DecorationSuffix, want: "本文\n- 著者 🎄\n#quote"},
+		{name: "前後に付ける", position: DecorationBoth, want: "🎄 本文\n- 著者 🎄\n#quote"},
+		{name: "テンプレートで使う", position: DecorationTemplate, template: "{{.Decoration}}{{.Text}}{{.Decoration}}", want: "🎄本文🎄\n#quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decorations, err := NewDecorations("", nil, map[string]string{"12-25": "🎄"}, tt.position)
+			if err != nil {
+				t.Fatalf("NewDecorations() error = %v", err)
+			}
+			decorations.now = christmas
+			f, err := NewFormatter(tt.template, nil, WithDecorations(decorations), WithHashtags(hashtags))
+			if err != nil {
+				t.Fatalf("NewFormatter() error = %v", err)
+			}
+			got, err := f.Format(quote, BlueskyCapabilities)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 禁止語を含む名言の扱い
+const (
+	DenyActionSkip = "skip" // 投稿せずに別の名言を選ぶ
+	DenyActionFlag = "flag" // 投稿するが、投稿履歴とログで知らせる
+)
+
+// DenyList は投稿してはいけない語句（大文字と小文字を区別しない）と正規表現です
+type DenyList struct {
+	words    []string
+	patterns []*regexp.Regexp
+}
+
+// NewDenyList は DenyList を作成します。空の語句は無視します
+func NewDenyList(words, patterns []string) (*DenyList, error) {
+	d := &DenyList{}
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			d.words = append(d.words, strings.ToLower(w))
+		}
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("禁止語の正規表現の解析に失敗しました: %s: %w", p, err)
+		}
+		d.patterns = append(d.patterns, re)
+	}
+	return d, nil
+}
+
+// Empty は語句も正規表現もない場合に true を返します
+func (d *DenyList) Empty() bool {
+	return len(d.words) == 0 && len(d.patterns) == 0
+}
+
+// Match は text に含まれる最初の禁止語を返します
+func (d *DenyList) Match(text string) (string, bool) {
+	lower := strings.ToLower(text)
+	for _, w := range d.words {
+		if strings.Contains(lower, w) {
+			return w, true
+		}
+	}
+	for _, re := range d.patterns {
+		if re.MatchString(text) {
+			return re.String(), true
+		}
+	}
+	return "", false
+}
+
+// MatchQuote は名言の本文、著者、タグに含まれる最初の禁止語を返します
+func (d *DenyList) MatchQuote(q *Quote) (string, bool) {
+	for _, text := range append([]string{q.Text, q.Author}, q.Tags...) {
+		if term, ok := d.Match(text); ok {
+			return term, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestDenyList_MatchQuote(t *testing.T) {
+	deny, err := NewDenyList([]string{"Forbidden", " ", "禁止"}, []string{`\bcasino\b`})
+	if err != nil {
+		t.Fatalf("NewDenyList() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		quote    Quote
+		wantTerm string
+		wantOK   bool
+	}{
+		{name: "正常系: 禁止語なし", quote: Quote{Text: "我思う、ゆえに我あり。", Author: "デカルト"}},
+		{name: "正常系: 大文字と小文字を区別しない", quote: Quote{Text: "This is FORBIDDEN fruit"}, wantTerm: "forbidden", wantOK: true},
+		{name: "正常系: 著者も確認する", quote: Quote{Text: "名言", Author: "禁止太郎"}, wantTerm: "禁止", wantOK: true},
+		{name: "正常系: タグも確認する", quote: Quote{Text: "名言", Tags: []string{"casino"}}, wantTerm: `\bcasino\b`, wantOK: true},
+		{name: "正常系: 正規表現は単語の一部には一致しない", quote: Quote{Text: "casinos"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term, ok := deny.MatchQuote(&tt.quote)
+			if term != tt.wantTerm || ok != tt.wantOK {
+				t.Errorf("MatchQuote() = %q, %v, want %q, %v", term, ok, tt.wantTerm, tt.wantOK)
+			}
+		})
+	}
+
+	if _, err := NewDenyList(nil, []string{"("}); err == nil {
+		t.Error("NewDenyList() error = nil, want error for an invalid pattern")
+	}
+	if empty, _ := NewDenyList([]string{" "}, nil); !empty.Empty() {
+		t.Error("Empty() = false, want true")
+	}
+}
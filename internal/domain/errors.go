@@ -0,0 +1,78 @@
+package domain
+
+import "errors"
+
+// 失敗の分類です。投稿先やユースケースのエラーはこれらを包むため、errors.Is で分類を確かめられます
+var (
+	// ErrRateLimited は投稿先のレート制限で拒否されたことを表します
+	ErrRateLimited = errors.New("投稿先のレート制限を超えました")
+	// ErrAuthExpired は投稿先の認証が切れ、リフレッシュもできなかったことを表します
+	ErrAuthExpired = errors.New("投稿先の認証の有効期限が切れています")
+	// ErrQuoteTooLong は投稿の本文が投稿先の文字数の上限を超えたことを表します
+	ErrQuoteTooLong = errors.New("投稿の本文が長すぎます")
+	// ErrPoolEmpty は投稿できる名言が1件もないことを表します
+	ErrPoolEmpty = errors.New("利用可能な名言がありません")
+	// ErrAccountSuspended は投稿先のアカウントが停止（テイクダウン）または無効化されていることを表します。
+	// 運用者が対処するまで、再試行しても成功しません
+	ErrAccountSuspended = errors.New("投稿先のアカウントが停止されています")
+)
+
+// ErrorClass はエラーの分類の名前です。イベントや通知など、エラーを文字列で渡す先に分類を伝えます
+type ErrorClass string
+
+// エラーの分類の名前
+const (
+	ErrorClassRateLimited  ErrorClass = "rate_limited"
+	ErrorClassAuthExpired  ErrorClass = "auth_expired"
+	ErrorClassQuoteTooLong ErrorClass = "quote_too_long"
+	ErrorClassPoolEmpty    ErrorClass = "pool_empty"
+
+	ErrorClassAccountSuspended ErrorClass = "account_suspended"
+)
+
+var errorClasses = []struct {
+	class ErrorClass
+	err   error
+}{
+	// 停止されたアカウントへのリクエストは認証の失敗としても返るため、先に確かめる
+	{ErrorClassAccountSuspended, ErrAccountSuspended},
+	{ErrorClassRateLimited, ErrRateLimited},
+	{ErrorClassAuthExpired, ErrAuthExpired},
+	{ErrorClassQuoteTooLong, ErrQuoteTooLong},
+	{ErrorClassPoolEmpty, ErrPoolEmpty},
+}
+
+// ClassifyError は err の分類の名前を返します。どの分類にも当たらない場合は空です
+func ClassifyError(err error) ErrorClass {
+	for _, c := range errorClasses {
+		if errors.Is(err, c.err) {
+			return c.class
+		}
+	}
+	return ""
+}
+
+// Err は分類のエラー（ErrRateLimited など）を返します。不明な分類の場合は nil です
+func (c ErrorClass) Err() error {
+	for _, ec := range errorClasses {
+		if ec.class == c {
+			return ec.err
+		}
+	}
+	return nil
+}
+
+// ClassifiedError は文字列で受け取ったエラーを、分類を保ったまま error に戻します。
+// errors.Is は分類のエラーに当たります
+type ClassifiedError struct {
+	Message string
+	Class   ErrorClass
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Message
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Class.Err()
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{name: "正常系: 包んだ分類のエラー", err: fmt.Errorf("投稿に失敗しました: %w", ErrRateLimited), want: ErrorClassRateLimited},
+		{name: "正常系: 本文が長すぎる", err: BlueskyCapabilities.Validate(strings.Repeat("あ", 301)), want: ErrorClassQuoteTooLong},
+		{name: "正常系: 文字列から戻したエラー", err: &ClassifiedError{Message: "認証切れ", Class: ErrorClassAuthExpired}, want: ErrorClassAuthExpired},
+		{name: "正常系: 分類できないエラー", err: errors.New("不明なエラー"), want: ""},
+		{name: "正常系: 不明な分類の名前", err: &ClassifiedError{Message: "不明", Class: "unknown"}, want: ""},
+		{name: "正常系: nil", err: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultPostTemplate は投稿本文のデフォルトのテンプレートです。
+// 区切りがデフォルトの場合は Quote.PostText と同じ「本文\n- 著者」です
+const DefaultPostTemplate = "{{.Text}}{{if .Author}}\n{{.Attribution}}{{end}}"
+
+// PostData はテンプレートで使える名言の値です
+type PostData struct {
+	ID          string
+	Text        string
+	Author      string
+	Attribution string // 区切りを付けた著者（例: "― 著者"）。著者がない場合は空です
+	Decoration  string // 今日の装飾（例: "🎄"）
+	Tags        []string
+	Platform    string
+}
+
+// Formatter は投稿先のプラットフォームごとのテンプレートで名言を整形し、
+// そのプラットフォームの長さの上限に収まるかを確認します
+type Formatter struct {
+	fallback    *template.Template
+	platforms   map[string]*template.Template
+	hashtags    *Hashtags    // 任意。本文の後に付けるハッシュタグ
+	decorations *Decorations // 任意。日付や曜日で変わる装飾
+	typo        Typography
+}
+
+// FormatterOption は Formatter の任意の設定です
+type FormatterOption func(*Formatter)
+
+// WithHashtags は整形した本文の後の行にハッシュタグを付けます。長さの上限はハッシュタグを含めて確認します
+func WithHashtags(hashtags *Hashtags) FormatterOption {
+	return func(f *Formatter) {
+		f.hashtags = hashtags
+	}
+}
+
+// WithDecorations は日付や曜日で変わる絵文字などの装飾を本文の前後に付けます。
+// 長さの上限は装飾を含めて確認します
+func WithDecorations(decorations *Decorations) FormatterOption {
+	return func(f *Formatter) {
+		f.decorations = decorations
+	}
+}
+
+// WithTypography は著者の区切りと、引用符・三点リーダーの表記を設定します
+func WithTypography(typography Typography) FormatterOption {
+	return func(f *Formatter) {
+		f.typo = typography
+	}
+}
+
+// ParsePostTemplate は投稿本文のテンプレートを解析します。
+// {{.Text}}、{{.Author}}、{{.Attribution}}、{{.Decoration}}、{{.ID}}、{{.Tags}}、{{.Platform}} が使えます
+func ParsePostTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("投稿のテンプレート %s の解析に失敗しました: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// NewFormatter は Formatter を作成します。fallback が空の場合は DefaultPostTemplate を使い、
+// platforms にないプラットフォーム（または空のテンプレート）には fallback を使います
+func NewFormatter(fallback string, platforms map[string]string, opts ...FormatterOption) (*Formatter, error) {
+	if fallback == "" {
+		fallback = DefaultPostTemplate
+	}
+	f := &Formatter{platforms: map[string]*template.Template{}, typo: DefaultTypography}
+	var err error
+	if f.fallback, err = ParsePostTemplate("default", fallback); err != nil {
+		return nil, err
+	}
+	for platform, text := range platforms {
+		if text == "" {
+			continue
+		}
+		if f.platforms[platform], err = ParsePostTemplate(platform, text); err != nil {
+			return nil, err
+		}
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f, nil
+}
+
+// DefaultFormatter はすべてのプラットフォームで DefaultPostTemplate を使う Formatter を返します
+func DefaultFormatter() *Formatter {
+	f, err := NewFormatter("", nil)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Format は caps.Platform のテンプレートで名言を整形して装飾とハッシュタグを付け、caps の上限に収まるかを確認します
+func (f *Formatter) Format(q *Quote, caps Capabilities) (string, error) {
+	text, err := f.Render(q, caps)
+	if err != nil {
+		return "", err
+	}
+	if err := caps.Validate(text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// Render は Format と同じように整形しますが、長さの上限は確認しません
+func (f *Formatter) Render(q *Quote, caps Capabilities) (string, error) {
+	tmpl, ok := f.platforms[caps.Platform]
+	if !ok {
+		tmpl = f.fallback
+	}
+
+	var b strings.Builder
+	var decoration string
+	if f.decorations != nil {
+		decoration = f.decorations.For(f.decorations.now())
+	}
+	author := f.typo.Normalize(q.Author)
+	data := PostData{
+		ID:          q.ID,
+		Text:        f.typo.Normalize(q.Text),
+		Author:      author,
+		Attribution: f.typo.Attribution(author),
+		Decoration:  decoration,
+		Tags:        q.Tags,
+		Platform:    caps.Platform,
+	}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("投稿のテンプレートの実行に失敗しました: %w", err)
+	}
+	text := b.String()
+	if f.decorations != nil {
+		text = f.decorations.apply(text, decoration)
+	}
+	if f.hashtags != nil {
+		if tags := f.hashtags.For(q); len(tags) > 0 {
+			text += "\n" + FormatHashtags(tags)
+		}
+	}
+	return text, nil
+}
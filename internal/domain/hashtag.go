@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ハッシュタグの付け方
+const (
+	// HashtagModeFixed は設定したハッシュタグをすべての投稿に付けます
+	HashtagModeFixed = "fixed"
+	// HashtagModeRotate は設定したハッシュタグを投稿ごとに順番に付けます
+	HashtagModeRotate = "rotate"
+)
+
+// MaxHashtagLength はBlueskyのハッシュタグの上限です（# を除く文字数）
+const MaxHashtagLength = 64
+
+// Hashtags は投稿の末尾に付けるハッシュタグを決めます。
+// 名言のタグに対応するハッシュタグと、固定またはローテーションのハッシュタグを組み合わせます
+type Hashtags struct {
+	mode    string
+	tags    []string
+	perPost int
+	mapping map[string]string // 名言のタグ（小文字）からハッシュタグ
+
+	mu   sync.Mutex
+	next int // ローテーションで次に使うハッシュタグの位置
+}
+
+// NewHashtags は Hashtags を作成します。perPost はローテーションで1回の投稿に付ける数です。
+// ハッシュタグは # の有無を問わずに指定できます
+func NewHashtags(mode string, tags []string, perPost int, mapping map[string]string) (*Hashtags, error) {
+	switch mode {
+	case HashtagModeFixed, HashtagModeRotate:
+	default:
+		return nil, fmt.Errorf("不明なハッシュタグの付け方です: %s", mode)
+	}
+	if mode == HashtagModeRotate && perPost < 1 {
+		return nil, fmt.Errorf("ローテーションで付けるハッシュタグの数は1以上で指定してください: %d", perPost)
+	}
+
+	h := &Hashtags{mode: mode, perPost: perPost, mapping: map[string]string{}}
+	for _, tag := range tags {
+		normalized, err := NormalizeHashtag(tag)
+		if err != nil {
+			return nil, err
+		}
+		h.tags = append(h.tags, normalized)
+	}
+	for quoteTag, tag := range mapping {
+		normalized, err := NormalizeHashtag(tag)
+		if err != nil {
+			return nil, err
+		}
+		h.mapping[strings.ToLower(quoteTag)] = normalized
+	}
+	return h, nil
+}
+
+// For は名言に付けるハッシュタグを # を除いて返します。名言のタグに対応するものが先で、
+// 同じハッシュタグは1回だけ付けます。ローテーションでは呼び出すたびに次のハッシュタグに進みます
+func (h *Hashtags) For(q *Quote) []string {
+	var result []string
+	seen := map[string]bool{}
+	add := func(tag string) {
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			result = append(result, tag)
+		}
+	}
+
+	for _, quoteTag := range q.Tags {
+		if tag, ok := h.mapping[strings.ToLower(quoteTag)]; ok {
+			add(tag)
+		}
+	}
+
+	if h.mode == HashtagModeRotate && len(h.tags) > 0 {
+		h.mu.Lock()
+		for i := 0; i < min(h.perPost, len(h.tags)); i++ {
+			add(h.tags[(h.next+i)%len(h.tags)])
+		}
+		h.next = (h.next + h.perPost) % len(h.tags)
+		h.mu.Unlock()
+	} else {
+		for _, tag := range h.tags {
+			add(tag)
+		}
+	}
+	return result
+}
+
+// NormalizeHashtag は先頭の # を取り除き、ハッシュタグとして使えるかを確認します
+func NormalizeHashtag(tag string) (string, error) {
+	trimmed := strings.TrimLeft(strings.TrimSpace(tag), "#＃")
+	if trimmed == "" {
+		return "", fmt.Errorf("ハッシュタグが空です: %q", tag)
+	}
+	if strings.IndexFunc(trimmed, unicode.IsSpace) >= 0 {
+		return "", fmt.Errorf("ハッシュタグに空白は使えません: %q", tag)
+	}
+	if utf8.RuneCountInString(trimmed) > MaxHashtagLength {
+		return "", fmt.Errorf("ハッシュタグが長すぎます（上限は%d文字）: %q", MaxHashtagLength, tag)
+	}
+	if strings.IndexFunc(trimmed, func(r rune) bool { return !unicode.IsDigit(r) && !unicode.IsPunct(r) }) < 0 {
+		return "", fmt.Errorf("数字や記号だけのハッシュタグは使えません: %q", tag)
+	}
+	return trimmed, nil
+}
+
+// FormatHashtags はハッシュタグを空白区切りの "#tag" の形式にします
+func FormatHashtags(tags []string) string {
+	items := make([]string, len(tags))
+	for i, tag := range tags {
+		items[i] = "#" + tag
+	}
+	return strings.Join(items, " ")
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHashtags_For(t *testing.T) {
+	quote := &Quote{Text: "名言", Author: "著者", Tags: []string{"Philosophy", "life"}}
+
+	tests := []struct {
+		name    string
+		mode    string
+		tags    []string
+		perPost int
+		mapping map[string]string
+		quote   *Quote
+		want    [][]string // 呼び出しごとの結果
+	}{
+		{
+			name:  "正常系: 固定のハッシュタグ",
+			mode:  HashtagModeFixed,
+			tags:  []string{"#名言", "quote"},
+			quote: quote,
+			want:  [][]string{{"名言", "quote"}, {"名言", "quote"}},
+		},
+		{
+			name:    "正常系: ローテーション",
+			mode:    HashtagModeRotate,
+			tags:    []string{"a", "b", "c"},
+			perPost: 2,
+			quote:   &Quote{Text: "名言"},
+			want:    [][]string{{"a", "b"}, {"c", "a"}, {"b", "c"}},
+		},
+		{
+			name:    "正常系: 名言のタグに対応するハッシュタグが先で、重複しない",
+			mode:    HashtagModeFixed,
+			tags:    []string{"哲学", "quote"},
+			mapping: map[string]string{"philosophy": "#哲学", "work": "仕事"},
+			quote:   quote,
+			want:    [][]string{{"哲学", "quote"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHashtags(tt.mode, tt.tags, tt.perPost, tt.mapping)
+			if err != nil {
+				t.Fatalf("NewHashtags() error = %v", err)
+			}
+			for i, want := range tt.want {
+				if got := h.For(tt.quote); !reflect.DeepEqual(got, want) {
+					t.Errorf("For() call %d = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: "#quote", want: "quote"},
+		{tag: "＃名言", want: "名言"},
+		{tag: "2024年", want: "2024年"},
+		{tag: "#", wantErr: true},
+		{tag: "two words", wantErr: true},
+		{tag: "2024", wantErr: true},
+		{tag: strings.Repeat("a", MaxHashtagLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := NormalizeHashtag(tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeHashtag(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeHashtag(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatter_FormatWithHashtags(t *testing.T) {
+	hashtags, err := NewHashtags(HashtagModeFixed, []string{"quote", "名言"}, 0, nil)
+	if err != nil {
+		t.Fatalf("NewHashtags() error = %v", err)
+	}
+	f, err := NewFormatter("", nil, WithHashtags(hashtags))
+	if err != nil {
+		t.Fatalf("NewFormatter() error = %v", err)
+	}
+
+	got, err := f.Format(&Quote{Text: "本文", Author: "著者"}, BlueskyCapabilities)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if want := "本文\n- 著者\n#quote #名言"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+
+	// 本文だけなら上限に収まっても、ハッシュタグを含めると超える場合はエラー
+	long := &Quote{Text: strings.Repeat("あ", MaxPostLength-10), Author: "著者"}
+	if _, err := f.Format(long, BlueskyCapabilities); err == nil {
+		t.Errorf("Format() error = nil, want error for text that exceeds the limit with hashtags")
+	}
+}
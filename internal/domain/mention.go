@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// Mention はアカウントへのメンションや返信です
+type Mention struct {
+	// Post はメンションした投稿です
+	Post PostRef
+	// Root はメンションした投稿が属するスレッドの最初の投稿です（スレッドの返信でなければ Post と同じ）
+	Root PostRef
+	// AuthorDID と AuthorHandle はメンションしたユーザーです
+	AuthorDID    string
+	AuthorHandle string
+	// AuthorMuted はボットのアカウントがユーザーをミュートしている（モデレーションリストを含む）ことを表します
+	AuthorMuted bool
+	// AuthorBlocked はボットのアカウントとユーザーの間にブロックがある（どちらからのブロックも含む）ことを表します
+	AuthorBlocked bool
+	// Text はメンションした投稿の本文です
+	Text string
+	// IndexedAt はメンションがサーバーに届いた時刻です
+	IndexedAt time.Time
+}
+
+// AccountFilter はメンションへの返信など、ほかのユーザーと関わる機能で相手にしないユーザーを判定します。
+// ボットのアカウントがミュート・ブロックしているユーザー、ボットをブロックしているユーザー、
+// 指定したDIDのユーザーとは関わりません
+type AccountFilter struct {
+	denied map[string]bool
+}
+
+// NewAccountFilter は dids（DENY_DIDS）のユーザーとも関わらない AccountFilter を作成します
+func NewAccountFilter(dids []string) AccountFilter {
+	denied := make(map[string]bool, len(dids))
+	for _, did := range dids {
+		denied[did] = true
+	}
+	return AccountFilter{denied: denied}
+}
+
+// Ignores はメンションしたユーザーと関わらない場合に、その理由（denied・blocked・muted）を返します。
+// 関わってよいユーザーの場合は空です
+func (f AccountFilter) Ignores(mention Mention) string {
+	switch {
+	case f.denied[mention.AuthorDID]:
+		return "denied"
+	case mention.AuthorBlocked:
+		return "blocked"
+	case mention.AuthorMuted:
+		return "muted"
+	}
+	return ""
+}
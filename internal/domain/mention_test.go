@@ -0,0 +1,25 @@
+package domain
+
+import "testing"
+
+func TestAccountFilter_Ignores(t *testing.T) {
+	filter := NewAccountFilter([]string{"did:plc:mallory"})
+	tests := []struct {
+		name    string
+		mention Mention
+		want    string
+	}{
+		{name: "正常系: 関わってよいユーザー", mention: Mention{AuthorDID: "did:plc:alice"}, want: ""},
+		{name: "正常系: DENY_DIDS のユーザー", mention: Mention{AuthorDID: "did:plc:mallory"}, want: "denied"},
+		{name: "正常系: ブロックしているユーザー", mention: Mention{AuthorDID: "did:plc:bob", AuthorBlocked: true, AuthorMuted: true}, want: "blocked"},
+		{name: "正常系: ミュートしているユーザー", mention: Mention{AuthorDID: "did:plc:carol", AuthorMuted: true}, want: "muted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Ignores(tt.mention); got != tt.want {
+				t.Errorf("Ignores() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
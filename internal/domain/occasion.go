@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Occasion は記念日や誕生日などの特別な日に投稿する名言です
+type Occasion struct {
+	// Date は投稿する日です。MM-DD は毎年、YYYY-MM-DD はその日だけ投稿します
+	Date string `json:"date"`
+	// QuoteID は投稿する名言ファイルの名言のIDです。Text とどちらか一方を指定します
+	QuoteID string `json:"quote,omitempty"`
+	// Text と Author は名言ファイルにない本文を投稿する場合に指定します
+	Text   string `json:"text,omitempty"`
+	Author string `json:"author,omitempty"`
+	// Replace はその日の定期投稿を行わず、特別な日の投稿だけにします
+	Replace bool `json:"replace,omitempty"`
+}
+
+// Quote は Text で指定した本文を名言にします。QuoteID で指定した場合は nil です
+func (o Occasion) Quote() *Quote {
+	if o.Text == "" {
+		return nil
+	}
+	return &Quote{Text: o.Text, Author: o.Author}
+}
+
+// Validate は日付の形式と、名言の指定を確認します
+func (o Occasion) Validate() error {
+	if _, _, err := parseOccasionDate(o.Date); err != nil {
+		return err
+	}
+	if (o.QuoteID == "") == (o.Text == "") {
+		return fmt.Errorf("%s: quote（名言のID）と text（本文）のどちらか一方を指定してください", o.Date)
+	}
+	if o.Author != "" && o.Text == "" {
+		return fmt.Errorf("%s: author は text と一緒に指定してください", o.Date)
+	}
+	return nil
+}
+
+// on は day（投稿する日の日付）が投稿する日かを返します
+func (o Occasion) on(day time.Time) bool {
+	year, md, err := parseOccasionDate(o.Date)
+	if err != nil {
+		return false
+	}
+	return md == newMonthDay(day) && (year == 0 || year == day.Year())
+}
+
+// parseOccasionDate は MM-DD または YYYY-MM-DD を解析します。MM-DD の場合 year は0です
+func parseOccasionDate(date string) (year int, md monthDay, err error) {
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		return t.Year(), newMonthDay(t), nil
+	}
+	// 02-29 を受け付けるよう、うるう年で解析する
+	if t, err := time.Parse("2006-01-02", "2000-"+date); err == nil {
+		return 0, newMonthDay(t), nil
+	}
+	return 0, 0, fmt.Errorf("日付の形式が正しくありません: %q（MM-DD または YYYY-MM-DD）", date)
+}
+
+// maxOccasionLookahead は次の特別な日を探す日数です。毎年の日付は必ずこの中に見つかります
+const maxOccasionLookahead = 366
+
+// Calendar は特別な日の投稿の予定です。特別な日の決まった時刻に、その日の名言を投稿します
+type Calendar struct {
+	occasions []Occasion
+	hour, min int
+	loc       *time.Location
+}
+
+// NewCalendar は occasions を at（HH:MM）に投稿する Calendar を作成します。日付と時刻は loc で解釈します
+func NewCalendar(occasions []Occasion, at string, loc *time.Location) (*Calendar, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return nil, fmt.Errorf("特別な日の投稿の時刻の形式が正しくありません: %q（HH:MM）", at)
+	}
+	for _, o := range occasions {
+		if err := o.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &Calendar{occasions: occasions, hour: t.Hour(), min: t.Minute(), loc: loc}, nil
+}
+
+// On は t の日に投稿する名言を、予定に書いた順に返します
+func (c *Calendar) On(t time.Time) []Occasion {
+	day := t.In(c.loc)
+	var occasions []Occasion
+	for _, o := range c.occasions {
+		if o.on(day) {
+			occasions = append(occasions, o)
+		}
+	}
+	return occasions
+}
+
+// Replaces は t の日の定期投稿を、特別な日の投稿に置き換えるかを返します
+func (c *Calendar) Replaces(t time.Time) bool {
+	for _, o := range c.On(t) {
+		if o.Replace {
+			return true
+		}
+	}
+	return false
+}
+
+// Next は after より後で、特別な日の投稿を行う最初の時刻を返します。
+// 1年以内に特別な日がない場合は1年後の時刻を返すため、その時刻にもう一度 Next で探してください
+func (c *Calendar) Next(after time.Time) time.Time {
+	local := after.In(c.loc)
+	var at time.Time
+	for i := 0; i <= maxOccasionLookahead; i++ {
+		at = time.Date(local.Year(), local.Month(), local.Day()+i, c.hour, c.min, 0, 0, c.loc)
+		if at.After(after) && len(c.On(at)) > 0 {
+			return at
+		}
+	}
+	return at
+}
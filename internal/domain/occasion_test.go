@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOccasion_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		occasion Occasion
+		wantErr  bool
+	}{
+		{name: "正常系: 毎年の日付と名言のID", occasion: Occasion{Date: "03-14", QuoteID: "einstein"}},
+		{name: "正常系: その日だけの日付と本文", occasion: Occasion{Date: "2025-05-01", Text: "10周年", Author: "運営"}},
+		{name: "正常系: うるう日", occasion: Occasion{Date: "02-29", QuoteID: "leap"}},
+		{name: "異常系: 日付の形式", occasion: Occasion{Date: "3/14", QuoteID: "einstein"}, wantErr: true},
+		{name: "異常系: 存在しない日付", occasion: Occasion{Date: "02-30", QuoteID: "einstein"}, wantErr: true},
+		{name: "異常系: 名言の指定なし", occasion: Occasion{Date: "03-14"}, wantErr: true},
+		{name: "異常系: 名言のIDと本文の両方", occasion: Occasion{Date: "03-14", QuoteID: "einstein", Text: "本文"}, wantErr: true},
+		{name: "異常系: 本文なしの著者", occasion: Occasion{Date: "03-14", QuoteID: "einstein", Author: "著者"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.occasion.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	calendar, err := NewCalendar([]Occasion{
+		{Date: "03-14", QuoteID: "einstein"},
+		{Date: "03-14", Text: "誕生日おめでとう", Replace: true},
+		{Date: "2025-05-01", QuoteID: "anniversary"},
+	}, "09:00", loc)
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+
+	// 正常系: 日付は loc で判定する（UTCでは3月13日）
+	piDay := time.Date(2025, 3, 13, 23, 0, 0, 0, time.UTC)
+	if got := calendar.On(piDay); len(got) != 2 || got[0].QuoteID != "einstein" {
+		t.Errorf("On() = %+v, want both occasions of 03-14", got)
+	}
+	if !calendar.Replaces(piDay) {
+		t.Errorf("Replaces() = false, want true")
+	}
+	if calendar.Replaces(piDay.AddDate(0, 0, 1)) {
+		t.Errorf("Replaces() on an ordinary day = true, want false")
+	}
+
+	tests := []struct {
+		name  string
+		after time.Time
+		want  time.Time
+	}{
+		{name: "正常系: その日の投稿時刻の前", after: time.Date(2025, 3, 14, 8, 0, 0, 0, loc), want: time.Date(2025, 3, 14, 9, 0, 0, 0, loc)},
+		{name: "正常系: その日の投稿時刻の後は次の特別な日", after: time.Date(2025, 3, 14, 9, 0, 0, 0, loc), want: time.Date(2025, 5, 1, 9, 0, 0, 0, loc)},
+		{name: "正常系: その日だけの日付を過ぎると翌年の毎年の日付", after: time.Date(2025, 5, 2, 0, 0, 0, 0, loc), want: time.Date(2026, 3, 14, 9, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 正常系: 1年以内に特別な日がなければ1年後にもう一度探す
+	once, _ := NewCalendar([]Occasion{{Date: "2020-01-01", QuoteID: "past"}}, "09:00", loc)
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, loc)
+	if got := once.Next(after); got.Before(after.AddDate(1, 0, 0)) {
+		t.Errorf("Next() = %v, want a year later", got)
+	}
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 投稿先のプラットフォーム名
+const (
+	PlatformMastodon = "mastodon"
+	PlatformTwitter  = "twitter"
+)
+
+// LengthUnit は投稿本文の長さの数え方です
+type LengthUnit string
+
+const (
+	// LengthGraphemes は書記素クラスタ数で数えます（絵文字の結合などは1文字）
+	LengthGraphemes LengthUnit = "graphemes"
+	// LengthCharacters はUnicodeのコードポイント数で数えます
+	LengthCharacters LengthUnit = "characters"
+	// LengthWeighted はTwitterの重み付きの文字数で数えます（CJKや絵文字は2文字）
+	LengthWeighted LengthUnit = "weighted"
+)
+
+// Capabilities は投稿先のプラットフォームが受け付ける投稿の制約です。
+// 名言の整形は、投稿先ごとのテンプレートで本文を組み立てた後、この制約で長さを確認します
+type Capabilities struct {
+	Platform  string
+	MaxLength int
+	Unit      LengthUnit
+}
+
+// 各プラットフォームの投稿の制約
+var (
+	BlueskyCapabilities  = Capabilities{Platform: PlatformBluesky, MaxLength: MaxPostLength, Unit: LengthGraphemes}
+	MastodonCapabilities = Capabilities{Platform: PlatformMastodon, MaxLength: 500, Unit: LengthCharacters}
+	TwitterCapabilities  = Capabilities{Platform: PlatformTwitter, MaxLength: 280, Unit: LengthWeighted}
+)
+
+// CapabilitiesFor はプラットフォーム名から投稿の制約を返します
+func CapabilitiesFor(platform string) (Capabilities, bool) {
+	for _, c := range []Capabilities{BlueskyCapabilities, MastodonCapabilities, TwitterCapabilities} {
+		if c.Platform == platform {
+			return c, true
+		}
+	}
+	return Capabilities{}, false
+}
+
+// Length はこのプラットフォームの数え方で text の長さを返します
+func (c Capabilities) Length(text string) int {
+	switch c.Unit {
+	case LengthGraphemes:
+		return CountGraphemes(text)
+	case LengthWeighted:
+		return weightedLength(text)
+	default:
+		return utf8.RuneCountInString(text)
+	}
+}
+
+// Validate は投稿の本文が空でなく、このプラットフォームの上限に収まるかを検証します
+func (c Capabilities) Validate(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("投稿の本文が空です")
+	}
+	if c.MaxLength > 0 {
+		if n := c.Length(text); n > c.MaxLength {
+			return fmt.Errorf("%w（%d文字、上限は%d文字）", ErrQuoteTooLong, n, c.MaxLength)
+		}
+	}
+	return nil
+}
+
+// CountGraphemes は書記素クラスタ数を数えます。結合文字、異体字セレクタ、ZWJで結合した絵文字、
+// 肌の色の修飾子、国旗（地域指示記号の組）、CRLFを前の文字と合わせて1文字として数える近似です
+func CountGraphemes(text string) int {
+	count := 0
+	prev := rune(-1)
+	joined := false   // 直前がZWJ
+	regional := false // 直前が組になっていない地域指示記号
+	for _, r := range text {
+		extends := joined ||
+			unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+			r == '\u200d' ||
+			(r >= 0xfe00 && r <= 0xfe0f) || (r >= 0xe0100 && r <= 0xe01ef) ||
+			(r >= 0x1f3fb && r <= 0x1f3ff) ||
+			(r >= 0xe0020 && r <= 0xe007f) ||
+			(r == '\n' && prev == '\r') ||
+			(regional && isRegionalIndicator(r))
+		if !extends || count == 0 {
+			count++
+		}
+
+		if isRegionalIndicator(r) {
+			regional = !regional
+		} else {
+			regional = false
+		}
+		joined = r == '\u200d'
+		prev = r
+	}
+	return count
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// weightedLength はTwitterの数え方で長さを返します。ラテン文字などは1文字、それ以外は2文字です
+// （URLの短縮は考慮しません）
+func weightedLength(text string) int {
+	n := 0
+	for _, r := range text {
+		switch {
+		case r <= 0x10ff,
+			r >= 0x2000 && r <= 0x200d,
+			r >= 0x2010 && r <= 0x201f,
+			r >= 0x2032 && r <= 0x2037:
+			n++
+		default:
+			n += 2
+		}
+	}
+	return n
+}
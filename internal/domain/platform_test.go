@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCapabilities_Length(t *testing.T) {
+	tests := []struct {
+		name string
+		caps Capabilities
+		text string
+		want int
+	}{
+		{
+			name: "Bluesky: ASCII",
+			caps: BlueskyCapabilities,
+			text: "hello",
+			want: 5,
+		},
+		{
+			name: "Bluesky: 結合文字",
+			caps: BlueskyCapabilities,
+			text: "éが",
+			want: 2,
+		},
+		{
+			name: "Bluesky: ZWJで結合した絵文字と肌の色",
+			caps: BlueskyCapabilities,
+			text: "👨‍👩‍👧‍👦👍🏽",
+			want: 2,
+		},
+		{
+			name: "Bluesky: 国旗は2つの地域指示記号で1文字",
+			caps: BlueskyCapabilities,
+			text: "🇯🇵🇺🇸",
+			want: 2,
+		},
+		{
+			name: "Bluesky: CRLF",
+			caps: BlueskyCapabilities,
+			text: "a\r\nb",
+			want: 3,
+		},
+		{
+			name: "Mastodon: コードポイント数",
+			caps: MastodonCapabilities,
+			text: "👍🏽あ",
+			want: 3,
+		},
+		{
+			name: "Twitter: CJKと絵文字は2文字",
+			caps: TwitterCapabilities,
+			text: "abあ👍",
+			want: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.caps.Length(tt.text); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatter_Format(t *testing.T) {
+	quote := &Quote{ID: "q1", Text: "我思う、ゆえに我あり。", Author: "デカルト", Tags: []string{"哲学"}}
+
+	tests := []struct {
+		name      string
+		fallback  string
+		platforms map[string]string
+		quote     *Quote
+		caps      Capabilities
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:  "正常系: デフォルトのテンプレート",
+			quote: quote,
+			caps:  BlueskyCapabilities,
+			want:  "我思う、ゆえに我あり。\n- デカルト",
+		},
+		{
+			name:  "正常系: 著者がない場合は本文のみ",
+			quote: &Quote{Text: "本文だけ"},
+			caps:  BlueskyCapabilities,
+			want:  "本文だけ",
+		},
+		{
+			name:      "正常系: プラットフォームごとのテンプレート",
+			fallback:  "{{.Text}} ({{.Author}})",
+			platforms: map[string]string{PlatformMastodon: "「{{.Text}}」— {{.Author}} #{{index .Tags 0}}"},
+			quote:     quote,
+			caps:      MastodonCapabilities,
+			want:      "「我思う、ゆえに我あり。」— デカルト #哲学",
+		},
+		{
+			name:      "正常系: テンプレートのないプラットフォームは共通のテンプレート",
+			fallback:  "{{.Text}} ({{.Author}})",
+			platforms: map[string]string{PlatformMastodon: "{{.Text}}"},
+			quote:     quote,
+			caps:      BlueskyCapabilities,
+			want:      "我思う、ゆえに我あり。 (デカルト)",
+		},
+		{
+			name:    "異常系: プラットフォームの上限を超える",
+			quote:   &Quote{Text: strings.Repeat("あ", 141), Author: "著者"},
+			caps:    TwitterCapabilities,
+			wantErr: true,
+		},
+		{
+			name:     "異常系: 存在しないフィールド",
+			fallback: "{{.Body}}",
+			quote:    quote,
+			caps:     BlueskyCapabilities,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFormatter(tt.fallback, tt.platforms)
+			if err != nil {
+				t.Fatalf("NewFormatter() error = %v", err)
+			}
+			got, err := f.Format(tt.quote, tt.caps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Format() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewFormatter("{{.Text", nil); err == nil {
+		t.Errorf("NewFormatter() with invalid template error = nil, want error")
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// PlatformBluesky は投稿先としてのBlueskyを表します
+const PlatformBluesky = "bluesky"
+
+// PostRef は投稿済みのメッセージへの参照です
+type PostRef struct {
+	Platform string
+	URI      string
+	CID      string
+	ID       string // 投稿先での投稿のID（Bluesky のレコードキー、Mastodon の投稿のIDなど）。わからない場合は空です
+	URL      string // ブラウザで投稿を開くURL。わからない場合は空です
+}
+
+// PublishedPost はアカウントが投稿済みの投稿です
+type PublishedPost struct {
+	Ref       PostRef
+	Text      string
+	CreatedAt time.Time
+}
+
+// Engagement は投稿への反応の数です
+type Engagement struct {
+	Likes   int
+	Reposts int
+	Replies int
+	Quotes  int
+}
+
+// Total は反応の合計です
+func (e Engagement) Total() int {
+	return e.Likes + e.Reposts + e.Replies + e.Quotes
+}
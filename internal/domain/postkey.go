@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// tidAlphabet は atproto の TID で使う、並べ替えても順番が変わらない base32 の文字です
+const tidAlphabet = "234567abcdefghijklmnopqrstuvwxyz"
+
+// NewPostKey は投稿の冪等キーを作成します。t と seed（リクエストIDなど）が同じなら同じキーになります。
+// キーは atproto の TID 形式（13文字）のため、そのまま投稿のレコードキーに使えます
+func NewPostKey(t time.Time, seed string) string {
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	clockID := uint64(h.Sum32() & 0x3ff)
+	// 先頭のビットは0、続く53ビットがマイクロ秒のUnix時刻、最後の10ビットがクロックID
+	v := (uint64(t.UnixMicro())&(1<<53-1))<<10 | clockID
+
+	key := make([]byte, 13)
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i] = tidAlphabet[v&0x1f]
+		v >>= 5
+	}
+	return string(key)
+}
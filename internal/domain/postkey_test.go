@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPostKey(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	key := NewPostKey(at, "3f2a9c1b7e4d8a60")
+
+	if len(key) != 13 || !strings.ContainsRune("234567abcdefghij", rune(key[0])) {
+		t.Errorf("NewPostKey() = %q, want a 13-character TID", key)
+	}
+	if got := NewPostKey(at, "3f2a9c1b7e4d8a60"); got != key {
+		t.Errorf("NewPostKey() = %q, want the same key %q", got, key)
+	}
+	if got := NewPostKey(at, "0000000000000000"); got == key {
+		t.Errorf("NewPostKey() with another seed = %q, want a different key", got)
+	}
+	// 後の時刻のキーは文字列として後に並ぶ
+	if later := NewPostKey(at.Add(time.Second), "3f2a9c1b7e4d8a60"); later <= key {
+		t.Errorf("NewPostKey(later) = %q, want after %q", later, key)
+	}
+}
@@ -0,0 +1,12 @@
+package domain
+
+// MaxProfileDescriptionLength はBlueskyのプロフィールの自己紹介の上限（書記素クラスタ数）です
+const MaxProfileDescriptionLength = 256
+
+// ProfileUpdate はアカウントのプロフィールの変更です。nil の項目は変更しません
+type ProfileUpdate struct {
+	// Description は自己紹介です
+	Description *string
+	// PinnedPost はプロフィールに固定する投稿です
+	PinnedPost *PostRef
+}
@@ -1,12 +1,180 @@
 package domain
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxPostLength はBlueskyの投稿本文の上限です（書記素クラスタ数）
+const MaxPostLength = 300
+
+// 翻訳の投稿の仕方（TRANSLATION_MODE）
+const (
+	TranslationModeReplace = "replace" // 翻訳がある名言は翻訳だけを投稿する
+	TranslationModeThread  = "thread"  // 原文を投稿し、翻訳を返信する
+)
+
+// contentIDPrefix は本文と著者から作ったIDの接頭辞です
+const contentIDPrefix = "q-"
+
 // Quote はドメインモデルとして名言とその著者を表します
 type Quote struct {
-	Text   string
-	Author string
+	// ID は名言を指す変わらないIDです。名言ファイルで省略した場合は、読み込むときに ContentID で補います
+	ID     string   `json:"id,omitempty"`
+	Text   string   `json:"text"`
+	Author string   `json:"author"`
+	Tags   []string `json:"tags,omitempty"`
+	// Weight は QUOTE_SELECTOR=weighted で選ばれる割合の重みです（省略または0の場合は1）
+	Weight float64 `json:"weight,omitempty"`
+	// Lang は Text の言語（BCP 47。例: ja）です。POST_LANG を設定した場合は投稿に言語として付けます
+	Lang string `json:"lang,omitempty"`
+	// Translations は言語ごとの本文の翻訳です（例: {"en": "Knowledge is power"}）
+	Translations map[string]string `json:"translations,omitempty"`
+}
+
+// ContentID は本文と著者から名言のIDを作ります。名言ファイルの並び替えや他の名言の追加・削除では変わりませんが、
+// 本文か著者を書き換えると変わります
+func ContentID(text, author string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(text) + "\x00" + strings.TrimSpace(author)))
+	return contentIDPrefix + hex.EncodeToString(sum[:5])
+}
+
+// NormalizeText は投稿の本文と名言の本文を比べるために、表記の揺れを取り除きます。
+// 全角・半角をそろえ（NFKC）、小文字にし、文字と数字以外（空白・句読点・記号・絵文字）を取り除きます
+func NormalizeText(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKC.String(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// StableID は名言のIDを返します。IDを指定していない場合は本文と著者から作ったIDです
+func (q *Quote) StableID() string {
+	if q.ID != "" {
+		return q.ID
+	}
+	return ContentID(q.Text, q.Author)
 }
 
 // Format は名言を表示用にフォーマットします
 func (q *Quote) Format() string {
 	return q.Text + "\n― " + q.Author
 }
+
+// PostText は名言を投稿する本文を返します。著者がない場合は本文のみです
+func (q *Quote) PostText() string {
+	if q.Author == "" {
+		return q.Text
+	}
+	return fmt.Sprintf("%s\n- %s", q.Text, q.Author)
+}
+
+// SelectionWeight は選ばれる割合の重みを返します。省略した場合は1です
+func (q *Quote) SelectionWeight() float64 {
+	if q.Weight <= 0 {
+		return 1
+	}
+	return q.Weight
+}
+
+// HasTag は名言に指定したタグが付いているかを返します（大文字と小文字は区別しません）
+func (q *Quote) HasTag(tag string) bool {
+	for _, t := range q.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Translation は lang の翻訳を返します。"en-US" の翻訳がない場合は "en" の翻訳を使います
+func (q *Quote) Translation(lang string) (string, bool) {
+	if lang == "" {
+		return "", false
+	}
+	for _, l := range []string{lang, BaseLanguage(lang)} {
+		for key, text := range q.Translations {
+			if strings.EqualFold(key, l) && strings.TrimSpace(text) != "" {
+				return text, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Localized は本文を lang の翻訳に置き換えた名言を返します。翻訳がない場合は名言をそのまま返します。
+// 翻訳してもIDは変わりません
+func (q *Quote) Localized(lang string) *Quote {
+	text, ok := q.Translation(lang)
+	if !ok {
+		return q
+	}
+	localized := *q
+	localized.ID = q.StableID()
+	localized.Text = text
+	localized.Lang = lang
+	return &localized
+}
+
+// BaseLanguage は言語タグの最初の部分（"en-US" の "en"）を返します
+func BaseLanguage(lang string) string {
+	base, _, _ := strings.Cut(lang, "-")
+	return base
+}
+
+// ValidLanguage は lang が言語タグ（"ja"、"en-US" など）の形式かを返します
+func ValidLanguage(lang string) bool {
+	for i, part := range strings.Split(lang, "-") {
+		if part == "" || len(part) > 8 || (i == 0 && (len(part) < 2 || len(part) > 3)) {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Validate は名言がそのまま投稿できるかを検証します
+func (q *Quote) Validate() error {
+	if strings.TrimSpace(q.Text) == "" {
+		return errors.New("名言の本文が空です")
+	}
+	if strings.TrimSpace(q.Author) == "" {
+		return errors.New("著者が空です")
+	}
+	if q.Weight < 0 {
+		return fmt.Errorf("重みは0以上で指定してください: %v", q.Weight)
+	}
+	if q.Lang != "" && !ValidLanguage(q.Lang) {
+		return fmt.Errorf("言語の形式が正しくありません: %s", q.Lang)
+	}
+	for lang, text := range q.Translations {
+		if !ValidLanguage(lang) {
+			return fmt.Errorf("翻訳の言語の形式が正しくありません: %s", lang)
+		}
+		if strings.TrimSpace(text) == "" {
+			return fmt.Errorf("%s の翻訳が空です", lang)
+		}
+		if err := ValidatePostText(q.Localized(lang).PostText()); err != nil {
+			return fmt.Errorf("%s の翻訳: %w", lang, err)
+		}
+	}
+	return ValidatePostText(q.PostText())
+}
+
+// ValidatePostText は投稿の本文がBlueskyの上限に収まるかを検証します
+func ValidatePostText(text string) error {
+	return BlueskyCapabilities.Validate(text)
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -53,3 +54,139 @@ func TestQuote_Format(t *testing.T) {
 		})
 	}
 }
+
+func TestQuote_StableID(t *testing.T) {
+	quote := Quote{Text: "我思う、ゆえに我あり。", Author: "ルネ・デカルト"}
+	id := quote.StableID()
+
+	if !strings.HasPrefix(id, "q-") || len(id) != 12 {
+		t.Errorf("StableID() = %q, want q- and 10 hex digits", id)
+	}
+	// 前後の空白では変わらず、本文か著者を変えると変わる
+	if got := (&Quote{Text: " 我思う、ゆえに我あり。\n", Author: "ルネ・デカルト"}).StableID(); got != id {
+		t.Errorf("StableID() with surrounding spaces = %q, want %q", got, id)
+	}
+	if got := (&Quote{Text: quote.Text, Author: "デカルト"}).StableID(); got == id {
+		t.Errorf("StableID() with another author = %q, want a different ID", got)
+	}
+	// 指定したIDはそのまま使う
+	if got := (&Quote{ID: "descartes", Text: quote.Text, Author: quote.Author}).StableID(); got != "descartes" {
+		t.Errorf("StableID() = %q, want descartes", got)
+	}
+}
+
+func TestQuote_Localized(t *testing.T) {
+	quote := &Quote{Text: "知は力なり", Author: "ベーコン", Lang: "ja", Translations: map[string]string{"en": "Knowledge is power", "pt-BR": "Conhecimento é poder"}}
+
+	tests := []struct {
+		name     string
+		lang     string
+		wantText string
+		wantLang string
+	}{
+		{name: "正常系: 翻訳がある言語", lang: "en", wantText: "Knowledge is power", wantLang: "en"},
+		{name: "正常系: 地域の翻訳がなければ言語の翻訳", lang: "en-GB", wantText: "Knowledge is power", wantLang: "en-GB"},
+		{name: "正常系: 大文字と小文字を区別しない", lang: "pt-br", wantText: "Conhecimento é poder", wantLang: "pt-br"},
+		{name: "正常系: 翻訳がない言語は原文", lang: "fr", wantText: "知は力なり", wantLang: "ja"},
+		{name: "正常系: 言語を指定しない", lang: "", wantText: "知は力なり", wantLang: "ja"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quote.Localized(tt.lang)
+			if got.Text != tt.wantText || got.Lang != tt.wantLang || got.Author != quote.Author {
+				t.Errorf("Localized(%q) = %+v, want %q in %s", tt.lang, got, tt.wantText, tt.wantLang)
+			}
+			if got.StableID() != quote.StableID() {
+				t.Errorf("Localized(%q).StableID() = %s, want %s", tt.lang, got.StableID(), quote.StableID())
+			}
+		})
+	}
+}
+
+func TestQuote_ValidateTranslations(t *testing.T) {
+	tests := []struct {
+		name         string
+		lang         string
+		translations map[string]string
+		wantErr      bool
+	}{
+		{name: "正常系: 翻訳あり", lang: "ja", translations: map[string]string{"en": "Knowledge is power"}},
+		{name: "異常系: 言語の形式が正しくない", lang: "japanese", wantErr: true},
+		{name: "異常系: 翻訳の言語の形式が正しくない", translations: map[string]string{"en_US": "Knowledge is power"}, wantErr: true},
+		{name: "異常系: 空の翻訳", translations: map[string]string{"en": " "}, wantErr: true},
+		{name: "異常系: 翻訳が長すぎる", translations: map[string]string{"en": strings.Repeat("a", MaxPostLength)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := &Quote{Text: "知は力なり", Author: "ベーコン", Lang: tt.lang, Translations: tt.translations}
+			if err := quote.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePostText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr bool
+	}{
+		{
+			name:    "正常系: 上限ちょうどの文字数",
+			text:    strings.Repeat("あ", MaxPostLength),
+			wantErr: false,
+		},
+		{
+			name:    "異常系: 空の本文",
+			text:    " \n",
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 文字数の上限を超える",
+			text:    strings.Repeat("a", MaxPostLength+1),
+			wantErr: true,
+		},
+		{
+			name:    "正常系: 結合した絵文字は1文字として数える",
+			text:    strings.Repeat("👨‍👩‍👧‍👦", 50),
+			wantErr: false,
+		},
+		{
+			name:    "異常系: 書記素クラスタ数の上限を超える",
+			text:    strings.Repeat("👍🏽", MaxPostLength+1),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePostText(tt.text); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePostText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{name: "正常系: 空白・改行・句読点の違いは無視する", a: "我思う、ゆえに我あり。", b: "我思う ゆえに\n我あり", same: true},
+		{name: "正常系: 全角・半角と大文字・小文字の違いは無視する", a: "Ｓｔａｙ　Ｈｕｎｇｒｙ!", b: "stay hungry", same: true},
+		{name: "正常系: 引用符と絵文字は無視する", a: "「知は力なり」📚", b: "知は力なり", same: true},
+		{name: "異常系: 文字が違う", a: "知は力なり", b: "知は力である", same: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeText(tt.a) == NormalizeText(tt.b); got != tt.same {
+				t.Errorf("NormalizeText(%q) = %q, NormalizeText(%q) = %q, want same = %v", tt.a, NormalizeText(tt.a), tt.b, NormalizeText(tt.b), tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 著者の前に付ける区切り
+const (
+	SeparatorHyphen        = "hyphen"         // - （ASCIIのハイフン）
+	SeparatorEmDash        = "em-dash"        // — （U+2014、英語圏）
+	SeparatorHorizontalBar = "horizontal-bar" // ― （U+2015、日本語の引用）
+	SeparatorBy            = "by"             // by
+)
+
+// separators は区切りの名前と実際の文字です。見た目の似た文字を取り違えないよう、名前で指定できます
+var separators = map[string]string{
+	SeparatorHyphen:        "-",
+	SeparatorEmDash:        "—",
+	SeparatorHorizontalBar: "―",
+	SeparatorBy:            "by",
+}
+
+// 引用符の正規化
+const (
+	QuotesKeep     = "keep"     // 変更しない
+	QuotesCurly    = "curly"    // "..." を “...” に、'...' を ‘...’ にする
+	QuotesStraight = "straight" // “...” や ‘...’ を "..." や '...' にする
+)
+
+// 三点リーダーの正規化
+const (
+	EllipsisKeep    = "keep"    // 変更しない
+	EllipsisUnicode = "unicode" // ... を … にする
+	EllipsisASCII   = "ascii"   // … を ... にする
+)
+
+// Typography は著者の区切りと、本文と著者の引用符・三点リーダーの表記を決めます
+type Typography struct {
+	separator string
+	quotes    string
+	ellipsis  string
+}
+
+// DefaultTypography は従来どおり「- 著者」とし、本文を変更しない表記です
+var DefaultTypography = Typography{separator: "-", quotes: QuotesKeep, ellipsis: EllipsisKeep}
+
+// NewTypography は Typography を作成します。separator には区切りの名前（hyphen, em-dash, horizontal-bar, by）か、
+// 区切りの文字そのものを指定できます。空の値はデフォルトです
+func NewTypography(separator, quotes, ellipsis string) (Typography, error) {
+	t := DefaultTypography
+	if separator != "" {
+		s, err := ResolveSeparator(separator)
+		if err != nil {
+			return Typography{}, err
+		}
+		t.separator = s
+	}
+	switch quotes {
+	case "":
+	case QuotesKeep, QuotesCurly, QuotesStraight:
+		t.quotes = quotes
+	default:
+		return Typography{}, fmt.Errorf("不明な引用符の表記です: %s（keep, curly, straight のいずれか）", quotes)
+	}
+	switch ellipsis {
+	case "":
+	case EllipsisKeep, EllipsisUnicode, EllipsisASCII:
+		t.ellipsis = ellipsis
+	default:
+		return Typography{}, fmt.Errorf("不明な三点リーダーの表記です: %s（keep, unicode, ascii のいずれか）", ellipsis)
+	}
+	return t, nil
+}
+
+// ResolveSeparator は区切りの名前を実際の文字にします。名前以外は空白を取り除いてそのまま使います
+func ResolveSeparator(separator string) (string, error) {
+	if s, ok := separators[strings.ToLower(separator)]; ok {
+		return s, nil
+	}
+	s := strings.TrimSpace(separator)
+	if s == "" || strings.ContainsAny(s, "\r\n") {
+		return "", fmt.Errorf("著者の区切りには空白や改行以外の文字を指定してください: %q", separator)
+	}
+	return s, nil
+}
+
+// Attribution は「区切り 著者」を返します。著者がない場合は空です
+func (t Typography) Attribution(author string) string {
+	if author == "" {
+		return ""
+	}
+	return t.separator + " " + author
+}
+
+// Normalize は引用符と三点リーダーの表記をそろえます
+func (t Typography) Normalize(text string) string {
+	switch t.ellipsis {
+	case EllipsisUnicode:
+		text = strings.ReplaceAll(text, "...", "…")
+	case EllipsisASCII:
+		text = strings.ReplaceAll(text, "…", "...")
+	}
+	switch t.quotes {
+	case QuotesCurly:
+		text = curlyQuotes(text)
+	case QuotesStraight:
+		text = strings.NewReplacer("“", `"`, "”", `"`, "‘", "'", "’", "'").Replace(text)
+	}
+	return text
+}
+
+// curlyQuotes はまっすぐな引用符を、前の文字から開き・閉じを判断して曲がった引用符にします。
+// 単語の途中の ' はアポストロフィ（’）にします
+func curlyQuotes(text string) string {
+	var b strings.Builder
+	prev := rune(0)
+	for _, r := range text {
+		opening := prev == 0 || unicode.IsSpace(prev) || strings.ContainsRune("([{“‘—―-", prev)
+		switch {
+		case r == '"' && opening:
+			b.WriteRune('“')
+		case r == '"':
+			b.WriteRune('”')
+		case r == '\'' && opening:
+			b.WriteRune('‘')
+		case r == '\'':
+			b.WriteRune('’')
+		default:
+			b.WriteRune(r)
+		}
+		prev = r
+	}
+	return b.String()
+}
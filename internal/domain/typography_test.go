@@ -0,0 +1,84 @@
+package domain
+
+import "testing"
+
+func TestTypography(t *testing.T) {
+	quote := &Quote{Text: `He said "don't wait..." and left`, Author: "Anon"}
+
+	tests := []struct {
+		name      string
+		separator string
+		quotes    string
+		ellipsis  string
+		quote     *Quote
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:  "正常系: デフォルトは従来どおり",
+			quote: quote,
+			want:  "He said \"don't wait...\" and left\n- Anon",
+		},
+		{
+			name:      "正常系: em dash と曲がった引用符、三点リーダー",
+			separator: SeparatorEmDash,
+			quotes:    QuotesCurly,
+			ellipsis:  EllipsisUnicode,
+			quote:     quote,
+			want:      "He said “don’t wait…” and left\n— Anon",
+		},
+		{
+			name:      "正常系: 日本語の区切りとまっすぐな引用符",
+			separator: SeparatorHorizontalBar,
+			quotes:    QuotesStraight,
+			ellipsis:  EllipsisASCII,
+			quote:     &Quote{Text: "“始めよ”…", Author: "著者"},
+			want:      "\"始めよ\"...\n― 著者",
+		},
+		{
+			name:      "正常系: by",
+			separator: "BY",
+			quote:     &Quote{Text: "Stay hungry.", Author: "Steve Jobs"},
+			want:      "Stay hungry.\nby Steve Jobs",
+		},
+		{
+			name:      "正常系: 任意の区切り文字",
+			separator: "〜",
+			quote:     &Quote{Text: "本文", Author: "著者"},
+			want:      "本文\n〜 著者",
+		},
+		{
+			name:    "異常系: 不明な引用符の表記",
+			quotes:  "fancy",
+			wantErr: true,
+		},
+		{
+			name:      "異常系: 空白だけの区切り",
+			separator: "  ",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typography, err := NewTypography(tt.separator, tt.quotes, tt.ellipsis)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewTypography() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			f, err := NewFormatter("", nil, WithTypography(typography))
+			if err != nil {
+				t.Fatalf("NewFormatter() error = %v", err)
+			}
+			got, err := f.Format(tt.quote, BlueskyCapabilities)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
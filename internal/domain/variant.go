@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"math/rand/v2"
+)
+
+// Variant はA/Bテストで比べる投稿形式の1つです
+type Variant struct {
+	Name      string
+	Weight    int
+	Formatter *Formatter
+}
+
+// Variants は重みに応じて投稿形式を選びます
+type Variants struct {
+	variants []Variant
+	total    int
+	intn     func(n int) int // [0, n) の乱数
+}
+
+// NewVariants は Variants を作成します。重みが0の形式は選ばれません
+func NewVariants(variants []Variant) (*Variants, error) {
+	v := &Variants{intn: rand.IntN}
+	for _, variant := range variants {
+		if variant.Weight <= 0 {
+			continue
+		}
+		v.variants = append(v.variants, variant)
+		v.total += variant.Weight
+	}
+	if v.total == 0 {
+		return nil, errors.New("重みが1以上の投稿形式がありません")
+	}
+	return v, nil
+}
+
+// Pick は重みに応じて投稿形式を1つ選びます
+func (v *Variants) Pick() Variant {
+	n := v.intn(v.total)
+	for _, variant := range v.variants {
+		if n < variant.Weight {
+			return variant
+		}
+		n -= variant.Weight
+	}
+	return v.variants[len(v.variants)-1]
+}
+
+// All は選ばれる可能性のあるすべての投稿形式を返します
+func (v *Variants) All() []Variant {
+	return append([]Variant{}, v.variants...)
+}
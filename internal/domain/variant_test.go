@@ -0,0 +1,47 @@
+package domain
+
+import "testing"
+
+func TestVariants_Pick(t *testing.T) {
+	variants := []Variant{
+		{Name: "a", Weight: 1},
+		{Name: "b", Weight: 0},
+		{Name: "c", Weight: 3},
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{name: "正常系: 最初の形式の範囲", n: 0, want: "a"},
+		{name: "正常系: 重みが0の形式は飛ばされる", n: 1, want: "c"},
+		{name: "正常系: 最後の形式の範囲", n: 3, want: "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewVariants(variants)
+			if err != nil {
+				t.Fatalf("NewVariants() error = %v", err)
+			}
+			v.intn = func(n int) int {
+				if n != 4 {
+					t.Errorf("intn(%d), want total weight 4", n)
+				}
+				return tt.n
+			}
+			if got := v.Pick(); got.Name != tt.want {
+				t.Errorf("Pick() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewVariants([]Variant{{Name: "a", Weight: 0}}); err == nil {
+		t.Error("NewVariants() error = nil, want error for zero total weight")
+	}
+	v, _ := NewVariants(variants)
+	if got := len(v.All()); got != 2 {
+		t.Errorf("len(All()) = %d, want 2", got)
+	}
+}
@@ -0,0 +1,137 @@
+// Package events はボットで起きたことを型付きのイベントとして、ログ、集計、通知、
+// 管理APIのストリームなどの購読者に配ります。投稿の処理は購読者を知らずにイベントを発行するだけです
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type はイベントの種類です
+type Type string
+
+// イベントの種類
+const (
+	QuoteSelected      Type = "quote_selected"       // 投稿する名言を選んだ
+	PostSucceeded      Type = "post_succeeded"       // 投稿に成功した
+	PostFailed         Type = "post_failed"          // 投稿に失敗した
+	PostHeld           Type = "post_held"            // 投稿せずに承認待ちに入れた
+	PostDryRun         Type = "post_dry_run"         // DRY_RUN のため投稿しなかった
+	PostBackoff        Type = "post_backoff"         // 投稿が続けて失敗したため投稿間隔を延ばした
+	TokenRefreshed     Type = "token_refreshed"      // アクセストークンをリフレッシュした
+	TokenRefreshFailed Type = "token_refresh_failed" // アクセストークンのリフレッシュに失敗した
+
+	SessionStateChanged Type = "session_state_changed" // 投稿先のセッションの状態が変わった
+)
+
+// Event はボットで起きたことです。種類ごとに使わないフィールドは空です
+type Event struct {
+	Type       Type      `json:"type"`
+	At         time.Time `json:"at"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Trigger    string    `json:"trigger,omitempty"`
+	QuoteID    string    `json:"quoteId,omitempty"`
+	Text       string    `json:"text,omitempty"`
+	URI        string    `json:"uri,omitempty"`
+	Variant    string    `json:"variant,omitempty"`
+	ApprovalID string    `json:"approvalId,omitempty"`
+	Error      string    `json:"error,omitempty"`      // 機密情報を除去したエラー
+	ErrorClass string    `json:"errorClass,omitempty"` // エラーの分類（domain.ErrorClass）
+	// SessionState は SessionStateChanged の変わった後の状態です（active, relogin_required など）
+	SessionState string `json:"sessionState,omitempty"`
+	// Failures と Interval は PostBackoff の連続した失敗の回数と、延ばした投稿間隔（2h0m0s など）です
+	Failures int    `json:"failures,omitempty"`
+	Interval string `json:"interval,omitempty"`
+}
+
+// Handler はイベントを受け取る関数です。Publish の中で呼ばれるため、すぐに戻ってください
+type Handler func(Event)
+
+// streamBuffer は Subscribe の購読者ごとに溜めておくイベントの数です
+const streamBuffer = 64
+
+// Bus はイベントを購読者に配ります。複数のゴルーチンから同時に使えます
+type Bus struct {
+	mu       sync.Mutex
+	handlers []Handler
+	streams  map[chan Event]struct{}
+}
+
+// NewBus は購読者のいない Bus を作成します
+func NewBus() *Bus {
+	return &Bus{streams: map[chan Event]struct{}{}}
+}
+
+// Handle は Publish のたびに、発行したゴルーチンで呼ばれる購読者を登録します。
+// ログや集計のように、すぐに終わりイベントを取りこぼしてはいけない購読者に使います
+func (b *Bus) Handle(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Subscribe はイベントを受け取るチャネルを返します。管理APIのストリームのように、
+// 接続先の都合で受け取りが遅れる購読者に使います。受け取りが遅れて溢れたイベントは捨てるため、
+// 購読者が投稿を遅らせることはありません。購読をやめるときは返した関数を呼び出してください。チャネルはそのときに閉じます
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, streamBuffer)
+	b.mu.Lock()
+	b.streams[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.streams[ch]; ok {
+			delete(b.streams, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish はイベントを購読者に配ります。At が空の場合は現在の時刻にします
+func (b *Bus) Publish(event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	b.mu.Lock()
+	handlers := b.handlers
+	for ch := range b.streams {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// Counter はイベントを種類ごとに数えます。Bus.Handle に Handle を登録して使います
+type Counter struct {
+	mu     sync.Mutex
+	counts map[Type]int
+}
+
+// Handle はイベントを数えます
+func (c *Counter) Handle(event Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[Type]int{}
+	}
+	c.counts[event.Type]++
+}
+
+// Counts は起動してからのイベントの数を種類ごとに返します
+func (c *Counter) Counts() map[Type]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[Type]int, len(c.counts))
+	for t, n := range c.counts {
+		counts[t] = n
+	}
+	return counts
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var handled []Type
+	bus.Handle(func(e Event) { handled = append(handled, e.Type) })
+	counter := &Counter{}
+	bus.Handle(counter.Handle)
+	stream, unsubscribe := bus.Subscribe()
+
+	bus.Publish(Event{Type: QuoteSelected, QuoteID: "q1"})
+	bus.Publish(Event{Type: PostSucceeded, URI: "at://1"})
+
+	if len(handled) != 2 || handled[0] != QuoteSelected || handled[1] != PostSucceeded {
+		t.Errorf("handled = %v, want both events in order", handled)
+	}
+	if got := counter.Counts(); got[QuoteSelected] != 1 || got[PostSucceeded] != 1 {
+		t.Errorf("Counts() = %v", got)
+	}
+	first := <-stream
+	if first.Type != QuoteSelected || first.At.IsZero() {
+		t.Errorf("stream received %+v, want quote_selected with a time", first)
+	}
+
+	// 受け取らない購読者がいても Publish は止まらない
+	for range streamBuffer * 2 {
+		bus.Publish(Event{Type: PostFailed})
+	}
+	if got := counter.Counts()[PostFailed]; got != streamBuffer*2 {
+		t.Errorf("Counts()[post_failed] = %d, want %d", got, streamBuffer*2)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	received := 0
+	for range stream {
+		received++
+	}
+	if received != streamBuffer {
+		t.Errorf("stream received %d events after the first, want %d", received, streamBuffer)
+	}
+	// 購読をやめた後の Publish はチャネルに送らない
+	bus.Publish(Event{Type: PostSucceeded})
+}
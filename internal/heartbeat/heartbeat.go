@@ -0,0 +1,299 @@
+// Package heartbeat は名言を投稿しなかった日に、ボットが稼働していることを知らせる投稿を行います。
+// 前に知らせた投稿は削除するため、アカウントに残るのは最新の1件だけです
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// DefaultMessage は HEARTBEAT_MESSAGE が空の場合の本文のテンプレートです
+const DefaultMessage = `ボットは稼働しています（起動から{{.UptimeDays}}日）。` +
+	`{{if .Paused}}名言の投稿は一時停止しています{{else if not .NextPostAt.IsZero}}次の名言は {{.NextPostAt.Format "1/2 15:04"}} に投稿します{{end}}`
+
+// stateKey は状態ファイルに最後に知らせた日と投稿を保存するキーです
+const stateKey = "heartbeat"
+
+// checkInterval は知らせる時刻を過ぎたかを確かめる間隔です
+const checkInterval = time.Minute
+
+// dateLayout は知らせた日を保存する形式です
+const dateLayout = "2006-01-02"
+
+// Publisher は稼働を知らせる投稿を行う投稿先です
+type Publisher interface {
+	Publish(ctx context.Context, message string) (*domain.PostRef, error)
+}
+
+// Deleter は投稿を削除できる投稿先です。投稿先が実装していれば、前に知らせた投稿を削除します
+type Deleter interface {
+	DeletePost(ctx context.Context, uri string) error
+}
+
+// StatusSource は本文に書くボットの状態を返します
+type StatusSource interface {
+	Status() app.Status
+}
+
+// EventStream はボットのイベントを配信します
+type EventStream interface {
+	Subscribe() (<-chan events.Event, func())
+}
+
+// MessageData は本文のテンプレートに渡す値です
+type MessageData struct {
+	// StartedAt はボットを起動した時刻です
+	StartedAt time.Time
+	// Uptime と UptimeDays は起動してからの時間と日数です
+	Uptime     time.Duration
+	UptimeDays int
+	// NextPostAt は次の定期投稿の予定時刻です。決まっていなければゼロ値です
+	NextPostAt time.Time
+	// LastQuoteAt は最後に名言を投稿した時刻です。記録がなければゼロ値です
+	LastQuoteAt time.Time
+	// Paused は定期投稿を一時停止しているかです
+	Paused bool
+	// PoolSize は読み込んでいる名言の数です
+	PoolSize int
+}
+
+// saved は状態ファイルに保存する値です
+type saved struct {
+	// Date は最後に知らせた（または名言を投稿していたため省いた）日です
+	Date string `json:"date,omitempty"`
+	// URI は最後に知らせた投稿です
+	URI string `json:"uri,omitempty"`
+	// LastQuoteAt は最後に名言を投稿した時刻です。再起動してもその日に投稿したことを覚えておきます
+	LastQuoteAt time.Time `json:"lastQuoteAt,omitempty"`
+}
+
+// Beacon は毎日 HEARTBEAT_TIME に、その日に名言を投稿していなければ稼働を知らせます。
+// app.Server として App の実行中だけ動きます
+type Beacon struct {
+	publisher    Publisher
+	status       StatusSource
+	stream       EventStream
+	store        state.Store // 任意。再起動しても同じ日に二度知らせず、前の投稿を削除できるようにします
+	message      *template.Template
+	hour, min    int
+	loc          *time.Location
+	keepPrevious bool
+	timeout      time.Duration
+	dryRun       bool
+	logger       *slog.Logger
+	now          func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// saved は run のゴルーチンだけが使います
+	saved saved
+}
+
+// New は cfg の HEARTBEAT_* の設定で稼働を知らせる Beacon を作成します。store は nil でも構いません
+func New(cfg *config.Config, publisher Publisher, status StatusSource, stream EventStream, store state.Store) (*Beacon, error) {
+	text := cfg.HeartbeatMessage
+	if text == "" {
+		text = DefaultMessage
+	}
+	message, err := template.New("heartbeat").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("HEARTBEAT_MESSAGE の解析に失敗しました: %w", err)
+	}
+	at, err := time.Parse("15:04", cfg.HeartbeatTime)
+	if err != nil {
+		return nil, fmt.Errorf("稼働を知らせる時刻の形式が正しくありません: %q（HH:MM）", cfg.HeartbeatTime)
+	}
+	b := &Beacon{
+		publisher:    publisher,
+		status:       status,
+		stream:       stream,
+		store:        store,
+		message:      message,
+		hour:         at.Hour(),
+		min:          at.Minute(),
+		loc:          cfg.DisplayLocation(),
+		keepPrevious: cfg.HeartbeatKeepPrevious,
+		timeout:      cfg.PostTimeout,
+		dryRun:       cfg.DryRun,
+		logger:       logging.ModuleFor(cfg, "heartbeat"),
+		now:          time.Now,
+	}
+	if store != nil {
+		if _, err := store.Get(stateKey, &b.saved); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Start はイベントの購読を始め、バックグラウンドで知らせる時刻を待ちます
+func (b *Beacon) Start() error {
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	received, unsubscribe := b.stream.Subscribe()
+	b.wg.Add(1)
+	safego.Go(b.logger, "heartbeat", func() {
+		defer b.wg.Done()
+		defer unsubscribe()
+		safego.Loop(b.logger, "heartbeat", b.ctx.Done(), func() { b.run(received) })
+	})
+	return nil
+}
+
+// Shutdown は実行中の投稿を中断して停止します
+func (b *Beacon) Shutdown(ctx context.Context) error {
+	if b.cancel == nil {
+		return nil
+	}
+	b.cancel()
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Beacon) run(received <-chan events.Event) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	b.check()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case event := <-received:
+			if event.Type == events.PostSucceeded {
+				b.saved.LastQuoteAt = event.At
+				b.save()
+			}
+		case <-ticker.C:
+			b.check()
+		}
+	}
+}
+
+// check は知らせる時刻を過ぎていて、その日にまだ知らせていなければ知らせます
+func (b *Beacon) check() {
+	if err := b.beat(b.now()); err != nil {
+		b.logger.Warn("稼働を知らせる投稿に失敗しました", "error", redact.Error(err))
+	}
+}
+
+// Due は now の日に知らせる時刻を過ぎていて、その日をまだ扱っていないかを返します
+func (b *Beacon) Due(now time.Time) bool {
+	local := now.In(b.loc)
+	if b.saved.Date == local.Format(dateLayout) {
+		return false
+	}
+	at := time.Date(local.Year(), local.Month(), local.Day(), b.hour, b.min, 0, 0, b.loc)
+	return !local.Before(at)
+}
+
+// beat は知らせる時刻であれば、その日に名言を投稿していない場合に限って稼働を知らせます
+func (b *Beacon) beat(now time.Time) error {
+	if !b.Due(now) {
+		return nil
+	}
+	st := b.status.Status()
+	// 定期投稿しないレプリカは知らせない（リーダーが知らせる）
+	if st.Standby {
+		return nil
+	}
+	today := now.In(b.loc).Format(dateLayout)
+	if !b.saved.LastQuoteAt.IsZero() && b.saved.LastQuoteAt.In(b.loc).Format(dateLayout) == today {
+		b.logger.Debug("今日は名言を投稿したため稼働を知らせませんでした")
+		b.saved.Date = today
+		b.save()
+		return nil
+	}
+
+	text, err := b.Message(now)
+	if err != nil {
+		return err
+	}
+	if b.dryRun {
+		b.logger.Info("DRY_RUN のため稼働を知らせる投稿をしませんでした", "text", text)
+		b.saved.Date = today
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, b.timeout)
+	defer cancel()
+	ref, err := b.publisher.Publish(ctx, text)
+	if err != nil {
+		return err
+	}
+	previous := b.saved.URI
+	b.saved.Date = today
+	b.saved.URI = ref.URI
+	b.save()
+	b.logger.Info("稼働を知らせる投稿をしました", "uri", ref.URI)
+
+	if deleter, ok := b.publisher.(Deleter); ok && previous != "" && !b.keepPrevious {
+		if err := deleter.DeletePost(ctx, previous); err != nil {
+			b.logger.Warn("前に稼働を知らせた投稿の削除に失敗しました", "uri", previous, "error", redact.Error(err))
+		}
+	}
+	return nil
+}
+
+// Message は現在の状態から稼働を知らせる投稿の本文を作成します
+func (b *Beacon) Message(now time.Time) (string, error) {
+	st := b.status.Status()
+	data := MessageData{
+		StartedAt:   st.StartedAt.In(b.loc),
+		LastQuoteAt: b.saved.LastQuoteAt,
+		Paused:      st.Paused,
+		PoolSize:    st.PoolSize,
+	}
+	if !st.StartedAt.IsZero() {
+		data.Uptime = now.Sub(st.StartedAt)
+		data.UptimeDays = int(data.Uptime / (24 * time.Hour))
+	}
+	if !st.NextPostAt.IsZero() {
+		data.NextPostAt = st.NextPostAt.In(b.loc)
+	}
+	if !data.LastQuoteAt.IsZero() {
+		data.LastQuoteAt = data.LastQuoteAt.In(b.loc)
+	}
+
+	var sb strings.Builder
+	if err := b.message.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("稼働を知らせる投稿の本文の作成に失敗しました: %w", err)
+	}
+	text := strings.TrimSpace(sb.String())
+	if text == "" {
+		return "", fmt.Errorf("稼働を知らせる投稿の本文が空です")
+	}
+	return text, nil
+}
+
+func (b *Beacon) save() {
+	if b.store == nil {
+		return
+	}
+	if err := b.store.Put(stateKey, b.saved); err != nil {
+		b.logger.Warn("稼働を知らせる投稿の状態の保存に失敗しました", "error", err)
+	}
+}
@@ -0,0 +1,179 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// mockPublisher は投稿と削除を記録します
+type mockPublisher struct {
+	posts   []string
+	deleted []string
+}
+
+func (p *mockPublisher) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	p.posts = append(p.posts, message)
+	return &domain.PostRef{URI: fmt.Sprintf("at://did:plc:test/app.bsky.feed.post/heartbeat%d", len(p.posts))}, nil
+}
+
+func (p *mockPublisher) DeletePost(ctx context.Context, uri string) error {
+	p.deleted = append(p.deleted, uri)
+	return nil
+}
+
+type mockStatus struct {
+	status app.Status
+}
+
+func (s *mockStatus) Status() app.Status {
+	return s.status
+}
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		PostTimeout:     time.Minute,
+		HeartbeatTime:   "21:00",
+		DisplayTimezone: "UTC",
+	}
+}
+
+func newTestBeacon(t *testing.T, cfg *config.Config, store state.Store) (*Beacon, *mockPublisher, *mockStatus) {
+	t.Helper()
+	publisher := &mockPublisher{}
+	status := &mockStatus{status: app.Status{
+		StartedAt:  time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		NextPostAt: time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC),
+	}}
+	b, err := New(cfg, publisher, status, events.NewBus(), store)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	b.ctx = context.Background()
+	return b, publisher, status
+}
+
+func TestBeacon_Message(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		paused   bool
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "正常系: デフォルトのテンプレート",
+			want: "ボットは稼働しています（起動から2日）。次の名言は 1/4 09:00 に投稿します",
+		},
+		{
+			name:   "正常系: 一時停止中",
+			paused: true,
+			want:   "ボットは稼働しています（起動から2日）。名言の投稿は一時停止しています",
+		},
+		{
+			name:     "正常系: 起動からの時間を使うテンプレート",
+			template: "稼働中 {{.Uptime}}",
+			want:     "稼働中 60h0m0s",
+		},
+		{
+			name:     "異常系: 本文が空",
+			template: "{{if .Paused}}停止中{{end}}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.HeartbeatMessage = tt.template
+			b, _, status := newTestBeacon(t, cfg, nil)
+			status.status.Paused = tt.paused
+
+			got, err := b.Message(time.Date(2024, 1, 3, 21, 0, 0, 0, time.UTC))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Message() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Message() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeacon_Beat(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	b, publisher, _ := newTestBeacon(t, newTestConfig(), store)
+
+	// 知らせる時刻の前は知らせない
+	if err := b.beat(time.Date(2024, 1, 2, 20, 59, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("beat() error = %v", err)
+	}
+	if len(publisher.posts) != 0 {
+		t.Fatalf("posts = %q, want none before the time", publisher.posts)
+	}
+
+	// 時刻を過ぎたら1日1回だけ知らせる
+	for _, now := range []time.Time{time.Date(2024, 1, 2, 21, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 23, 0, 0, 0, time.UTC)} {
+		if err := b.beat(now); err != nil {
+			t.Fatalf("beat() error = %v", err)
+		}
+	}
+	if len(publisher.posts) != 1 {
+		t.Fatalf("posts = %q, want one heartbeat", publisher.posts)
+	}
+
+	// 名言を投稿した日は知らせない
+	b.saved.LastQuoteAt = time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+	if err := b.beat(time.Date(2024, 1, 3, 21, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("beat() error = %v", err)
+	}
+	if len(publisher.posts) != 1 {
+		t.Errorf("posts = %q, want no heartbeat on a day with a quote", publisher.posts)
+	}
+
+	// 再起動しても扱った日と前に知らせた投稿を覚えていて、新しく知らせたら消す
+	restarted, publisher, _ := newTestBeacon(t, newTestConfig(), store)
+	if err := restarted.beat(time.Date(2024, 1, 3, 22, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("beat() error = %v", err)
+	}
+	if len(publisher.posts) != 0 {
+		t.Fatalf("posts = %q, want no heartbeat on a day already handled before the restart", publisher.posts)
+	}
+	if err := restarted.beat(time.Date(2024, 1, 4, 21, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("beat() error = %v", err)
+	}
+	if len(publisher.posts) != 1 || len(publisher.deleted) != 1 || publisher.deleted[0] != "at://did:plc:test/app.bsky.feed.post/heartbeat1" {
+		t.Errorf("posts = %q, deleted = %q, want the previous heartbeat deleted", publisher.posts, publisher.deleted)
+	}
+}
+
+func TestBeacon_BeatStandby(t *testing.T) {
+	b, publisher, status := newTestBeacon(t, newTestConfig(), nil)
+	status.status.Standby = true
+
+	if err := b.beat(time.Date(2024, 1, 2, 21, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("beat() error = %v", err)
+	}
+	if len(publisher.posts) != 0 {
+		t.Errorf("posts = %q, want no heartbeat from a standby replica", publisher.posts)
+	}
+	// リーダーになれば同じ日のうちに知らせる
+	status.status.Standby = false
+	if err := b.beat(time.Date(2024, 1, 2, 21, 1, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("beat() error = %v", err)
+	}
+	if len(publisher.posts) != 1 {
+		t.Errorf("posts = %q, want one heartbeat", publisher.posts)
+	}
+}
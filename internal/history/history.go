@@ -0,0 +1,302 @@
+// Package history は投稿の試行をJSONL形式の監査ログ、または STATE_BACKEND のデータベースに記録します
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// logStream は state.Log に投稿履歴を記録するストリームの名前です
+const logStream = "history"
+
+// 投稿の結果
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultBlocked = "blocked" // 禁止語を含むため投稿しなかった
+)
+
+// Entry は監査ログの1行です
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	RequestID string    `json:"requestId,omitempty"`
+	Trigger   string    `json:"trigger,omitempty"`
+	Platform  string    `json:"platform"`
+	Quote     Quote     `json:"quote"`
+	Text      string    `json:"text,omitempty"`
+	Result    string    `json:"result"`
+	URI       string    `json:"uri,omitempty"`
+	CID       string    `json:"cid,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Variant   string    `json:"variant,omitempty"` // A/Bテストで使った投稿形式
+	Flags     []string  `json:"flags,omitempty"`   // 投稿に付けた印（例: deny:禁止語）
+	// PostID は投稿先によらない投稿のID（domain.NewPostKey）です。Outbox の冪等キーと同じ値で、
+	// ほかの投稿先（CROSS_POST_PLUGINS）に投稿した場合も1つのIDになります
+	PostID string `json:"postId,omitempty"`
+	// Posts は投稿先ごとの投稿です。Platform・URI・CID は互換性のために最初の投稿先の値も記録します
+	Posts []Post `json:"posts,omitempty"`
+}
+
+// Post は1つの投稿先での投稿です
+type Post struct {
+	Platform string `json:"platform"`
+	ID       string `json:"id,omitempty"` // 投稿先での投稿のID
+	URI      string `json:"uri,omitempty"`
+	CID      string `json:"cid,omitempty"`
+	URL      string `json:"url,omitempty"` // 投稿を表示するページ
+	// Error はほかの投稿先（CROSS_POST_PLUGINS）に投稿できなかった場合のエラーです。この場合 URI などは空です
+	Error string `json:"error,omitempty"`
+}
+
+// NewPost は投稿先の投稿から投稿履歴に記録する投稿を作ります
+func NewPost(ref *domain.PostRef) Post {
+	return Post{Platform: ref.Platform, ID: ref.ID, URI: ref.URI, CID: ref.CID, URL: ref.URL}
+}
+
+// PostOn は platform に投稿できた投稿を返します。Posts がない古い履歴では Platform・URI・CID から作ります
+func (e Entry) PostOn(platform string) (Post, bool) {
+	for _, post := range e.Posts {
+		if post.Platform == platform && post.Error == "" {
+			return post, true
+		}
+	}
+	if len(e.Posts) == 0 && e.URI != "" && (e.Platform == platform || e.Platform == "") {
+		return Post{Platform: platform, URI: e.URI, CID: e.CID}, true
+	}
+	return Post{}, false
+}
+
+// Quote は投稿した名言です
+type Quote struct {
+	// ID は名言のID（domain.Quote.StableID）です。名言ファイルを編集しても同じ名言を指します
+	ID     string `json:"id,omitempty"`
+	Text   string `json:"text"`
+	Author string `json:"author"`
+}
+
+// NewQuote は名言から投稿履歴に記録する名言を作ります
+func NewQuote(quote *domain.Quote) Quote {
+	return Quote{ID: quote.StableID(), Text: quote.Text, Author: quote.Author}
+}
+
+// Recorder は投稿の履歴を記録します
+type Recorder interface {
+	Record(entry Entry) error
+}
+
+// NewRecorder は設定した保存先に記録する Recorder を作成します。store が state.Log
+// （STATE_BACKEND がデータベース）の場合はそこに、それ以外は HISTORY_FILE に記録します。
+// どちらもない場合は nil を返します
+func NewRecorder(cfg *config.Config, store state.Store) (Recorder, error) {
+	if log, ok := store.(state.Log); ok {
+		return &LogRecorder{log: log}, nil
+	}
+	recorder, err := NewFileRecorder(cfg)
+	if recorder == nil {
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// Read は NewRecorder と同じ保存先から投稿履歴を古い順に読み込みます
+func Read(cfg *config.Config, store state.Store) ([]Entry, error) {
+	log, ok := store.(state.Log)
+	if !ok {
+		return ReadEntries(cfg.HistoryFile, cfg.HistoryMaxBackups)
+	}
+	values, err := log.Read(logStream)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(values))
+	for _, value := range values {
+		var entry Entry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// LogRecorder は state.Log に投稿履歴を記録します。ローテーションはしません
+type LogRecorder struct {
+	log state.Log
+}
+
+// Record はエントリを追記します
+func (r *LogRecorder) Record(entry Entry) error {
+	return r.log.Append(logStream, entry)
+}
+
+// FileRecorder はJSONLファイルに追記し、サイズが上限を超えるとローテーションします。
+// ローテーションしたファイルは path.1, path.2, ... の順に古くなります
+type FileRecorder struct {
+	path       string
+	maxSize    int64 // 0の場合はローテーションしない
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileRecorder は HISTORY_FILE に追記する FileRecorder を作成します。
+// HISTORY_FILE が未設定の場合は nil を返します
+func NewFileRecorder(cfg *config.Config) (*FileRecorder, error) {
+	if cfg.HistoryFile == "" {
+		return nil, nil
+	}
+
+	r := &FileRecorder{
+		path:       cfg.HistoryFile,
+		maxSize:    int64(cfg.HistoryMaxSizeMB) * 1024 * 1024,
+		maxBackups: cfg.HistoryMaxBackups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record はエントリを1行のJSONとして追記します
+func (r *FileRecorder) Record(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("履歴のエンコードに失敗しました: %w", err)
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("履歴の書き込みに失敗しました: %w", err)
+	}
+	return nil
+}
+
+// Close は履歴ファイルを閉じます
+func (r *FileRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// open は履歴ファイルを追記モードで開きます
+func (r *FileRecorder) open() error {
+	if dir := filepath.Dir(r.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("履歴ディレクトリの作成に失敗しました: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("履歴ファイルのオープンに失敗しました: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("履歴ファイルの情報の取得に失敗しました: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate は現在のファイルを path.1 に移し、古いファイルを1つずつずらします。
+// maxBackups を超えたファイルは削除されます
+func (r *FileRecorder) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("履歴ファイルのクローズに失敗しました: %w", err)
+	}
+
+	if r.maxBackups <= 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("履歴ファイルの削除に失敗しました: %w", err)
+		}
+		return r.open()
+	}
+
+	os.Remove(backupPath(r.path, r.maxBackups))
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(r.path, i), backupPath(r.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("履歴ファイルのローテーションに失敗しました: %w", err)
+		}
+	}
+	if err := os.Rename(r.path, backupPath(r.path, 1)); err != nil {
+		return fmt.Errorf("履歴ファイルのローテーションに失敗しました: %w", err)
+	}
+	return r.open()
+}
+
+// backupPath はn世代前の履歴ファイルのパスを返します
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// ReadEntries は履歴ファイルとローテーションした maxBackups 世代までのファイルを、古い順に読み込みます。
+// 存在しないファイルは無視し、解析できない行は読み飛ばします
+func ReadEntries(path string, maxBackups int) ([]Entry, error) {
+	var entries []Entry
+	for i := maxBackups; i >= 0; i-- {
+		p := path
+		if i > 0 {
+			p = backupPath(path, i)
+		}
+		read, err := readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, read...)
+	}
+	return entries, nil
+}
+
+// readFile は1つの履歴ファイルを読み込みます
+func readFile(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("履歴ファイルのオープンに失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// 書き込み中に途切れた行など
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("履歴ファイルの読み込みに失敗しました: %w", err)
+	}
+	return entries, nil
+}
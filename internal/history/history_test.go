@@ -0,0 +1,408 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// readEntries はJSONLファイルのエントリを読み込みます
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("os.Open() error = %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("json.Unmarshal(%q) error = %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestNewFileRecorder(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    func(dir string) string
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "正常系: HISTORY_FILE が未設定の場合は無効",
+			path:    func(dir string) string { return "" },
+			wantNil: true,
+			wantErr: false,
+		},
+		{
+			name:    "正常系: 存在しないディレクトリは作成される",
+			path:    func(dir string) string { return filepath.Join(dir, "logs", "history.jsonl") },
+			wantNil: false,
+			wantErr: false,
+		},
+		{
+			name: "異常系: ディレクトリを作成できない",
+			path: func(dir string) string {
+				blocker := filepath.Join(dir, "file")
+				os.WriteFile(blocker, nil, 0o600)
+				return filepath.Join(blocker, "history.jsonl")
+			},
+			wantNil: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{HistoryFile: tt.path(t.TempDir())}
+			recorder, err := NewFileRecorder(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFileRecorder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (recorder == nil) != tt.wantNil {
+				t.Errorf("NewFileRecorder() = %v, wantNil %v", recorder, tt.wantNil)
+			}
+			if recorder != nil {
+				recorder.Close()
+			}
+		})
+	}
+}
+
+func TestFileRecorder_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	recorder, err := NewFileRecorder(&config.Config{HistoryFile: path, HistoryMaxSizeMB: 10, HistoryMaxBackups: 5})
+	if err != nil {
+		t.Fatalf("NewFileRecorder() error = %v", err)
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := []Entry{
+		{
+			Timestamp: now,
+			RequestID: "req-1",
+			Trigger:   "scheduled",
+			Platform:  "bluesky",
+			Quote:     Quote{Text: "テスト名言", Author: "著者"},
+			Text:      "テスト名言\n- 著者",
+			Result:    ResultSuccess,
+			URI:       "at://did:plc:test/app.bsky.feed.post/1",
+			CID:       "bafytest",
+		},
+		{
+			Timestamp: now.Add(time.Hour),
+			RequestID: "req-2",
+			Trigger:   "manual",
+			Platform:  "bluesky",
+			Quote:     Quote{Text: "テスト名言", Author: "著者"},
+			Result:    ResultFailure,
+			Error:     "failed to create record",
+		},
+	}
+	for _, entry := range want {
+		if err := recorder.Record(entry); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	recorder.Close()
+
+	// 再度開くと既存のファイルに追記される
+	recorder, err = NewFileRecorder(&config.Config{HistoryFile: path, HistoryMaxSizeMB: 10, HistoryMaxBackups: 5})
+	if err != nil {
+		t.Fatalf("NewFileRecorder() error = %v", err)
+	}
+	want = append(want, Entry{Timestamp: now.Add(2 * time.Hour), Platform: "bluesky", Result: ResultSuccess})
+	if err := recorder.Record(want[2]); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	recorder.Close()
+
+	got := readEntries(t, path)
+	if len(got) != len(want) {
+		t.Fatalf("len(entries) = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Errorf("entries[%d].Timestamp = %v, want %v", i, got[i].Timestamp, want[i].Timestamp)
+		}
+		got[i].Timestamp = want[i].Timestamp
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("entries[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("os.Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+}
+
+func TestFileRecorder_Rotate(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxBackups  int
+		wantBackups int
+	}{
+		{
+			name:        "正常系: 保持する世代数を超えたファイルは削除される",
+			maxBackups:  2,
+			wantBackups: 2,
+		},
+		{
+			name:        "正常系: 世代数が0の場合はバックアップを残さない",
+			maxBackups:  0,
+			wantBackups: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "history.jsonl")
+			recorder, err := NewFileRecorder(&config.Config{HistoryFile: path, HistoryMaxBackups: tt.maxBackups})
+			if err != nil {
+				t.Fatalf("NewFileRecorder() error = %v", err)
+			}
+			defer recorder.Close()
+			// 1行ごとにローテーションするよう上限を小さくする
+			recorder.maxSize = 1
+
+			for i := 0; i < 5; i++ {
+				if err := recorder.Record(Entry{RequestID: string(rune('a' + i)), Result: ResultSuccess}); err != nil {
+					t.Fatalf("Record() error = %v", err)
+				}
+			}
+
+			// 現在のファイルには最新のエントリだけが残る
+			if got := readEntries(t, path); len(got) != 1 || got[0].RequestID != "e" {
+				t.Errorf("current entries = %+v, want [e]", got)
+			}
+			for i := 1; i <= tt.wantBackups; i++ {
+				got := readEntries(t, backupPath(path, i))
+				if want := string(rune('e' - i)); len(got) != 1 || got[0].RequestID != want {
+					t.Errorf("backup %d entries = %+v, want [%s]", i, got, want)
+				}
+			}
+			if _, err := os.Stat(backupPath(path, tt.wantBackups+1)); !os.IsNotExist(err) {
+				t.Errorf("backup %d exists, want removed", tt.wantBackups+1)
+			}
+		})
+	}
+}
+
+func TestReadEntries(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.jsonl")
+	write := func(p string, lines ...string) {
+		var data []byte
+		for _, line := range lines {
+			data = append(data, line+"\n"...)
+		}
+		if err := os.WriteFile(p, data, 0o600); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	write(backupPath(path, 2), `{"uri":"at://2","result":"success"}`)
+	write(backupPath(path, 1), `{"uri":"at://1","result":"success","variant":"a"}`, `{"uri":`)
+	write(path, `{"uri":"at://0","result":"failure"}`)
+
+	tests := []struct {
+		name       string
+		maxBackups int
+		want       []string
+	}{
+		{
+			name:       "正常系: 古い世代から順に読み込み、途切れた行は読み飛ばす",
+			maxBackups: 3,
+			want:       []string{"at://2", "at://1", "at://0"},
+		},
+		{
+			name:       "正常系: 世代数を超えるファイルは読まない",
+			maxBackups: 1,
+			want:       []string{"at://1", "at://0"},
+		},
+		{
+			name:       "正常系: バックアップなし",
+			maxBackups: 0,
+			want:       []string{"at://0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ReadEntries(path, tt.maxBackups)
+			if err != nil {
+				t.Fatalf("ReadEntries() error = %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.URI)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ReadEntries() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ReadEntries()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if entries, err := ReadEntries(filepath.Join(dir, "missing.jsonl"), 1); err != nil || len(entries) != 0 {
+		t.Errorf("ReadEntries(missing) = %v, %v", entries, err)
+	}
+}
+
+// memoryLog はメモリ上の state.Log です
+type memoryLog struct {
+	state.Store
+	streams map[string][]json.RawMessage
+}
+
+func (m *memoryLog) Append(stream string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	m.streams[stream] = append(m.streams[stream], raw)
+	return nil
+}
+
+func (m *memoryLog) Read(stream string) ([]json.RawMessage, error) {
+	return m.streams[stream], nil
+}
+
+func TestNewRecorder(t *testing.T) {
+	entry := Entry{Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Platform: "bluesky", Quote: Quote{ID: "q1", Text: "名言", Author: "著者"}, Result: ResultSuccess}
+	tests := []struct {
+		name  string
+		cfg   *config.Config
+		store state.Store
+		want  string
+	}{
+		{
+			name: "正常系: 保存先がなければ記録しない",
+			cfg:  &config.Config{},
+		},
+		{
+			name: "正常系: HISTORY_FILE に記録する",
+			cfg:  &config.Config{HistoryFile: "history.jsonl", HistoryMaxSizeMB: 10},
+			want: "*history.FileRecorder",
+		},
+		{
+			name:  "正常系: STATE_BACKEND のデータベースに記録する",
+			cfg:   &config.Config{HistoryFile: "history.jsonl"},
+			store: &memoryLog{streams: map[string][]json.RawMessage{}},
+			want:  "*history.LogRecorder",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.HistoryFile != "" {
+				tt.cfg.HistoryFile = filepath.Join(t.TempDir(), tt.cfg.HistoryFile)
+			}
+			recorder, err := NewRecorder(tt.cfg, tt.store)
+			if err != nil {
+				t.Fatalf("NewRecorder() error = %v", err)
+			}
+			if recorder == nil {
+				if tt.want != "" {
+					t.Errorf("NewRecorder() = nil, want %s", tt.want)
+				}
+				return
+			}
+			if got := fmt.Sprintf("%T", recorder); got != tt.want {
+				t.Fatalf("NewRecorder() = %s, want %s", got, tt.want)
+			}
+			if file, ok := recorder.(*FileRecorder); ok {
+				defer file.Close()
+			}
+			if err := recorder.Record(entry); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+			// 記録した保存先から読み込める
+			entries, err := Read(tt.cfg, tt.store)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if len(entries) != 1 || !reflect.DeepEqual(entries[0], entry) {
+				t.Errorf("Read() = %+v, want [%+v]", entries, entry)
+			}
+		})
+	}
+}
+
+func TestEntry_PostOn(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    Entry
+		platform string
+		want     string
+		wantOK   bool
+	}{
+		{
+			name: "正常系: 投稿先ごとの投稿から探す",
+			entry: Entry{Platform: "bluesky", URI: "at://1", Posts: []Post{
+				{Platform: "bluesky", URI: "at://1"},
+				{Platform: "mastodon", ID: "42", URI: "https://example.social/@quotes/42"},
+			}},
+			platform: "mastodon",
+			want:     "https://example.social/@quotes/42",
+			wantOK:   true,
+		},
+		{
+			name:     "正常系: posts のない古い履歴は uri を使う",
+			entry:    Entry{Platform: "bluesky", URI: "at://1"},
+			platform: "bluesky",
+			want:     "at://1",
+			wantOK:   true,
+		},
+		{
+			name:     "正常系: platform のない古い履歴は Bluesky の投稿とみなす",
+			entry:    Entry{URI: "at://1"},
+			platform: "bluesky",
+			want:     "at://1",
+			wantOK:   true,
+		},
+		{
+			name:     "異常系: ほかの投稿先の投稿",
+			entry:    Entry{Platform: "mastodon", URI: "https://example.social/@quotes/42"},
+			platform: "bluesky",
+		},
+		{
+			name: "異常系: 投稿できなかったほかの投稿先",
+			entry: Entry{Platform: "bluesky", URI: "at://1", Posts: []Post{
+				{Platform: "bluesky", URI: "at://1"},
+				{Platform: "mastodon", Error: "503 Service Unavailable"},
+			}},
+			platform: "mastodon",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.entry.PostOn(tt.platform)
+			if ok != tt.wantOK || got.URI != tt.want {
+				t.Errorf("PostOn() = %q, %v, want %q, %v", got.URI, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package history
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// TriggerSync は history sync で Bluesky の投稿から復元したエントリの投稿のきっかけです
+const TriggerSync = "sync"
+
+// Matcher は投稿の本文から、投稿した名言を探します
+type Matcher struct {
+	candidates []candidate
+}
+
+// candidate は照合に使う名言の本文（翻訳を含む）です
+type candidate struct {
+	text  string // domain.NormalizeText した本文
+	quote *domain.Quote
+}
+
+// NewMatcher は quotes の名言を探す Matcher を作成します
+func NewMatcher(quotes []domain.Quote) *Matcher {
+	m := &Matcher{}
+	for i := range quotes {
+		quote := &quotes[i]
+		texts := []string{quote.Text}
+		for _, translation := range quote.Translations {
+			texts = append(texts, translation)
+		}
+		for _, text := range texts {
+			if normalized := domain.NormalizeText(text); normalized != "" {
+				m.candidates = append(m.candidates, candidate{text: normalized, quote: quote})
+			}
+		}
+	}
+	// 短い名言が長い名言の一部になっている場合に、長い名言を優先する
+	sort.SliceStable(m.candidates, func(i, j int) bool {
+		return len(m.candidates[i].text) > len(m.candidates[j].text)
+	})
+	return m
+}
+
+// Match は本文を含む名言を返します。投稿には著者やハッシュタグが付くため、表記の揺れを取り除いた本文に
+// 名言の本文が含まれていれば一致とみなします。一致する名言がなければ nil を返します
+func (m *Matcher) Match(text string) *domain.Quote {
+	normalized := domain.NormalizeText(text)
+	for _, c := range m.candidates {
+		if strings.Contains(normalized, c.text) {
+			return c.quote
+		}
+	}
+	return nil
+}
+
+// Reconstruct は posts のうち existing に記録されていない投稿を名言と照合し、投稿履歴のエントリにして古い順に返します。
+// どの名言とも一致しなかった投稿は unmatched に返します
+func Reconstruct(posts []domain.PublishedPost, quotes []domain.Quote, existing []Entry) (entries []Entry, unmatched []domain.PublishedPost) {
+	recorded := map[string]bool{}
+	for _, entry := range existing {
+		if post, ok := entry.PostOn(domain.PlatformBluesky); ok {
+			recorded[post.URI] = true
+		}
+	}
+
+	sorted := append([]domain.PublishedPost(nil), posts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	matcher := NewMatcher(quotes)
+	for _, post := range sorted {
+		if recorded[post.Ref.URI] {
+			continue
+		}
+		recorded[post.Ref.URI] = true
+		quote := matcher.Match(post.Text)
+		if quote == nil {
+			unmatched = append(unmatched, post)
+			continue
+		}
+		entries = append(entries, Entry{
+			Timestamp: post.CreatedAt,
+			Trigger:   TriggerSync,
+			Platform:  post.Ref.Platform,
+			Quote:     NewQuote(quote),
+			Text:      post.Text,
+			Result:    ResultSuccess,
+			URI:       post.Ref.URI,
+			CID:       post.Ref.CID,
+			PostID:    post.Ref.ID,
+			Posts:     []Post{NewPost(&post.Ref)},
+		})
+	}
+	return entries, unmatched
+}
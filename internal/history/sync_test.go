@@ -0,0 +1,66 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	quotes := []domain.Quote{
+		{ID: "bacon", Text: "知は力なり", Author: "ベーコン"},
+		{ID: "bacon-long", Text: "知は力なり。されど使わねば無に等しい", Author: "作者不詳"},
+		{ID: "jobs", Text: "Stay hungry, stay foolish.", Author: "Steve Jobs", Translations: map[string]string{"ja": "ハングリーであれ、愚か者であれ"}},
+	}
+	matcher := NewMatcher(quotes)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "正常系: 著者とハッシュタグが付いた投稿", text: "知は力なり\n- ベーコン #名言", want: "bacon"},
+		{name: "正常系: 長い名言を優先する", text: "「知は力なり。されど使わねば無に等しい」― 作者不詳", want: "bacon-long"},
+		{name: "正常系: 表記の揺れは無視する", text: "STAY HUNGRY stay foolish!! 🍎", want: "jobs"},
+		{name: "正常系: 翻訳で投稿した名言", text: "ハングリーであれ。愚か者であれ。\n- スティーブ・ジョブズ", want: "jobs"},
+		{name: "異常系: 名言を含まない投稿", text: "本日はメンテナンスのためお休みします"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matcher.Match(tt.text)
+			if (got == nil) != (tt.want == "") || (got != nil && got.ID != tt.want) {
+				t.Errorf("Match(%q) = %+v, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconstruct(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := func(n string, at time.Time, text string) domain.PublishedPost {
+		uri := "at://did:plc:test/app.bsky.feed.post/" + n
+		return domain.PublishedPost{Ref: domain.PostRef{Platform: domain.PlatformBluesky, URI: uri, ID: n}, Text: text, CreatedAt: at}
+	}
+	quotes := []domain.Quote{{ID: "bacon", Text: "知は力なり", Author: "ベーコン"}}
+	// 新しい順に並んだ投稿
+	posts := []domain.PublishedPost{
+		post("3", base.Add(2*time.Hour), "知は力なり\n- ベーコン"),
+		post("2", base.Add(time.Hour), "お知らせ"),
+		post("1", base, "知は力なり\n- ベーコン"),
+	}
+	existing := []Entry{{Platform: domain.PlatformBluesky, URI: "at://did:plc:test/app.bsky.feed.post/3", Result: ResultSuccess}}
+
+	entries, unmatched := Reconstruct(posts, quotes, existing)
+	if len(entries) != 1 || entries[0].URI != "at://did:plc:test/app.bsky.feed.post/1" {
+		t.Fatalf("Reconstruct() entries = %+v, want only the post missing from the history", entries)
+	}
+	entry := entries[0]
+	if entry.Quote.ID != "bacon" || entry.Trigger != TriggerSync || entry.Result != ResultSuccess || !entry.Timestamp.Equal(base) || entry.PostID != "1" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if len(unmatched) != 1 || unmatched[0].Text != "お知らせ" {
+		t.Errorf("Reconstruct() unmatched = %+v, want the post without a quote", unmatched)
+	}
+}
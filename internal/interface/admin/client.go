@@ -0,0 +1,149 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/approval"
+	"github.com/littleironwaltz/quotebot/internal/deadletter"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/suggestion"
+)
+
+// Client talks to the admin API of a running bot, e.g. for `quotebot status`
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the admin API at ADMIN_ADDR
+func NewClient(cfg *config.Config) *Client {
+	baseURL := cfg.AdminAddr
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		baseURL = "http://" + baseURL
+	}
+	// without ADMIN_TOKEN the read-only token is enough for commands such as quotebot status
+	token := cfg.AdminToken
+	if token == "" {
+		token = cfg.AdminReadToken
+	}
+	// approving a post waits for the post itself, so callers set the deadline through ctx
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{},
+	}
+}
+
+// Status returns the status of the running bot
+func (c *Client) Status(ctx context.Context) (*app.Status, error) {
+	var status app.Status
+	if err := c.do(ctx, http.MethodGet, "/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Approvals returns the posts waiting for approval
+func (c *Client) Approvals(ctx context.Context) ([]approval.Item, error) {
+	var items []approval.Item
+	if err := c.do(ctx, http.MethodGet, "/approvals", &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Approve approves a post waiting for approval and returns the result of posting it
+func (c *Client) Approve(ctx context.Context, id string) (*app.PostResult, error) {
+	var result app.PostResult
+	if err := c.do(ctx, http.MethodPost, "/approvals/"+url.PathEscape(id)+"/approve", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Reject discards a post waiting for approval
+func (c *Client) Reject(ctx context.Context, id string) error {
+	var output map[string]string
+	return c.do(ctx, http.MethodPost, "/approvals/"+url.PathEscape(id)+"/reject", &output)
+}
+
+// DeadLetters returns the posts that failed at the platform and can be retried
+func (c *Client) DeadLetters(ctx context.Context) ([]deadletter.Item, error) {
+	var items []deadletter.Item
+	if err := c.do(ctx, http.MethodGet, "/dead-letters", &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// RetryDeadLetter posts a failed post again and returns the result
+func (c *Client) RetryDeadLetter(ctx context.Context, id string) (*app.PostResult, error) {
+	var result app.PostResult
+	if err := c.do(ctx, http.MethodPost, "/dead-letters/"+url.PathEscape(id)+"/retry", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DiscardDeadLetter discards a failed post without posting it
+func (c *Client) DiscardDeadLetter(ctx context.Context, id string) error {
+	var output map[string]string
+	return c.do(ctx, http.MethodPost, "/dead-letters/"+url.PathEscape(id)+"/discard", &output)
+}
+
+// Suggestions returns the quotes suggested in replies that wait for review
+func (c *Client) Suggestions(ctx context.Context) ([]suggestion.Item, error) {
+	var items []suggestion.Item
+	if err := c.do(ctx, http.MethodGet, "/suggestions", &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// AcceptSuggestion adds a suggested quote to the quotes file and returns the added quote
+func (c *Client) AcceptSuggestion(ctx context.Context, id string) (*domain.Quote, error) {
+	var quote domain.Quote
+	if err := c.do(ctx, http.MethodPost, "/suggestions/"+url.PathEscape(id)+"/accept", &quote); err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// RejectSuggestion discards a suggested quote
+func (c *Client) RejectSuggestion(ctx context.Context, id string) error {
+	var output map[string]string
+	return c.do(ctx, http.MethodPost, "/suggestions/"+url.PathEscape(id)+"/reject", &output)
+}
+
+// do sends an authenticated request and decodes the JSON response into output
+func (c *Client) do(ctx context.Context, method, path string, output interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the admin API at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("admin API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(output); err != nil {
+		return fmt.Errorf("failed to decode admin API response: %w", err)
+	}
+	return nil
+}
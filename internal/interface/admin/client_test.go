@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestClient_Status(t *testing.T) {
+	server := NewServer(&config.Config{AdminToken: testAdminToken}, &fakeController{paused: true})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		addr    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:    "正常系: URLのアドレス",
+			addr:    ts.URL,
+			token:   testAdminToken,
+			wantErr: false,
+		},
+		{
+			name:    "正常系: host:port形式のアドレス",
+			addr:    strings.TrimPrefix(ts.URL, "http://"),
+			token:   testAdminToken,
+			wantErr: false,
+		},
+		{
+			name:    "異常系: 誤ったトークン",
+			addr:    ts.URL,
+			token:   "wrong",
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 起動していない",
+			addr:    "127.0.0.1:1",
+			token:   testAdminToken,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&config.Config{AdminAddr: tt.addr, AdminToken: tt.token})
+			status, err := client.Status(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Status() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (!status.Paused || status.PoolSize != 3) {
+				t.Errorf("Status() = %+v", status)
+			}
+		})
+	}
+}
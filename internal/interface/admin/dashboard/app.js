@@ -0,0 +1,293 @@
+// quotebot の管理画面。管理APIを ADMIN_TOKEN で呼び出し、状態を定期的に表示し直します。
+// /events を購読できる間はイベントが届くたびに表示を更新します
+"use strict";
+
+const tokenKey = "quotebot.adminToken";
+const refreshInterval = 10000;
+const maxFeedItems = 50;
+
+const $ = (id) => document.getElementById(id);
+let refreshTimer = null;
+let stream = null;
+
+// 状態の表示に関わるイベント。届いたらすぐに /status を取得し直します
+const refreshOn = new Set(["post_succeeded", "post_failed", "post_held", "post_dry_run", "token_refreshed", "token_refresh_failed", "session_state_changed"]);
+
+// api は管理APIを呼び出します。管理画面は /ui/ で配信されるため、APIはひとつ上のパスにあります
+async function api(method, path) {
+  const response = await fetch(new URL("../" + path, location.href), {
+    method,
+    headers: { Authorization: "Bearer " + sessionStorage.getItem(tokenKey) },
+  });
+  if (response.status === 401) {
+    logout("トークンが正しくありません");
+    throw new Error("unauthorized");
+  }
+  const body = await response.json().catch(() => null);
+  if (!response.ok) {
+    throw new Error((body && body.error) || response.statusText);
+  }
+  return body;
+}
+
+function formatTime(value) {
+  if (!value || value.startsWith("0001-")) {
+    return "未定";
+  }
+  return new Date(value).toLocaleString();
+}
+
+function formatDuration(ms) {
+  const minutes = Math.floor(ms / 60000);
+  const days = Math.floor(minutes / 1440);
+  const hours = Math.floor((minutes % 1440) / 60);
+  if (days > 0) {
+    return `${days}日${hours}時間`;
+  }
+  return `${hours}時間${minutes % 60}分`;
+}
+
+function postResult(post) {
+  if (post.error) {
+    return "失敗: " + post.error;
+  }
+  if (post.approvalId) {
+    return "承認待ち: " + post.approvalId;
+  }
+  if (post.dryRun) {
+    return "DRY_RUN";
+  }
+  return "成功";
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function render(status) {
+  $("bot-name").textContent = status.bot || "";
+  const state = $("state");
+  state.className = "badge";
+  if (status.halted) {
+    state.textContent = "停止中（アカウントの停止: " + status.halted + "）";
+    state.classList.add("halted");
+  } else if (status.paused) {
+    state.textContent = "一時停止中";
+    state.classList.add("paused");
+  } else if (status.standby) {
+    state.textContent = "待機中（リーダーではありません）";
+    state.classList.add("standby");
+  } else {
+    state.textContent = status.dryRun ? "稼働中（DRY_RUN）" : "稼働中";
+  }
+  $("pause").hidden = status.paused;
+  $("resume").hidden = !status.paused;
+
+  $("next-post").textContent = formatTime(status.nextPostAt);
+  $("uptime").textContent = formatDuration(Date.now() - new Date(status.startedAt));
+  $("pool-size").textContent = status.poolSize;
+  $("token-expiry").textContent = status.tokenExpiresAt ? formatTime(status.tokenExpiresAt) : "不明";
+
+  const events = status.events || {};
+  const failed = events.post_failed || 0;
+  const attempts = (events.post_succeeded || 0) + failed;
+  $("error-rate").textContent = attempts === 0 ? "-" : `${((failed / attempts) * 100).toFixed(1)}%（${failed} / ${attempts}）`;
+
+  const posts = $("recent-posts");
+  posts.replaceChildren();
+  for (const post of (status.recentPosts || []).slice().reverse()) {
+    const row = posts.insertRow();
+    cell(row, formatTime(post.at));
+    cell(row, post.trigger);
+    cell(row, postResult(post), post.error ? "failed" : "");
+    const text = cell(row, "", "text");
+    if (post.url && /^https?:\/\//.test(post.url)) {
+      const link = document.createElement("a");
+      link.href = post.url;
+      link.rel = "noopener noreferrer";
+      link.target = "_blank";
+      link.textContent = post.text || post.url;
+      text.append(link);
+    } else {
+      text.textContent = post.text || "";
+    }
+  }
+
+  const errors = $("recent-errors");
+  errors.replaceChildren();
+  for (const entry of (status.recentErrors || []).slice().reverse()) {
+    const item = document.createElement("li");
+    item.textContent = `${formatTime(entry.at)} ${entry.message}`;
+    item.className = "failed";
+    errors.append(item);
+  }
+  if (errors.childElementCount === 0) {
+    errors.append(Object.assign(document.createElement("li"), { textContent: "なし" }));
+  }
+}
+
+async function refresh() {
+  try {
+    render(await api("GET", "status"));
+  } catch (err) {
+    if (err.message !== "unauthorized") {
+      $("message").textContent = "状態の取得に失敗しました: " + err.message;
+    }
+  }
+}
+
+// action はボタンの操作を管理APIに送り、結果を表示します
+async function action(path, describe) {
+  $("message").textContent = "実行中…";
+  try {
+    $("message").textContent = describe(await api("POST", path));
+  } catch (err) {
+    $("message").textContent = "失敗しました: " + err.message;
+  }
+  refresh();
+}
+
+function describeEvent(event) {
+  const parts = [event.type];
+  for (const key of ["trigger", "uri", "approvalId", "sessionState", "error"]) {
+    if (event[key]) {
+      parts.push(`${key}=${event[key]}`);
+    }
+  }
+  return parts.join(" ");
+}
+
+function showEvent(event) {
+  const feed = $("event-feed");
+  const item = document.createElement("li");
+  item.textContent = `${formatTime(event.at)} ${describeEvent(event)}`;
+  if (event.type.endsWith("_failed")) {
+    item.className = "failed";
+  }
+  feed.prepend(item);
+  while (feed.childElementCount > maxFeedItems) {
+    feed.lastElementChild.remove();
+  }
+  if (refreshOn.has(event.type)) {
+    refresh();
+  }
+}
+
+// subscribe は /events をストリームとして読み、届いたイベントを表示します。
+// EventSource は Authorization ヘッダーを送れないため fetch で読みます
+async function subscribe(controller) {
+  const response = await fetch(new URL("../events", location.href), {
+    headers: { Authorization: "Bearer " + sessionStorage.getItem(tokenKey) },
+    signal: controller.signal,
+  });
+  if (!response.ok || !response.body) {
+    throw new Error(response.statusText || "event stream is not available");
+  }
+  $("live").textContent = "ライブ";
+  const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = "";
+  for (;;) {
+    const { value, done } = await reader.read();
+    if (done) {
+      return;
+    }
+    buffer += value;
+    let end;
+    while ((end = buffer.indexOf("\n\n")) >= 0) {
+      const frame = buffer.slice(0, end);
+      buffer = buffer.slice(end + 2);
+      const data = frame
+        .split("\n")
+        .filter((line) => line.startsWith("data:"))
+        .map((line) => line.slice(5).trim())
+        .join("\n");
+      if (data) {
+        showEvent(JSON.parse(data));
+      }
+    }
+  }
+}
+
+// connect はストリームが切れても、切断されるまで数秒おきに繋ぎ直します
+async function connect() {
+  const controller = new AbortController();
+  stream = controller;
+  while (stream === controller) {
+    try {
+      await subscribe(controller);
+    } catch (err) {
+      if (controller.signal.aborted) {
+        return;
+      }
+    }
+    $("live").textContent = "再接続中…";
+    await new Promise((resolve) => setTimeout(resolve, 3000));
+  }
+}
+
+function disconnect() {
+  if (stream) {
+    stream.abort();
+    stream = null;
+  }
+  $("live").textContent = "";
+}
+
+function login(token) {
+  sessionStorage.setItem(tokenKey, token);
+  $("login").hidden = true;
+  $("dashboard").hidden = false;
+  refresh();
+  clearInterval(refreshTimer);
+  refreshTimer = setInterval(refresh, refreshInterval);
+  disconnect();
+  $("event-feed").replaceChildren();
+  connect();
+}
+
+function logout(message) {
+  sessionStorage.removeItem(tokenKey);
+  clearInterval(refreshTimer);
+  disconnect();
+  $("dashboard").hidden = true;
+  $("login").hidden = false;
+  $("state").textContent = message || "";
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  $("login").addEventListener("submit", (e) => {
+    e.preventDefault();
+    login($("token").value);
+    $("token").value = "";
+  });
+  $("logout").addEventListener("click", () => logout());
+  $("post-now").addEventListener("click", () => {
+    if (confirm("名言を今すぐ投稿しますか？")) {
+      action("post-now", (r) => (r.uri ? "投稿しました: " + r.uri : postResult(r)));
+    }
+  });
+  $("pause").addEventListener("click", () => action("pause", () => "一時停止しました"));
+  $("resume").addEventListener("click", () => action("resume", () => "再開しました"));
+  $("reload-quotes").addEventListener("click", () => action("reload-quotes", (r) => `名言を読み込み直しました（${r.count}件）`));
+  $("reload-config").addEventListener("click", () =>
+    action("reload-config", (r) => {
+      let text = "設定を読み込み直しました";
+      if (r.restartRequired && r.restartRequired.length > 0) {
+        text += `（再起動が必要: ${r.restartRequired.join(", ")}）`;
+      }
+      return text;
+    }),
+  );
+
+  const token = sessionStorage.getItem(tokenKey);
+  if (token) {
+    login(token);
+  } else {
+    logout();
+  }
+});
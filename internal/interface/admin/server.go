@@ -0,0 +1,511 @@
+// Package admin provides the authenticated HTTP API used to control a running bot
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/approval"
+	"github.com/littleironwaltz/quotebot/internal/auth"
+	"github.com/littleironwaltz/quotebot/internal/deadletter"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/internal/suggestion"
+)
+
+// Controller is the part of the bot exposed through the admin API
+type Controller interface {
+	PostNow(ctx context.Context) (*app.PostResult, error)
+	Pause()
+	Resume()
+	Status() app.Status
+	ReloadQuotes() (int, error)
+}
+
+// ConfigReloader reloads the settings that can change without a restart
+type ConfigReloader interface {
+	ReloadConfig() (*app.ReloadResult, error)
+}
+
+// Approver approves or rejects posts waiting in the approval queue
+type Approver interface {
+	Approvals() ([]approval.Item, error)
+	Approve(ctx context.Context, id string) (*app.PostResult, error)
+	Reject(id string) error
+}
+
+// DeadLetterQueue retries or discards posts that failed at the platform
+type DeadLetterQueue interface {
+	DeadLetters() ([]deadletter.Item, error)
+	RetryDeadLetter(ctx context.Context, id string) (*app.PostResult, error)
+	DiscardDeadLetter(id string) error
+}
+
+// SuggestionInbox accepts or rejects quotes suggested in replies to the bot's posts
+type SuggestionInbox interface {
+	Suggestions() ([]suggestion.Item, error)
+	Accept(id string) (*domain.Quote, error)
+	Reject(id string) error
+}
+
+// EventStream delivers the bot's events to a subscriber
+type EventStream interface {
+	Subscribe() (<-chan events.Event, func())
+}
+
+const (
+	defaultKeepAlive = 15 * time.Second // well under the usual 60s idle timeout of proxies
+	sseRetry         = 3 * time.Second
+)
+
+// Server serves the admin API
+type Server struct {
+	addr        string
+	token       string
+	readToken   string // optional, allows only GET requests
+	controller  Controller
+	reloader    ConfigReloader  // optional
+	approver    Approver        // optional
+	deadLetters DeadLetterQueue // optional
+	suggestions SuggestionInbox // optional
+	events      EventStream     // optional
+	logger      *slog.Logger
+	httpServer  *http.Server
+	listener    net.Listener
+	done        chan struct{} // closed on Shutdown to end the event streams
+	keepAlive   time.Duration // interval of the comments that keep idle event streams open
+}
+
+// Option configures optional parts of the admin API
+type Option func(*Server)
+
+// WithConfigReloader enables POST /reload-config
+func WithConfigReloader(reloader ConfigReloader) Option {
+	return func(s *Server) {
+		s.reloader = reloader
+	}
+}
+
+// WithApprover enables the /approvals endpoints
+func WithApprover(approver Approver) Option {
+	return func(s *Server) {
+		s.approver = approver
+	}
+}
+
+// WithDeadLetters enables the /dead-letters endpoints
+func WithDeadLetters(queue DeadLetterQueue) Option {
+	return func(s *Server) {
+		s.deadLetters = queue
+	}
+}
+
+// WithSuggestions enables the /suggestions endpoints
+func WithSuggestions(inbox SuggestionInbox) Option {
+	return func(s *Server) {
+		s.suggestions = inbox
+	}
+}
+
+// WithEvents enables GET /events
+func WithEvents(stream EventStream) Option {
+	return func(s *Server) {
+		s.events = stream
+	}
+}
+
+// NewServer creates a new admin API server listening on ADMIN_ADDR
+func NewServer(cfg *config.Config, controller Controller, opts ...Option) *Server {
+	s := &Server{
+		addr:       cfg.AdminAddr,
+		token:      cfg.AdminToken,
+		readToken:  cfg.AdminReadToken,
+		controller: controller,
+		logger:     logging.ModuleFor(cfg, "admin"),
+		done:       make(chan struct{}),
+		keepAlive:  defaultKeepAlive,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.httpServer = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the admin API routes, wrapped in authentication, and the web UI under /ui/
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /post-now", s.handlePostNow)
+	mux.HandleFunc("POST /pause", s.handlePause)
+	mux.HandleFunc("POST /resume", s.handleResume)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("POST /reload-quotes", s.handleReloadQuotes)
+	mux.HandleFunc("POST /reload-config", s.handleReloadConfig)
+	mux.HandleFunc("GET /approvals", s.handleApprovals)
+	mux.HandleFunc("POST /approvals/{id}/approve", s.handleApprove)
+	mux.HandleFunc("POST /approvals/{id}/reject", s.handleReject)
+	mux.HandleFunc("GET /dead-letters", s.handleDeadLetters)
+	mux.HandleFunc("POST /dead-letters/{id}/retry", s.handleRetryDeadLetter)
+	mux.HandleFunc("POST /dead-letters/{id}/discard", s.handleDiscardDeadLetter)
+	mux.HandleFunc("GET /suggestions", s.handleSuggestions)
+	mux.HandleFunc("POST /suggestions/{id}/accept", s.handleAcceptSuggestion)
+	mux.HandleFunc("POST /suggestions/{id}/reject", s.handleRejectSuggestion)
+	mux.HandleFunc("GET /events", s.handleEvents)
+
+	root := http.NewServeMux()
+	root.Handle("/", s.authenticate(mux))
+	root.Handle("GET /ui/", dashboardHandler())
+	root.Handle("GET /{$}", http.RedirectHandler("ui/", http.StatusFound))
+	return root
+}
+
+// Start starts listening and serves the API in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	s.listener = listener
+	s.logger.Info("管理APIを開始しました", "addr", listener.Addr().String())
+
+	safego.Go(s.logger, "admin", func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("管理APIが停止しました", "error", err)
+		}
+	})
+	return nil
+}
+
+// Addr returns the address the server is listening on, once started
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	// event streams never go idle, so end them before waiting for the connections
+	close(s.done)
+	return s.httpServer.Shutdown(ctx)
+}
+
+// authenticate requires the ADMIN_TOKEN as a bearer token, or the ADMIN_READ_TOKEN for requests that change nothing
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		switch {
+		case ok && auth.TokenMatches(token, s.token):
+		case ok && auth.TokenMatches(token, s.readToken):
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "ADMIN_READ_TOKEN is read-only"})
+				return
+			}
+		default:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="quotebot"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handlePostNow(w http.ResponseWriter, r *http.Request) {
+	result, err := s.controller.PostNow(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.controller.Pause()
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.controller.Resume()
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+func (s *Server) handleReloadQuotes(w http.ResponseWriter, r *http.Request) {
+	count, err := s.controller.ReloadQuotes()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"count": count})
+}
+
+func (s *Server) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if s.reloader == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": app.ErrReloadUnavailable.Error()})
+		return
+	}
+	result, err := s.reloader.ReloadConfig()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, app.ErrReloadUnavailable) {
+			status = http.StatusNotImplemented
+		}
+		writeJSON(w, status, map[string]string{"error": redact.String(err.Error())})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	if !s.requireApprover(w) {
+		return
+	}
+	items, err := s.approver.Approvals()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+		return
+	}
+	if items == nil {
+		items = []approval.Item{}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	if !s.requireApprover(w) {
+		return
+	}
+	result, err := s.approver.Approve(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, approval.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil && result == nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+	case err != nil:
+		writeJSON(w, http.StatusBadGateway, result)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func (s *Server) handleReject(w http.ResponseWriter, r *http.Request) {
+	if !s.requireApprover(w) {
+		return
+	}
+	err := s.approver.Reject(r.PathValue("id"))
+	switch {
+	case errors.Is(err, approval.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"id": r.PathValue("id")})
+	}
+}
+
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDeadLetters(w) {
+		return
+	}
+	items, err := s.deadLetters.DeadLetters()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+		return
+	}
+	if items == nil {
+		items = []deadletter.Item{}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleRetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDeadLetters(w) {
+		return
+	}
+	result, err := s.deadLetters.RetryDeadLetter(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, deadletter.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil && result == nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+	case err != nil:
+		writeJSON(w, http.StatusBadGateway, result)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func (s *Server) handleDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDeadLetters(w) {
+		return
+	}
+	err := s.deadLetters.DiscardDeadLetter(r.PathValue("id"))
+	switch {
+	case errors.Is(err, deadletter.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"id": r.PathValue("id")})
+	}
+}
+
+func (s *Server) handleSuggestions(w http.ResponseWriter, r *http.Request) {
+	if !s.requireSuggestions(w) {
+		return
+	}
+	items, err := s.suggestions.Suggestions()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+		return
+	}
+	if items == nil {
+		items = []suggestion.Item{}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleAcceptSuggestion(w http.ResponseWriter, r *http.Request) {
+	if !s.requireSuggestions(w) {
+		return
+	}
+	quote, err := s.suggestions.Accept(r.PathValue("id"))
+	switch {
+	case errors.Is(err, suggestion.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		// the suggestion stays in the inbox, e.g. when it duplicates an existing quote
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": redact.String(err.Error())})
+	default:
+		writeJSON(w, http.StatusOK, quote)
+	}
+}
+
+func (s *Server) handleRejectSuggestion(w http.ResponseWriter, r *http.Request) {
+	if !s.requireSuggestions(w) {
+		return
+	}
+	err := s.suggestions.Reject(r.PathValue("id"))
+	switch {
+	case errors.Is(err, suggestion.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": redact.String(err.Error())})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"id": r.PathValue("id")})
+	}
+}
+
+// handleEvents streams the bot's events as server-sent events until the client disconnects.
+// ?type=post_succeeded,post_failed limits the stream to the given event types
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "event stream is not enabled"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming is not supported"})
+		return
+	}
+	var types map[events.Type]bool
+	if param := r.URL.Query().Get("type"); param != "" {
+		types = map[events.Type]bool{}
+		for _, name := range strings.Split(param, ",") {
+			types[events.Type(strings.TrimSpace(name))] = true
+		}
+	}
+	received, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// reverse proxies such as nginx buffer responses unless told otherwise
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// clients reconnect after this many milliseconds when the stream drops
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(s.keepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-keepAlive.C:
+			// a comment line, ignored by clients, so that proxies do not close an idle stream
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event := <-received:
+			if types != nil && !types[event.Type] {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// requireApprover responds with 501 when the approval queue is not enabled
+func (s *Server) requireApprover(w http.ResponseWriter) bool {
+	if s.approver == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "approval queue is not enabled (APPROVAL_REQUIRED)"})
+		return false
+	}
+	return true
+}
+
+// requireDeadLetters responds with 501 when failed posts are not kept
+func (s *Server) requireDeadLetters(w http.ResponseWriter) bool {
+	if s.deadLetters == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "dead letter queue is not enabled (DEAD_LETTER_ENABLED)"})
+		return false
+	}
+	return true
+}
+
+// requireSuggestions responds with 501 when the suggestion inbox is not enabled
+func (s *Server) requireSuggestions(w http.ResponseWriter) bool {
+	if s.suggestions == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "suggestion inbox is not enabled (SUGGESTIONS_ENABLED)"})
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
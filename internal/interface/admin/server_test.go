@@ -0,0 +1,675 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/approval"
+	"github.com/littleironwaltz/quotebot/internal/deadletter"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/suggestion"
+)
+
+// fakeController は呼び出しを記録するテスト用のコントローラーです
+type fakeController struct {
+	paused    bool
+	posts     int
+	postErr   error
+	reloadErr error
+}
+
+func (f *fakeController) PostNow(ctx context.Context) (*app.PostResult, error) {
+	f.posts++
+	result := &app.PostResult{RequestID: "0123456789abcdef", Trigger: app.TriggerManual}
+	if f.postErr != nil {
+		result.Error = f.postErr.Error()
+		return result, f.postErr
+	}
+	return result, nil
+}
+
+func (f *fakeController) Pause()  { f.paused = true }
+func (f *fakeController) Resume() { f.paused = false }
+
+func (f *fakeController) Status() app.Status {
+	return app.Status{Paused: f.paused, PoolSize: 3}
+}
+
+func (f *fakeController) ReloadQuotes() (int, error) {
+	if f.reloadErr != nil {
+		return 0, f.reloadErr
+	}
+	return 3, nil
+}
+
+const (
+	testAdminToken = "admin-secret"
+	testReadToken  = "read-secret"
+)
+
+func TestServer_Authentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		wantStatus int
+	}{
+		{
+			name:       "正常系: 正しいトークン",
+			header:     "Bearer " + testAdminToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "異常系: トークンなし",
+			header:     "",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "異常系: 誤ったトークン",
+			header:     "Bearer wrong",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "異常系: Bearer以外のスキーム",
+			header:     "Basic " + testAdminToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "正常系: 読み取り専用のトークンで参照",
+			header:     "Bearer " + testReadToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "異常系: 読み取り専用のトークンで操作",
+			method:     http.MethodPost,
+			path:       "/pause",
+			header:     "Bearer " + testReadToken,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "正常系: ADMIN_TOKEN で操作",
+			method:     http.MethodPost,
+			path:       "/pause",
+			header:     "Bearer " + testAdminToken,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	server := NewServer(&config.Config{AdminToken: testAdminToken, AdminReadToken: testReadToken}, &fakeController{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, path := http.MethodGet, "/status"
+			if tt.method != "" {
+				method, path = tt.method, tt.path
+			}
+			req := httptest.NewRequest(method, path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServer_Dashboard(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantType     string
+		wantBody     string
+		wantLocation string
+	}{
+		{name: "正常系: 管理画面はトークンなしで表示できる", path: "/ui/", wantStatus: http.StatusOK, wantType: "text/html", wantBody: "ADMIN_TOKEN"},
+		{name: "正常系: スクリプト", path: "/ui/app.js", wantStatus: http.StatusOK, wantType: "javascript", wantBody: "Authorization"},
+		{name: "正常系: ルートは管理画面に転送する", path: "/", wantStatus: http.StatusFound, wantLocation: "/ui/"},
+		{name: "異常系: 存在しないファイル", path: "/ui/missing.js", wantStatus: http.StatusNotFound},
+		{name: "異常系: APIはトークンが必要なまま", path: "/status", wantStatus: http.StatusUnauthorized},
+	}
+
+	server := NewServer(&config.Config{AdminToken: testAdminToken}, &fakeController{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Header().Get("Content-Type"), tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), tt.wantType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body does not contain %q", tt.wantBody)
+			}
+			if tt.wantLocation != "" && rec.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", rec.Header().Get("Location"), tt.wantLocation)
+			}
+			if strings.HasPrefix(tt.path, "/ui/") && rec.Header().Get("Content-Security-Policy") == "" {
+				t.Errorf("no Content-Security-Policy on the web UI")
+			}
+		})
+	}
+}
+
+func TestServer_Routes(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		controller *fakeController
+		wantStatus int
+		check      func(t *testing.T, c *fakeController, body map[string]interface{})
+	}{
+		{
+			name:       "正常系: 状態の取得",
+			method:     http.MethodGet,
+			path:       "/status",
+			controller: &fakeController{},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, c *fakeController, body map[string]interface{}) {
+				if body["poolSize"] != float64(3) || body["paused"] != false {
+					t.Errorf("body = %v", body)
+				}
+			},
+		},
+		{
+			name:       "正常系: 一時停止",
+			method:     http.MethodPost,
+			path:       "/pause",
+			controller: &fakeController{},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, c *fakeController, body map[string]interface{}) {
+				if !c.paused || body["paused"] != true {
+					t.Errorf("paused = %v, body = %v", c.paused, body)
+				}
+			},
+		},
+		{
+			name:       "正常系: 再開",
+			method:     http.MethodPost,
+			path:       "/resume",
+			controller: &fakeController{paused: true},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, c *fakeController, body map[string]interface{}) {
+				if c.paused {
+					t.Errorf("paused = true, want false")
+				}
+			},
+		},
+		{
+			name:       "正常系: 即時投稿",
+			method:     http.MethodPost,
+			path:       "/post-now",
+			controller: &fakeController{},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, c *fakeController, body map[string]interface{}) {
+				if c.posts != 1 || body["requestId"] != "0123456789abcdef" {
+					t.Errorf("posts = %d, body = %v", c.posts, body)
+				}
+			},
+		},
+		{
+			name:       "異常系: 即時投稿の失敗",
+			method:     http.MethodPost,
+			path:       "/post-now",
+			controller: &fakeController{postErr: errors.New("PDS unavailable")},
+			wantStatus: http.StatusBadGateway,
+			check: func(t *testing.T, c *fakeController, body map[string]interface{}) {
+				if body["error"] != "PDS unavailable" {
+					t.Errorf("body = %v", body)
+				}
+			},
+		},
+		{
+			name:       "正常系: 名言の再読み込み",
+			method:     http.MethodPost,
+			path:       "/reload-quotes",
+			controller: &fakeController{},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, c *fakeController, body map[string]interface{}) {
+				if body["count"] != float64(3) {
+					t.Errorf("body = %v", body)
+				}
+			},
+		},
+		{
+			name:       "異常系: 名言の再読み込みの失敗",
+			method:     http.MethodPost,
+			path:       "/reload-quotes",
+			controller: &fakeController{reloadErr: errors.New("名言データのデコードに失敗しました")},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "異常系: 状態変更はGETでは受け付けない",
+			method:     http.MethodGet,
+			path:       "/pause",
+			controller: &fakeController{},
+			wantStatus: http.StatusMethodNotAllowed,
+			check: func(t *testing.T, c *fakeController, body map[string]interface{}) {
+				if c.paused {
+					t.Errorf("paused = true, want false")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(&config.Config{AdminToken: testAdminToken}, tt.controller)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var body map[string]interface{}
+			json.Unmarshal(rec.Body.Bytes(), &body)
+			if tt.check != nil {
+				tt.check(t, tt.controller, body)
+			}
+		})
+	}
+}
+
+func TestServer_Start(t *testing.T) {
+	server := NewServer(&config.Config{AdminAddr: "127.0.0.1:0", AdminToken: testAdminToken}, &fakeController{})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+server.Addr()+"/status", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /status error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+// fakeReloader は設定の再読み込みの結果を返すテスト用の実装です
+type fakeReloader struct {
+	err error
+}
+
+func (f *fakeReloader) ReloadConfig() (*app.ReloadResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &app.ReloadResult{Applied: []string{"POST_INTERVAL"}, RestartRequired: []string{"PDS_URL"}}, nil
+}
+
+func TestServer_ReloadConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "正常系: 設定の再読み込み",
+			opts:       []Option{WithConfigReloader(&fakeReloader{})},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"applied":["POST_INTERVAL"],"restartRequired":["PDS_URL"]}`,
+		},
+		{
+			name:       "異常系: 再読み込みの失敗",
+			opts:       []Option{WithConfigReloader(&fakeReloader{err: errors.New("設定に1件の問題があります")})},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "異常系: 再読み込みが設定されていない",
+			wantStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(&config.Config{AdminToken: testAdminToken}, &fakeController{}, tt.opts...)
+			req := httptest.NewRequest(http.MethodPost, "/reload-config", nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+// fakeApprover は ID が "a1" の承認待ちの投稿だけを持つテスト用の Approver です
+type fakeApprover struct {
+	approved []string
+	rejected []string
+}
+
+func (f *fakeApprover) Approvals() ([]approval.Item, error) {
+	return []approval.Item{{ID: "a1", Text: "名言"}}, nil
+}
+
+func (f *fakeApprover) Approve(ctx context.Context, id string) (*app.PostResult, error) {
+	if id != "a1" {
+		return nil, approval.ErrNotFound
+	}
+	f.approved = append(f.approved, id)
+	return &app.PostResult{Trigger: app.TriggerApproval, URI: "at://post"}, nil
+}
+
+func (f *fakeApprover) Reject(id string) error {
+	if id != "a1" {
+		return approval.ErrNotFound
+	}
+	f.rejected = append(f.rejected, id)
+	return nil
+}
+
+func TestServer_Approvals(t *testing.T) {
+	tests := []struct {
+		name       string
+		noApprover bool
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "正常系: 承認待ちの一覧",
+			method:     http.MethodGet,
+			path:       "/approvals",
+			wantStatus: http.StatusOK,
+			wantBody:   `"id":"a1"`,
+		},
+		{
+			name:       "正常系: 承認",
+			method:     http.MethodPost,
+			path:       "/approvals/a1/approve",
+			wantStatus: http.StatusOK,
+			wantBody:   `"uri":"at://post"`,
+		},
+		{
+			name:       "正常系: 却下",
+			method:     http.MethodPost,
+			path:       "/approvals/a1/reject",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "異常系: 存在しないID",
+			method:     http.MethodPost,
+			path:       "/approvals/missing/approve",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "異常系: 承認待ちが有効でない",
+			noApprover: true,
+			method:     http.MethodGet,
+			path:       "/approvals",
+			wantStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if !tt.noApprover {
+				opts = append(opts, WithApprover(&fakeApprover{}))
+			}
+			server := NewServer(&config.Config{AdminToken: testAdminToken}, &fakeController{}, opts...)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want to contain %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+// fakeSuggestions は ID が s1 の提案だけを持つテスト用の受付箱です。s2 は既存の名言と重複しています
+type fakeSuggestions struct{}
+
+func (fakeSuggestions) Suggestions() ([]suggestion.Item, error) {
+	return []suggestion.Item{{ID: "s1", Quote: domain.Quote{Text: "名言", Author: "著者"}}}, nil
+}
+
+func (fakeSuggestions) Accept(id string) (*domain.Quote, error) {
+	switch id {
+	case "s1":
+		return &domain.Quote{ID: "q-s1", Text: "名言", Author: "著者"}, nil
+	case "s2":
+		return nil, errors.New("名言を追加できません: 本文が重複しています")
+	}
+	return nil, suggestion.ErrNotFound
+}
+
+func (fakeSuggestions) Reject(id string) error {
+	if id != "s1" {
+		return suggestion.ErrNotFound
+	}
+	return nil
+}
+
+func TestServer_Suggestions(t *testing.T) {
+	tests := []struct {
+		name          string
+		noSuggestions bool
+		method        string
+		path          string
+		wantStatus    int
+		wantBody      string
+	}{
+		{name: "正常系: 提案の一覧", method: http.MethodGet, path: "/suggestions", wantStatus: http.StatusOK, wantBody: `"id":"s1"`},
+		{name: "正常系: 採用", method: http.MethodPost, path: "/suggestions/s1/accept", wantStatus: http.StatusOK, wantBody: `"id":"q-s1"`},
+		{name: "正常系: 却下", method: http.MethodPost, path: "/suggestions/s1/reject", wantStatus: http.StatusOK},
+		{name: "異常系: 名言ファイルに追加できない", method: http.MethodPost, path: "/suggestions/s2/accept", wantStatus: http.StatusUnprocessableEntity, wantBody: "重複"},
+		{name: "異常系: 存在しないID", method: http.MethodPost, path: "/suggestions/missing/reject", wantStatus: http.StatusNotFound},
+		{name: "異常系: 提案の受付が有効でない", noSuggestions: true, method: http.MethodGet, path: "/suggestions", wantStatus: http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if !tt.noSuggestions {
+				opts = append(opts, WithSuggestions(fakeSuggestions{}))
+			}
+			server := NewServer(&config.Config{AdminToken: testAdminToken}, &fakeController{}, opts...)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want to contain %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+// fakeDeadLetters は ID が d1 の失敗した投稿だけを持つテスト用のキューです。d2 は送り直してもまた失敗します
+type fakeDeadLetters struct{}
+
+func (fakeDeadLetters) DeadLetters() ([]deadletter.Item, error) {
+	return []deadletter.Item{{ID: "d1", Text: "名言\n- 著者", Error: "503"}}, nil
+}
+
+func (fakeDeadLetters) RetryDeadLetter(ctx context.Context, id string) (*app.PostResult, error) {
+	switch id {
+	case "d1":
+		return &app.PostResult{Trigger: app.TriggerRetry, URI: "at://post"}, nil
+	case "d2":
+		return &app.PostResult{Trigger: app.TriggerRetry, Error: "503", DeadLetterID: "d3"}, errors.New("503")
+	}
+	return nil, deadletter.ErrNotFound
+}
+
+func (fakeDeadLetters) DiscardDeadLetter(id string) error {
+	if id != "d1" {
+		return deadletter.ErrNotFound
+	}
+	return nil
+}
+
+func TestServer_DeadLetters(t *testing.T) {
+	tests := []struct {
+		name          string
+		noDeadLetters bool
+		method        string
+		path          string
+		wantStatus    int
+		wantBody      string
+	}{
+		{name: "正常系: 失敗した投稿の一覧", method: http.MethodGet, path: "/dead-letters", wantStatus: http.StatusOK, wantBody: `"id":"d1"`},
+		{name: "正常系: 送り直す", method: http.MethodPost, path: "/dead-letters/d1/retry", wantStatus: http.StatusOK, wantBody: `"uri":"at://post"`},
+		{name: "正常系: 破棄", method: http.MethodPost, path: "/dead-letters/d1/discard", wantStatus: http.StatusOK},
+		{name: "異常系: 送り直しても失敗", method: http.MethodPost, path: "/dead-letters/d2/retry", wantStatus: http.StatusBadGateway, wantBody: `"deadLetterId":"d3"`},
+		{name: "異常系: 存在しないID", method: http.MethodPost, path: "/dead-letters/missing/retry", wantStatus: http.StatusNotFound},
+		{name: "異常系: 失敗した投稿を残していない", noDeadLetters: true, method: http.MethodGet, path: "/dead-letters", wantStatus: http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if !tt.noDeadLetters {
+				opts = append(opts, WithDeadLetters(fakeDeadLetters{}))
+			}
+			server := NewServer(&config.Config{AdminToken: testAdminToken}, &fakeController{}, opts...)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want to contain %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	bus := events.NewBus()
+	server := NewServer(&config.Config{AdminAddr: "127.0.0.1:0", AdminToken: testAdminToken}, &fakeController{}, WithEvents(bus))
+	server.keepAlive = 50 * time.Millisecond
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+server.Addr()+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /events error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET /events = %d %s, want an event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readFrame := func() []string {
+		t.Helper()
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read event error = %v", err)
+			}
+			if line = strings.TrimSpace(line); line == "" {
+				return lines
+			}
+			lines = append(lines, line)
+		}
+	}
+	if frame := readFrame(); len(frame) != 1 || frame[0] != "retry: 3000" {
+		t.Errorf("first frame = %q, want a retry hint", frame)
+	}
+
+	// ヘッダーを受け取った時点で購読している
+	bus.Publish(events.Event{Type: events.PostSucceeded, RequestID: "0123456789abcdef", URI: "at://1"})
+	if frame := readFrame(); len(frame) != 2 || frame[0] != "event: post_succeeded" || !strings.Contains(frame[1], `"requestId":"0123456789abcdef"`) {
+		t.Errorf("event = %q", frame)
+	}
+
+	// イベントがなくても接続を保つためのコメントが届く
+	if frame := readFrame(); len(frame) != 1 || frame[0] != ": keep-alive" {
+		t.Errorf("idle frame = %q, want a keep-alive comment", frame)
+	}
+
+	// 接続中のストリームがあっても停止できる
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+func TestServer_EventsTypeFilter(t *testing.T) {
+	bus := events.NewBus()
+	server := NewServer(&config.Config{AdminAddr: "127.0.0.1:0", AdminToken: testAdminToken}, &fakeController{}, WithEvents(bus))
+	server.keepAlive = time.Hour
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+server.Addr()+"/events?type=post_failed,token_refresh_failed", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /events error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 指定していない種類のイベントは届かない
+	bus.Publish(events.Event{Type: events.PostSucceeded, URI: "at://1"})
+	bus.Publish(events.Event{Type: events.PostFailed, Error: "boom"})
+	reader := bufio.NewReader(resp.Body)
+	var got []string
+	for len(got) == 0 || !strings.HasPrefix(got[len(got)-1], "data:") {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event error = %v", err)
+		}
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "event:") || strings.HasPrefix(line, "data:") {
+			got = append(got, line)
+		}
+	}
+	if len(got) != 2 || got[0] != "event: post_failed" {
+		t.Errorf("events = %q, want only post_failed", got)
+	}
+}
@@ -0,0 +1,300 @@
+// Package grpcapi serves the gRPC API (api/quotebot/v1) used by other services to drive a running bot
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	quotebotv1 "github.com/littleironwaltz/quotebot/api/quotebot/v1"
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/auth"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+)
+
+// Controller is the part of the bot exposed through the gRPC API
+type Controller interface {
+	PostNow(ctx context.Context) (*app.PostResult, error)
+	Status() app.Status
+}
+
+// EventStream delivers the bot's events to a subscriber
+type EventStream interface {
+	Subscribe() (<-chan events.Event, func())
+}
+
+// QuoteStore lists the loaded quotes and adds new ones to the quotes file
+type QuoteStore interface {
+	Quotes() []domain.Quote
+	AddQuote(quote domain.Quote) (*domain.Quote, error)
+}
+
+// Server serves the gRPC API
+type Server struct {
+	quotebotv1.UnimplementedQuoteBotServiceServer
+
+	addr       string
+	token      string
+	readToken  string // optional, allows only the readOnlyMethods
+	controller Controller
+	quotes     QuoteStore
+	events     EventStream
+	logger     *slog.Logger
+	grpcServer *grpc.Server
+	listener   net.Listener
+	done       chan struct{} // closed on Shutdown to end the event streams
+}
+
+// NewServer creates a new gRPC API server listening on GRPC_ADDR
+func NewServer(cfg *config.Config, controller Controller, quotes QuoteStore, stream EventStream) *Server {
+	s := &Server{
+		addr:       cfg.GRPCAddr,
+		token:      cfg.AdminToken,
+		readToken:  cfg.AdminReadToken,
+		controller: controller,
+		quotes:     quotes,
+		events:     stream,
+		logger:     logging.ModuleFor(cfg, "grpc"),
+		done:       make(chan struct{}),
+	}
+	s.grpcServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.authenticateUnary),
+		grpc.ChainStreamInterceptor(s.authenticateStream),
+	)
+	quotebotv1.RegisterQuoteBotServiceServer(s.grpcServer, s)
+	return s
+}
+
+// Start starts listening and serves the API in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	s.listener = listener
+	s.logger.Info("gRPC APIを開始しました", "addr", listener.Addr().String())
+
+	safego.Go(s.logger, "grpc", func() {
+		if err := s.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			s.logger.Error("gRPC APIが停止しました", "error", err)
+		}
+	})
+	return nil
+}
+
+// Addr returns the address the server is listening on, once started
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Shutdown ends the event streams and stops the server, waiting for in-flight calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	close(s.done)
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// readOnlyMethods are the RPCs that ADMIN_READ_TOKEN may call
+var readOnlyMethods = map[string]bool{
+	quotebotv1.QuoteBotService_ListQuotes_FullMethodName:   true,
+	quotebotv1.QuoteBotService_GetStatus_FullMethodName:    true,
+	quotebotv1.QuoteBotService_StreamEvents_FullMethodName: true,
+}
+
+// authenticateUnary requires the ADMIN_TOKEN, or the ADMIN_READ_TOKEN for read-only RPCs, as a bearer token
+func (s *Server) authenticateUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authenticate(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticateStream requires the ADMIN_TOKEN, or the ADMIN_READ_TOKEN for read-only RPCs, as a bearer token
+func (s *Server) authenticateStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authenticate(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (s *Server) authenticate(ctx context.Context, method string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if !ok {
+			continue
+		}
+		if auth.TokenMatches(token, s.token) {
+			return nil
+		}
+		if auth.TokenMatches(token, s.readToken) {
+			if readOnlyMethods[method] {
+				return nil
+			}
+			return status.Error(codes.PermissionDenied, "ADMIN_READ_TOKEN is read-only")
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+// PostNow posts a quote immediately, even while paused
+func (s *Server) PostNow(ctx context.Context, req *quotebotv1.PostNowRequest) (*quotebotv1.PostNowResponse, error) {
+	result, err := s.controller.PostNow(ctx)
+	switch {
+	case errors.Is(err, app.ErrShuttingDown):
+		return nil, status.Error(codes.Unavailable, err.Error())
+	case err != nil:
+		return nil, status.Errorf(codes.Unavailable, "post %s failed: %s", result.RequestID, result.Error)
+	}
+	return &quotebotv1.PostNowResponse{Result: toPostResult(result)}, nil
+}
+
+// AddQuote appends a quote to the quotes file and reloads it
+func (s *Server) AddQuote(ctx context.Context, req *quotebotv1.AddQuoteRequest) (*quotebotv1.AddQuoteResponse, error) {
+	if req.GetQuote() == nil {
+		return nil, status.Error(codes.InvalidArgument, "quote is required")
+	}
+	quote, err := s.quotes.AddQuote(fromQuote(req.GetQuote()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, redact.String(err.Error()))
+	}
+	s.logger.Info("名言を追加しました", "quote_id", quote.ID)
+	return &quotebotv1.AddQuoteResponse{Quote: toQuote(*quote)}, nil
+}
+
+// ListQuotes returns the loaded quotes, optionally only those with a tag
+func (s *Server) ListQuotes(ctx context.Context, req *quotebotv1.ListQuotesRequest) (*quotebotv1.ListQuotesResponse, error) {
+	resp := &quotebotv1.ListQuotesResponse{}
+	for _, quote := range s.quotes.Quotes() {
+		if req.GetTag() != "" && !quote.HasTag(req.GetTag()) {
+			continue
+		}
+		resp.Quotes = append(resp.Quotes, toQuote(quote))
+	}
+	return resp, nil
+}
+
+// GetStatus returns the bot's current status
+func (s *Server) GetStatus(ctx context.Context, req *quotebotv1.GetStatusRequest) (*quotebotv1.GetStatusResponse, error) {
+	st := s.controller.Status()
+	resp := &quotebotv1.GetStatusResponse{
+		Paused:     st.Paused,
+		DryRun:     st.DryRun,
+		StartedAt:  toTimestamp(st.StartedAt),
+		NextPostAt: toTimestamp(st.NextPostAt),
+		PoolSize:   int32(st.PoolSize),
+	}
+	if st.LastPost != nil {
+		resp.LastPost = toPostResult(st.LastPost)
+	}
+	if st.TokenExpiresAt != nil {
+		resp.TokenExpiresAt = toTimestamp(*st.TokenExpiresAt)
+	}
+	if len(st.Events) > 0 {
+		resp.Events = make(map[string]int64, len(st.Events))
+		for t, n := range st.Events {
+			resp.Events[string(t)] = int64(n)
+		}
+	}
+	for _, e := range st.RecentErrors {
+		resp.RecentErrors = append(resp.RecentErrors, &quotebotv1.ErrorEntry{At: toTimestamp(e.At), RequestId: e.RequestID, Message: e.Message})
+	}
+	return resp, nil
+}
+
+// StreamEvents sends the bot's events until the client disconnects or the server shuts down
+func (s *Server) StreamEvents(req *quotebotv1.StreamEventsRequest, stream grpc.ServerStreamingServer[quotebotv1.Event]) error {
+	received, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return nil
+		case event := <-received:
+			if err := stream.Send(toEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toEvent(event events.Event) *quotebotv1.Event {
+	e := &quotebotv1.Event{Type: string(event.Type), At: toTimestamp(event.At), RequestId: event.RequestID, QuoteId: event.QuoteID, Error: event.Error}
+	switch event.Type {
+	case events.PostSucceeded, events.PostFailed, events.PostHeld, events.PostDryRun:
+		e.Post = &quotebotv1.PostResult{
+			At:         toTimestamp(event.At),
+			RequestId:  event.RequestID,
+			Trigger:    event.Trigger,
+			Text:       event.Text,
+			Uri:        event.URI,
+			Error:      event.Error,
+			DryRun:     event.Type == events.PostDryRun,
+			Variant:    event.Variant,
+			QuoteId:    event.QuoteID,
+			ApprovalId: event.ApprovalID,
+		}
+	}
+	return e
+}
+
+func toPostResult(result *app.PostResult) *quotebotv1.PostResult {
+	return &quotebotv1.PostResult{
+		At:         toTimestamp(result.At),
+		RequestId:  result.RequestID,
+		Trigger:    result.Trigger,
+		Text:       result.Text,
+		Uri:        result.URI,
+		Error:      result.Error,
+		DryRun:     result.DryRun,
+		Variant:    result.Variant,
+		QuoteId:    result.QuoteID,
+		ApprovalId: result.ApprovalID,
+	}
+}
+
+func toQuote(quote domain.Quote) *quotebotv1.Quote {
+	return &quotebotv1.Quote{Id: quote.ID, Text: quote.Text, Author: quote.Author, Tags: quote.Tags, Weight: quote.Weight}
+}
+
+func fromQuote(quote *quotebotv1.Quote) domain.Quote {
+	return domain.Quote{ID: quote.GetId(), Text: quote.GetText(), Author: quote.GetAuthor(), Tags: quote.GetTags(), Weight: quote.GetWeight()}
+}
+
+// toTimestamp converts t, leaving the zero time unset
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,243 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	quotebotv1 "github.com/littleironwaltz/quotebot/api/quotebot/v1"
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+)
+
+const (
+	testToken     = "admin-secret"
+	testReadToken = "read-secret"
+)
+
+// fakeController は投稿の結果を Bus に発行するテスト用のコントローラーです
+type fakeController struct {
+	postErr error
+	bus     *events.Bus
+}
+
+func (f *fakeController) PostNow(ctx context.Context) (*app.PostResult, error) {
+	result := &app.PostResult{At: time.Now(), RequestID: "0123456789abcdef", Trigger: app.TriggerManual, URI: "at://did:plc:test/app.bsky.feed.post/1"}
+	if f.postErr != nil {
+		result.URI = ""
+		result.Error = f.postErr.Error()
+		return result, f.postErr
+	}
+	if f.bus != nil {
+		f.bus.Publish(events.Event{Type: events.PostSucceeded, RequestID: result.RequestID, URI: result.URI})
+	}
+	return result, nil
+}
+
+func (f *fakeController) Status() app.Status {
+	return app.Status{Paused: true, PoolSize: 3, Events: map[events.Type]int{events.PostSucceeded: 2}}
+}
+
+// countingBus は購読者の数を数えます
+type countingBus struct {
+	*events.Bus
+
+	mu          sync.Mutex
+	subscribers int
+}
+
+func (b *countingBus) Subscribe() (<-chan events.Event, func()) {
+	b.mu.Lock()
+	b.subscribers++
+	b.mu.Unlock()
+	return b.Bus.Subscribe()
+}
+
+func (b *countingBus) subscribed() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscribers
+}
+
+// fakeQuotes は追加した名言を一覧に加えます
+type fakeQuotes struct {
+	quotes []domain.Quote
+}
+
+func (f *fakeQuotes) Quotes() []domain.Quote { return f.quotes }
+
+func (f *fakeQuotes) AddQuote(quote domain.Quote) (*domain.Quote, error) {
+	if quote.Author == "" {
+		return nil, errors.New("著者が空です")
+	}
+	quote.ID = quote.StableID()
+	f.quotes = append(f.quotes, quote)
+	return &quote, nil
+}
+
+// startServer はテスト用のサーバーを起動し、接続したクライアントを返します
+func startServer(t *testing.T, controller Controller, quotes QuoteStore, stream EventStream) quotebotv1.QuoteBotServiceClient {
+	t.Helper()
+	server := NewServer(&config.Config{GRPCAddr: "127.0.0.1:0", AdminToken: testToken, AdminReadToken: testReadToken}, controller, quotes, stream)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient(server.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return quotebotv1.NewQuoteBotServiceClient(conn)
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestServer_Authentication(t *testing.T) {
+	client := startServer(t, &fakeController{}, &fakeQuotes{}, events.NewBus())
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantCode codes.Code
+	}{
+		{
+			name:     "正常系: 正しいトークン",
+			ctx:      withToken(context.Background(), testToken),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "異常系: トークンなし",
+			ctx:      context.Background(),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "異常系: 誤ったトークン",
+			ctx:      withToken(context.Background(), "wrong"),
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.GetStatus(tt.ctx, &quotebotv1.GetStatusRequest{})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("GetStatus() code = %v, want %v", got, tt.wantCode)
+			}
+			if err == nil && (!resp.GetPaused() || resp.GetPoolSize() != 3 || resp.GetEvents()["post_succeeded"] != 2) {
+				t.Errorf("GetStatus() = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestServer_ReadOnlyToken(t *testing.T) {
+	client := startServer(t, &fakeController{}, &fakeQuotes{}, events.NewBus())
+	ctx := withToken(context.Background(), testReadToken)
+
+	if _, err := client.GetStatus(ctx, &quotebotv1.GetStatusRequest{}); err != nil {
+		t.Errorf("GetStatus() error = %v, want the read-only token to be accepted", err)
+	}
+	if _, err := client.ListQuotes(ctx, &quotebotv1.ListQuotesRequest{}); err != nil {
+		t.Errorf("ListQuotes() error = %v, want the read-only token to be accepted", err)
+	}
+	// 投稿や名言の追加はできない
+	if _, err := client.PostNow(ctx, &quotebotv1.PostNowRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("PostNow() error = %v, want PermissionDenied", err)
+	}
+	quote := &quotebotv1.Quote{Text: "本文", Author: "著者"}
+	if _, err := client.AddQuote(ctx, &quotebotv1.AddQuoteRequest{Quote: quote}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("AddQuote() error = %v, want PermissionDenied", err)
+	}
+}
+
+func TestServer_PostNow(t *testing.T) {
+	ctx := withToken(context.Background(), testToken)
+
+	client := startServer(t, &fakeController{}, &fakeQuotes{}, events.NewBus())
+	resp, err := client.PostNow(ctx, &quotebotv1.PostNowRequest{})
+	if err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+	if resp.GetResult().GetUri() == "" || resp.GetResult().GetRequestId() != "0123456789abcdef" {
+		t.Errorf("PostNow() = %+v", resp)
+	}
+
+	client = startServer(t, &fakeController{postErr: errors.New("pds unavailable")}, &fakeQuotes{}, events.NewBus())
+	if _, err := client.PostNow(ctx, &quotebotv1.PostNowRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("PostNow() error = %v, want Unavailable", err)
+	}
+}
+
+func TestServer_Quotes(t *testing.T) {
+	ctx := withToken(context.Background(), testToken)
+	quotes := &fakeQuotes{quotes: []domain.Quote{{ID: "q1", Text: "テスト名言1", Author: "著者1", Tags: []string{"birthday"}}}}
+	client := startServer(t, &fakeController{}, quotes, events.NewBus())
+
+	added, err := client.AddQuote(ctx, &quotebotv1.AddQuoteRequest{Quote: &quotebotv1.Quote{Text: "テスト名言2", Author: "著者2"}})
+	if err != nil {
+		t.Fatalf("AddQuote() error = %v", err)
+	}
+	if added.GetQuote().GetId() != domain.ContentID("テスト名言2", "著者2") {
+		t.Errorf("AddQuote() = %+v, want the content ID", added)
+	}
+	if _, err := client.AddQuote(ctx, &quotebotv1.AddQuoteRequest{Quote: &quotebotv1.Quote{Text: "著者なし"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("AddQuote() without an author error = %v, want InvalidArgument", err)
+	}
+
+	all, err := client.ListQuotes(ctx, &quotebotv1.ListQuotesRequest{})
+	if err != nil {
+		t.Fatalf("ListQuotes() error = %v", err)
+	}
+	if len(all.GetQuotes()) != 2 {
+		t.Errorf("ListQuotes() = %+v, want 2 quotes", all)
+	}
+	tagged, err := client.ListQuotes(ctx, &quotebotv1.ListQuotesRequest{Tag: "Birthday"})
+	if err != nil {
+		t.Fatalf("ListQuotes() error = %v", err)
+	}
+	if len(tagged.GetQuotes()) != 1 || tagged.GetQuotes()[0].GetId() != "q1" {
+		t.Errorf("ListQuotes(tag) = %+v, want q1", tagged)
+	}
+}
+
+func TestServer_StreamEvents(t *testing.T) {
+	bus := &countingBus{Bus: events.NewBus()}
+	controller := &fakeController{bus: bus.Bus}
+	client := startServer(t, controller, &fakeQuotes{}, bus)
+	ctx, cancel := context.WithTimeout(withToken(context.Background(), testToken), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamEvents(ctx, &quotebotv1.StreamEventsRequest{})
+	if err != nil {
+		t.Fatalf("StreamEvents() error = %v", err)
+	}
+	// 購読が始まってから投稿する
+	for bus.subscribed() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := client.PostNow(ctx, &quotebotv1.PostNowRequest{}); err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if event.GetType() != "post_succeeded" || event.GetPost().GetUri() == "" || event.GetRequestId() != "0123456789abcdef" {
+		t.Errorf("Recv() = %+v, want post_succeeded", event)
+	}
+}
@@ -0,0 +1,238 @@
+// Package publicstats serves a public, unauthenticated page with the bot's statistics,
+// separate from the admin API so that it can be linked from the bot's profile
+package publicstats
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/analytics"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+)
+
+//go:embed page.html
+var pageSource string
+
+var page = template.Must(template.New("page").Parse(pageSource))
+
+// StatusSource reports the status of the running bot
+type StatusSource interface {
+	Status() app.Status
+}
+
+// Stats is what the page shows
+type Stats struct {
+	Bot             string    `json:"bot,omitempty"`
+	TotalPosts      int       `json:"totalPosts"` // successful posts still in the history files
+	PoolSize        int       `json:"poolSize"`
+	TopQuoteOfMonth *TopQuote `json:"topQuoteOfMonth,omitempty"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// TopQuote is the quote whose posts got the most likes this month
+type TopQuote struct {
+	Text   string `json:"text"`
+	Author string `json:"author,omitempty"`
+	Likes  int    `json:"likes"`
+}
+
+// Server serves the public stats page
+type Server struct {
+	addr       string
+	history    func() ([]history.Entry, error)
+	cacheTTL   time.Duration
+	location   *time.Location
+	status     StatusSource
+	engagement analytics.EngagementSource // optional, without it there is no top quote
+	clock      clock.Clock
+	logger     *slog.Logger
+	httpServer *http.Server
+	listener   net.Listener
+
+	mu       sync.Mutex
+	cached   *Stats
+	cachedAt time.Time
+}
+
+// Option configures optional parts of the stats page
+type Option func(*Server)
+
+// WithEngagement enables the most liked quote of the month
+func WithEngagement(source analytics.EngagementSource) Option {
+	return func(s *Server) {
+		s.engagement = source
+	}
+}
+
+// WithHistory reads the post history with read instead of from HISTORY_FILE, for a history
+// stored in the STATE_BACKEND database
+func WithHistory(read func() ([]history.Entry, error)) Option {
+	return func(s *Server) {
+		s.history = read
+	}
+}
+
+// WithClock uses c instead of the real clock, for tests
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) {
+		s.clock = c
+	}
+}
+
+// NewServer creates the stats page server listening on PUBLIC_STATS_ADDR
+func NewServer(cfg *config.Config, status StatusSource, opts ...Option) *Server {
+	s := &Server{
+		addr: cfg.PublicStatsAddr,
+		history: func() ([]history.Entry, error) {
+			return history.ReadEntries(cfg.HistoryFile, cfg.HistoryMaxBackups)
+		},
+		cacheTTL: cfg.PublicStatsCacheTTL,
+		location: cfg.DisplayLocation(),
+		status:   status,
+		clock:    clock.Real,
+		logger:   logging.ModuleFor(cfg, "publicstats"),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.httpServer = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the page and its JSON form
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePage)
+	mux.HandleFunc("GET /stats.json", s.handleJSON)
+	return mux
+}
+
+// Start starts listening and serves the page in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	s.listener = listener
+	s.logger.Info("統計ページを開始しました", "addr", listener.Addr().String())
+
+	safego.Go(s.logger, "publicstats", func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("統計ページが停止しました", "error", err)
+		}
+	})
+	return nil
+}
+
+// Addr returns the address the server is listening on, once started
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.Stats(r.Context())
+	if err != nil {
+		http.Error(w, "統計を集計できませんでした", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// the page has no scripts and only an inline stylesheet
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.cacheTTL.Seconds())))
+	if err := page.Execute(w, stats); err != nil {
+		s.logger.Warn("統計ページの表示に失敗しました", "error", err)
+	}
+}
+
+func (s *Server) handleJSON(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.Stats(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "stats are not available"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.cacheTTL.Seconds())))
+	json.NewEncoder(w).Encode(stats)
+}
+
+// Stats returns the statistics, counting them again at most once per PUBLIC_STATS_CACHE_TTL.
+// When counting fails the previous statistics are returned, if there are any
+func (s *Server) Stats(ctx context.Context) (*Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.cached != nil && now.Sub(s.cachedAt) < s.cacheTTL {
+		return s.cached, nil
+	}
+	stats, err := s.collect(ctx, now)
+	if err != nil {
+		s.logger.Warn("統計の集計に失敗しました", "error", redact.Error(err))
+		if s.cached != nil {
+			return s.cached, nil
+		}
+		return nil, err
+	}
+	s.cached, s.cachedAt = stats, now
+	return stats, nil
+}
+
+func (s *Server) collect(ctx context.Context, now time.Time) (*Stats, error) {
+	entries, err := s.history()
+	if err != nil {
+		return nil, err
+	}
+	status := s.status.Status()
+	stats := &Stats{Bot: status.Bot, PoolSize: status.PoolSize, UpdatedAt: now}
+	for _, entry := range entries {
+		if entry.Result == history.ResultSuccess {
+			stats.TotalPosts++
+		}
+	}
+
+	if s.engagement != nil {
+		local := now.In(s.location)
+		monthStart := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, s.location)
+		summaries, err := analytics.SummarizeQuotes(ctx, entries, monthStart, s.engagement)
+		if err != nil {
+			// the counts are still worth showing without the top quote
+			s.logger.Warn("今月の反応の取得に失敗しました", "error", redact.Error(err))
+		}
+		for _, summary := range summaries {
+			if summary.Likes > 0 && (stats.TopQuoteOfMonth == nil || summary.Likes > stats.TopQuoteOfMonth.Likes) {
+				stats.TopQuoteOfMonth = &TopQuote{Text: summary.Text, Author: summary.Author, Likes: summary.Likes}
+			}
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,135 @@
+package publicstats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/history"
+)
+
+type fakeStatus struct{}
+
+func (fakeStatus) Status() app.Status { return app.Status{Bot: "philosophy", PoolSize: 42} }
+
+// fakeEngagement は URI ごとの反応を返し、呼ばれた回数を数えます
+type fakeEngagement struct {
+	likes map[string]int
+	err   error
+	calls int
+}
+
+func (f *fakeEngagement) Engagement(ctx context.Context, uris []string) (map[string]domain.Engagement, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	result := map[string]domain.Engagement{}
+	for _, uri := range uris {
+		result[uri] = domain.Engagement{Likes: f.likes[uri]}
+	}
+	return result, nil
+}
+
+func writeHistory(t *testing.T, entries ...history.Entry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	var lines []string
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(data))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func entry(at time.Time, id, text, uri, result string) history.Entry {
+	return history.Entry{
+		Timestamp: at,
+		Platform:  domain.PlatformBluesky,
+		Quote:     history.Quote{ID: id, Text: text, Author: "著者"},
+		Result:    result,
+		URI:       uri,
+	}
+}
+
+func TestServer_Stats(t *testing.T) {
+	now := time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC)
+	path := writeHistory(t,
+		entry(time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC), "april", "先月の名言", "at://1", history.ResultSuccess),
+		entry(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), "may", "今月の名言", "at://2", history.ResultSuccess),
+		entry(time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC), "other", "もう1つの名言", "at://3", history.ResultSuccess),
+		entry(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC), "failed", "失敗した名言", "", history.ResultFailure),
+	)
+	// 先月の投稿はもっと「いいね」されていても今月の名言にはならない
+	source := &fakeEngagement{likes: map[string]int{"at://1": 100, "at://2": 7, "at://3": 3}}
+	fake := clock.NewFake(now)
+	cfg := &config.Config{HistoryFile: path, PublicStatsCacheTTL: 10 * time.Minute, DisplayTimezone: "UTC"}
+	server := NewServer(cfg, fakeStatus{}, WithEngagement(source), WithClock(fake))
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /stats.json = %d", rec.Code)
+	}
+	var stats Stats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if stats.Bot != "philosophy" || stats.TotalPosts != 3 || stats.PoolSize != 42 {
+		t.Errorf("stats = %+v, want 3 posts and 42 quotes", stats)
+	}
+	if stats.TopQuoteOfMonth == nil || stats.TopQuoteOfMonth.Text != "今月の名言" || stats.TopQuoteOfMonth.Likes != 7 {
+		t.Errorf("TopQuoteOfMonth = %+v, want 今月の名言 with 7 likes", stats.TopQuoteOfMonth)
+	}
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "今月の名言") || !strings.Contains(rec.Body.String(), "3件") {
+		t.Errorf("GET / = %d %s", rec.Code, rec.Body.String())
+	}
+
+	// キャッシュの期間中は Bluesky に問い合わせない
+	if source.calls != 1 {
+		t.Errorf("Engagement() called %d times, want 1 within the cache TTL", source.calls)
+	}
+	fake.Advance(11 * time.Minute)
+	if _, err := server.Stats(context.Background()); err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if source.calls != 2 {
+		t.Errorf("Engagement() called %d times, want 2 after the cache TTL", source.calls)
+	}
+}
+
+func TestServer_StatsWithoutEngagement(t *testing.T) {
+	path := writeHistory(t, entry(time.Now(), "q", "名言", "at://1", history.ResultSuccess))
+	cfg := &config.Config{HistoryFile: path, PublicStatsCacheTTL: time.Minute}
+	source := &fakeEngagement{err: errors.New("unavailable")}
+	server := NewServer(cfg, fakeStatus{}, WithEngagement(source))
+
+	// 反応を取得できなくても投稿の数は表示する
+	stats, err := server.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.TotalPosts != 1 || stats.TopQuoteOfMonth != nil {
+		t.Errorf("Stats() = %+v, want 1 post and no top quote", stats)
+	}
+}
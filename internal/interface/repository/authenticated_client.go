@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// AuthenticatedClient makes XRPC calls as the account. It adds the authorization headers
+// and, when the PDS rejects the token with 401, refreshes it (or, for OAuth, picks up a
+// new DPoP nonce) and retries the call once
+type AuthenticatedClient struct {
+	tokenManager *TokenManager
+	xrpc         *XRPCClient
+}
+
+// NewAuthenticatedClient creates an AuthenticatedClient that takes its tokens from tokenManager
+func NewAuthenticatedClient(tokenManager *TokenManager, xrpc *XRPCClient) *AuthenticatedClient {
+	return &AuthenticatedClient{tokenManager: tokenManager, xrpc: xrpc}
+}
+
+// Do runs call with the authorization headers for an HTTP method request to the nsid
+// endpoint, retrying it once with new headers if it fails with 401
+func (c *AuthenticatedClient) Do(ctx context.Context, method, nsid string, call func(headers map[string]string) error) error {
+	url := c.xrpc.URL(nsid)
+	headers, err := c.tokenManager.AuthorizationHeaders(method, url)
+	if err != nil {
+		return err
+	}
+	err = call(headers)
+	if errors.Is(err, domain.ErrAccountSuspended) {
+		c.tokenManager.setState(SessionSuspended, err)
+		return err
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		return err
+	}
+	if err := c.tokenManager.HandleUnauthorized(ctx, url, httpErr); err != nil {
+		return fmt.Errorf("failed to refresh token: %w", err)
+	}
+	headers, err = c.tokenManager.AuthorizationHeaders(method, url)
+	if err != nil {
+		return fmt.Errorf("failed to get refreshed access token: %w", err)
+	}
+	return call(headers)
+}
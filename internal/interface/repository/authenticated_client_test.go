@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+func TestAuthenticatedClient_Do(t *testing.T) {
+	unauthorized := &HTTPError{StatusCode: http.StatusUnauthorized, Message: "401 Unauthorized"}
+	tests := []struct {
+		name        string
+		errs        []error // call が呼ばれるたびに返すエラー
+		refreshFail bool
+		wantCalls   int
+		wantRefresh bool
+		wantErr     error
+		wantState   SessionState // 空の場合は確かめない
+	}{
+		{name: "正常系: 成功した呼び出しはそのまま返す", errs: []error{nil}, wantCalls: 1},
+		{name: "正常系: 401 ならリフレッシュして1回だけやり直す", errs: []error{unauthorized, nil}, wantCalls: 2, wantRefresh: true},
+		{
+			name:        "異常系: やり直しても 401 ならそのエラーを返す",
+			errs:        []error{unauthorized, unauthorized},
+			wantCalls:   2,
+			wantRefresh: true,
+			wantErr:     domain.ErrAuthExpired,
+		},
+		{
+			name:      "異常系: 401 以外のエラーはやり直さない",
+			errs:      []error{&HTTPError{StatusCode: http.StatusTooManyRequests}},
+			wantCalls: 1,
+			wantErr:   domain.ErrRateLimited,
+		},
+		{
+			name:      "異常系: 停止されたアカウントはリフレッシュせずに返す",
+			errs:      []error{&HTTPError{StatusCode: http.StatusUnauthorized, Message: `401 Unauthorized: {"error":"AccountTakedown"}`}},
+			wantCalls: 1,
+			wantErr:   domain.ErrAccountSuspended,
+			wantState: SessionSuspended,
+		},
+		{
+			name:        "異常系: リフレッシュに失敗したらやり直さない",
+			errs:        []error{unauthorized},
+			refreshFail: true,
+			wantCalls:   1,
+			wantRefresh: true,
+			wantErr:     domain.ErrAuthExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refreshes atomic.Int32
+			var refreshFail atomic.Bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/xrpc/"+NSIDRefreshSession {
+					http.NotFound(w, r)
+					return
+				}
+				refreshes.Add(1)
+				if refreshFail.Load() {
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken"})
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]string{"accessJwt": "new-token", "refreshJwt": "new-refresh-token"})
+			}))
+			defer server.Close()
+
+			repo, err := NewBlueskyRepository(&config.Config{
+				AccessJWT:            "old-token",
+				RefreshJWT:           "refresh-token",
+				DID:                  "did:plc:test",
+				PDSURL:               server.URL,
+				HTTPTimeout:          3 * time.Second,
+				TokenRefreshInterval: time.Hour,
+			})
+			if err != nil {
+				t.Fatalf("NewBlueskyRepository() error = %v", err)
+			}
+			defer repo.Shutdown()
+			refreshes.Store(0)
+			refreshFail.Store(tt.refreshFail)
+
+			var calls []string
+			err = repo.client.Do(context.Background(), http.MethodGet, NSIDGetSession, func(headers map[string]string) error {
+				calls = append(calls, headers["Authorization"])
+				return tt.errs[len(calls)-1]
+			})
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(calls) != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", len(calls), tt.wantCalls)
+			}
+			if got := refreshes.Load() > 0; got != tt.wantRefresh {
+				t.Errorf("refreshed = %v, want %v", got, tt.wantRefresh)
+			}
+			if state, _ := repo.tokenManager.SessionState(); tt.wantState != "" && state != tt.wantState {
+				t.Errorf("SessionState() = %s, want %s", state, tt.wantState)
+			}
+			if tt.wantCalls == 2 && calls[1] != "Bearer new-token" {
+				t.Errorf("retry Authorization = %q, want the refreshed token", calls[1])
+			}
+		})
+	}
+}
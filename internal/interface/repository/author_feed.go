@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// authorFeedPageSize is the number of posts requested per page of the author feed (the maximum getAuthorFeed allows)
+const authorFeedPageSize = 100
+
+// AuthorPosts returns a page of the account's own posts, newest first, and the cursor of the next page.
+// Replies, reposts and pinned posts are left out. The cursor is empty after the last page
+func (r *BlueskyRepository) AuthorPosts(ctx context.Context, cursor string) ([]domain.PublishedPost, string, error) {
+	var output *GetAuthorFeedOutput
+	err := r.client.Do(ctx, http.MethodGet, NSIDGetAuthorFeed, func(headers map[string]string) (err error) {
+		output, err = r.xrpc.GetAuthorFeed(ctx, r.cfg.DID, authorFeedPageSize, cursor, headers)
+		return err
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get author feed: %w", err)
+	}
+
+	var posts []domain.PublishedPost
+	for _, item := range output.Feed {
+		// Reposts are other accounts' posts, and a pinned post also appears at its own place in the feed
+		if len(item.Reason) > 0 || item.Post.Author.DID != r.cfg.DID {
+			continue
+		}
+		var record struct {
+			Text      string    `json:"text"`
+			CreatedAt time.Time `json:"createdAt"`
+		}
+		if err := json.Unmarshal(item.Post.Record, &record); err != nil {
+			r.logger.Warn("Ignoring post with an unreadable record", "uri", item.Post.URI, "error", err)
+			continue
+		}
+		posts = append(posts, domain.PublishedPost{
+			Ref:       *blueskyPostRef(item.Post.URI, item.Post.CID),
+			Text:      record.Text,
+			CreatedAt: record.CreatedAt,
+		})
+	}
+	if len(output.Feed) == 0 {
+		return posts, "", nil
+	}
+	return posts, output.Cursor, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBlueskyRepository_AuthorPosts(t *testing.T) {
+	item := func(uri, author, record, reason string) FeedViewPost {
+		var out FeedViewPost
+		out.Post.URI = uri
+		out.Post.CID = "bafy"
+		out.Post.Author.DID = author
+		out.Post.Record = json.RawMessage(record)
+		if reason != "" {
+			out.Reason = json.RawMessage(reason)
+		}
+		return out
+	}
+	pages := map[string]GetAuthorFeedOutput{
+		"": {Cursor: "page2", Feed: []FeedViewPost{
+			item("at://did:plc:test/app.bsky.feed.post/pinned", "did:plc:test", `{"text":"固定した投稿"}`, `{"$type":"app.bsky.feed.defs#reasonPin"}`),
+			item("at://did:plc:test/app.bsky.feed.post/2", "did:plc:test", `{"text":"知は力なり\n- ベーコン","createdAt":"2024-01-02T00:00:00Z"}`, ""),
+			item("at://did:plc:other/app.bsky.feed.post/1", "did:plc:other", `{"text":"リポストした投稿"}`, `{"$type":"app.bsky.feed.defs#reasonRepost"}`),
+		}},
+		"page2": {Cursor: "page3", Feed: []FeedViewPost{
+			item("at://did:plc:test/app.bsky.feed.post/1", "did:plc:test", `{"text":5}`, ""),
+		}},
+	}
+	var actors []string
+	repo := newProfileTestRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/"+NSIDGetAuthorFeed {
+			return
+		}
+		actors = append(actors, r.URL.Query().Get("actor"))
+		json.NewEncoder(w).Encode(pages[r.URL.Query().Get("cursor")])
+	})
+
+	// 正常系: 自分の投稿だけを返し、リポストと固定した投稿は含まない
+	posts, cursor, err := repo.AuthorPosts(context.Background(), "")
+	if err != nil {
+		t.Fatalf("AuthorPosts() error = %v", err)
+	}
+	if len(posts) != 1 || posts[0].Text != "知は力なり\n- ベーコン" || cursor != "page2" {
+		t.Fatalf("AuthorPosts() = %+v, %q, want the own post and the next cursor", posts, cursor)
+	}
+	if posts[0].Ref.ID != "2" || !posts[0].CreatedAt.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("AuthorPosts()[0] = %+v", posts[0])
+	}
+
+	// 正常系: 読めない投稿は読み飛ばす
+	posts, cursor, err = repo.AuthorPosts(context.Background(), "page2")
+	if err != nil || len(posts) != 0 || cursor != "page3" {
+		t.Errorf("AuthorPosts(page2) = %+v, %q, %v", posts, cursor, err)
+	}
+
+	// 正常系: 最後のページの次はカーソルが空
+	posts, cursor, err = repo.AuthorPosts(context.Background(), "page3")
+	if err != nil || len(posts) != 0 || cursor != "" {
+		t.Errorf("AuthorPosts(page3) = %+v, %q, %v", posts, cursor, err)
+	}
+	if actors[0] != "did:plc:test" {
+		t.Errorf("actor = %q, want the account's DID", actors[0])
+	}
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/internal/redact"
+)
+
+var (
+	// ErrBlobTooLarge is returned for a blob larger than BLOB_MAX_BYTES
+	ErrBlobTooLarge = errors.New("blob is too large")
+	// ErrBlobEmpty is returned for a blob without any data
+	ErrBlobEmpty = errors.New("blob is empty")
+	// ErrBlobChanged is returned when the source returns different data while an upload is retried
+	ErrBlobChanged = errors.New("blob source changed during upload")
+	// ErrBlobChecksumMismatch is returned when the PDS stored something other than what was sent
+	ErrBlobChecksumMismatch = errors.New("uploaded blob does not match the data sent")
+)
+
+// rawSHA256CIDPrefix is how a CIDv1 with the raw codec and a sha2-256 multihash starts in
+// base32, which is the form the PDS uses for blob refs
+const rawSHA256CIDPrefix = "bafkrei"
+
+// BlobSource opens the data of a blob. It is called again when an upload is retried, so that
+// the data is read from the start instead of from a partially consumed stream.
+type BlobSource func() (io.ReadCloser, error)
+
+// FileBlobSource reads a blob from a file
+func FileBlobSource(path string) BlobSource {
+	return func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+}
+
+// BytesBlobSource reads a blob from memory
+func BytesBlobSource(data []byte) BlobSource {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// UploadBlob uploads a blob (e.g. an image for a post or a link card) and returns the ref to
+// put in an embed. An empty mimeType is detected from the data. The data must not be larger
+// than BLOB_MAX_BYTES. The ref the PDS returns is checked against the sha256 of the data, and
+// if it doesn't match the source is read again and the upload is retried.
+func (r *BlueskyRepository) UploadBlob(ctx context.Context, source BlobSource, mimeType string) (*BlobRef, error) {
+	var checksum [sha256.Size]byte
+	var err error
+	for attempt := 0; attempt <= r.cfg.MaxRetries; attempt++ {
+		var data []byte
+		data, err = readBlob(source, r.cfg.BlobMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		if attempt == 0 {
+			checksum = sum
+		} else if sum != checksum {
+			return nil, ErrBlobChanged
+		}
+		contentType := mimeType
+		if contentType == "" {
+			contentType = detectBlobType(data)
+		}
+
+		var output *UploadBlobOutput
+		err = r.client.Do(ctx, http.MethodPost, NSIDUploadBlob, func(headers map[string]string) error {
+			var err error
+			output, err = r.xrpc.UploadBlob(ctx, data, contentType, headers)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload blob: %w", err)
+		}
+		if err = verifyBlob(&output.Blob, sum, int64(len(data))); err == nil {
+			return &output.Blob, nil
+		}
+		r.logger.Warn("Uploaded blob does not match, retrying",
+			"attempt", attempt+1, "max_attempts", r.cfg.MaxRetries+1, "error", redact.Error(err))
+	}
+	return nil, err
+}
+
+// readBlob reads the whole source, failing if it is larger than maxBytes (zero means no limit)
+func readBlob(source BlobSource, maxBytes int64) ([]byte, error) {
+	rc, err := source()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	defer rc.Close()
+
+	reader := io.Reader(rc)
+	if maxBytes > 0 {
+		// Read one byte more than allowed to tell a blob at the limit from one over it
+		reader = io.LimitReader(rc, maxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBlobTooLarge, maxBytes)
+	}
+	if len(data) == 0 {
+		return nil, ErrBlobEmpty
+	}
+	return data, nil
+}
+
+// detectBlobType sniffs the MIME type of the data, without parameters such as charset
+func detectBlobType(data []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// verifyBlob checks the size and, for a raw sha256 CID, the checksum of an uploaded blob.
+// Refs in another form can't be computed locally and are accepted.
+func verifyBlob(blob *BlobRef, sum [sha256.Size]byte, size int64) error {
+	if blob.Size != 0 && blob.Size != size {
+		return fmt.Errorf("%w: size is %d, sent %d bytes", ErrBlobChecksumMismatch, blob.Size, size)
+	}
+	if !strings.HasPrefix(blob.Ref.Link, rawSHA256CIDPrefix) {
+		return nil
+	}
+	if want := rawCID(sum); blob.Ref.Link != want {
+		return fmt.Errorf("%w: ref is %s, want %s", ErrBlobChecksumMismatch, blob.Ref.Link, want)
+	}
+	return nil
+}
+
+var cidEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// rawCID returns the CIDv1 (raw codec, sha2-256 multihash) of data with the given sha256,
+// in the base32 multibase form used by atproto
+func rawCID(sum [sha256.Size]byte) string {
+	cid := append([]byte{0x01, 0x55, 0x12, sha256.Size}, sum[:]...)
+	return "b" + cidEncoding.EncodeToString(cid)
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestRawCID(t *testing.T) {
+	// 空のデータの CIDv1（raw, sha2-256）
+	if got, want := rawCID(sha256.Sum256(nil)), "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"; got != want {
+		t.Errorf("rawCID() = %s, want %s", got, want)
+	}
+}
+
+func TestBlueskyRepository_UploadBlob(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	pngFile := filepath.Join(t.TempDir(), "quote.png")
+	if err := os.WriteFile(pngFile, png, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		source       BlobSource
+		mimeType     string
+		maxBytes     int64
+		badRefs      int32 // 最初の何回のアップロードで誤った ref を返すか
+		wantType     string
+		wantUploads  int32
+		wantErr      error
+		wantAnyError bool
+	}{
+		{
+			name:        "正常系: ファイルの種類を判定してアップロードする",
+			source:      FileBlobSource(pngFile),
+			wantType:    "image/png",
+			wantUploads: 1,
+		},
+		{
+			name:        "正常系: 指定した種類で送る",
+			source:      BytesBlobSource(png),
+			mimeType:    "image/webp",
+			wantType:    "image/webp",
+			wantUploads: 1,
+		},
+		{
+			name:        "正常系: チェックサムが合わなければ読み直して再試行する",
+			source:      FileBlobSource(pngFile),
+			badRefs:     1,
+			wantType:    "image/png",
+			wantUploads: 2,
+		},
+		{
+			name:        "異常系: 再試行してもチェックサムが合わない",
+			source:      BytesBlobSource(png),
+			badRefs:     10,
+			wantUploads: 3,
+			wantErr:     ErrBlobChecksumMismatch,
+		},
+		{
+			name:     "異常系: 上限を超えるblobは送らない",
+			source:   BytesBlobSource(png),
+			maxBytes: int64(len(png)) - 1,
+			wantErr:  ErrBlobTooLarge,
+		},
+		{
+			name:    "異常系: 空のblob",
+			source:  BytesBlobSource(nil),
+			wantErr: ErrBlobEmpty,
+		},
+		{
+			name:         "異常系: ファイルがない",
+			source:       FileBlobSource(filepath.Join(t.TempDir(), "missing.png")),
+			wantAnyError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uploads atomic.Int32
+			var gotType atomic.Value
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/xrpc/"+NSIDRefreshSession {
+					json.NewEncoder(w).Encode(map[string]string{"accessJwt": "access-token", "refreshJwt": "refresh-token"})
+					return
+				}
+				if r.URL.Path != "/xrpc/"+NSIDUploadBlob {
+					http.NotFound(w, r)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				gotType.Store(r.Header.Get("Content-Type"))
+				ref := rawCID(sha256.Sum256(body))
+				if uploads.Add(1) <= tt.badRefs {
+					ref = rawCID(sha256.Sum256([]byte("corrupted")))
+				}
+				json.NewEncoder(w).Encode(UploadBlobOutput{Blob: BlobRef{
+					Type:     "blob",
+					Ref:      CIDLink{Link: ref},
+					MimeType: r.Header.Get("Content-Type"),
+					Size:     int64(len(body)),
+				}})
+			}))
+			defer server.Close()
+
+			maxBytes := tt.maxBytes
+			if maxBytes == 0 {
+				maxBytes = 1000000
+			}
+			repo, err := NewBlueskyRepository(&config.Config{
+				AccessJWT:            "access-token",
+				RefreshJWT:           "refresh-token",
+				DID:                  "did:plc:test",
+				PDSURL:               server.URL,
+				HTTPTimeout:          3 * time.Second,
+				TokenRefreshInterval: time.Hour,
+				MaxRetries:           2,
+				RetryBackoff:         time.Millisecond,
+				BlobMaxBytes:         maxBytes,
+			})
+			if err != nil {
+				t.Fatalf("NewBlueskyRepository() error = %v", err)
+			}
+			defer repo.Shutdown()
+
+			blob, err := repo.UploadBlob(context.Background(), tt.source, tt.mimeType)
+			if tt.wantErr != nil || tt.wantAnyError {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("UploadBlob() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("UploadBlob() error = %v", err)
+			} else if blob.Ref.Link != rawCID(sha256.Sum256(png)) || blob.MimeType != tt.wantType {
+				t.Errorf("UploadBlob() = %+v", blob)
+			}
+			if got := uploads.Load(); got != tt.wantUploads {
+				t.Errorf("uploads = %d, want %d", got, tt.wantUploads)
+			}
+			if tt.wantType != "" && gotType.Load() != tt.wantType {
+				t.Errorf("Content-Type = %v, want %v", gotType.Load(), tt.wantType)
+			}
+		})
+	}
+}
@@ -3,10 +3,16 @@ package repository
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
 	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
 )
 
 // BlueskyRepository handles posting to Bluesky
@@ -14,86 +20,257 @@ type BlueskyRepository struct {
 	cfg          *config.Config
 	tokenManager *TokenManager
 	httpClient   *HTTPClient
-	Done         chan struct{} // Exported for cleanup in main
+	xrpc         *XRPCClient
+	client       *AuthenticatedClient // Calls xrpc as the account, refreshing the token on 401
+	clock        clock.Clock          // Stamps the createdAt of new records
+	logger       *slog.Logger
 }
 
-// NewBlueskyRepository creates a new BlueskyRepository instance
-func NewBlueskyRepository(cfg *config.Config) *BlueskyRepository {
+// NewBlueskyRepository creates a new BlueskyRepository instance. The middlewares are added
+// to its HTTP client, e.g. MetricsMiddleware with the process's metrics sink
+func NewBlueskyRepository(cfg *config.Config, middlewares ...Middleware) (*BlueskyRepository, error) {
 	// Create the HTTP client
-	httpClient := NewHTTPClient(cfg)
+	httpClient, err := NewHTTPClient(cfg, middlewares...)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create the token encryptor
-	encryptor := NewTokenEncryptor()
+	encryptor, err := NewTokenEncryptorFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token encryptor: %w", err)
+	}
 
 	// Create the token manager
 	tokenManager := NewTokenManager(cfg, encryptor, httpClient)
+	xrpc := newConfiguredXRPCClient(cfg, httpClient)
 
 	return &BlueskyRepository{
 		cfg:          cfg,
 		tokenManager: tokenManager,
 		httpClient:   httpClient,
-		Done:         make(chan struct{}),
-	}
+		xrpc:         xrpc,
+		client:       NewAuthenticatedClient(tokenManager, xrpc),
+		clock:        clock.Real,
+		logger:       logging.ModuleFor(cfg, "bluesky"),
+	}, nil
 }
 
 // PostMessage posts the specified message to Bluesky
 func (r *BlueskyRepository) PostMessage(ctx context.Context, message string) error {
-	url := fmt.Sprintf("%s/xrpc/com.atproto.repo.createRecord", r.cfg.PDSURL)
+	_, err := r.Publish(ctx, message)
+	return err
+}
+
+// Publish posts the specified message to Bluesky and returns a reference to the created post.
+// The record key is made from the request ID, so that retrying the request cannot post twice
+func (r *BlueskyRepository) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	return r.PublishOnce(ctx, message, domain.NewPostKey(r.clock.Now(), RequestIDFromContext(ctx)))
+}
 
-	// Get access token
-	accessToken, err := r.tokenManager.GetToken(AccessToken)
+// PublishOnce posts the message with key as its record key, so that a post that already
+// went out (e.g. before a crash or a timeout) is returned instead of being posted twice
+func (r *BlueskyRepository) PublishOnce(ctx context.Context, message, key string) (*domain.PostRef, error) {
+	ref, err := r.publish(ctx, message, key)
+	if err == nil {
+		return ref, nil
+	}
+	// applyWrites fails for a record key that is already taken
+	if existing, findErr := r.FindPost(ctx, key); findErr == nil && existing != nil {
+		r.logger.Info("Post with this idempotency key already exists", "key", key, "uri", existing.URI)
+		return existing, nil
+	}
+	return nil, err
+}
+
+// FindPost returns the post created with key as its record key, or nil if there is none
+func (r *BlueskyRepository) FindPost(ctx context.Context, key string) (*domain.PostRef, error) {
+	var output *GetRecordOutput
+	err := r.client.Do(ctx, http.MethodGet, NSIDGetRecord, func(headers map[string]string) (err error) {
+		output, err = r.xrpc.GetRecord(ctx, r.cfg.DID, CollectionFeedPost, key, headers)
+		return err
+	})
+	if IsRecordNotFound(err) {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get access token: %w", err)
-	}
-
-	// Create request body
-	requestBody := map[string]interface{}{
-		"repo":       r.cfg.DID,
-		"collection": "app.bsky.feed.post",
-		"record": map[string]interface{}{
-			"$type":     "app.bsky.feed.post",
-			"text":      message,
-			"createdAt": time.Now().Format(time.RFC3339),
-			"facets":    []interface{}{},
-		},
+		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
+	return blueskyPostRef(output.URI, output.CID), nil
+}
 
-	// Set request headers
-	headers := map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", accessToken),
-		"Content-Type":  "application/json",
+// publish creates the post record with rkey as its record key
+func (r *BlueskyRepository) publish(ctx context.Context, message, rkey string) (*domain.PostRef, error) {
+	// Refresh proactively if the access token is about to expire
+	if err := r.tokenManager.EnsureFreshToken(ctx); err != nil {
+		r.logger.Warn("Proactive token refresh failed, trying the current token", "error", redact.Error(err))
 	}
 
-	// Send the request
-	resp, err := r.httpClient.DoRequest(ctx, "POST", url, requestBody, headers)
+	input, err := r.BuildRecord(message, r.clock.Now())
 	if err != nil {
-		// If unauthorized, try to refresh the token and retry
-		if httpErr, ok := err.(*HTTPError); ok && httpErr.StatusCode == 401 {
-			if err := r.tokenManager.RefreshToken(ctx); err != nil {
-				return fmt.Errorf("failed to refresh token: %w", err)
-			}
+		return nil, err
+	}
+	input.Rkey = rkey
+	setLangs(input, usecase.LangsFromContext(ctx))
 
-			// Get new access token
-			accessToken, err = r.tokenManager.GetToken(AccessToken)
-			if err != nil {
-				return fmt.Errorf("failed to get refreshed access token: %w", err)
-			}
+	output, err := r.createKeyedRecord(ctx, *input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post message: %w", err)
+	}
+	return blueskyPostRef(output.URI, output.CID), nil
+}
 
-			// Update header with new token
-			headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+// createKeyedRecord creates a record with its record key set, through applyWrites. The PDS
+// rejects a second record with the same key, so unlike createRecord the request is safe to
+// retry after a 5xx or a timeout
+func (r *BlueskyRepository) createKeyedRecord(ctx context.Context, input CreateRecordInput) (*CreateRecordOutput, error) {
+	write := ApplyWrite{Type: ApplyWritesCreate, Collection: input.Collection, Rkey: input.Rkey, Value: input.Record}
+	var output *ApplyWritesOutput
+	err := r.client.Do(ctx, http.MethodPost, NSIDApplyWrites, func(headers map[string]string) (err error) {
+		output, err = r.xrpc.ApplyWrites(ctx, ApplyWritesInput{Repo: input.Repo, Validate: input.Validate, Writes: []ApplyWrite{write}}, headers)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Results) == 1 {
+		return &output.Results[0], nil
+	}
+	// Without results the record has to be read back for its CID
+	var record *GetRecordOutput
+	err = r.client.Do(ctx, http.MethodGet, NSIDGetRecord, func(headers map[string]string) (err error) {
+		record, err = r.xrpc.GetRecord(ctx, input.Repo, input.Collection, input.Rkey, headers)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the created record: %w", err)
+	}
+	return &CreateRecordOutput{URI: record.URI, CID: record.CID}, nil
+}
+
+// createRecord creates a record as the account
+func (r *BlueskyRepository) createRecord(ctx context.Context, input CreateRecordInput) (output *CreateRecordOutput, err error) {
+	err = r.client.Do(ctx, http.MethodPost, NSIDCreateRecord, func(headers map[string]string) error {
+		output, err = r.xrpc.CreateRecord(ctx, input, headers)
+		return err
+	})
+	return output, err
+}
+
+// blueskyPostRef returns the reference to a Bluesky post, with its record key as the ID
+// and its bsky.app page as the URL
+func blueskyPostRef(uri, cid string) *domain.PostRef {
+	ref := &domain.PostRef{Platform: domain.PlatformBluesky, URI: uri, CID: cid}
+	if did, rkey, ok := splitPostURI(uri); ok {
+		ref.ID = rkey
+		ref.URL = BlueskyWebURL + "/profile/" + did + "/post/" + rkey
+	}
+	return ref
+}
+
+// Capabilities returns the length limit Bluesky applies to post text
+func (r *BlueskyRepository) Capabilities() domain.Capabilities {
+	return domain.BlueskyCapabilities
+}
 
-			// Retry the request
-			resp, err = r.httpClient.DoRequest(ctx, "POST", url, requestBody, headers)
-			if err != nil {
-				return fmt.Errorf("failed to post message after token refresh: %w", err)
+// FormatDatetime formats t as an atproto datetime for record fields such as createdAt.
+// The lexicon expects RFC 3339 with a timezone; the time is always sent in UTC and
+// without fractional seconds, so that records don't depend on the host's timezone.
+func FormatDatetime(t time.Time) string {
+	return t.UTC().Truncate(time.Second).Format(time.RFC3339)
+}
+
+// BuildRecord builds the createRecord input for a post without sending it,
+// rejecting text that Bluesky would not accept
+func (r *BlueskyRepository) BuildRecord(message string, now time.Time) (*CreateRecordInput, error) {
+	if err := domain.ValidatePostText(message); err != nil {
+		return nil, fmt.Errorf("invalid post text: %w", err)
+	}
+	return &CreateRecordInput{
+		Repo:       r.cfg.DID,
+		Collection: CollectionFeedPost,
+		Record: FeedPost{
+			Type:      CollectionFeedPost,
+			Text:      message,
+			CreatedAt: FormatDatetime(now),
+			Facets:    tagFacets(message),
+		},
+	}, nil
+}
+
+// setLangs tags the post record with the languages of its text
+func setLangs(input *CreateRecordInput, langs []string) {
+	if len(langs) == 0 {
+		return
+	}
+	record := input.Record.(FeedPost)
+	record.Langs = langs
+	input.Record = record
+}
+
+// SessionInfo describes the account behind the current session
+type SessionInfo struct {
+	Handle string `json:"handle"`
+	DID    string `json:"did"`
+	Active *bool  `json:"active,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// ValidateSession verifies that the access token is accepted by the PDS by calling
+// com.atproto.server.getSession, refreshing the token once if it has expired
+func (r *BlueskyRepository) ValidateSession(ctx context.Context) (*SessionInfo, error) {
+	var session *SessionInfo
+	err := r.client.Do(ctx, http.MethodGet, NSIDGetSession, func(headers map[string]string) (err error) {
+		session, err = r.xrpc.GetSession(ctx, headers)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if r.cfg.DID != "" && session.DID != r.cfg.DID {
+		return session, fmt.Errorf("session belongs to %s, but DID is configured as %s", session.DID, r.cfg.DID)
+	}
+	if session.Active != nil && !*session.Active {
+		return session, fmt.Errorf("account %s is not active (status: %s)", session.Handle, session.Status)
+	}
+
+	return session, nil
+}
+
+// Engagement fetches the like, repost, reply and quote counts of the given posts with
+// app.bsky.feed.getPosts, in batches of MaxGetPostsURIs. Posts that no longer exist are
+// missing from the result.
+func (r *BlueskyRepository) Engagement(ctx context.Context, uris []string) (map[string]domain.Engagement, error) {
+	result := make(map[string]domain.Engagement, len(uris))
+	for start := 0; start < len(uris); start += MaxGetPostsURIs {
+		batch := uris[start:min(start+MaxGetPostsURIs, len(uris))]
+		output, err := r.getPosts(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range output.Posts {
+			result[post.URI] = domain.Engagement{
+				Likes:   post.LikeCount,
+				Reposts: post.RepostCount,
+				Replies: post.ReplyCount,
+				Quotes:  post.QuoteCount,
 			}
-		} else {
-			return fmt.Errorf("failed to post message: %w", err)
 		}
 	}
-	defer resp.Body.Close()
+	return result, nil
+}
 
-	return nil
+// getPosts calls app.bsky.feed.getPosts, refreshing the token once if it has expired
+func (r *BlueskyRepository) getPosts(ctx context.Context, uris []string) (output *GetPostsOutput, err error) {
+	err = r.client.Do(ctx, http.MethodGet, NSIDGetPosts, func(headers map[string]string) error {
+		output, err = r.xrpc.GetPosts(ctx, uris, headers)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+	return output, nil
 }
 
 // RefreshToken refreshes the access token
@@ -101,20 +278,40 @@ func (r *BlueskyRepository) RefreshToken(ctx context.Context) error {
 	return r.tokenManager.RefreshToken(ctx)
 }
 
-// PostRandomQuote selects a random quote and posts it
-func (r *BlueskyRepository) PostRandomQuote(ctx context.Context, quote *domain.Quote) error {
-	if quote == nil {
-		return fmt.Errorf("quote cannot be nil")
-	}
+// OnTokenRefresh registers a function that is called with the result of every token refresh
+func (r *BlueskyRepository) OnTokenRefresh(observer func(error)) {
+	r.tokenManager.SetRefreshObserver(observer)
+}
+
+// SessionState returns the state of the account's session, see SessionState
+func (r *BlueskyRepository) SessionState() string {
+	state, _ := r.tokenManager.SessionState()
+	return string(state)
+}
+
+// OnSessionStateChange registers a function that is called on every session state change
+func (r *BlueskyRepository) OnSessionStateChange(observer func(from, to string, err error)) {
+	r.tokenManager.SetSessionStateObserver(func(from, to SessionState, err error) {
+		observer(string(from), string(to), err)
+	})
+}
+
+// AccessTokenExpiry returns when the current access token expires, if known
+func (r *BlueskyRepository) AccessTokenExpiry() (time.Time, bool) {
+	return r.tokenManager.AccessTokenExpiry()
+}
+
+// CheckRefreshInterval returns an error when TOKEN_REFRESH_INTERVAL is longer than the access token's lifetime
+func (r *BlueskyRepository) CheckRefreshInterval() error {
+	return r.tokenManager.CheckRefreshInterval()
+}
 
-	formattedMessage := fmt.Sprintf("%s\n- %s", quote.Text, quote.Author)
-	return r.PostMessage(ctx, formattedMessage)
+// RateLimits returns the rate limit budget the PDS last reported for each endpoint
+func (r *BlueskyRepository) RateLimits() map[string]RateLimit {
+	return r.httpClient.RateLimits()
 }
 
-// Shutdown cleans up resources
+// Shutdown stops the background token refresh and waits for it to exit
 func (r *BlueskyRepository) Shutdown() {
-	// Shut down token manager
 	r.tokenManager.Shutdown()
-	// Signal that we're done
-	close(r.Done)
 }
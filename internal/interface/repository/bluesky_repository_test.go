@@ -3,12 +3,16 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
 )
 
 func TestBlueskyRepository_PostMessage(t *testing.T) {
@@ -16,7 +20,7 @@ func TestBlueskyRepository_PostMessage(t *testing.T) {
 	var refreshCount int
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
-		case "/xrpc/com.atproto.repo.createRecord":
+		case "/xrpc/com.atproto.repo.applyWrites":
 			if r.Header.Get("Authorization") == "Bearer invalid-token" {
 				w.WriteHeader(http.StatusUnauthorized)
 				json.NewEncoder(w).Encode(map[string]string{
@@ -25,9 +29,9 @@ func TestBlueskyRepository_PostMessage(t *testing.T) {
 				return
 			}
 			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(map[string]string{
-				"uri": "at://did:plc:test/app.bsky.feed.post/test",
-			})
+			json.NewEncoder(w).Encode(ApplyWritesOutput{Results: []CreateRecordOutput{{
+				URI: "at://did:plc:test/app.bsky.feed.post/test",
+			}}})
 		case "/xrpc/com.atproto.server.refreshSession":
 			refreshCount++
 			w.WriteHeader(http.StatusOK)
@@ -80,7 +84,10 @@ func TestBlueskyRepository_PostMessage(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			refreshCount = 0
-			repo := NewBlueskyRepository(tt.cfg)
+			repo, err := NewBlueskyRepository(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewBlueskyRepository() error = %v", err)
+			}
 			ctx := context.Background()
 
 			// 初期化時に最低1回トークンリフレッシュが呼ばれる
@@ -90,7 +97,7 @@ func TestBlueskyRepository_PostMessage(t *testing.T) {
 
 			// 投稿前に明示的なリフレッシュを行う（main.goの動作に合わせる）
 			beforeRefreshCount := refreshCount
-			err := repo.RefreshToken(ctx)
+			err = repo.RefreshToken(ctx)
 			if err != nil {
 				t.Errorf("明示的なトークンリフレッシュに失敗しました: %v", err)
 			}
@@ -159,7 +166,10 @@ func TestBlueskyRepository_RefreshToken(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			refreshCount = 0
-			repo := NewBlueskyRepository(tt.cfg)
+			repo, err := NewBlueskyRepository(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewBlueskyRepository() error = %v", err)
+			}
 			ctx := context.Background()
 
 			// 初期化時に最低1回トークンリフレッシュが呼ばれる
@@ -170,7 +180,7 @@ func TestBlueskyRepository_RefreshToken(t *testing.T) {
 
 			// 明示的なトークンリフレッシュ
 			beforeRefreshCount := refreshCount
-			err := repo.RefreshToken(ctx)
+			err = repo.RefreshToken(ctx)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("BlueskyRepository.RefreshToken() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -184,3 +194,351 @@ func TestBlueskyRepository_RefreshToken(t *testing.T) {
 		})
 	}
 }
+
+func TestBlueskyRepository_ValidateSession(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessJWT   string
+		sessionBody map[string]interface{}
+		wantHandle  string
+		wantErr     bool
+	}{
+		{
+			name:      "正常系: 有効なセッション",
+			accessJWT: "valid-token",
+			sessionBody: map[string]interface{}{
+				"handle": "bot.example.com",
+				"did":    "did:plc:test",
+				"active": true,
+			},
+			wantHandle: "bot.example.com",
+		},
+		{
+			name:      "エラー後の回復: 認証エラー後にトークンを更新して成功",
+			accessJWT: "invalid-token",
+			sessionBody: map[string]interface{}{
+				"handle": "bot.example.com",
+				"did":    "did:plc:test",
+			},
+			wantHandle: "bot.example.com",
+		},
+		{
+			name:      "異常系: DIDが一致しない",
+			accessJWT: "valid-token",
+			sessionBody: map[string]interface{}{
+				"handle": "other.example.com",
+				"did":    "did:plc:other",
+			},
+			wantErr: true,
+		},
+		{
+			name:      "異常系: アカウントが無効",
+			accessJWT: "valid-token",
+			sessionBody: map[string]interface{}{
+				"handle": "bot.example.com",
+				"did":    "did:plc:test",
+				"active": false,
+				"status": "takendown",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/xrpc/com.atproto.server.getSession":
+					if r.Header.Get("Authorization") == "Bearer invalid-token" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					json.NewEncoder(w).Encode(tt.sessionBody)
+				case "/xrpc/com.atproto.server.refreshSession":
+					// 初期化時のリフレッシュでは同じトークンを返す
+					accessJWT := tt.accessJWT
+					if r.Header.Get("Authorization") == "Bearer second-refresh-token" {
+						accessJWT = "valid-token"
+					}
+					json.NewEncoder(w).Encode(map[string]string{
+						"accessJwt":  accessJWT,
+						"refreshJwt": "second-refresh-token",
+					})
+				}
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				AccessJWT:            tt.accessJWT,
+				RefreshJWT:           "refresh-token",
+				DID:                  "did:plc:test",
+				PDSURL:               server.URL,
+				HTTPTimeout:          3 * time.Second,
+				TokenRefreshInterval: 1 * time.Hour,
+			}
+			repo, err := NewBlueskyRepository(cfg)
+			if err != nil {
+				t.Fatalf("NewBlueskyRepository() error = %v", err)
+			}
+			defer repo.Shutdown()
+
+			session, err := repo.ValidateSession(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSession() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && session.Handle != tt.wantHandle {
+				t.Errorf("ValidateSession() handle = %v, want %v", session.Handle, tt.wantHandle)
+			}
+		})
+	}
+}
+
+func TestBlueskyRepository_Engagement(t *testing.T) {
+	var requests [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/app.bsky.feed.getPosts":
+			uris := r.URL.Query()["uris"]
+			requests = append(requests, uris)
+			var posts []PostView
+			for i, uri := range uris {
+				// 削除された投稿は返らない
+				if uri == "at://did:plc:test/app.bsky.feed.post/deleted" {
+					continue
+				}
+				posts = append(posts, PostView{URI: uri, LikeCount: i + 1, RepostCount: 2, ReplyCount: 1, QuoteCount: 0})
+			}
+			json.NewEncoder(w).Encode(GetPostsOutput{Posts: posts})
+		case "/xrpc/com.atproto.server.refreshSession":
+			json.NewEncoder(w).Encode(map[string]string{
+				"accessJwt":  "valid-token",
+				"refreshJwt": "refresh-token",
+			})
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		AccessJWT:            "valid-token",
+		RefreshJWT:           "refresh-token",
+		DID:                  "did:plc:test",
+		PDSURL:               server.URL,
+		HTTPTimeout:          3 * time.Second,
+		TokenRefreshInterval: 1 * time.Hour,
+	}
+	repo, err := NewBlueskyRepository(cfg)
+	if err != nil {
+		t.Fatalf("NewBlueskyRepository() error = %v", err)
+	}
+	defer repo.Shutdown()
+
+	var uris []string
+	for i := 0; i < MaxGetPostsURIs+2; i++ {
+		uris = append(uris, fmt.Sprintf("at://did:plc:test/app.bsky.feed.post/%d", i))
+	}
+	uris = append(uris, "at://did:plc:test/app.bsky.feed.post/deleted")
+
+	got, err := repo.Engagement(context.Background(), uris)
+	if err != nil {
+		t.Fatalf("Engagement() error = %v", err)
+	}
+	// 上限ごとに分けて問い合わせる
+	if len(requests) != 2 || len(requests[0]) != MaxGetPostsURIs || len(requests[1]) != 3 {
+		t.Errorf("requests = %d, want 2 batches", len(requests))
+	}
+	if len(got) != MaxGetPostsURIs+2 {
+		t.Errorf("Engagement() returned %d posts, want %d", len(got), MaxGetPostsURIs+2)
+	}
+	want := domain.Engagement{Likes: 1, Reposts: 2, Replies: 1}
+	if e := got["at://did:plc:test/app.bsky.feed.post/0"]; e != want {
+		t.Errorf("Engagement()[0] = %+v, want %+v", e, want)
+	}
+	if _, ok := got["at://did:plc:test/app.bsky.feed.post/deleted"]; ok {
+		t.Error("deleted post should be missing")
+	}
+}
+
+func TestBlueskyRepository_PublishOnce(t *testing.T) {
+	var mu sync.Mutex
+	records := map[string]string{} // rkey -> text
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.repo.applyWrites":
+			var input struct {
+				Writes []struct {
+					Type  string   `json:"$type"`
+					Rkey  string   `json:"rkey"`
+					Value FeedPost `json:"value"`
+				} `json:"writes"`
+			}
+			json.NewDecoder(r.Body).Decode(&input)
+			if len(input.Writes) != 1 || input.Writes[0].Type != ApplyWritesCreate || input.Writes[0].Rkey == "" {
+				t.Errorf("writes = %+v, want one create with a record key", input.Writes)
+			}
+			rkey := input.Writes[0].Rkey
+			if _, ok := records[rkey]; ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"InvalidRequest","message":"Record already exists"}`))
+				return
+			}
+			records[rkey] = input.Writes[0].Value.Text
+			json.NewEncoder(w).Encode(ApplyWritesOutput{Results: []CreateRecordOutput{{URI: "at://did:plc:test/app.bsky.feed.post/" + rkey, CID: "bafy" + rkey}}})
+		case "/xrpc/com.atproto.repo.getRecord":
+			rkey := r.URL.Query().Get("rkey")
+			if _, ok := records[rkey]; !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"RecordNotFound","message":"Could not locate record"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(GetRecordOutput{URI: "at://did:plc:test/app.bsky.feed.post/" + rkey, CID: "bafy" + rkey})
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		AccessJWT:            "valid-token",
+		RefreshJWT:           "refresh-token",
+		DID:                  "did:plc:test",
+		PDSURL:               server.URL,
+		HTTPTimeout:          3 * time.Second,
+		TokenRefreshInterval: 1 * time.Hour,
+	}
+	repo, err := NewBlueskyRepository(cfg)
+	if err != nil {
+		t.Fatalf("NewBlueskyRepository() error = %v", err)
+	}
+	defer repo.Shutdown()
+
+	key := domain.NewPostKey(time.Now(), "0123456789abcdef")
+	if ref, err := repo.FindPost(context.Background(), key); err != nil || ref != nil {
+		t.Fatalf("FindPost() before posting = %+v, %v, want nil", ref, err)
+	}
+	first, err := repo.PublishOnce(context.Background(), "テスト名言", key)
+	if err != nil {
+		t.Fatalf("PublishOnce() error = %v", err)
+	}
+	if !strings.HasSuffix(first.URI, "/"+key) {
+		t.Errorf("URI = %s, want the key as the record key", first.URI)
+	}
+
+	// 同じキーでもう一度投稿しても、すでにある投稿を返す
+	second, err := repo.PublishOnce(context.Background(), "テスト名言", key)
+	if err != nil {
+		t.Fatalf("PublishOnce() twice error = %v", err)
+	}
+	if *second != *first || len(records) != 1 {
+		t.Errorf("PublishOnce() twice = %+v with %d records, want %+v and 1 record", second, len(records), first)
+	}
+	if ref, err := repo.FindPost(context.Background(), key); err != nil || ref == nil || ref.URI != first.URI {
+		t.Errorf("FindPost() = %+v, %v, want %s", ref, err, first.URI)
+	}
+}
+
+func TestBlueskyRepository_PublishRetry(t *testing.T) {
+	var mu sync.Mutex
+	records := map[string]bool{}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/xrpc/" + NSIDApplyWrites:
+			calls++
+			var input ApplyWritesInput
+			json.NewDecoder(r.Body).Decode(&input)
+			rkey := input.Writes[0].Rkey
+			if records[rkey] {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"InvalidRequest","message":"Record already exists"}`))
+				return
+			}
+			// 書き込んだ後で 500 を返す
+			records[rkey] = true
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/xrpc/" + NSIDGetRecord:
+			rkey := r.URL.Query().Get("rkey")
+			if !records[rkey] {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"RecordNotFound","message":"Could not locate record"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(GetRecordOutput{URI: "at://did:plc:test/app.bsky.feed.post/" + rkey, CID: "bafy" + rkey})
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		AccessJWT:            "valid-token",
+		RefreshJWT:           "refresh-token",
+		DID:                  "did:plc:test",
+		PDSURL:               server.URL,
+		HTTPTimeout:          3 * time.Second,
+		TokenRefreshInterval: 1 * time.Hour,
+		MaxRetries:           2,
+	}
+	repo, err := NewBlueskyRepository(cfg)
+	if err != nil {
+		t.Fatalf("NewBlueskyRepository() error = %v", err)
+	}
+	defer repo.Shutdown()
+
+	// 再試行してもレコードキーが同じため、投稿は1件だけになる
+	ref, err := repo.Publish(WithRequestID(context.Background(), "0123456789abcdef"), "テスト名言")
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if calls != 2 || len(records) != 1 {
+		t.Errorf("applyWrites called %d times with %d records, want 2 calls and 1 record", calls, len(records))
+	}
+	if ref.URI == "" || ref.CID == "" {
+		t.Errorf("Publish() = %+v, want the existing post", ref)
+	}
+}
+
+func TestBlueskyRepository_BuildRecord(t *testing.T) {
+	repo := &BlueskyRepository{cfg: &config.Config{DID: "did:plc:test"}}
+	// createdAt はマシンのタイムゾーンにかかわらずUTCで、秒未満を含めない
+	now := time.Date(2024, 1, 2, 12, 4, 5, 123456789, time.FixedZone("JST", 9*60*60))
+
+	tests := []struct {
+		name    string
+		message string
+		wantErr bool
+	}{
+		{
+			name:    "正常系: 投稿レコードを組み立てる",
+			message: "テスト名言\n- 著者",
+			wantErr: false,
+		},
+		{
+			name:    "異常系: 空の本文",
+			message: "",
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 上限を超える本文",
+			message: strings.Repeat("あ", 301),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := repo.BuildRecord(tt.message, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			post, ok := input.Record.(FeedPost)
+			if input.Repo != "did:plc:test" || input.Collection != CollectionFeedPost || !ok ||
+				post.Text != tt.message || post.CreatedAt != "2024-01-02T03:04:05Z" {
+				t.Errorf("BuildRecord() = %+v", input)
+			}
+		})
+	}
+}
@@ -10,11 +10,20 @@ const (
 	DefaultIdleTimeout  = 180 * time.Second
 	MaxIdleConnections  = 100
 	MaxIdleConnsPerHost = 5
+	DefaultDialTimeout  = 30 * time.Second
+	DefaultKeepAlive    = 30 * time.Second
 
 	// Token related constants
-	TokenCacheTimeout = 60 * time.Minute
-	DefaultKeySize    = 32 // AES-256
+	TokenCacheTimeout    = 60 * time.Minute
+	DefaultKeySize       = 32 // AES-256
+	MinTokenRefreshDelay = 30 * time.Second
+
+	// TokenFileKDFIterations is the PBKDF2-SHA256 work factor for the token file key
+	TokenFileKDFIterations = 600000
 
 	// Retry related constants
 	DefaultMaxRetries = 3
+
+	// BlueskyWebURL is the web app that the URLs of posts point to
+	BlueskyWebURL = "https://bsky.app"
 )
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// DialContextFunc is the signature of http.Transport.DialContext
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// NewDialContext builds the dial function for outgoing connections from the config.
+// With HTTP_UNIX_SOCKET, every connection goes to that socket regardless of the URL's host,
+// which is used for a PDS that is only reachable through a sidecar. HTTP_LOCAL_ADDR binds
+// TCP connections to a specific local IP address (and thus network interface).
+func NewDialContext(cfg *config.Config) (DialContextFunc, error) {
+	dialer := &net.Dialer{
+		Timeout:   DefaultDialTimeout,
+		KeepAlive: DefaultKeepAlive,
+	}
+
+	if cfg.HTTPLocalAddr != "" {
+		if cfg.HTTPUnixSocket != "" {
+			return nil, fmt.Errorf("HTTP_LOCAL_ADDR cannot be combined with HTTP_UNIX_SOCKET")
+		}
+		ip := net.ParseIP(cfg.HTTPLocalAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid HTTP_LOCAL_ADDR: %s", cfg.HTTPLocalAddr)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	if cfg.HTTPUnixSocket != "" {
+		socket := cfg.HTTPUnixSocket
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}, nil
+	}
+
+	return dialer.DialContext, nil
+}
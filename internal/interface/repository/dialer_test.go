@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestHTTPClient_UnixSocket(t *testing.T) {
+	// macOSではソケットのパス長が制限されるため短いディレクトリを使う
+	dir, err := os.MkdirTemp("", "qb")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "pds.sock")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not available: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ホストはURLのものがそのまま送られる
+		if r.Host != "pds.internal" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	cfg := &config.Config{HTTPTimeout: 3 * time.Second, HTTPUnixSocket: socket}
+	client := newTestHTTPClient(t, cfg)
+
+	resp, err := client.DoRequest(context.Background(), "GET", "http://pds.internal/xrpc/_health", nil, nil)
+	if err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewDialContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{
+			name:    "正常系: デフォルトのダイアラー",
+			cfg:     &config.Config{},
+			wantErr: false,
+		},
+		{
+			name:    "正常系: ローカルアドレスを指定",
+			cfg:     &config.Config{HTTPLocalAddr: "127.0.0.1"},
+			wantErr: false,
+		},
+		{
+			name:    "異常系: 不正なローカルアドレス",
+			cfg:     &config.Config{HTTPLocalAddr: "not-an-ip"},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: Unixソケットとローカルアドレスの併用",
+			cfg:     &config.Config{HTTPLocalAddr: "127.0.0.1", HTTPUnixSocket: "/run/pds.sock"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dial, err := NewDialContext(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewDialContext() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// ローカルのリスナーに接続し、送信元アドレスを確認
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			defer listener.Close()
+
+			conn, err := dial(context.Background(), "tcp", listener.Addr().String())
+			if err != nil {
+				t.Fatalf("dial() error = %v", err)
+			}
+			defer conn.Close()
+
+			if tt.cfg.HTTPLocalAddr != "" {
+				local := conn.LocalAddr().(*net.TCPAddr)
+				if local.IP.String() != tt.cfg.HTTPLocalAddr {
+					t.Errorf("local address = %v, want %v", local.IP, tt.cfg.HTTPLocalAddr)
+				}
+			}
+		})
+	}
+}
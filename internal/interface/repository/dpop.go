@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+)
+
+// DPoPKey is the ES256 key that OAuth tokens are bound to (RFC 9449)
+type DPoPKey struct {
+	privateKey *ecdsa.PrivateKey
+}
+
+// NewDPoPKey generates a new P-256 DPoP key
+func NewDPoPKey() (*DPoPKey, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate DPoP key: %w", err)
+	}
+	return &DPoPKey{privateKey: privateKey}, nil
+}
+
+// ParseDPoPKey parses a key produced by DPoPKey.Marshal
+func ParseDPoPKey(encoded string) (*DPoPKey, error) {
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode DPoP key: %w", err)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DPoP key: %w", err)
+	}
+
+	privateKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || privateKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("DPoP key is not a P-256 ECDSA key")
+	}
+	return &DPoPKey{privateKey: privateKey}, nil
+}
+
+// Marshal encodes the private key as base64 PKCS#8 for storage
+func (k *DPoPKey) Marshal() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal DPoP key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// Proof creates a DPoP proof JWT for a request.
+// nonce is the latest DPoP-Nonce received from the server, and accessToken is set
+// for resource server requests so that the proof is bound to the token (ath claim).
+func (k *DPoPKey) Proof(method, requestURL, nonce, accessToken string) (string, error) {
+	// htu must not include the query or fragment
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid request URL: %w", err)
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	jti := make([]byte, 16)
+	rand.Read(jti)
+
+	header := map[string]interface{}{
+		"typ": "dpop+jwt",
+		"alg": "ES256",
+		"jwk": k.publicJWK(),
+	}
+	claims := map[string]interface{}{
+		"jti": base64.RawURLEncoding.EncodeToString(jti),
+		"htm": method,
+		"htu": u.String(),
+		"iat": time.Now().Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	if accessToken != "" {
+		hash := sha256.Sum256([]byte(accessToken))
+		claims["ath"] = base64.RawURLEncoding.EncodeToString(hash[:])
+	}
+
+	return k.sign(header, claims)
+}
+
+// publicJWK returns the public key in JWK form
+func (k *DPoPKey) publicJWK() map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(padScalar(k.privateKey.X)),
+		"y":   base64.RawURLEncoding.EncodeToString(padScalar(k.privateKey.Y)),
+	}
+}
+
+// sign creates a compact ES256 JWS
+func (k *DPoPKey) sign(header, claims map[string]interface{}) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, k.privateKey, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign DPoP proof: %w", err)
+	}
+
+	// JWS uses the fixed-size r||s encoding rather than ASN.1
+	signature := append(padScalar(r), padScalar(s)...)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// padScalar encodes a P-256 scalar or coordinate as exactly 32 bytes
+func padScalar(n *big.Int) []byte {
+	out := make([]byte, 32)
+	return n.FillBytes(out)
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// verifyDPoPProof はDPoPプルーフの署名を検証し、ヘッダーとクレームを返します
+func verifyDPoPProof(t *testing.T, proof string) (map[string]interface{}, map[string]interface{}) {
+	t.Helper()
+
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		t.Fatalf("proof has %d parts, want 3", len(parts))
+	}
+
+	var header struct {
+		Typ string            `json:"typ"`
+		Alg string            `json:"alg"`
+		JWK map[string]string `json:"jwk"`
+	}
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		t.Fatalf("failed to parse header: %v", err)
+	}
+	if header.Typ != "dpop+jwt" || header.Alg != "ES256" {
+		t.Errorf("header typ/alg = %s/%s, want dpop+jwt/ES256", header.Typ, header.Alg)
+	}
+
+	x, _ := base64.RawURLEncoding.DecodeString(header.JWK["x"])
+	y, _ := base64.RawURLEncoding.DecodeString(header.JWK["y"])
+	publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if len(signature) != 64 {
+		t.Fatalf("signature length = %d, want 64", len(signature))
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(publicKey, digest[:], r, s) {
+		t.Fatalf("proof signature does not verify")
+	}
+
+	var rawHeader, claims map[string]interface{}
+	json.Unmarshal(headerJSON, &rawHeader)
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Fatalf("failed to parse claims: %v", err)
+	}
+	return rawHeader, claims
+}
+
+func TestDPoPKey_Proof(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		nonce       string
+		accessToken string
+		wantHTU     string
+	}{
+		{
+			name:    "正常系: 認可サーバー向け（nonceなし）",
+			url:     "https://auth.example.com/oauth/token",
+			wantHTU: "https://auth.example.com/oauth/token",
+		},
+		{
+			name:        "正常系: PDS向け（nonceとathあり、クエリは除外）",
+			url:         "https://pds.example.com/xrpc/com.atproto.server.getSession?foo=bar",
+			nonce:       "server-nonce",
+			accessToken: "access-token",
+			wantHTU:     "https://pds.example.com/xrpc/com.atproto.server.getSession",
+		},
+	}
+
+	key, err := NewDPoPKey()
+	if err != nil {
+		t.Fatalf("NewDPoPKey() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, err := key.Proof("POST", tt.url, tt.nonce, tt.accessToken)
+			if err != nil {
+				t.Fatalf("Proof() error = %v", err)
+			}
+
+			_, claims := verifyDPoPProof(t, proof)
+			if claims["htm"] != "POST" || claims["htu"] != tt.wantHTU {
+				t.Errorf("htm/htu = %v/%v, want POST/%v", claims["htm"], claims["htu"], tt.wantHTU)
+			}
+			if claims["jti"] == nil || claims["iat"] == nil {
+				t.Errorf("proof is missing jti or iat: %v", claims)
+			}
+
+			if tt.nonce == "" && claims["nonce"] != nil {
+				t.Errorf("nonce = %v, want none", claims["nonce"])
+			}
+			if tt.nonce != "" && claims["nonce"] != tt.nonce {
+				t.Errorf("nonce = %v, want %v", claims["nonce"], tt.nonce)
+			}
+
+			if tt.accessToken != "" {
+				hash := sha256.Sum256([]byte(tt.accessToken))
+				if claims["ath"] != base64.RawURLEncoding.EncodeToString(hash[:]) {
+					t.Errorf("ath = %v, want hash of the access token", claims["ath"])
+				}
+			} else if claims["ath"] != nil {
+				t.Errorf("ath = %v, want none", claims["ath"])
+			}
+		})
+	}
+}
+
+func TestDPoPKey_MarshalRoundTrip(t *testing.T) {
+	key, err := NewDPoPKey()
+	if err != nil {
+		t.Fatalf("NewDPoPKey() error = %v", err)
+	}
+
+	encoded, err := key.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	parsed, err := ParseDPoPKey(encoded)
+	if err != nil {
+		t.Fatalf("ParseDPoPKey() error = %v", err)
+	}
+
+	// 同じ鍵であればJWKが一致する
+	if parsed.publicJWK()["x"] != key.publicJWK()["x"] || parsed.publicJWK()["y"] != key.publicJWK()["y"] {
+		t.Errorf("parsed key does not match the original")
+	}
+
+	if _, err := ParseDPoPKey("not-a-key"); err == nil {
+		t.Errorf("ParseDPoPKey() with invalid input: expected error")
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// FacetTypeTag is the facet feature type for hashtags
+const FacetTypeTag = "app.bsky.richtext.facet#tag"
+
+// Facet annotates a byte range of a post's text
+type Facet struct {
+	Index    FacetIndex    `json:"index"`
+	Features []interface{} `json:"features"`
+}
+
+// FacetIndex is a UTF-8 byte range, end exclusive
+type FacetIndex struct {
+	ByteStart int `json:"byteStart"`
+	ByteEnd   int `json:"byteEnd"`
+}
+
+// FacetTag is an app.bsky.richtext.facet#tag feature
+type FacetTag struct {
+	Type string `json:"$type"`
+	Tag  string `json:"tag"`
+}
+
+// tagFacets finds the hashtags in text the way Bluesky clients do: a # or ＃
+// at the start of the text or after whitespace, up to the next whitespace,
+// without trailing punctuation, containing something other than digits
+func tagFacets(text string) []interface{} {
+	var facets []interface{}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		atBoundary := i == 0
+		if !atBoundary {
+			prev, _ := utf8.DecodeLastRuneInString(text[:i])
+			atBoundary = unicode.IsSpace(prev)
+		}
+		if (r != '#' && r != '＃') || !atBoundary {
+			i += size
+			continue
+		}
+
+		start := i
+		end := start + size
+		if n := strings.IndexFunc(text[end:], unicode.IsSpace); n >= 0 {
+			end += n
+		} else {
+			end = len(text)
+		}
+		tag := strings.TrimRightFunc(text[start+size:end], unicode.IsPunct)
+		// "##tag" is not a hashtag
+		doubled := strings.HasPrefix(tag, "#") || strings.HasPrefix(tag, "＃")
+		if _, err := domain.NormalizeHashtag(tag); err == nil && !doubled {
+			facets = append(facets, Facet{
+				Index:    FacetIndex{ByteStart: start, ByteEnd: start + size + len(tag)},
+				Features: []interface{}{FacetTag{Type: FacetTypeTag, Tag: tag}},
+			})
+		}
+		i = end
+	}
+	return facets
+}
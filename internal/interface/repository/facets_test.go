@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagFacets(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []interface{}
+	}{
+		{
+			name: "正常系: ハッシュタグなし",
+			text: "Stay hungry, stay foolish.\n- Steve Jobs",
+		},
+		{
+			name: "正常系: 最後の行のハッシュタグ",
+			text: "名言\n#quote #哲学",
+			want: []interface{}{
+				Facet{Index: FacetIndex{ByteStart: 7, ByteEnd: 13}, Features: []interface{}{FacetTag{Type: FacetTypeTag, Tag: "quote"}}},
+				Facet{Index: FacetIndex{ByteStart: 14, ByteEnd: 21}, Features: []interface{}{FacetTag{Type: FacetTypeTag, Tag: "哲学"}}},
+			},
+		},
+		{
+			name: "正常系: 末尾の句読点はタグに含めない",
+			text: "#quote!",
+			want: []interface{}{
+				Facet{Index: FacetIndex{ByteStart: 0, ByteEnd: 6}, Features: []interface{}{FacetTag{Type: FacetTypeTag, Tag: "quote"}}},
+			},
+		},
+		{
+			name: "正常系: 全角の＃",
+			text: "＃名言",
+			want: []interface{}{
+				Facet{Index: FacetIndex{ByteStart: 0, ByteEnd: 9}, Features: []interface{}{FacetTag{Type: FacetTypeTag, Tag: "名言"}}},
+			},
+		},
+		{
+			name: "正常系: 数字だけ、単語の途中、## はハッシュタグにしない",
+			text: "No.#1 #2024 ##tag a#b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagFacets(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tagFacets(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,84 +3,304 @@ package repository
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math/rand"
+	"mime"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/version"
 )
 
 // HTTPError holds error information for HTTP requests
 type HTTPError struct {
 	StatusCode int
 	Message    string
+	Header     http.Header // Response headers, e.g. for DPoP-Nonce or Retry-After
+	RequestID  string      // X-Request-ID the request was sent with, if any
 	Err        error
 }
 
 func (e *HTTPError) Error() string {
-	return fmt.Sprintf("HTTP error (status %d): %s: %v", e.StatusCode, e.Message, e.Err)
+	msg := fmt.Sprintf("HTTP error (status %d): %s: %v", e.StatusCode, e.Message, e.Err)
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+	return msg
+}
+
+// accountSuspendedErrors are the XRPC error names the PDS answers with for an account that
+// was taken down, suspended, or deactivated
+var accountSuspendedErrors = []string{
+	"AccountTakedown", "AccountSuspended", "AccountDeactivated",
+	"RepoTakendown", "RepoSuspended", "RepoDeactivated",
+}
+
+// Is classifies the error for errors.Is: 429 is domain.ErrRateLimited, an account takedown or
+// deactivation is domain.ErrAccountSuspended, and otherwise 401 or a rejected token
+// (ExpiredToken, InvalidToken, or an OAuth invalid_grant) is domain.ErrAuthExpired
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case domain.ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case domain.ErrAccountSuspended:
+		return e.accountSuspended()
+	case domain.ErrAuthExpired:
+		// a new token does not help a suspended account
+		if e.accountSuspended() {
+			return false
+		}
+		if e.StatusCode == http.StatusUnauthorized {
+			return true
+		}
+		return e.StatusCode == http.StatusBadRequest &&
+			(strings.Contains(e.Message, "ExpiredToken") || strings.Contains(e.Message, "InvalidToken") || strings.Contains(e.Message, "invalid_grant"))
+	}
+	return false
+}
+
+func (e *HTTPError) accountSuspended() bool {
+	if e.StatusCode != http.StatusBadRequest && e.StatusCode != http.StatusUnauthorized && e.StatusCode != http.StatusForbidden {
+		return false
+	}
+	for _, name := range accountSuspendedErrors {
+		if strings.Contains(e.Message, name) {
+			return true
+		}
+	}
+	return false
 }
 
+// ErrRetryBudgetExhausted is returned when a retry is skipped because the retry budget is used up
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// Errors returned by DecodeJSONResponse for a response it refuses to decode
+var (
+	ErrResponseTooLarge      = errors.New("response body exceeds the size limit")
+	ErrUnexpectedContentType = errors.New("response is not JSON")
+)
+
+// JitterStrategy defines how randomness is applied to the retry backoff
+type JitterStrategy string
+
+const (
+	// JitterNone uses the plain exponential backoff
+	JitterNone JitterStrategy = "none"
+	// JitterFull picks a random duration between zero and the exponential backoff
+	JitterFull JitterStrategy = "full"
+	// JitterEqual keeps half of the exponential backoff and randomizes the other half
+	JitterEqual JitterStrategy = "equal"
+)
+
 // RetryPolicy defines the retry behavior for HTTP requests
 type RetryPolicy struct {
 	MaxRetries   int
 	RetryBackoff time.Duration
+	Jitter       JitterStrategy // Zero value behaves like JitterNone
+	// SafeOnly only retries failures the server cannot have acted on: 429 and connections
+	// that failed before the request was sent. Requests that are not idempotent use it,
+	// since a 5xx or a timeout may come after the server already did the work.
+	SafeOnly bool
+}
+
+// RequestClass groups requests that share a retry policy
+type RequestClass string
+
+const (
+	// RequestClassRead is the default: queries and writes that are safe to repeat,
+	// like putRecord and deleteRecord
+	RequestClassRead RequestClass = "read"
+	// RequestClassTokenRefresh is refreshing the session tokens
+	RequestClassTokenRefresh RequestClass = "token_refresh"
+	// RequestClassCreateRecord creates a record; repeating it after the server wrote the
+	// record posts twice
+	RequestClassCreateRecord RequestClass = "create_record"
+)
+
+// RetryPolicies returns the retry policy of every request class from MAX_RETRIES and its
+// per-class overrides
+func RetryPolicies(cfg *config.Config) map[RequestClass]RetryPolicy {
+	base := RetryPolicy{
+		MaxRetries:   cfg.MaxRetries,
+		RetryBackoff: cfg.RetryBackoff,
+		Jitter:       JitterStrategy(cfg.RetryJitter),
+	}
+	refresh := base
+	if cfg.MaxRetriesRefresh >= 0 {
+		refresh.MaxRetries = cfg.MaxRetriesRefresh
+	}
+	createRecord := base
+	if cfg.MaxRetriesCreateRecord >= 0 {
+		createRecord.MaxRetries = cfg.MaxRetriesCreateRecord
+	}
+	createRecord.SafeOnly = !cfg.RetryCreateRecordServerErrors
+	return map[RequestClass]RetryPolicy{
+		RequestClassRead:         base,
+		RequestClassTokenRefresh: refresh,
+		RequestClassCreateRecord: createRecord,
+	}
 }
 
 // HTTPClient handles HTTP communication
 type HTTPClient struct {
 	client      *http.Client
-	retryPolicy RetryPolicy
+	timeout     time.Duration                // Default per-attempt timeout, see WithTimeout
+	retryPolicy RetryPolicy                  // For RequestClassRead and requests without a class
+	policies    map[RequestClass]RetryPolicy // Overrides by request class
+	retryBudget *RetryBudget
+	maxBody     int64 // Largest response DecodeJSONResponse reads; zero means no limit
+	metrics     *RetryMetrics
+	rateLimiter *RateLimiter
 	bufferPool  *sync.Pool
+	middlewares []Middleware
+	roundTrip   RoundTripFunc // client.Do wrapped in the middlewares
+	clock       clock.Clock   // Times the backoff between attempts
+	logger      *slog.Logger
 }
 
-// NewHTTPClient creates a new HTTPClient instance
-func NewHTTPClient(cfg *config.Config) *HTTPClient {
-	// Configure TLS
-	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS12,
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-		},
+// NewHTTPClient creates a new HTTPClient instance with the given middlewares.
+// It fails if the TLS settings (CA bundle, client certificate) or dialer settings are invalid.
+func NewHTTPClient(cfg *config.Config, middlewares ...Middleware) (*HTTPClient, error) {
+	tlsConfig, err := NewTLSConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	dialContext, err := NewDialContext(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure dialer: %w", err)
 	}
 
 	transport := &http.Transport{
+		DialContext:         dialContext,
 		IdleConnTimeout:     DefaultIdleTimeout,
 		MaxIdleConns:        MaxIdleConnections,
 		MaxIdleConnsPerHost: MaxIdleConnsPerHost,
 		TLSClientConfig:     tlsConfig,
 	}
 
-	return &HTTPClient{
+	logger := logging.ModuleFor(cfg, "http")
+	rateLimiter := NewRateLimiter(cfg.RateLimitReserve, cfg.RateLimitMaxWait, logger)
+	policies := RetryPolicies(cfg)
+
+	// Identify the bot to PDS operators and tag every request with its request ID
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = version.UserAgent()
+	}
+	middlewares = append([]Middleware{
+		HeaderMiddleware(map[string]string{"User-Agent": userAgent}),
+		RequestIDMiddleware(),
+		rateLimiter.Middleware(),
+	}, middlewares...)
+	if cfg.HTTPLogRequests {
+		middlewares = append(middlewares, loggingMiddleware(logger, LogSampling{
+			Rate:      cfg.HTTPLogSampleRate,
+			PerMinute: cfg.HTTPLogPerMinute,
+		}))
+	}
+
+	c := &HTTPClient{
+		// The timeout is applied per attempt through the request context, so that
+		// it can be overridden for individual requests
 		client: &http.Client{
-			Timeout:   cfg.HTTPTimeout,
 			Transport: transport,
 		},
-		retryPolicy: RetryPolicy{
-			MaxRetries:   cfg.MaxRetries,
-			RetryBackoff: cfg.RetryBackoff,
-		},
+		timeout:     cfg.HTTPTimeout,
+		clock:       clock.Real,
+		logger:      logger,
+		retryPolicy: policies[RequestClassRead],
+		policies:    policies,
+		retryBudget: NewRetryBudget(cfg.RetryBudget, cfg.RetryBudgetWindow),
+		maxBody:     cfg.HTTPMaxResponseBytes,
+		metrics:     &RetryMetrics{},
+		rateLimiter: rateLimiter,
 		bufferPool: &sync.Pool{
 			New: func() interface{} {
 				return new(bytes.Buffer)
 			},
 		},
 	}
+	c.Use(middlewares...)
+	return c, nil
+}
+
+// Use appends middlewares to the chain. The first middleware registered is the outermost.
+// Use must not be called while requests are in flight.
+func (c *HTTPClient) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+	c.roundTrip = Chain(c.client.Do, c.middlewares...)
+}
+
+// RequestOption customizes a single DoRequest call
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	timeout time.Duration
+	class   RequestClass
+}
+
+// WithTimeout overrides the per-attempt timeout (HTTP_TIMEOUT) for a single request,
+// e.g. for blob uploads that need much longer than API calls. Zero means no timeout.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRequestClass retries a single request with the policy of its class instead of the
+// default one
+func WithRequestClass(class RequestClass) RequestOption {
+	return func(o *requestOptions) {
+		o.class = class
+	}
+}
+
+// SetRetryPolicy overrides the retry policy of a request class.
+// SetRetryPolicy must not be called while requests are in flight.
+func (c *HTTPClient) SetRetryPolicy(class RequestClass, policy RetryPolicy) {
+	if c.policies == nil {
+		c.policies = make(map[RequestClass]RetryPolicy)
+	}
+	c.policies[class] = policy
+	if class == RequestClassRead {
+		c.retryPolicy = policy
+	}
+}
+
+// policyFor returns the retry policy of a request class
+func (c *HTTPClient) policyFor(class RequestClass) RetryPolicy {
+	if policy, ok := c.policies[class]; ok && class != RequestClassRead {
+		return policy
+	}
+	return c.retryPolicy
 }
 
-// DoRequest sends an HTTP request with retry logic
-func (c *HTTPClient) DoRequest(ctx context.Context, method string, url string, body interface{}, headers map[string]string) (*http.Response, error) {
+// RawBody is a request body that DoRequest sends as-is instead of encoding it as JSON
+type RawBody []byte
+
+// DoRequest sends an HTTP request with retry logic.
+// The body is encoded as JSON unless it is a RawBody.
+func (c *HTTPClient) DoRequest(ctx context.Context, method string, url string, body interface{}, headers map[string]string, opts ...RequestOption) (*http.Response, error) {
+	options := requestOptions{timeout: c.timeout}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// Retries share the request ID so that they can be correlated in the logs
+	ctx, requestID := ensureRequestID(ctx)
+
 	// Encode body if provided
 	var buf *bytes.Buffer
 	var bodyBytes []byte
@@ -89,7 +309,9 @@ func (c *HTTPClient) DoRequest(ctx context.Context, method string, url string, b
 		buf.Reset()
 		defer c.bufferPool.Put(buf)
 
-		if err := json.NewEncoder(buf).Encode(body); err != nil {
+		if raw, ok := body.(RawBody); ok {
+			buf.Write(raw)
+		} else if err := json.NewEncoder(buf).Encode(body); err != nil {
 			return nil, fmt.Errorf("failed to encode request body: %w", err)
 		}
 
@@ -98,17 +320,19 @@ func (c *HTTPClient) DoRequest(ctx context.Context, method string, url string, b
 		copy(bodyBytes, buf.Bytes())
 	}
 
+	policy := c.policyFor(options.class)
+
 	// Execute request with retries
 	var resp *http.Response
 	var err error
 
-	for attempt := 0; attempt <= c.retryPolicy.MaxRetries; attempt++ {
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
 		if attempt > 0 {
 			// Apply backoff with a maximum limit
-			backoff := c.calculateBackoff(attempt)
+			backoff := calculateBackoff(policy, attempt)
 
 			select {
-			case <-time.After(backoff):
+			case <-c.clock.After(backoff):
 				// Continue with retry
 			case <-ctx.Done():
 				return nil, fmt.Errorf("context cancelled during backoff: %w", ctx.Err())
@@ -122,39 +346,91 @@ func (c *HTTPClient) DoRequest(ctx context.Context, method string, url string, b
 		}
 
 		// Make the actual request
-		resp, err = c.sendRequest(ctx, method, url, buf, headers)
+		resp, err = c.sendRequest(withAttempt(ctx, attempt+1), method, url, buf, headers, options.timeout)
 		if err == nil {
 			// Request succeeded
+			c.metrics.successes.Add(1)
 			return resp, nil
 		}
 
 		// Determine if we should retry
-		if !c.shouldRetry(err, attempt) {
+		if !c.shouldRetry(policy, err, attempt) {
+			c.metrics.failures.Add(1)
 			return nil, err
 		}
 
+		// Stop retrying when the shared budget is used up, so that a
+		// misbehaving endpoint can't multiply our request volume
+		if !c.retryBudget.Allow() {
+			c.metrics.failures.Add(1)
+			c.metrics.budgetExhausted.Add(1)
+			c.logger.Warn("Retry budget exhausted, giving up", "request_id", requestID, "error", redact.Error(err))
+			return nil, fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+		}
+		c.metrics.retries.Add(1)
+
 		// Log retry attempt
-		log.Printf("Request failed (attempt %d/%d): %v. Retrying...",
-			attempt+1, c.retryPolicy.MaxRetries+1, sanitizeError(err))
+		c.logger.Warn("Request failed, retrying",
+			"request_id", requestID, "attempt", attempt+1, "max_attempts", policy.MaxRetries+1, "error", redact.Error(err))
 	}
 
 	// All retries failed
-	return nil, fmt.Errorf("request failed after %d attempts: %w", c.retryPolicy.MaxRetries+1, err)
+	c.metrics.failures.Add(1)
+	return nil, fmt.Errorf("request %s failed after %d attempts: %w", requestID, policy.MaxRetries+1, err)
+}
+
+// Metrics returns a snapshot of the retry, success, and failure counters
+func (c *HTTPClient) Metrics() RetryMetricsSnapshot {
+	return c.metrics.Snapshot()
+}
+
+// RateLimits returns the last rate limit budget the servers reported, keyed by host and path
+func (c *HTTPClient) RateLimits() map[string]RateLimit {
+	return c.rateLimiter.Budgets()
 }
 
 // calculateBackoff determines the backoff duration for a retry
-func (c *HTTPClient) calculateBackoff(attempt int) time.Duration {
-	backoff := c.retryPolicy.RetryBackoff * time.Duration(1<<uint(attempt-1))
+func calculateBackoff(policy RetryPolicy, attempt int) time.Duration {
+	backoff := policy.RetryBackoff * time.Duration(1<<uint(attempt-1))
 	if backoff > MaxBackoffDuration {
 		backoff = MaxBackoffDuration
 	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	// Spread retries out so that several bot instances sharing a PDS
+	// don't all retry at the same moment
+	switch policy.Jitter {
+	case JitterFull:
+		backoff = time.Duration(rand.Int63n(int64(backoff) + 1))
+	case JitterEqual:
+		half := backoff / 2
+		backoff = half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+	}
 	return backoff
 }
 
 // shouldRetry determines if a request should be retried
-func (c *HTTPClient) shouldRetry(err error, attempt int) bool {
+func (c *HTTPClient) shouldRetry(policy RetryPolicy, err error, attempt int) bool {
+	// Count failures without a response by class, and give up at once on those
+	// that fail the same way every time
+	if _, ok := err.(*HTTPError); !ok {
+		class := ClassifyNetworkError(err)
+		c.metrics.recordNetworkError(class)
+		if !class.Retryable() {
+			c.logger.Warn("Request failed and cannot succeed on retry", "class", class, "error", redact.Error(err))
+			return false
+		}
+	}
+
 	// Don't retry if we've reached the maximum
-	if attempt >= c.retryPolicy.MaxRetries {
+	if attempt >= policy.MaxRetries {
+		return false
+	}
+
+	if policy.SafeOnly && !retrySafe(err) {
+		c.logger.Warn("Request may have reached the server, not retrying", "error", redact.Error(err))
 		return false
 	}
 
@@ -167,36 +443,61 @@ func (c *HTTPClient) shouldRetry(err error, attempt int) bool {
 
 		// Log rate limiting specifically
 		if httpErr.StatusCode == 429 {
-			log.Printf("Rate limit exceeded (attempt %d/%d), backing off",
-				attempt+1, c.retryPolicy.MaxRetries+1)
+			c.logger.Warn("Rate limit exceeded, backing off",
+				"attempt", attempt+1, "max_attempts", policy.MaxRetries+1)
 		}
 
 		// Retry on server errors and rate limits
 		return true
 	}
 
-	// Retry on network errors
+	// Retry on DNS, connection, TLS handshake and timeout errors
 	return true
 }
 
-// sendRequest sends a single HTTP request without retrying
-func (c *HTTPClient) sendRequest(ctx context.Context, method string, url string, body *bytes.Buffer, headers map[string]string) (*http.Response, error) {
+// retrySafe reports whether the server cannot have acted on a failed request: it was
+// rejected with 429, or the connection failed before the request was sent
+func retrySafe(err error) bool {
+	if httpErr, ok := err.(*HTTPError); ok {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	switch ClassifyNetworkError(err) {
+	case NetworkErrorDNS, NetworkErrorRefused, NetworkErrorTLS:
+		return true
+	}
+	return false
+}
+
+// sendRequest sends a single HTTP request without retrying.
+// A positive timeout bounds the whole attempt, including reading the response body.
+func (c *HTTPClient) sendRequest(ctx context.Context, method string, url string, body *bytes.Buffer, headers map[string]string, timeout time.Duration) (*http.Response, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		bodyReader = body
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	attemptCtx, cancel := context.WithCancel(ctx)
+	if timeout > 0 {
+		attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	req, err := http.NewRequestWithContext(attemptCtx, method, url, bodyReader)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if (req.URL.Scheme != "http" && req.URL.Scheme != "https") || req.URL.Host == "" {
+		cancel()
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, req.URL.Redacted())
+	}
 
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
 
-	resp, err := c.client.Do(req)
+	resp, err := c.roundTrip(req)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
@@ -215,27 +516,78 @@ func (c *HTTPClient) sendRequest(ctx context.Context, method string, url string,
 			}
 		}
 
-		// Sanitize the error body
-		errorBody = sanitizeErrorBody(errorBody)
+		// The body has been read, so the attempt is over
+		cancel()
+
+		// Scrub credentials from the error body, which ends up in logs and error messages
+		errorBody = redact.Body(errorBody)
 
 		return resp, &HTTPError{
 			StatusCode: resp.StatusCode,
 			Message:    fmt.Sprintf("%s: %s", resp.Status, errorBody),
+			Header:     resp.Header,
+			RequestID:  RequestIDFromContext(ctx),
 			Err:        err,
 		}
 	}
 
+	// Keep the attempt's context alive until the caller has read the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
 	return resp, nil
 }
 
-// DecodeJSONResponse decodes a JSON response into the provided target
+// cancelOnClose cancels the request context when the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// DecodeJSONResponse decodes a JSON response into the provided target. It fails with
+// ErrUnexpectedContentType if the response says it is not JSON, and with ErrResponseTooLarge
+// instead of reading more than HTTP_MAX_RESPONSE_BYTES
 func (c *HTTPClient) DecodeJSONResponse(resp *http.Response, target interface{}) error {
-	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+	if contentType := resp.Header.Get("Content-Type"); !isJSONContentType(contentType) {
+		return fmt.Errorf("%w: %s", ErrUnexpectedContentType, contentType)
+	}
+
+	body := resp.Body
+	if c.maxBody > 0 {
+		if resp.ContentLength > c.maxBody {
+			return fmt.Errorf("%w: %d bytes, the limit is %d", ErrResponseTooLarge, resp.ContentLength, c.maxBody)
+		}
+		body = http.MaxBytesReader(nil, resp.Body, c.maxBody)
+	}
+
+	if err := json.NewDecoder(body).Decode(target); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("%w: the limit is %d bytes", ErrResponseTooLarge, c.maxBody)
+		}
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
 	return nil
 }
 
+// isJSONContentType reports whether a response with the Content-Type can be decoded as JSON.
+// A missing type and text/plain, which servers that don't set the type get from content
+// sniffing, are accepted
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "text/plain"
+}
+
 // EncodeJSONRequest encodes a request body as JSON and returns a buffer from the pool
 func (c *HTTPClient) EncodeJSONRequest(body interface{}) (*bytes.Buffer, []byte, error) {
 	buf := c.bufferPool.Get().(*bytes.Buffer)
@@ -252,56 +604,3 @@ func (c *HTTPClient) EncodeJSONRequest(body interface{}) (*bytes.Buffer, []byte,
 
 	return buf, bodyBytes, nil
 }
-
-// sanitizeError removes sensitive information from error messages
-func sanitizeError(err error) error {
-	if err == nil {
-		return nil
-	}
-
-	errMsg := err.Error()
-	// Mask sensitive patterns
-	sensitivePatterns := []string{"Bearer ", "accessJwt", "refreshJwt", "Authorization"}
-
-	for _, pattern := range sensitivePatterns {
-		if strings.Contains(strings.ToLower(errMsg), strings.ToLower(pattern)) {
-			start := strings.Index(strings.ToLower(errMsg), strings.ToLower(pattern))
-			end := start + len(pattern) + 30 // pattern + some extra chars for the token
-			if end > len(errMsg) {
-				end = len(errMsg)
-			}
-
-			errMsg = errMsg[:start] + "[REDACTED]" + errMsg[end:]
-		}
-	}
-
-	if errMsg != err.Error() {
-		return fmt.Errorf("%s", errMsg)
-	}
-	return err
-}
-
-// sanitizeErrorBody removes sensitive information from error response bodies
-func sanitizeErrorBody(body string) string {
-	if body == "" {
-		return ""
-	}
-
-	// Sanitize JWT tokens and other sensitive information
-	sensitivePatterns := []string{"eyJ", "jwt", "bearer", "auth", "token"}
-
-	result := body
-	for _, pattern := range sensitivePatterns {
-		if strings.Contains(strings.ToLower(result), strings.ToLower(pattern)) {
-			start := strings.Index(strings.ToLower(result), strings.ToLower(pattern))
-			end := start + len(pattern) + 30 // pattern + some extra chars
-			if end > len(result) {
-				end = len(result)
-			}
-
-			result = result[:start] + "[REDACTED]" + result[end:]
-		}
-	}
-
-	return result
-}
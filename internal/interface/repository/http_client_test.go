@@ -4,16 +4,31 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
 )
 
+// newTestHTTPClient はテスト用のHTTPClientを作成します
+func newTestHTTPClient(t *testing.T, cfg *config.Config) *HTTPClient {
+	t.Helper()
+	client, err := NewHTTPClient(cfg)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	return client
+}
+
 func TestHTTPClient_NewHTTPClient(t *testing.T) {
 	tests := []struct {
 		name string
@@ -33,19 +48,20 @@ func TestHTTPClient_NewHTTPClient(t *testing.T) {
 				HTTPTimeout:  5 * time.Second,
 				MaxRetries:   5,
 				RetryBackoff: 2 * time.Second,
+				RetryJitter:  "equal",
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client := NewHTTPClient(tt.cfg)
-			if client == nil {
-				t.Errorf("NewHTTPClient() = nil, want non-nil")
+			client, err := NewHTTPClient(tt.cfg)
+			if err != nil || client == nil {
+				t.Errorf("NewHTTPClient() = %v, %v, want non-nil", client, err)
 				return
 			}
-			if client.client.Timeout != tt.cfg.HTTPTimeout {
-				t.Errorf("client.Timeout = %v, want %v", client.client.Timeout, tt.cfg.HTTPTimeout)
+			if client.timeout != tt.cfg.HTTPTimeout {
+				t.Errorf("client.timeout = %v, want %v", client.timeout, tt.cfg.HTTPTimeout)
 			}
 			if client.retryPolicy.MaxRetries != tt.cfg.MaxRetries {
 				t.Errorf("retryPolicy.MaxRetries = %v, want %v", client.retryPolicy.MaxRetries, tt.cfg.MaxRetries)
@@ -53,6 +69,9 @@ func TestHTTPClient_NewHTTPClient(t *testing.T) {
 			if client.retryPolicy.RetryBackoff != tt.cfg.RetryBackoff {
 				t.Errorf("retryPolicy.RetryBackoff = %v, want %v", client.retryPolicy.RetryBackoff, tt.cfg.RetryBackoff)
 			}
+			if client.retryPolicy.Jitter != JitterStrategy(tt.cfg.RetryJitter) {
+				t.Errorf("retryPolicy.Jitter = %v, want %v", client.retryPolicy.Jitter, tt.cfg.RetryJitter)
+			}
 		})
 	}
 }
@@ -198,7 +217,7 @@ func TestHTTPClient_DoRequest(t *testing.T) {
 			}
 
 			// HTTPクライアントの作成
-			client := NewHTTPClient(cfg)
+			client := newTestHTTPClient(t, cfg)
 
 			// リトライポリシーのカスタマイズ
 			if tt.retryPolicy.MaxRetries > 0 {
@@ -227,10 +246,13 @@ func TestHTTPClient_DoRequest(t *testing.T) {
 
 func TestHTTPClient_DecodeJSONResponse(t *testing.T) {
 	tests := []struct {
-		name     string
-		jsonBody string
-		target   interface{}
-		wantErr  bool
+		name        string
+		jsonBody    string
+		contentType string
+		maxBytes    int64
+		target      interface{}
+		wantErr     bool
+		wantErrIs   error
 	}{
 		{
 			name:     "正常系: 有効なJSONを正常にデコード",
@@ -244,22 +266,51 @@ func TestHTTPClient_DecodeJSONResponse(t *testing.T) {
 			target:   &map[string]interface{}{},
 			wantErr:  true,
 		},
+		{
+			name:        "正常系: charset 付きの application/json",
+			jsonBody:    `{"key": "value", "number": 123}`,
+			contentType: "application/json; charset=utf-8",
+			maxBytes:    1024,
+			target:      &map[string]interface{}{},
+		},
+		{
+			name:        "異常系: HTMLのレスポンスはデコードしない",
+			jsonBody:    `<html>Bad Gateway</html>`,
+			contentType: "text/html",
+			target:      &map[string]interface{}{},
+			wantErr:     true,
+			wantErrIs:   ErrUnexpectedContentType,
+		},
+		{
+			name:      "異常系: 上限を超えるレスポンス",
+			jsonBody:  `{"key": "` + strings.Repeat("a", 100) + `"}`,
+			maxBytes:  64,
+			target:    &map[string]interface{}{},
+			wantErr:   true,
+			wantErrIs: ErrResponseTooLarge,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// HTTPレスポンスのモックを作成
 			resp := &http.Response{
-				Body: io.NopCloser(strings.NewReader(tt.jsonBody)),
+				Header:        http.Header{},
+				Body:          io.NopCloser(strings.NewReader(tt.jsonBody)),
+				ContentLength: -1,
+			}
+			if tt.contentType != "" {
+				resp.Header.Set("Content-Type", tt.contentType)
 			}
 
 			// クライアントの作成
 			cfg := &config.Config{
-				HTTPTimeout:  1 * time.Second,
-				MaxRetries:   3,
-				RetryBackoff: 10 * time.Millisecond,
+				HTTPTimeout:          1 * time.Second,
+				MaxRetries:           3,
+				RetryBackoff:         10 * time.Millisecond,
+				HTTPMaxResponseBytes: tt.maxBytes,
 			}
-			client := NewHTTPClient(cfg)
+			client := newTestHTTPClient(t, cfg)
 
 			// JSONのデコード
 			err := client.DecodeJSONResponse(resp, tt.target)
@@ -269,6 +320,9 @@ func TestHTTPClient_DecodeJSONResponse(t *testing.T) {
 				t.Errorf("DecodeJSONResponse() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("DecodeJSONResponse() error = %v, want %v", err, tt.wantErrIs)
+			}
 
 			// 正常系の場合はデコードされた結果を確認
 			if !tt.wantErr {
@@ -331,11 +385,11 @@ func TestHTTPClient_CalculateBackoff(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// クライアントの作成
 			cfg := &config.Config{HTTPTimeout: 1 * time.Second}
-			client := NewHTTPClient(cfg)
+			client := newTestHTTPClient(t, cfg)
 			client.retryPolicy = tt.retryPolicy
 
 			// バックオフの計算
-			got := client.calculateBackoff(tt.attempt)
+			got := calculateBackoff(client.retryPolicy, tt.attempt)
 
 			// 最大値を超える場合は最大値を確認
 			if tt.wantMax {
@@ -353,12 +407,72 @@ func TestHTTPClient_CalculateBackoff(t *testing.T) {
 	}
 }
 
+func TestHTTPClient_CalculateBackoffWithJitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		jitter  JitterStrategy
+		attempt int
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{
+			name:    "正常系: ジッターなし",
+			jitter:  JitterNone,
+			attempt: 2,
+			wantMin: 200 * time.Millisecond,
+			wantMax: 200 * time.Millisecond,
+		},
+		{
+			name:    "正常系: フルジッター",
+			jitter:  JitterFull,
+			attempt: 2,
+			wantMin: 0,
+			wantMax: 200 * time.Millisecond,
+		},
+		{
+			name:    "正常系: イコールジッター",
+			jitter:  JitterEqual,
+			attempt: 2,
+			wantMin: 100 * time.Millisecond,
+			wantMax: 200 * time.Millisecond,
+		},
+		{
+			name:    "正常系: フルジッターでも最大バックオフを超えない",
+			jitter:  JitterFull,
+			attempt: 20,
+			wantMin: 0,
+			wantMax: MaxBackoffDuration,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// クライアントの作成
+			cfg := &config.Config{HTTPTimeout: 1 * time.Second}
+			client := newTestHTTPClient(t, cfg)
+			client.retryPolicy = RetryPolicy{
+				RetryBackoff: 100 * time.Millisecond,
+				Jitter:       tt.jitter,
+			}
+
+			// ランダム性があるため複数回計算して範囲を確認
+			for i := 0; i < 100; i++ {
+				got := calculateBackoff(client.retryPolicy, tt.attempt)
+				if got < tt.wantMin || got > tt.wantMax {
+					t.Fatalf("calculateBackoff() = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+				}
+			}
+		})
+	}
+}
+
 func TestHTTPClient_ShouldRetry(t *testing.T) {
 	tests := []struct {
 		name       string
 		err        error
 		attempt    int
 		maxRetries int
+		safeOnly   bool
 		want       bool
 	}{
 		{
@@ -403,17 +517,46 @@ func TestHTTPClient_ShouldRetry(t *testing.T) {
 			maxRetries: 3,
 			want:       true,
 		},
+		{
+			name:       "異常系: 書き込み済みかもしれないサーバーエラー（SafeOnly）",
+			err:        &HTTPError{StatusCode: 500, Message: "Internal Server Error"},
+			maxRetries: 3,
+			safeOnly:   true,
+			want:       false,
+		},
+		{
+			name:       "異常系: 送信後のタイムアウト（SafeOnly）",
+			err:        fmt.Errorf("failed to send request: %w", context.DeadlineExceeded),
+			maxRetries: 3,
+			safeOnly:   true,
+			want:       false,
+		},
+		{
+			name:       "正常系: レート制限エラー（SafeOnly）",
+			err:        &HTTPError{StatusCode: 429, Message: "Too Many Requests"},
+			maxRetries: 3,
+			safeOnly:   true,
+			want:       true,
+		},
+		{
+			name:       "正常系: 接続できなかった（SafeOnly）",
+			err:        fmt.Errorf("failed to send request: %w", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}),
+			maxRetries: 3,
+			safeOnly:   true,
+			want:       true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// クライアントの作成
 			cfg := &config.Config{HTTPTimeout: 1 * time.Second}
-			client := NewHTTPClient(cfg)
+			client := newTestHTTPClient(t, cfg)
 			client.retryPolicy.MaxRetries = tt.maxRetries
+			client.retryPolicy.SafeOnly = tt.safeOnly
 
 			// 再試行判定
-			got := client.shouldRetry(tt.err, tt.attempt)
+			got := client.shouldRetry(client.retryPolicy, tt.err, tt.attempt)
 
 			// 期待される結果と比較
 			if got != tt.want {
@@ -458,3 +601,102 @@ func TestHTTPError_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPError_Is(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		rateLimited bool
+		authExpired bool
+		suspended   bool
+	}{
+		{name: "正常系: 429 はレート制限", err: &HTTPError{StatusCode: 429}, rateLimited: true},
+		{name: "正常系: 401 は認証切れ", err: &HTTPError{StatusCode: 401}, authExpired: true},
+		{
+			name:        "正常系: 期限切れのリフレッシュトークンは認証切れ",
+			err:         &HTTPError{StatusCode: 400, Message: `400 Bad Request: {"error":"ExpiredToken"}`},
+			authExpired: true,
+		},
+		{
+			name:        "正常系: 包んだエラーも分類できる",
+			err:         fmt.Errorf("failed to post message: %w", fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, &HTTPError{StatusCode: 429})),
+			rateLimited: true,
+		},
+		{
+			name:      "正常系: テイクダウンされたアカウントは認証切れではなく停止",
+			err:       &HTTPError{StatusCode: 401, Message: `401 Unauthorized: {"error":"AccountTakedown","message":"Account has been taken down"}`},
+			suspended: true,
+		},
+		{
+			name:      "正常系: 無効化されたアカウントも停止",
+			err:       &HTTPError{StatusCode: 400, Message: `400 Bad Request: {"error":"AccountDeactivated"}`},
+			suspended: true,
+		},
+		{name: "正常系: その他の 400 は分類しない", err: &HTTPError{StatusCode: 400, Message: "InvalidRequest"}},
+		{name: "正常系: 500 は分類しない", err: &HTTPError{StatusCode: 500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, domain.ErrRateLimited); got != tt.rateLimited {
+				t.Errorf("errors.Is(err, ErrRateLimited) = %v, want %v", got, tt.rateLimited)
+			}
+			if got := errors.Is(tt.err, domain.ErrAuthExpired); got != tt.authExpired {
+				t.Errorf("errors.Is(err, ErrAuthExpired) = %v, want %v", got, tt.authExpired)
+			}
+			if got := errors.Is(tt.err, domain.ErrAccountSuspended); got != tt.suspended {
+				t.Errorf("errors.Is(err, ErrAccountSuspended) = %v, want %v", got, tt.suspended)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_WithTimeout(t *testing.T) {
+	// 200ms後にボディを返すサーバー
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		opts    []RequestOption
+		wantErr bool
+	}{
+		{
+			name:    "異常系: デフォルトのタイムアウトではボディの読み込み中にタイムアウト",
+			opts:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "正常系: リクエストごとにタイムアウトを延長できる",
+			opts:    []RequestOption{WithTimeout(2 * time.Second)},
+			wantErr: false,
+		},
+		{
+			name:    "正常系: 0はタイムアウトなし",
+			opts:    []RequestOption{WithTimeout(0)},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestHTTPClient(t, &config.Config{HTTPTimeout: 50 * time.Millisecond})
+
+			resp, err := client.DoRequest(context.Background(), "GET", server.URL, nil, nil, tt.opts...)
+			if err == nil {
+				// タイムアウトはレスポンスボディの読み込みにも適用される
+				var result map[string]string
+				err = client.DecodeJSONResponse(resp, &result)
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DoRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/metrics"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+)
+
+// RoundTripFunc sends a single HTTP request attempt
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps a RoundTripFunc to add cross-cutting behavior such as logging,
+// metrics, header or auth injection, or rate limiting.
+// Middlewares run once per attempt, so a request that is retried passes through them again.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Chain composes middlewares around a RoundTripFunc. The first middleware is the outermost,
+// i.e. it sees the request first and the response last.
+func Chain(base RoundTripFunc, middlewares ...Middleware) RoundTripFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		base = middlewares[i](base)
+	}
+	return base
+}
+
+// HeaderMiddleware sets fixed headers on every request that doesn't already have them
+func HeaderMiddleware(headers map[string]string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			for key, value := range headers {
+				if req.Header.Get(key) == "" {
+					req.Header.Set(key, value)
+				}
+			}
+			return next(req)
+		}
+	}
+}
+
+// LogSampling limits how many successful requests LoggingMiddleware logs.
+// Failed requests and retried attempts are always logged.
+type LogSampling struct {
+	Rate      float64 // Fraction of successful requests that are logged, 0 to 1
+	PerMinute int     // Most successful requests logged per minute; zero means no limit
+}
+
+// LoggingMiddleware logs the method, path, status, duration, and attempt number of every
+// request attempt. Successful requests are logged at debug level and sampled, so that a bot
+// running for months doesn't fill the logs with routine calls; failures and retries are logged
+// in full. Query strings are not logged, and errors and response headers are
+// passed through the redact package.
+func LoggingMiddleware(sampling LogSampling) Middleware {
+	return loggingMiddleware(logging.Module("http"), sampling)
+}
+
+// MetricsMiddleware sends the duration of every request attempt to sink as http.request, tagged
+// with the XRPC method and the status code, and counts retried attempts as http.retry.
+// Requests outside /xrpc/, such as link card fetches, share one endpoint tag to keep the
+// number of tag values small.
+func MetricsMiddleware(sink metrics.Sink) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			elapsed := time.Since(start)
+
+			endpoint, ok := strings.CutPrefix(req.URL.Path, "/xrpc/")
+			if !ok {
+				endpoint = "other"
+			}
+			status := "error"
+			if err == nil {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+			sink.Timing("http.request", elapsed, "endpoint:"+endpoint, "status:"+status)
+			if AttemptFromContext(req.Context()) > 1 {
+				sink.Count("http.retry", 1, "endpoint:"+endpoint)
+			}
+			return resp, err
+		}
+	}
+}
+
+// loggingMiddleware is LoggingMiddleware writing to logger
+func loggingMiddleware(logger *slog.Logger, sampling LogSampling) Middleware {
+	sampler := newLogSampler(sampling)
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			elapsed := time.Since(start).Round(time.Millisecond)
+
+			attempt := AttemptFromContext(req.Context())
+			attrs := []any{
+				"method", req.Method,
+				"host", req.URL.Host,
+				"path", req.URL.Path,
+				"duration", elapsed,
+				"attempt", attempt,
+				"request_id", RequestIDFromContext(req.Context()),
+			}
+			if err != nil {
+				logger.Warn("HTTP request failed", append(attrs, "error", redact.Error(err))...)
+				return resp, err
+			}
+			attrs = append(attrs, "status", resp.StatusCode)
+			if resp.StatusCode >= 400 {
+				// Response headers help diagnose auth and rate limit failures; only allowlisted ones are logged verbatim
+				logger.Warn("HTTP request", append(attrs, "headers", redact.Headers(resp.Header))...)
+				return resp, nil
+			}
+			if attempt > 1 {
+				// A retry that succeeded belongs to an incident, so it is not sampled
+				logger.Info("HTTP request", attrs...)
+				return resp, nil
+			}
+			if !logger.Enabled(req.Context(), slog.LevelDebug) {
+				return resp, nil
+			}
+			if ok, suppressed := sampler.allow(time.Now()); ok {
+				if suppressed > 0 {
+					attrs = append(attrs, "suppressed", suppressed)
+				}
+				logger.Debug("HTTP request", attrs...)
+			}
+			return resp, nil
+		}
+	}
+}
+
+// logSampler decides which successful requests are logged
+type logSampler struct {
+	sampling LogSampling
+
+	mu          sync.Mutex
+	windowStart time.Time
+	logged      int
+	suppressed  int // Requests dropped since the last one that was logged
+}
+
+func newLogSampler(sampling LogSampling) *logSampler {
+	return &logSampler{sampling: sampling}
+}
+
+// allow reports whether a request finished at now should be logged and, if so,
+// how many requests were dropped since the last one that was logged
+func (s *logSampler) allow(now time.Time) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sampling.Rate < 1 && (s.sampling.Rate <= 0 || rand.Float64() >= s.sampling.Rate) {
+		s.suppressed++
+		return false, 0
+	}
+	if s.sampling.PerMinute > 0 {
+		if now.Sub(s.windowStart) >= time.Minute {
+			s.windowStart = now
+			s.logged = 0
+		}
+		if s.logged >= s.sampling.PerMinute {
+			s.suppressed++
+			return false, 0
+		}
+		s.logged++
+	}
+	suppressed := s.suppressed
+	s.suppressed = 0
+	return true, suppressed
+}
+
+type attemptKey struct{}
+
+// withAttempt returns a context carrying the 1-based attempt number of a request
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns the 1-based attempt number of the request being sent,
+// or 1 for a request that wasn't sent by DoRequest
+func AttemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
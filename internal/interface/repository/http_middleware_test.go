@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestChain_Order(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+":before")
+				resp, err := next(req)
+				order = append(order, name+":after")
+				return resp, err
+			}
+		}
+	}
+	base := func(req *http.Request) (*http.Response, error) {
+		order = append(order, "base")
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	Chain(base, record("outer"), record("inner"))(req)
+
+	want := []string{"outer:before", "inner:before", "base", "inner:after", "outer:after"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("order = %v, want %v", order, want)
+			break
+		}
+	}
+}
+
+func TestHTTPClient_Middlewares(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int32
+		requestHeader string
+		wantHeader    string
+		wantAttempts  int32
+	}{
+		{
+			name:         "正常系: ヘッダーが付与される",
+			failures:     0,
+			wantHeader:   "quotebot-test",
+			wantAttempts: 1,
+		},
+		{
+			name:          "正常系: リクエストのヘッダーは上書きしない",
+			failures:      0,
+			requestHeader: "explicit",
+			wantHeader:    "explicit",
+			wantAttempts:  1,
+		},
+		{
+			name:         "正常系: 再試行のたびにミドルウェアが実行される",
+			failures:     2,
+			wantHeader:   "quotebot-test",
+			wantAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			var gotHeader atomic.Value
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHeader.Store(r.Header.Get("X-Client"))
+				if atomic.AddInt32(&requests, 1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			var attempts, lastAttempt int32
+			countAttempts := func(next RoundTripFunc) RoundTripFunc {
+				return func(req *http.Request) (*http.Response, error) {
+					atomic.AddInt32(&attempts, 1)
+					atomic.StoreInt32(&lastAttempt, int32(AttemptFromContext(req.Context())))
+					return next(req)
+				}
+			}
+
+			cfg := &config.Config{HTTPTimeout: 3 * time.Second, MaxRetries: 3, RetryBackoff: time.Millisecond}
+			client, err := NewHTTPClient(cfg, HeaderMiddleware(map[string]string{"X-Client": "quotebot-test"}))
+			if err != nil {
+				t.Fatalf("NewHTTPClient() error = %v", err)
+			}
+			client.Use(countAttempts)
+
+			var headers map[string]string
+			if tt.requestHeader != "" {
+				headers = map[string]string{"X-Client": tt.requestHeader}
+			}
+			resp, err := client.DoRequest(context.Background(), "GET", server.URL, nil, headers)
+			if err != nil {
+				t.Fatalf("DoRequest() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if got := gotHeader.Load(); got != tt.wantHeader {
+				t.Errorf("X-Client = %v, want %v", got, tt.wantHeader)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("middleware ran %d times, want %d", attempts, tt.wantAttempts)
+			}
+			if lastAttempt != tt.wantAttempts {
+				t.Errorf("last attempt number = %d, want %d", lastAttempt, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+// recordingSink は送ったメトリクスを記録します
+type recordingSink struct {
+	mu      sync.Mutex
+	metrics []string
+}
+
+func (s *recordingSink) record(kind, name string, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = append(s.metrics, kind+" "+name+" "+strings.Join(tags, ","))
+}
+
+func (s *recordingSink) Count(name string, value int64, tags ...string) {
+	s.record("count", name, tags)
+}
+func (s *recordingSink) Gauge(name string, value float64, tags ...string) {
+	s.record("gauge", name, tags)
+}
+func (s *recordingSink) Timing(name string, d time.Duration, tags ...string) {
+	s.record("timing", name, tags)
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink := &recordingSink{}
+	client, err := NewHTTPClient(&config.Config{HTTPTimeout: 3 * time.Second, MaxRetries: 3, RetryBackoff: time.Millisecond}, MetricsMiddleware(sink))
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	for _, path := range []string{"/xrpc/com.atproto.repo.getRecord", "/card"} {
+		resp, err := client.DoRequest(context.Background(), "GET", server.URL+path, nil, nil)
+		if err != nil {
+			t.Fatalf("DoRequest() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	want := []string{
+		"timing http.request endpoint:com.atproto.repo.getRecord,status:503",
+		"timing http.request endpoint:com.atproto.repo.getRecord,status:200",
+		"count http.retry endpoint:com.atproto.repo.getRecord",
+		"timing http.request endpoint:other,status:200",
+	}
+	if !reflect.DeepEqual(sink.metrics, want) {
+		t.Errorf("metrics = %q, want %q", sink.metrics, want)
+	}
+}
+
+func TestLogSampler_Allow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		sampling       LogSampling
+		at             []time.Duration // start からの経過時間ごとに allow を呼ぶ
+		wantLogged     []bool
+		wantSuppressed []int
+	}{
+		{
+			name:           "正常系: 上限がなければすべて記録する",
+			sampling:       LogSampling{Rate: 1},
+			at:             []time.Duration{0, time.Second, 2 * time.Second},
+			wantLogged:     []bool{true, true, true},
+			wantSuppressed: []int{0, 0, 0},
+		},
+		{
+			name:           "正常系: 1分間の上限を超えた分は捨て、次に記録するときに件数を添える",
+			sampling:       LogSampling{Rate: 1, PerMinute: 2},
+			at:             []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, time.Minute},
+			wantLogged:     []bool{true, true, false, false, true},
+			wantSuppressed: []int{0, 0, 0, 0, 2},
+		},
+		{
+			name:           "正常系: 割合が0なら記録しない",
+			sampling:       LogSampling{Rate: 0},
+			at:             []time.Duration{0, time.Second},
+			wantLogged:     []bool{false, false},
+			wantSuppressed: []int{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler := newLogSampler(tt.sampling)
+			for i, d := range tt.at {
+				logged, suppressed := sampler.allow(start.Add(d))
+				if logged != tt.wantLogged[i] || suppressed != tt.wantSuppressed[i] {
+					t.Errorf("allow(#%d) = %v, %d, want %v, %d", i, logged, suppressed, tt.wantLogged[i], tt.wantSuppressed[i])
+				}
+			}
+		})
+	}
+}
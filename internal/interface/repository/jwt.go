@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// jwtClaims holds the subset of JWT claims the bot cares about
+type jwtClaims struct {
+	Exp int64 `json:"exp"`
+	Iat int64 `json:"iat"`
+}
+
+// parseJWTClaims decodes the payload of a JWT without verifying its signature.
+// The PDS is the authority on token validity; we only read the claims to plan refreshes.
+func parseJWTClaims(token string) (jwtClaims, bool) {
+	var claims jwtClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, false
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, false
+	}
+	return claims, true
+}
+
+// tokenExpiry returns the expiry time encoded in the exp claim of a JWT
+func tokenExpiry(token string) (time.Time, bool) {
+	claims, ok := parseJWTClaims(token)
+	if !ok || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// tokenLifetime returns how long a JWT is valid from issue to expiry, from its iat and exp claims
+func tokenLifetime(token string) (time.Duration, bool) {
+	claims, ok := parseJWTClaims(token)
+	if !ok || claims.Exp == 0 || claims.Iat == 0 || claims.Exp <= claims.Iat {
+		return 0, false
+	}
+	return time.Duration(claims.Exp-claims.Iat) * time.Second, true
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// makeTestJWT は指定した有効期限を持つテスト用のJWTを作成します（署名は検証されません）
+func makeTestJWT(exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256K","typ":"at+jwt"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"scope":"com.atproto.access","sub":"did:plc:test","iat":%d,"exp":%d}`, exp.Add(-2*time.Hour).Unix(), exp.Unix())))
+	return header + "." + payload + ".signature"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		token  string
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "正常系: expクレームを含むJWT",
+			token:  makeTestJWT(exp),
+			want:   exp,
+			wantOK: true,
+		},
+		{
+			name:   "異常系: JWT形式ではない",
+			token:  "not-a-jwt",
+			wantOK: false,
+		},
+		{
+			name:   "異常系: ペイロードがJSONではない",
+			token:  "a." + base64.RawURLEncoding.EncodeToString([]byte("garbage")) + ".c",
+			wantOK: false,
+		},
+		{
+			name:   "異常系: expクレームがない",
+			token:  "a." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x"}`)) + ".c",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tokenExpiry(tt.token)
+			if ok != tt.wantOK {
+				t.Errorf("tokenExpiry() ok = %v, want %v", ok, tt.wantOK)
+				return
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("tokenExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenManager_NextRefreshDelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken string
+		wantMin     time.Duration
+		wantMax     time.Duration
+	}{
+		{
+			name:        "正常系: 有効期限の直前にリフレッシュ",
+			accessToken: makeTestJWT(time.Now().Add(2 * time.Hour)),
+			wantMin:     time.Hour + 50*time.Minute,
+			wantMax:     time.Hour + 55*time.Minute,
+		},
+		{
+			name:        "正常系: 期限切れのトークンは最小間隔で再試行",
+			accessToken: makeTestJWT(time.Now().Add(-time.Hour)),
+			wantMin:     MinTokenRefreshDelay,
+			wantMax:     MinTokenRefreshDelay,
+		},
+		{
+			name:        "正常系: expが読めない場合は固定間隔",
+			accessToken: "opaque-token",
+			wantMin:     45 * time.Minute,
+			wantMax:     45 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// リフレッシュが失敗するようにPDSURLを無効にする
+			cfg := &config.Config{
+				AccessJWT:            tt.accessToken,
+				RefreshJWT:           "refresh-token",
+				PDSURL:               "http://invalid-url",
+				TokenRefreshInterval: 45 * time.Minute,
+				TokenRefreshMargin:   5 * time.Minute,
+				HTTPTimeout:          1 * time.Second,
+			}
+			tm := NewTokenManager(cfg, NewTokenEncryptor(), newTestHTTPClient(t, cfg))
+			defer tm.Shutdown()
+
+			got := tm.nextRefreshDelay()
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("nextRefreshDelay() = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestTokenManager_EnsureFreshToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken string
+		wantRefresh bool
+	}{
+		{
+			name:        "正常系: 有効期限まで余裕がある場合はリフレッシュしない",
+			accessToken: makeTestJWT(time.Now().Add(time.Hour)),
+			wantRefresh: false,
+		},
+		{
+			name:        "正常系: 有効期限が近い場合はリフレッシュする",
+			accessToken: makeTestJWT(time.Now().Add(time.Minute)),
+			wantRefresh: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refreshCount int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				refreshCount++
+				// 初期化時のリフレッシュでは同じトークンを返し、有効期限を維持する
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintf(w, `{"accessJwt": %q, "refreshJwt": "new-refresh-token"}`, tt.accessToken)
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				AccessJWT:            tt.accessToken,
+				RefreshJWT:           "refresh-token",
+				PDSURL:               server.URL,
+				TokenRefreshInterval: 45 * time.Minute,
+				TokenRefreshMargin:   5 * time.Minute,
+				HTTPTimeout:          1 * time.Second,
+			}
+			tm := NewTokenManager(cfg, NewTokenEncryptor(), newTestHTTPClient(t, cfg))
+			defer tm.Shutdown()
+
+			before := refreshCount
+			if err := tm.EnsureFreshToken(context.Background()); err != nil {
+				t.Fatalf("EnsureFreshToken() error = %v", err)
+			}
+
+			if got := refreshCount > before; got != tt.wantRefresh {
+				t.Errorf("EnsureFreshToken() refreshed = %v, want %v", got, tt.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestTokenManager_CheckRefreshInterval(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken string
+		interval    time.Duration
+		wantErr     bool
+	}{
+		{
+			name:        "正常系: 有効期間より短い間隔",
+			accessToken: makeTestJWT(time.Now().Add(time.Hour)),
+			interval:    45 * time.Minute,
+		},
+		{
+			name:        "正常系: 有効期間が読めない場合は確かめない",
+			accessToken: "opaque-token",
+			interval:    3 * time.Hour,
+		},
+		{
+			name:        "異常系: 有効期間（2時間）より長い間隔",
+			accessToken: makeTestJWT(time.Now().Add(time.Hour)),
+			interval:    3 * time.Hour,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// リフレッシュが失敗するようにPDSURLを無効にする
+			cfg := &config.Config{
+				AccessJWT:            tt.accessToken,
+				RefreshJWT:           "refresh-token",
+				PDSURL:               "http://invalid-url",
+				TokenRefreshInterval: tt.interval,
+				TokenRefreshMargin:   5 * time.Minute,
+				HTTPTimeout:          1 * time.Second,
+			}
+			tm := NewTokenManager(cfg, NewTokenEncryptor(), newTestHTTPClient(t, cfg))
+			defer tm.Shutdown()
+
+			err := tm.CheckRefreshInterval()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckRefreshInterval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "1h55m0s or less") {
+				t.Errorf("CheckRefreshInterval() error = %v, want the safe interval", err)
+			}
+		})
+	}
+}
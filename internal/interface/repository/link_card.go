@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// Link card related constants
+const (
+	// DefaultLinkCardCacheTTL is how long a fetched card is reused before the page is fetched again
+	DefaultLinkCardCacheTTL = 24 * time.Hour
+	// DefaultLinkCardMaxBytes is how much of a page is read looking for its Open Graph tags
+	DefaultLinkCardMaxBytes = 512 * 1024
+	// DefaultLinkCardCacheSize is how many cards are kept in memory
+	DefaultLinkCardCacheSize = 1000
+)
+
+// ErrNotHTML is returned when a link does not point to an HTML page
+var ErrNotHTML = errors.New("link is not an HTML page")
+
+// LinkCard is the Open Graph metadata of a linked page, used for external embeds
+type LinkCard struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Image       string    `json:"image,omitempty"` // Absolute URL of og:image, if any
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// LinkCardOptions configures a LinkCardFetcher. Zero values use the defaults
+type LinkCardOptions struct {
+	// CacheDir keeps fetched cards across restarts. Empty caches in memory only
+	CacheDir string
+	TTL      time.Duration
+	MaxBytes int64
+	// CacheSize is how many cards are kept in memory. The least recently used card is dropped first
+	CacheSize int
+}
+
+// LinkCardFetcher fetches the Open Graph metadata of links and caches it,
+// so that posting the same link again doesn't request the page again
+type LinkCardFetcher struct {
+	httpClient *HTTPClient
+	opts       LinkCardOptions
+	now        func() time.Time
+	logger     *slog.Logger
+
+	mu    sync.Mutex
+	cards map[string]*list.Element // Values are LinkCard
+	lru   *list.List               // Most recently used card first
+}
+
+// NewLinkCardFetcher creates a LinkCardFetcher that requests pages through httpClient,
+// with its retry policy and budget
+func NewLinkCardFetcher(httpClient *HTTPClient, opts LinkCardOptions) (*LinkCardFetcher, error) {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultLinkCardCacheTTL
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultLinkCardMaxBytes
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = DefaultLinkCardCacheSize
+	}
+	if opts.CacheDir != "" {
+		if err := os.MkdirAll(opts.CacheDir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create link card cache directory: %w", err)
+		}
+	}
+	return &LinkCardFetcher{
+		httpClient: httpClient,
+		opts:       opts,
+		now:        time.Now,
+		logger:     httpClient.logger,
+		cards:      map[string]*list.Element{},
+		lru:        list.New(),
+	}, nil
+}
+
+// NewLinkCardFetcherFromConfig creates a LinkCardFetcher with the LINK_CARD_* settings
+func NewLinkCardFetcherFromConfig(cfg *config.Config, httpClient *HTTPClient) (*LinkCardFetcher, error) {
+	return NewLinkCardFetcher(httpClient, LinkCardOptions{
+		CacheDir:  cfg.LinkCardCacheDir,
+		TTL:       cfg.LinkCardCacheTTL,
+		MaxBytes:  cfg.LinkCardMaxBytes,
+		CacheSize: cfg.LinkCardCacheSize,
+	})
+}
+
+// Fetch returns the card of the page at link, from the cache if it was fetched within the TTL
+func (f *LinkCardFetcher) Fetch(ctx context.Context, link string) (*LinkCard, error) {
+	pageURL, err := url.Parse(link)
+	if err != nil || (pageURL.Scheme != "http" && pageURL.Scheme != "https") || pageURL.Host == "" {
+		return nil, fmt.Errorf("invalid link %q", link)
+	}
+	link = pageURL.String()
+
+	if card, ok := f.cached(link); ok {
+		return card, nil
+	}
+
+	card, err := f.fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	f.store(*card)
+	return card, nil
+}
+
+// fetch requests the page and reads the Open Graph tags from its head
+func (f *LinkCardFetcher) fetch(ctx context.Context, pageURL *url.URL) (*LinkCard, error) {
+	resp, err := f.httpClient.DoRequest(ctx, http.MethodGet, pageURL.String(), nil, map[string]string{
+		"Accept": "text/html,application/xhtml+xml",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch link: %w", err)
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("%w: %s", ErrNotHTML, mediaType)
+	}
+
+	// The tags are in the head, so a truncated page is still usable
+	card := parseLinkCard(io.LimitReader(resp.Body, f.opts.MaxBytes), pageURL)
+	if card.Title == "" {
+		return nil, fmt.Errorf("link has no title: %s", pageURL)
+	}
+	card.FetchedAt = f.now()
+	return card, nil
+}
+
+// parseLinkCard reads og:title, og:description and og:image from the head of an HTML page,
+// falling back to <title> and the description meta tag
+func parseLinkCard(r io.Reader, pageURL *url.URL) *LinkCard {
+	card := &LinkCard{URL: pageURL.String()}
+	var title, description string
+	tokenizer := html.NewTokenizer(r)
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+		if token.Data == "body" || (tokenType == html.EndTagToken && token.Data == "head") {
+			break
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		switch token.Data {
+		case "title":
+			if tokenizer.Next() == html.TextToken {
+				title = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case "meta":
+			attrs := map[string]string{}
+			for _, attr := range token.Attr {
+				attrs[attr.Key] = attr.Val
+			}
+			content := strings.TrimSpace(attrs["content"])
+			key := attrs["property"]
+			if key == "" {
+				key = attrs["name"]
+			}
+			switch strings.ToLower(key) {
+			case "og:title":
+				card.Title = content
+			case "og:description":
+				card.Description = content
+			case "description":
+				description = content
+			case "og:image":
+				if image, err := pageURL.Parse(content); err == nil && content != "" {
+					card.Image = image.String()
+				}
+			}
+		}
+	}
+	if card.Title == "" {
+		card.Title = title
+	}
+	if card.Description == "" {
+		card.Description = description
+	}
+	return card
+}
+
+// cached returns the card of link if it was fetched within the TTL,
+// loading it from the cache directory if it isn't in memory
+func (f *LinkCardFetcher) cached(link string) (*LinkCard, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var card LinkCard
+	elem, ok := f.cards[link]
+	if ok {
+		card = elem.Value.(LinkCard)
+		f.lru.MoveToFront(elem)
+	} else if f.opts.CacheDir != "" {
+		data, err := os.ReadFile(f.cachePath(link))
+		ok = err == nil && json.Unmarshal(data, &card) == nil && card.URL == link
+		if ok {
+			f.remember(card)
+		}
+	}
+	if !ok || f.now().Sub(card.FetchedAt) >= f.opts.TTL {
+		return nil, false
+	}
+	return &card, true
+}
+
+// store caches the card in memory and, if configured, in the cache directory
+func (f *LinkCardFetcher) store(card LinkCard) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.remember(card)
+	if f.opts.CacheDir == "" {
+		return
+	}
+	data, err := json.Marshal(card)
+	if err == nil {
+		err = os.WriteFile(f.cachePath(card.URL), data, 0o600)
+	}
+	if err != nil {
+		f.logger.Warn("Failed to write link card cache", "url", card.URL, "error", err)
+	}
+}
+
+// remember keeps the card in memory as the most recently used, dropping the least recently used
+// card past CacheSize. The caller must hold f.mu
+func (f *LinkCardFetcher) remember(card LinkCard) {
+	if elem, ok := f.cards[card.URL]; ok {
+		elem.Value = card
+		f.lru.MoveToFront(elem)
+		return
+	}
+	f.cards[card.URL] = f.lru.PushFront(card)
+	for f.lru.Len() > f.opts.CacheSize {
+		oldest := f.lru.Back()
+		f.lru.Remove(oldest)
+		delete(f.cards, oldest.Value.(LinkCard).URL)
+	}
+}
+
+// cachePath is the cache file of link, named by its hash so that any URL is a valid file name
+func (f *LinkCardFetcher) cachePath(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return filepath.Join(f.opts.CacheDir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestParseLinkCard(t *testing.T) {
+	pageURL, err := url.Parse("https://example.com/articles/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		page string
+		want LinkCard
+	}{
+		{
+			name: "正常系: Open Graph のタグを読む",
+			page: `<html><head>
+				<meta property="og:title" content=" 名言の記事 ">
+				<meta property="og:description" content="説明">
+				<meta property="og:image" content="/images/card.png">
+				<title>ページのタイトル</title>
+			</head><body>本文</body></html>`,
+			want: LinkCard{
+				URL:         "https://example.com/articles/1",
+				Title:       "名言の記事",
+				Description: "説明",
+				Image:       "https://example.com/images/card.png",
+			},
+		},
+		{
+			name: "正常系: Open Graph のタグがなければ title と description を使う",
+			page: `<html><head><title>ページのタイトル</title><meta name="description" content="ページの説明"></head></html>`,
+			want: LinkCard{
+				URL:         "https://example.com/articles/1",
+				Title:       "ページのタイトル",
+				Description: "ページの説明",
+			},
+		},
+		{
+			name: "正常系: body のタグは読まない",
+			page: `<html><head><title>タイトル</title></head><body><meta property="og:title" content="本文"></body></html>`,
+			want: LinkCard{URL: "https://example.com/articles/1", Title: "タイトル"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseLinkCard(strings.NewReader(tt.page), pageURL)
+			if *got != tt.want {
+				t.Errorf("parseLinkCard() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkCardFetcher_Fetch(t *testing.T) {
+	page := `<html><head><meta property="og:title" content="名言の記事"></head></html>`
+	tests := []struct {
+		name        string
+		contentType string
+		page        string
+		link        string
+		wantErr     error
+		wantAnyErr  bool
+	}{
+		{name: "正常系: ページのカードを返す", contentType: "text/html; charset=utf-8", page: page},
+		{name: "異常系: HTML でないリンク", contentType: "image/png", page: page, wantErr: ErrNotHTML},
+		{name: "異常系: タイトルのないページ", contentType: "text/html", page: "<html></html>", wantAnyErr: true},
+		{name: "異常系: http でないリンク", link: "ftp://example.com/", wantAnyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.page))
+			}))
+			defer server.Close()
+
+			fetcher, err := NewLinkCardFetcher(newTestHTTPClient(t, &config.Config{HTTPTimeout: time.Second}), LinkCardOptions{})
+			if err != nil {
+				t.Fatalf("NewLinkCardFetcher() error = %v", err)
+			}
+			link := tt.link
+			if link == "" {
+				link = server.URL + "/articles/1"
+			}
+			card, err := fetcher.Fetch(context.Background(), link)
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("Fetch() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if card.Title != "名言の記事" || card.URL != link {
+				t.Errorf("Fetch() = %+v", card)
+			}
+		})
+	}
+}
+
+func TestLinkCardFetcher_Cache(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<title>名言の記事</title>`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newFetcher := func() *LinkCardFetcher {
+		fetcher, err := NewLinkCardFetcher(newTestHTTPClient(t, &config.Config{HTTPTimeout: time.Second}),
+			LinkCardOptions{CacheDir: dir, TTL: time.Hour})
+		if err != nil {
+			t.Fatalf("NewLinkCardFetcher() error = %v", err)
+		}
+		fetcher.now = func() time.Time { return now }
+		return fetcher
+	}
+	link := server.URL + "/articles/1"
+	fetch := func(fetcher *LinkCardFetcher) {
+		t.Helper()
+		if _, err := fetcher.Fetch(context.Background(), link); err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+	}
+
+	fetcher := newFetcher()
+	fetch(fetcher)
+	fetch(fetcher)
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d after fetching twice, want 1", got)
+	}
+
+	// 再起動してもキャッシュディレクトリのカードを使う
+	fetch(newFetcher())
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d after restarting, want 1", got)
+	}
+
+	// TTL が過ぎたら取得し直す
+	now = now.Add(time.Hour)
+	fetch(fetcher)
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d after the TTL, want 2", got)
+	}
+}
+
+func TestLinkCardFetcher_MaxBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Repeat("<!-- padding -->", 100) + `<title>名言の記事</title>`))
+	}))
+	defer server.Close()
+
+	fetcher, err := NewLinkCardFetcher(newTestHTTPClient(t, &config.Config{HTTPTimeout: time.Second}), LinkCardOptions{MaxBytes: 256})
+	if err != nil {
+		t.Fatalf("NewLinkCardFetcher() error = %v", err)
+	}
+	// 上限より後にあるタグは読まない
+	if _, err := fetcher.Fetch(context.Background(), server.URL); err == nil {
+		t.Error("Fetch() error = nil, want an error for a title past MaxBytes")
+	}
+}
+
+func TestLinkCardFetcher_CacheSize(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<title>名言の記事</title>`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HTTPTimeout: time.Second, LinkCardCacheSize: 2}
+	fetcher, err := NewLinkCardFetcherFromConfig(cfg, newTestHTTPClient(t, cfg))
+	if err != nil {
+		t.Fatalf("NewLinkCardFetcherFromConfig() error = %v", err)
+	}
+	fetch := func(path string) {
+		t.Helper()
+		if _, err := fetcher.Fetch(context.Background(), server.URL+path); err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+	}
+
+	fetch("/1")
+	fetch("/2")
+	fetch("/1") // /2 が最も長く使われていないカードになる
+	fetch("/3")
+	if got := len(fetcher.cards); got != 2 {
+		t.Errorf("cached cards = %d, want 2", got)
+	}
+
+	// 最近使ったカードは残り、最も長く使っていないカードは捨てられる
+	requests.Store(0)
+	fetch("/1")
+	fetch("/3")
+	if got := requests.Load(); got != 0 {
+		t.Errorf("requests = %d for recently used cards, want 0", got)
+	}
+	fetch("/2")
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d for the evicted card, want 1", got)
+	}
+}
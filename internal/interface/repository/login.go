@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// Login creates a session with a handle and app password and saves the tokens to the
+// configured token store (TOKEN_FILE or keyring/Vault), so the bot can start from them
+func Login(ctx context.Context, cfg *config.Config, identifier, password string) (*SessionOutput, error) {
+	store, err := NewTokenStore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token store: %w", err)
+	}
+	if store == nil {
+		return nil, fmt.Errorf("a token store is required: set TOKEN_FILE or CREDENTIALS_BACKEND")
+	}
+
+	httpClient, err := NewHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	session, err := newConfiguredXRPCClient(cfg, httpClient).CreateSession(ctx, CreateSessionInput{
+		Identifier: identifier,
+		Password:   password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	if cfg.DID != "" && session.DID != cfg.DID {
+		return nil, fmt.Errorf("session DID %s does not match configured DID %s", session.DID, cfg.DID)
+	}
+
+	err = store.Save(StoredTokens{
+		AccessJWT:  session.AccessJWT,
+		RefreshJWT: session.RefreshJWT,
+		SavedAt:    time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save tokens: %w", err)
+	}
+	return session, nil
+}
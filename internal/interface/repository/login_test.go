@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/com.atproto.server.createSession" {
+			t.Errorf("予期しないパス: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var input CreateSessionInput
+		json.NewDecoder(r.Body).Decode(&input)
+		if input.Identifier != "bot.example.com" || input.Password != "app-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"AuthenticationRequired","message":"Invalid identifier or password"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"accessJwt":  "login-access-token",
+			"refreshJwt": "login-refresh-token",
+			"handle":     "bot.example.com",
+			"did":        "did:plc:test",
+		})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		did       string
+		password  string
+		tokenFile bool
+		wantErr   bool
+	}{
+		{
+			name:      "正常系: トークンファイルに保存される",
+			did:       "did:plc:test",
+			password:  "app-password",
+			tokenFile: true,
+			wantErr:   false,
+		},
+		{
+			name:      "異常系: パスワードが間違っている",
+			did:       "did:plc:test",
+			password:  "wrong-password",
+			tokenFile: true,
+			wantErr:   true,
+		},
+		{
+			name:      "異常系: 設定されたDIDと一致しない",
+			did:       "did:plc:other",
+			password:  "app-password",
+			tokenFile: true,
+			wantErr:   true,
+		},
+		{
+			name:      "異常系: トークンの保存先がない",
+			did:       "did:plc:test",
+			password:  "app-password",
+			tokenFile: false,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				DID:         tt.did,
+				PDSURL:      server.URL,
+				HTTPTimeout: 3 * time.Second,
+			}
+			path := filepath.Join(t.TempDir(), "tokens.json")
+			if tt.tokenFile {
+				cfg.TokenFile = path
+				cfg.TokenFilePassphrase = "passphrase"
+			}
+
+			session, err := Login(context.Background(), cfg, "bot.example.com", tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if session.Handle != "bot.example.com" {
+				t.Errorf("Login() handle = %v, want %v", session.Handle, "bot.example.com")
+			}
+
+			// 保存されたトークンを読み込める
+			stored, err := NewFileTokenStore(path, "passphrase").Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if stored == nil || stored.AccessJWT != "login-access-token" || stored.RefreshJWT != "login-refresh-token" {
+				t.Errorf("Load() = %+v", stored)
+			}
+		})
+	}
+}
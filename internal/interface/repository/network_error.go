@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrInvalidURL is returned for a request URL that is not an absolute http or https URL
+var ErrInvalidURL = errors.New("invalid request URL")
+
+// NetworkErrorClass classifies a request that failed without getting an HTTP response
+type NetworkErrorClass string
+
+const (
+	// NetworkErrorDNS means the host name could not be resolved
+	NetworkErrorDNS NetworkErrorClass = "dns"
+	// NetworkErrorRefused means nothing accepted the connection, e.g. while the PDS restarts
+	NetworkErrorRefused NetworkErrorClass = "connection_refused"
+	// NetworkErrorTimeout means the connection or the response took longer than the timeout
+	NetworkErrorTimeout NetworkErrorClass = "timeout"
+	// NetworkErrorTLS means the TLS handshake failed for a reason other than the certificate
+	NetworkErrorTLS NetworkErrorClass = "tls"
+	// NetworkErrorCertificate means the server's certificate was rejected
+	NetworkErrorCertificate NetworkErrorClass = "certificate"
+	// NetworkErrorInvalidURL means the request could not be sent to its URL at all
+	NetworkErrorInvalidURL NetworkErrorClass = "invalid_url"
+	// NetworkErrorOther is any other failure, such as a connection reset
+	NetworkErrorOther NetworkErrorClass = "other"
+)
+
+// ClassifyNetworkError returns the class of a request error that is not an HTTPError
+func ClassifyNetworkError(err error) NetworkErrorClass {
+	var (
+		urlErr         *url.Error
+		verifyErr      *tls.CertificateVerificationError
+		unknownAuthErr x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+		certErr        x509.CertificateInvalidError
+		dnsErr         *net.DNSError
+		netErr         net.Error
+		recordErr      tls.RecordHeaderError
+		alertErr       tls.AlertError
+	)
+	switch {
+	case errors.Is(err, ErrInvalidURL), errors.As(err, &urlErr) && urlErr.Op == "parse":
+		return NetworkErrorInvalidURL
+	case errors.As(err, &verifyErr), errors.As(err, &unknownAuthErr), errors.As(err, &hostnameErr), errors.As(err, &certErr):
+		return NetworkErrorCertificate
+	case errors.As(err, &dnsErr):
+		return NetworkErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return NetworkErrorRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return NetworkErrorTimeout
+	case errors.As(err, &recordErr), errors.As(err, &alertErr):
+		return NetworkErrorTLS
+	default:
+		return NetworkErrorOther
+	}
+}
+
+// Retryable reports whether a request that failed this way may succeed when it is retried.
+// A malformed URL or a rejected certificate fails the same way every time
+func (c NetworkErrorClass) Retryable() bool {
+	return c != NetworkErrorInvalidURL && c != NetworkErrorCertificate
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestClassifyNetworkError(t *testing.T) {
+	_, parseErr := url.Parse("http://[::1")
+	tests := []struct {
+		name      string
+		err       error
+		want      NetworkErrorClass
+		retryable bool
+	}{
+		{
+			name:      "正常系: 名前解決の失敗",
+			err:       &url.Error{Op: "Get", URL: "https://pds.invalid", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "pds.invalid", IsNotFound: true}}},
+			want:      NetworkErrorDNS,
+			retryable: true,
+		},
+		{
+			name:      "正常系: 接続の拒否",
+			err:       fmt.Errorf("failed to send request: %w", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}),
+			want:      NetworkErrorRefused,
+			retryable: true,
+		},
+		{
+			name:      "正常系: タイムアウト",
+			err:       fmt.Errorf("failed to send request: %w", context.DeadlineExceeded),
+			want:      NetworkErrorTimeout,
+			retryable: true,
+		},
+		{
+			name:      "正常系: TLSのハンドシェイクの失敗",
+			err:       &url.Error{Op: "Get", URL: "https://pds.example.com", Err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}},
+			want:      NetworkErrorTLS,
+			retryable: true,
+		},
+		{
+			name: "異常系: 証明書の検証の失敗は再試行しない",
+			err:  &url.Error{Op: "Get", URL: "https://pds.example.com", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}},
+			want: NetworkErrorCertificate,
+		},
+		{
+			name: "異常系: 解析できないURLは再試行しない",
+			err:  fmt.Errorf("failed to create request: %w", parseErr),
+			want: NetworkErrorInvalidURL,
+		},
+		{
+			name: "異常系: http でないURLは再試行しない",
+			err:  fmt.Errorf("%w: ftp://pds.example.com", ErrInvalidURL),
+			want: NetworkErrorInvalidURL,
+		},
+		{
+			name:      "正常系: その他のエラー",
+			err:       errors.New("connection reset by peer"),
+			want:      NetworkErrorOther,
+			retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyNetworkError(tt.err)
+			if got != tt.want {
+				t.Errorf("ClassifyNetworkError() = %q, want %q", got, tt.want)
+			}
+			if got.Retryable() != tt.retryable {
+				t.Errorf("Retryable() = %v, want %v", got.Retryable(), tt.retryable)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_DoRequestNetworkErrors(t *testing.T) {
+	// 信頼していない証明書のサーバー
+	untrusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer untrusted.Close()
+
+	// 閉じたポート
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		wantClass  NetworkErrorClass
+		wantErrors int64 // 再試行を含めて失敗した回数
+	}{
+		{name: "異常系: 証明書の失敗は1回で諦める", url: untrusted.URL, wantClass: NetworkErrorCertificate, wantErrors: 1},
+		{name: "異常系: http でないURLは1回で諦める", url: "ftp://127.0.0.1/", wantClass: NetworkErrorInvalidURL, wantErrors: 1},
+		{name: "異常系: 接続の拒否は再試行する", url: closed, wantClass: NetworkErrorRefused, wantErrors: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestHTTPClient(t, &config.Config{HTTPTimeout: time.Second, MaxRetries: 2, RetryBackoff: time.Millisecond})
+			if _, err := client.DoRequest(context.Background(), http.MethodGet, tt.url, nil, nil); err == nil {
+				t.Fatal("DoRequest() error = nil, want an error")
+			}
+			metrics := client.Metrics()
+			if got := metrics.NetworkErrors[tt.wantClass]; got != tt.wantErrors {
+				t.Errorf("NetworkErrors = %v, want %d %s", metrics.NetworkErrors, tt.wantErrors, tt.wantClass)
+			}
+		})
+	}
+}
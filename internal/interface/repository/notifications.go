@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// mentionReasons are the notification reasons of posts that address the account
+var mentionReasons = []string{"mention", "reply"}
+
+// Notification paging limits for Mentions
+const (
+	notificationPageSize = 50
+	maxNotificationPages = 40
+)
+
+// Mentions returns the posts that mentioned or replied to the account after since, oldest first.
+// At most maxNotificationPages pages are read, so only an outage with more mentions than that skips the oldest ones
+func (r *BlueskyRepository) Mentions(ctx context.Context, since time.Time) ([]domain.Mention, error) {
+	var mentions []domain.Mention
+	cursor := ""
+	reached := false
+	for page := 0; page < maxNotificationPages && !reached; page++ {
+		output, err := r.listNotifications(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		done := output.Cursor == "" || len(output.Notifications) == 0
+		for _, n := range output.Notifications {
+			if !n.IndexedAt.After(since) {
+				done = true
+				break
+			}
+			if mention, ok := r.readMention(n); ok {
+				mentions = append(mentions, mention)
+			}
+		}
+		reached = done
+		cursor = output.Cursor
+	}
+	if !reached {
+		r.logger.Warn("Skipping mentions older than the notification page limit", "since", since, "pages", maxNotificationPages)
+	}
+
+	// Notifications are listed newest first
+	slices.Reverse(mentions)
+	return mentions, nil
+}
+
+// MentionPage returns one page of the posts that mentioned or replied to the account, newest first.
+// An empty cursor reads the newest page; next is the cursor of the following, older page, or empty after the last one.
+// Cursors keep pointing at the same position as new mentions arrive, so they can be saved and resumed later
+func (r *BlueskyRepository) MentionPage(ctx context.Context, cursor string) (mentions []domain.Mention, next string, err error) {
+	output, err := r.listNotifications(ctx, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, n := range output.Notifications {
+		if mention, ok := r.readMention(n); ok {
+			mentions = append(mentions, mention)
+		}
+	}
+	if len(output.Notifications) == 0 {
+		return mentions, "", nil
+	}
+	return mentions, output.Cursor, nil
+}
+
+// readMention reads a notification, logging the ones whose post can't be read
+func (r *BlueskyRepository) readMention(n Notification) (domain.Mention, bool) {
+	mention, err := mentionFromNotification(n)
+	if err != nil {
+		r.logger.Warn("Ignoring notification with an unreadable post", "uri", n.URI, "error", err)
+		return domain.Mention{}, false
+	}
+	return mention, true
+}
+
+func (r *BlueskyRepository) listNotifications(ctx context.Context, cursor string) (output *ListNotificationsOutput, err error) {
+	err = r.client.Do(ctx, http.MethodGet, NSIDListNotifications, func(headers map[string]string) error {
+		output, err = r.xrpc.ListNotifications(ctx, mentionReasons, notificationPageSize, cursor, headers)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return output, nil
+}
+
+// mentionFromNotification reads the post record of a mention or reply notification
+func mentionFromNotification(n Notification) (domain.Mention, error) {
+	var record struct {
+		Text  string    `json:"text"`
+		Reply *ReplyRef `json:"reply"`
+	}
+	if err := json.Unmarshal(n.Record, &record); err != nil {
+		return domain.Mention{}, err
+	}
+	post := domain.PostRef{Platform: domain.PlatformBluesky, URI: n.URI, CID: n.CID}
+	root := post
+	if record.Reply != nil && record.Reply.Root.URI != "" {
+		root = domain.PostRef{Platform: domain.PlatformBluesky, URI: record.Reply.Root.URI, CID: record.Reply.Root.CID}
+	}
+	// mutes and blocks through moderation lists count the same as direct ones
+	viewer := n.Author.Viewer
+	return domain.Mention{
+		Post:          post,
+		Root:          root,
+		AuthorDID:     n.Author.DID,
+		AuthorHandle:  n.Author.Handle,
+		AuthorMuted:   viewer.Muted || len(viewer.MutedByList) > 0,
+		AuthorBlocked: viewer.Blocking != "" || len(viewer.BlockingByList) > 0 || viewer.BlockedBy,
+		Text:          record.Text,
+		IndexedAt:     n.IndexedAt,
+	}, nil
+}
+
+// Reply posts message as a reply to the mention, in the mention's thread
+func (r *BlueskyRepository) Reply(ctx context.Context, message string, to domain.Mention) (*domain.PostRef, error) {
+	return r.PublishReply(ctx, message, to.Root, to.Post)
+}
+
+// PublishReply posts message as a reply to parent. root is the first post of parent's thread
+func (r *BlueskyRepository) PublishReply(ctx context.Context, message string, root, parent domain.PostRef) (*domain.PostRef, error) {
+	input, err := r.BuildRecord(message, r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	record := input.Record.(FeedPost)
+	record.Reply = &ReplyRef{
+		Root:   StrongRef{URI: root.URI, CID: root.CID},
+		Parent: StrongRef{URI: parent.URI, CID: parent.CID},
+	}
+	input.Record = record
+	setLangs(input, usecase.LangsFromContext(ctx))
+
+	output, err := r.createRecord(ctx, *input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post reply: %w", err)
+	}
+	return blueskyPostRef(output.URI, output.CID), nil
+}
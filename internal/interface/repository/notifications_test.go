@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+func TestBlueskyRepository_Mentions(t *testing.T) {
+	since := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	notification := func(n int, at time.Time, record string) Notification {
+		var out Notification
+		out.URI = fmt.Sprintf("at://did:plc:user/app.bsky.feed.post/%d", n)
+		out.CID = fmt.Sprintf("bafy%d", n)
+		out.Author.DID = "did:plc:user"
+		out.Author.Handle = "user.bsky.social"
+		out.Reason = "mention"
+		out.Record = json.RawMessage(record)
+		out.IndexedAt = at
+		return out
+	}
+	muted := func(n Notification) Notification {
+		n.Author.Viewer.MutedByList = json.RawMessage(`{"uri":"at://did:plc:bot/app.bsky.graph.list/spam"}`)
+		return n
+	}
+	pages := map[string]ListNotificationsOutput{
+		"": {Cursor: "page2", Notifications: []Notification{
+			notification(3, since.Add(3*time.Minute), `{"text":"@bot #quote 勇気","reply":{"root":{"uri":"at://did:plc:other/app.bsky.feed.post/root","cid":"bafyroot"},"parent":{"uri":"x","cid":"y"}}}`),
+			notification(2, since.Add(2*time.Minute), `{"text":5}`),
+		}},
+		"page2": {Cursor: "page3", Notifications: []Notification{
+			muted(notification(1, since.Add(time.Minute), `{"text":"@bot #quote 愛"}`)),
+			notification(0, since, `{"text":"古いメンション"}`),
+		}},
+	}
+	var requested []string
+	repo := newProfileTestRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/"+NSIDListNotifications {
+			return
+		}
+		cursor := r.URL.Query().Get("cursor")
+		requested = append(requested, cursor)
+		json.NewEncoder(w).Encode(pages[cursor])
+	})
+
+	mentions, err := repo.Mentions(context.Background(), since)
+	if err != nil {
+		t.Fatalf("Mentions() error = %v", err)
+	}
+	// 古いメンションに届いたらそれ以上読まない
+	if len(requested) != 2 {
+		t.Errorf("requested cursors = %q, want 2 pages", requested)
+	}
+	if len(mentions) != 2 || mentions[0].Text != "@bot #quote 愛" || mentions[1].Text != "@bot #quote 勇気" {
+		t.Fatalf("Mentions() = %+v, want the two readable mentions oldest first", mentions)
+	}
+	if !mentions[0].AuthorMuted || mentions[0].AuthorBlocked || mentions[1].AuthorMuted {
+		t.Errorf("AuthorMuted = %v, %v, want only the author muted by a list", mentions[0].AuthorMuted, mentions[1].AuthorMuted)
+	}
+	if mentions[0].Root != mentions[0].Post {
+		t.Errorf("Root = %+v, want the post itself outside a thread", mentions[0].Root)
+	}
+	if mentions[1].Root.URI != "at://did:plc:other/app.bsky.feed.post/root" || mentions[1].AuthorHandle != "user.bsky.social" {
+		t.Errorf("Mentions()[1] = %+v, want the thread root and author", mentions[1])
+	}
+}
+
+func TestBlueskyRepository_MentionPage(t *testing.T) {
+	pages := map[string]ListNotificationsOutput{
+		"": {Cursor: "page2", Notifications: []Notification{
+			{URI: "at://did:plc:user/app.bsky.feed.post/2", Record: json.RawMessage(`{"text":"新しいメンション"}`)},
+			{URI: "at://did:plc:user/app.bsky.feed.post/1", Record: json.RawMessage(`{"text":5}`)},
+		}},
+		"page2": {Cursor: "page3"},
+	}
+	repo := newProfileTestRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/xrpc/"+NSIDListNotifications {
+			json.NewEncoder(w).Encode(pages[r.URL.Query().Get("cursor")])
+		}
+	})
+
+	mentions, next, err := repo.MentionPage(context.Background(), "")
+	if err != nil {
+		t.Fatalf("MentionPage() error = %v", err)
+	}
+	if len(mentions) != 1 || mentions[0].Text != "新しいメンション" || next != "page2" {
+		t.Errorf("MentionPage() = %+v, %q, want the readable mention and the next cursor", mentions, next)
+	}
+	// 通知のないページで終わる
+	if mentions, next, err := repo.MentionPage(context.Background(), "page2"); len(mentions) != 0 || next != "" || err != nil {
+		t.Errorf("MentionPage(page2) = %+v, %q, %v, want the last page", mentions, next, err)
+	}
+}
+
+func TestBlueskyRepository_Reply(t *testing.T) {
+	var created struct {
+		Record FeedPost `json:"record"`
+	}
+	repo := newProfileTestRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/xrpc/"+NSIDCreateRecord {
+			json.NewDecoder(r.Body).Decode(&created)
+			json.NewEncoder(w).Encode(CreateRecordOutput{URI: "at://did:plc:test/app.bsky.feed.post/reply", CID: "bafyreply"})
+		}
+	})
+
+	mention := domain.Mention{
+		Post: domain.PostRef{URI: "at://did:plc:user/app.bsky.feed.post/1", CID: "bafy1"},
+		Root: domain.PostRef{URI: "at://did:plc:user/app.bsky.feed.post/root", CID: "bafyroot"},
+	}
+	ref, err := repo.Reply(context.Background(), "知は力なり\n- ベーコン", mention)
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if ref.URI != "at://did:plc:test/app.bsky.feed.post/reply" {
+		t.Errorf("Reply() = %+v", ref)
+	}
+	want := ReplyRef{Root: StrongRef{URI: mention.Root.URI, CID: mention.Root.CID}, Parent: StrongRef{URI: mention.Post.URI, CID: mention.Post.CID}}
+	if created.Record.Reply == nil || *created.Record.Reply != want || created.Record.Text != "知は力なり\n- ベーコン" {
+		t.Errorf("record = %+v, want a reply in the mention's thread", created.Record)
+	}
+}
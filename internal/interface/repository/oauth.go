@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// OAuthSession holds everything besides the tokens themselves that is needed
+// to use and refresh a DPoP-bound atproto OAuth session
+type OAuthSession struct {
+	Issuer        string    `json:"issuer"`
+	TokenEndpoint string    `json:"tokenEndpoint"`
+	ClientID      string    `json:"clientId"`
+	DID           string    `json:"sub"`
+	Scope         string    `json:"scope"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+	DPoPKey       string    `json:"dpopKey"` // base64 PKCS#8, see DPoPKey.Marshal
+}
+
+// OAuthServerMetadata is the subset of the authorization server metadata (RFC 8414) used by the bot
+type OAuthServerMetadata struct {
+	Issuer                             string `json:"issuer"`
+	AuthorizationEndpoint              string `json:"authorization_endpoint"`
+	TokenEndpoint                      string `json:"token_endpoint"`
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint"`
+}
+
+// OAuthAuthorization is an authorization request waiting for the user to approve it in the browser
+type OAuthAuthorization struct {
+	AuthorizationURL string
+	State            string
+	codeVerifier     string
+	metadata         *OAuthServerMetadata
+	dpopKey          *DPoPKey
+}
+
+// oauthTokenResponse is the token endpoint response
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+	ExpiresIn    int    `json:"expires_in"`
+	Sub          string `json:"sub"`
+}
+
+// OAuthClient implements the atproto OAuth flow: PAR with PKCE, DPoP-bound tokens, and refresh
+type OAuthClient struct {
+	httpClient  *HTTPClient
+	clientID    string
+	redirectURI string
+	scope       string
+	nonces      sync.Map // origin -> latest DPoP-Nonce
+}
+
+// NewOAuthClient creates a new OAuthClient instance.
+// Without OAUTH_CLIENT_ID, the localhost development client of the atproto OAuth profile is used,
+// which needs no hosted client metadata document.
+func NewOAuthClient(cfg *config.Config, httpClient *HTTPClient) *OAuthClient {
+	clientID := cfg.OAuthClientID
+	if clientID == "" {
+		query := url.Values{}
+		query.Set("redirect_uri", cfg.OAuthRedirectURI)
+		query.Set("scope", cfg.OAuthScope)
+		clientID = "http://localhost?" + query.Encode()
+	}
+
+	return &OAuthClient{
+		httpClient:  httpClient,
+		clientID:    clientID,
+		redirectURI: cfg.OAuthRedirectURI,
+		scope:       cfg.OAuthScope,
+	}
+}
+
+// DiscoverAuthServer finds the authorization server of a PDS via its protected resource metadata
+func (c *OAuthClient) DiscoverAuthServer(ctx context.Context, pdsURL string) (*OAuthServerMetadata, error) {
+	var resource struct {
+		AuthorizationServers []string `json:"authorization_servers"`
+	}
+	if err := c.getJSON(ctx, strings.TrimRight(pdsURL, "/")+"/.well-known/oauth-protected-resource", &resource); err != nil {
+		return nil, fmt.Errorf("failed to get protected resource metadata: %w", err)
+	}
+	if len(resource.AuthorizationServers) == 0 {
+		return nil, fmt.Errorf("PDS %s does not advertise an authorization server", pdsURL)
+	}
+
+	issuer := resource.AuthorizationServers[0]
+	var metadata OAuthServerMetadata
+	if err := c.getJSON(ctx, strings.TrimRight(issuer, "/")+"/.well-known/oauth-authorization-server", &metadata); err != nil {
+		return nil, fmt.Errorf("failed to get authorization server metadata: %w", err)
+	}
+	if metadata.Issuer != issuer {
+		return nil, fmt.Errorf("authorization server issuer %s does not match %s", metadata.Issuer, issuer)
+	}
+	if metadata.TokenEndpoint == "" || metadata.AuthorizationEndpoint == "" || metadata.PushedAuthorizationRequestEndpoint == "" {
+		return nil, fmt.Errorf("authorization server metadata is missing required endpoints")
+	}
+	return &metadata, nil
+}
+
+// StartAuthorization pushes an authorization request (PAR) for the account and returns
+// the URL the user has to open in a browser. loginHint is the handle or DID, and may be empty.
+func (c *OAuthClient) StartAuthorization(ctx context.Context, pdsURL, loginHint string) (*OAuthAuthorization, error) {
+	metadata, err := c.DiscoverAuthServer(ctx, pdsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	dpopKey, err := NewDPoPKey()
+	if err != nil {
+		return nil, err
+	}
+
+	codeVerifier := randomURLSafe(32)
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	state := randomURLSafe(16)
+
+	form := url.Values{}
+	form.Set("client_id", c.clientID)
+	form.Set("response_type", "code")
+	form.Set("redirect_uri", c.redirectURI)
+	form.Set("scope", c.scope)
+	form.Set("state", state)
+	form.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	form.Set("code_challenge_method", "S256")
+	if loginHint != "" {
+		form.Set("login_hint", loginHint)
+	}
+
+	var parResp struct {
+		RequestURI string `json:"request_uri"`
+	}
+	if err := c.postForm(ctx, metadata.PushedAuthorizationRequestEndpoint, dpopKey, form, &parResp); err != nil {
+		return nil, fmt.Errorf("failed to push authorization request: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("client_id", c.clientID)
+	query.Set("request_uri", parResp.RequestURI)
+
+	return &OAuthAuthorization{
+		AuthorizationURL: metadata.AuthorizationEndpoint + "?" + query.Encode(),
+		State:            state,
+		codeVerifier:     codeVerifier,
+		metadata:         metadata,
+		dpopKey:          dpopKey,
+	}, nil
+}
+
+// ExchangeCode completes an authorization with the code from the redirect and returns the
+// new session along with its access and refresh tokens.
+// expectedDID, if set, must match the account that approved the request.
+func (c *OAuthClient) ExchangeCode(ctx context.Context, auth *OAuthAuthorization, code, issuer, expectedDID string) (*OAuthSession, string, string, error) {
+	if issuer != "" && issuer != auth.metadata.Issuer {
+		return nil, "", "", fmt.Errorf("authorization response issuer %s does not match %s", issuer, auth.metadata.Issuer)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", c.clientID)
+	form.Set("redirect_uri", c.redirectURI)
+	form.Set("code", code)
+	form.Set("code_verifier", auth.codeVerifier)
+
+	var tokenResp oauthTokenResponse
+	if err := c.postForm(ctx, auth.metadata.TokenEndpoint, auth.dpopKey, form, &tokenResp); err != nil {
+		return nil, "", "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	if expectedDID != "" && tokenResp.Sub != expectedDID {
+		return nil, "", "", fmt.Errorf("authorized account %s does not match configured DID %s", tokenResp.Sub, expectedDID)
+	}
+
+	encodedKey, err := auth.dpopKey.Marshal()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	session := &OAuthSession{
+		Issuer:        auth.metadata.Issuer,
+		TokenEndpoint: auth.metadata.TokenEndpoint,
+		ClientID:      c.clientID,
+		DID:           tokenResp.Sub,
+		DPoPKey:       encodedKey,
+	}
+	if err := applyTokenResponse(session, &tokenResp); err != nil {
+		return nil, "", "", err
+	}
+	return session, tokenResp.AccessToken, tokenResp.RefreshToken, nil
+}
+
+// Refresh exchanges the refresh token for new tokens. The session's expiry and scope are updated in place.
+func (c *OAuthClient) Refresh(ctx context.Context, session *OAuthSession, dpopKey *DPoPKey, refreshToken string) (string, string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("client_id", session.ClientID)
+	form.Set("refresh_token", refreshToken)
+
+	var tokenResp oauthTokenResponse
+	if err := c.postForm(ctx, session.TokenEndpoint, dpopKey, form, &tokenResp); err != nil {
+		return "", "", fmt.Errorf("failed to refresh OAuth token: %w", err)
+	}
+	if tokenResp.Sub != session.DID {
+		return "", "", fmt.Errorf("refreshed token belongs to %s, expected %s", tokenResp.Sub, session.DID)
+	}
+	if err := applyTokenResponse(session, &tokenResp); err != nil {
+		return "", "", err
+	}
+	return tokenResp.AccessToken, tokenResp.RefreshToken, nil
+}
+
+// ResourceHeaders returns the Authorization and DPoP headers for a request to the PDS
+func (c *OAuthClient) ResourceHeaders(dpopKey *DPoPKey, method, requestURL, accessToken string) (map[string]string, error) {
+	proof, err := dpopKey.Proof(method, requestURL, c.nonce(requestURL), accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"Authorization": "DPoP " + accessToken,
+		"DPoP":          proof,
+	}, nil
+}
+
+// UpdateNonce records the DPoP-Nonce returned with a failed request.
+// It reports whether the request should be retried with the new nonce.
+func (c *OAuthClient) UpdateNonce(requestURL string, err error) bool {
+	httpErr, ok := err.(*HTTPError)
+	if !ok || httpErr.Header == nil {
+		return false
+	}
+	nonce := httpErr.Header.Get("DPoP-Nonce")
+	if nonce == "" || nonce == c.nonce(requestURL) {
+		return false
+	}
+	c.nonces.Store(originOf(requestURL), nonce)
+	return true
+}
+
+// postForm sends a DPoP-signed form POST to an authorization server endpoint and decodes the JSON response.
+// Requests rejected with a new DPoP-Nonce are retried once with that nonce, as the servers demand.
+// Proofs are single-use, so this does not go through DoRequest's retry loop.
+func (c *OAuthClient) postForm(ctx context.Context, endpoint string, dpopKey *DPoPKey, form url.Values, target interface{}) error {
+	for attempt := 0; ; attempt++ {
+		proof, err := dpopKey.Proof(http.MethodPost, endpoint, c.nonce(endpoint), "")
+		if err != nil {
+			return err
+		}
+		headers := map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+			"DPoP":         proof,
+		}
+
+		resp, err := c.httpClient.sendRequest(ctx, http.MethodPost, endpoint, bytes.NewBufferString(form.Encode()), headers, c.httpClient.timeout)
+		if err != nil {
+			if attempt == 0 && c.UpdateNonce(endpoint, err) {
+				continue
+			}
+			return err
+		}
+		defer resp.Body.Close()
+
+		if nonce := resp.Header.Get("DPoP-Nonce"); nonce != "" {
+			c.nonces.Store(originOf(endpoint), nonce)
+		}
+		return c.httpClient.DecodeJSONResponse(resp, target)
+	}
+}
+
+// getJSON fetches a metadata document
+func (c *OAuthClient) getJSON(ctx context.Context, url string, target interface{}) error {
+	resp, err := c.httpClient.DoRequest(ctx, http.MethodGet, url, nil, map[string]string{"Accept": "application/json"})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return c.httpClient.DecodeJSONResponse(resp, target)
+}
+
+// nonce returns the latest DPoP-Nonce for the server of a URL
+func (c *OAuthClient) nonce(requestURL string) string {
+	if nonce, ok := c.nonces.Load(originOf(requestURL)); ok {
+		return nonce.(string)
+	}
+	return ""
+}
+
+// applyTokenResponse validates a token response and updates the session with it
+func applyTokenResponse(session *OAuthSession, tokenResp *oauthTokenResponse) error {
+	if !strings.EqualFold(tokenResp.TokenType, "DPoP") {
+		return fmt.Errorf("unexpected token type %q, expected DPoP", tokenResp.TokenType)
+	}
+	if tokenResp.AccessToken == "" || tokenResp.RefreshToken == "" {
+		return fmt.Errorf("token response is missing the access or refresh token")
+	}
+	if !strings.Contains(" "+tokenResp.Scope+" ", " atproto ") {
+		return fmt.Errorf("token response scope %q does not include atproto", tokenResp.Scope)
+	}
+
+	session.Scope = tokenResp.Scope
+	session.ExpiresAt = time.Time{}
+	if tokenResp.ExpiresIn > 0 {
+		session.ExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return nil
+}
+
+// originOf returns the scheme and host of a URL, which DPoP nonces are scoped to
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// randomURLSafe returns n random bytes encoded as unpadded base64url
+func randomURLSafe(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
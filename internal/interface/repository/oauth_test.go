@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// fakeOAuthServer はPDSと認可サーバーを兼ねるテスト用サーバーです
+type fakeOAuthServer struct {
+	*httptest.Server
+	did string
+
+	mu            sync.Mutex
+	codeChallenge string
+	refreshCount  int
+}
+
+// dpopClaims はDPoPプルーフのクレームを署名検証なしで取り出します
+func dpopClaims(proof string) map[string]interface{} {
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]interface{}
+	json.Unmarshal(raw, &claims)
+	return claims
+}
+
+func newFakeOAuthServer(t *testing.T, did string) *fakeOAuthServer {
+	f := &fakeOAuthServer{did: did}
+	mux := http.NewServeMux()
+
+	// 認可サーバーはDPoPのnonceを要求する
+	requireNonce := func(w http.ResponseWriter, r *http.Request, nonce string, status int) bool {
+		claims := dpopClaims(r.Header.Get("DPoP"))
+		if claims == nil {
+			http.Error(w, `{"error":"invalid_dpop_proof"}`, http.StatusBadRequest)
+			return false
+		}
+		if claims["nonce"] != nonce {
+			w.Header().Set("DPoP-Nonce", nonce)
+			w.Header().Set("WWW-Authenticate", `DPoP error="use_dpop_nonce"`)
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"use_dpop_nonce"}`))
+			return false
+		}
+		return true
+	}
+	writeTokens := func(w http.ResponseWriter, access, refresh string) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  access,
+			"refresh_token": refresh,
+			"token_type":    "DPoP",
+			"scope":         "atproto transition:generic",
+			"expires_in":    3600,
+			"sub":           f.did,
+		})
+	}
+
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"authorization_servers": []string{f.URL}})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                                f.URL,
+			"authorization_endpoint":                f.URL + "/oauth/authorize",
+			"token_endpoint":                        f.URL + "/oauth/token",
+			"pushed_authorization_request_endpoint": f.URL + "/oauth/par",
+		})
+	})
+	mux.HandleFunc("/oauth/par", func(w http.ResponseWriter, r *http.Request) {
+		if !requireNonce(w, r, "as-nonce", http.StatusBadRequest) {
+			return
+		}
+		r.ParseForm()
+		f.mu.Lock()
+		f.codeChallenge = r.PostForm.Get("code_challenge")
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"request_uri": "urn:ietf:params:oauth:request_uri:test"})
+	})
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if !requireNonce(w, r, "as-nonce", http.StatusBadRequest) {
+			return
+		}
+		r.ParseForm()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if r.PostForm.Get("code") != "auth-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != f.codeChallenge {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			writeTokens(w, "oauth-access-token", "oauth-refresh-token")
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "oauth-refresh-token" && r.PostForm.Get("refresh_token") != "oauth-refreshed-refresh-token" {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			f.refreshCount++
+			writeTokens(w, "oauth-refreshed-access-token", "oauth-refreshed-refresh-token")
+		default:
+			http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+		}
+	})
+	mux.HandleFunc("/xrpc/com.atproto.server.getSession", func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "DPoP ")
+		claims := dpopClaims(r.Header.Get("DPoP"))
+		hash := sha256.Sum256([]byte(token))
+		if claims == nil || claims["ath"] != base64.RawURLEncoding.EncodeToString(hash[:]) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !requireNonce(w, r, "pds-nonce", http.StatusUnauthorized) {
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"handle": "bot.example.com", "did": f.did})
+	})
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func newOAuthTestConfig(serverURL string) *config.Config {
+	return &config.Config{
+		DID:                  "did:plc:test",
+		PDSURL:               serverURL,
+		AuthMode:             config.AuthModeOAuth,
+		OAuthRedirectURI:     "http://127.0.0.1:8085/callback",
+		OAuthScope:           "atproto transition:generic",
+		TokenRefreshInterval: 1 * time.Hour,
+		TokenRefreshMargin:   5 * time.Minute,
+		HTTPTimeout:          3 * time.Second,
+	}
+}
+
+func TestOAuthClient_AuthorizationFlow(t *testing.T) {
+	tests := []struct {
+		name        string
+		serverDID   string
+		expectedDID string
+		wantErr     bool
+	}{
+		{
+			name:        "正常系: 認可コードをトークンに交換できる",
+			serverDID:   "did:plc:test",
+			expectedDID: "did:plc:test",
+			wantErr:     false,
+		},
+		{
+			name:        "異常系: 認可したアカウントのDIDが一致しない",
+			serverDID:   "did:plc:someone-else",
+			expectedDID: "did:plc:test",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOAuthServer(t, tt.serverDID)
+			cfg := newOAuthTestConfig(server.URL)
+			client := NewOAuthClient(cfg, newTestHTTPClient(t, cfg))
+
+			auth, err := client.StartAuthorization(context.Background(), server.URL, "bot.example.com")
+			if err != nil {
+				t.Fatalf("StartAuthorization() error = %v", err)
+			}
+
+			authURL, err := url.Parse(auth.AuthorizationURL)
+			if err != nil {
+				t.Fatalf("invalid authorization URL: %v", err)
+			}
+			if authURL.Query().Get("request_uri") != "urn:ietf:params:oauth:request_uri:test" {
+				t.Errorf("authorization URL = %s, want request_uri from PAR", auth.AuthorizationURL)
+			}
+			if !strings.HasPrefix(authURL.Query().Get("client_id"), "http://localhost?") {
+				t.Errorf("client_id = %s, want localhost development client", authURL.Query().Get("client_id"))
+			}
+
+			session, accessToken, refreshToken, err := client.ExchangeCode(context.Background(), auth, "auth-code", server.URL, tt.expectedDID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExchangeCode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if accessToken != "oauth-access-token" || refreshToken != "oauth-refresh-token" {
+				t.Errorf("ExchangeCode() tokens = %s/%s, want oauth-access-token/oauth-refresh-token", accessToken, refreshToken)
+			}
+			if session.DID != tt.expectedDID || session.TokenEndpoint != server.URL+"/oauth/token" {
+				t.Errorf("ExchangeCode() session = %+v", session)
+			}
+			if time.Until(session.ExpiresAt) <= 0 {
+				t.Errorf("ExchangeCode() ExpiresAt = %v, want in the future", session.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestTokenManager_OAuthSession(t *testing.T) {
+	server := newFakeOAuthServer(t, "did:plc:test")
+	cfg := newOAuthTestConfig(server.URL)
+	cfg.TokenFile = filepath.Join(t.TempDir(), "tokens.json")
+	cfg.TokenFilePassphrase = "passphrase"
+
+	// oauth-login相当の処理でセッションを保存
+	client := NewOAuthClient(cfg, newTestHTTPClient(t, cfg))
+	auth, err := client.StartAuthorization(context.Background(), server.URL, "")
+	if err != nil {
+		t.Fatalf("StartAuthorization() error = %v", err)
+	}
+	session, accessToken, refreshToken, err := client.ExchangeCode(context.Background(), auth, "auth-code", "", cfg.DID)
+	if err != nil {
+		t.Fatalf("ExchangeCode() error = %v", err)
+	}
+	store := NewFileTokenStore(cfg.TokenFile, cfg.TokenFilePassphrase)
+	store.iterations = 1000
+	err = store.Save(StoredTokens{AccessJWT: accessToken, RefreshJWT: refreshToken, OAuth: session, SavedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// 起動時にOAuthのリフレッシュが行われ、新しいトークンが保存される
+	repo, err := NewBlueskyRepository(cfg)
+	if err != nil {
+		t.Fatalf("NewBlueskyRepository() error = %v", err)
+	}
+	defer repo.Shutdown()
+
+	got, err := repo.tokenManager.GetToken(AccessToken)
+	if err != nil || got != "oauth-refreshed-access-token" {
+		t.Errorf("GetToken() = %v, %v, want oauth-refreshed-access-token", got, err)
+	}
+	if _, ok := repo.tokenManager.AccessTokenExpiry(); !ok {
+		t.Errorf("AccessTokenExpiry() ok = false, want expiry from expires_in")
+	}
+
+	stored, err := store.Load()
+	if err != nil || stored.OAuth == nil || stored.AccessJWT != "oauth-refreshed-access-token" {
+		t.Errorf("stored tokens after refresh = %+v, %v", stored, err)
+	}
+
+	// PDSへのリクエストはDPoPで認証され、nonceの要求には再送で応じる
+	info, err := repo.ValidateSession(context.Background())
+	if err != nil {
+		t.Fatalf("ValidateSession() error = %v", err)
+	}
+	if info.DID != "did:plc:test" {
+		t.Errorf("ValidateSession() DID = %v, want did:plc:test", info.DID)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.refreshCount != 1 {
+		t.Errorf("refresh count = %d, want 1 (a nonce challenge must not trigger a refresh)", server.refreshCount)
+	}
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// LoadOccasions は特別な日の投稿の予定（OCCASIONS_FILE）を読み込みます。
+// 書き間違いに気付けるよう、未知のフィールドはエラーにします
+func LoadOccasions(path string) ([]domain.Occasion, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("特別な日の予定のファイルのオープンに失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	var occasions []domain.Occasion
+	if err := decoder.Decode(&occasions); err != nil {
+		return nil, fmt.Errorf("特別な日の予定のデコードに失敗しました: %w", err)
+	}
+	return occasions, nil
+}
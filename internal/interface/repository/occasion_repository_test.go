@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOccasions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "正常系: 名言のIDと本文の予定",
+			path: write("valid.json", `[
+				{"date": "03-14", "quote": "einstein"},
+				{"date": "2025-05-01", "text": "10周年", "author": "運営", "replace": true}
+			]`),
+			want: 2,
+		},
+		{
+			name:    "異常系: 未知のフィールド",
+			path:    write("unknown.json", `[{"date": "03-14", "quoteId": "einstein"}]`),
+			wantErr: true,
+		},
+		{
+			name:    "異常系: ファイルがない",
+			path:    filepath.Join(dir, "missing.json"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadOccasions(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadOccasions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("LoadOccasions() = %+v, want %d occasions", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/pkg/publisher"
+)
+
+// pluginStopTimeout is how long Shutdown waits for the plugin to exit after closing its stdin
+const pluginStopTimeout = 5 * time.Second
+
+// errPluginExited is returned when the plugin exits while a request is in flight
+var errPluginExited = errors.New("publisher plugin exited")
+
+// PluginRepository posts through a publisher plugin (PUBLISHER_PLUGIN), an external process
+// speaking the line-delimited JSON protocol of pkg/publisher.
+// The process is started by NewPluginRepository and restarted on the next post if it exits
+// or a post times out
+type PluginRepository struct {
+	command string
+	args    []string
+	timeout time.Duration // for the handshake
+	logger  *slog.Logger
+
+	mu           sync.Mutex
+	proc         *pluginProcess
+	nextID       uint64
+	capabilities domain.Capabilities
+}
+
+// pluginProcess is one run of the plugin
+type pluginProcess struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	responses chan publisher.Response // closed when stdout is closed
+	exited    chan struct{}           // closed when the process has been waited for
+}
+
+// NewPluginRepository starts the publisher plugin (PUBLISHER_PLUGIN) and performs the handshake
+func NewPluginRepository(cfg *config.Config) (*PluginRepository, error) {
+	return NewPluginRepositoryWithCommand(cfg, cfg.PublisherPlugin, cfg.PublisherPluginArgs)
+}
+
+// NewPluginRepositoryWithCommand starts the given publisher plugin, such as one of CROSS_POST_PLUGINS, and performs the handshake
+func NewPluginRepositoryWithCommand(cfg *config.Config, command string, args []string) (*PluginRepository, error) {
+	r := &PluginRepository{
+		command: command,
+		args:    args,
+		timeout: cfg.HTTPTimeout,
+		logger:  logging.ModuleFor(cfg, "plugin"),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.start(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Publish posts the message through the plugin and returns a reference to the created post
+func (r *PluginRepository) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.proc != nil {
+		select {
+		case <-r.proc.exited:
+			// The plugin exited since the last post
+			r.proc = nil
+		default:
+		}
+	}
+	if r.proc == nil {
+		if err := r.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := r.call(ctx, publisher.Request{Method: publisher.MethodPublish, Text: message, RequestID: RequestIDFromContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("publisher plugin failed to post: %s", resp.Error)
+	}
+	if resp.URI == "" {
+		return nil, errors.New("publisher plugin returned no URI")
+	}
+	return &domain.PostRef{Platform: r.capabilities.Platform, URI: resp.URI, CID: resp.CID, ID: resp.PostID, URL: resp.URL}, nil
+}
+
+// Capabilities returns the platform limits the plugin reported in its handshake
+func (r *PluginRepository) Capabilities() domain.Capabilities {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.capabilities
+}
+
+// Shutdown closes the plugin's stdin and waits for it to exit, killing it if it does not
+func (r *PluginRepository) Shutdown() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.proc == nil {
+		return
+	}
+	r.proc.stdin.Close()
+	r.proc.drain(r.logger)
+	select {
+	case <-r.proc.exited:
+	case <-time.After(pluginStopTimeout):
+		r.proc.cmd.Process.Kill()
+		<-r.proc.exited
+	}
+	r.proc = nil
+}
+
+// start starts the plugin and performs the handshake. The caller must hold r.mu
+func (r *PluginRepository) start() error {
+	cmd := exec.Command(r.command, r.args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start publisher plugin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start publisher plugin: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start publisher plugin: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start publisher plugin %s: %w", r.command, err)
+	}
+
+	proc := &pluginProcess{cmd: cmd, stdin: stdin, responses: make(chan publisher.Response), exited: make(chan struct{})}
+	var output sync.WaitGroup
+	output.Add(2)
+	safego.Go(r.logger, "plugin-stdout", func() {
+		defer output.Done()
+		defer close(proc.responses)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var resp publisher.Response
+			if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+				r.logger.Warn("Ignoring invalid output from the publisher plugin", "error", err)
+				continue
+			}
+			proc.responses <- resp
+		}
+	})
+	safego.Go(r.logger, "plugin-stderr", func() {
+		defer output.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			r.logger.Info("Publisher plugin output", "line", scanner.Text())
+		}
+	})
+	safego.Go(r.logger, "plugin-wait", func() {
+		defer close(proc.exited)
+		// Wait closes the pipes, so read them to the end first
+		output.Wait()
+		if err := cmd.Wait(); err != nil {
+			r.logger.Warn("Publisher plugin exited", "error", err)
+		}
+	})
+	r.proc = proc
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	resp, err := r.call(ctx, publisher.Request{Method: publisher.MethodHandshake, ProtocolVersion: publisher.ProtocolVersion})
+	if err == nil && resp.Error != "" {
+		err = errors.New(resp.Error)
+	}
+	if err == nil {
+		r.capabilities, err = capabilitiesFromHandshake(resp)
+	}
+	if err != nil {
+		r.stop()
+		return fmt.Errorf("publisher plugin handshake failed: %w", err)
+	}
+	r.logger.Info("Started the publisher plugin", "command", r.command, "platform", r.capabilities.Platform, "pid", cmd.Process.Pid)
+	return nil
+}
+
+// call sends a request and waits for its response. If ctx is done first, or the plugin exits,
+// the plugin is stopped so that a late response is never mistaken for the next request's.
+// The caller must hold r.mu
+func (r *PluginRepository) call(ctx context.Context, req publisher.Request) (publisher.Response, error) {
+	r.nextID++
+	req.ID = r.nextID
+	line, err := json.Marshal(req)
+	if err != nil {
+		return publisher.Response{}, err
+	}
+	if _, err := r.proc.stdin.Write(append(line, '\n')); err != nil {
+		r.stop()
+		return publisher.Response{}, fmt.Errorf("failed to write to the publisher plugin: %w", err)
+	}
+
+	for {
+		select {
+		case resp, ok := <-r.proc.responses:
+			if !ok {
+				r.stop()
+				return publisher.Response{}, errPluginExited
+			}
+			if resp.ID != req.ID {
+				r.logger.Warn("Ignoring a response to another request from the publisher plugin", "id", resp.ID, "want", req.ID)
+				continue
+			}
+			return resp, nil
+		case <-ctx.Done():
+			r.stop()
+			return publisher.Response{}, fmt.Errorf("publisher plugin did not respond: %w", ctx.Err())
+		}
+	}
+}
+
+// stop kills the plugin. It is started again on the next post. The caller must hold r.mu
+func (r *PluginRepository) stop() {
+	if r.proc == nil {
+		return
+	}
+	r.proc.stdin.Close()
+	r.proc.cmd.Process.Kill()
+	r.proc.drain(r.logger)
+	<-r.proc.exited
+	r.proc = nil
+}
+
+// drain discards the responses nobody will wait for, so that the reader can finish
+func (p *pluginProcess) drain(logger *slog.Logger) {
+	safego.Go(logger, "plugin-drain", func() {
+		for range p.responses {
+		}
+	})
+}
+
+// capabilitiesFromHandshake checks the handshake response and converts the platform limits
+func capabilitiesFromHandshake(resp publisher.Response) (domain.Capabilities, error) {
+	if resp.ProtocolVersion != publisher.ProtocolVersion {
+		return domain.Capabilities{}, fmt.Errorf("unsupported protocol version %d (want %d)", resp.ProtocolVersion, publisher.ProtocolVersion)
+	}
+	if resp.Platform == "" {
+		return domain.Capabilities{}, errors.New("platform is empty")
+	}
+	capabilities := domain.Capabilities{Platform: resp.Platform, MaxLength: resp.MaxLength, Unit: domain.LengthUnit(resp.Unit)}
+	if known, ok := domain.CapabilitiesFor(resp.Platform); ok {
+		// Fill in the limits the plugin left out
+		if capabilities.MaxLength == 0 {
+			capabilities.MaxLength = known.MaxLength
+		}
+		if capabilities.Unit == "" {
+			capabilities.Unit = known.Unit
+		}
+	}
+	switch capabilities.Unit {
+	case domain.LengthGraphemes, domain.LengthCharacters, domain.LengthWeighted:
+	case "":
+		capabilities.Unit = domain.LengthCharacters
+	default:
+		return domain.Capabilities{}, fmt.Errorf("unknown length unit %q", resp.Unit)
+	}
+	return capabilities, nil
+}
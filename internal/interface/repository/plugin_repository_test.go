@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/pkg/publisher"
+)
+
+// testPluginEnv は、テストのバイナリを投稿プラグインとして動かすときの動作を指定します
+const testPluginEnv = "QUOTEBOT_TEST_PUBLISHER_PLUGIN"
+
+// testPlugin はテスト用の投稿プラグインです
+type testPlugin struct {
+	mode  string
+	posts int
+}
+
+func (p *testPlugin) Info() publisher.Info {
+	return publisher.Info{Platform: domain.PlatformMastodon}
+}
+
+func (p *testPlugin) Publish(ctx context.Context, text, requestID string) (*publisher.Post, error) {
+	switch p.mode {
+	case "fail":
+		return nil, errors.New("status is too long")
+	case "exit":
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	p.posts++
+	return &publisher.Post{URI: fmt.Sprintf("https://example.social/@quotes/%d?request_id=%s", p.posts, requestID)}, nil
+}
+
+// TestHelperPublisherPlugin はテストのバイナリを投稿プラグインとして動かします。テストとしては何もしません
+func TestHelperPublisherPlugin(t *testing.T) {
+	mode := os.Getenv(testPluginEnv)
+	if mode == "" {
+		return
+	}
+	if mode == "handshake" {
+		fmt.Println(`{"id":1,"error":"missing credentials"}`)
+		os.Exit(0)
+	}
+	if err := publisher.Serve(&testPlugin{mode: mode}); err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+// newTestPluginRepository はテストのバイナリを mode の投稿プラグインとして起動します
+func newTestPluginRepository(t *testing.T, mode string) (*PluginRepository, error) {
+	t.Helper()
+	t.Setenv(testPluginEnv, mode)
+	cfg := &config.Config{
+		PublisherPlugin:     os.Args[0],
+		PublisherPluginArgs: []string{"-test.run=^TestHelperPublisherPlugin$"},
+		HTTPTimeout:         10 * time.Second,
+	}
+	repo, err := NewPluginRepository(cfg)
+	if err == nil {
+		t.Cleanup(repo.Shutdown)
+	}
+	return repo, err
+}
+
+func TestPluginRepository_Publish(t *testing.T) {
+	repo, err := newTestPluginRepository(t, "ok")
+	if err != nil {
+		t.Fatalf("NewPluginRepository() error = %v", err)
+	}
+	if got := repo.Capabilities(); got != domain.MastodonCapabilities {
+		t.Errorf("Capabilities() = %+v, want %+v", got, domain.MastodonCapabilities)
+	}
+
+	for i := 1; i <= 2; i++ {
+		ref, err := repo.Publish(WithRequestID(context.Background(), "0123456789abcdef"), "テスト名言")
+		if err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+		want := fmt.Sprintf("https://example.social/@quotes/%d?request_id=0123456789abcdef", i)
+		if ref.URI != want || ref.Platform != domain.PlatformMastodon {
+			t.Errorf("Publish() = %+v, want URI %s", ref, want)
+		}
+	}
+}
+
+func TestPluginRepository_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		timeout time.Duration
+		wantErr string
+	}{
+		{
+			name:    "異常系: 投稿先のエラー",
+			mode:    "fail",
+			wantErr: "status is too long",
+		},
+		{
+			name:    "異常系: 投稿中にプラグインが終了",
+			mode:    "exit",
+			wantErr: errPluginExited.Error(),
+		},
+		{
+			name:    "異常系: プラグインが応答しない",
+			mode:    "hang",
+			timeout: 500 * time.Millisecond,
+			wantErr: "did not respond",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := newTestPluginRepository(t, tt.mode)
+			if err != nil {
+				t.Fatalf("NewPluginRepository() error = %v", err)
+			}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			if _, err := repo.Publish(ctx, "テスト名言"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Publish() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPluginRepository_Restart(t *testing.T) {
+	repo, err := newTestPluginRepository(t, "exit")
+	if err != nil {
+		t.Fatalf("NewPluginRepository() error = %v", err)
+	}
+	if _, err := repo.Publish(context.Background(), "テスト名言"); err == nil {
+		t.Fatal("Publish() error = nil, want the plugin to exit")
+	}
+
+	// 次の投稿ではプラグインを起動し直す
+	t.Setenv(testPluginEnv, "ok")
+	if _, err := repo.Publish(context.Background(), "テスト名言"); err != nil {
+		t.Errorf("Publish() after a restart error = %v", err)
+	}
+}
+
+func TestNewPluginRepository_HandshakeFailure(t *testing.T) {
+	if _, err := newTestPluginRepository(t, "handshake"); err == nil || !strings.Contains(err.Error(), "missing credentials") {
+		t.Errorf("NewPluginRepository() error = %v, want the handshake error", err)
+	}
+}
+
+func TestCapabilitiesFromHandshake(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    publisher.Response
+		want    domain.Capabilities
+		wantErr bool
+	}{
+		{
+			name: "正常系: 既知のプラットフォームの上限を補う",
+			resp: publisher.Response{ProtocolVersion: 1, Platform: domain.PlatformTwitter},
+			want: domain.TwitterCapabilities,
+		},
+		{
+			name: "正常系: 独自のプラットフォーム",
+			resp: publisher.Response{ProtocolVersion: 1, Platform: "misskey", MaxLength: 3000},
+			want: domain.Capabilities{Platform: "misskey", MaxLength: 3000, Unit: domain.LengthCharacters},
+		},
+		{
+			name:    "異常系: プロトコルのバージョンが違う",
+			resp:    publisher.Response{ProtocolVersion: 2, Platform: "misskey"},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 不明な長さの単位",
+			resp:    publisher.Response{ProtocolVersion: 1, Platform: "misskey", Unit: "bytes"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := capabilitiesFromHandshake(tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("capabilitiesFromHandshake() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("capabilitiesFromHandshake() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// profileRkey is the record key of the account's profile record
+const profileRkey = "self"
+
+// UpdateProfile changes the account's profile record, keeping the fields it does not touch
+// (display name, avatar, banner, ...). The write fails instead of overwriting the profile
+// if it was edited since it was read
+func (r *BlueskyRepository) UpdateProfile(ctx context.Context, update domain.ProfileUpdate) error {
+	var current *GetRecordOutput
+	err := r.client.Do(ctx, http.MethodGet, NSIDGetRecord, func(headers map[string]string) (err error) {
+		current, err = r.xrpc.GetRecord(ctx, r.cfg.DID, CollectionActorProfile, profileRkey, headers)
+		return err
+	})
+
+	// An account that never set up a profile has no record yet
+	record := map[string]json.RawMessage{}
+	input := PutRecordInput{Repo: r.cfg.DID, Collection: CollectionActorProfile, Rkey: profileRkey}
+	switch {
+	case IsRecordNotFound(err):
+	case err != nil:
+		return fmt.Errorf("failed to get profile: %w", err)
+	default:
+		if err := json.Unmarshal(current.Value, &record); err != nil {
+			return fmt.Errorf("failed to decode profile: %w", err)
+		}
+		input.SwapRecord = current.CID
+	}
+	if err := applyProfileUpdate(record, update); err != nil {
+		return err
+	}
+	input.Record = record
+
+	err = r.client.Do(ctx, http.MethodPost, NSIDPutRecord, func(headers map[string]string) error {
+		_, err := r.xrpc.PutRecord(ctx, input, headers)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	return nil
+}
+
+// applyProfileUpdate sets the changed fields of a profile record
+func applyProfileUpdate(record map[string]json.RawMessage, update domain.ProfileUpdate) error {
+	set := func(field string, value interface{}) error {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		record[field] = raw
+		return nil
+	}
+	if err := set("$type", CollectionActorProfile); err != nil {
+		return err
+	}
+	if update.Description != nil {
+		if err := set("description", *update.Description); err != nil {
+			return err
+		}
+	}
+	if update.PinnedPost != nil {
+		if err := set("pinnedPost", StrongRef{URI: update.PinnedPost.URI, CID: update.PinnedPost.CID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletePost deletes one of the account's posts. A post that is already gone is not an error
+func (r *BlueskyRepository) DeletePost(ctx context.Context, uri string) error {
+	input, err := r.deleteRecordInput(uri)
+	if err != nil {
+		return err
+	}
+
+	err = r.client.Do(ctx, http.MethodPost, NSIDDeleteRecord, func(headers map[string]string) error {
+		return r.xrpc.DeleteRecord(ctx, input, headers)
+	})
+	if err != nil && !IsRecordNotFound(err) {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+	return nil
+}
+
+// deleteRecordInput parses an at:// URI of one of the account's posts
+func (r *BlueskyRepository) deleteRecordInput(uri string) (DeleteRecordInput, error) {
+	did, rkey, ok := splitPostURI(uri)
+	if !ok {
+		return DeleteRecordInput{}, fmt.Errorf("not a post URI: %s", uri)
+	}
+	if did != r.cfg.DID {
+		return DeleteRecordInput{}, fmt.Errorf("post %s does not belong to %s", uri, r.cfg.DID)
+	}
+	return DeleteRecordInput{Repo: did, Collection: CollectionFeedPost, Rkey: rkey}, nil
+}
+
+// splitPostURI splits the at:// URI of a post into the DID of its repo and its record key
+func splitPostURI(uri string) (did, rkey string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+	if !strings.HasPrefix(uri, "at://") || len(parts) != 3 || parts[1] != CollectionFeedPost || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[2], true
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// newProfileTestRepository は handler を PDS として使う BlueskyRepository を作成します
+func newProfileTestRepository(t *testing.T, handler http.HandlerFunc) *BlueskyRepository {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	cfg := &config.Config{
+		AccessJWT:            "valid-token",
+		RefreshJWT:           "refresh-token",
+		DID:                  "did:plc:test",
+		PDSURL:               server.URL,
+		HTTPTimeout:          3 * time.Second,
+		TokenRefreshInterval: time.Hour,
+	}
+	repo, err := NewBlueskyRepository(cfg)
+	if err != nil {
+		t.Fatalf("NewBlueskyRepository() error = %v", err)
+	}
+	t.Cleanup(repo.Shutdown)
+	return repo
+}
+
+func TestBlueskyRepository_UpdateProfile(t *testing.T) {
+	description := "次の名言は 12:00 に投稿します"
+	pinned := &domain.PostRef{URI: "at://did:plc:test/app.bsky.feed.post/index", CID: "bafyindex"}
+
+	tests := []struct {
+		name       string
+		existing   string // 既存のプロフィール（空の場合はまだない）
+		update     domain.ProfileUpdate
+		wantRecord map[string]interface{}
+		wantSwap   string
+	}{
+		{
+			name:     "正常系: 他の項目を残して自己紹介を変更",
+			existing: `{"$type":"app.bsky.actor.profile","displayName":"名言bot","description":"古い自己紹介","avatar":{"$type":"blob"}}`,
+			update:   domain.ProfileUpdate{Description: &description},
+			wantRecord: map[string]interface{}{
+				"$type":       "app.bsky.actor.profile",
+				"displayName": "名言bot",
+				"description": description,
+				"avatar":      map[string]interface{}{"$type": "blob"},
+			},
+			wantSwap: "bafyprofile",
+		},
+		{
+			name:   "正常系: プロフィールがない場合は作成して投稿を固定",
+			update: domain.ProfileUpdate{PinnedPost: pinned},
+			wantRecord: map[string]interface{}{
+				"$type":      "app.bsky.actor.profile",
+				"pinnedPost": map[string]interface{}{"uri": pinned.URI, "cid": pinned.CID},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var put struct {
+				Repo       string                 `json:"repo"`
+				Collection string                 `json:"collection"`
+				Rkey       string                 `json:"rkey"`
+				Record     map[string]interface{} `json:"record"`
+				SwapRecord string                 `json:"swapRecord"`
+			}
+			repo := newProfileTestRepository(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/xrpc/com.atproto.repo.getRecord":
+					if tt.existing == "" {
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(`{"error":"RecordNotFound","message":"Could not locate record"}`))
+						return
+					}
+					json.NewEncoder(w).Encode(GetRecordOutput{URI: "at://did:plc:test/app.bsky.actor.profile/self", CID: "bafyprofile", Value: json.RawMessage(tt.existing)})
+				case "/xrpc/com.atproto.repo.putRecord":
+					json.NewDecoder(r.Body).Decode(&put)
+					json.NewEncoder(w).Encode(CreateRecordOutput{URI: "at://did:plc:test/app.bsky.actor.profile/self", CID: "bafynew"})
+				}
+			})
+
+			if err := repo.UpdateProfile(context.Background(), tt.update); err != nil {
+				t.Fatalf("UpdateProfile() error = %v", err)
+			}
+			if put.Collection != CollectionActorProfile || put.Rkey != "self" || put.Repo != "did:plc:test" || put.SwapRecord != tt.wantSwap {
+				t.Errorf("putRecord = %+v", put)
+			}
+			got, _ := json.Marshal(put.Record)
+			want, _ := json.Marshal(tt.wantRecord)
+			if string(got) != string(want) {
+				t.Errorf("record = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestBlueskyRepository_DeletePost(t *testing.T) {
+	var deleted DeleteRecordInput
+	repo := newProfileTestRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/xrpc/com.atproto.repo.deleteRecord" {
+			json.NewDecoder(r.Body).Decode(&deleted)
+			w.Write([]byte(`{}`))
+		}
+	})
+
+	if err := repo.DeletePost(context.Background(), "at://did:plc:test/app.bsky.feed.post/3kxyz"); err != nil {
+		t.Fatalf("DeletePost() error = %v", err)
+	}
+	if deleted != (DeleteRecordInput{Repo: "did:plc:test", Collection: CollectionFeedPost, Rkey: "3kxyz"}) {
+		t.Errorf("deleteRecord = %+v", deleted)
+	}
+
+	for _, uri := range []string{"at://did:plc:other/app.bsky.feed.post/3kxyz", "at://did:plc:test/app.bsky.actor.profile/self", "https://bsky.app"} {
+		if err := repo.DeletePost(context.Background(), uri); err == nil {
+			t.Errorf("DeletePost(%s) error = nil, want an error", uri)
+		}
+	}
+}
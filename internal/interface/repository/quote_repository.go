@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/littleironwaltz/quotebot/config"
 	"github.com/littleironwaltz/quotebot/internal/domain"
@@ -11,6 +14,7 @@ import (
 
 // QuoteRepository は名言データの永続化を処理します
 type QuoteRepository struct {
+	mu         sync.Mutex
 	quotesFile string
 }
 
@@ -21,9 +25,23 @@ func NewQuoteRepository(cfg *config.Config) *QuoteRepository {
 	}
 }
 
+// QuotesFile は読み込む名言ファイルのパスを返します
+func (r *QuoteRepository) QuotesFile() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.quotesFile
+}
+
+// SetQuotesFile は読み込む名言ファイルを変更します。次の読み込みから反映されます
+func (r *QuoteRepository) SetQuotesFile(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quotesFile = path
+}
+
 // LoadQuotes はファイルから名言データを読み込みます
 func (r *QuoteRepository) LoadQuotes() ([]domain.Quote, error) {
-	file, err := os.Open(r.quotesFile)
+	file, err := os.Open(r.QuotesFile())
 	if err != nil {
 		return nil, fmt.Errorf("名言ファイルのオープンに失敗しました: %w", err)
 	}
@@ -36,3 +54,114 @@ func (r *QuoteRepository) LoadQuotes() ([]domain.Quote, error) {
 
 	return quotes, nil
 }
+
+// QuotesHash は名言ファイルの内容のSHA-256を返します
+func (r *QuoteRepository) QuotesHash() (string, error) {
+	data, err := os.ReadFile(r.QuotesFile())
+	if err != nil {
+		return "", fmt.Errorf("名言ファイルの読み込みに失敗しました: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// LoadQuotesStrict は未知のフィールドや末尾の余分なデータを許可せずに名言データを読み込みます。
+// `quotebot validate` で名言ファイルの形式を確認するために使用します
+func (r *QuoteRepository) LoadQuotesStrict() ([]domain.Quote, error) {
+	file, err := os.Open(r.QuotesFile())
+	if err != nil {
+		return nil, fmt.Errorf("名言ファイルのオープンに失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	var quotes []domain.Quote
+	if err := decoder.Decode(&quotes); err != nil {
+		return nil, fmt.Errorf("名言データのデコードに失敗しました: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("名言データの後に余分なデータがあります")
+	}
+
+	return quotes, nil
+}
+
+// AppendQuote は名言ファイルの末尾に名言を追加します。既存の項目は書き換えず、
+// 一時ファイルに書いてから置き換えるため、途中で止まっても名言ファイルが壊れません
+func (r *QuoteRepository) AppendQuote(quote domain.Quote) error {
+	path := r.QuotesFile()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("名言ファイルの読み込みに失敗しました: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("名言ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	// 既存の項目は知らないフィールドも含めてそのまま残す
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("名言データのデコードに失敗しました: %w", err)
+	}
+	item, err := json.Marshal(quote)
+	if err != nil {
+		return fmt.Errorf("名言のエンコードに失敗しました: %w", err)
+	}
+	out, err := json.MarshalIndent(append(items, item), "", "  ")
+	if err != nil {
+		return fmt.Errorf("名言のエンコードに失敗しました: %w", err)
+	}
+	return writeFileAtomic(path, append(out, '\n'), info.Mode().Perm())
+}
+
+// RewriteQuotes は名言ファイルの指定した位置の項目の id、text、author を書き換えます。
+// ほかのフィールドと項目はそのまま残し、AppendQuote と同じく一時ファイルに書いてから置き換えます
+func (r *QuoteRepository) RewriteQuotes(quotes map[int]domain.Quote) error {
+	path := r.QuotesFile()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("名言ファイルの読み込みに失敗しました: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("名言ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	// 書き換えない項目は知らないフィールドも並び順もそのまま残す
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("名言データのデコードに失敗しました: %w", err)
+	}
+	for index, quote := range quotes {
+		if index < 0 || index >= len(items) {
+			return fmt.Errorf("%d件目の名言がありません", index+1)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(items[index], &fields); err != nil {
+			return fmt.Errorf("名言データのデコードに失敗しました: %w", err)
+		}
+		for key, value := range map[string]string{"id": quote.ID, "text": quote.Text, "author": quote.Author} {
+			if value == "" {
+				continue
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("名言のエンコードに失敗しました: %w", err)
+			}
+			fields[key] = encoded
+		}
+		item, err := json.Marshal(fields)
+		if err != nil {
+			return fmt.Errorf("名言のエンコードに失敗しました: %w", err)
+		}
+		items[index] = item
+	}
+	out, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("名言のエンコードに失敗しました: %w", err)
+	}
+	return writeFileAtomic(path, append(out, '\n'), info.Mode().Perm())
+}
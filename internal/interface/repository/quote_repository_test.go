@@ -3,6 +3,7 @@ package repository
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/littleironwaltz/quotebot/config"
@@ -119,3 +120,156 @@ func TestQuoteRepository_LoadQuotes(t *testing.T) {
 		})
 	}
 }
+
+func TestQuoteRepository_LoadQuotesStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "正常系: 有効なJSONファイルを読み込む",
+			content: `[{"text": "テスト名言1", "author": "テスト著者1"}]`,
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:    "異常系: 未知のフィールド",
+			content: `[{"text": "テスト名言1", "auther": "テスト著者1"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 配列の後に余分なデータ",
+			content: `[{"text": "テスト名言1", "author": "テスト著者1"}] []`,
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 配列ではない",
+			content: `{"text": "テスト名言1", "author": "テスト著者1"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "quotes.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("テストファイルの作成に失敗しました: %v", err)
+			}
+
+			repo := NewQuoteRepository(&config.Config{QuotesFile: path})
+			quotes, err := repo.LoadQuotesStrict()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadQuotesStrict() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(quotes) != tt.want {
+				t.Errorf("LoadQuotesStrict() = %v, want %d quotes", quotes, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteRepository_AppendQuote(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.json")
+	// 知らないフィールドは追加した後も残る
+	if err := os.WriteFile(path, []byte(`[{"text": "テスト名言1", "author": "テスト著者1", "note": "メモ"}]`), 0o640); err != nil {
+		t.Fatalf("テストファイルの作成に失敗しました: %v", err)
+	}
+	repo := NewQuoteRepository(&config.Config{QuotesFile: path})
+
+	if err := repo.AppendQuote(domain.Quote{ID: "q2", Text: "テスト名言2", Author: "テスト著者2", Tags: []string{"tag"}}); err != nil {
+		t.Fatalf("AppendQuote() error = %v", err)
+	}
+
+	quotes, err := repo.LoadQuotes()
+	if err != nil {
+		t.Fatalf("LoadQuotes() error = %v", err)
+	}
+	if len(quotes) != 2 || quotes[1].ID != "q2" || quotes[1].Tags[0] != "tag" {
+		t.Errorf("LoadQuotes() = %+v, want the appended quote", quotes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"note": "メモ"`) {
+		t.Errorf("quotes file = %s, want the unknown field kept", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("quotes file mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	if err := NewQuoteRepository(&config.Config{QuotesFile: filepath.Join(t.TempDir(), "missing.json")}).AppendQuote(domain.Quote{Text: "t", Author: "a"}); err == nil {
+		t.Error("AppendQuote() to a missing file error = nil, want error")
+	}
+}
+
+func TestQuoteRepository_RewriteQuotes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.json")
+	if err := os.WriteFile(path, []byte(`[{"text": "名言1 ", "author": "著者1", "note": "メモ"}, {"note": "そのまま", "text": "名言2", "author": "著者2"}]`), 0o640); err != nil {
+		t.Fatalf("テストファイルの作成に失敗しました: %v", err)
+	}
+	repo := NewQuoteRepository(&config.Config{QuotesFile: path})
+
+	if err := repo.RewriteQuotes(map[int]domain.Quote{0: {ID: "q1", Text: "名言1", Author: "著者1"}}); err != nil {
+		t.Fatalf("RewriteQuotes() error = %v", err)
+	}
+
+	quotes, err := repo.LoadQuotes()
+	if err != nil {
+		t.Fatalf("LoadQuotes() error = %v", err)
+	}
+	if len(quotes) != 2 || quotes[0].ID != "q1" || quotes[0].Text != "名言1" || quotes[1].Text != "名言2" {
+		t.Errorf("LoadQuotes() = %+v, want the first quote rewritten", quotes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	// 書き換えない項目はフィールドの並び順も残る
+	if !strings.Contains(string(data), `"note": "メモ"`) || !strings.Contains(string(data), `"note": "そのまま",`) {
+		t.Errorf("quotes file = %s, want the other fields kept", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("quotes file mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	if err := repo.RewriteQuotes(map[int]domain.Quote{2: {Text: "t", Author: "a"}}); err == nil {
+		t.Error("RewriteQuotes() out of range error = nil, want error")
+	}
+}
+
+func TestQuoteRepository_QuotesHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("テストファイルの作成に失敗しました: %v", err)
+		}
+	}
+	repo := NewQuoteRepository(&config.Config{QuotesFile: path})
+	hash := func() string {
+		got, err := repo.QuotesHash()
+		if err != nil {
+			t.Fatalf("QuotesHash() error = %v", err)
+		}
+		return got
+	}
+
+	write(`[{"text": "テスト名言1", "author": "テスト著者1"}]`)
+	first := hash()
+	// 同じ内容で書き直してもハッシュは変わらない
+	write(`[{"text": "テスト名言1", "author": "テスト著者1"}]`)
+	if got := hash(); got != first {
+		t.Errorf("QuotesHash() = %s after rewriting the same content, want %s", got, first)
+	}
+	write(`[{"text": "テスト名言2", "author": "テスト著者1"}]`)
+	if got := hash(); got == first {
+		t.Error("QuotesHash() did not change with the content")
+	}
+
+	repo.SetQuotesFile(filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := repo.QuotesHash(); err == nil {
+		t.Error("QuotesHash() of a missing file error = nil, want error")
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/clock"
+)
+
+// RateLimit is the request budget the server reported for an endpoint through the
+// ratelimit-limit, ratelimit-remaining, and ratelimit-reset response headers
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// RateLimiter tracks the budget of every endpoint from the responses and delays requests
+// to an endpoint whose budget is nearly spent until it resets, instead of running into 429
+type RateLimiter struct {
+	reserve int           // Requests left in the budget when the limiter starts waiting
+	maxWait time.Duration // Longest wait for a reset; longer waits are left to the server
+	clock   clock.Clock
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	budgets map[string]RateLimit // By host and path
+}
+
+// NewRateLimiter creates a RateLimiter that waits once no more than reserve requests are
+// left, for at most maxWait. A maxWait of zero only tracks the budgets.
+func NewRateLimiter(reserve int, maxWait time.Duration, logger *slog.Logger) *RateLimiter {
+	return &RateLimiter{
+		reserve: reserve,
+		maxWait: maxWait,
+		clock:   clock.Real,
+		logger:  logger,
+		budgets: map[string]RateLimit{},
+	}
+}
+
+// Middleware waits before requests to an exhausted endpoint and records the budget
+// from every response, including 429
+func (l *RateLimiter) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			key := req.URL.Host + req.URL.Path
+			if wait := l.wait(key); wait > 0 {
+				l.logger.Info("Rate limit budget nearly exhausted, waiting for the reset",
+					"path", req.URL.Path, "wait", wait.Round(time.Millisecond), "request_id", RequestIDFromContext(req.Context()))
+				select {
+				case <-l.clock.After(wait):
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				}
+			}
+			resp, err := next(req)
+			if resp != nil {
+				l.observe(key, resp.Header)
+			}
+			return resp, err
+		}
+	}
+}
+
+// wait returns how long a request to key should wait for its budget to reset
+func (l *RateLimiter) wait(key string) time.Duration {
+	if l.maxWait <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	budget, ok := l.budgets[key]
+	l.mu.Unlock()
+	if !ok || budget.Remaining > l.reserve {
+		return 0
+	}
+	wait := clock.Until(l.clock, budget.Reset)
+	if wait <= 0 {
+		return 0
+	}
+	if wait > l.maxWait {
+		l.logger.Warn("Rate limit budget exhausted, but the reset is too far away to wait for",
+			"key", key, "reset", budget.Reset, "max_wait", l.maxWait)
+		return 0
+	}
+	return wait
+}
+
+// observe records the budget from the response headers, if they carry one
+func (l *RateLimiter) observe(key string, header http.Header) {
+	budget, ok := parseRateLimit(header, l.clock.Now())
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.budgets[key] = budget
+}
+
+// Budgets returns the last budget reported for every endpoint, keyed by host and path
+func (l *RateLimiter) Budgets() map[string]RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return maps.Clone(l.budgets)
+}
+
+// parseRateLimit reads the ratelimit-* headers. The reset is accepted both as a Unix time,
+// as Bluesky sends it, and as seconds from now, as in the IETF draft.
+func parseRateLimit(header http.Header, now time.Time) (RateLimit, bool) {
+	limit, err1 := strconv.Atoi(strings.TrimSpace(header.Get("RateLimit-Limit")))
+	remaining, err2 := strconv.Atoi(strings.TrimSpace(header.Get("RateLimit-Remaining")))
+	reset, err3 := strconv.ParseInt(strings.TrimSpace(header.Get("RateLimit-Reset")), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return RateLimit{}, false
+	}
+	budget := RateLimit{Limit: limit, Remaining: remaining}
+	// Anything before 2001 is too small to be a Unix time
+	if reset > 1_000_000_000 {
+		budget.Reset = time.Unix(reset, 0)
+	} else {
+		budget.Reset = now.Add(time.Duration(reset) * time.Second)
+	}
+	return budget, true
+}
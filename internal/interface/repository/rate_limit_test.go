@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/clock"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   RateLimit
+		wantOK bool
+	}{
+		{
+			name:   "正常系: リセットがUnix時刻",
+			header: map[string]string{"RateLimit-Limit": "5000", "RateLimit-Remaining": "4999", "RateLimit-Reset": "1704168000"},
+			want:   RateLimit{Limit: 5000, Remaining: 4999, Reset: time.Unix(1704168000, 0)},
+			wantOK: true,
+		},
+		{
+			name:   "正常系: リセットが秒数",
+			header: map[string]string{"RateLimit-Limit": "100", "RateLimit-Remaining": "0", "RateLimit-Reset": "30"},
+			want:   RateLimit{Limit: 100, Remaining: 0, Reset: now.Add(30 * time.Second)},
+			wantOK: true,
+		},
+		{
+			name:   "異常系: ヘッダーがない",
+			header: map[string]string{},
+		},
+		{
+			name:   "異常系: 数値でない",
+			header: map[string]string{"RateLimit-Limit": "100", "RateLimit-Remaining": "many", "RateLimit-Reset": "30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range tt.header {
+				header.Set(key, value)
+			}
+			got, ok := parseRateLimit(header, now)
+			if ok != tt.wantOK {
+				t.Fatalf("parseRateLimit() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !got.Reset.Equal(tt.want.Reset) || got.Limit != tt.want.Limit || got.Remaining != tt.want.Remaining {
+				t.Errorf("parseRateLimit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining string
+		reset     string
+		maxWait   time.Duration
+		wantWait  bool
+	}{
+		{
+			name:      "正常系: 残りに余裕があれば待たない",
+			remaining: "10",
+			reset:     "60",
+			maxWait:   time.Minute,
+		},
+		{
+			name:      "正常系: 残りが少なければリセットまで待つ",
+			remaining: "2",
+			reset:     "30",
+			maxWait:   time.Minute,
+			wantWait:  true,
+		},
+		{
+			name:      "正常系: リセットが遠ければ待たない",
+			remaining: "0",
+			reset:     "3600",
+			maxWait:   time.Minute,
+		},
+		{
+			name:      "正常系: 待ち時間が0なら記録だけする",
+			remaining: "0",
+			reset:     "30",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC))
+			limiter := NewRateLimiter(2, tt.maxWait, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			limiter.clock = fake
+			calls := 0
+			roundTrip := limiter.Middleware()(func(req *http.Request) (*http.Response, error) {
+				calls++
+				header := http.Header{}
+				header.Set("RateLimit-Limit", "100")
+				header.Set("RateLimit-Remaining", tt.remaining)
+				header.Set("RateLimit-Reset", tt.reset)
+				return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody}, nil
+			})
+			send := func() error {
+				req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "https://pds.example/xrpc/com.atproto.repo.createRecord", nil)
+				_, err := roundTrip(req)
+				return err
+			}
+
+			// 1回目のレスポンスで残りを記録する
+			if err := send(); err != nil {
+				t.Fatalf("first request error = %v", err)
+			}
+			if got := limiter.Budgets()["pds.example/xrpc/com.atproto.repo.createRecord"]; got.Limit != 100 {
+				t.Errorf("Budgets() = %v, want the budget of createRecord", limiter.Budgets())
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- send() }()
+			if tt.wantWait {
+				fake.BlockUntil(1)
+				select {
+				case <-done:
+					t.Fatal("request was sent before the budget reset")
+				case <-time.After(10 * time.Millisecond):
+				}
+				fake.Advance(30 * time.Second)
+			}
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("second request error = %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("request is still waiting")
+			}
+			if calls != 2 {
+				t.Errorf("calls = %d, want 2", calls)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_MiddlewareCanceled(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC))
+	limiter := NewRateLimiter(0, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	limiter.clock = fake
+	limiter.budgets["pds.example/xrpc/x"] = RateLimit{Limit: 10, Remaining: 0, Reset: fake.Now().Add(time.Minute)}
+	roundTrip := limiter.Middleware()(func(req *http.Request) (*http.Response, error) {
+		t.Fatal("request was sent while waiting for the reset")
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://pds.example/xrpc/x", nil)
+	if _, err := roundTrip(req); err != context.Canceled {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// recordCID returns the CID the PDS gives a record: a CIDv1 with the dag-cbor codec and the
+// sha2-256 of the record's DAG-CBOR encoding. The posts of a thread created in one applyWrites
+// refer to each other by CID before any of them exists, so the bot computes the CIDs itself.
+func recordCID(record interface{}) (string, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode record: %w", err)
+	}
+	// Decode into generic values, as the PDS does, so that the encoding follows the JSON form
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("failed to decode record: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeDAGCBOR(&buf, value); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	cid := append([]byte{0x01, 0x71, 0x12, sha256.Size}, sum[:]...)
+	return "b" + cidEncoding.EncodeToString(cid), nil
+}
+
+// encodeDAGCBOR writes a JSON value in the atproto data model as DAG-CBOR: map keys sorted by
+// length and then bytewise, the shortest form of every integer, {"$link"} objects as CID links
+// and {"$bytes"} objects as byte strings. Floats are not part of the data model.
+func encodeDAGCBOR(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return fmt.Errorf("records can only hold integers, got %s", v)
+		}
+		if n >= 0 {
+			writeCBORHead(buf, 0, uint64(n))
+		} else {
+			writeCBORHead(buf, 1, uint64(-1-n))
+		}
+	case string:
+		writeCBORHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeCBORHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeDAGCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		if link, ok := v["$link"].(string); ok && len(v) == 1 {
+			cid, err := decodeCID(link)
+			if err != nil {
+				return err
+			}
+			// tag 42, with the binary CID behind the identity multibase prefix
+			buf.Write([]byte{0xd8, 0x2a})
+			writeCBORHead(buf, 2, uint64(len(cid)+1))
+			buf.WriteByte(0x00)
+			buf.Write(cid)
+			return nil
+		}
+		if encoded, ok := v["$bytes"].(string); ok && len(v) == 1 {
+			data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+			if err != nil {
+				return fmt.Errorf("invalid $bytes: %w", err)
+			}
+			writeCBORHead(buf, 2, uint64(len(data)))
+			buf.Write(data)
+			return nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		writeCBORHead(buf, 5, uint64(len(v)))
+		for _, key := range keys {
+			writeCBORHead(buf, 3, uint64(len(key)))
+			buf.WriteString(key)
+			if err := encodeDAGCBOR(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value in record: %T", value)
+	}
+	return nil
+}
+
+// writeCBORHead writes the major type and its argument in the shortest form
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= 0xff:
+		buf.Write([]byte{major | 24, byte(n)})
+	case n <= 0xffff:
+		buf.WriteByte(major | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= 0xffffffff:
+		buf.WriteByte(major | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(major | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+// decodeCID returns the binary form of a CID in the base32 multibase form used by atproto
+func decodeCID(cid string) ([]byte, error) {
+	if !strings.HasPrefix(cid, "b") {
+		return nil, fmt.Errorf("unsupported CID encoding: %s", cid)
+	}
+	data, err := cidEncoding.DecodeString(cid[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CID %s: %w", cid, err)
+	}
+	return data, nil
+}
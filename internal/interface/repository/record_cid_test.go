@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestRecordCID(t *testing.T) {
+	// 空のマップ（0xa0）のCIDはよく知られた値
+	got, err := recordCID(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("recordCID() error = %v", err)
+	}
+	if want := "bafyreigbtj4x7ip5legnfznufuopl4sg4knzc2cof6duas4b3q2fy6swua"; got != want {
+		t.Errorf("recordCID({}) = %s, want %s", got, want)
+	}
+
+	if _, err := recordCID(map[string]interface{}{"n": 1.5}); err == nil {
+		t.Error("recordCID() with a float error = nil, want error")
+	}
+}
+
+func TestEncodeDAGCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string // hex
+	}{
+		{
+			name: "正常系: キーは長さ、次にバイト順で並べる",
+			json: `{"bb":1,"a":2,"c":3}`,
+			want: "a3" + "6161" + "02" + "6163" + "03" + "626262" + "01",
+		},
+		{
+			name: "正常系: 整数は最短の形",
+			json: `[0,23,24,255,256,-1,-25,65536]`,
+			want: "88" + "00" + "17" + "1818" + "18ff" + "190100" + "20" + "3818" + "1a00010000",
+		},
+		{
+			name: "正常系: 文字列はUTF-8のバイト数",
+			json: `"知"`,
+			want: "63e79fa5",
+		},
+		{
+			name: "正常系: null と真偽値",
+			json: `[null,true,false]`,
+			want: "83f6f5f4",
+		},
+		{
+			name: "正常系: $link はCIDのリンク",
+			json: `{"$link":"bafyreigbtj4x7ip5legnfznufuopl4sg4knzc2cof6duas4b3q2fy6swua"}`,
+			want: "d82a582500" + "01711220" + "c19a797fa1fd590cd2e5b42d1cf5f246e29b91684e2f87404b81dc345c7a56a0",
+		},
+		{
+			name: "正常系: $bytes はバイト列",
+			json: `{"$bytes":"AQI"}`,
+			want: "420102",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := json.NewDecoder(bytes.NewReader([]byte(tt.json)))
+			decoder.UseNumber()
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := encodeDAGCBOR(&buf, value); err != nil {
+				t.Fatalf("encodeDAGCBOR() error = %v", err)
+			}
+			if got := hex.EncodeToString(buf.Bytes()); got != tt.want {
+				t.Errorf("encodeDAGCBOR() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestHTTPClient_UserAgentAndRequestID(t *testing.T) {
+	tests := []struct {
+		name          string
+		userAgent     string
+		requestID     string
+		wantUserAgent string
+	}{
+		{
+			name:          "正常系: デフォルトのUser-AgentとリクエストIDの自動生成",
+			wantUserAgent: "QuoteBot/",
+		},
+		{
+			name:          "正常系: 設定したUser-AgentとコンテキストのリクエストID",
+			userAgent:     "MyBot/1.0 (ops@example.com)",
+			requestID:     "fixed-request-id",
+			wantUserAgent: "MyBot/1.0 (ops@example.com)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var userAgents, requestIDs []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				userAgents = append(userAgents, r.Header.Get("User-Agent"))
+				requestIDs = append(requestIDs, r.Header.Get(RequestIDHeader))
+				attempt := len(requestIDs)
+				mu.Unlock()
+
+				// 1回目は失敗させて再試行させる
+				if attempt == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{HTTPTimeout: 3 * time.Second, MaxRetries: 1, UserAgent: tt.userAgent}
+			client := newTestHTTPClient(t, cfg)
+
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = WithRequestID(ctx, tt.requestID)
+			}
+			resp, err := client.DoRequest(ctx, "GET", server.URL, nil, nil)
+			if err != nil {
+				t.Fatalf("DoRequest() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if len(requestIDs) != 2 {
+				t.Fatalf("server received %d requests, want 2", len(requestIDs))
+			}
+			for _, ua := range userAgents {
+				if !strings.HasPrefix(ua, tt.wantUserAgent) {
+					t.Errorf("User-Agent = %q, want prefix %q", ua, tt.wantUserAgent)
+				}
+			}
+
+			// 再試行でも同じリクエストIDが送られる
+			if requestIDs[0] == "" || requestIDs[0] != requestIDs[1] {
+				t.Errorf("request IDs = %v, want the same non-empty ID", requestIDs)
+			}
+			if tt.requestID != "" && requestIDs[0] != tt.requestID {
+				t.Errorf("request ID = %v, want %v", requestIDs[0], tt.requestID)
+			}
+		})
+	}
+}
+
+func TestHTTPError_IncludesRequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HTTPTimeout: 3 * time.Second}
+	client := newTestHTTPClient(t, cfg)
+
+	_, err := client.DoRequest(WithRequestID(context.Background(), "abc123"), "GET", server.URL, nil, nil)
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		t.Fatalf("DoRequest() error = %v, want *HTTPError", err)
+	}
+	if httpErr.RequestID != "abc123" || !strings.Contains(httpErr.Error(), "abc123") {
+		t.Errorf("HTTPError = %v, want request id abc123", httpErr)
+	}
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetryBudget limits the number of retries allowed within a sliding time window
+type RetryBudget struct {
+	maxRetries int
+	window     time.Duration
+	now        func() time.Time
+	mu         sync.Mutex
+	retries    []time.Time
+}
+
+// NewRetryBudget creates a new RetryBudget instance.
+// A maxRetries of zero or less disables the budget.
+func NewRetryBudget(maxRetries int, window time.Duration) *RetryBudget {
+	return &RetryBudget{
+		maxRetries: maxRetries,
+		window:     window,
+		now:        time.Now,
+	}
+}
+
+// Allow reports whether another retry fits in the budget and records it if so
+func (b *RetryBudget) Allow() bool {
+	if b == nil || b.maxRetries <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.prune(now)
+	if len(b.retries) >= b.maxRetries {
+		return false
+	}
+
+	b.retries = append(b.retries, now)
+	return true
+}
+
+// Remaining returns the number of retries still available in the current window
+func (b *RetryBudget) Remaining() int {
+	if b == nil || b.maxRetries <= 0 {
+		return -1
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prune(b.now())
+	return b.maxRetries - len(b.retries)
+}
+
+// prune drops retries that fell out of the window. Caller must hold mu.
+func (b *RetryBudget) prune(now time.Time) {
+	cutoff := now.Add(-b.window)
+	i := 0
+	for i < len(b.retries) && !b.retries[i].After(cutoff) {
+		i++
+	}
+	b.retries = b.retries[i:]
+}
+
+// RetryMetrics holds counters describing the outcome of HTTP requests
+type RetryMetrics struct {
+	retries         atomic.Int64
+	successes       atomic.Int64
+	failures        atomic.Int64
+	budgetExhausted atomic.Int64
+
+	mu            sync.Mutex
+	networkErrors map[NetworkErrorClass]int64
+}
+
+// RetryMetricsSnapshot is a point-in-time copy of RetryMetrics
+type RetryMetricsSnapshot struct {
+	Retries         int64
+	Successes       int64
+	Failures        int64
+	BudgetExhausted int64
+	// NetworkErrors counts the attempts that failed without a response, by class
+	NetworkErrors map[NetworkErrorClass]int64
+}
+
+// Snapshot returns the current counter values
+func (m *RetryMetrics) Snapshot() RetryMetricsSnapshot {
+	return RetryMetricsSnapshot{
+		Retries:         m.retries.Load(),
+		Successes:       m.successes.Load(),
+		Failures:        m.failures.Load(),
+		BudgetExhausted: m.budgetExhausted.Load(),
+		NetworkErrors:   m.networkErrorCounts(),
+	}
+}
+
+// recordNetworkError counts an attempt that failed without a response
+func (m *RetryMetrics) recordNetworkError(class NetworkErrorClass) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.networkErrors == nil {
+		m.networkErrors = map[NetworkErrorClass]int64{}
+	}
+	m.networkErrors[class]++
+}
+
+func (m *RetryMetrics) networkErrorCounts() map[NetworkErrorClass]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.networkErrors)
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestRetryBudget_Allow(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		calls      int
+		advance    time.Duration
+		wantAllow  bool
+	}{
+		{
+			name:       "正常系: バジェット内の再試行",
+			maxRetries: 3,
+			calls:      2,
+			wantAllow:  true,
+		},
+		{
+			name:       "異常系: バジェットを使い切った",
+			maxRetries: 3,
+			calls:      3,
+			wantAllow:  false,
+		},
+		{
+			name:       "正常系: ウィンドウ経過後に回復",
+			maxRetries: 3,
+			calls:      3,
+			advance:    2 * time.Minute,
+			wantAllow:  true,
+		},
+		{
+			name:       "正常系: バジェット無効",
+			maxRetries: 0,
+			calls:      100,
+			wantAllow:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			budget := NewRetryBudget(tt.maxRetries, time.Minute)
+			budget.now = func() time.Time { return now }
+
+			for i := 0; i < tt.calls; i++ {
+				budget.Allow()
+			}
+
+			// 時間を進める
+			now = now.Add(tt.advance)
+
+			if got := budget.Allow(); got != tt.wantAllow {
+				t.Errorf("Allow() = %v, want %v", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_DoRequestRetryBudget(t *testing.T) {
+	// 常にサーバーエラーを返すテストサーバー
+	var requestCount int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		if r.URL.Path == "/success" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		HTTPTimeout:       1 * time.Second,
+		MaxRetries:        5,
+		RetryBackoff:      1 * time.Millisecond,
+		RetryBudget:       2,
+		RetryBudgetWindow: time.Hour,
+	}
+	client := newTestHTTPClient(t, cfg)
+	ctx := context.Background()
+
+	// 成功リクエスト
+	if _, err := client.DoRequest(ctx, "GET", server.URL+"/success", nil, nil); err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+
+	// 失敗リクエスト（バジェットを超えたら再試行を打ち切る）
+	requestCount = 0
+	_, err := client.DoRequest(ctx, "GET", server.URL+"/fail", nil, nil)
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("DoRequest() error = %v, want %v", err, ErrRetryBudgetExhausted)
+	}
+
+	// 初回 + バジェット分の再試行のみ実行される
+	if requestCount != 3 {
+		t.Errorf("request count = %d, want 3", requestCount)
+	}
+
+	got := client.Metrics()
+	want := RetryMetricsSnapshot{
+		Retries:         2,
+		Successes:       1,
+		Failures:        1,
+		BudgetExhausted: 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Metrics() = %+v, want %+v", got, want)
+	}
+}
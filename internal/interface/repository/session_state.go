@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+)
+
+// SessionState is where the account's session is in its lifecycle. It decides what the
+// TokenManager does next: nothing while active, refresh when the access token is about to
+// expire or was rejected, and log in again (or ask the operator to) when the refresh token
+// no longer works.
+type SessionState string
+
+const (
+	// SessionUnauthenticated means there are no tokens yet
+	SessionUnauthenticated SessionState = "unauthenticated"
+	// SessionActive means the access token is valid
+	SessionActive SessionState = "active"
+	// SessionNeedsRefresh means the access token expires within TOKEN_REFRESH_MARGIN
+	SessionNeedsRefresh SessionState = "needs_refresh"
+	// SessionExpired means the access token has expired or was rejected by the PDS
+	SessionExpired SessionState = "expired"
+	// SessionReloginRequired means the refresh token was rejected, so only a new login helps
+	SessionReloginRequired SessionState = "relogin_required"
+	// SessionSuspended means the PDS reported the account as taken down, suspended, or
+	// deactivated. Neither a refresh nor a new login helps until the operator resolves it.
+	SessionSuspended SessionState = "suspended"
+)
+
+// SessionStateObserver is told about every change of the session state. err is the
+// failure that caused the change, or nil.
+type SessionStateObserver func(from, to SessionState, err error)
+
+// SessionState returns the current session state and when it was entered
+func (tm *TokenManager) SessionState() (SessionState, time.Time) {
+	tm.stateMutex.Lock()
+	defer tm.stateMutex.Unlock()
+	return tm.state, tm.stateSince
+}
+
+// SetSessionStateObserver registers a function that is called on every session state change
+func (tm *TokenManager) SetSessionStateObserver(observer SessionStateObserver) {
+	tm.stateMutex.Lock()
+	defer tm.stateMutex.Unlock()
+	tm.stateObserver = observer
+}
+
+// setState moves the session to a new state, logging the transition. err is the failure
+// that caused it, if any.
+func (tm *TokenManager) setState(to SessionState, err error) {
+	tm.stateMutex.Lock()
+	from := tm.state
+	if from == to {
+		tm.stateMutex.Unlock()
+		return
+	}
+	tm.state = to
+	tm.stateSince = tm.clock.Now()
+	observer := tm.stateObserver
+	tm.stateMutex.Unlock()
+
+	attrs := []any{"from", from, "to", to}
+	if err != nil {
+		attrs = append(attrs, "error", redact.Error(err))
+	}
+	switch to {
+	case SessionSuspended:
+		tm.logger.Error("セッションの状態が変わりました", attrs...)
+	case SessionExpired, SessionReloginRequired:
+		tm.logger.Warn("セッションの状態が変わりました", attrs...)
+	default:
+		tm.logger.Info("セッションの状態が変わりました", attrs...)
+	}
+	if observer != nil {
+		observer(from, to, err)
+	}
+}
+
+// initialState is the state before the first refresh: unauthenticated without tokens,
+// otherwise decided by the access token's expiry
+func (tm *TokenManager) initialState() SessionState {
+	if tm.currentOAuthSession() == nil {
+		if tm.oauthClient != nil {
+			return SessionUnauthenticated
+		}
+		tm.encryptedTokensMutex.RLock()
+		hasTokens := tm.cfg.RefreshJWT != ""
+		tm.encryptedTokensMutex.RUnlock()
+		if !hasTokens {
+			return SessionUnauthenticated
+		}
+	}
+	return tm.stateForExpiry()
+}
+
+// stateForExpiry returns the state the access token's expiry calls for.
+// Tokens without a known expiry are assumed to be valid.
+func (tm *TokenManager) stateForExpiry() SessionState {
+	expiry, ok := tm.AccessTokenExpiry()
+	if !ok {
+		return SessionActive
+	}
+	switch remaining := expiry.Sub(tm.clock.Now()); {
+	case remaining <= 0:
+		return SessionExpired
+	case remaining <= tm.cfg.TokenRefreshMargin:
+		return SessionNeedsRefresh
+	default:
+		return SessionActive
+	}
+}
+
+// refreshFailed moves the session after a failed refresh: to relogin_required when the
+// refresh token itself was rejected, to expired when the access token has run out meanwhile.
+// Other failures, such as network errors, leave the state as it is so that the refresh is retried.
+func (tm *TokenManager) refreshFailed(err error) {
+	switch {
+	case errors.Is(err, domain.ErrAccountSuspended):
+		tm.setState(SessionSuspended, err)
+	case errors.Is(err, domain.ErrAuthExpired):
+		tm.setState(SessionReloginRequired, err)
+	case tm.stateForExpiry() == SessionExpired:
+		tm.setState(SessionExpired, err)
+	}
+}
+
+// canRelogin reports whether the TokenManager can log in again by itself, i.e. it has the
+// handle and app password. OAuth sessions need the operator to authorize again.
+func (tm *TokenManager) canRelogin() bool {
+	return tm.oauthClient == nil && tm.cfg.Handle != "" && tm.cfg.AppPassword != ""
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestTokenManager_SessionState(t *testing.T) {
+	tests := []struct {
+		name        string
+		refresh     int // 2回目以降のリフレッシュへの応答のステータス
+		appPassword string
+		unauthorize bool // リフレッシュの前に401を受けたことにする
+		wantErr     bool
+		want        []SessionState // 状態の変化（初期化時の active の後）
+		wantLogins  int32
+	}{
+		{
+			name:    "正常系: リフレッシュに成功すれば active のまま",
+			refresh: http.StatusOK,
+			want:    nil,
+		},
+		{
+			name:        "正常系: 401を受けると expired になり、リフレッシュで active に戻る",
+			refresh:     http.StatusOK,
+			unauthorize: true,
+			want:        []SessionState{SessionExpired, SessionActive},
+		},
+		{
+			name:    "異常系: ネットワークなどの失敗では状態を変えない",
+			refresh: http.StatusInternalServerError,
+			wantErr: true,
+			want:    nil,
+		},
+		{
+			name:    "異常系: リフレッシュトークンが拒否されると relogin_required になる",
+			refresh: http.StatusBadRequest,
+			wantErr: true,
+			want:    []SessionState{SessionReloginRequired},
+		},
+		{
+			name:        "正常系: アプリパスワードがあればログインし直す",
+			refresh:     http.StatusBadRequest,
+			appPassword: "app-password",
+			want:        nil,
+			wantLogins:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refreshStatus atomic.Int32
+			var logins atomic.Int32
+			refreshStatus.Store(http.StatusOK)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/xrpc/" + NSIDRefreshSession:
+					if status := int(refreshStatus.Load()); status != http.StatusOK {
+						w.WriteHeader(status)
+						json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken"})
+						return
+					}
+				case "/xrpc/" + NSIDCreateSession:
+					logins.Add(1)
+				default:
+					http.NotFound(w, r)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]string{"accessJwt": "new-token", "refreshJwt": "new-refresh-token", "did": "did:plc:test"})
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				AccessJWT:            "access-token",
+				RefreshJWT:           "refresh-token",
+				DID:                  "did:plc:test",
+				Handle:               "bot.example.com",
+				AppPassword:          tt.appPassword,
+				PDSURL:               server.URL,
+				HTTPTimeout:          3 * time.Second,
+				TokenRefreshInterval: time.Hour,
+			}
+			tm := NewTokenManager(cfg, NewTokenEncryptor(), newTestHTTPClient(t, cfg))
+			defer tm.Shutdown()
+			if state, _ := tm.SessionState(); state != SessionActive {
+				t.Fatalf("initial state = %s, want %s", state, SessionActive)
+			}
+
+			var mu sync.Mutex
+			var got []SessionState
+			tm.SetSessionStateObserver(func(from, to SessionState, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, to)
+			})
+			refreshStatus.Store(int32(tt.refresh))
+
+			var err error
+			if tt.unauthorize {
+				err = tm.HandleUnauthorized(context.Background(), server.URL, &HTTPError{StatusCode: http.StatusUnauthorized})
+			} else {
+				err = tm.RefreshToken(context.Background())
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("refresh error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("transitions = %v, want %v", got, tt.want)
+			}
+			if logins.Load() != tt.wantLogins {
+				t.Errorf("logins = %d, want %d", logins.Load(), tt.wantLogins)
+			}
+			if state, _ := tm.SessionState(); state == SessionReloginRequired && tm.nextRefreshDelay() != cfg.TokenRefreshInterval {
+				t.Errorf("nextRefreshDelay() = %v, want %v while relogin is required", tm.nextRefreshDelay(), cfg.TokenRefreshInterval)
+			}
+		})
+	}
+}
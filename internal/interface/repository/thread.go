@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// PublishThread posts a thread in a single applyWrites, so that either all of its posts are
+// created or none are. The first post gets key as its record key and the replies keys made
+// from it; a thread whose first post already exists is returned as that post alone.
+func (r *BlueskyRepository) PublishThread(ctx context.Context, key string, posts []usecase.ThreadPost) ([]*domain.PostRef, error) {
+	if len(posts) == 0 {
+		return nil, nil
+	}
+	if err := r.tokenManager.EnsureFreshToken(ctx); err != nil {
+		r.logger.Warn("Proactive token refresh failed, trying the current token", "error", redact.Error(err))
+	}
+
+	now := r.clock.Now()
+	writes := make([]ApplyWrite, len(posts))
+	refs := make([]StrongRef, len(posts))
+	for i, post := range posts {
+		input, err := r.BuildRecord(post.Text, now)
+		if err != nil {
+			return nil, err
+		}
+		setLangs(input, post.Langs)
+		record := input.Record.(FeedPost)
+		rkey := key
+		if i > 0 {
+			rkey = domain.NewPostKey(now.Add(time.Duration(i)*time.Microsecond), key)
+			record.Reply = &ReplyRef{Root: refs[0], Parent: refs[i-1]}
+		}
+		// The replies refer to the earlier posts before they are created
+		cid, err := recordCID(record)
+		if err != nil {
+			return nil, err
+		}
+		refs[i] = StrongRef{URI: fmt.Sprintf("at://%s/%s/%s", r.cfg.DID, CollectionFeedPost, rkey), CID: cid}
+		writes[i] = ApplyWrite{Type: ApplyWritesCreate, Collection: CollectionFeedPost, Rkey: rkey, Value: record}
+	}
+
+	var output *ApplyWritesOutput
+	err := r.client.Do(ctx, http.MethodPost, NSIDApplyWrites, func(headers map[string]string) (err error) {
+		output, err = r.xrpc.ApplyWrites(ctx, ApplyWritesInput{Repo: r.cfg.DID, Writes: writes}, headers)
+		return err
+	})
+	if err != nil {
+		// applyWrites fails for a record key that is already taken
+		if existing, findErr := r.FindPost(ctx, key); findErr == nil && existing != nil {
+			r.logger.Info("Post with this idempotency key already exists", "key", key, "uri", existing.URI)
+			return []*domain.PostRef{existing}, nil
+		}
+		return nil, fmt.Errorf("failed to post thread: %w", err)
+	}
+
+	result := make([]*domain.PostRef, len(refs))
+	for i, ref := range refs {
+		// Older PDS versions return no results; the computed CIDs are then the best there is
+		if len(output.Results) == len(refs) && output.Results[i].CID != ref.CID {
+			r.logger.Warn("PDS computed a different CID for a thread post, replies to it may not show in the thread",
+				"uri", ref.URI, "cid", ref.CID, "pds_cid", output.Results[i].CID)
+			ref.CID = output.Results[i].CID
+		}
+		result[i] = blueskyPostRef(ref.URI, ref.CID)
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+func TestBlueskyRepository_PublishThread(t *testing.T) {
+	var requests int
+	var writes []ApplyWrite
+	repo := newProfileTestRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/"+NSIDApplyWrites {
+			return
+		}
+		requests++
+		var input struct {
+			Writes []struct {
+				ApplyWrite
+				Value map[string]interface{} `json:"value"`
+			} `json:"writes"`
+		}
+		json.NewDecoder(r.Body).Decode(&input)
+		// 投稿先と同じく、受け取ったレコードからCIDを計算して返す
+		var output ApplyWritesOutput
+		for _, write := range input.Writes {
+			cid, err := recordCID(write.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			write.ApplyWrite.Value = write.Value
+			writes = append(writes, write.ApplyWrite)
+			output.Results = append(output.Results, CreateRecordOutput{URI: "at://did:plc:test/app.bsky.feed.post/" + write.Rkey, CID: cid})
+		}
+		json.NewEncoder(w).Encode(output)
+	})
+
+	key := domain.NewPostKey(time.Now(), "0123456789abcdef")
+	refs, err := repo.PublishThread(context.Background(), key, []usecase.ThreadPost{
+		{Text: "知は力なり\n- ベーコン #名言", Langs: []string{"ja"}},
+		{Text: "Knowledge is power\n- Bacon", Langs: []string{"en"}},
+		{Text: "Scientia potentia est"},
+	})
+	if err != nil {
+		t.Fatalf("PublishThread() error = %v", err)
+	}
+
+	// スレッドは1回のリクエストで作る
+	if requests != 1 || len(writes) != 3 || len(refs) != 3 {
+		t.Fatalf("requests = %d, writes = %d, refs = %d, want 1 request with 3 writes", requests, len(writes), len(refs))
+	}
+	if writes[0].Rkey != key || refs[0].ID != key {
+		t.Errorf("first post key = %s, ref = %+v, want %s", writes[0].Rkey, refs[0], key)
+	}
+	// 返信は、投稿先が計算したのと同じCIDで前の投稿を参照する
+	for i := 1; i < len(writes); i++ {
+		reply := writes[i].Value.(map[string]interface{})["reply"].(map[string]interface{})
+		root := reply["root"].(map[string]interface{})
+		parent := reply["parent"].(map[string]interface{})
+		if root["uri"] != refs[0].URI || root["cid"] != refs[0].CID {
+			t.Errorf("post %d root = %v, want %+v", i, root, refs[0])
+		}
+		if parent["uri"] != refs[i-1].URI || parent["cid"] != refs[i-1].CID {
+			t.Errorf("post %d parent = %v, want %+v", i, parent, refs[i-1])
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// restrictedCipherSuites are the TLS 1.2 cipher suites used unless TLS_CIPHER_SUITES=default.
+// TLS 1.3 suites are not configurable in Go and are always enabled.
+var restrictedCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+}
+
+// NewTLSConfig builds the TLS configuration for outgoing connections from the config.
+// A custom CA bundle is added to the system roots, so public PDS instances keep working.
+func NewTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	switch cfg.TLSMinVersion {
+	case "", "1.2":
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported TLS_MIN_VERSION: %s", cfg.TLSMinVersion)
+	}
+
+	switch cfg.TLSCipherSuites {
+	case "", config.TLSCipherSuitesRestricted:
+		tlsConfig.CipherSuites = restrictedCipherSuites
+	case config.TLSCipherSuitesDefault:
+		// Leave CipherSuites nil to use Go's secure defaults
+	default:
+		return nil, fmt.Errorf("unsupported TLS_CIPHER_SUITES: %s", cfg.TLSCipherSuites)
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSClientCertFile != "" || cfg.TLSClientKeyFile != "" {
+		if cfg.TLSClientCertFile == "" || cfg.TLSClientKeyFile == "" {
+			return nil, fmt.Errorf("TLS_CLIENT_CERT_FILE and TLS_CLIENT_KEY_FILE must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLSClientCertFile, cfg.TLSClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// writeSelfSignedCert は自己署名のクライアント証明書と鍵をPEMファイルとして書き出します
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "quotebot-test-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, _ = x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile, cert
+}
+
+func TestHTTPClient_CustomCAAndClientCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeSelfSignedCert(t, dir)
+
+	// クライアント証明書を要求する社内PKI相当のサーバー
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{
+			name: "正常系: カスタムCAとクライアント証明書で接続できる",
+			cfg: &config.Config{
+				TLSCAFile:         caFile,
+				TLSClientCertFile: certFile,
+				TLSClientKeyFile:  keyFile,
+			},
+			wantErr: false,
+		},
+		{
+			name: "異常系: カスタムCAがないと証明書を検証できない",
+			cfg: &config.Config{
+				TLSClientCertFile: certFile,
+				TLSClientKeyFile:  keyFile,
+			},
+			wantErr: true,
+		},
+		{
+			name: "異常系: クライアント証明書がないとサーバーに拒否される",
+			cfg: &config.Config{
+				TLSCAFile: caFile,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.HTTPTimeout = 3 * time.Second
+			client := newTestHTTPClient(t, tt.cfg)
+
+			resp, err := client.DoRequest(context.Background(), "GET", server.URL, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DoRequest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeSelfSignedCert(t, dir)
+	invalidCA := filepath.Join(dir, "invalid.pem")
+	os.WriteFile(invalidCA, []byte("not a certificate"), 0o600)
+
+	tests := []struct {
+		name            string
+		cfg             *config.Config
+		wantMinVersion  uint16
+		wantRestricted  bool
+		wantClientCerts int
+		wantErr         bool
+	}{
+		{
+			name:           "正常系: デフォルトは限定された暗号スイート",
+			cfg:            &config.Config{},
+			wantMinVersion: tls.VersionTLS12,
+			wantRestricted: true,
+		},
+		{
+			name:            "正常系: Goの標準の暗号スイートとTLS 1.3",
+			cfg:             &config.Config{TLSCipherSuites: "default", TLSMinVersion: "1.3", TLSClientCertFile: certFile, TLSClientKeyFile: keyFile},
+			wantMinVersion:  tls.VersionTLS13,
+			wantRestricted:  false,
+			wantClientCerts: 1,
+		},
+		{
+			name:    "異常系: 証明書を含まないCAファイル",
+			cfg:     &config.Config{TLSCAFile: invalidCA},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 存在しないCAファイル",
+			cfg:     &config.Config{TLSCAFile: filepath.Join(dir, "missing.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: クライアント鍵のみ指定",
+			cfg:     &config.Config{TLSClientKeyFile: keyFile},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 不明な暗号スイート指定",
+			cfg:     &config.Config{TLSCipherSuites: "weak"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTLSConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.MinVersion != tt.wantMinVersion {
+				t.Errorf("MinVersion = %x, want %x", got.MinVersion, tt.wantMinVersion)
+			}
+			if (got.CipherSuites != nil) != tt.wantRestricted {
+				t.Errorf("CipherSuites = %v, want restricted %v", got.CipherSuites, tt.wantRestricted)
+			}
+			if len(got.Certificates) != tt.wantClientCerts {
+				t.Errorf("len(Certificates) = %d, want %d", len(got.Certificates), tt.wantClientCerts)
+			}
+		})
+	}
+}
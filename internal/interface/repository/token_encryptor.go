@@ -3,114 +3,219 @@ package repository
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/pbkdf2"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"io"
-	"log"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/logging"
 )
 
+// tokenEncryptionSalt is the fixed KDF salt prefix for passphrase-derived encryption keys.
+// The DID is appended so that accounts sharing a passphrase still get distinct keys.
+const tokenEncryptionSalt = "quotebot/token-encryption/v1/"
+
+// encryptedPrefix marks values in the versioned envelope format "enc:v1:<base64(nonce||ciphertext)>".
+// The prefix is also bound to the ciphertext as GCM additional data, so it can't be swapped.
+const encryptedPrefix = "enc:v1:"
+
 // TokenEncryptor handles encryption and decryption of tokens
 type TokenEncryptor struct {
-	encryptKey []byte
-	aesBlock   cipher.Block
+	// aeads holds one cipher per key; the first entry is the primary key used for
+	// encryption, the rest are previous keys that are still accepted for decryption
+	aeads []cipher.AEAD
 }
 
-// NewTokenEncryptor creates a new TokenEncryptor instance
+// NewTokenEncryptor creates a new TokenEncryptor instance with a random, per-process key.
+// Values encrypted with it cannot be decrypted after a restart.
 func NewTokenEncryptor() *TokenEncryptor {
-	// Generate encryption key
+	// crypto/rand.Read never fails; it crashes the program instead of returning weak randomness
 	encryptKey := make([]byte, DefaultKeySize)
-	if _, err := rand.Read(encryptKey); err != nil {
-		log.Printf("Warning: failed to generate secure encryption key: %v", err)
-		// Use a fallback mechanism to ensure we have a key, but log a warning
-		for i := range encryptKey {
-			encryptKey[i] = byte(i)
-		}
-	}
+	rand.Read(encryptKey)
 
-	block, err := aes.NewCipher(encryptKey)
+	te, err := NewTokenEncryptorWithKeys(encryptKey)
 	if err != nil {
-		log.Printf("Warning: failed to initialize AES cipher: %v", err)
+		// Unreachable: the key always has a valid AES size
+		panic(err)
 	}
+	return te
+}
 
-	return &TokenEncryptor{
-		encryptKey: encryptKey,
-		aesBlock:   block,
+// NewTokenEncryptorWithKeys creates a TokenEncryptor from explicit keys.
+// The primary key encrypts; previous keys are only used to decrypt values during key rotation.
+func NewTokenEncryptorWithKeys(primary []byte, previous ...[]byte) (*TokenEncryptor, error) {
+	te := &TokenEncryptor{}
+	for i, key := range append([][]byte{primary}, previous...) {
+		if len(key) != DefaultKeySize {
+			return nil, fmt.Errorf("encryption key %d must be %d bytes, got %d", i, DefaultKeySize, len(key))
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		aesGCM, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCM: %w", err)
+		}
+		te.aeads = append(te.aeads, aesGCM)
 	}
+	return te, nil
 }
 
-// Encrypt encrypts a string using AES-GCM
-func (te *TokenEncryptor) Encrypt(plaintext string) (string, error) {
-	var block cipher.Block
-	var err error
+// NewTokenEncryptorFromConfig creates a TokenEncryptor with the key configured via
+// TOKEN_ENCRYPTION_KEY or TOKEN_ENCRYPTION_PASSPHRASE. Without either, a random key is used.
+func NewTokenEncryptorFromConfig(cfg *config.Config) (*TokenEncryptor, error) {
+	var primary []byte
+	switch {
+	case cfg.TokenEncryptionKey != "":
+		key, err := decodeEncryptionKey(cfg.TokenEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOKEN_ENCRYPTION_KEY: %w", err)
+		}
+		primary = key
+	case cfg.TokenEncryptionPassphrase != "":
+		key, err := pbkdf2.Key(sha256.New, cfg.TokenEncryptionPassphrase, []byte(tokenEncryptionSalt+cfg.DID), TokenFileKDFIterations, DefaultKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+		}
+		primary = key
+	default:
+		logging.ModuleFor(cfg, "token").Info("TOKEN_ENCRYPTION_KEY が未設定のため、プロセスごとのランダムな鍵でトークンを暗号化します")
+		return NewTokenEncryptor(), nil
+	}
 
-	// Use pre-initialized AES block if available
-	if te.aesBlock != nil {
-		block = te.aesBlock
-	} else {
-		// Fallback: create a new block if needed
-		block, err = aes.NewCipher(te.encryptKey)
+	var previous [][]byte
+	for _, encoded := range cfg.TokenEncryptionPreviousKeys {
+		key, err := decodeEncryptionKey(encoded)
 		if err != nil {
-			return "", fmt.Errorf("failed to create cipher: %w", err)
+			return nil, fmt.Errorf("invalid TOKEN_ENCRYPTION_PREVIOUS_KEYS entry: %w", err)
 		}
+		previous = append(previous, key)
 	}
 
-	aesGCM, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+	return NewTokenEncryptorWithKeys(primary, previous...)
+}
+
+// decodeEncryptionKey decodes a base64 (standard or URL-safe, padded or not) encryption key
+func decodeEncryptionKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(encoded); err == nil {
+			if len(key) != DefaultKeySize {
+				return nil, fmt.Errorf("key must be %d bytes, got %d", DefaultKeySize, len(key))
+			}
+			return key, nil
+		}
 	}
+	return nil, fmt.Errorf("key is not valid base64")
+}
+
+// Encrypt encrypts a string using AES-GCM with the primary key and wraps it in the envelope format
+func (te *TokenEncryptor) Encrypt(plaintext string) (string, error) {
+	aesGCM := te.aeads[0]
 
 	nonce := make([]byte, aesGCM.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	ciphertext := aesGCM.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	ciphertext := aesGCM.Seal(nonce, nonce, []byte(plaintext), []byte(encryptedPrefix))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt decrypts a string using AES-GCM
+// Decrypt decrypts a string using AES-GCM, trying the primary key first and then previous keys.
+// Values in the legacy unversioned format (bare base64) are still accepted.
 func (te *TokenEncryptor) Decrypt(encryptedString string) (string, error) {
-	ciphertext, err := base64.StdEncoding.DecodeString(encryptedString)
+	plaintext, _, err := te.decrypt(encryptedString)
+	return plaintext, err
+}
+
+// ReEncrypt re-encrypts a value with the primary key and the current envelope format if it
+// was encrypted with a previous key or in the legacy format.
+// The returned bool reports whether the value changed.
+func (te *TokenEncryptor) ReEncrypt(encryptedString string) (string, bool, error) {
+	plaintext, keyIndex, err := te.decrypt(encryptedString)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode base64: %w", err)
+		return "", false, err
+	}
+	if keyIndex == 0 && te.IsEncrypted(encryptedString) {
+		return encryptedString, false, nil
 	}
 
-	var block cipher.Block
+	reencrypted, err := te.Encrypt(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return reencrypted, true, nil
+}
 
-	// Use pre-initialized AES block if available
-	if te.aesBlock != nil {
-		block = te.aesBlock
-	} else {
-		// Fallback: create a new block if needed
-		block, err = aes.NewCipher(te.encryptKey)
-		if err != nil {
-			return "", fmt.Errorf("failed to create cipher: %w", err)
-		}
+// EncryptIfNeeded returns value in the current encrypted format.
+// Encrypted values (including legacy ones that authenticate with a known key) are migrated
+// with ReEncrypt; anything else is treated as plaintext, encrypted, and also returned as plaintext.
+func (te *TokenEncryptor) EncryptIfNeeded(value string) (encrypted string, plaintext string, err error) {
+	if te.IsEncrypted(value) {
+		encrypted, _, err = te.ReEncrypt(value)
+		return encrypted, "", err
+	}
+
+	// A legacy value only counts as encrypted if it actually decrypts, so real
+	// tokens that happen to be valid base64 are never mistaken for ciphertext
+	if _, _, legacyErr := te.decrypt(value); legacyErr == nil {
+		encrypted, _, err = te.ReEncrypt(value)
+		return encrypted, "", err
 	}
 
-	aesGCM, err := cipher.NewGCM(block)
+	encrypted, err = te.Encrypt(value)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return "", "", err
 	}
+	return encrypted, value, nil
+}
 
-	nonceSize := aesGCM.NonceSize()
-	if len(ciphertext) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
+// decrypt decrypts a value and returns the index of the key that succeeded
+func (te *TokenEncryptor) decrypt(encryptedString string) (string, int, error) {
+	encoded, additionalData := encryptedString, []byte(encryptedPrefix)
+	if strings.HasPrefix(encryptedString, encryptedPrefix) {
+		encoded = strings.TrimPrefix(encryptedString, encryptedPrefix)
+	} else {
+		// Legacy format without a version prefix or additional data
+		additionalData = nil
 	}
 
-	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt: %w", err)
+		return "", 0, fmt.Errorf("failed to decode base64: %w", err)
 	}
 
-	return string(plaintext), nil
+	var lastErr error
+	for i, aesGCM := range te.aeads {
+		nonceSize := aesGCM.NonceSize()
+		if len(ciphertext) < nonceSize+aesGCM.Overhead() {
+			return "", 0, fmt.Errorf("ciphertext too short")
+		}
+
+		nonce, sealed := ciphertext[:nonceSize], ciphertext[nonceSize:]
+		plaintext, err := aesGCM.Open(nil, nonce, sealed, additionalData)
+		if err == nil {
+			return string(plaintext), i, nil
+		}
+		lastErr = err
+	}
+
+	return "", 0, fmt.Errorf("failed to decrypt: %w", lastErr)
 }
 
-// IsEncrypted attempts to determine if a string is already encrypted
-// by trying to decode it as base64
+// IsEncrypted reports whether a string is in the versioned encrypted envelope format.
+// Use Decrypt to verify that it actually authenticates with a known key.
 func (te *TokenEncryptor) IsEncrypted(text string) bool {
-	_, err := base64.StdEncoding.DecodeString(text)
+	if !strings.HasPrefix(text, encryptedPrefix) {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(text, encryptedPrefix))
 	return err == nil
 }
@@ -1,7 +1,15 @@
 package repository
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
 )
 
 func TestTokenEncryptor_EncryptDecrypt(t *testing.T) {
@@ -103,38 +111,52 @@ func TestTokenEncryptor_DecryptInvalid(t *testing.T) {
 }
 
 func TestTokenEncryptor_IsEncrypted(t *testing.T) {
+	// 暗号化器の作成
+	encryptor := NewTokenEncryptor()
+	encrypted, err := encryptor.Encrypt("test-token")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
 	tests := []struct {
 		name string
 		text string
 		want bool
 	}{
 		{
-			name: "正常系: Base64エンコードされた文字列",
-			text: "aGVsbG8gd29ybGQ=", // "hello world" in Base64
+			name: "正常系: 暗号化されたエンベロープ形式",
+			text: encrypted,
 			want: true,
 		},
+		{
+			name: "正常系: プレフィックスのないBase64文字列は暗号化済みとみなさない",
+			text: "aGVsbG8gd29ybGQ=", // "hello world" in Base64
+			want: false,
+		},
 		{
 			name: "正常系: 通常のテキスト（Base64ではない）",
 			text: "hello world",
 			want: false,
 		},
 		{
-			name: "正常系: 空文字列",
+			name: "正常系: 空文字列は暗号化済みとみなさない",
 			text: "",
-			want: true, // 空文字列はBase64としても有効
+			want: false,
+		},
+		{
+			name: "正常系: JWT",
+			text: makeTestJWT(time.Now()),
+			want: false,
 		},
 		{
-			name: "正常系: Base64として無効な文字を含む",
-			text: "aGVsbG8!=",
+			name: "異常系: プレフィックスのみでBase64として無効",
+			text: "enc:v1:aGVsbG8!=",
 			want: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// 暗号化器の作成
-			encryptor := NewTokenEncryptor()
-
 			// IsEncryptedの呼び出し
 			got := encryptor.IsEncrypted(tt.text)
 
@@ -146,6 +168,93 @@ func TestTokenEncryptor_IsEncrypted(t *testing.T) {
 	}
 }
 
+func TestTokenEncryptor_EncryptIfNeeded(t *testing.T) {
+	key := bytes.Repeat([]byte{0x03}, DefaultKeySize)
+	encryptor, err := NewTokenEncryptorWithKeys(key)
+	if err != nil {
+		t.Fatalf("NewTokenEncryptorWithKeys() error = %v", err)
+	}
+
+	// 旧形式（プレフィックスなし、追加データなし）の暗号文を作成
+	block, _ := aes.NewCipher(key)
+	aesGCM, _ := cipher.NewGCM(block)
+	nonce := make([]byte, aesGCM.NonceSize())
+	legacy := base64.StdEncoding.EncodeToString(aesGCM.Seal(nonce, nonce, []byte("legacy-token"), nil))
+
+	current, err := encryptor.Encrypt("current-token")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		value         string
+		wantPlaintext string
+		wantDecrypted string
+		wantSame      bool
+	}{
+		{
+			name:          "正常系: 平文は暗号化される",
+			value:         "plain-token",
+			wantPlaintext: "plain-token",
+			wantDecrypted: "plain-token",
+		},
+		{
+			name:          "正常系: Base64として有効な平文も暗号化される",
+			value:         "aGVsbG8gd29ybGQ=",
+			wantPlaintext: "aGVsbG8gd29ybGQ=",
+			wantDecrypted: "aGVsbG8gd29ybGQ=",
+		},
+		{
+			name:          "正常系: 旧形式の暗号文は新形式に移行される",
+			value:         legacy,
+			wantDecrypted: "legacy-token",
+		},
+		{
+			name:          "正常系: 新形式の暗号文はそのまま",
+			value:         current,
+			wantDecrypted: "current-token",
+			wantSame:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, plaintext, err := encryptor.EncryptIfNeeded(tt.value)
+			if err != nil {
+				t.Fatalf("EncryptIfNeeded() error = %v", err)
+			}
+			if plaintext != tt.wantPlaintext {
+				t.Errorf("EncryptIfNeeded() plaintext = %q, want %q", plaintext, tt.wantPlaintext)
+			}
+			if !encryptor.IsEncrypted(encrypted) {
+				t.Errorf("EncryptIfNeeded() = %q, not in envelope format", encrypted)
+			}
+			if (encrypted == tt.value) != tt.wantSame {
+				t.Errorf("EncryptIfNeeded() unchanged = %v, want %v", encrypted == tt.value, tt.wantSame)
+			}
+
+			decrypted, err := encryptor.Decrypt(encrypted)
+			if err != nil || decrypted != tt.wantDecrypted {
+				t.Errorf("Decrypt() = %q, %v, want %q", decrypted, err, tt.wantDecrypted)
+			}
+		})
+	}
+}
+
+func TestTokenEncryptor_TamperedPrefix(t *testing.T) {
+	encryptor := NewTokenEncryptor()
+	encrypted, err := encryptor.Encrypt("test-token")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	// プレフィックスを外した値は追加データが一致しないため復号できない
+	if _, err := encryptor.Decrypt(strings.TrimPrefix(encrypted, encryptedPrefix)); err == nil {
+		t.Errorf("Decrypt() without prefix succeeded, want error")
+	}
+}
+
 func TestTokenEncryptor_MultipleCalls(t *testing.T) {
 	// 暗号化器の作成
 	encryptor := NewTokenEncryptor()
@@ -231,3 +340,110 @@ func TestTokenEncryptor_NewInstanceEachTime(t *testing.T) {
 		t.Errorf("Encryptor1 should not be able to decrypt text encrypted by Encryptor2")
 	}
 }
+
+func TestNewTokenEncryptorFromConfig(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x42}, DefaultKeySize))
+
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		wantErr    bool
+		wantStable bool
+	}{
+		{
+			name:       "正常系: 鍵を直接指定",
+			cfg:        &config.Config{TokenEncryptionKey: key},
+			wantStable: true,
+		},
+		{
+			name:       "正常系: パスフレーズから鍵を導出",
+			cfg:        &config.Config{TokenEncryptionPassphrase: "passphrase", DID: "did:plc:test"},
+			wantStable: true,
+		},
+		{
+			name:       "正常系: 未設定の場合はランダムな鍵",
+			cfg:        &config.Config{},
+			wantStable: false,
+		},
+		{
+			name:    "異常系: 鍵の長さが不正",
+			cfg:     &config.Config{TokenEncryptionKey: base64.StdEncoding.EncodeToString([]byte("short"))},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: Base64ではない鍵",
+			cfg:     &config.Config{TokenEncryptionKey: "not base64!"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := NewTokenEncryptorFromConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewTokenEncryptorFromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// 再起動を想定して別インスタンスで復号できるか確認
+			second, err := NewTokenEncryptorFromConfig(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewTokenEncryptorFromConfig() error = %v", err)
+			}
+
+			encrypted, err := first.Encrypt("test-token")
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			_, err = second.Decrypt(encrypted)
+			if stable := err == nil; stable != tt.wantStable {
+				t.Errorf("decryptable across instances = %v, want %v", stable, tt.wantStable)
+			}
+		})
+	}
+}
+
+func TestTokenEncryptor_KeyRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{0x01}, DefaultKeySize)
+	newKey := bytes.Repeat([]byte{0x02}, DefaultKeySize)
+
+	oldEncryptor, err := NewTokenEncryptorWithKeys(oldKey)
+	if err != nil {
+		t.Fatalf("NewTokenEncryptorWithKeys() error = %v", err)
+	}
+	encrypted, err := oldEncryptor.Encrypt("test-token")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	// 新しい鍵のみでは復号できない
+	newOnly, _ := NewTokenEncryptorWithKeys(newKey)
+	if _, err := newOnly.Decrypt(encrypted); err == nil {
+		t.Errorf("Decrypt() with new key only succeeded, want error")
+	}
+
+	// 以前の鍵を併用すれば復号でき、再暗号化で新しい鍵に移行される
+	rotating, err := NewTokenEncryptorWithKeys(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewTokenEncryptorWithKeys() error = %v", err)
+	}
+	reencrypted, changed, err := rotating.ReEncrypt(encrypted)
+	if err != nil {
+		t.Fatalf("ReEncrypt() error = %v", err)
+	}
+	if !changed {
+		t.Errorf("ReEncrypt() changed = false, want true")
+	}
+
+	got, err := newOnly.Decrypt(reencrypted)
+	if err != nil || got != "test-token" {
+		t.Errorf("Decrypt() after rotation = %v, %v, want test-token", got, err)
+	}
+
+	// 現在の鍵で暗号化済みの値は変更されない
+	if _, changed, _ := rotating.ReEncrypt(reencrypted); changed {
+		t.Errorf("ReEncrypt() of current value changed = true, want false")
+	}
+}
@@ -2,12 +2,18 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
 )
 
 // TokenType defines the type of token
@@ -25,81 +31,225 @@ type TokenManager struct {
 	cfg                  *config.Config
 	encryptor            *TokenEncryptor
 	httpClient           *HTTPClient
+	xrpc                 *XRPCClient
 	cachedAccessToken    string
 	cachedRefreshToken   string
 	encryptedTokensMutex sync.RWMutex // Protects encrypted token storage in config
 	cachedTokensMutex    sync.RWMutex // Protects decrypted token cache
-	refreshTick          *time.Ticker
+	refreshMutex         sync.Mutex   // Serializes refreshSession calls
+	refreshTimer         clock.Timer  // Reset by on-demand refreshes too, see RefreshToken
+	lastRefresh          time.Time    // Time of the last successful refresh or login, protected by lastRefreshMutex
+	lastRefreshMutex     sync.RWMutex
+	clock                clock.Clock
+	store                TokenStore    // Optional; persists tokens across restarts
+	oauthClient          *OAuthClient  // Set when AUTH_MODE=oauth
+	oauthSession         *OAuthSession // Current OAuth session, protected by oauthMutex
+	dpopKey              *DPoPKey      // Key the OAuth tokens are bound to
+	oauthMutex           sync.RWMutex
+	refreshObserver      func(error) // Optional; notified of every refresh result, protected by refreshMutex
+	logger               *slog.Logger
 	Done                 chan struct{}
+	stopped              chan struct{} // Closed when backgroundTokenRefresh returns
+	shutdownOnce         sync.Once
+
+	// Session state machine, see SessionState
+	stateMutex    sync.Mutex
+	state         SessionState
+	stateSince    time.Time
+	stateObserver SessionStateObserver
+}
+
+// TokenManagerOption customizes a TokenManager
+type TokenManagerOption func(*TokenManager)
+
+// WithClock makes the TokenManager schedule background refreshes and stamp saved tokens
+// with c instead of the real clock, e.g. a clock.Fake in tests
+func WithClock(c clock.Clock) TokenManagerOption {
+	return func(tm *TokenManager) {
+		tm.clock = c
+	}
 }
 
 // NewTokenManager creates a new TokenManager instance
-func NewTokenManager(cfg *config.Config, encryptor *TokenEncryptor, httpClient *HTTPClient) *TokenManager {
+func NewTokenManager(cfg *config.Config, encryptor *TokenEncryptor, httpClient *HTTPClient, opts ...TokenManagerOption) *TokenManager {
 	tm := &TokenManager{
 		cfg:        cfg,
 		encryptor:  encryptor,
 		httpClient: httpClient,
+		xrpc:       newConfiguredXRPCClient(cfg, httpClient),
+		clock:      clock.Real,
+		logger:     logging.ModuleFor(cfg, "token"),
 		Done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(tm)
+	}
+	if cfg.AuthMode == config.AuthModeOAuth {
+		tm.oauthClient = NewOAuthClient(cfg, httpClient)
+	}
+
+	// Load tokens saved by a previous run, if a token store is configured
+	store, err := NewTokenStore(cfg)
+	if err != nil {
+		tm.logger.Warn("Could not set up token store", "error", redact.Error(err))
+	}
+	if store != nil {
+		tm.store = store
+		if err := tm.loadStoredTokens(); err != nil {
+			tm.logger.Warn("Could not load stored tokens", "error", redact.Error(err))
+		}
 	}
 
+	hasSession := tm.cfg.RefreshJWT != ""
+
 	// Encrypt initial tokens if they're not already encrypted
 	if err := tm.encryptTokensIfNeeded(); err != nil {
-		log.Printf("Warning: could not encrypt tokens: %v", err)
+		tm.logger.Warn("Could not encrypt tokens", "error", redact.Error(err))
 	}
 
+	tm.state = tm.initialState()
+	tm.stateSince = tm.clock.Now()
+	tm.logger.Info("セッションの状態", "state", tm.state)
+
 	// 初期化時に明示的にトークンリフレッシュを試みる
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
 	defer cancel()
 
-	log.Println("TokenManager初期化時にトークンリフレッシュを試みます...")
-	if err := tm.RefreshToken(ctx); err != nil {
-		log.Printf("初期トークンリフレッシュに失敗しましたが、処理を続行します: %v", err)
-	} else {
-		log.Println("初期トークンリフレッシュに成功しました")
+	switch {
+	case tm.oauthClient != nil && tm.currentOAuthSession() == nil:
+		tm.logger.Warn("OAuthセッションがありません。`quotebot oauth-login` を実行してください")
+	case tm.oauthClient == nil && !hasSession && cfg.Handle != "" && cfg.AppPassword != "":
+		// No session yet: log in with the app password
+		tm.logger.Info("トークンがないため、アプリパスワードでセッションを作成します")
+		if err := tm.CreateSession(ctx); err != nil {
+			tm.logger.Error("セッションの作成に失敗しましたが、処理を続行します", "error", redact.Error(err))
+		} else {
+			tm.logger.Info("セッションの作成に成功しました")
+		}
+	default:
+		tm.logger.Info("TokenManager初期化時にトークンリフレッシュを試みます")
+		if err := tm.RefreshToken(ctx); err != nil {
+			tm.logger.Error("初期トークンリフレッシュに失敗しましたが、処理を続行します", "error", redact.Error(err))
+		} else {
+			tm.logger.Info("初期トークンリフレッシュに成功しました")
+		}
 	}
 
 	// Start background token refresh
-	tm.refreshTick = time.NewTicker(cfg.TokenRefreshInterval)
-	log.Printf("バックグラウンドトークンリフレッシュを開始します（間隔: %v）", cfg.TokenRefreshInterval)
-	go tm.backgroundTokenRefresh()
+	delay := tm.nextRefreshDelay()
+	tm.refreshTimer = tm.clock.NewTimer(delay)
+	tm.logger.Info("バックグラウンドトークンリフレッシュを開始します", "next_refresh_in", delay)
+	restarted := false
+	safego.Go(tm.logger, "token_refresh", func() {
+		defer close(tm.stopped)
+		safego.Loop(tm.logger, "token_refresh", tm.Done, func() {
+			if restarted {
+				// The refresh that panicked did not schedule the next one
+				tm.refreshTimer.Stop()
+				tm.refreshTimer.Reset(tm.nextRefreshDelay())
+			}
+			restarted = true
+			tm.backgroundTokenRefresh()
+		})
+	})
 
 	return tm
 }
 
-// encryptTokensIfNeeded encrypts the access and refresh tokens if they are not already encrypted
-func (tm *TokenManager) encryptTokensIfNeeded() error {
-	// Make a copy of the original tokens
-	accessJWT := tm.cfg.AccessJWT
-	refreshJWT := tm.cfg.RefreshJWT
-
-	// Check if they are already encrypted
-	if tm.encryptor.IsEncrypted(accessJWT) {
-		// Already looks like an encrypted token
+// loadStoredTokens replaces the configured tokens with the ones from the token file,
+// unless the configured access token was issued more recently
+func (tm *TokenManager) loadStoredTokens() error {
+	stored, err := tm.store.Load()
+	if err != nil {
+		return err
+	}
+	if tm.oauthClient != nil {
+		// OAuth tokens are bound to the stored DPoP key, so the configured JWTs can't be used
+		if stored == nil || stored.OAuth == nil {
+			return fmt.Errorf("no OAuth session has been stored")
+		}
+		if err := tm.setOAuthSession(stored.OAuth); err != nil {
+			return err
+		}
+	}
+	if stored == nil || stored.AccessJWT == "" || stored.RefreshJWT == "" {
+		tm.logger.Info("保存済みのトークンがないため、設定のトークンを使用します")
 		return nil
 	}
 
-	// Encrypt tokens
-	encryptedAccessJWT, err := tm.encryptor.Encrypt(accessJWT)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt access token: %w", err)
+	if tm.cfg.AccessJWT != "" && tm.oauthClient == nil {
+		configClaims, configOK := parseJWTClaims(tm.cfg.AccessJWT)
+		storedClaims, storedOK := parseJWTClaims(stored.AccessJWT)
+		if configOK && storedOK && configClaims.Iat > storedClaims.Iat {
+			tm.logger.Info("設定のトークンの方が新しいため、保存済みのトークンは使用しません")
+			return nil
+		}
 	}
 
-	encryptedRefreshJWT, err := tm.encryptor.Encrypt(refreshJWT)
+	tm.logger.Info("保存済みのトークンを読み込みました", "saved_at", stored.SavedAt.Format(time.RFC3339))
+	tm.encryptedTokensMutex.Lock()
+	tm.cfg.AccessJWT = stored.AccessJWT
+	tm.cfg.RefreshJWT = stored.RefreshJWT
+	tm.encryptedTokensMutex.Unlock()
+	return nil
+}
+
+// persistTokens writes the tokens to the token file, if one is configured
+func (tm *TokenManager) persistTokens(accessJWT, refreshJWT string) {
+	if tm.store == nil {
+		return
+	}
+
+	err := tm.store.Save(StoredTokens{
+		AccessJWT:  accessJWT,
+		RefreshJWT: refreshJWT,
+		OAuth:      tm.currentOAuthSession(),
+		SavedAt:    tm.clock.Now(),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to encrypt refresh token: %w", err)
+		tm.logger.Error("トークンの保存に失敗しました", "error", redact.Error(err))
 	}
+}
 
-	// Store encrypted tokens
-	tm.encryptedTokensMutex.Lock()
-	tm.cfg.AccessJWT = encryptedAccessJWT
-	tm.cfg.RefreshJWT = encryptedRefreshJWT
-	tm.encryptedTokensMutex.Unlock()
+// encryptTokensIfNeeded encrypts the configured tokens that are still plaintext, and
+// migrates already encrypted ones to the current envelope format and primary key
+func (tm *TokenManager) encryptTokensIfNeeded() error {
+	tokens := []struct {
+		tokenType TokenType
+		value     *string
+		cached    *string
+	}{
+		{AccessToken, &tm.cfg.AccessJWT, &tm.cachedAccessToken},
+		{RefreshToken, &tm.cfg.RefreshJWT, &tm.cachedRefreshToken},
+	}
 
-	// Cache the decrypted tokens
-	tm.cachedTokensMutex.Lock()
-	tm.cachedAccessToken = accessJWT
-	tm.cachedRefreshToken = refreshJWT
-	tm.cachedTokensMutex.Unlock()
+	for _, token := range tokens {
+		tm.encryptedTokensMutex.RLock()
+		value := *token.value
+		tm.encryptedTokensMutex.RUnlock()
+
+		if value == "" {
+			continue
+		}
+
+		encrypted, plaintext, err := tm.encryptor.EncryptIfNeeded(value)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s token: %w", token.tokenType, err)
+		}
+
+		// Store the encrypted token
+		tm.encryptedTokensMutex.Lock()
+		*token.value = encrypted
+		tm.encryptedTokensMutex.Unlock()
+
+		// Cache the decrypted token if we just encrypted it
+		if plaintext != "" {
+			tm.cachedTokensMutex.Lock()
+			*token.cached = plaintext
+			tm.cachedTokensMutex.Unlock()
+		}
+	}
 
 	return nil
 }
@@ -158,73 +308,392 @@ func (tm *TokenManager) GetToken(tokenType TokenType) (string, error) {
 	return decrypted, nil
 }
 
-// backgroundTokenRefresh runs a background process to periodically refresh tokens
+// backgroundTokenRefresh runs a background process that refreshes tokens shortly before they expire
 func (tm *TokenManager) backgroundTokenRefresh() {
 	for {
 		select {
-		case <-tm.refreshTick.C:
-			log.Printf("バックグラウンドでトークンリフレッシュを開始します（間隔: %v）", tm.cfg.TokenRefreshInterval)
+		case <-tm.refreshTimer.C():
+			tm.logger.Debug("バックグラウンドでトークンリフレッシュを開始します")
 			ctx, cancel := context.WithTimeout(context.Background(), tm.cfg.HTTPTimeout)
-			if err := tm.RefreshToken(ctx); err != nil {
-				log.Printf("バックグラウンドでのトークンリフレッシュに失敗しました: %v", err)
-			} else {
-				log.Println("バックグラウンドでのトークンリフレッシュに成功しました")
+			switch refreshed, err := tm.refreshUnlessRecent(ctx); {
+			case !refreshed:
+				tm.logger.Info("直前にトークンをリフレッシュしたため、予定のリフレッシュを省略します", "last_refresh", tm.LastRefresh())
+			case err != nil:
+				tm.logger.Error("バックグラウンドでのトークンリフレッシュに失敗しました", "error", redact.Error(err))
+			default:
+				tm.logger.Info("バックグラウンドでのトークンリフレッシュに成功しました")
 			}
 			cancel()
+
+			delay := tm.nextRefreshDelay()
+			tm.logger.Info("次回のバックグラウンドトークンリフレッシュを予約しました", "next_refresh_in", delay)
+			tm.refreshTimer.Reset(delay)
 		case <-tm.Done:
-			log.Println("トークンリフレッシュのバックグラウンドタスクを終了します")
-			tm.refreshTick.Stop()
+			tm.logger.Info("トークンリフレッシュのバックグラウンドタスクを終了します")
+			tm.refreshTimer.Stop()
 			return
 		}
 	}
 }
 
-// RefreshToken uses the refresh token to obtain a new access token
+// nextRefreshDelay computes how long to wait before the next background refresh.
+// When the access token carries an exp claim, the refresh is planned TokenRefreshMargin
+// before expiry; otherwise TokenRefreshInterval is used as a fixed interval.
+func (tm *TokenManager) nextRefreshDelay() time.Duration {
+	// Without an app password only the operator can fix a rejected refresh token,
+	// so don't keep hammering the PDS with it
+	// nor keep trying for a suspended account
+	switch state, _ := tm.SessionState(); {
+	case state == SessionReloginRequired && !tm.canRelogin(), state == SessionSuspended:
+		return tm.cfg.TokenRefreshInterval
+	}
+	expiry, ok := tm.AccessTokenExpiry()
+	if !ok {
+		return tm.cfg.TokenRefreshInterval
+	}
+
+	delay := clock.Until(tm.clock, expiry) - tm.cfg.TokenRefreshMargin
+	if delay < MinTokenRefreshDelay {
+		// Avoid a tight loop when the token is already (nearly) expired
+		// and the refresh keeps failing
+		delay = MinTokenRefreshDelay
+	}
+	return delay
+}
+
+// refreshUnlessRecent performs the scheduled refresh, unless a refresh that succeeded since the
+// last one already covers this cycle, e.g. one triggered by a 401 while the timer was firing.
+// It reports whether it refreshed.
+func (tm *TokenManager) refreshUnlessRecent(ctx context.Context) (bool, error) {
+	tm.refreshMutex.Lock()
+	defer tm.refreshMutex.Unlock()
+
+	if last := tm.LastRefresh(); !last.IsZero() && clock.Since(tm.clock, last) < tm.nextRefreshDelay() {
+		return false, nil
+	}
+	tm.markRefreshDue()
+	return true, tm.refresh(ctx)
+}
+
+// LastRefresh returns when the tokens were last refreshed or obtained by logging in,
+// or the zero time before the first success
+func (tm *TokenManager) LastRefresh() time.Time {
+	tm.lastRefreshMutex.RLock()
+	defer tm.lastRefreshMutex.RUnlock()
+	return tm.lastRefresh
+}
+
+// refreshSucceeded records a successful refresh or login. Caller must hold refreshMutex.
+func (tm *TokenManager) refreshSucceeded() {
+	tm.lastRefreshMutex.Lock()
+	tm.lastRefresh = tm.clock.Now()
+	tm.lastRefreshMutex.Unlock()
+	tm.setState(SessionActive, nil)
+}
+
+// rescheduleRefresh restarts the background refresh timer after an on-demand refresh,
+// so that the scheduled one doesn't follow seconds later
+func (tm *TokenManager) rescheduleRefresh() {
+	if tm.refreshTimer == nil {
+		// Still in NewTokenManager, which schedules the first refresh afterwards
+		return
+	}
+	delay := tm.nextRefreshDelay()
+	tm.refreshTimer.Reset(delay)
+	tm.logger.Debug("トークンをリフレッシュしたため、次回のバックグラウンドトークンリフレッシュを予約し直しました", "next_refresh_in", delay)
+}
+
+// CheckRefreshInterval returns an error when TOKEN_REFRESH_INTERVAL is longer than the lifetime
+// of the current access token, which would let the token expire between refreshes whenever the
+// interval is used. It logs the longest safe interval. Tokens without iat and exp pass.
+func (tm *TokenManager) CheckRefreshInterval() error {
+	if tm.oauthClient != nil {
+		// OAuth access tokens are opaque and their expiry is already tracked from expires_in
+		return nil
+	}
+	accessToken, err := tm.GetToken(AccessToken)
+	if err != nil {
+		return nil
+	}
+	return tm.checkRefreshInterval(accessToken)
+}
+
+// checkRefreshInterval compares TOKEN_REFRESH_INTERVAL with the lifetime of accessToken
+func (tm *TokenManager) checkRefreshInterval(accessToken string) error {
+	lifetime, ok := tokenLifetime(accessToken)
+	if !ok || tm.cfg.TokenRefreshInterval <= lifetime {
+		return nil
+	}
+	safe := lifetime - tm.cfg.TokenRefreshMargin
+	if safe < MinTokenRefreshDelay {
+		safe = lifetime / 2
+	}
+	tm.logger.Warn("TOKEN_REFRESH_INTERVAL がアクセストークンの有効期間より長くなっています",
+		"interval", tm.cfg.TokenRefreshInterval, "lifetime", lifetime, "safe_interval", safe)
+	return fmt.Errorf("TOKEN_REFRESH_INTERVAL %s exceeds the access token lifetime of %s; set it to %s or less",
+		tm.cfg.TokenRefreshInterval, lifetime, safe)
+}
+
+// AccessTokenExpiry returns the expiry time of the current access token, if it can be determined
+func (tm *TokenManager) AccessTokenExpiry() (time.Time, bool) {
+	// OAuth access tokens are opaque to clients; use expires_in from the token response
+	if session := tm.currentOAuthSession(); session != nil && !session.ExpiresAt.IsZero() {
+		return session.ExpiresAt, true
+	}
+
+	accessToken, err := tm.GetToken(AccessToken)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return tokenExpiry(accessToken)
+}
+
+// EnsureFreshToken refreshes the tokens if the access token expires within TokenRefreshMargin.
+// Tokens without an exp claim are assumed to be valid.
+func (tm *TokenManager) EnsureFreshToken(ctx context.Context) error {
+	expiry, ok := tm.AccessTokenExpiry()
+	if !ok || clock.Until(tm.clock, expiry) > tm.cfg.TokenRefreshMargin {
+		return nil
+	}
+
+	tm.logger.Info("アクセストークンの有効期限が近いためリフレッシュします", "expires_at", expiry.Format(time.RFC3339))
+	tm.markRefreshDue()
+	return tm.RefreshToken(ctx)
+}
+
+// SetRefreshObserver registers a function that is called with the result of every token refresh,
+// e.g. to alert on repeated failures
+func (tm *TokenManager) SetRefreshObserver(observer func(error)) {
+	tm.refreshMutex.Lock()
+	defer tm.refreshMutex.Unlock()
+	tm.refreshObserver = observer
+}
+
+// RefreshToken uses the refresh token to obtain a new access token. Once the PDS has rejected
+// the refresh token, it logs in again with the app password instead, if one is configured.
+// A successful refresh restarts the background refresh timer.
 func (tm *TokenManager) RefreshToken(ctx context.Context) error {
-	log.Println("トークンのリフレッシュを実行します...")
+	tm.refreshMutex.Lock()
+	defer tm.refreshMutex.Unlock()
+
+	err := tm.refresh(ctx)
+	if err == nil {
+		tm.rescheduleRefresh()
+	}
+	return err
+}
+
+// refresh refreshes or logs in again and reports the result. Caller must hold refreshMutex.
+func (tm *TokenManager) refresh(ctx context.Context) error {
+	err := tm.refreshOrRelogin(ctx)
+	if err == nil {
+		tm.refreshSucceeded()
+	}
+	if tm.refreshObserver != nil {
+		tm.refreshObserver(err)
+	}
+	return err
+}
+
+// refreshOrRelogin refreshes the tokens, or logs in again when the refresh token has been
+// rejected and the app password is available. Caller must hold refreshMutex.
+func (tm *TokenManager) refreshOrRelogin(ctx context.Context) error {
+	if state, _ := tm.SessionState(); state != SessionReloginRequired || !tm.canRelogin() {
+		err := tm.refreshToken(ctx)
+		if err == nil || !errors.Is(err, domain.ErrAuthExpired) || !tm.canRelogin() {
+			if err != nil {
+				tm.refreshFailed(err)
+			}
+			return err
+		}
+	}
+
+	tm.logger.Info("リフレッシュトークンが無効なため、アプリパスワードでログインし直します")
+	if err := tm.createSession(ctx); err != nil {
+		if errors.Is(err, domain.ErrAccountSuspended) {
+			tm.setState(SessionSuspended, err)
+		} else {
+			tm.setState(SessionReloginRequired, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// markRefreshDue moves an active session to needs_refresh (or expired) when its access token
+// is about to run out
+func (tm *TokenManager) markRefreshDue() {
+	if state, _ := tm.SessionState(); state != SessionActive {
+		return
+	}
+	if due := tm.stateForExpiry(); due != SessionActive {
+		tm.setState(due, nil)
+	}
+}
+
+// refreshToken performs the refresh. Caller must hold refreshMutex.
+func (tm *TokenManager) refreshToken(ctx context.Context) error {
+	tm.logger.Debug("トークンのリフレッシュを実行します")
 	// Get the current refresh token
 	refreshToken, err := tm.GetToken(RefreshToken)
 	if err != nil {
 		return fmt.Errorf("failed to get refresh token: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/xrpc/com.atproto.server.refreshSession", tm.cfg.PDSURL)
-
-	headers := map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", refreshToken),
-		"Content-Type":  "application/json",
+	if tm.oauthClient != nil {
+		return tm.refreshOAuthToken(ctx, refreshToken)
 	}
 
-	// Use the HTTP client to make the request
-	resp, err := tm.httpClient.DoRequest(ctx, "POST", url, nil, headers)
+	session, err := tm.xrpc.RefreshSession(ctx, refreshToken)
 	if err != nil {
 		return fmt.Errorf("failed to refresh token: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Parse the response
-	var refreshResp struct {
-		AccessJWT  string `json:"accessJwt"`
-		RefreshJWT string `json:"refreshJwt"`
+	if err := tm.storeTokens(session.AccessJWT, session.RefreshJWT); err != nil {
+		return err
+	}
+
+	tm.logger.Info("新しいトークンの取得とキャッシュが完了しました")
+	return nil
+}
+
+// refreshOAuthToken runs the OAuth refresh_token grant. Caller must hold refreshMutex.
+func (tm *TokenManager) refreshOAuthToken(ctx context.Context, refreshToken string) error {
+	current := tm.currentOAuthSession()
+	if current == nil {
+		return fmt.Errorf("no OAuth session; run `quotebot oauth-login` first")
+	}
+
+	// Refresh a copy so that a failed refresh leaves the current session untouched
+	tm.oauthMutex.RLock()
+	session := *current
+	dpopKey := tm.dpopKey
+	tm.oauthMutex.RUnlock()
+
+	accessToken, newRefreshToken, err := tm.oauthClient.Refresh(ctx, &session, dpopKey, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	tm.oauthMutex.Lock()
+	tm.oauthSession = &session
+	tm.oauthMutex.Unlock()
+
+	if err := tm.storeTokens(accessToken, newRefreshToken); err != nil {
+		return err
+	}
+
+	tm.logger.Info("新しいOAuthトークンの取得とキャッシュが完了しました")
+	return nil
+}
+
+// setOAuthSession installs an OAuth session and its DPoP key
+func (tm *TokenManager) setOAuthSession(session *OAuthSession) error {
+	if tm.cfg.DID != "" && session.DID != tm.cfg.DID {
+		return fmt.Errorf("stored OAuth session belongs to %s, but DID is configured as %s", session.DID, tm.cfg.DID)
+	}
+
+	dpopKey, err := ParseDPoPKey(session.DPoPKey)
+	if err != nil {
+		return err
+	}
+
+	tm.oauthMutex.Lock()
+	tm.oauthSession = session
+	tm.dpopKey = dpopKey
+	tm.oauthMutex.Unlock()
+	return nil
+}
+
+// currentOAuthSession returns the current OAuth session, or nil when not using OAuth
+func (tm *TokenManager) currentOAuthSession() *OAuthSession {
+	tm.oauthMutex.RLock()
+	defer tm.oauthMutex.RUnlock()
+	return tm.oauthSession
+}
+
+// AuthorizationHeaders returns the headers that authenticate a request to the PDS:
+// a Bearer token for app password sessions, or a DPoP-bound token and proof for OAuth
+func (tm *TokenManager) AuthorizationHeaders(method, url string) (map[string]string, error) {
+	accessToken, err := tm.GetToken(AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
 	}
 
-	if err := tm.httpClient.DecodeJSONResponse(resp, &refreshResp); err != nil {
-		return fmt.Errorf("failed to decode refresh response: %w", err)
+	if tm.oauthClient == nil {
+		return map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", accessToken),
+		}, nil
 	}
 
+	tm.oauthMutex.RLock()
+	dpopKey := tm.dpopKey
+	tm.oauthMutex.RUnlock()
+	if dpopKey == nil {
+		return nil, fmt.Errorf("no OAuth session; run `quotebot oauth-login` first")
+	}
+	return tm.oauthClient.ResourceHeaders(dpopKey, method, url, accessToken)
+}
+
+// HandleUnauthorized prepares a retry after the PDS rejected a request with 401.
+// For OAuth, a rejection that only asks for a new DPoP nonce is retried without refreshing.
+func (tm *TokenManager) HandleUnauthorized(ctx context.Context, url string, err error) error {
+	if tm.oauthClient != nil && tm.oauthClient.UpdateNonce(url, err) {
+		return nil
+	}
+	tm.setState(SessionExpired, err)
+	return tm.RefreshToken(ctx)
+}
+
+// CreateSession logs in with the configured handle and app password to obtain new tokens
+func (tm *TokenManager) CreateSession(ctx context.Context) error {
+	tm.refreshMutex.Lock()
+	defer tm.refreshMutex.Unlock()
+
+	if err := tm.createSession(ctx); err != nil {
+		return err
+	}
+	tm.refreshSucceeded()
+	tm.rescheduleRefresh()
+	return nil
+}
+
+// createSession performs the login. Caller must hold refreshMutex.
+func (tm *TokenManager) createSession(ctx context.Context) error {
+	if tm.cfg.Handle == "" || tm.cfg.AppPassword == "" {
+		return fmt.Errorf("handle and app password are required to create a session")
+	}
+
+	session, err := tm.xrpc.CreateSession(ctx, CreateSessionInput{
+		Identifier: tm.cfg.Handle,
+		Password:   tm.cfg.AppPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	if tm.cfg.DID != "" && session.DID != tm.cfg.DID {
+		return fmt.Errorf("session DID %s does not match configured DID %s", session.DID, tm.cfg.DID)
+	}
+
+	return tm.storeTokens(session.AccessJWT, session.RefreshJWT)
+}
+
+// storeTokens caches, encrypts, and persists a new pair of tokens
+func (tm *TokenManager) storeTokens(accessJWT, refreshJWT string) error {
 	// Update the cached tokens
 	tm.cachedTokensMutex.Lock()
-	tm.cachedAccessToken = refreshResp.AccessJWT
-	tm.cachedRefreshToken = refreshResp.RefreshJWT
+	tm.cachedAccessToken = accessJWT
+	tm.cachedRefreshToken = refreshJWT
 	tm.cachedTokensMutex.Unlock()
 
 	// Encrypt and store the new tokens
-	encryptedAccessJWT, err := tm.encryptor.Encrypt(refreshResp.AccessJWT)
+	encryptedAccessJWT, err := tm.encryptor.Encrypt(accessJWT)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt new access token: %w", err)
 	}
 
-	encryptedRefreshJWT, err := tm.encryptor.Encrypt(refreshResp.RefreshJWT)
+	encryptedRefreshJWT, err := tm.encryptor.Encrypt(refreshJWT)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt new refresh token: %w", err)
 	}
@@ -235,11 +704,18 @@ func (tm *TokenManager) RefreshToken(ctx context.Context) error {
 	tm.cfg.RefreshJWT = encryptedRefreshJWT
 	tm.encryptedTokensMutex.Unlock()
 
-	log.Println("新しいトークンの取得とキャッシュが完了しました")
+	// Persist the new tokens so that a restart doesn't need freshly minted JWTs
+	tm.persistTokens(accessJWT, refreshJWT)
+	// The PDS may shorten the lifetime while the bot runs; only warn, as the interval can't be rejected now
+	_ = tm.checkRefreshInterval(accessJWT)
 	return nil
 }
 
-// Shutdown stops the background token refresh process
+// Shutdown stops the background token refresh process and waits for an in-flight
+// refresh to finish. It is safe to call more than once.
 func (tm *TokenManager) Shutdown() {
-	close(tm.Done)
+	tm.shutdownOnce.Do(func() {
+		close(tm.Done)
+	})
+	<-tm.stopped
 }
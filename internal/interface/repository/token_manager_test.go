@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
 )
 
 func TestTokenManager_GetToken(t *testing.T) {
@@ -50,7 +51,7 @@ func TestTokenManager_GetToken(t *testing.T) {
 
 			// 実際のコンポーネントの作成
 			encryptor := NewTokenEncryptor()
-			httpClient := NewHTTPClient(cfg)
+			httpClient := newTestHTTPClient(t, cfg)
 			tm := NewTokenManager(cfg, encryptor, httpClient)
 
 			// トークンの取得
@@ -143,9 +144,13 @@ func TestTokenManager_RefreshToken(t *testing.T) {
 
 			// 実際のコンポーネントの作成
 			encryptor := NewTokenEncryptor()
-			httpClient := NewHTTPClient(cfg)
+			httpClient := newTestHTTPClient(t, cfg)
 			tm := NewTokenManager(cfg, encryptor, httpClient)
 
+			// リフレッシュの結果は登録した関数にも通知される
+			var observed []error
+			tm.SetRefreshObserver(func(err error) { observed = append(observed, err) })
+
 			// トークンの更新
 			ctx := context.Background()
 			err := tm.RefreshToken(ctx)
@@ -155,6 +160,9 @@ func TestTokenManager_RefreshToken(t *testing.T) {
 				t.Errorf("RefreshToken() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if len(observed) != 1 || observed[0] != err {
+				t.Errorf("observed = %v, want [%v]", observed, err)
+			}
 
 			// 成功した場合、トークンが更新されているか確認
 			if err == nil {
@@ -231,15 +239,21 @@ func TestTokenManager_BackgroundRefresh(t *testing.T) {
 		HTTPTimeout:          3 * time.Second,
 	}
 
-	// TokenManagerの作成
+	// TokenManagerの作成。偽の時計で時刻を進めてバックグラウンド更新を発火させる
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
 	encryptor := NewTokenEncryptor()
-	httpClient := NewHTTPClient(cfg)
-	tm := NewTokenManager(cfg, encryptor, httpClient)
+	httpClient := newTestHTTPClient(t, cfg)
+	tm := NewTokenManager(cfg, encryptor, httpClient, WithClock(fake))
 
-	// しばらく待機してバックグラウンド更新が何回か実行されるのを確認
-	time.Sleep(350 * time.Millisecond)
+	// 次の更新を予約するのを待ってから、更新の間隔だけ時刻を進める
+	for range 3 {
+		fake.BlockUntil(1)
+		fake.Advance(cfg.TokenRefreshInterval)
+	}
+	fake.BlockUntil(1)
 
-	// TokenManagerのシャットダウン
+	// TokenManagerのシャットダウン（複数回呼び出しても問題ない）
+	tm.Shutdown()
 	tm.Shutdown()
 
 	// カウンターの取得をミューテックスで保護
@@ -247,8 +261,68 @@ func TestTokenManager_BackgroundRefresh(t *testing.T) {
 	count := refreshCallCount
 	counterMutex.Unlock()
 
-	// 初期化時に1回 + バックグラウンドで3回程度（タイミングによって2〜4回）のリフレッシュが想定される
-	if count < 3 {
-		t.Errorf("Expected at least 3 refresh calls (including the initial one), but got %d", count)
+	// 初期化時に1回 + バックグラウンドで3回のリフレッシュ
+	if count != 4 {
+		t.Errorf("Expected 4 refresh calls (including the initial one), but got %d", count)
+	}
+}
+
+func TestTokenManager_OnDemandRefreshResetsTimer(t *testing.T) {
+	var refreshCallCount int
+	var counterMutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/xrpc/com.atproto.server.refreshSession" {
+			counterMutex.Lock()
+			refreshCallCount++
+			counterMutex.Unlock()
+			w.Write([]byte(`{"accessJwt": "new-access-token", "refreshJwt": "new-refresh-token"}`))
+		}
+	}))
+	defer server.Close()
+	count := func() int {
+		counterMutex.Lock()
+		defer counterMutex.Unlock()
+		return refreshCallCount
+	}
+
+	cfg := &config.Config{
+		AccessJWT:            "access-token",
+		RefreshJWT:           "refresh-token",
+		PDSURL:               server.URL,
+		TokenRefreshInterval: 100 * time.Millisecond,
+		HTTPTimeout:          3 * time.Second,
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	tm := NewTokenManager(cfg, NewTokenEncryptor(), newTestHTTPClient(t, cfg), WithClock(fake))
+	defer tm.Shutdown()
+	fake.BlockUntil(1)
+
+	// 401 などで予定より前にリフレッシュすると、予定のリフレッシュはそこから数え直す
+	fake.Advance(60 * time.Millisecond)
+	if err := tm.RefreshToken(context.Background()); err != nil {
+		t.Fatalf("RefreshToken() error = %v", err)
+	}
+	if got := tm.LastRefresh(); !got.Equal(start.Add(60 * time.Millisecond)) {
+		t.Errorf("LastRefresh() = %v, want the time of the on-demand refresh", got)
+	}
+	fake.Advance(40 * time.Millisecond)
+	fake.BlockUntil(1)
+	if got := count(); got != 2 {
+		t.Fatalf("refresh calls = %d, want 2 (no scheduled refresh right after the on-demand one)", got)
+	}
+	fake.Advance(60 * time.Millisecond)
+	fake.BlockUntil(1)
+	tm.Shutdown()
+	if got := count(); got != 3 {
+		t.Errorf("refresh calls = %d, want 3 (the rescheduled refresh)", got)
+	}
+
+	// 予定のリフレッシュは、直前のリフレッシュで足りていれば省略する
+	if refreshed, err := tm.refreshUnlessRecent(context.Background()); refreshed || err != nil {
+		t.Errorf("refreshUnlessRecent() = %v, %v, want skipped right after a refresh", refreshed, err)
+	}
+	if got := count(); got != 3 {
+		t.Errorf("refresh calls = %d, want 3", got)
 	}
 }
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// tokenFileVersion is the current on-disk format version of the token file
+const tokenFileVersion = 1
+
+// StoredTokens holds the session tokens persisted between restarts
+type StoredTokens struct {
+	AccessJWT  string        `json:"accessJwt"`
+	RefreshJWT string        `json:"refreshJwt"`
+	OAuth      *OAuthSession `json:"oauth,omitempty"` // Set for AUTH_MODE=oauth sessions
+	SavedAt    time.Time     `json:"savedAt"`
+}
+
+// TokenStore persists session tokens between restarts
+type TokenStore interface {
+	// Load returns the stored tokens, or (nil, nil) if nothing has been stored yet
+	Load() (*StoredTokens, error)
+	// Save stores the tokens, replacing any previous ones
+	Save(tokens StoredTokens) error
+}
+
+// NewTokenStore creates the TokenStore selected by the configuration.
+// It returns nil if tokens should only be kept in memory.
+func NewTokenStore(cfg *config.Config) (TokenStore, error) {
+	if cfg.TokenFile != "" {
+		return NewFileTokenStore(cfg.TokenFile, cfg.TokenFilePassphrase), nil
+	}
+
+	creds, err := config.NewCredentialStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		return NewCredentialTokenStore(creds), nil
+	}
+	return nil, nil
+}
+
+// CredentialTokenStore persists tokens in a config.CredentialStore such as the OS keyring
+type CredentialTokenStore struct {
+	creds config.CredentialStore
+}
+
+// NewCredentialTokenStore creates a new CredentialTokenStore instance
+func NewCredentialTokenStore(creds config.CredentialStore) *CredentialTokenStore {
+	return &CredentialTokenStore{creds: creds}
+}
+
+// Load reads the tokens from the credential store
+func (s *CredentialTokenStore) Load() (*StoredTokens, error) {
+	accessJWT, err := s.creds.Get(config.CredentialAccessJWT)
+	if errors.Is(err, config.ErrCredentialNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refreshJWT, err := s.creds.Get(config.CredentialRefreshJWT)
+	if errors.Is(err, config.ErrCredentialNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := &StoredTokens{
+		AccessJWT:  accessJWT,
+		RefreshJWT: refreshJWT,
+	}
+
+	oauthSession, err := s.creds.Get(config.CredentialOAuthSession)
+	if err != nil && !errors.Is(err, config.ErrCredentialNotFound) {
+		return nil, err
+	}
+	if oauthSession != "" {
+		if err := json.Unmarshal([]byte(oauthSession), &tokens.OAuth); err != nil {
+			return nil, fmt.Errorf("failed to parse stored OAuth session: %w", err)
+		}
+	}
+	return tokens, nil
+}
+
+// Save writes the tokens to the credential store
+func (s *CredentialTokenStore) Save(tokens StoredTokens) error {
+	if err := s.creds.Set(config.CredentialAccessJWT, tokens.AccessJWT); err != nil {
+		return err
+	}
+	if err := s.creds.Set(config.CredentialRefreshJWT, tokens.RefreshJWT); err != nil {
+		return err
+	}
+
+	if tokens.OAuth == nil {
+		return s.creds.Delete(config.CredentialOAuthSession)
+	}
+	oauthSession, err := json.Marshal(tokens.OAuth)
+	if err != nil {
+		return fmt.Errorf("failed to encode OAuth session: %w", err)
+	}
+	return s.creds.Set(config.CredentialOAuthSession, string(oauthSession))
+}
+
+// tokenFile is the on-disk representation of the encrypted token file
+type tokenFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Data       []byte `json:"data"` // nonce || AES-GCM ciphertext of StoredTokens
+}
+
+// FileTokenStore persists tokens to a file encrypted with a key derived from a passphrase
+type FileTokenStore struct {
+	path       string
+	passphrase string
+	iterations int
+	mu         sync.Mutex
+	salt       []byte
+	keyIter    int
+	key        []byte
+}
+
+// NewFileTokenStore creates a new FileTokenStore instance
+func NewFileTokenStore(path string, passphrase string) *FileTokenStore {
+	return &FileTokenStore{
+		path:       path,
+		passphrase: passphrase,
+		iterations: TokenFileKDFIterations,
+	}
+}
+
+// Load reads and decrypts the token file.
+// It returns (nil, nil) if the file does not exist yet.
+func (s *FileTokenStore) Load() (*StoredTokens, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	var file tokenFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse token file: %w", err)
+	}
+	if file.Version != tokenFileVersion {
+		return nil, fmt.Errorf("unsupported token file version: %d", file.Version)
+	}
+
+	key, err := s.deriveKey(file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := openGCM(key, file.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token file (wrong passphrase?): %w", err)
+	}
+
+	var tokens StoredTokens
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse stored tokens: %w", err)
+	}
+	return &tokens, nil
+}
+
+// Save encrypts the tokens and atomically replaces the token file
+func (s *FileTokenStore) Save(tokens StoredTokens) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Reuse the salt of the current key so that the KDF only runs once per process
+	salt, iterations := s.salt, s.keyIter
+	if salt == nil {
+		iterations = s.iterations
+		salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+
+	key, err := s.deriveKey(salt, iterations)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens: %w", err)
+	}
+
+	data, err := sealGCM(key, plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt tokens: %w", err)
+	}
+
+	raw, err := json.Marshal(tokenFile{
+		Version:    tokenFileVersion,
+		Salt:       salt,
+		Iterations: iterations,
+		Data:       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode token file: %w", err)
+	}
+
+	return writeFileAtomic(s.path, raw, 0o600)
+}
+
+// deriveKey derives the file key from the passphrase, caching the result per salt. Caller must hold mu.
+func (s *FileTokenStore) deriveKey(salt []byte, iterations int) ([]byte, error) {
+	if s.key != nil && s.keyIter == iterations && string(s.salt) == string(salt) {
+		return s.key, nil
+	}
+	if s.passphrase == "" {
+		return nil, fmt.Errorf("token file passphrase is not set")
+	}
+
+	key, err := pbkdf2.Key(sha256.New, s.passphrase, salt, iterations, DefaultKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token file key: %w", err)
+	}
+
+	s.salt = salt
+	s.keyIter = iterations
+	s.key = key
+	return key, nil
+}
+
+// sealGCM encrypts plaintext with AES-GCM and prepends the nonce
+func sealGCM(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aesGCM.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openGCM decrypts data produced by sealGCM
+func openGCM(key []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := aesGCM.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return aesGCM.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
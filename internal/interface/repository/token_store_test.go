@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/zalando/go-keyring"
+)
+
+func TestFileTokenStore_SaveLoad(t *testing.T) {
+	tests := []struct {
+		name           string
+		loadPassphrase string
+		wantErr        bool
+	}{
+		{
+			name:           "正常系: 保存したトークンを読み込める",
+			loadPassphrase: "correct-passphrase",
+			wantErr:        false,
+		},
+		{
+			name:           "異常系: パスフレーズが異なる",
+			loadPassphrase: "wrong-passphrase",
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tokens.json")
+
+			// テストを高速化するため反復回数を減らす
+			store := NewFileTokenStore(path, "correct-passphrase")
+			store.iterations = 1000
+
+			saved := StoredTokens{
+				AccessJWT:  "stored-access-token",
+				RefreshJWT: "stored-refresh-token",
+				SavedAt:    time.Now().Truncate(time.Second),
+			}
+			if err := store.Save(saved); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			// ファイルのパーミッションと平文が含まれていないことを確認
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Stat() error = %v", err)
+			}
+			if info.Mode().Perm() != 0o600 {
+				t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+			}
+			raw, _ := os.ReadFile(path)
+			if strings.Contains(string(raw), saved.AccessJWT) {
+				t.Errorf("token file contains plaintext token")
+			}
+
+			loaded, err := NewFileTokenStore(path, tt.loadPassphrase).Load()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if loaded.AccessJWT != saved.AccessJWT || loaded.RefreshJWT != saved.RefreshJWT {
+				t.Errorf("Load() = %+v, want %+v", loaded, saved)
+			}
+			if !loaded.SavedAt.Equal(saved.SavedAt) {
+				t.Errorf("Load() SavedAt = %v, want %v", loaded.SavedAt, saved.SavedAt)
+			}
+		})
+	}
+}
+
+func TestFileTokenStore_LoadMissingFile(t *testing.T) {
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "missing.json"), "passphrase")
+
+	tokens, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if tokens != nil {
+		t.Errorf("Load() = %+v, want nil", tokens)
+	}
+}
+
+func TestTokenManager_PersistsRefreshedTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{
+			"accessJwt":  "refreshed-access-token",
+			"refreshJwt": "refreshed-refresh-token",
+		})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	cfg := &config.Config{
+		AccessJWT:            "env-access-token",
+		RefreshJWT:           "env-refresh-token",
+		PDSURL:               server.URL,
+		TokenRefreshInterval: 1 * time.Hour,
+		HTTPTimeout:          3 * time.Second,
+		TokenFile:            path,
+		TokenFilePassphrase:  "passphrase",
+	}
+
+	// 初期化時のリフレッシュでトークンファイルが書き込まれる
+	tm := NewTokenManager(cfg, NewTokenEncryptor(), newTestHTTPClient(t, cfg))
+	tm.Shutdown()
+
+	// 再起動を想定し、リフレッシュが失敗する環境で新しいTokenManagerを作成
+	restartCfg := &config.Config{
+		PDSURL:               "http://invalid-url",
+		TokenRefreshInterval: 1 * time.Hour,
+		HTTPTimeout:          1 * time.Second,
+		TokenFile:            path,
+		TokenFilePassphrase:  "passphrase",
+	}
+	restarted := NewTokenManager(restartCfg, NewTokenEncryptor(), newTestHTTPClient(t, restartCfg))
+	defer restarted.Shutdown()
+
+	got, err := restarted.GetToken(AccessToken)
+	if err != nil {
+		t.Fatalf("GetToken() error = %v", err)
+	}
+	if got != "refreshed-access-token" {
+		t.Errorf("GetToken() after restart = %v, want %v", got, "refreshed-access-token")
+	}
+}
+
+func TestCredentialTokenStore_SaveLoad(t *testing.T) {
+	keyring.MockInit()
+	store := NewCredentialTokenStore(config.NewKeyringStore("quotebot-test", "did:plc:test"))
+
+	// 保存前は何も返さない
+	tokens, err := store.Load()
+	if err != nil || tokens != nil {
+		t.Fatalf("Load() before Save() = %+v, %v, want nil, nil", tokens, err)
+	}
+
+	if err := store.Save(StoredTokens{AccessJWT: "access", RefreshJWT: "refresh"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tokens, err = store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if tokens.AccessJWT != "access" || tokens.RefreshJWT != "refresh" {
+		t.Errorf("Load() = %+v, want access/refresh", tokens)
+	}
+}
+
+func TestTokenManager_CreateSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		sessionDID string
+		wantErr    bool
+	}{
+		{
+			name:       "正常系: アプリパスワードでセッション作成",
+			sessionDID: "did:plc:test",
+			wantErr:    false,
+		},
+		{
+			name:       "異常系: DIDが一致しない",
+			sessionDID: "did:plc:someone-else",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/xrpc/com.atproto.server.createSession" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				var body map[string]string
+				json.NewDecoder(r.Body).Decode(&body)
+				if body["identifier"] != "bot.example.com" || body["password"] != "app-password" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]string{
+					"accessJwt":  "session-access-token",
+					"refreshJwt": "session-refresh-token",
+					"did":        tt.sessionDID,
+				})
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{
+				DID:                  "did:plc:test",
+				Handle:               "bot.example.com",
+				AppPassword:          "app-password",
+				PDSURL:               server.URL,
+				TokenRefreshInterval: 1 * time.Hour,
+				HTTPTimeout:          3 * time.Second,
+			}
+			tm := NewTokenManager(cfg, NewTokenEncryptor(), newTestHTTPClient(t, cfg))
+			defer tm.Shutdown()
+
+			err := tm.CreateSession(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateSession() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := tm.GetToken(AccessToken)
+			if err != nil || got != "session-access-token" {
+				t.Errorf("GetToken() = %v, %v, want session-access-token", got, err)
+			}
+		})
+	}
+}
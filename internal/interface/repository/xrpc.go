@@ -0,0 +1,472 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// XRPC method identifiers (NSIDs) used by the bot
+const (
+	NSIDCreateSession     = "com.atproto.server.createSession"
+	NSIDRefreshSession    = "com.atproto.server.refreshSession"
+	NSIDGetSession        = "com.atproto.server.getSession"
+	NSIDCreateRecord      = "com.atproto.repo.createRecord"
+	NSIDGetRecord         = "com.atproto.repo.getRecord"
+	NSIDPutRecord         = "com.atproto.repo.putRecord"
+	NSIDDeleteRecord      = "com.atproto.repo.deleteRecord"
+	NSIDApplyWrites       = "com.atproto.repo.applyWrites"
+	NSIDUploadBlob        = "com.atproto.repo.uploadBlob"
+	NSIDGetPosts          = "app.bsky.feed.getPosts"
+	NSIDListNotifications = "app.bsky.notification.listNotifications"
+	NSIDGetAuthorFeed     = "app.bsky.feed.getAuthorFeed"
+)
+
+// MaxGetPostsURIs is the maximum number of URIs app.bsky.feed.getPosts accepts per request
+const MaxGetPostsURIs = 25
+
+// CollectionFeedPost is the collection and $type of Bluesky posts
+const CollectionFeedPost = "app.bsky.feed.post"
+
+// CollectionActorProfile is the collection and $type of the account's profile, stored with the record key "self"
+const CollectionActorProfile = "app.bsky.actor.profile"
+
+// CreateSessionInput is the input of com.atproto.server.createSession
+type CreateSessionInput struct {
+	Identifier string `json:"identifier"`
+	Password   string `json:"password"`
+}
+
+// SessionOutput is the output of com.atproto.server.createSession and refreshSession
+type SessionOutput struct {
+	AccessJWT  string `json:"accessJwt"`
+	RefreshJWT string `json:"refreshJwt"`
+	Handle     string `json:"handle"`
+	DID        string `json:"did"`
+	Active     *bool  `json:"active,omitempty"`
+	Status     string `json:"status,omitempty"`
+}
+
+// CreateRecordInput is the input of com.atproto.repo.createRecord
+type CreateRecordInput struct {
+	Repo       string      `json:"repo"`
+	Collection string      `json:"collection"`
+	Rkey       string      `json:"rkey,omitempty"`
+	Validate   *bool       `json:"validate,omitempty"`
+	Record     interface{} `json:"record"`
+}
+
+// CreateRecordOutput is the output of com.atproto.repo.createRecord
+type CreateRecordOutput struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+// GetRecordOutput is the output of com.atproto.repo.getRecord
+type GetRecordOutput struct {
+	URI   string          `json:"uri"`
+	CID   string          `json:"cid"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// PutRecordInput is the input of com.atproto.repo.putRecord
+type PutRecordInput struct {
+	Repo       string      `json:"repo"`
+	Collection string      `json:"collection"`
+	Rkey       string      `json:"rkey"`
+	Record     interface{} `json:"record"`
+	// SwapRecord makes the write fail if the record has changed since it was read (its CID then)
+	SwapRecord string `json:"swapRecord,omitempty"`
+}
+
+// DeleteRecordInput is the input of com.atproto.repo.deleteRecord
+type DeleteRecordInput struct {
+	Repo       string `json:"repo"`
+	Collection string `json:"collection"`
+	Rkey       string `json:"rkey"`
+}
+
+// ApplyWritesCreate is the $type of a create in com.atproto.repo.applyWrites
+const ApplyWritesCreate = "com.atproto.repo.applyWrites#create"
+
+// ApplyWritesInput is the input of com.atproto.repo.applyWrites
+type ApplyWritesInput struct {
+	Repo     string       `json:"repo"`
+	Validate *bool        `json:"validate,omitempty"`
+	Writes   []ApplyWrite `json:"writes"`
+}
+
+// ApplyWrite is one write of com.atproto.repo.applyWrites. The bot only creates records
+type ApplyWrite struct {
+	Type       string      `json:"$type"`
+	Collection string      `json:"collection"`
+	Rkey       string      `json:"rkey,omitempty"`
+	Value      interface{} `json:"value"`
+}
+
+// ApplyWritesOutput is the output of com.atproto.repo.applyWrites.
+// Older PDS versions return no results.
+type ApplyWritesOutput struct {
+	Results []CreateRecordOutput `json:"results,omitempty"`
+}
+
+// StrongRef is a com.atproto.repo.strongRef, a reference to a specific version of a record
+type StrongRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+// FeedPost is an app.bsky.feed.post record
+type FeedPost struct {
+	Type      string        `json:"$type"`
+	Text      string        `json:"text"`
+	CreatedAt string        `json:"createdAt"`
+	Facets    []interface{} `json:"facets,omitempty"`
+	Langs     []string      `json:"langs,omitempty"`
+	Embed     interface{}   `json:"embed,omitempty"`
+	Reply     *ReplyRef     `json:"reply,omitempty"`
+}
+
+// ReplyRef places a post in a thread: Root is the first post of the thread and Parent the post replied to
+type ReplyRef struct {
+	Root   StrongRef `json:"root"`
+	Parent StrongRef `json:"parent"`
+}
+
+// Notification is the part of app.bsky.notification.listNotifications#notification the bot reads
+type Notification struct {
+	URI    string `json:"uri"`
+	CID    string `json:"cid"`
+	Author struct {
+		DID    string      `json:"did"`
+		Handle string      `json:"handle"`
+		Viewer ViewerState `json:"viewer"`
+	} `json:"author"`
+	// Reason is why the account was notified, e.g. "mention" or "reply"
+	Reason    string          `json:"reason"`
+	Record    json.RawMessage `json:"record"`
+	IndexedAt time.Time       `json:"indexedAt"`
+}
+
+// ViewerState is app.bsky.actor.defs#viewerState, the account's relationship with another account
+type ViewerState struct {
+	Muted          bool            `json:"muted,omitempty"`
+	MutedByList    json.RawMessage `json:"mutedByList,omitempty"`
+	BlockedBy      bool            `json:"blockedBy,omitempty"`
+	Blocking       string          `json:"blocking,omitempty"` // URI of the block record
+	BlockingByList json.RawMessage `json:"blockingByList,omitempty"`
+}
+
+// ListNotificationsOutput is the output of app.bsky.notification.listNotifications
+type ListNotificationsOutput struct {
+	Cursor        string         `json:"cursor,omitempty"`
+	Notifications []Notification `json:"notifications"`
+}
+
+// PostView is the part of app.bsky.feed.defs#postView the bot reads
+type PostView struct {
+	URI         string `json:"uri"`
+	CID         string `json:"cid"`
+	LikeCount   int    `json:"likeCount"`
+	RepostCount int    `json:"repostCount"`
+	ReplyCount  int    `json:"replyCount"`
+	QuoteCount  int    `json:"quoteCount"`
+	Author      struct {
+		DID string `json:"did"`
+	} `json:"author"`
+	Record json.RawMessage `json:"record,omitempty"`
+}
+
+// GetPostsOutput is the output of app.bsky.feed.getPosts
+type GetPostsOutput struct {
+	Posts []PostView `json:"posts"`
+}
+
+// FeedViewPost is an item of a feed. Reason is set for reposts and pinned posts
+type FeedViewPost struct {
+	Post   PostView        `json:"post"`
+	Reason json.RawMessage `json:"reason,omitempty"`
+}
+
+// GetAuthorFeedOutput is the output of app.bsky.feed.getAuthorFeed
+type GetAuthorFeedOutput struct {
+	Cursor string         `json:"cursor,omitempty"`
+	Feed   []FeedViewPost `json:"feed"`
+}
+
+// BlobRef references an uploaded blob from a record
+type BlobRef struct {
+	Type     string  `json:"$type"`
+	Ref      CIDLink `json:"ref"`
+	MimeType string  `json:"mimeType"`
+	Size     int64   `json:"size"`
+}
+
+// CIDLink is the JSON encoding of a CID link
+type CIDLink struct {
+	Link string `json:"$link"`
+}
+
+// UploadBlobOutput is the output of com.atproto.repo.uploadBlob
+type UploadBlobOutput struct {
+	Blob BlobRef `json:"blob"`
+}
+
+// XRPCClient is a typed client for the XRPC endpoints of a PDS.
+// Authentication headers are passed in by the caller, since they depend on the session type.
+type XRPCClient struct {
+	httpClient *HTTPClient
+	host       string
+	timeouts   map[string]time.Duration // NSID -> per-attempt timeout override
+}
+
+// NewXRPCClient creates a new XRPCClient for the given PDS URL
+func NewXRPCClient(httpClient *HTTPClient, host string) *XRPCClient {
+	return &XRPCClient{
+		httpClient: httpClient,
+		host:       strings.TrimRight(host, "/"),
+		timeouts:   make(map[string]time.Duration),
+	}
+}
+
+// requestClasses are the XRPC methods that are retried with their own policy
+// instead of the default one for idempotent requests. applyWrites is idempotent
+// because the bot always sets the record keys of its creates.
+var requestClasses = map[string]RequestClass{
+	NSIDRefreshSession: RequestClassTokenRefresh,
+	NSIDCreateRecord:   RequestClassCreateRecord,
+}
+
+// newConfiguredXRPCClient creates an XRPCClient for PDS_URL with the configured endpoint timeouts
+func newConfiguredXRPCClient(cfg *config.Config, httpClient *HTTPClient) *XRPCClient {
+	c := NewXRPCClient(httpClient, cfg.PDSURL)
+	c.SetTimeouts(EndpointTimeouts(cfg))
+	return c
+}
+
+// EndpointTimeouts returns the configured per-endpoint timeout overrides.
+// Endpoints without an override use HTTP_TIMEOUT.
+func EndpointTimeouts(cfg *config.Config) map[string]time.Duration {
+	timeouts := make(map[string]time.Duration)
+	if cfg.RefreshTimeout > 0 {
+		timeouts[NSIDRefreshSession] = cfg.RefreshTimeout
+	}
+	if cfg.CreateRecordTimeout > 0 {
+		// posts are created through applyWrites when they have a record key
+		timeouts[NSIDCreateRecord] = cfg.CreateRecordTimeout
+		timeouts[NSIDApplyWrites] = cfg.CreateRecordTimeout
+	}
+	if cfg.UploadBlobTimeout > 0 {
+		timeouts[NSIDUploadBlob] = cfg.UploadBlobTimeout
+	}
+	return timeouts
+}
+
+// SetTimeouts overrides the per-attempt timeout of the given XRPC methods
+func (c *XRPCClient) SetTimeouts(timeouts map[string]time.Duration) {
+	for nsid, timeout := range timeouts {
+		c.timeouts[nsid] = timeout
+	}
+}
+
+// URL returns the endpoint URL of an XRPC method
+func (c *XRPCClient) URL(nsid string) string {
+	return fmt.Sprintf("%s/xrpc/%s", c.host, nsid)
+}
+
+// Query calls an XRPC query (GET) and decodes the output into output, if not nil
+func (c *XRPCClient) Query(ctx context.Context, nsid string, params url.Values, headers map[string]string, output interface{}) error {
+	endpoint := c.URL(nsid)
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	return c.do(ctx, nsid, http.MethodGet, endpoint, nil, headers, output)
+}
+
+// Procedure calls an XRPC procedure (POST) with a JSON input and decodes the output into output, if not nil
+func (c *XRPCClient) Procedure(ctx context.Context, nsid string, input interface{}, headers map[string]string, output interface{}) error {
+	requestHeaders := map[string]string{"Content-Type": "application/json"}
+	for key, value := range headers {
+		requestHeaders[key] = value
+	}
+	return c.do(ctx, nsid, http.MethodPost, c.URL(nsid), input, requestHeaders, output)
+}
+
+// CreateSession logs in with an identifier (handle or DID) and an app password
+func (c *XRPCClient) CreateSession(ctx context.Context, input CreateSessionInput) (*SessionOutput, error) {
+	var output SessionOutput
+	if err := c.Procedure(ctx, NSIDCreateSession, input, nil, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// RefreshSession exchanges a refresh JWT for a new pair of tokens
+func (c *XRPCClient) RefreshSession(ctx context.Context, refreshJWT string) (*SessionOutput, error) {
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", refreshJWT),
+	}
+	var output SessionOutput
+	if err := c.Procedure(ctx, NSIDRefreshSession, nil, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// GetSession returns the account behind the authenticated session
+func (c *XRPCClient) GetSession(ctx context.Context, headers map[string]string) (*SessionInfo, error) {
+	var output SessionInfo
+	if err := c.Query(ctx, NSIDGetSession, nil, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// CreateRecord creates a record in the authenticated account's repository
+func (c *XRPCClient) CreateRecord(ctx context.Context, input CreateRecordInput, headers map[string]string) (*CreateRecordOutput, error) {
+	var output CreateRecordOutput
+	if err := c.Procedure(ctx, NSIDCreateRecord, input, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// ApplyWrites applies all writes to the authenticated account's repository in one commit,
+// or none of them
+func (c *XRPCClient) ApplyWrites(ctx context.Context, input ApplyWritesInput, headers map[string]string) (*ApplyWritesOutput, error) {
+	var output ApplyWritesOutput
+	if err := c.Procedure(ctx, NSIDApplyWrites, input, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// GetRecord fetches a single record from a repository
+func (c *XRPCClient) GetRecord(ctx context.Context, repo, collection, rkey string, headers map[string]string) (*GetRecordOutput, error) {
+	params := url.Values{"repo": {repo}, "collection": {collection}, "rkey": {rkey}}
+	var output GetRecordOutput
+	if err := c.Query(ctx, NSIDGetRecord, params, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// PutRecord creates or replaces a record in the authenticated account's repository
+func (c *XRPCClient) PutRecord(ctx context.Context, input PutRecordInput, headers map[string]string) (*CreateRecordOutput, error) {
+	var output CreateRecordOutput
+	if err := c.Procedure(ctx, NSIDPutRecord, input, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// DeleteRecord deletes a record from the authenticated account's repository
+func (c *XRPCClient) DeleteRecord(ctx context.Context, input DeleteRecordInput, headers map[string]string) error {
+	return c.Procedure(ctx, NSIDDeleteRecord, input, headers, nil)
+}
+
+// IsRecordNotFound reports whether err is the error getRecord returns for a record that does not exist
+func IsRecordNotFound(err error) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	return httpErr.StatusCode == http.StatusNotFound ||
+		(httpErr.StatusCode == http.StatusBadRequest && strings.Contains(httpErr.Message, "RecordNotFound"))
+}
+
+// GetPosts calls app.bsky.feed.getPosts for at most MaxGetPostsURIs URIs.
+// Deleted posts are missing from the output.
+func (c *XRPCClient) GetPosts(ctx context.Context, uris []string, headers map[string]string) (*GetPostsOutput, error) {
+	if len(uris) > MaxGetPostsURIs {
+		return nil, fmt.Errorf("getPosts accepts at most %d URIs, got %d", MaxGetPostsURIs, len(uris))
+	}
+	params := url.Values{"uris": uris}
+	var output GetPostsOutput
+	if err := c.Query(ctx, NSIDGetPosts, params, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// GetAuthorFeed lists the posts of actor, newest first, without replies
+func (c *XRPCClient) GetAuthorFeed(ctx context.Context, actor string, limit int, cursor string, headers map[string]string) (*GetAuthorFeedOutput, error) {
+	params := url.Values{
+		"actor":  {actor},
+		"limit":  {strconv.Itoa(limit)},
+		"filter": {"posts_no_replies"},
+	}
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	var output GetAuthorFeedOutput
+	if err := c.Query(ctx, NSIDGetAuthorFeed, params, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// ListNotifications lists the account's notifications, newest first, optionally only those with the given reasons
+func (c *XRPCClient) ListNotifications(ctx context.Context, reasons []string, limit int, cursor string, headers map[string]string) (*ListNotificationsOutput, error) {
+	params := url.Values{"limit": {strconv.Itoa(limit)}}
+	if len(reasons) > 0 {
+		params["reasons"] = reasons
+	}
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	var output ListNotificationsOutput
+	if err := c.Query(ctx, NSIDListNotifications, params, headers, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// UploadBlob uploads binary data (e.g. an image) and returns a reference to embed in a record
+func (c *XRPCClient) UploadBlob(ctx context.Context, data []byte, mimeType string, headers map[string]string) (*UploadBlobOutput, error) {
+	requestHeaders := map[string]string{"Content-Type": mimeType}
+	for key, value := range headers {
+		requestHeaders[key] = value
+	}
+
+	var output UploadBlobOutput
+	if err := c.do(ctx, NSIDUploadBlob, http.MethodPost, c.URL(NSIDUploadBlob), RawBody(data), requestHeaders, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// do sends an XRPC request and decodes the JSON output
+func (c *XRPCClient) do(ctx context.Context, nsid, method, endpoint string, body interface{}, headers map[string]string, output interface{}) error {
+	var opts []RequestOption
+	if timeout, ok := c.timeouts[nsid]; ok {
+		opts = append(opts, WithTimeout(timeout))
+	}
+	if class, ok := requestClasses[nsid]; ok {
+		opts = append(opts, WithRequestClass(class))
+	}
+
+	resp, err := c.httpClient.DoRequest(ctx, method, endpoint, body, headers, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if output == nil {
+		return nil
+	}
+	// An empty body is not an error: the request itself succeeded
+	if err := c.httpClient.DecodeJSONResponse(resp, output); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
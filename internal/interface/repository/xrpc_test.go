@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestXRPCClient_CreateRecord(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/com.atproto.repo.createRecord" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(CreateRecordOutput{URI: "at://did:plc:test/app.bsky.feed.post/1", CID: "bafy"})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HTTPTimeout: 3 * time.Second}
+	client := NewXRPCClient(newTestHTTPClient(t, cfg), server.URL+"/")
+
+	output, err := client.CreateRecord(context.Background(), CreateRecordInput{
+		Repo:       "did:plc:test",
+		Collection: CollectionFeedPost,
+		Record: FeedPost{
+			Type:      CollectionFeedPost,
+			Text:      "hello",
+			CreatedAt: "2024-01-01T00:00:00Z",
+		},
+	}, map[string]string{"Authorization": "Bearer token"})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+	if output.URI != "at://did:plc:test/app.bsky.feed.post/1" || output.CID != "bafy" {
+		t.Errorf("CreateRecord() = %+v", output)
+	}
+
+	// レキシコンどおりのフィールド名で送信されている
+	record, _ := got["record"].(map[string]interface{})
+	if got["repo"] != "did:plc:test" || got["collection"] != CollectionFeedPost {
+		t.Errorf("request = %v", got)
+	}
+	if record["$type"] != CollectionFeedPost || record["text"] != "hello" || record["createdAt"] != "2024-01-01T00:00:00Z" {
+		t.Errorf("record = %v", record)
+	}
+	if _, ok := got["rkey"]; ok {
+		t.Errorf("empty rkey should be omitted: %v", got)
+	}
+}
+
+func TestXRPCClient_Sessions(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    func(c *XRPCClient) (*SessionOutput, error)
+		wantErr bool
+	}{
+		{
+			name: "正常系: createSession",
+			call: func(c *XRPCClient) (*SessionOutput, error) {
+				return c.CreateSession(context.Background(), CreateSessionInput{Identifier: "bot.example.com", Password: "app-password"})
+			},
+			wantErr: false,
+		},
+		{
+			name: "正常系: refreshSession",
+			call: func(c *XRPCClient) (*SessionOutput, error) {
+				return c.RefreshSession(context.Background(), "refresh-token")
+			},
+			wantErr: false,
+		},
+		{
+			name: "異常系: 無効なリフレッシュトークン",
+			call: func(c *XRPCClient) (*SessionOutput, error) {
+				return c.RefreshSession(context.Background(), "expired-token")
+			},
+			wantErr: true,
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			var input CreateSessionInput
+			json.NewDecoder(r.Body).Decode(&input)
+			if input.Identifier != "bot.example.com" || input.Password != "app-password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "/xrpc/com.atproto.server.refreshSession":
+			if r.Header.Get("Authorization") != "Bearer refresh-token" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken"})
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(SessionOutput{AccessJWT: "access", RefreshJWT: "refresh", DID: "did:plc:test"})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HTTPTimeout: 3 * time.Second}
+	client := NewXRPCClient(newTestHTTPClient(t, cfg), server.URL)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := tt.call(client)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (session.AccessJWT != "access" || session.DID != "did:plc:test") {
+				t.Errorf("session = %+v", session)
+			}
+		})
+	}
+}
+
+func TestXRPCClient_UploadBlob(t *testing.T) {
+	image := []byte{0x89, 'P', 'N', 'G', 0x00, 0x01}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// 画像データはJSONエンコードされずにそのまま送られる
+		if r.Header.Get("Content-Type") != "image/png" || string(body) != string(image) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(UploadBlobOutput{Blob: BlobRef{
+			Type:     "blob",
+			Ref:      CIDLink{Link: "bafkrei-test"},
+			MimeType: "image/png",
+			Size:     int64(len(body)),
+		}})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HTTPTimeout: 3 * time.Second}
+	client := NewXRPCClient(newTestHTTPClient(t, cfg), server.URL)
+
+	output, err := client.UploadBlob(context.Background(), image, "image/png", nil)
+	if err != nil {
+		t.Fatalf("UploadBlob() error = %v", err)
+	}
+	if output.Blob.Ref.Link != "bafkrei-test" || output.Blob.Size != int64(len(image)) {
+		t.Errorf("UploadBlob() = %+v", output)
+	}
+}
+
+func TestXRPCClient_EndpointTimeouts(t *testing.T) {
+	// 200ms後に応答するサーバー
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		json.NewEncoder(w).Encode(UploadBlobOutput{Blob: BlobRef{Type: "blob", MimeType: "image/png", Size: 3}})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HTTPTimeout: 50 * time.Millisecond, UploadBlobTimeout: 2 * time.Second}
+	timeouts := EndpointTimeouts(cfg)
+	if _, ok := timeouts[NSIDCreateRecord]; ok {
+		t.Errorf("EndpointTimeouts() = %v, createRecord should use HTTP_TIMEOUT", timeouts)
+	}
+
+	client := NewXRPCClient(newTestHTTPClient(t, cfg), server.URL)
+	client.SetTimeouts(timeouts)
+
+	// uploadBlobは延長されたタイムアウトで成功する
+	if _, err := client.UploadBlob(context.Background(), []byte("png"), "image/png", nil); err != nil {
+		t.Errorf("UploadBlob() error = %v, want nil", err)
+	}
+	// 上書きのないエンドポイントはHTTP_TIMEOUTでタイムアウトする
+	if _, err := client.CreateRecord(context.Background(), CreateRecordInput{Repo: "did:plc:test"}, nil); err == nil {
+		t.Error("CreateRecord() error = nil, want timeout")
+	}
+}
+
+func TestXRPCClient_RetryPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		status    int
+		wantCalls map[string]int
+	}{
+		{
+			name:   "正常系: 5xxでは投稿だけ再試行しない",
+			cfg:    config.Config{MaxRetries: 2, MaxRetriesRefresh: -1, MaxRetriesCreateRecord: -1},
+			status: http.StatusBadGateway,
+			wantCalls: map[string]int{
+				NSIDCreateRecord:   1,
+				NSIDGetRecord:      3,
+				NSIDRefreshSession: 3,
+			},
+		},
+		{
+			name:   "正常系: 429は投稿も再試行する",
+			cfg:    config.Config{MaxRetries: 2, MaxRetriesRefresh: 1, MaxRetriesCreateRecord: -1},
+			status: http.StatusTooManyRequests,
+			wantCalls: map[string]int{
+				NSIDCreateRecord:   3,
+				NSIDGetRecord:      3,
+				NSIDRefreshSession: 2,
+			},
+		},
+		{
+			name:   "正常系: RETRY_CREATE_RECORD_SERVER_ERRORS",
+			cfg:    config.Config{MaxRetries: 2, MaxRetriesCreateRecord: 1, RetryCreateRecordServerErrors: true},
+			status: http.StatusInternalServerError,
+			wantCalls: map[string]int{
+				NSIDCreateRecord:   2,
+				NSIDGetRecord:      3,
+				NSIDRefreshSession: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := map[string]int{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				calls[strings.TrimPrefix(r.URL.Path, "/xrpc/")]++
+				mu.Unlock()
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			cfg := tt.cfg
+			cfg.HTTPTimeout = time.Second
+			client := NewXRPCClient(newTestHTTPClient(t, &cfg), server.URL)
+			ctx := context.Background()
+			client.CreateRecord(ctx, CreateRecordInput{Repo: "did:plc:test"}, nil)
+			client.GetRecord(ctx, "did:plc:test", "app.bsky.feed.post", "abc", nil)
+			client.RefreshSession(ctx, "refresh")
+
+			mu.Lock()
+			defer mu.Unlock()
+			for nsid, want := range tt.wantCalls {
+				if calls[nsid] != want {
+					t.Errorf("%s called %d times, want %d", nsid, calls[nsid], want)
+				}
+			}
+		})
+	}
+}
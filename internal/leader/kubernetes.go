@@ -0,0 +1,217 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// serviceAccountDir は Pod にマウントされるサービスアカウントのトークン・CA証明書・名前空間の場所です
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// microTimeFormat は Lease の acquireTime と renewTime の形式（Kubernetes の MicroTime）です
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// KubernetesLease は Kubernetes の Lease（coordination.k8s.io/v1）をリースとして使う Backend です。
+// Pod のサービスアカウントには、Lease の get・create・update の権限が必要です
+type KubernetesLease struct {
+	leasesURL string // 名前空間の Lease の一覧のURL
+	name      string
+	namespace string
+	tokenFile string // サービスアカウントのトークンは更新されるため、リクエストのたびに読み込みます
+	client    *http.Client
+}
+
+// NewKubernetesLease は Pod のサービスアカウントで Kubernetes API に接続する KubernetesLease を作成します。
+// bots のボットでは、ボットごとに別の Lease（LEADER_ELECTION_LEASE-<ボットの名前>）を使います
+func NewKubernetesLease(cfg *config.Config) (*KubernetesLease, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("LEADER_ELECTION は Kubernetes の Pod の中でのみ使えます（KUBERNETES_SERVICE_HOST が未設定です）")
+	}
+
+	namespace := cfg.LeaderElectionNamespace
+	if namespace == "" {
+		data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("Pod の名前空間を取得できません（LEADER_ELECTION_NAMESPACE を指定してください）: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	caData, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("Kubernetes API のCA証明書の読み込みに失敗しました: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("Kubernetes API のCA証明書の解析に失敗しました")
+	}
+	client := &http.Client{
+		Timeout: cfg.HTTPTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+
+	name := cfg.LeaderElectionLease
+	if bot := cfg.Bot(); bot != "" {
+		name += "-" + bot
+	}
+	apiURL := "https://" + net.JoinHostPort(host, port)
+	return newKubernetesLease(apiURL, namespace, name, filepath.Join(serviceAccountDir, "token"), client), nil
+}
+
+// newKubernetesLease は apiURL の Kubernetes API の namespace の name の Lease を使う KubernetesLease を作成します
+func newKubernetesLease(apiURL, namespace, name, tokenFile string, client *http.Client) *KubernetesLease {
+	return &KubernetesLease{
+		leasesURL: fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", apiURL, url.PathEscape(namespace)),
+		name:      name,
+		namespace: namespace,
+		tokenFile: tokenFile,
+		client:    client,
+	}
+}
+
+// lease は Lease リソースのうち、リーダー選出に使う部分です
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+// Get は Lease を取得します
+func (l *KubernetesLease) Get(ctx context.Context) (*Record, string, error) {
+	var out lease
+	if err := l.do(ctx, http.MethodGet, l.leasesURL+"/"+url.PathEscape(l.name), nil, &out); err != nil {
+		return nil, "", err
+	}
+	record := &Record{
+		Holder:      out.Spec.HolderIdentity,
+		Duration:    time.Duration(out.Spec.LeaseDurationSeconds) * time.Second,
+		AcquireTime: parseMicroTime(out.Spec.AcquireTime),
+		RenewTime:   parseMicroTime(out.Spec.RenewTime),
+		Transitions: out.Spec.LeaseTransitions,
+	}
+	return record, out.Metadata.ResourceVersion, nil
+}
+
+// Create は Lease を作成します
+func (l *KubernetesLease) Create(ctx context.Context, record Record) error {
+	return l.do(ctx, http.MethodPost, l.leasesURL, l.lease(record, ""), nil)
+}
+
+// Update は resourceVersion が version のままであれば Lease を置き換えます
+func (l *KubernetesLease) Update(ctx context.Context, record Record, version string) error {
+	return l.do(ctx, http.MethodPut, l.leasesURL+"/"+url.PathEscape(l.name), l.lease(record, version), nil)
+}
+
+// lease は record を Lease リソースにします
+func (l *KubernetesLease) lease(record Record, version string) *lease {
+	return &lease{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata:   leaseMetadata{Name: l.name, Namespace: l.namespace, ResourceVersion: version},
+		Spec: leaseSpec{
+			HolderIdentity:       record.Holder,
+			LeaseDurationSeconds: max(int(record.Duration/time.Second), 1),
+			AcquireTime:          formatMicroTime(record.AcquireTime),
+			RenewTime:            formatMicroTime(record.RenewTime),
+			LeaseTransitions:     record.Transitions,
+		},
+	}
+}
+
+// do は Kubernetes API を呼び出し、out に結果を読み込みます。
+// 404 は ErrNotFound、409（作成済み・resourceVersion の不一致）は ErrConflict になります
+func (l *KubernetesLease) do(ctx context.Context, method, target string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("Lease のエンコードに失敗しました: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token, err := os.ReadFile(l.tokenFile)
+	if err != nil {
+		return fmt.Errorf("サービスアカウントのトークンの読み込みに失敗しました: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Kubernetes API への接続に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case resp.StatusCode/100 != 2:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Kubernetes API がエラーを返しました（%s %s）: %s", method, resp.Status, strings.TrimSpace(string(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("Lease の解析に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// formatMicroTime は t を MicroTime の形式にします。ゼロ値は空です
+func formatMicroTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(microTimeFormat)
+}
+
+// parseMicroTime は MicroTime の形式の時刻を解析します。空や不正な値はゼロ値です
+func parseMicroTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
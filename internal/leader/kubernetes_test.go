@@ -0,0 +1,114 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLeaseServer は1つの Lease だけを扱う Kubernetes API です
+type fakeLeaseServer struct {
+	mu      sync.Mutex
+	lease   *lease
+	version int
+}
+
+func (s *fakeLeaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	const collection = "/apis/coordination.k8s.io/v1/namespaces/bots/leases"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == collection+"/quotebot":
+		if s.lease == nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(s.lease)
+	case r.Method == http.MethodPost && r.URL.Path == collection:
+		if s.lease != nil {
+			http.Error(w, "already exists", http.StatusConflict)
+			return
+		}
+		s.store(w, r)
+	case r.Method == http.MethodPut && r.URL.Path == collection+"/quotebot":
+		var in lease
+		json.NewDecoder(r.Body).Decode(&in)
+		if s.lease == nil || in.Metadata.ResourceVersion != s.lease.Metadata.ResourceVersion {
+			http.Error(w, "conflict", http.StatusConflict)
+			return
+		}
+		s.lease = &in
+		s.version++
+		s.lease.Metadata.ResourceVersion = strconv.Itoa(s.version)
+		json.NewEncoder(w).Encode(s.lease)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (s *fakeLeaseServer) store(w http.ResponseWriter, r *http.Request) {
+	var in lease
+	json.NewDecoder(r.Body).Decode(&in)
+	s.lease = &in
+	s.version++
+	s.lease.Metadata.ResourceVersion = strconv.Itoa(s.version)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s.lease)
+}
+
+func TestKubernetesLease(t *testing.T) {
+	server := httptest.NewServer(&fakeLeaseServer{})
+	defer server.Close()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("test-token\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	l := newKubernetesLease(server.URL, "bots", "quotebot", tokenFile, server.Client())
+	ctx := context.Background()
+
+	// 異常系: 作成前は ErrNotFound
+	if _, _, err := l.Get(ctx); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 123456000, time.UTC)
+	if err := l.Create(ctx, Record{Holder: "replica-a", Duration: 15 * time.Second, AcquireTime: now, RenewTime: now}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// 異常系: 2つ目の作成は ErrConflict
+	if err := l.Create(ctx, Record{Holder: "replica-b"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Create() error = %v, want ErrConflict", err)
+	}
+
+	// 正常系: 作成した内容が読める
+	record, version, err := l.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := Record{Holder: "replica-a", Duration: 15 * time.Second, AcquireTime: now, RenewTime: now}
+	if *record != want {
+		t.Errorf("Get() = %+v, want %+v", *record, want)
+	}
+
+	// 正常系: 同じバージョンでの更新は成功し、古いバージョンでの更新は ErrConflict
+	record.RenewTime = now.Add(3 * time.Second)
+	if err := l.Update(ctx, *record, version); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := l.Update(ctx, *record, version); !errors.Is(err, ErrConflict) {
+		t.Errorf("Update() with a stale version error = %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,282 @@
+// Package leader は複数のレプリカでボットを動かすときに、投稿するレプリカを1つに決めるリーダー選出です。
+// レプリカはリースを取り合い、リースを持っているレプリカ（リーダー）だけが投稿します。
+// リーダーが停止してリースを更新しなくなると、リースの期間が過ぎてからほかのレプリカが引き継ぎます
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+)
+
+var (
+	// ErrNotFound はリースがまだ作成されていないことを表します
+	ErrNotFound = errors.New("リースがありません")
+	// ErrConflict はほかのレプリカが先にリースを作成・更新したことを表します
+	ErrConflict = errors.New("リースがほかのレプリカに更新されました")
+)
+
+// Record はリースの内容です
+type Record struct {
+	Holder      string        // リースを持っているレプリカ。空の場合は誰も持っていません
+	Duration    time.Duration // 更新されなくなってから、ほかのレプリカが引き継げるようになるまでの時間
+	AcquireTime time.Time
+	RenewTime   time.Time
+	Transitions int // リーダーが入れ替わった回数
+}
+
+// Backend はリースの保存先です。更新はバージョンを比べて行い、同時に更新した場合は片方だけが成功します
+type Backend interface {
+	// Get はリースと、Update に渡すバージョンを返します。リースがない場合は ErrNotFound を返します
+	Get(ctx context.Context) (*Record, string, error)
+	// Create はリースを作成します。ほかのレプリカが先に作成していた場合は ErrConflict を返します
+	Create(ctx context.Context, record Record) error
+	// Update はリースのバージョンが version のままであれば record で置き換えます。変わっていた場合は ErrConflict を返します
+	Update(ctx context.Context, record Record, version string) error
+}
+
+// Elector はリースを取り合ってリーダーを選びます。Start してから Shutdown するまで、
+// リーダーであればリースを更新し続け、そうでなければリーダーが交代できるようになるのを待ちます
+type Elector struct {
+	backend       Backend
+	identity      string
+	duration      time.Duration
+	renewDeadline time.Duration // リースを更新できない状態がこれだけ続くと、リーダーをやめます
+	retryPeriod   time.Duration // リースを取得・更新する間隔
+	clock         clock.Clock
+	logger        *slog.Logger
+
+	mu         sync.Mutex // 以下のフィールドを保護します
+	leading    bool
+	renewedAt  time.Time // 最後にリースを取得・更新した時刻
+	observed   Record    // 最後に見たリース
+	observedAt time.Time // observed が変わったのを見た時刻。レプリカ間の時計のずれの影響を受けないよう、自分の時計で数えます
+	reported   bool      // 最後にログに出力したリーダーかどうか
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Option は Elector の任意の設定です
+type Option func(*Elector)
+
+// WithClock は実際の時計の代わりに c を使います（テストでの clock.Fake など）
+func WithClock(c clock.Clock) Option {
+	return func(e *Elector) {
+		e.clock = c
+	}
+}
+
+// New は backend のリースを identity の名前で取り合う Elector を作成します
+func New(cfg *config.Config, backend Backend, identity string, opts ...Option) *Elector {
+	duration := cfg.LeaderElectionLeaseDuration
+	e := &Elector{
+		backend:       backend,
+		identity:      identity,
+		duration:      duration,
+		renewDeadline: duration * 2 / 3,
+		retryPeriod:   duration / 5,
+		clock:         clock.Real,
+		logger:        logging.ModuleFor(cfg, "leader"),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Leader は Elector と AdvisoryLock に共通の、リーダー選出の操作です（app.Leader）
+type Leader interface {
+	Start() error
+	Shutdown(ctx context.Context) error
+	// IsLeader はこのレプリカがリーダーかを返します
+	IsLeader() bool
+}
+
+// NewFromConfig は LEADER_ELECTION の設定でリーダー選出を作成します。LEADER_ELECTION_BACKEND が kubernetes なら
+// Kubernetes の Lease を、postgres なら STATE_DSN の PostgreSQL のアドバイザリロックを取り合います。
+// LEADER_ELECTION が無効な場合は nil を返します
+func NewFromConfig(cfg *config.Config) (Leader, error) {
+	if !cfg.LeaderElection {
+		return nil, nil
+	}
+	identity := cfg.LeaderElectionIdentity
+	if identity == "" {
+		var err error
+		if identity, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("レプリカの名前にするホスト名を取得できません（LEADER_ELECTION_IDENTITY を指定してください）: %w", err)
+		}
+	}
+	if cfg.LeaderElectionBackend == config.LeaderElectionBackendPostgres {
+		return NewAdvisoryLock(cfg, identity)
+	}
+	backend, err := NewKubernetesLease(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg, backend, identity), nil
+}
+
+// IsLeader はこのレプリカがリーダーかを返します。リースを更新できない状態が続いている場合は、
+// ほかのレプリカが引き継ぐ前にリーダーをやめます
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isLeaderLocked(e.clock.Now())
+}
+
+// isLeaderLocked は now の時点でリーダーかを返します。e.mu を持って呼び出します
+func (e *Elector) isLeaderLocked(now time.Time) bool {
+	return e.leading && now.Before(e.renewedAt.Add(e.renewDeadline))
+}
+
+// Start はリースを1回取り合ってから、バックグラウンドでリースの取得・更新を始めます。
+// 起動してすぐに投稿するかを決められるよう、最初の1回は戻る前に行います
+func (e *Elector) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	e.logger.Info("リーダー選出を開始します", "identity", e.identity, "lease_duration", e.duration)
+
+	e.tryAcquireOrRenew(ctx)
+	safego.Go(e.logger, "leader", func() {
+		defer close(e.done)
+		safego.Loop(e.logger, "leader", ctx.Done(), func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-e.clock.After(e.retryPeriod):
+					e.tryAcquireOrRenew(ctx)
+				}
+			}
+		})
+	})
+	return nil
+}
+
+// Shutdown はリースの取得・更新をやめ、リーダーであればリースを手放します。
+// リースの期間を待たずに、ほかのレプリカがすぐに引き継げるようになります
+func (e *Elector) Shutdown(ctx context.Context) error {
+	if e.cancel == nil {
+		return nil
+	}
+	e.cancel()
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if !e.IsLeader() {
+		return nil
+	}
+	e.mu.Lock()
+	e.leading = false
+	e.mu.Unlock()
+	if err := e.release(ctx); err != nil {
+		e.logger.Warn("リースの解放に失敗しました", "error", redact.Error(err))
+		return err
+	}
+	e.logger.Info("リーダーを辞退しました", "identity", e.identity)
+	return nil
+}
+
+// tryAcquireOrRenew はリースを取得または更新し、リーダーになった・やめたことをログに出力します
+func (e *Elector) tryAcquireOrRenew(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.retryPeriod)
+	defer cancel()
+
+	now := e.clock.Now()
+	acquired, err := e.acquireOrRenew(ctx, now)
+	if err != nil && ctx.Err() == nil {
+		e.logger.Warn("リースの更新に失敗しました", "error", redact.Error(err))
+	}
+
+	e.mu.Lock()
+	switch {
+	case acquired:
+		e.leading, e.renewedAt = true, now
+	case err == nil:
+		// ほかのレプリカがリースを持っている
+		e.leading = false
+	}
+	// 更新に失敗しただけであれば、renewDeadline まではリーダーのままにする
+	leading := e.isLeaderLocked(e.clock.Now())
+	changed := leading != e.reported
+	e.reported = leading
+	holder := e.observed.Holder
+	e.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if leading {
+		e.logger.Info("リーダーになりました", "identity", e.identity)
+	} else {
+		e.logger.Warn("リーダーではなくなりました", "identity", e.identity, "holder", holder)
+	}
+}
+
+// acquireOrRenew はリースを持っていれば更新し、誰も持っていないか持ち主が更新しなくなっていれば取得します。
+// ほかのレプリカがリースを持っている場合は false を返します
+func (e *Elector) acquireOrRenew(ctx context.Context, now time.Time) (bool, error) {
+	record, version, err := e.backend.Get(ctx)
+	if errors.Is(err, ErrNotFound) {
+		err = e.backend.Create(ctx, Record{Holder: e.identity, Duration: e.duration, AcquireTime: now, RenewTime: now})
+		if errors.Is(err, ErrConflict) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	e.mu.Lock()
+	if *record != e.observed {
+		e.observed, e.observedAt = *record, now
+	}
+	observedAt := e.observedAt
+	e.mu.Unlock()
+	if record.Holder != "" && record.Holder != e.identity && now.Before(observedAt.Add(record.Duration)) {
+		return false, nil
+	}
+
+	next := *record
+	if record.Holder != e.identity {
+		next.AcquireTime = now
+		next.Transitions++
+	}
+	next.Holder, next.Duration, next.RenewTime = e.identity, e.duration, now
+	err = e.backend.Update(ctx, next, version)
+	if errors.Is(err, ErrConflict) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// release は自分が持っているリースを手放します
+func (e *Elector) release(ctx context.Context) error {
+	record, version, err := e.backend.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if record.Holder != e.identity {
+		return nil
+	}
+	record.Holder = ""
+	record.Duration = time.Second
+	record.RenewTime = e.clock.Now()
+	return e.backend.Update(ctx, *record, version)
+}
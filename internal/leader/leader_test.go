@@ -0,0 +1,137 @@
+package leader
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+)
+
+// memoryBackend はメモリ上のリースです。Update はバージョンが一致した場合だけ成功します
+type memoryBackend struct {
+	mu      sync.Mutex
+	record  *Record
+	version int
+}
+
+func (b *memoryBackend) Get(ctx context.Context) (*Record, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.record == nil {
+		return nil, "", ErrNotFound
+	}
+	record := *b.record
+	return &record, strconv.Itoa(b.version), nil
+}
+
+func (b *memoryBackend) Create(ctx context.Context, record Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.record != nil {
+		return ErrConflict
+	}
+	b.record = &record
+	b.version++
+	return nil
+}
+
+func (b *memoryBackend) Update(ctx context.Context, record Record, version string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if version != strconv.Itoa(b.version) {
+		return ErrConflict
+	}
+	b.record = &record
+	b.version++
+	return nil
+}
+
+func (b *memoryBackend) holder() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.record == nil {
+		return ""
+	}
+	return b.record.Holder
+}
+
+func TestElector(t *testing.T) {
+	cfg := &config.Config{LeaderElectionLeaseDuration: 15 * time.Second}
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	backend := &memoryBackend{}
+	a := New(cfg, backend, "replica-a", WithClock(fake))
+	b := New(cfg, backend, "replica-b", WithClock(fake))
+	ctx := context.Background()
+
+	// 正常系: リースがなければ最初のレプリカがリーダーになる
+	a.tryAcquireOrRenew(ctx)
+	b.tryAcquireOrRenew(ctx)
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("IsLeader() = %v, %v, want only replica-a", a.IsLeader(), b.IsLeader())
+	}
+
+	// 正常系: リーダーが更新している間は引き継がない
+	for i := 0; i < 10; i++ {
+		fake.Advance(3 * time.Second)
+		a.tryAcquireOrRenew(ctx)
+		b.tryAcquireOrRenew(ctx)
+	}
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("IsLeader() = %v, %v, want replica-a to keep the lease", a.IsLeader(), b.IsLeader())
+	}
+
+	// 正常系: リーダーが更新しなくなると、更新の期限でリーダーをやめ、リースの期間の後にほかのレプリカが引き継ぐ
+	fake.Advance(10 * time.Second)
+	if a.IsLeader() {
+		t.Errorf("IsLeader() = true after the renew deadline, want false")
+	}
+	b.tryAcquireOrRenew(ctx)
+	if b.IsLeader() {
+		t.Errorf("replica-b took over before the lease expired")
+	}
+	fake.Advance(6 * time.Second)
+	b.tryAcquireOrRenew(ctx)
+	if !b.IsLeader() || backend.holder() != "replica-b" {
+		t.Fatalf("IsLeader() = %v, holder = %q, want replica-b", b.IsLeader(), backend.holder())
+	}
+	if backend.record.Transitions != 1 {
+		t.Errorf("Transitions = %d, want 1", backend.record.Transitions)
+	}
+
+	// 正常系: 元のリーダーが戻ってきても、リースを持っているレプリカがリーダーのまま
+	a.tryAcquireOrRenew(ctx)
+	if a.IsLeader() {
+		t.Errorf("replica-a took the lease back while replica-b holds it")
+	}
+}
+
+func TestElector_ShutdownReleasesLease(t *testing.T) {
+	cfg := &config.Config{LeaderElectionLeaseDuration: 15 * time.Second}
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	backend := &memoryBackend{}
+	a := New(cfg, backend, "replica-a", WithClock(fake))
+	b := New(cfg, backend, "replica-b", WithClock(fake))
+
+	if err := a.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if !a.IsLeader() {
+		t.Fatalf("IsLeader() = false after Start, want true")
+	}
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if a.IsLeader() || backend.holder() != "" {
+		t.Fatalf("IsLeader() = %v, holder = %q after Shutdown, want the lease released", a.IsLeader(), backend.holder())
+	}
+
+	// 正常系: 手放したリースはリースの期間を待たずに引き継げる
+	b.tryAcquireOrRenew(context.Background())
+	if !b.IsLeader() {
+		t.Errorf("replica-b could not take over a released lease")
+	}
+}
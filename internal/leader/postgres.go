@@ -0,0 +1,314 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// lockSession はアドバイザリロックを持つデータベースとのセッションです。
+// ロックはセッションごとのため、ロックを取得したセッションは Unlock か Reset まで持ち続けます
+type lockSession interface {
+	// TryLock はロックの取得を試み、取得できたかを返します
+	TryLock(ctx context.Context) (bool, error)
+	// Held は持っているセッションがまだロックを持っているかを、データベースに問い合わせて確かめます
+	Held(ctx context.Context) (bool, error)
+	// Unlock はロックを手放してセッションを閉じます
+	Unlock(ctx context.Context) error
+	// Reset はセッションを捨てます。セッションが切れていればロックも解放されています
+	Reset()
+	// Close はデータベースとの接続を閉じます
+	Close() error
+}
+
+// AdvisoryLock は PostgreSQL のアドバイザリロック（pg_try_advisory_lock）を取り合ってリーダーを選びます。
+// ロックはデータベースとの接続（セッション）ごとのため、リーダーは接続を持ち続けます。
+// リーダーが停止したり接続が切れたりするとロックはすぐに解放され、ほかのレプリカが引き継ぎます
+type AdvisoryLock struct {
+	session       lockSession
+	name          string
+	identity      string
+	renewDeadline time.Duration // ロックを持っていることを確かめられない状態がこれだけ続くと、リーダーをやめます
+	retryPeriod   time.Duration // ロックを取得・確認する間隔
+	clock         clock.Clock
+	logger        *slog.Logger
+
+	mu        sync.Mutex // 以下のフィールドを保護します
+	holding   bool       // セッションがロックを取得してから、失ったとわかるまで true
+	checkedAt time.Time  // 最後にロックを持っていることを確かめた時刻
+	reported  bool       // 最後にログに出力したリーダーかどうか
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewAdvisoryLock は STATE_DSN の PostgreSQL のアドバイザリロックを identity の名前で取り合う AdvisoryLock を作成します。
+// bots のボットでは、ボットごとに別のロック（LEADER_ELECTION_LEASE-<ボットの名前>）を使います
+func NewAdvisoryLock(cfg *config.Config, identity string) (*AdvisoryLock, error) {
+	db, err := state.OpenDB(config.StateBackendPostgres, cfg.StateDSN)
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.LeaderElectionLease
+	if bot := cfg.Bot(); bot != "" {
+		name += "-" + bot
+	}
+	return newAdvisoryLock(cfg, &postgresSession{db: db, key: lockKey(name)}, name, identity), nil
+}
+
+// newAdvisoryLock は session のロックを取り合う AdvisoryLock を作成します
+func newAdvisoryLock(cfg *config.Config, session lockSession, name, identity string) *AdvisoryLock {
+	duration := cfg.LeaderElectionLeaseDuration
+	return &AdvisoryLock{
+		session:       session,
+		name:          name,
+		identity:      identity,
+		renewDeadline: duration * 2 / 3,
+		retryPeriod:   duration / 5,
+		clock:         clock.Real,
+		logger:        logging.ModuleFor(cfg, "leader"),
+	}
+}
+
+// lockKey は name からアドバイザリロックのキーを作ります。ほかのアプリケーションのロックと重ならないよう接頭辞を付けます
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("quotebot-leader:" + name))
+	return int64(h.Sum64())
+}
+
+// IsLeader はこのレプリカがリーダーかを返します。ロックを持っていることを確かめられない状態が続いている場合は、
+// 接続が切れてほかのレプリカが引き継いでいる可能性があるため、リーダーをやめます
+func (l *AdvisoryLock) IsLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.isLeaderLocked(l.clock.Now())
+}
+
+// isLeaderLocked は now の時点でリーダーかを返します。l.mu を持って呼び出します
+func (l *AdvisoryLock) isLeaderLocked(now time.Time) bool {
+	return l.holding && now.Before(l.checkedAt.Add(l.renewDeadline))
+}
+
+// ConfirmLeader はリーダーであれば、まだロックを持っていることをデータベースに問い合わせて確かめます（app.LeaderConfirmer）。
+// 接続が切れてほかのレプリカが引き継いでいても、renewDeadline までは IsLeader が true を返すため、投稿の直前に確かめ直します
+func (l *AdvisoryLock) ConfirmLeader(ctx context.Context) bool {
+	if !l.IsLeader() {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.retryPeriod)
+	defer cancel()
+	held, err := l.session.Held(ctx)
+	if err != nil {
+		l.logger.Warn("アドバイザリロックの確認に失敗しました", "error", redact.Error(err))
+		return false
+	}
+	return held
+}
+
+// Start はロックを1回取り合ってから、バックグラウンドでロックの取得・確認を始めます。
+// 起動してすぐに投稿するかを決められるよう、最初の1回は戻る前に行います
+func (l *AdvisoryLock) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.done = make(chan struct{})
+	l.logger.Info("リーダー選出を開始します", "identity", l.identity, "lock", l.name)
+
+	l.tryAcquireOrCheck(ctx)
+	safego.Go(l.logger, "leader", func() {
+		defer close(l.done)
+		safego.Loop(l.logger, "leader", ctx.Done(), func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-l.clock.After(l.retryPeriod):
+					l.tryAcquireOrCheck(ctx)
+				}
+			}
+		})
+	})
+	return nil
+}
+
+// Shutdown はロックの取得・確認をやめ、リーダーであればロックを手放してデータベースとの接続を閉じます
+func (l *AdvisoryLock) Shutdown(ctx context.Context) error {
+	if l.cancel == nil {
+		return l.session.Close()
+	}
+	l.cancel()
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	wasLeader := l.holding
+	l.holding = false
+	l.mu.Unlock()
+	if err := l.session.Unlock(ctx); err != nil {
+		l.logger.Warn("アドバイザリロックの解放に失敗しました", "error", redact.Error(err))
+	}
+	// 接続を閉じればロックは解放されるため、解放に失敗してもほかのレプリカは引き継げる
+	if err := l.session.Close(); err != nil {
+		return err
+	}
+	if wasLeader {
+		l.logger.Info("リーダーを辞退しました", "identity", l.identity)
+	}
+	return nil
+}
+
+// tryAcquireOrCheck はロックを持っていればまだ持っていることを確かめ、持っていなければ取得を試みて、
+// リーダーになった・やめたことをログに出力します
+func (l *AdvisoryLock) tryAcquireOrCheck(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, l.retryPeriod)
+	defer cancel()
+
+	l.mu.Lock()
+	holding := l.holding
+	l.mu.Unlock()
+
+	now := l.clock.Now()
+	var held bool
+	var err error
+	if holding {
+		held, err = l.session.Held(ctx)
+	} else {
+		held, err = l.session.TryLock(ctx)
+	}
+	if err != nil && ctx.Err() == nil {
+		l.logger.Warn("アドバイザリロックの確認に失敗しました", "error", redact.Error(err))
+	}
+
+	l.mu.Lock()
+	switch {
+	case held:
+		l.holding, l.checkedAt = true, now
+	case holding && err == nil:
+		// データベースの再起動などでセッションがロックを失った
+		l.holding = false
+	case holding && !l.isLeaderLocked(l.clock.Now()):
+		// 確かめられない状態が renewDeadline まで続いた。接続が切れていればロックも解放されている
+		l.holding = false
+	}
+	// 確認に失敗しただけであれば、renewDeadline まではリーダーのままにする
+	lost := holding && !l.holding
+	leading := l.isLeaderLocked(l.clock.Now())
+	changed := leading != l.reported
+	l.reported = leading
+	l.mu.Unlock()
+
+	if lost {
+		// ロックを持ったまま接続をプールに戻さないよう、接続は捨てる。次の確認で新しい接続で取得し直す
+		l.session.Reset()
+	}
+	if !changed {
+		return
+	}
+	if leading {
+		l.logger.Info("リーダーになりました", "identity", l.identity)
+	} else {
+		l.logger.Warn("リーダーではなくなりました", "identity", l.identity)
+	}
+}
+
+// postgresSession は PostgreSQL のデータベースとのセッションで、key のアドバイザリロックを取り合います
+type postgresSession struct {
+	db  *sql.DB
+	key int64
+
+	mu   sync.Mutex // conn を保護します
+	conn *sql.Conn  // ロックを持っている接続
+}
+
+// heldQuery はこの接続（バックエンド）が key のアドバイザリロックを持っているかを pg_locks で確かめます。
+// bigint のキーは上位32ビットが classid、下位32ビットが objid に入り、objsubid は 1 です
+const heldQuery = `SELECT EXISTS (
+	SELECT 1 FROM pg_locks
+	WHERE locktype = 'advisory' AND pid = pg_backend_pid() AND granted
+		AND classid = $1::bigint::oid AND objid = $2::bigint::oid AND objsubid = 1
+)`
+
+// TryLock はロックを持っていなければ新しい接続で取得を試みます
+func (s *postgresSession) TryLock(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return true, nil
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("データベースへの接続に失敗しました: %w", err)
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, s.key).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("アドバイザリロックの取得に失敗しました: %w", err)
+	}
+	if !acquired {
+		// ほかのレプリカがロックを持っている
+		conn.Close()
+		return false, nil
+	}
+	s.conn = conn
+	return true, nil
+}
+
+// Held はロックを取得した接続が、まだロックを持っているかを確かめます
+func (s *postgresSession) Held(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return false, nil
+	}
+	var held bool
+	if err := s.conn.QueryRowContext(ctx, heldQuery, int64(uint32(s.key>>32)), int64(uint32(s.key))).Scan(&held); err != nil {
+		return false, err
+	}
+	return held, nil
+}
+
+// Unlock はロックを手放して接続を閉じます
+func (s *postgresSession) Unlock(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	_, err := s.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, s.key)
+	s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// Reset はロックを持っていた接続をプールに戻さずに閉じます
+func (s *postgresSession) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return
+	}
+	s.conn.Raw(func(any) error { return driver.ErrBadConn })
+	s.conn.Close()
+	s.conn = nil
+}
+
+// Close はデータベースとの接続を閉じます
+func (s *postgresSession) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,253 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/clock"
+)
+
+// fakeSession はデータベースの代わりに、ロックを取得できるか・持っているかをテストから決めるセッションです
+type fakeSession struct {
+	mu       sync.Mutex
+	free     bool  // TryLock でロックを取得できるか
+	held     bool  // Held の結果
+	heldErr  error // Held のエラー
+	locked   bool  // ロックを取得したセッションを持っているか
+	resets   int
+	unlocked bool
+}
+
+func (s *fakeSession) TryLock(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.free {
+		s.locked, s.held = true, true
+	}
+	return s.locked, nil
+}
+
+func (s *fakeSession) Held(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.heldErr != nil {
+		return false, s.heldErr
+	}
+	return s.locked && s.held, nil
+}
+
+func (s *fakeSession) Unlock(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locked, s.unlocked = false, true
+	return nil
+}
+
+func (s *fakeSession) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locked = false
+	s.resets++
+}
+
+func (s *fakeSession) Close() error { return nil }
+
+func (s *fakeSession) set(f func(s *fakeSession)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s)
+}
+
+func newTestAdvisoryLock(session *fakeSession, fake *clock.Fake) *AdvisoryLock {
+	cfg := &config.Config{LeaderElectionLeaseDuration: 15 * time.Second}
+	l := newAdvisoryLock(cfg, session, "quotebot", "replica-a")
+	l.clock = fake
+	return l
+}
+
+func TestLockKey(t *testing.T) {
+	if lockKey("quotebot") != lockKey("quotebot") {
+		t.Error("lockKey() differs for the same name, want a stable key")
+	}
+	if lockKey("quotebot") == lockKey("quotebot-poetry") {
+		t.Error("lockKey() is the same for different names, want a key per bot")
+	}
+}
+
+func TestAdvisoryLock_AcquireAndCheck(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	session := &fakeSession{}
+	l := newTestAdvisoryLock(session, fake)
+	ctx := context.Background()
+
+	// 正常系: ほかのレプリカがロックを持っている間はリーダーにならない
+	l.tryAcquireOrCheck(ctx)
+	if l.IsLeader() {
+		t.Fatal("IsLeader() = true while the lock is taken, want false")
+	}
+
+	// 正常系: ロックを取得するとリーダーになり、持っていることを確かめている間はリーダーのまま
+	session.set(func(s *fakeSession) { s.free = true })
+	for i := 0; i < 10; i++ {
+		l.tryAcquireOrCheck(ctx)
+		if !l.IsLeader() {
+			t.Fatalf("IsLeader() = false after check %d, want true", i)
+		}
+		fake.Advance(l.retryPeriod)
+	}
+
+	// 正常系: データベースの再起動などでロックを失ったら、すぐにリーダーをやめて接続を捨てる
+	session.set(func(s *fakeSession) { s.held, s.free = false, false })
+	l.tryAcquireOrCheck(ctx)
+	if l.IsLeader() {
+		t.Error("IsLeader() = true after losing the lock, want false")
+	}
+	if session.resets != 1 {
+		t.Errorf("Reset() calls = %d, want 1", session.resets)
+	}
+
+	// 正常系: ロックが空けば新しい接続で取得し直す
+	session.set(func(s *fakeSession) { s.free = true })
+	l.tryAcquireOrCheck(ctx)
+	if !l.IsLeader() {
+		t.Error("IsLeader() = false after the lock is free again, want true")
+	}
+}
+
+func TestAdvisoryLock_CheckError(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	session := &fakeSession{free: true}
+	l := newTestAdvisoryLock(session, fake)
+	ctx := context.Background()
+	l.tryAcquireOrCheck(ctx)
+
+	// 正常系: 一時的に確かめられないだけであれば、renewDeadline まではリーダーのまま
+	session.set(func(s *fakeSession) { s.heldErr = errors.New("connection reset") })
+	fake.Advance(l.retryPeriod)
+	l.tryAcquireOrCheck(ctx)
+	if !l.IsLeader() || session.resets != 0 {
+		t.Fatalf("IsLeader() = %v, resets = %d after one failed check, want leading", l.IsLeader(), session.resets)
+	}
+	session.set(func(s *fakeSession) { s.heldErr = nil })
+	fake.Advance(l.retryPeriod)
+	l.tryAcquireOrCheck(ctx)
+	if !l.IsLeader() {
+		t.Fatal("IsLeader() = false after the check recovers, want true")
+	}
+
+	// 異常系: renewDeadline を過ぎても確かめられなければ、リーダーをやめて接続を捨てる
+	session.set(func(s *fakeSession) { s.heldErr = errors.New("connection reset") })
+	for elapsed := time.Duration(0); elapsed <= l.renewDeadline; elapsed += l.retryPeriod {
+		fake.Advance(l.retryPeriod)
+		l.tryAcquireOrCheck(ctx)
+	}
+	if l.IsLeader() {
+		t.Error("IsLeader() = true past the renew deadline, want false")
+	}
+	if session.resets != 1 {
+		t.Errorf("Reset() calls = %d, want 1", session.resets)
+	}
+}
+
+func TestAdvisoryLock_ConfirmLeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *fakeSession)
+		want   bool
+	}{
+		{name: "正常系: ロックを持っている", modify: func(s *fakeSession) {}, want: true},
+		{name: "異常系: ロックを失っている", modify: func(s *fakeSession) { s.held = false }, want: false},
+		{name: "異常系: ロックを確かめられない", modify: func(s *fakeSession) { s.heldErr = errors.New("connection reset") }, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+			session := &fakeSession{free: true}
+			l := newTestAdvisoryLock(session, fake)
+			l.tryAcquireOrCheck(context.Background())
+
+			// バックグラウンドの確認ではまだリーダーのまま、投稿の直前に確かめ直す
+			session.set(tt.modify)
+			if got := l.ConfirmLeader(context.Background()); got != tt.want {
+				t.Errorf("ConfirmLeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("異常系: リーダーでなければ問い合わせない", func(t *testing.T) {
+		fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		session := &fakeSession{held: true}
+		l := newTestAdvisoryLock(session, fake)
+		if l.ConfirmLeader(context.Background()) {
+			t.Error("ConfirmLeader() = true before acquiring the lock, want false")
+		}
+	})
+}
+
+func TestAdvisoryLock_Shutdown(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	session := &fakeSession{free: true}
+	l := newTestAdvisoryLock(session, fake)
+	if err := l.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if l.IsLeader() || !session.unlocked {
+		t.Errorf("IsLeader() = %v, unlocked = %v after Shutdown(), want the lock released", l.IsLeader(), session.unlocked)
+	}
+}
+
+// TestAdvisoryLock は QUOTEBOT_TEST_POSTGRES_DSN の PostgreSQL データベースで実行します
+func TestAdvisoryLock(t *testing.T) {
+	dsn := os.Getenv("QUOTEBOT_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("QUOTEBOT_TEST_POSTGRES_DSN is not set")
+	}
+	cfg := &config.Config{
+		LeaderElectionLease:         "quotebot-test",
+		LeaderElectionLeaseDuration: 15 * time.Second,
+		StateDSN:                    dsn,
+	}
+	newLock := func(identity string) *AdvisoryLock {
+		l, err := NewAdvisoryLock(cfg, identity)
+		if err != nil {
+			t.Fatalf("NewAdvisoryLock() error = %v", err)
+		}
+		if err := l.Start(); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		return l
+	}
+
+	// 先にロックを取ったレプリカだけがリーダーになる
+	first := newLock("pod-a")
+	second := newLock("pod-b")
+	defer second.Shutdown(context.Background())
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("IsLeader() = %v, %v, want only the first replica leading", first.IsLeader(), second.IsLeader())
+	}
+	// pg_locks でロックを持っていることを確かめられる
+	if !first.ConfirmLeader(context.Background()) || second.ConfirmLeader(context.Background()) {
+		t.Fatal("ConfirmLeader() doesn't match the lock holder, want only the first replica confirmed")
+	}
+
+	// リーダーが停止するとすぐにロックが解放され、次の確認で引き継ぐ
+	if err := first.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	deadline := time.Now().Add(2 * second.retryPeriod)
+	for !second.IsLeader() && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !second.IsLeader() {
+		t.Error("IsLeader() = false, want the second replica to take over")
+	}
+}
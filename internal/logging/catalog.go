@@ -0,0 +1,251 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Log languages
+const (
+	LangJapanese = "ja"
+	LangEnglish  = "en"
+)
+
+// catalog maps a log message, as written at the call site, to its translation per language.
+// Messages are written in either Japanese or English, so each language only lists the
+// messages written in the other one. A message without an entry is logged as written.
+var catalog = map[string]map[string]string{
+	LangEnglish: {
+		"QuoteBotが起動しました":                                "QuoteBot started",
+		"シグナルを受信しました。シャットダウンします":                         "Received signal, shutting down",
+		"セッションを確認しました":                                   "Session verified",
+		"セッションの検証に失敗しました。投稿に失敗する可能性があります":                "Session validation failed; posts may fail",
+		"OAuthログインに失敗しました":                               "OAuth login failed",
+		"状態の取得に失敗しました":                                   "Failed to get the status",
+		"Blueskyリポジトリの初期化に失敗しました":                        "Failed to initialize the Bluesky repository",
+		"ユースケースの初期化に失敗しました":                              "Failed to initialize the use case",
+		"メッセージの投稿に成功しました":                                "Message posted",
+		"メッセージの投稿に失敗しました":                                "Failed to post message",
+		"投稿履歴の記録に失敗しました":                                 "Failed to record post history",
+		"投稿履歴の初期化に失敗しました":                                "Failed to initialize post history",
+		"アラートの送信先の設定に失敗しました":                             "Failed to configure alert channels",
+		"アラートの送信に失敗しました":                                 "Failed to send alert",
+		"アラートを送信しました":                                    "Sent alert",
+		"シャットダウンが完了しました":                                 "Shutdown complete",
+		"アプリケーションの初期化に失敗しました":                            "Failed to initialize the application",
+		"アプリケーションの起動に失敗しました":                             "Failed to start the application",
+		"シャットダウン中にエラーが発生しました":                            "Errors occurred during shutdown",
+		"検証に失敗しました":                                      "Validation failed",
+		"即時投稿に失敗しました":                                    "Failed to post now",
+		"ログインに失敗しました":                                    "Login failed",
+		"ビルド情報":                                          "Build info",
+		"更新の確認に失敗しました":                                   "Update check failed",
+		"新しいバージョンが公開されています":                              "A newer version is available",
+		"設定に問題があります":                                     "Invalid configuration",
+		"設定の表示に失敗しました":                                   "Failed to show the configuration",
+		"設定を再読み込みしました":                                   "Reloaded configuration",
+		"再起動が必要な設定の変更は反映されていません":                         "Configuration changes that require a restart were not applied",
+		"設定の再読み込みに失敗しました":                                "Failed to reload configuration",
+		"DRY_RUN のため投稿しませんでした":                           "Skipped publishing because DRY_RUN is enabled",
+		"プロファイルを使用します":                                   "Using profile",
+		"レポートの作成に失敗しました":                                 "Failed to create report",
+		"投稿のフックでエラーが発生しました":                              "A post pipeline hook failed",
+		"禁止語を含む名言をスキップしました":                              "Skipped a quote containing a denied term",
+		"禁止語を含む名言を投稿します":                                 "Posting a quote containing a denied term",
+		"承認に失敗しました":                                      "Failed to process the approval",
+		"状態ファイルの読み込みに失敗しました":                             "Failed to load the state file",
+		"承認待ちの投稿が承認されました":                                "A post waiting for approval was approved",
+		"承認待ちの投稿が却下されました":                                "A post waiting for approval was rejected",
+		"承認待ちの投稿の読み込みに失敗しました":                            "Failed to load the posts waiting for approval",
+		"期限までに承認されなかった投稿を破棄しました":                         "Discarded a post that was not approved in time",
+		"投稿を承認待ちに入れました":                                  "Queued the post for approval",
+		"送信中の投稿の記録の削除に失敗しました":                            "Failed to remove the in-flight post from the outbox",
+		"送信中の投稿の読み込みに失敗しました":                             "Failed to load the in-flight posts",
+		"送信中だった投稿を確認できませんでした":                            "Could not check an in-flight post from the previous run",
+		"前回の実行で投稿済みだった投稿を記録しました":                         "Recorded a post that was published before the previous run stopped",
+		"前回の実行で送信されなかった投稿を破棄しました":                        "Discarded a post that was not sent before the previous run stopped",
+		"バックフィルに失敗しました":                                  "Backfill failed",
+		"名言の一覧の表示に失敗しました":                                "Failed to list quotes",
+		"gRPC APIを開始しました":                                "gRPC API started",
+		"gRPC APIが停止しました":                                "gRPC API stopped",
+		"名言を追加しました":                                      "Quote added",
+		"投稿先の初期化に失敗しました":                                 "Failed to initialize the publisher",
+		"プロフィールの自己紹介の更新に失敗しました":                          "Failed to update profile description",
+		"プロフィールに固定する投稿の更新に失敗しました":                        "Failed to update pinned post",
+		"DRY_RUN のためプロフィールを更新しませんでした":                    "Dry-run: profile not updated",
+		"プロフィールの自己紹介を更新しました":                             "Profile description updated",
+		"固定できなかった投稿の削除に失敗しました":                           "Failed to delete post that could not be pinned",
+		"プロフィールに固定する投稿を更新しました":                           "Pinned post updated",
+		"前に固定していた投稿の削除に失敗しました":                           "Failed to delete previously pinned post",
+		"プロフィールの状態の保存に失敗しました":                            "Failed to save profile state",
+		"投稿本文のテンプレートの解析に失敗しました":                          "Failed to parse post template",
+		"メンションの取得に失敗しました":                                "Failed to fetch mentions",
+		"待ち時間中のユーザーのリクエストを無視しました":                        "Ignored quote request from user in cooldown",
+		"リクエストされたトピックの名言が見つかりませんでした":                     "No quote found for requested topic",
+		"返信する名言の整形に失敗しました":                               "Failed to format quote for reply",
+		"DRY_RUN のためリクエストに返信しませんでした":                     "Dry-run: quote request not answered",
+		"リクエストへの返信に失敗しました":                               "Failed to reply to quote request",
+		"リクエストに名言を返信しました":                                "Replied to quote request",
+		"名言のリクエストの状態の保存に失敗しました":                          "Failed to save quote request state",
+		"翻訳を返信しました":                                      "Posted translation as a reply",
+		"セッションの有効期限が切れています。quotebot login でログインし直してください": "Session has expired; log in again with quotebot login",
+		"セッションの状態":                                       "Session state",
+		"セッションの状態が変わりました":                                "Session state changed",
+		"リフレッシュトークンが無効なため、アプリパスワードでログインし直します":            "Refresh token was rejected, logging in again with the app password",
+		"ボットの設定に問題があるため起動しません":                           "Bot configuration is invalid, not starting it",
+		"ボットの初期化に失敗したため起動しません":                           "Failed to initialize bot, not starting it",
+		"ボットを起動します":                                      "Starting bots",
+		"ボットが異常終了しました":                                   "Bot crashed",
+		"ボットが停止しました":                                     "Bot stopped",
+		"リーダーではないため投稿をスキップしました":                          "Skipped post because this replica is not the leader",
+		"リーダー選出を開始します":                                   "Starting leader election",
+		"リースの更新に失敗しました":                                  "Failed to renew lease",
+		"リーダーになりました":                                     "Became the leader",
+		"リーダーではなくなりました":                                  "No longer the leader",
+		"リースの解放に失敗しました":                                  "Failed to release lease",
+		"リーダーを辞退しました":                                    "Released leadership",
+		"投稿履歴の同期に失敗しました":                                 "Failed to sync the post history",
+		"特別な日のため定期投稿をスキップしました":                           "Skipped the scheduled post for a special occasion",
+		"特別な日の名言が見つかりません":                                "Quote for the special occasion not found",
+		"特別な日の名言を投稿します":                                  "Posting the quote for the special occasion",
+		"提案された名言を採用しました":                                 "Accepted a suggested quote",
+		"提案された名言を却下しました":                                 "Rejected a suggested quote",
+		"投稿できない名言の提案を無視しました":                             "Ignored a suggested quote that cannot be posted",
+		"名言の提案の保存に失敗しました":                                "Failed to save a quote suggestion",
+		"確認待ちの提案が上限に達しているため、名言の提案を無視しました":                "Ignored a quote suggestion because the inbox is full",
+		"確認待ちの提案と重複する名言の提案を無視しました":                       "Ignored a quote suggestion that duplicates a pending one",
+		"名言の提案を受け付けました":                                  "Received a quote suggestion",
+		"名言の提案の確認位置の保存に失敗しました":                           "Failed to save the suggestion inbox cursor",
+		"名言の提案の確認に失敗しました":                                "Failed to review quote suggestions",
+		"関わらないユーザーのメンションを無視しました":                         "Ignored a mention from an account the bot does not interact with",
+		"統計ページを開始しました":                                   "Public stats page started",
+		"統計ページが停止しました":                                   "Public stats page stopped",
+		"統計ページの表示に失敗しました":                                "Failed to render the public stats page",
+		"統計の集計に失敗しました":                                   "Failed to collect the public stats",
+		"今月の反応の取得に失敗しました":                                "Failed to fetch this month's engagement",
+		"投稿に成功したため投稿間隔を元に戻しました":                          "Post succeeded, restored the post interval",
+		"投稿が続けて失敗したため投稿間隔を延ばしました":                        "Posts keep failing, lengthened the post interval",
+		"投稿先のアカウントが停止されているため定期投稿を止めました":                  "The account is suspended, stopped scheduled posts",
+		"名言の表記の確認に失敗しました":                                "Failed to lint quotes",
+		"ログファイルを開き直せませんでした":                              "Failed to reopen the log file",
+		"稼働を知らせる投稿に失敗しました":                               "Failed to post the heartbeat",
+		"今日は名言を投稿したため稼働を知らせませんでした":                       "Skipped the heartbeat because a quote was posted today",
+		"DRY_RUN のため稼働を知らせる投稿をしませんでした":                   "Did not post the heartbeat because of DRY_RUN",
+		"稼働を知らせる投稿をしました":                                 "Posted the heartbeat",
+		"前に稼働を知らせた投稿の削除に失敗しました":                          "Failed to delete the previous heartbeat post",
+		"稼働を知らせる投稿の状態の保存に失敗しました":                         "Failed to save the heartbeat state",
+		"失敗した投稿の処理に失敗しました":                               "Failed to process the failed posts",
+		"失敗した投稿の保存に失敗しました":                               "Failed to save the failed post",
+		"失敗した投稿を送り直せるよう残しました":                            "Kept the failed post so that it can be retried",
+		"失敗した投稿は投稿済みでした":                                 "The failed post had been posted",
+		"失敗した投稿を送り直します":                                  "Retrying the failed post",
+		"失敗した投稿を破棄しました":                                  "Discarded the failed post",
+		"確認しきれないメンションを次の確認に回します":                         "Leaving the remaining mentions for the next check",
+		"直前にトークンをリフレッシュしたため、予定のリフレッシュを省略します":             "Skipping the scheduled token refresh because the tokens were just refreshed",
+		"トークンをリフレッシュしたため、次回のバックグラウンドトークンリフレッシュを予約し直しました":  "Rescheduled the next background token refresh after a refresh",
+		"TOKEN_REFRESH_INTERVAL がアクセストークンの有効期間より長くなっています": "TOKEN_REFRESH_INTERVAL is longer than the access token lifetime",
+		"ゴルーチンがパニックしました": "A goroutine panicked",
+		"停止中に届いたメンションをさかのぼっています。続きは次の確認でさかのぼります":                 "Going back through the mentions received while stopped; continuing on the next check",
+		"state のサブコマンドの指定が正しくありません":                              "Invalid state subcommand",
+		"状態の暗号化し直しに失敗しました":                                       "Failed to re-encrypt the state",
+		"アドバイザリロックの確認に失敗しました":                                    "Failed to check the advisory lock",
+		"アドバイザリロックの解放に失敗しました":                                    "Failed to release the advisory lock",
+		"ほかの投稿先に投稿しました":                                          "Posted to another destination",
+		"ほかの投稿先への投稿に失敗しました":                                      "Failed to post to another destination",
+		"パニックしたゴルーチンを再開します":                                      "Restarting the goroutine that panicked",
+		"一時停止中のため投稿をスキップしました":                                    "Skipped post while paused",
+		"定期投稿を一時停止しました":                                          "Paused scheduled posts",
+		"定期投稿を再開しました":                                            "Resumed scheduled posts",
+		"名言を再読み込みしました":                                           "Reloaded quotes",
+		"管理APIを開始しました":                                           "Admin API started",
+		"管理APIが停止しました":                                           "Admin API stopped",
+		"OAuthセッションがありません。`quotebot oauth-login` を実行してください":      "No OAuth session; run `quotebot oauth-login`",
+		"トークンがないため、アプリパスワードでセッションを作成します":                         "No tokens, creating a session with the app password",
+		"セッションの作成に成功しました":                                        "Session created",
+		"セッションの作成に失敗しましたが、処理を続行します":                              "Failed to create session, continuing",
+		"TokenManager初期化時にトークンリフレッシュを試みます":                       "Refreshing tokens on TokenManager startup",
+		"初期トークンリフレッシュに成功しました":                                    "Initial token refresh succeeded",
+		"初期トークンリフレッシュに失敗しましたが、処理を続行します":                          "Initial token refresh failed, continuing",
+		"バックグラウンドトークンリフレッシュを開始します":                               "Starting background token refresh",
+		"保存済みのトークンがないため、設定のトークンを使用します":                           "No stored tokens, using the configured tokens",
+		"設定のトークンの方が新しいため、保存済みのトークンは使用しません":                       "Configured tokens are newer, ignoring the stored tokens",
+		"保存済みのトークンを読み込みました":                                      "Loaded stored tokens",
+		"トークンの保存に失敗しました":                                         "Failed to save tokens",
+		"バックグラウンドでトークンリフレッシュを開始します":                              "Background token refresh started",
+		"バックグラウンドでのトークンリフレッシュに成功しました":                            "Background token refresh succeeded",
+		"バックグラウンドでのトークンリフレッシュに失敗しました":                            "Background token refresh failed",
+		"次回のバックグラウンドトークンリフレッシュを予約しました":                           "Scheduled next background token refresh",
+		"トークンリフレッシュのバックグラウンドタスクを終了します":                           "Stopping background token refresh",
+		"アクセストークンの有効期限が近いためリフレッシュします":                            "Access token expires soon, refreshing",
+		"トークンのリフレッシュを実行します":                                      "Refreshing tokens",
+		"新しいトークンの取得とキャッシュが完了しました":                                "Fetched and cached new tokens",
+		"新しいOAuthトークンの取得とキャッシュが完了しました":                           "Fetched and cached new OAuth tokens",
+		"TOKEN_ENCRYPTION_KEY が未設定のため、プロセスごとのランダムな鍵でトークンを暗号化します": "TOKEN_ENCRYPTION_KEY is not set, encrypting tokens with a random per-process key",
+	},
+	LangJapanese: {
+		"Could not set up token store":                             "トークンストアを準備できませんでした",
+		"Could not load stored tokens":                             "保存済みのトークンを読み込めませんでした",
+		"Could not encrypt tokens":                                 "トークンを暗号化できませんでした",
+		"Proactive token refresh failed, trying the current token": "事前のトークンリフレッシュに失敗したため、現在のトークンで試みます",
+		"Post with this idempotency key already exists":            "同じ冪等キーの投稿がすでにあります",
+		"HTTP request":                      "HTTPリクエスト",
+		"HTTP request failed":               "HTTPリクエストに失敗しました",
+		"Request failed, retrying":          "リクエストに失敗したため、再試行します",
+		"Retry budget exhausted, giving up": "再試行バジェットを使い切ったため、再試行を中止します",
+		"Skipping mentions older than the notification page limit":                                 "通知のページ数の上限より古いメンションを読み飛ばします",
+		"PDS computed a different CID for a thread post, replies to it may not show in the thread": "投稿先が計算したスレッドの投稿のCIDが異なるため、返信がスレッドに表示されないことがあります",
+		"Request may have reached the server, not retrying":                                        "リクエストが投稿先に届いた可能性があるため、再試行しません",
+		"Rate limit budget nearly exhausted, waiting for the reset":                                "レート制限の残りが少ないため、リセットまで待機します",
+		"Rate limit budget exhausted, but the reset is too far away to wait for":                   "レート制限の残りがありませんが、リセットまでが長いため待機しません",
+		"Ignoring post with an unreadable record":                                                  "読み込めない投稿を無視します",
+		"Uploaded blob does not match, retrying":                                                   "アップロードしたblobが送信したデータと一致しないため、再試行します",
+		"Request failed and cannot succeed on retry":                                               "再試行しても成功しないため、リクエストを再試行しません",
+		"Failed to write link card cache":                                                          "リンクカードのキャッシュの書き込みに失敗しました",
+		"Ignoring notification with an unreadable post":                                            "読み取れない投稿の通知を無視します",
+		"Started the publisher plugin":                                                             "投稿プラグインを起動しました",
+		"Publisher plugin output":                                                                  "投稿プラグインの出力",
+		"Publisher plugin exited":                                                                  "投稿プラグインが終了しました",
+		"Ignoring invalid output from the publisher plugin":                                        "投稿プラグインの不正な出力を無視します",
+		"Ignoring a response to another request from the publisher plugin":                         "投稿プラグインからの別のリクエストへの応答を無視します",
+		"Rate limit exceeded, backing off":                                                         "レート制限を超えたため、待機して再試行します",
+	},
+}
+
+// SupportedLang reports whether lang has a message catalog
+func SupportedLang(lang string) bool {
+	_, ok := catalog[lang]
+	return ok
+}
+
+// Translate returns the message in the given language, or the message as written
+// if the catalog has no translation for it
+func Translate(lang, msg string) string {
+	if translated, ok := catalog[lang][msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// translateHandler rewrites record messages into the configured language.
+// Attributes are left untouched, so that log processing can rely on their keys and values.
+type translateHandler struct {
+	lang    string
+	handler slog.Handler
+}
+
+func (h *translateHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *translateHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.Message = Translate(h.lang, record.Message)
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *translateHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &translateHandler{lang: h.lang, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *translateHandler) WithGroup(name string) slog.Handler {
+	return &translateHandler{lang: h.lang, handler: h.handler.WithGroup(name)}
+}
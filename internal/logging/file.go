@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/clock"
+)
+
+// FileOptions configures the rotation of a log file
+type FileOptions struct {
+	MaxSize    int64         // Rotate before the file grows beyond this many bytes, 0 to not rotate by size
+	Interval   time.Duration // Rotate at every multiple of this interval (UTC), 0 to not rotate by time
+	MaxBackups int           // Rotated files to keep as path.1, path.2, ...
+}
+
+// File is a log file that rotates itself by size and time, and that can be reopened after
+// an external tool such as logrotate has moved it away
+type File struct {
+	path  string
+	opts  FileOptions
+	clock clock.Clock
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time // zero when not rotating by time
+}
+
+// OpenFile opens path for appending, creating it and its directory when missing
+func OpenFile(path string, opts FileOptions) (*File, error) {
+	f := &File{path: path, opts: opts, clock: clock.Real}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p, rotating the file first when it is due. A record is never split
+// across two files.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.due(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Reopen closes the file and opens path again, so that writes go to a new file after
+// the old one was renamed. It is what logrotate expects on SIGUSR1.
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	return f.open()
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// due reports whether the file must be rotated before writing n more bytes; f.mu must be held
+func (f *File) due(n int) bool {
+	if f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(n) > f.opts.MaxSize {
+		return true
+	}
+	return !f.rotateAt.IsZero() && !f.clock.Now().Before(f.rotateAt)
+}
+
+// open opens path for appending; f.mu must be held or f not yet shared
+func (f *File) open() error {
+	if dir := filepath.Dir(f.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	if f.opts.Interval > 0 {
+		f.rotateAt = f.clock.Now().Truncate(f.opts.Interval).Add(f.opts.Interval)
+	}
+	return nil
+}
+
+// rotate moves the file to path.1, shifting the older files by one and removing those
+// beyond MaxBackups, and opens a new file; f.mu must be held. When the file cannot be
+// moved away, it is opened again so that later writes still go to path.
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	if err := f.shift(); err != nil {
+		if openErr := f.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+	return f.open()
+}
+
+// shift moves the closed file out of the way of a new one; f.mu must be held
+func (f *File) shift() error {
+	if f.opts.MaxBackups <= 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove log file: %w", err)
+		}
+		return nil
+	}
+
+	os.Remove(backupPath(f.path, f.opts.MaxBackups))
+	for i := f.opts.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(f.path, i), backupPath(f.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	if err := os.Rename(f.path, backupPath(f.path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
+
+// backupPath returns the path of the log file rotated n times ago
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
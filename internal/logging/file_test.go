@@ -0,0 +1,157 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/clock"
+)
+
+// readFile はファイルの内容を返します。ファイルがなければ空文字列を返します
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	return string(data)
+}
+
+func TestFile_Rotate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     FileOptions
+		advance  time.Duration
+		writes   []string
+		want     string
+		wantOld  []string // path.1, path.2, ... の内容
+		wantNone string   // 存在しないはずのファイル
+	}{
+		{
+			name:    "正常系: サイズを超える前にローテーションする",
+			opts:    FileOptions{MaxSize: 10, MaxBackups: 2},
+			writes:  []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"},
+			want:    "dddddd\n",
+			wantOld: []string{"cccccc\n", "bbbbbb\n"},
+		},
+		{
+			name:     "正常系: 保持する数が0なら古いファイルを残さない",
+			opts:     FileOptions{MaxSize: 10},
+			writes:   []string{"aaaaaa\n", "bbbbbb\n"},
+			want:     "bbbbbb\n",
+			wantNone: ".1",
+		},
+		{
+			name:    "正常系: 間隔の区切りを過ぎたらローテーションする",
+			opts:    FileOptions{Interval: time.Hour, MaxBackups: 1},
+			advance: time.Hour,
+			writes:  []string{"before\n", "after\n"},
+			want:    "after\n",
+			wantOld: []string{"before\n"},
+		},
+		{
+			name:     "正常系: サイズが0ならローテーションしない",
+			opts:     FileOptions{MaxBackups: 1},
+			writes:   []string{"aaaaaa\n", "bbbbbb\n"},
+			want:     "aaaaaa\nbbbbbb\n",
+			wantNone: ".1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "logs", "quotebot.log")
+			fake := clock.NewFake(time.Date(2024, 1, 2, 3, 30, 0, 0, time.UTC))
+			f := &File{path: path, opts: tt.opts, clock: fake}
+			if err := f.open(); err != nil {
+				t.Fatalf("open() error = %v", err)
+			}
+			defer f.Close()
+
+			for i, line := range tt.writes {
+				// 最後の書き込みの前に時間を進める
+				if i == len(tt.writes)-1 {
+					fake.Advance(tt.advance)
+				}
+				if _, err := f.Write([]byte(line)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("log file = %q, want %q", got, tt.want)
+			}
+			for i, want := range tt.wantOld {
+				if got := readFile(t, backupPath(path, i+1)); got != want {
+					t.Errorf("%s = %q, want %q", backupPath(path, i+1), got, want)
+				}
+			}
+			if tt.wantNone != "" {
+				if _, err := os.Stat(path + tt.wantNone); !os.IsNotExist(err) {
+					t.Errorf("%s exists, want none", path+tt.wantNone)
+				}
+			}
+		})
+	}
+}
+
+func TestFile_RotateRenameError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotebot.log")
+	f := &File{path: path, opts: FileOptions{MaxSize: 10, MaxBackups: 1}, clock: clock.Real}
+	if err := f.open(); err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	defer f.Close()
+
+	// path.1 を空でないディレクトリにして、ローテーションの名前の変更を失敗させる
+	if err := os.MkdirAll(filepath.Join(backupPath(path, 1), "keep"), 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if _, err := f.Write([]byte("aaaaaa\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := f.Write([]byte("bbbbbb\n")); err == nil {
+		t.Fatal("Write() error = nil, want the rename error")
+	}
+
+	// 異常系: ローテーションに失敗しても、閉じたファイルではなく開き直したファイルに書き続ける
+	f.opts.MaxSize = 0
+	if _, err := f.Write([]byte("dddddd\n")); err != nil {
+		t.Fatalf("Write() error = %v, want the reopened file", err)
+	}
+	if got, want := readFile(t, path), "aaaaaa\ndddddd\n"; got != want {
+		t.Errorf("log file = %q, want %q", got, want)
+	}
+}
+
+func TestFile_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotebot.log")
+	f, err := OpenFile(path, FileOptions{})
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write([]byte("before\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// logrotate がファイルを移してから開き直させる
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("Reopen() error = %v", err)
+	}
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if got := readFile(t, path); got != "after\n" {
+		t.Errorf("log file = %q, want %q", got, "after\n")
+	}
+	if got := readFile(t, path+".1"); got != "before\n" {
+		t.Errorf("moved file = %q, want %q", got, "before\n")
+	}
+}
@@ -0,0 +1,235 @@
+// Package logging configures the process-wide log/slog logger and hands out
+// per-module loggers whose level can be set independently.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// Log output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var (
+	mu           sync.RWMutex
+	base         slog.Handler // nil until Setup; slog's default handler is used until then
+	defaultLevel = slog.LevelInfo
+	moduleLevels = map[string]slog.Level{}
+
+	// Loggers read their level through these, so SetLevels applies to loggers that already exist
+	defaultVar = new(slog.LevelVar)
+	moduleVars = map[string]*slog.LevelVar{}
+
+	// output is the log file set up from LOG_FILE, nil when logging to stderr
+	output *File
+)
+
+// Options configures the logger
+type Options struct {
+	Format       string            // FormatText or FormatJSON
+	Level        string            // Default level, see ParseLevel
+	Lang         string            // Message language, LangJapanese or LangEnglish
+	ModuleLevels map[string]string // Module name -> level
+}
+
+// Setup installs the logger configured by LOG_FORMAT, LOG_LEVEL, LOG_LANG, and LOG_MODULE_LEVELS
+// as the slog default, and routes the standard log package through it. With LOG_FILE the logs
+// go to that file, rotated by LOG_MAX_SIZE_MB and LOG_ROTATE_INTERVAL, instead of stderr.
+func Setup(cfg *config.Config) error {
+	opts := Options{
+		Format:       cfg.LogFormat,
+		Level:        cfg.LogLevel,
+		Lang:         cfg.LogLang,
+		ModuleLevels: cfg.LogModuleLevels,
+	}
+	if cfg.LogFile == "" {
+		return SetupWriter(os.Stderr, opts)
+	}
+
+	file, err := OpenFile(cfg.LogFile, FileOptions{
+		MaxSize:    int64(cfg.LogMaxSizeMB) * 1024 * 1024,
+		Interval:   cfg.LogRotateInterval,
+		MaxBackups: cfg.LogMaxBackups,
+	})
+	if err != nil {
+		return err
+	}
+	if err := SetupWriter(file, opts); err != nil {
+		file.Close()
+		return err
+	}
+	mu.Lock()
+	output = file
+	mu.Unlock()
+	return nil
+}
+
+// Reopen reopens the log file set up from LOG_FILE, for logrotate's create mode.
+// It does nothing when logging to stderr.
+func Reopen() error {
+	mu.RLock()
+	file := output
+	mu.RUnlock()
+	if file == nil {
+		return nil
+	}
+	return file.Reopen()
+}
+
+// SetupWriter is Setup with an explicit output, e.g. for tests
+func SetupWriter(w io.Writer, opts Options) error {
+	lvl, levels, err := parseLevels(opts.Level, opts.ModuleLevels)
+	if err != nil {
+		return err
+	}
+	lang := opts.Lang
+	if lang == "" {
+		lang = LangJapanese
+	}
+	if !SupportedLang(lang) {
+		return fmt.Errorf("unknown log language: %s", lang)
+	}
+
+	// The base handler lets everything through; levels are enforced per logger,
+	// so that a module can be more verbose than the default
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return fmt.Errorf("unknown log format: %s", opts.Format)
+	}
+	handler = &translateHandler{lang: lang, handler: handler}
+
+	mu.Lock()
+	base = handler
+	applyLevels(lvl, levels)
+	mu.Unlock()
+
+	slog.SetDefault(slog.New(&levelHandler{level: defaultVar, handler: handler}))
+	return nil
+}
+
+// SetLevels changes LOG_LEVEL and LOG_MODULE_LEVELS at runtime, including for loggers
+// that were already created. The output format and language are kept.
+func SetLevels(level string, modules map[string]string) error {
+	lvl, levels, err := parseLevels(level, modules)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	applyLevels(lvl, levels)
+	mu.Unlock()
+	return nil
+}
+
+// parseLevels parses the default level and the per-module levels
+func parseLevels(level string, modules map[string]string) (slog.Level, map[string]slog.Level, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return 0, nil, err
+	}
+	levels := make(map[string]slog.Level, len(modules))
+	for module, value := range modules {
+		moduleLevel, err := ParseLevel(value)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid level for module %s: %w", module, err)
+		}
+		levels[module] = moduleLevel
+	}
+	return lvl, levels, nil
+}
+
+// applyLevels stores the levels and updates the level of every existing logger; mu must be held
+func applyLevels(lvl slog.Level, levels map[string]slog.Level) {
+	defaultLevel = lvl
+	moduleLevels = levels
+	defaultVar.Set(lvl)
+	for module, v := range moduleVars {
+		v.Set(moduleLevel(module))
+	}
+}
+
+// moduleLevel returns the level for a module; mu must be held
+func moduleLevel(name string) slog.Level {
+	if level, ok := moduleLevels[name]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+// ParseLevel parses debug, info, warn, or error (case-insensitive)
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level: %s", s)
+	}
+	return level, nil
+}
+
+// Module returns a logger that tags every record with module=name and
+// uses the module's level from LOG_MODULE_LEVELS, or LOG_LEVEL otherwise.
+// Loggers are bound to the output configured at the time of the call, so components
+// should get theirs in their constructors, after Setup. Levels follow SetLevels.
+func Module(name string) *slog.Logger {
+	mu.Lock()
+	handler := base
+	level, ok := moduleVars[name]
+	if !ok {
+		level = new(slog.LevelVar)
+		moduleVars[name] = level
+	}
+	level.Set(moduleLevel(name))
+	mu.Unlock()
+
+	if handler == nil {
+		return slog.Default().With("module", name)
+	}
+	return slog.New(&levelHandler{level: level, handler: handler}).With("module", name)
+}
+
+// ModuleFor returns Module(name) for the bot cfg configures. When several bots run in one
+// process (the bots section of the config file), records are also tagged with bot=<name>
+// so that each bot's logs can be told apart.
+func ModuleFor(cfg *config.Config, name string) *slog.Logger {
+	logger := Module(name)
+	if bot := cfg.Bot(); bot != "" {
+		logger = logger.With("bot", bot)
+	}
+	return logger
+}
+
+// levelHandler filters records below a minimum level before passing them on
+type levelHandler struct {
+	level   slog.Leveler
+	handler slog.Handler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithGroup(name)}
+}
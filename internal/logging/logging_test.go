@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// resetLogging はテスト後にグローバルなロガー設定を元に戻します
+func resetLogging(t *testing.T) {
+	t.Helper()
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		mu.Lock()
+		base = nil
+		applyLevels(slog.LevelInfo, map[string]slog.Level{})
+		mu.Unlock()
+	})
+}
+
+func TestSetupWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		level   string
+		lang    string
+		modules map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "正常系: テキスト形式",
+			format: "text",
+			level:  "info",
+		},
+		{
+			name:    "正常系: JSON形式とモジュールごとのレベル",
+			format:  "JSON",
+			level:   "warn",
+			modules: map[string]string{"http": "debug"},
+		},
+		{
+			name:   "正常系: 英語のログ",
+			format: "text",
+			level:  "info",
+			lang:   "en",
+		},
+		{
+			name:    "異常系: 不明な言語",
+			format:  "text",
+			level:   "info",
+			lang:    "fr",
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 不明な形式",
+			format:  "xml",
+			level:   "info",
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 不明なレベル",
+			format:  "text",
+			level:   "verbose",
+			wantErr: true,
+		},
+		{
+			name:    "異常系: モジュールのレベルが不正",
+			format:  "text",
+			level:   "info",
+			modules: map[string]string{"http": "loud"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetLogging(t)
+			var buf bytes.Buffer
+			err := SetupWriter(&buf, Options{Format: tt.format, Level: tt.level, Lang: tt.lang, ModuleLevels: tt.modules})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetupWriter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModule(t *testing.T) {
+	resetLogging(t)
+	var buf bytes.Buffer
+	if err := SetupWriter(&buf, Options{Format: FormatJSON, Level: "warn", ModuleLevels: map[string]string{"http": "debug"}}); err != nil {
+		t.Fatalf("SetupWriter() error = %v", err)
+	}
+
+	// LOG_LEVEL=warn なので token モジュールの Info は出力されない
+	Module("token").Info("suppressed")
+	// http モジュールは debug まで出力される
+	Module("http").Debug("request", "status", 200)
+	Module("token").Error("refresh failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2: %s", len(lines), buf.String())
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if record["module"] != "http" || record["msg"] != "request" || record["level"] != "DEBUG" || record["status"] != float64(200) {
+		t.Errorf("record = %v", record)
+	}
+	if !strings.Contains(lines[1], `"module":"token"`) || !strings.Contains(lines[1], `"level":"ERROR"`) {
+		t.Errorf("second record = %s", lines[1])
+	}
+}
+
+func TestSetLevels(t *testing.T) {
+	resetLogging(t)
+	var buf bytes.Buffer
+	if err := SetupWriter(&buf, Options{Format: FormatJSON, Level: "info"}); err != nil {
+		t.Fatalf("SetupWriter() error = %v", err)
+	}
+	// 作成済みのロガーにも反映される
+	logger := Module("token")
+	logger.Debug("before")
+
+	if err := SetLevels("info", map[string]string{"token": "debug"}); err != nil {
+		t.Fatalf("SetLevels() error = %v", err)
+	}
+	logger.Debug("after")
+	Module("http").Debug("suppressed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"msg":"after"`) {
+		t.Errorf("log lines = %q, want only the record after SetLevels", lines)
+	}
+
+	if err := SetLevels("verbose", nil); err == nil {
+		t.Errorf("SetLevels() with unknown level error = nil, want error")
+	}
+}
@@ -0,0 +1,145 @@
+// Package metrics は処理時間の分布を数えるヒストグラムです。
+// 起動してからの値をメモリ上で数え、管理APIの状態などで p50・p95 を確認できるようにします
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets は処理時間のヒストグラムのバケットの上限です。
+// 名言の選択のような数ミリ秒の処理から、再試行を含む数十秒の投稿までを区別できる幅にしています
+var DefaultDurationBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// Histogram は処理時間をバケットごとに数えます。複数のゴルーチンから使えます
+type Histogram struct {
+	bounds []time.Duration
+
+	mu     sync.Mutex
+	counts []int64 // bounds ごとの数。最後の要素は最大のバケットを超えた数です
+	count  int64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram は bounds（昇順）をバケットの上限とする Histogram を作成します
+func NewHistogram(bounds []time.Duration) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]int64, len(bounds)+1)}
+}
+
+// Observe は処理時間 d を数えます
+func (h *Histogram) Observe(d time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	h.max = max(h.max, d)
+}
+
+// Bucket は上限 Le 以下だった数です（累積）
+type Bucket struct {
+	Le    time.Duration `json:"le"`
+	Count int64         `json:"count"`
+}
+
+// HistogramSnapshot はある時点の Histogram の値です。P50・P95・P99 はバケットから推定した値です
+type HistogramSnapshot struct {
+	Count   int64         `json:"count"`
+	Sum     time.Duration `json:"sum"`
+	Max     time.Duration `json:"max"`
+	P50     time.Duration `json:"p50"`
+	P95     time.Duration `json:"p95"`
+	P99     time.Duration `json:"p99"`
+	Buckets []Bucket      `json:"buckets"`
+}
+
+// Snapshot は現在の値を返します
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := HistogramSnapshot{Count: h.count, Sum: h.sum, Max: h.max, Buckets: make([]Bucket, len(h.bounds))}
+	var cumulative int64
+	for i, le := range h.bounds {
+		cumulative += h.counts[i]
+		s.Buckets[i] = Bucket{Le: le, Count: cumulative}
+	}
+	s.P50, s.P95, s.P99 = s.Quantile(0.5), s.Quantile(0.95), s.Quantile(0.99)
+	return s
+}
+
+// Quantile は q（0〜1）分位の処理時間を、バケットの中では均等に分布しているとみなして推定します。
+// 最大のバケットを超えた値は、観測した最大値で推定します
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := q * float64(s.Count)
+	var lower time.Duration
+	var below int64
+	for _, b := range s.Buckets {
+		if float64(b.Count) >= rank && b.Count > below {
+			upper := min(b.Le, s.Max)
+			fraction := (rank - float64(below)) / float64(b.Count-below)
+			return lower + time.Duration(fraction*float64(upper-lower))
+		}
+		lower, below = b.Le, b.Count
+	}
+	return s.Max
+}
+
+// Histograms はラベル（処理の段階など）ごとの Histogram です。複数のゴルーチンから使えます
+type Histograms struct {
+	bounds []time.Duration
+
+	mu         sync.Mutex
+	histograms map[string]*Histogram
+}
+
+// NewHistograms は bounds をバケットの上限とする Histograms を作成します
+func NewHistograms(bounds []time.Duration) *Histograms {
+	return &Histograms{bounds: bounds, histograms: map[string]*Histogram{}}
+}
+
+// Observe は label の処理時間 d を数えます
+func (h *Histograms) Observe(label string, d time.Duration) {
+	h.mu.Lock()
+	histogram, ok := h.histograms[label]
+	if !ok {
+		histogram = NewHistogram(h.bounds)
+		h.histograms[label] = histogram
+	}
+	h.mu.Unlock()
+	histogram.Observe(d)
+}
+
+// Snapshot はラベルごとの現在の値を返します。まだ何も数えていない場合は nil です
+func (h *Histograms) Snapshot() map[string]HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.histograms) == 0 {
+		return nil
+	}
+	out := make(map[string]HistogramSnapshot, len(h.histograms))
+	for label, histogram := range h.histograms {
+		out[label] = histogram.Snapshot()
+	}
+	return out
+}
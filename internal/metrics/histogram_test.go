@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]time.Duration{100 * time.Millisecond, time.Second})
+	// 100ms 以下が90件、100ms〜1s が9件、1s を超えたものが1件
+	for i := 0; i < 90; i++ {
+		h.Observe(50 * time.Millisecond)
+	}
+	for i := 0; i < 9; i++ {
+		h.Observe(800 * time.Millisecond)
+	}
+	h.Observe(3 * time.Second)
+
+	s := h.Snapshot()
+	if s.Count != 100 || s.Max != 3*time.Second {
+		t.Fatalf("Snapshot() = %+v, want 100 observations up to 3s", s)
+	}
+	if s.Sum != 90*50*time.Millisecond+9*800*time.Millisecond+3*time.Second {
+		t.Errorf("Sum = %s", s.Sum)
+	}
+	if len(s.Buckets) != 2 || s.Buckets[0].Count != 90 || s.Buckets[1].Count != 99 {
+		t.Errorf("Buckets = %+v, want cumulative counts 90, 99", s.Buckets)
+	}
+
+	tests := []struct {
+		name string
+		q    float64
+		want time.Duration
+	}{
+		{name: "正常系: 最初のバケットの中", q: 0.45, want: 50 * time.Millisecond},
+		{name: "正常系: 2番目のバケットの中", q: 0.95, want: 600 * time.Millisecond},
+		{name: "正常系: 最大のバケットを超えた値は最大値", q: 1, want: 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Quantile(tt.q); got != tt.want {
+				t.Errorf("Quantile(%v) = %s, want %s", tt.q, got, tt.want)
+			}
+		})
+	}
+	if s.P95 != s.Quantile(0.95) {
+		t.Errorf("P95 = %s, want %s", s.P95, s.Quantile(0.95))
+	}
+}
+
+func TestHistograms(t *testing.T) {
+	h := NewHistograms(DefaultDurationBuckets)
+	if s := h.Snapshot(); s != nil {
+		t.Fatalf("Snapshot() = %v, want nil before any observation", s)
+	}
+	h.Observe("select", 2*time.Millisecond)
+	h.Observe("publish:bluesky", 400*time.Millisecond)
+	h.Observe("publish:bluesky", 600*time.Millisecond)
+
+	s := h.Snapshot()
+	if len(s) != 2 || s["select"].Count != 1 || s["publish:bluesky"].Count != 2 {
+		t.Errorf("Snapshot() = %+v, want counts per label", s)
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// Sink はメトリクスを外部の集計サービスに送ります。tags は "key:value" の形式です。
+// 送信に失敗しても呼び出し側には返さないため、投稿の処理を止めることはありません
+type Sink interface {
+	// Count は回数に value を加えます
+	Count(name string, value int64, tags ...string)
+	// Gauge は現在の値を value にします
+	Gauge(name string, value float64, tags ...string)
+	// Timing は処理時間 d を記録します
+	Timing(name string, d time.Duration, tags ...string)
+}
+
+// Discard は何も送らない Sink です（METRICS_BACKEND=none）
+var Discard Sink = discard{}
+
+type discard struct{}
+
+func (discard) Count(string, int64, ...string)          {}
+func (discard) Gauge(string, float64, ...string)        {}
+func (discard) Timing(string, time.Duration, ...string) {}
+
+// NewSink は METRICS_BACKEND の Sink を作成します。none の場合は Discard を返します。
+// bots のボットでは、すべてのメトリクスに bot:<ボットの名前> のタグを付けます
+func NewSink(cfg *config.Config) (Sink, error) {
+	switch cfg.MetricsBackend {
+	case config.MetricsBackendStatsd, config.MetricsBackendDogStatsD:
+		tags := cfg.MetricsTags
+		if bot := cfg.Bot(); bot != "" {
+			tags = append(tags[:len(tags):len(tags)], "bot:"+bot)
+		}
+		return NewStatsd(cfg.MetricsStatsdAddr, cfg.MetricsPrefix, tags, cfg.MetricsBackend == config.MetricsBackendDogStatsD)
+	}
+	return Discard, nil
+}
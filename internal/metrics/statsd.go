@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statsd はメトリクスを statsd または DogStatsD のエージェントにUDPで送ります。
+// タグは DogStatsD の拡張のため、statsd には送りません
+type Statsd struct {
+	conn      net.Conn
+	prefix    string
+	tags      []string // すべてのメトリクスに付けるタグ
+	dogstatsd bool
+}
+
+// NewStatsd は addr（host:port）のエージェントに送る Statsd を作成します。
+// メトリクスの名前の前には prefix を付けます
+func NewStatsd(addr, prefix string, tags []string, dogstatsd bool) (*Statsd, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd のエージェントに接続できません: %w", err)
+	}
+	return &Statsd{conn: conn, prefix: prefix, tags: tags, dogstatsd: dogstatsd}, nil
+}
+
+// Count は回数に value を加えます
+func (s *Statsd) Count(name string, value int64, tags ...string) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Gauge は現在の値を value にします
+func (s *Statsd) Gauge(name string, value float64, tags ...string) {
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// Timing は処理時間 d をミリ秒で記録します
+func (s *Statsd) Timing(name string, d time.Duration, tags ...string) {
+	s.send(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", tags)
+}
+
+// Close はエージェントへの接続を閉じます
+func (s *Statsd) Close() error {
+	return s.conn.Close()
+}
+
+// send は1つのメトリクスを1つのパケットで送ります。UDPのため、エージェントが動いていなくてもエラーにはなりません
+func (s *Statsd) send(name, value, kind string, tags []string) {
+	var b strings.Builder
+	b.WriteString(sanitize(s.prefix + name))
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(kind)
+	if all := slices.Concat(s.tags, tags); s.dogstatsd && len(all) > 0 {
+		b.WriteString("|#")
+		for i, tag := range all {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, value, _ := strings.Cut(tag, ":")
+			b.WriteString(sanitize(key))
+			if value != "" {
+				b.WriteByte(':')
+				b.WriteString(sanitize(value))
+			}
+		}
+	}
+	s.conn.Write([]byte(b.String()))
+}
+
+// sanitize はプロトコルの区切りに使う文字を _ に置き換えます
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// listenStatsd は statsd のエージェントの代わりにパケットを受け取る UDP ソケットを開きます
+func listenStatsd(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// receive は次のパケットを返します
+func receive(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestStatsd(t *testing.T) {
+	tests := []struct {
+		name      string
+		dogstatsd bool
+		send      func(s *Statsd)
+		want      string
+	}{
+		{
+			name: "正常系: statsd にはタグを送らない",
+			send: func(s *Statsd) { s.Count("events", 1, "type:post_succeeded") },
+			want: "quotebot.events:1|c",
+		},
+		{
+			name:      "正常系: DogStatsD には共通のタグとタグを送る",
+			dogstatsd: true,
+			send:      func(s *Statsd) { s.Count("events", 2, "type:post_succeeded") },
+			want:      "quotebot.events:2|c|#env:prod,type:post_succeeded",
+		},
+		{
+			name:      "正常系: 時間はミリ秒",
+			dogstatsd: true,
+			send:      func(s *Statsd) { s.Timing("post.stage", 1500*time.Microsecond, "stage:publish:bluesky") },
+			want:      "quotebot.post.stage:1.5|ms|#env:prod,stage:publish_bluesky",
+		},
+		{
+			name: "正常系: ゲージ",
+			send: func(s *Statsd) { s.Gauge("quotes", 120) },
+			want: "quotebot.quotes:120|g",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := listenStatsd(t)
+			s, err := NewStatsd(agent.LocalAddr().String(), "quotebot.", []string{"env:prod"}, tt.dogstatsd)
+			if err != nil {
+				t.Fatalf("NewStatsd() error = %v", err)
+			}
+			defer s.Close()
+
+			tt.send(s)
+			if got := receive(t, agent); got != tt.want {
+				t.Errorf("packet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	if sink, err := NewSink(&config.Config{MetricsBackend: config.MetricsBackendNone}); err != nil || sink != Discard {
+		t.Errorf("NewSink(none) = %v, %v, want Discard", sink, err)
+	}
+
+	agent := listenStatsd(t)
+	sink, err := NewSink(&config.Config{MetricsBackend: config.MetricsBackendDogStatsD, MetricsStatsdAddr: agent.LocalAddr().String(), MetricsPrefix: "qb."})
+	if err != nil {
+		t.Fatalf("NewSink() error = %v", err)
+	}
+	defer sink.(*Statsd).Close()
+	sink.Count("events", 1)
+	if got := receive(t, agent); got != "qb.events:1|c" {
+		t.Errorf("packet = %q, want qb.events:1|c", got)
+	}
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+)
+
+// Email はSMTPでメールを送信して通知します
+type Email struct {
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+	logger   *slog.Logger
+
+	// sendMail はテストで差し替えられるようにしています
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmail は ALERT_SMTP_ADDR 経由で ALERT_EMAIL_TO に送信する Email を作成します
+func NewEmail(cfg *config.Config) *Email {
+	return &Email{
+		addr:     cfg.AlertSMTPAddr,
+		username: cfg.AlertSMTPUsername,
+		password: cfg.AlertSMTPPassword,
+		from:     cfg.AlertEmailFrom,
+		to:       cfg.AlertEmailTo,
+		logger:   logging.ModuleFor(cfg, "notify"),
+		sendMail: smtp.SendMail,
+	}
+}
+
+// Notify はメールを送信します。smtp.SendMail はコンテキストに対応していないため、
+// キャンセルされた場合は送信の完了を待たずに戻ります
+func (e *Email) Notify(ctx context.Context, alert Alert) error {
+	var auth smtp.Auth
+	if e.username != "" {
+		host, _, err := net.SplitHostPort(e.addr)
+		if err != nil {
+			return fmt.Errorf("ALERT_SMTP_ADDR が不正です: %w", err)
+		}
+		auth = smtp.PlainAuth("", e.username, e.password, host)
+	}
+
+	msg := e.message(alert)
+	done := make(chan error, 1)
+	safego.Go(e.logger, "email", func() {
+		// 送信中にパニックしても、待っている Notify には失敗として返す
+		err := errors.New("送信中にパニックしました")
+		defer func() { done <- err }()
+		err = e.sendMail(e.addr, auth, e.from, e.to, msg)
+	})
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("メールの送信に失敗しました: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("メールの送信に失敗しました: %w", ctx.Err())
+	}
+}
+
+// message はUTF-8のテキストメールを組み立てます
+func (e *Email) message(alert Alert) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", alert.Subject()))
+	fmt.Fprintf(&buf, "Date: %s\r\n", alert.At.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(alert.Message()))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes()
+}
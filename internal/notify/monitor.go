@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+)
+
+// Monitor は処理ごとの連続失敗回数を数え、ALERT_THRESHOLD に達したときに1回だけ通知します。
+// その後に成功すると復旧を通知します
+type Monitor struct {
+	notifier  Notifier
+	threshold int
+	account   string
+	timeout   time.Duration
+	logger    *slog.Logger
+
+	mu        sync.Mutex
+	failures  map[string]int
+	escalated map[string]bool // Escalate で閾値を待たずに通知した処理
+	wg        sync.WaitGroup
+}
+
+// NewMonitor は notifier に通知する Monitor を作成します
+func NewMonitor(cfg *config.Config, notifier Notifier) *Monitor {
+	account := cfg.Handle
+	if account == "" {
+		account = cfg.DID
+	}
+	return &Monitor{
+		notifier:  notifier,
+		threshold: cfg.AlertThreshold,
+		account:   account,
+		timeout:   cfg.HTTPTimeout,
+		logger:    logging.ModuleFor(cfg, "notify"),
+		failures:  make(map[string]int),
+		escalated: make(map[string]bool),
+	}
+}
+
+// Observe は処理の結果を記録します。err が nil の場合は成功として扱います。
+// 通知はバックグラウンドで送信するため、呼び出し元をブロックしません
+func (m *Monitor) Observe(source string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alert := Alert{Source: source, Account: m.account, At: time.Now()}
+	if err == nil {
+		failures := m.failures[source]
+		escalated := m.escalated[source]
+		delete(m.failures, source)
+		delete(m.escalated, source)
+		if failures < m.threshold && !escalated {
+			return
+		}
+		alert.Failures = failures
+		alert.Resolved = true
+	} else {
+		m.failures[source]++
+		if m.failures[source] != m.threshold || m.escalated[source] {
+			return
+		}
+		alert.Failures = m.failures[source]
+		alert.Error = redact.String(err.Error())
+		alert.Class = string(domain.ClassifyError(err))
+	}
+
+	m.wg.Add(1)
+	safego.Go(m.logger, "alert", func() { m.send(alert) })
+}
+
+// Escalate は ALERT_THRESHOLD を待たずにすぐに通知します（再試行しても直らない失敗など）。
+// すでに通知している場合は何もしません。その後に Observe で成功すると復旧を通知します
+func (m *Monitor) Escalate(source string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.escalated[source] || m.failures[source] >= m.threshold {
+		return
+	}
+	m.escalated[source] = true
+	alert := Alert{
+		Source:   source,
+		Account:  m.account,
+		At:       time.Now(),
+		Failures: max(m.failures[source], 1),
+		Error:    redact.String(err.Error()),
+		Class:    string(domain.ClassifyError(err)),
+	}
+
+	m.wg.Add(1)
+	safego.Go(m.logger, "alert", func() { m.send(alert) })
+}
+
+// Wait は送信中の通知がすべて完了するまで待ちます
+func (m *Monitor) Wait() {
+	m.wg.Wait()
+}
+
+// send は通知を送信し、結果をログに出力します
+func (m *Monitor) send(alert Alert) {
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	if err := m.notifier.Notify(ctx, alert); err != nil {
+		m.logger.Warn("アラートの送信に失敗しました", "source", alert.Source, "error", redact.Error(err))
+		return
+	}
+	m.logger.Info("アラートを送信しました", "source", alert.Source, "failures", alert.Failures, "resolved", alert.Resolved)
+}
@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// recordingNotifier は送信された通知を保持します
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, alert Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func TestMonitor_Observe(t *testing.T) {
+	failure := errors.New("failed with Bearer secret-token")
+
+	tests := []struct {
+		name    string
+		results []error
+		want    []Alert
+	}{
+		{
+			name:    "正常系: しきい値未満の失敗は通知しない",
+			results: []error{failure, failure, nil, failure, failure},
+			want:    nil,
+		},
+		{
+			name:    "正常系: しきい値に達したときに1回だけ通知する",
+			results: []error{failure, failure, failure, failure, failure},
+			want: []Alert{
+				{Source: SourcePost, Account: "bot.example.com", Failures: 3, Error: "failed with Bearer [REDACTED]"},
+			},
+		},
+		{
+			name:    "正常系: 通知後に成功すると復旧を通知する",
+			results: []error{failure, failure, failure, failure, nil, nil},
+			want: []Alert{
+				{Source: SourcePost, Account: "bot.example.com", Failures: 3, Error: "failed with Bearer [REDACTED]"},
+				{Source: SourcePost, Account: "bot.example.com", Failures: 4, Resolved: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			cfg := &config.Config{Handle: "bot.example.com", DID: "did:plc:test", AlertThreshold: 3, HTTPTimeout: time.Second}
+			monitor := NewMonitor(cfg, notifier)
+
+			for _, err := range tt.results {
+				monitor.Observe(SourcePost, err)
+				// 送信の順序を確定させる
+				monitor.Wait()
+			}
+
+			if len(notifier.alerts) != len(tt.want) {
+				t.Fatalf("alerts = %+v, want %+v", notifier.alerts, tt.want)
+			}
+			for i, want := range tt.want {
+				got := notifier.alerts[i]
+				if got.At.IsZero() {
+					t.Errorf("alerts[%d].At is not set", i)
+				}
+				got.At = time.Time{}
+				if got != want {
+					t.Errorf("alerts[%d] = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMonitor_ObserveSourcesIndependently(t *testing.T) {
+	notifier := &recordingNotifier{}
+	monitor := NewMonitor(&config.Config{DID: "did:plc:test", AlertThreshold: 2, HTTPTimeout: time.Second}, notifier)
+
+	// 別の処理の成功で失敗回数はリセットされない
+	monitor.Observe(SourceTokenRefresh, errors.New("refresh failed"))
+	monitor.Observe(SourcePost, nil)
+	monitor.Observe(SourceTokenRefresh, errors.New("refresh failed"))
+	monitor.Wait()
+
+	if len(notifier.alerts) != 1 || notifier.alerts[0].Source != SourceTokenRefresh || notifier.alerts[0].Account != "did:plc:test" {
+		t.Errorf("alerts = %+v", notifier.alerts)
+	}
+}
+
+func TestMonitor_Escalate(t *testing.T) {
+	notifier := &recordingNotifier{}
+	cfg := &config.Config{Handle: "bot.example.com", AlertThreshold: 3, HTTPTimeout: time.Second}
+	monitor := NewMonitor(cfg, notifier)
+	failure := &domain.ClassifiedError{Message: "ExpiredToken", Class: domain.ErrorClassAuthExpired}
+
+	// しきい値を待たずに通知し、その後の失敗ではしきい値に達しても通知しない
+	monitor.Escalate(SourceTokenRefresh, failure)
+	monitor.Wait()
+	monitor.Escalate(SourceTokenRefresh, failure)
+	for range 3 {
+		monitor.Observe(SourceTokenRefresh, failure)
+		monitor.Wait()
+	}
+	monitor.Observe(SourceTokenRefresh, nil)
+	monitor.Wait()
+
+	want := []Alert{
+		{Source: SourceTokenRefresh, Account: "bot.example.com", Failures: 1, Error: "ExpiredToken", Class: "auth_expired"},
+		{Source: SourceTokenRefresh, Account: "bot.example.com", Failures: 3, Resolved: true},
+	}
+	if len(notifier.alerts) != len(want) {
+		t.Fatalf("alerts = %+v, want %+v", notifier.alerts, want)
+	}
+	for i := range want {
+		got := notifier.alerts[i]
+		got.At = time.Time{}
+		if got != want[i] {
+			t.Errorf("alerts[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package notify は投稿やトークンのリフレッシュが繰り返し失敗したときに、
+// Webhook、Discord、メールで運用者に通知します
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// 監視する処理
+const (
+	SourcePost         = "post"
+	SourceTokenRefresh = "token_refresh"
+)
+
+// Alert は運用者への通知の内容です
+type Alert struct {
+	Source   string    `json:"source"`
+	Account  string    `json:"account,omitempty"`
+	Failures int       `json:"failures"`
+	Error    string    `json:"error,omitempty"`
+	Class    string    `json:"class,omitempty"` // エラーの分類（domain.ErrorClass）
+	Resolved bool      `json:"resolved"`
+	At       time.Time `json:"at"`
+}
+
+// Message は通知の本文を返します
+func (a Alert) Message() string {
+	subject := a.Subject()
+	if a.Resolved {
+		return subject
+	}
+	message := fmt.Sprintf("%s\n最後のエラー: %s", subject, a.Error)
+	if hint := classHint(domain.ErrorClass(a.Class)); hint != "" {
+		message += "\n" + hint
+	}
+	return message
+}
+
+// classHint はエラーの分類ごとの対処を返します
+func classHint(class domain.ErrorClass) string {
+	switch class {
+	case domain.ErrorClassRateLimited:
+		return "投稿先のレート制限です。しばらくすると回復します。続く場合は POST_INTERVAL を長くしてください"
+	case domain.ErrorClassAuthExpired:
+		return "投稿先の認証が切れています。quotebot login（OAuthの場合は quotebot oauth-login）でログインし直してください"
+	case domain.ErrorClassQuoteTooLong:
+		return "名言が投稿先の文字数の上限を超えています。名言か POST_TEMPLATE を短くしてください"
+	case domain.ErrorClassPoolEmpty:
+		return "投稿できる名言がありません。QUOTES_FILE を確認してください"
+	case domain.ErrorClassAccountSuspended:
+		return "投稿先のアカウントが停止または無効化されたため、定期投稿を止めました。アカウントが復旧したら管理APIの POST /resume で再開してください"
+	default:
+		return ""
+	}
+}
+
+// Subject は通知の件名（1行の要約）を返します
+func (a Alert) Subject() string {
+	name := sourceName(a.Source)
+	account := ""
+	if a.Account != "" {
+		account = fmt.Sprintf("（%s）", a.Account)
+	}
+	if a.Resolved {
+		return fmt.Sprintf("[QuoteBot]%s %sが復旧しました（%d回連続で失敗していました）", account, name, a.Failures)
+	}
+	return fmt.Sprintf("[QuoteBot]%s %sが%d回連続で失敗しました", account, name, a.Failures)
+}
+
+// sourceName は監視する処理の表示名を返します
+func sourceName(source string) string {
+	switch source {
+	case SourcePost:
+		return "投稿"
+	case SourceTokenRefresh:
+		return "トークンのリフレッシュ"
+	default:
+		return source
+	}
+}
+
+// Notifier は通知の送信先です
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// New は設定された送信先すべてに通知する Notifier を作成します。
+// 送信先が1つも設定されていない場合は nil を返します
+func New(cfg *config.Config) (Notifier, error) {
+	var notifiers multiNotifier
+
+	if cfg.AlertWebhookURL != "" {
+		webhook, err := NewWebhook(cfg.AlertWebhookURL, cfg.HTTPTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("ALERT_WEBHOOK_URL が不正です: %w", err)
+		}
+		notifiers = append(notifiers, webhook)
+	}
+	if cfg.AlertDiscordURL != "" {
+		discord, err := NewDiscord(cfg.AlertDiscordURL, cfg.HTTPTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("ALERT_DISCORD_WEBHOOK_URL が不正です: %w", err)
+		}
+		notifiers = append(notifiers, discord)
+	}
+	if len(cfg.AlertEmailTo) > 0 {
+		notifiers = append(notifiers, NewEmail(cfg))
+	}
+
+	switch len(notifiers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return notifiers[0], nil
+	default:
+		return notifiers, nil
+	}
+}
+
+// multiNotifier は複数の送信先に通知します。一部の送信に失敗しても残りの送信先には通知します
+type multiNotifier []Notifier
+
+func (m multiNotifier) Notify(ctx context.Context, alert Alert) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
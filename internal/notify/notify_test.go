@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+var testAlert = Alert{
+	Source:   SourcePost,
+	Account:  "bot.example.com",
+	Failures: 3,
+	Error:    "failed to create record",
+	At:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestAlert_Message(t *testing.T) {
+	tests := []struct {
+		name  string
+		alert Alert
+		want  string
+	}{
+		{
+			name:  "正常系: 失敗の通知には最後のエラーが含まれる",
+			alert: testAlert,
+			want:  "[QuoteBot]（bot.example.com） 投稿が3回連続で失敗しました\n最後のエラー: failed to create record",
+		},
+		{
+			name:  "正常系: 分類できたエラーには対処が含まれる",
+			alert: Alert{Source: SourcePost, Failures: 3, Error: "利用可能な名言がありません", Class: "pool_empty"},
+			want:  "[QuoteBot] 投稿が3回連続で失敗しました\n最後のエラー: 利用可能な名言がありません\n投稿できる名言がありません。QUOTES_FILE を確認してください",
+		},
+		{
+			name:  "正常系: 復旧の通知",
+			alert: Alert{Source: SourceTokenRefresh, Failures: 5, Resolved: true},
+			want:  "[QuoteBot] トークンのリフレッシュが復旧しました（5回連続で失敗していました）",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.alert.Message(); got != tt.want {
+				t.Errorf("Message() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "正常系: 送信先が未設定の場合は無効",
+			cfg:     &config.Config{},
+			wantNil: true,
+			wantErr: false,
+		},
+		{
+			name: "正常系: 複数の送信先",
+			cfg: &config.Config{
+				AlertWebhookURL: "https://hooks.example.com/xxx",
+				AlertDiscordURL: "https://discord.com/api/webhooks/1/token",
+				AlertSMTPAddr:   "smtp.example.com:587",
+				AlertEmailFrom:  "bot@example.com",
+				AlertEmailTo:    []string{"ops@example.com"},
+			},
+			wantNil: false,
+			wantErr: false,
+		},
+		{
+			name:    "異常系: 不正なWebhookのURL",
+			cfg:     &config.Config{AlertWebhookURL: "hooks.example.com/xxx"},
+			wantNil: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("New() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	tests := []struct {
+		name    string
+		newFunc func(string, time.Duration) (*Webhook, error)
+		status  int
+		check   func(t *testing.T, body map[string]interface{})
+		wantErr bool
+	}{
+		{
+			name:    "正常系: 汎用Webhookには本文と詳細が送信される",
+			newFunc: NewWebhook,
+			status:  http.StatusOK,
+			check: func(t *testing.T, body map[string]interface{}) {
+				if body["text"] != testAlert.Message() || body["source"] != SourcePost || body["failures"] != float64(3) {
+					t.Errorf("body = %v", body)
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "正常系: Discordには本文が content として送信される",
+			newFunc: NewDiscord,
+			status:  http.StatusNoContent,
+			check: func(t *testing.T, body map[string]interface{}) {
+				if body["content"] != testAlert.Message() || len(body) != 1 {
+					t.Errorf("body = %v", body)
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "異常系: Webhookがエラーを返す",
+			newFunc: NewWebhook,
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			webhook, err := tt.newFunc(server.URL+"/hook/secret", time.Second)
+			if err != nil {
+				t.Fatalf("newFunc() error = %v", err)
+			}
+			err = webhook.Notify(context.Background(), testAlert)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, body)
+			}
+		})
+	}
+}
+
+func TestWebhook_NotifyHidesURL(t *testing.T) {
+	// 接続できないURLでも、エラーにWebhookのトークンが含まれない
+	webhook, err := NewWebhook("http://127.0.0.1:1/hook/secret-token", time.Second)
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	err = webhook.Notify(context.Background(), testAlert)
+	if err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Notify() error = %v", err)
+	}
+}
+
+func TestEmail_Notify(t *testing.T) {
+	cfg := &config.Config{
+		AlertSMTPAddr:     "smtp.example.com:587",
+		AlertSMTPUsername: "user",
+		AlertSMTPPassword: "password",
+		AlertEmailFrom:    "bot@example.com",
+		AlertEmailTo:      []string{"ops@example.com", "dev@example.com"},
+	}
+	email := NewEmail(cfg)
+
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	email.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		if a == nil {
+			t.Errorf("sendMail() auth = nil, want PLAIN auth")
+		}
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+
+	if err := email.Notify(context.Background(), testAlert); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if gotAddr != cfg.AlertSMTPAddr || gotFrom != cfg.AlertEmailFrom || len(gotTo) != 2 {
+		t.Errorf("sendMail(%q, %q, %v)", gotAddr, gotFrom, gotTo)
+	}
+
+	header, body, _ := strings.Cut(string(gotMsg), "\r\n\r\n")
+	if !strings.Contains(header, "To: ops@example.com, dev@example.com\r\n") || !strings.Contains(header, "Subject: =?UTF-8?b?") {
+		t.Errorf("header = %q", header)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\r\n", ""))
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+	if string(decoded) != testAlert.Message() {
+		t.Errorf("body = %q, want %q", decoded, testAlert.Message())
+	}
+
+	// 送信に失敗した場合はエラーを返す
+	email.sendMail = func(string, smtp.Auth, string, []string, []byte) error {
+		return errors.New("connection refused")
+	}
+	if err := email.Notify(context.Background(), testAlert); err == nil {
+		t.Errorf("Notify() error = nil, want error")
+	}
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookPayload は汎用Webhookに送信するJSONです。
+// `text` を含むため、SlackのIncoming Webhookなどにもそのまま送信できます
+type webhookPayload struct {
+	Text string `json:"text"`
+	Alert
+}
+
+// discordPayload はDiscordのWebhookに送信するJSONです
+type discordPayload struct {
+	Content string `json:"content"`
+}
+
+// Webhook は任意のURLにJSONをPOSTして通知します
+type Webhook struct {
+	url        string
+	payload    func(Alert) interface{}
+	httpClient *http.Client
+}
+
+// NewWebhook は通知の内容をJSONでPOSTする Webhook を作成します
+func NewWebhook(rawURL string, timeout time.Duration) (*Webhook, error) {
+	return newWebhook(rawURL, timeout, func(alert Alert) interface{} {
+		return webhookPayload{Text: alert.Message(), Alert: alert}
+	})
+}
+
+// NewDiscord はDiscordのWebhookに通知する Webhook を作成します
+func NewDiscord(rawURL string, timeout time.Duration) (*Webhook, error) {
+	return newWebhook(rawURL, timeout, func(alert Alert) interface{} {
+		return discordPayload{Content: alert.Message()}
+	})
+}
+
+func newWebhook(rawURL string, timeout time.Duration, payload func(Alert) interface{}) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		// WebhookのURLにはトークンが含まれるため、エラーには含めない
+		return nil, fmt.Errorf("http(s)のURLを指定してください")
+	}
+	return &Webhook{
+		url:        rawURL,
+		payload:    payload,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Notify は通知の内容をWebhookにPOSTします
+func (w *Webhook) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(w.payload(alert))
+	if err != nil {
+		return fmt.Errorf("Webhookのリクエストのエンコードに失敗しました: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Webhookのリクエストの作成に失敗しました: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		// *url.Error はWebhookのURL（トークンを含む）をメッセージに含むため取り除く
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("Webhookへの送信に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhookがエラーを返しました: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Package outbox は送信する前の投稿を状態ファイルに書いておき、
+// 投稿の直後にプロセスが止まっても、再起動したときに投稿済みかを確かめられるようにします
+package outbox
+
+import (
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// stateKey は送信中の投稿を保存する状態のキーです
+const stateKey = "outbox"
+
+// Entry は送信中の投稿です
+type Entry struct {
+	// Key は投稿の冪等キー（domain.NewPostKey）です
+	Key       string       `json:"key"`
+	RequestID string       `json:"requestId"`
+	Trigger   string       `json:"trigger"`
+	Platform  string       `json:"platform"`
+	Quote     domain.Quote `json:"quote"`
+	Text      string       `json:"text"`
+	Variant   string       `json:"variant,omitempty"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// Outbox は送信中の投稿を状態ファイルに保存します
+type Outbox struct {
+	store state.Store
+
+	mu sync.Mutex
+}
+
+// New は Outbox を作成します
+func New(store state.Store) *Outbox {
+	return &Outbox{store: store}
+}
+
+// Begin は送信する前の投稿を保存します。保存できなかった場合は送信しないでください
+func (o *Outbox) Begin(entry Entry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries, err := o.load()
+	if err != nil {
+		return err
+	}
+	return o.store.Put(stateKey, append(entries, entry))
+}
+
+// Done は投稿の結果を記録し終えた投稿を取り除きます
+func (o *Outbox) Done(key string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries, err := o.load()
+	if err != nil {
+		return err
+	}
+	rest := entries[:0]
+	for _, entry := range entries {
+		if entry.Key != key {
+			rest = append(rest, entry)
+		}
+	}
+	if len(rest) == len(entries) {
+		return nil
+	}
+	return o.store.Put(stateKey, rest)
+}
+
+// Pending は送信中のまま残っている投稿を、古い順に返します
+func (o *Outbox) Pending() ([]Entry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.load()
+}
+
+func (o *Outbox) load() ([]Entry, error) {
+	var entries []Entry
+	if _, err := o.store.Get(stateKey, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
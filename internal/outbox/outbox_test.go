@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+func TestOutbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := state.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	o := New(store)
+	for _, key := range []string{"key1", "key2"} {
+		if err := o.Begin(Entry{Key: key, Text: "名言"}); err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+	}
+	if err := o.Done("key1"); err != nil {
+		t.Fatalf("Done() error = %v", err)
+	}
+	if err := o.Done("missing"); err != nil {
+		t.Errorf("Done(missing) error = %v, want nil", err)
+	}
+
+	// 再起動した後も送信中の投稿が残る
+	store, err = state.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	pending, err := New(store).Pending()
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].Key != "key2" {
+		t.Errorf("Pending() = %+v, want key2", pending)
+	}
+}
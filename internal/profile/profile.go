@@ -0,0 +1,351 @@
+// Package profile は投稿するアカウントのプロフィールを更新し続けます。
+// 自己紹介には次の投稿の時刻を書き、最近の名言を並べた投稿をプロフィールに固定します
+package profile
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// DefaultDescription は PROFILE_DESCRIPTION が空の場合の自己紹介のテンプレートです
+const DefaultDescription = `{{if .Paused}}名言の投稿を一時停止しています{{else}}次の名言は {{.NextPostAt.Format "15:04"}} に投稿します{{end}}`
+
+// stateKey は状態ファイルに固定した投稿と最近の名言を保存するキーです
+const stateKey = "profile"
+
+// checkInterval は自己紹介を書き直す必要があるかを確かめる間隔です。
+// 一時停止や投稿間隔の変更はイベントにならないため、定期的に確かめます
+const checkInterval = time.Minute
+
+// excerptLength は固定する投稿に載せる名言の本文の長さ（文字数）の上限です
+const excerptLength = 30
+
+// Updater はプロフィールを更新できる投稿先です
+type Updater interface {
+	UpdateProfile(ctx context.Context, update domain.ProfileUpdate) error
+	Publish(ctx context.Context, message string) (*domain.PostRef, error)
+	DeletePost(ctx context.Context, uri string) error
+}
+
+// StatusSource は自己紹介に書くボットの状態を返します
+type StatusSource interface {
+	Status() app.Status
+}
+
+// QuoteLookup はIDで名言を探します
+type QuoteLookup interface {
+	QuoteByID(id string) (*domain.Quote, error)
+}
+
+// EventStream はボットのイベントを配信します
+type EventStream interface {
+	Subscribe() (<-chan events.Event, func())
+}
+
+// DescriptionData は自己紹介のテンプレートに渡す値です
+type DescriptionData struct {
+	// NextPostAt は次の定期投稿の予定時刻です
+	NextPostAt time.Time
+	// Paused は定期投稿を一時停止しているかです
+	Paused bool
+	// PoolSize は読み込んでいる名言の数です
+	PoolSize int
+}
+
+// recentPost は固定する投稿に並べる、最近投稿した名言です
+type recentPost struct {
+	QuoteID string    `json:"quoteId"`
+	URI     string    `json:"uri"`
+	At      time.Time `json:"at"`
+}
+
+// pinnedPost は固定した投稿です
+type pinnedPost struct {
+	URI string    `json:"uri"`
+	CID string    `json:"cid"`
+	At  time.Time `json:"at"`
+	// Latest は固定した投稿に並べた最新の名言の投稿です
+	Latest string `json:"latest,omitempty"`
+}
+
+// saved は状態ファイルに保存する値です
+type saved struct {
+	Pinned *pinnedPost  `json:"pinned,omitempty"`
+	Recent []recentPost `json:"recent,omitempty"`
+}
+
+// Maintainer はプロフィールを更新し続けます。app.Server として App の実行中だけ動きます
+type Maintainer struct {
+	updater     Updater
+	status      StatusSource
+	quotes      QuoteLookup
+	stream      EventStream
+	store       state.Store // 任意。再起動しても固定した投稿を作り直して古い投稿を消せるようにします
+	description *template.Template
+	pinCount    int
+	pinInterval time.Duration
+	pinTitle    string
+	timeout     time.Duration
+	dryRun      bool
+	logger      *slog.Logger
+	now         func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// 以下は run のゴルーチンだけが使います
+	lastDescription string
+	saved           saved
+}
+
+// New は cfg の PROFILE_* の設定でプロフィールを更新する Maintainer を作成します。store は nil でも構いません
+func New(cfg *config.Config, updater Updater, status StatusSource, quotes QuoteLookup, stream EventStream, store state.Store) (*Maintainer, error) {
+	text := cfg.ProfileDescription
+	if text == "" {
+		text = DefaultDescription
+	}
+	description, err := template.New("description").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("PROFILE_DESCRIPTION の解析に失敗しました: %w", err)
+	}
+	m := &Maintainer{
+		updater:     updater,
+		status:      status,
+		quotes:      quotes,
+		stream:      stream,
+		store:       store,
+		description: description,
+		pinCount:    cfg.ProfilePinCount,
+		pinInterval: cfg.ProfilePinInterval,
+		pinTitle:    cfg.ProfilePinTitle,
+		timeout:     cfg.PostTimeout,
+		dryRun:      cfg.DryRun,
+		logger:      logging.ModuleFor(cfg, "profile"),
+		now:         time.Now,
+	}
+	if store != nil {
+		if _, err := store.Get(stateKey, &m.saved); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Start はイベントの購読を始め、バックグラウンドでプロフィールを更新します
+func (m *Maintainer) Start() error {
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	received, unsubscribe := m.stream.Subscribe()
+	m.wg.Add(1)
+	safego.Go(m.logger, "profile", func() {
+		defer m.wg.Done()
+		defer unsubscribe()
+		safego.Loop(m.logger, "profile", m.ctx.Done(), func() { m.run(received) })
+	})
+	return nil
+}
+
+// Shutdown は実行中の更新を中断して停止します
+func (m *Maintainer) Shutdown(ctx context.Context) error {
+	if m.cancel == nil {
+		return nil
+	}
+	m.cancel()
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Maintainer) run(received <-chan events.Event) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	m.refresh()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case event := <-received:
+			if event.Type == events.PostSucceeded && event.QuoteID != "" {
+				m.addRecent(recentPost{QuoteID: event.QuoteID, URI: event.URI, At: event.At})
+			}
+			m.refresh()
+		case <-ticker.C:
+			m.refresh()
+		}
+	}
+}
+
+// refresh は自己紹介が変わっていれば書き直し、固定する投稿を作り直す時期なら作り直します
+func (m *Maintainer) refresh() {
+	if err := m.updateDescription(); err != nil {
+		m.logger.Warn("プロフィールの自己紹介の更新に失敗しました", "error", redact.Error(err))
+	}
+	if m.pinDue(m.now()) {
+		if err := m.updatePinned(); err != nil {
+			m.logger.Warn("プロフィールに固定する投稿の更新に失敗しました", "error", redact.Error(err))
+		}
+	}
+}
+
+// Description は現在の状態から自己紹介を作成します
+func (m *Maintainer) Description() (string, error) {
+	st := m.status.Status()
+	var b strings.Builder
+	data := DescriptionData{NextPostAt: st.NextPostAt, Paused: st.Paused, PoolSize: st.PoolSize}
+	if err := m.description.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("自己紹介の作成に失敗しました: %w", err)
+	}
+	text := strings.TrimSpace(b.String())
+	if n := domain.CountGraphemes(text); n > domain.MaxProfileDescriptionLength {
+		return "", fmt.Errorf("自己紹介が長すぎます（%d文字、上限は%d文字）", n, domain.MaxProfileDescriptionLength)
+	}
+	return text, nil
+}
+
+func (m *Maintainer) updateDescription() error {
+	text, err := m.Description()
+	if err != nil {
+		return err
+	}
+	// まだ次の投稿の時刻が決まっていない
+	if m.status.Status().NextPostAt.IsZero() || text == m.lastDescription {
+		return nil
+	}
+	if m.dryRun {
+		m.logger.Info("DRY_RUN のためプロフィールを更新しませんでした", "description", text)
+	} else {
+		ctx, cancel := context.WithTimeout(m.ctx, m.timeout)
+		defer cancel()
+		if err := m.updater.UpdateProfile(ctx, domain.ProfileUpdate{Description: &text}); err != nil {
+			return err
+		}
+		m.logger.Info("プロフィールの自己紹介を更新しました", "description", text)
+	}
+	m.lastDescription = text
+	return nil
+}
+
+// addRecent は投稿した名言を最近の名言の先頭に加えます
+func (m *Maintainer) addRecent(post recentPost) {
+	recent := append([]recentPost{post}, m.saved.Recent...)
+	if len(recent) > m.pinCount {
+		recent = recent[:m.pinCount]
+	}
+	m.saved.Recent = recent
+	m.save()
+}
+
+// pinDue は固定する投稿を作り直す時期かを返します。前回から新しい名言を投稿していない場合は作り直しません
+func (m *Maintainer) pinDue(now time.Time) bool {
+	if m.pinCount <= 0 || len(m.saved.Recent) == 0 {
+		return false
+	}
+	pinned := m.saved.Pinned
+	if pinned == nil {
+		return true
+	}
+	return pinned.Latest != m.saved.Recent[0].URI && now.Sub(pinned.At) >= m.pinInterval
+}
+
+// IndexText は最近の名言を新しい順に並べた投稿の本文を作成します。
+// Blueskyの上限に収まらない古い名言は省きます
+func (m *Maintainer) IndexText() string {
+	text := m.pinTitle
+	listed := 0
+	for _, post := range m.saved.Recent {
+		quote, err := m.quotes.QuoteByID(post.QuoteID)
+		if err != nil {
+			// 名言ファイルから消された名言は載せない
+			continue
+		}
+		next := text + "\n" + fmt.Sprintf("・%s「%s」", quote.Author, excerpt(quote.Text, excerptLength))
+		if domain.BlueskyCapabilities.Validate(next) != nil {
+			break
+		}
+		text = next
+		listed++
+	}
+	if listed == 0 {
+		return ""
+	}
+	return text
+}
+
+// updatePinned は最近の名言を並べた投稿を作ってプロフィールに固定し、前に固定した投稿を消します
+func (m *Maintainer) updatePinned() error {
+	text := m.IndexText()
+	if text == "" {
+		return nil
+	}
+	if m.dryRun {
+		m.logger.Info("DRY_RUN のためプロフィールを更新しませんでした", "pinned_text", text)
+		m.saved.Pinned = &pinnedPost{At: m.now(), Latest: m.saved.Recent[0].URI}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, m.timeout)
+	defer cancel()
+	ref, err := m.updater.Publish(ctx, text)
+	if err != nil {
+		return err
+	}
+	if err := m.updater.UpdateProfile(ctx, domain.ProfileUpdate{PinnedPost: ref}); err != nil {
+		// 固定できなかった投稿は残さない
+		if err := m.updater.DeletePost(ctx, ref.URI); err != nil {
+			m.logger.Warn("固定できなかった投稿の削除に失敗しました", "uri", ref.URI, "error", redact.Error(err))
+		}
+		return err
+	}
+	previous := m.saved.Pinned
+	m.saved.Pinned = &pinnedPost{URI: ref.URI, CID: ref.CID, At: m.now(), Latest: m.saved.Recent[0].URI}
+	m.save()
+	m.logger.Info("プロフィールに固定する投稿を更新しました", "uri", ref.URI)
+
+	if previous != nil && previous.URI != "" {
+		if err := m.updater.DeletePost(ctx, previous.URI); err != nil {
+			m.logger.Warn("前に固定していた投稿の削除に失敗しました", "uri", previous.URI, "error", redact.Error(err))
+		}
+	}
+	return nil
+}
+
+func (m *Maintainer) save() {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Put(stateKey, m.saved); err != nil {
+		m.logger.Warn("プロフィールの状態の保存に失敗しました", "error", err)
+	}
+}
+
+// excerpt は本文を1行にし、max 文字を超える部分を省略します
+func excerpt(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
@@ -0,0 +1,238 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+// mockUpdater はプロフィールの更新と投稿を記録します
+type mockUpdater struct {
+	updates []domain.ProfileUpdate
+	posts   []string
+	deleted []string
+	err     error
+}
+
+func (u *mockUpdater) UpdateProfile(ctx context.Context, update domain.ProfileUpdate) error {
+	if u.err != nil {
+		return u.err
+	}
+	u.updates = append(u.updates, update)
+	return nil
+}
+
+func (u *mockUpdater) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	u.posts = append(u.posts, message)
+	n := len(u.posts)
+	return &domain.PostRef{URI: fmt.Sprintf("at://did:plc:test/app.bsky.feed.post/index%d", n), CID: fmt.Sprintf("bafy%d", n)}, nil
+}
+
+func (u *mockUpdater) DeletePost(ctx context.Context, uri string) error {
+	u.deleted = append(u.deleted, uri)
+	return nil
+}
+
+type mockStatus struct {
+	status app.Status
+}
+
+func (s *mockStatus) Status() app.Status {
+	return s.status
+}
+
+type mockQuotes map[string]domain.Quote
+
+func (q mockQuotes) QuoteByID(id string) (*domain.Quote, error) {
+	quote, ok := q[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &quote, nil
+}
+
+func newTestMaintainer(t *testing.T, cfg *config.Config, store state.Store) (*Maintainer, *mockUpdater, *mockStatus, *time.Time) {
+	t.Helper()
+	updater := &mockUpdater{}
+	status := &mockStatus{status: app.Status{NextPostAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), PoolSize: 3}}
+	quotes := mockQuotes{
+		"q1": {ID: "q1", Text: "知は力なり", Author: "ベーコン"},
+		"q2": {ID: "q2", Text: "我思う、ゆえに我あり", Author: "デカルト"},
+	}
+	m, err := New(cfg, updater, status, quotes, events.NewBus(), store)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.ctx = context.Background()
+	return m, updater, status, &now
+}
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		PostTimeout:        time.Minute,
+		ProfilePinCount:    5,
+		ProfilePinInterval: 24 * time.Hour,
+		ProfilePinTitle:    "最近の名言",
+	}
+}
+
+func TestMaintainer_Description(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		paused   bool
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "正常系: デフォルトのテンプレート",
+			want: "次の名言は 12:00 に投稿します",
+		},
+		{
+			name:   "正常系: 一時停止中",
+			paused: true,
+			want:   "名言の投稿を一時停止しています",
+		},
+		{
+			name:     "正常系: 名言の数を使うテンプレート",
+			template: "{{.PoolSize}}件の名言を投稿しています",
+			want:     "3件の名言を投稿しています",
+		},
+		{
+			name:     "異常系: 自己紹介の上限を超える",
+			template: strings.Repeat("あ", domain.MaxProfileDescriptionLength+1),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ProfileDescription = tt.template
+			m, _, status, _ := newTestMaintainer(t, cfg, nil)
+			status.status.Paused = tt.paused
+
+			got, err := m.Description()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Description() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Description() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintainer_UpdateDescription(t *testing.T) {
+	m, updater, status, _ := newTestMaintainer(t, newTestConfig(), nil)
+
+	m.refresh()
+	m.refresh()
+	if len(updater.updates) != 1 || *updater.updates[0].Description != "次の名言は 12:00 に投稿します" {
+		t.Fatalf("updates = %+v, want one update", updater.updates)
+	}
+
+	// 次の投稿の時刻が変わったときだけ書き直す
+	status.status.NextPostAt = status.status.NextPostAt.Add(time.Hour)
+	m.refresh()
+	if len(updater.updates) != 2 || *updater.updates[1].Description != "次の名言は 13:00 に投稿します" {
+		t.Errorf("updates = %+v, want the new time", updater.updates)
+	}
+}
+
+func TestMaintainer_Pinned(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	m, updater, _, now := newTestMaintainer(t, newTestConfig(), store)
+
+	m.addRecent(recentPost{QuoteID: "q1", URI: "at://did:plc:test/app.bsky.feed.post/1"})
+	m.addRecent(recentPost{QuoteID: "q2", URI: "at://did:plc:test/app.bsky.feed.post/2"})
+	m.refresh()
+
+	wantText := "最近の名言\n・デカルト「我思う、ゆえに我あり」\n・ベーコン「知は力なり」"
+	if len(updater.posts) != 1 || updater.posts[0] != wantText {
+		t.Fatalf("posts = %q, want %q", updater.posts, wantText)
+	}
+	pinned := updater.updates[len(updater.updates)-1].PinnedPost
+	if pinned == nil || pinned.URI != "at://did:plc:test/app.bsky.feed.post/index1" {
+		t.Errorf("pinned = %+v, want the index post", pinned)
+	}
+
+	// 間隔が経っても新しい名言がなければ作り直さない
+	*now = now.Add(25 * time.Hour)
+	m.refresh()
+	if len(updater.posts) != 1 {
+		t.Errorf("posts = %d, want no new index post", len(updater.posts))
+	}
+
+	// 新しい名言を投稿しても間隔が経つまでは作り直さない
+	m.addRecent(recentPost{QuoteID: "q1", URI: "at://did:plc:test/app.bsky.feed.post/3"})
+	m.saved.Pinned.At = *now
+	m.refresh()
+	if len(updater.posts) != 1 {
+		t.Errorf("posts = %d, want no new index post before the interval", len(updater.posts))
+	}
+
+	// 再起動しても前に固定した投稿を覚えていて、作り直したら消す
+	restarted, updater, _, now := newTestMaintainer(t, newTestConfig(), store)
+	*now = now.Add(50 * time.Hour)
+	restarted.refresh()
+	if len(updater.posts) != 1 || len(updater.deleted) != 1 || updater.deleted[0] != "at://did:plc:test/app.bsky.feed.post/index1" {
+		t.Errorf("posts = %q, deleted = %q, want the previous index post deleted", updater.posts, updater.deleted)
+	}
+}
+
+func TestMaintainer_IndexText(t *testing.T) {
+	m, _, _, _ := newTestMaintainer(t, newTestConfig(), nil)
+	if got := m.IndexText(); got != "" {
+		t.Errorf("IndexText() = %q, want empty without posts", got)
+	}
+
+	// 名言ファイルから消された名言は載せない
+	m.saved.Recent = []recentPost{{QuoteID: "removed"}, {QuoteID: "q1"}}
+	if got := m.IndexText(); got != "最近の名言\n・ベーコン「知は力なり」" {
+		t.Errorf("IndexText() = %q", got)
+	}
+
+	// Blueskyの上限に収まらない古い名言は省く
+	long := mockQuotes{}
+	m.saved.Recent = nil
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("long%d", i)
+		long[id] = domain.Quote{ID: id, Text: strings.Repeat("長", 50), Author: "著者"}
+		m.saved.Recent = append(m.saved.Recent, recentPost{QuoteID: id})
+	}
+	m.quotes = long
+	got := m.IndexText()
+	if err := domain.BlueskyCapabilities.Validate(got); err != nil {
+		t.Errorf("IndexText() is too long: %v", err)
+	}
+	if lines := strings.Count(got, "\n"); lines == 0 || lines >= 20 {
+		t.Errorf("IndexText() has %d quotes, want some but not all", lines)
+	}
+}
+
+func TestMaintainer_DryRun(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.DryRun = true
+	m, updater, _, _ := newTestMaintainer(t, cfg, nil)
+	m.addRecent(recentPost{QuoteID: "q1", URI: "at://did:plc:test/app.bsky.feed.post/1"})
+	m.refresh()
+	if len(updater.updates) != 0 || len(updater.posts) != 0 {
+		t.Errorf("updates = %+v, posts = %q, want nothing in dry-run", updater.updates, updater.posts)
+	}
+}
@@ -0,0 +1,368 @@
+// Package quoterequest はメンションで届いた名言のリクエストに返信します。
+// 「@bot #quote 勇気」のように投稿されると、タグ「勇気」が付いた名言を返信します
+package quoterequest
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/internal/state"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// stateKey は状態ファイルに確認済みのメンションとユーザーごとの返信時刻を保存するキーです
+const stateKey = "quote_requests"
+
+// StateKeys は Responder が状態に保存するキーです
+var StateKeys = []string{stateKey}
+
+// maxScanPages は1回の確認でさかのぼるメンションの通知の最大のページ数です。
+// 停止中にたまったメンションが多い場合は、続きを次の確認でさかのぼります
+const maxScanPages = 20
+
+// Mentions はアカウントへのメンションを取得し、返信できる投稿先です
+type Mentions interface {
+	// MentionPage はメンションの1ページを新しい順に返します。cursor が空なら最新のページです。
+	// next は次の（古い）ページのカーソルで、最後のページでは空です
+	MentionPage(ctx context.Context, cursor string) (mentions []domain.Mention, next string, err error)
+	Reply(ctx context.Context, message string, to domain.Mention) (*domain.PostRef, error)
+}
+
+// QuoteFinder はタグで名言を探します
+type QuoteFinder interface {
+	RandomQuoteWithTag(tag string) (*domain.Quote, error)
+}
+
+// saved は状態ファイルに保存する値です
+type saved struct {
+	// Since は確認済みの最新のメンションの時刻です
+	Since time.Time `json:"since"`
+	// Seen は時刻が Since と同じ確認済みのメンションのURIです。同じ時刻に届いたメンションを
+	// 途中まで確認して止まっても、残りを取りこぼさず確認済みのものにも二重に返信しません
+	Seen []string `json:"seen,omitempty"`
+	// Pages は Since より後のメンションを含む、まだ確認し終えていないページのカーソルです（新しい順）。
+	// 最新のページは含めず、Pages を確認し終えた後に最新のページからさかのぼり直します
+	Pages []string `json:"pages,omitempty"`
+	// ScanCursor は Since までさかのぼる途中で止めたときの、次にさかのぼるページのカーソルです
+	ScanCursor string `json:"scanCursor,omitempty"`
+	// Replied はユーザー（DID）ごとの最後に返信した時刻です
+	Replied map[string]time.Time `json:"replied,omitempty"`
+}
+
+// Responder は定期的にメンションを確認し、名言のリクエストに返信します。
+// app.Server として App の実行中だけ動きます
+type Responder struct {
+	mentions     Mentions
+	quotes       QuoteFinder
+	formatter    *domain.Formatter
+	accounts     domain.AccountFilter
+	store        state.Store
+	did          string
+	hashtag      string
+	lang         string // 翻訳して返信する言語（POST_LANG）
+	cooldown     time.Duration
+	pollInterval time.Duration
+	batchSize    int // 1回の確認で扱う最大のメンション数
+	timeout      time.Duration
+	dryRun       bool
+	logger       *slog.Logger
+	now          func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	saved saved // poll のゴルーチンだけが使います
+}
+
+// New は cfg の QUOTE_REQUEST_* の設定で Responder を作成します。
+// ユーザーごとの待ち時間を再起動しても守るため、store は必須です
+func New(cfg *config.Config, mentions Mentions, quotes QuoteFinder, formatter *domain.Formatter, store state.Store) (*Responder, error) {
+	r := &Responder{
+		mentions:     mentions,
+		quotes:       quotes,
+		formatter:    formatter,
+		accounts:     domain.NewAccountFilter(cfg.DenyDIDs),
+		store:        store,
+		did:          cfg.DID,
+		hashtag:      cfg.QuoteRequestHashtag,
+		lang:         cfg.PostLang,
+		cooldown:     cfg.QuoteRequestCooldown,
+		pollInterval: cfg.QuoteRequestPollInterval,
+		batchSize:    cfg.QuoteRequestBatchSize,
+		timeout:      cfg.PostTimeout,
+		dryRun:       cfg.DryRun,
+		logger:       logging.ModuleFor(cfg, "requests"),
+		now:          time.Now,
+	}
+	if _, err := store.Get(stateKey, &r.saved); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start はバックグラウンドでメンションの確認を始めます
+func (r *Responder) Start() error {
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.wg.Add(1)
+	safego.Go(r.logger, "quote_requests", func() {
+		defer r.wg.Done()
+		safego.Loop(r.logger, "quote_requests", r.ctx.Done(), r.pollLoop)
+	})
+	return nil
+}
+
+// Shutdown は実行中の返信を中断して停止します
+func (r *Responder) Shutdown(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pollLoop は停止するまで pollInterval ごとに poll を呼び出します
+func (r *Responder) pollLoop() {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	for {
+		r.poll()
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll は前回から届いたメンションを確認し、リクエストに返信します
+func (r *Responder) poll() {
+	// 初めて起動したときは、有効にする前のメンションに返信しない
+	if r.saved.Since.IsZero() {
+		r.saved.Since = r.now()
+		r.save()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+	// このポーリングで取得したページ。さかのぼったときのページをもう一度取得しない
+	pages := map[string][]domain.Mention{}
+	if len(r.saved.Pages) == 0 || r.saved.ScanCursor != "" {
+		reached, err := r.scan(ctx, pages)
+		r.save()
+		if err != nil {
+			r.logger.Warn("メンションの取得に失敗しました", "error", redact.Error(err))
+			return
+		}
+		if !reached {
+			r.logger.Info("停止中に届いたメンションをさかのぼっています。続きは次の確認でさかのぼります", "pages", len(r.saved.Pages))
+			return
+		}
+	}
+	if err := r.handlePages(ctx, pages); err != nil {
+		r.logger.Warn("メンションの取得に失敗しました", "error", redact.Error(err))
+	}
+	r.pruneReplied()
+	r.save()
+}
+
+// scan は最新のページ（または ScanCursor）から Since のメンションを含むページまでさかのぼり、
+// ページのカーソルを Pages に加えます。Since まで届いたかどうかを返します
+func (r *Responder) scan(ctx context.Context, pages map[string][]domain.Mention) (bool, error) {
+	cursor := r.saved.ScanCursor
+	for range maxScanPages {
+		mentions, next, err := r.mentions.MentionPage(ctx, cursor)
+		if err != nil {
+			// 次の確認で、取得できなかったページからさかのぼり直す
+			r.saved.ScanCursor = cursor
+			return false, err
+		}
+		pages[cursor] = mentions
+		if cursor != "" {
+			r.saved.Pages = append(r.saved.Pages, cursor)
+		}
+		// ページは新しい順のため、最後のメンションが Since より前ならそれより古いページは確認済み
+		if next == "" || len(mentions) > 0 && mentions[len(mentions)-1].IndexedAt.Before(r.saved.Since) {
+			r.saved.ScanCursor = ""
+			return true, nil
+		}
+		cursor = next
+	}
+	r.saved.ScanCursor = cursor
+	return false, nil
+}
+
+// handlePages は Pages と最新のページのメンションを古い順に確認し、リクエストに返信します。
+// 1回の確認では batchSize 件までを扱い、確認し終えたページは Pages から除きます
+func (r *Responder) handlePages(ctx context.Context, pages map[string][]domain.Mention) error {
+	cursors := slices.Clone(r.saved.Pages)
+	slices.Reverse(cursors)
+	// 最新のページは、このポーリングでさかのぼり始めたときだけ確認する。
+	// 前回さかのぼったときより新しいメンションは、次の確認で最新のページからさかのぼり直して確認する
+	if _, ok := pages[""]; ok {
+		cursors = append(cursors, "")
+	}
+	handled := 0
+	for _, cursor := range cursors {
+		mentions, ok := pages[cursor]
+		if !ok {
+			var err error
+			if mentions, _, err = r.mentions.MentionPage(ctx, cursor); err != nil {
+				return err
+			}
+		}
+		// Since と同じ時刻のメンションも含め、確認済みのものを除いて古い順に扱う
+		mentions = r.unseen(mentions)
+		slices.Reverse(mentions)
+		for _, mention := range mentions {
+			if r.ctx.Err() != nil {
+				return nil
+			}
+			// 停止中にたまったメンションは少しずつ扱い、残りは次の確認に回す
+			if r.batchSize > 0 && handled >= r.batchSize {
+				r.logger.Info("確認しきれないメンションを次の確認に回します", "pages", len(r.saved.Pages))
+				return nil
+			}
+			r.handle(mention)
+			// 1件ごとに保存し、途中で止まっても再起動後に同じメンションへ二重に返信しない
+			r.markSeen(mention)
+			r.save()
+			handled++
+		}
+		if cursor != "" {
+			r.saved.Pages = r.saved.Pages[:len(r.saved.Pages)-1]
+			r.save()
+		}
+	}
+	return nil
+}
+
+// unseen は確認済みの位置より後のメンションを返します
+func (r *Responder) unseen(mentions []domain.Mention) []domain.Mention {
+	var out []domain.Mention
+	for _, mention := range mentions {
+		if mention.IndexedAt.Before(r.saved.Since) ||
+			mention.IndexedAt.Equal(r.saved.Since) && slices.Contains(r.saved.Seen, mention.Post.URI) {
+			continue
+		}
+		out = append(out, mention)
+	}
+	return out
+}
+
+// markSeen は確認済みの位置を mention まで進めます
+func (r *Responder) markSeen(mention domain.Mention) {
+	switch {
+	case mention.IndexedAt.After(r.saved.Since):
+		r.saved.Since = mention.IndexedAt
+		r.saved.Seen = []string{mention.Post.URI}
+	case mention.IndexedAt.Equal(r.saved.Since):
+		r.saved.Seen = append(r.saved.Seen, mention.Post.URI)
+	}
+}
+
+// handle はリクエストのメンションに名言を返信します。リクエストでないメンションは無視します
+func (r *Responder) handle(mention domain.Mention) {
+	topic, ok := ParseRequest(mention.Text, r.hashtag)
+	if !ok || mention.AuthorDID == r.did {
+		return
+	}
+	logger := r.logger.With("author", mention.AuthorHandle, "topic", topic, "uri", mention.Post.URI)
+	if reason := r.accounts.Ignores(mention); reason != "" {
+		logger.Info("関わらないユーザーのメンションを無視しました", "reason", reason)
+		return
+	}
+
+	now := r.now()
+	if last, ok := r.saved.Replied[mention.AuthorDID]; ok && now.Sub(last) < r.cooldown {
+		logger.Info("待ち時間中のユーザーのリクエストを無視しました", "next_at", last.Add(r.cooldown))
+		return
+	}
+	// 名言が見つからなくても待ち時間は数え始め、存在しないトピックを繰り返し試せないようにする
+	if r.saved.Replied == nil {
+		r.saved.Replied = map[string]time.Time{}
+	}
+	r.saved.Replied[mention.AuthorDID] = now
+
+	quote, err := r.quotes.RandomQuoteWithTag(topic)
+	if err != nil {
+		logger.Info("リクエストされたトピックの名言が見つかりませんでした")
+		return
+	}
+	// 返信はスレッドにしないため、TRANSLATION_MODE にかかわらず翻訳があれば翻訳で返信する
+	quote = quote.Localized(r.lang)
+	text, err := r.formatter.Format(quote, domain.BlueskyCapabilities)
+	if err != nil {
+		logger.Warn("返信する名言の整形に失敗しました", "quote_id", quote.ID, "error", err)
+		return
+	}
+	if r.dryRun {
+		logger.Info("DRY_RUN のためリクエストに返信しませんでした", "quote_id", quote.ID, "text", text)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+	var langs []string
+	if quote.Lang != "" {
+		langs = []string{quote.Lang}
+	}
+	ref, err := r.mentions.Reply(usecase.WithLangs(ctx, langs), text, mention)
+	if err != nil {
+		logger.Warn("リクエストへの返信に失敗しました", "quote_id", quote.ID, "error", redact.Error(err))
+		return
+	}
+	logger.Info("リクエストに名言を返信しました", "quote_id", quote.ID, "reply_uri", ref.URI)
+}
+
+// pruneReplied は待ち時間が過ぎたユーザーを忘れ、状態ファイルが大きくならないようにします
+func (r *Responder) pruneReplied() {
+	now := r.now()
+	for did, last := range r.saved.Replied {
+		if now.Sub(last) >= r.cooldown {
+			delete(r.saved.Replied, did)
+		}
+	}
+}
+
+func (r *Responder) save() {
+	if err := r.store.Put(stateKey, r.saved); err != nil {
+		r.logger.Warn("名言のリクエストの状態の保存に失敗しました", "error", err)
+	}
+}
+
+// ParseRequest は投稿の本文から「#<hashtag> <トピック>」のトピックを取り出します。
+// ハッシュタグは大文字と小文字を区別しません。トピックのないハッシュタグはリクエストとみなしません
+func ParseRequest(text, hashtag string) (topic string, ok bool) {
+	fields := strings.Fields(text)
+	for i, field := range fields {
+		if !strings.EqualFold(field, "#"+hashtag) || i+1 >= len(fields) {
+			continue
+		}
+		topic = strings.TrimPrefix(fields[i+1], "#")
+		if topic != "" {
+			return topic, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,303 @@
+package quoterequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantTopic string
+		wantOK    bool
+	}{
+		{name: "正常系: トピック付きのリクエスト", text: "@bot.bsky.social #quote 勇気", wantTopic: "勇気", wantOK: true},
+		{name: "正常系: 大文字のハッシュタグ", text: "#Quote love please", wantTopic: "love", wantOK: true},
+		{name: "正常系: トピックもハッシュタグ", text: "@bot #quote #courage", wantTopic: "courage", wantOK: true},
+		{name: "異常系: トピックがない", text: "@bot #quote", wantOK: false},
+		{name: "異常系: 別のハッシュタグ", text: "@bot #quotes 勇気", wantOK: false},
+		{name: "異常系: ハッシュタグがない", text: "@bot quote 勇気", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topic, ok := ParseRequest(tt.text, "quote")
+			if topic != tt.wantTopic || ok != tt.wantOK {
+				t.Errorf("ParseRequest() = %q, %v, want %q, %v", topic, ok, tt.wantTopic, tt.wantOK)
+			}
+		})
+	}
+}
+
+// mockMentions は決まったメンションを pageSize 件ずつのページで新しい順に返し、返信を記録します。
+// カーソルは前のページの最後のメンションのURIで、新しいメンションが届いても同じ位置を指します
+type mockMentions struct {
+	mentions []domain.Mention
+	pageSize int               // 0 はすべてを1ページで返す
+	cursors  []string          // 取得したページのカーソル
+	replies  map[string]string // 返信先の投稿のURI → 本文
+	calls    int               // 同じメンションへの二重の返信も数える
+}
+
+func (m *mockMentions) MentionPage(ctx context.Context, cursor string) ([]domain.Mention, string, error) {
+	m.cursors = append(m.cursors, cursor)
+	sorted := slices.Clone(m.mentions)
+	slices.Reverse(sorted)
+	slices.SortStableFunc(sorted, func(a, b domain.Mention) int { return b.IndexedAt.Compare(a.IndexedAt) })
+	if cursor != "" {
+		i := slices.IndexFunc(sorted, func(mention domain.Mention) bool { return mention.Post.URI == cursor })
+		sorted = sorted[i+1:]
+	}
+	if m.pageSize == 0 || len(sorted) <= m.pageSize {
+		return sorted, "", nil
+	}
+	page := sorted[:m.pageSize]
+	return page, page[len(page)-1].Post.URI, nil
+}
+
+func (m *mockMentions) Reply(ctx context.Context, message string, to domain.Mention) (*domain.PostRef, error) {
+	m.replies[to.Post.URI] = message
+	m.calls++
+	return &domain.PostRef{URI: "at://did:plc:bot/app.bsky.feed.post/reply"}, nil
+}
+
+type mockQuotes []domain.Quote
+
+func (q mockQuotes) RandomQuoteWithTag(tag string) (*domain.Quote, error) {
+	for _, quote := range q {
+		if quote.HasTag(tag) {
+			return &quote, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func TestResponder(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	cfg := &config.Config{
+		DID:                  "did:plc:bot",
+		QuoteRequestHashtag:  "quote",
+		QuoteRequestCooldown: time.Hour,
+		PostTimeout:          time.Minute,
+		DenyDIDs:             []string{"did:plc:mallory"},
+	}
+	mentions := &mockMentions{replies: map[string]string{}}
+	quotes := mockQuotes{{ID: "q1", Text: "知は力なり", Author: "ベーコン", Tags: []string{"知識"}}}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	newResponder := func() *Responder {
+		r, err := New(cfg, mentions, quotes, domain.DefaultFormatter(), store)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		r.now = func() time.Time { return now }
+		r.ctx = context.Background()
+		return r
+	}
+	mention := func(n, author, text string, at time.Time) domain.Mention {
+		return domain.Mention{
+			Post:      domain.PostRef{URI: "at://" + author + "/app.bsky.feed.post/" + n},
+			AuthorDID: author,
+			Text:      text,
+			IndexedAt: at,
+		}
+	}
+
+	// 有効にする前のメンションには返信しない
+	mentions.mentions = []domain.Mention{mention("old", "did:plc:alice", "#quote 知識", now.Add(-time.Minute))}
+	r := newResponder()
+	r.poll()
+	r.poll()
+	if len(mentions.replies) != 0 {
+		t.Fatalf("replies = %v, want none for mentions before the first start", mentions.replies)
+	}
+
+	now = now.Add(10 * time.Minute)
+	mentions.mentions = []domain.Mention{
+		mention("1", "did:plc:alice", "@bot #quote 知識", now.Add(-5*time.Minute)),
+		mention("2", "did:plc:alice", "@bot #quote 知識", now.Add(-4*time.Minute)), // 待ち時間中
+		mention("3", "did:plc:bob", "@bot #quote 存在しない", now.Add(-3*time.Minute)),
+		mention("4", "did:plc:bob", "@bot #quote 知識", now.Add(-2*time.Minute)), // 見つからなかったリクエストも待ち時間に数える
+		mention("5", "did:plc:bot", "#quote 知識", now.Add(-time.Minute)),        // 自分の投稿
+		mention("6", "did:plc:carol", "こんにちは", now.Add(-time.Minute)),
+		mention("m", "did:plc:mallory", "@bot #quote 知識", now.Add(-time.Minute)), // DENY_DIDS
+	}
+	muted := mention("d", "did:plc:dave", "@bot #quote 知識", now.Add(-time.Minute))
+	muted.AuthorMuted = true
+	blocked := mention("e", "did:plc:erin", "@bot #quote 知識", now.Add(-time.Minute))
+	blocked.AuthorBlocked = true
+	mentions.mentions = append(mentions.mentions, muted, blocked)
+	r.poll()
+	if len(mentions.replies) != 1 || mentions.replies["at://did:plc:alice/app.bsky.feed.post/1"] != "知は力なり\n- ベーコン" {
+		t.Fatalf("replies = %v, want one reply to the first request", mentions.replies)
+	}
+
+	// 再起動しても待ち時間と確認済みのメンションを覚えている
+	r = newResponder()
+	now = now.Add(30 * time.Minute)
+	mentions.mentions = append(mentions.mentions, mention("7", "did:plc:alice", "#quote 知識", now))
+	r.poll()
+	if len(mentions.replies) != 1 {
+		t.Errorf("replies = %v, want no reply during the cooldown", mentions.replies)
+	}
+
+	now = now.Add(time.Hour)
+	mentions.mentions = append(mentions.mentions, mention("8", "did:plc:alice", "#quote 知識", now))
+	r.poll()
+	if _, ok := mentions.replies["at://did:plc:alice/app.bsky.feed.post/8"]; !ok || len(mentions.replies) != 2 {
+		t.Errorf("replies = %v, want a reply after the cooldown", mentions.replies)
+	}
+}
+
+func TestResponder_DryRun(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	cfg := &config.Config{QuoteRequestHashtag: "quote", QuoteRequestCooldown: time.Hour, PostTimeout: time.Minute, DryRun: true}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	mentions := &mockMentions{replies: map[string]string{}, mentions: []domain.Mention{
+		{Post: domain.PostRef{URI: "at://did:plc:alice/app.bsky.feed.post/1"}, AuthorDID: "did:plc:alice", Text: "#quote 知識", IndexedAt: now.Add(time.Minute)},
+	}}
+	r, err := New(cfg, mentions, mockQuotes{{Text: "知は力なり", Author: "ベーコン", Tags: []string{"知識"}}}, domain.DefaultFormatter(), store)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	r.now = func() time.Time { return now }
+	r.ctx = context.Background()
+	r.poll()
+	r.poll()
+	if len(mentions.cursors) != 1 || len(mentions.replies) != 0 {
+		t.Errorf("cursors = %q, replies = %v, want the mention checked but not answered", mentions.cursors, mentions.replies)
+	}
+}
+
+func TestResponder_Batches(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	cfg := &config.Config{QuoteRequestHashtag: "quote", QuoteRequestBatchSize: 2, PostTimeout: time.Minute}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	mentions := &mockMentions{replies: map[string]string{}}
+	quotes := mockQuotes{{Text: "知は力なり", Author: "ベーコン", Tags: []string{"知識"}}}
+	newResponder := func() *Responder {
+		r, err := New(cfg, mentions, quotes, domain.DefaultFormatter(), store)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		r.now = func() time.Time { return now }
+		r.ctx = context.Background()
+		return r
+	}
+	r := newResponder()
+	r.poll()
+
+	// 停止中に届いたメンション。3件は同じ時刻に届いた
+	at := now.Add(time.Minute)
+	for i, author := range []string{"did:plc:alice", "did:plc:bob", "did:plc:carol", "did:plc:dave"} {
+		indexedAt := at
+		if i == 3 {
+			indexedAt = at.Add(time.Second)
+		}
+		mentions.mentions = append(mentions.mentions, domain.Mention{
+			Post:      domain.PostRef{URI: "at://" + author + "/app.bsky.feed.post/1"},
+			AuthorDID: author,
+			Text:      "#quote 知識",
+			IndexedAt: indexedAt,
+		})
+	}
+	now = now.Add(time.Hour)
+
+	// 1回の確認では BATCH_SIZE 件だけ返信する
+	r.poll()
+	if len(mentions.replies) != 2 {
+		t.Fatalf("replies = %v, want the first batch of two", mentions.replies)
+	}
+
+	// 再起動しても、同じ時刻に届いた残りのメンションを取りこぼさず、返信済みのものには返信しない
+	r = newResponder()
+	r.poll()
+	r.poll()
+	r.poll()
+	if len(mentions.replies) != 4 || mentions.calls != 4 {
+		t.Errorf("replies = %v (%d calls), want one reply to each mention", mentions.replies, mentions.calls)
+	}
+}
+
+func TestResponder_Backlog(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	cfg := &config.Config{QuoteRequestHashtag: "quote", QuoteRequestBatchSize: 10, PostTimeout: time.Minute}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	mentions := &mockMentions{replies: map[string]string{}, pageSize: 1}
+	newResponder := func() *Responder {
+		r, err := New(cfg, mentions, mockQuotes{{Text: "知は力なり", Author: "ベーコン", Tags: []string{"知識"}}}, domain.DefaultFormatter(), store)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		r.now = func() time.Time { return now }
+		r.ctx = context.Background()
+		return r
+	}
+	r := newResponder()
+	r.poll()
+
+	// 停止中に maxScanPages より多くのページのメンションが届いた
+	total := maxScanPages + 5
+	for i := range total {
+		mentions.mentions = append(mentions.mentions, domain.Mention{
+			Post:      domain.PostRef{URI: fmt.Sprintf("at://did:plc:alice/app.bsky.feed.post/%d", i)},
+			AuthorDID: "did:plc:alice",
+			Text:      "#quote 知識",
+			IndexedAt: now.Add(time.Duration(i+1) * time.Second),
+		})
+	}
+	now = now.Add(time.Hour)
+
+	// 1回の確認では maxScanPages ページまでさかのぼり、続きは次の確認でさかのぼる
+	mentions.cursors = nil
+	r.poll()
+	if len(mentions.cursors) != maxScanPages || len(mentions.replies) != 0 {
+		t.Fatalf("cursors = %d, replies = %d, want %d pages read and no reply yet", len(mentions.cursors), len(mentions.replies), maxScanPages)
+	}
+
+	// さかのぼり終えたら古い順に BATCH_SIZE 件ずつ返信し、最新のページからは読み直さない
+	r = newResponder()
+	mentions.cursors = nil
+	r.poll()
+	if len(mentions.replies) != 10 || slices.Contains(mentions.cursors[1:], "") {
+		t.Fatalf("replies = %d, cursors = %q, want the 10 oldest mentions answered without rereading the newest page", len(mentions.replies), mentions.cursors)
+	}
+	if _, ok := mentions.replies["at://did:plc:alice/app.bsky.feed.post/0"]; !ok {
+		t.Errorf("replies = %v, want the oldest mention answered first", mentions.replies)
+	}
+	for range 3 {
+		r.poll()
+	}
+	if len(mentions.replies) != total || mentions.calls != total {
+		t.Errorf("replies = %d (%d calls), want one reply to each of the %d mentions", len(mentions.replies), mentions.calls, total)
+	}
+
+	// 追いついた後は最新のページから確認済みの位置までだけを読む
+	mentions.cursors = nil
+	r.poll()
+	if len(mentions.cursors) > 2 || mentions.calls != total {
+		t.Errorf("cursors = %q, calls = %d, want only the newest pages read and no reply", mentions.cursors, mentions.calls)
+	}
+}
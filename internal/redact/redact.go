@@ -0,0 +1,180 @@
+// Package redact removes credentials from strings, errors, headers, and JSON
+// bodies before they are logged or returned in error messages.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces every redacted value
+const Placeholder = "[REDACTED]"
+
+// sensitiveFields are JSON field and form parameter names whose values are always redacted.
+// Names are compared case-insensitively.
+var sensitiveFields = map[string]bool{
+	"accessjwt":     true,
+	"refreshjwt":    true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"token":         true,
+	"password":      true,
+	"apppassword":   true,
+	"app_password":  true,
+	"passphrase":    true,
+	"secret":        true,
+	"client_secret": true,
+	"code":          true,
+	"code_verifier": true,
+	"dpopkey":       true,
+	"authorization": true,
+}
+
+// safeHeaders are headers that are logged verbatim; all others are redacted
+var safeHeaders = map[string]bool{
+	"Accept":              true,
+	"Content-Type":        true,
+	"Content-Length":      true,
+	"Date":                true,
+	"User-Agent":          true,
+	"X-Request-Id":        true,
+	"Retry-After":         true,
+	"Ratelimit-Limit":     true,
+	"Ratelimit-Remaining": true,
+	"Ratelimit-Reset":     true,
+	"Ratelimit-Policy":    true,
+	"Www-Authenticate":    true,
+	"Dpop-Nonce":          true,
+	"Cache-Control":       true,
+}
+
+var (
+	// jwtPattern matches the structure of a JWT or JWS: three base64url segments, the first being a JSON header
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	// authSchemePattern matches credentials following an HTTP authentication scheme
+	authSchemePattern = regexp.MustCompile(`(?i)\b(Bearer|DPoP|Basic)\s+[A-Za-z0-9._~+/=-]+`)
+	// jsonFieldPattern matches "field": "value" pairs in JSON that could not be parsed, e.g. truncated bodies
+	jsonFieldPattern = regexp.MustCompile(`"([A-Za-z_]+)"\s*:\s*"(?:[^"\\]|\\.)*"?`)
+	// paramPattern matches key=value pairs in query strings, form bodies, and log messages
+	paramPattern = regexp.MustCompile(`\b([A-Za-z_]+)=([^&\s"]+)`)
+)
+
+// String redacts JWTs, authentication scheme credentials, and the values of sensitive
+// JSON fields and key=value parameters in s
+func String(s string) string {
+	if s == "" {
+		return s
+	}
+
+	s = jwtPattern.ReplaceAllString(s, Placeholder)
+	s = authSchemePattern.ReplaceAllString(s, "$1 "+Placeholder)
+	s = jsonFieldPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := jsonFieldPattern.FindStringSubmatch(match)[1]
+		if !sensitiveFields[strings.ToLower(name)] {
+			return match
+		}
+		return `"` + name + `":"` + Placeholder + `"`
+	})
+	s = paramPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := paramPattern.FindStringSubmatch(match)[1]
+		if !sensitiveFields[strings.ToLower(name)] {
+			return match
+		}
+		return name + "=" + Placeholder
+	})
+	return s
+}
+
+// Error returns err with its message redacted. The original error stays
+// reachable through errors.Is and errors.As.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	redacted := String(msg)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{msg: redacted, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Headers returns a copy of h in which every header outside the allowlist is redacted
+func Headers(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for key, values := range h {
+		if safeHeaders[http.CanonicalHeaderKey(key)] {
+			redacted[key] = append([]string(nil), values...)
+			continue
+		}
+		redacted[key] = []string{Placeholder}
+	}
+	return redacted
+}
+
+// JSON scrubs the values of sensitive fields at any depth of a JSON document.
+// Bodies that are not valid JSON (e.g. truncated or HTML error pages) are redacted with String.
+func JSON(body []byte) []byte {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil || decoder.More() {
+		return []byte(String(string(body)))
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(scrub(doc)); err != nil {
+		return []byte(String(string(body)))
+	}
+	// Tokens can also appear inside non-sensitive fields, e.g. in an error message
+	return []byte(String(strings.TrimSuffix(out.String(), "\n")))
+}
+
+// Body redacts an HTTP response or request body for logging
+func Body(body string) string {
+	if strings.TrimSpace(body) == "" {
+		return body
+	}
+	return string(JSON([]byte(body)))
+}
+
+// scrub replaces the values of sensitive fields in a decoded JSON value
+func scrub(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if sensitiveFields[strings.ToLower(key)] {
+				v[key] = Placeholder
+				continue
+			}
+			v[key] = scrub(field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrub(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,76 @@
+// Package safego はパニックしてもプロセスを終了させないゴルーチンを起動します。
+// bots で複数のボットを1つのプロセスで動かすとき、1つのボットのパニックでほかのボットが止まらないようにします
+package safego
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// 定期的な処理がパニックしたときに、実行し直すまで待つ時間。続けてパニックするたびに倍にします
+var (
+	minRestartDelay = time.Second
+	maxRestartDelay = 5 * time.Minute
+)
+
+// Go は fn を新しいゴルーチンで実行します。fn がパニックした場合はスタックトレースとともにログに出力し、
+// そのゴルーチンだけを終了します。name はログでゴルーチンを見分けるための名前です
+func Go(logger *slog.Logger, name string, fn func()) {
+	go func() {
+		defer Recover(logger, name)
+		fn()
+	}()
+}
+
+// Recover はパニックから回復してログに出力します。ゴルーチンの最初に defer で呼び出します
+func Recover(logger *slog.Logger, name string) {
+	if r := recover(); r != nil {
+		logPanic(logger, name, r)
+	}
+}
+
+// Loop は定期的な処理 fn を呼び出し、fn が戻るか stop が閉じられるまで戻りません。
+// fn がパニックした場合はスタックトレースとともにログに出力し、少し待ってから fn を呼び出し直すため、
+// トークンの更新などの定期的な処理がパニックで黙って止まることはありません。
+// fn は止めるときに戻り、呼び出し直しても問題がないようにします
+func Loop(logger *slog.Logger, name string, stop <-chan struct{}, fn func()) {
+	delay := minRestartDelay
+	for {
+		started := time.Now()
+		if !run(logger, name, fn) {
+			return
+		}
+		// しばらく動いてからのパニックであれば、待つ時間を最初に戻す
+		if time.Since(started) >= maxRestartDelay {
+			delay = minRestartDelay
+		}
+		logger.Error("パニックしたゴルーチンを再開します", "goroutine", name, "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxRestartDelay)
+	}
+}
+
+// run は fn を呼び出し、パニックしたかを返します
+func run(logger *slog.Logger, name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic(logger, name, r)
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// logPanic はパニックをスタックトレースとともにログに出力します
+func logPanic(logger *slog.Logger, name string, r any) {
+	logger.Error("ゴルーチンがパニックしました", "goroutine", name, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+}
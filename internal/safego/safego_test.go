@@ -0,0 +1,73 @@
+package safego
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chanWriter はログの各行をチャネルに送ります
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestGo(t *testing.T) {
+	lines := make(chanWriter, 1)
+	logger := slog.New(slog.NewTextHandler(lines, nil))
+
+	// ゴルーチンのパニックでテストのプロセスが終了せず、ログに残る
+	Go(logger, "refresh", func() {
+		panic("boom")
+	})
+	got := <-lines
+	for _, want := range []string{"goroutine=refresh", "panic=boom", "stack="} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestLoop(t *testing.T) {
+	defer func(minDelay, maxDelay time.Duration) { minRestartDelay, maxRestartDelay = minDelay, maxDelay }(minRestartDelay, maxRestartDelay)
+	minRestartDelay, maxRestartDelay = time.Millisecond, 4*time.Millisecond
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// 正常系: パニックしても呼び出し直し、fn が戻ると終了する
+	calls := 0
+	Loop(logger, "refresh", make(chan struct{}), func() {
+		calls++
+		if calls < 3 {
+			panic("boom")
+		}
+	})
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+
+	// 正常系: 呼び出し直すのを待っている間に stop が閉じられたら終了する
+	minRestartDelay = time.Hour
+	stop := make(chan struct{})
+	close(stop)
+	calls = 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Loop(logger, "refresh", stop, func() {
+			calls++
+			panic("boom")
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Loop() did not return after stop was closed")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
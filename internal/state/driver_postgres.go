@@ -0,0 +1,6 @@
+//go:build !nopostgres
+
+package state
+
+// STATE_BACKEND=postgres のドライバ。-tags nopostgres でビルドすると組み込みません
+import _ "github.com/jackc/pgx/v5/stdlib"
@@ -0,0 +1,6 @@
+//go:build !nosqlite
+
+package state
+
+// STATE_BACKEND=sqlite のドライバ（cgo を使わない実装）。-tags nosqlite でビルドすると組み込みません
+import _ "modernc.org/sqlite"
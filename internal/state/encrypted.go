@@ -0,0 +1,105 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Cipher は値を暗号化・復号します（repository.TokenEncryptor）
+type Cipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(encrypted string) (string, error)
+	// ReEncrypt は以前の鍵で暗号化された値を現在の鍵で暗号化し直し、変わったかどうかを返します
+	ReEncrypt(encrypted string) (string, bool, error)
+	// IsEncrypted は値が暗号化された形式かどうかを返します
+	IsEncrypted(text string) bool
+}
+
+// EncryptedStore は値を暗号化して store に保存します。
+// 読み込みでは復号し、暗号化する前に保存された平文の値もそのまま読み込みます
+type EncryptedStore struct {
+	store  Store
+	cipher Cipher
+}
+
+// NewEncryptedStore は store に暗号化して保存する EncryptedStore を作成します
+func NewEncryptedStore(store Store, cipher Cipher) *EncryptedStore {
+	return &EncryptedStore{store: store, cipher: cipher}
+}
+
+// Get は key の値を復号して v に読み込みます
+func (s *EncryptedStore) Get(key string, v interface{}) (bool, error) {
+	ciphertext, raw, ok, err := s.load(key)
+	if !ok || err != nil {
+		return false, err
+	}
+	if ciphertext != "" {
+		plaintext, err := s.cipher.Decrypt(ciphertext)
+		if err != nil {
+			return false, fmt.Errorf("状態 %s の復号に失敗しました: %w", key, err)
+		}
+		raw = json.RawMessage(plaintext)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("状態 %s の解析に失敗しました: %w", key, err)
+	}
+	return true, nil
+}
+
+// Put は v を暗号化して key の値を置き換えます
+func (s *EncryptedStore) Put(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("状態 %s のエンコードに失敗しました: %w", key, err)
+	}
+	encrypted, err := s.cipher.Encrypt(string(raw))
+	if err != nil {
+		return fmt.Errorf("状態 %s の暗号化に失敗しました: %w", key, err)
+	}
+	return s.store.Put(key, encrypted)
+}
+
+// Rekey は keys の値を現在の鍵で暗号化し直します。以前の鍵で暗号化された値と、
+// 暗号化する前に保存された平文の値が対象です。書き換えたキーの数を返します
+func (s *EncryptedStore) Rekey(keys ...string) (int, error) {
+	rewritten := 0
+	for _, key := range keys {
+		ciphertext, raw, ok, err := s.load(key)
+		if err != nil {
+			return rewritten, err
+		}
+		if !ok {
+			continue
+		}
+		var value string
+		if ciphertext != "" {
+			var changed bool
+			value, changed, err = s.cipher.ReEncrypt(ciphertext)
+			if err != nil {
+				return rewritten, fmt.Errorf("状態 %s の復号に失敗しました: %w", key, err)
+			}
+			if !changed {
+				continue
+			}
+		} else if value, err = s.cipher.Encrypt(string(raw)); err != nil {
+			return rewritten, fmt.Errorf("状態 %s の暗号化に失敗しました: %w", key, err)
+		}
+		if err := s.store.Put(key, value); err != nil {
+			return rewritten, err
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+// load は key の保存された値を読み込みます。暗号化されていれば ciphertext を、平文なら raw を返します
+func (s *EncryptedStore) load(key string) (ciphertext string, raw json.RawMessage, ok bool, err error) {
+	if ok, err := s.store.Get(key, &raw); !ok || err != nil {
+		return "", nil, false, err
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil && s.cipher.IsEncrypted(text) {
+		return text, nil, true, nil
+	}
+	return "", raw, true, nil
+}
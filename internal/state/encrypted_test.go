@@ -0,0 +1,112 @@
+package state
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeCipher は鍵の名前と Base64 で「暗号化」します。previous の鍵で暗号化された値も復号できます
+type fakeCipher struct {
+	key      string
+	previous []string
+}
+
+func (c fakeCipher) Encrypt(plaintext string) (string, error) {
+	return "enc:" + c.key + ":" + base64.StdEncoding.EncodeToString([]byte(plaintext)), nil
+}
+
+func (c fakeCipher) Decrypt(encrypted string) (string, error) {
+	key, plaintext, err := c.open(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if key != c.key && !slices.Contains(c.previous, key) {
+		return "", fmt.Errorf("unknown key %s", key)
+	}
+	return plaintext, nil
+}
+
+func (c fakeCipher) ReEncrypt(encrypted string) (string, bool, error) {
+	plaintext, err := c.Decrypt(encrypted)
+	if err != nil {
+		return "", false, err
+	}
+	if key, _, _ := c.open(encrypted); key == c.key {
+		return encrypted, false, nil
+	}
+	reencrypted, err := c.Encrypt(plaintext)
+	return reencrypted, true, err
+}
+
+func (c fakeCipher) IsEncrypted(text string) bool {
+	return strings.HasPrefix(text, "enc:")
+}
+
+func (c fakeCipher) open(encrypted string) (key, plaintext string, err error) {
+	parts := strings.SplitN(encrypted, ":", 3)
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("malformed value")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(parts[2])
+	return parts[1], string(decoded), err
+}
+
+func TestEncryptedStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	inner, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	// 暗号化を有効にする前に保存された平文の値
+	if err := inner.Put("plain", []string{"bob"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	store := NewEncryptedStore(inner, fakeCipher{key: "old"})
+	if err := store.Put("secret", []string{"alice"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(data), "alice") {
+		t.Errorf("state file = %s, want the value encrypted", data)
+	}
+
+	// 鍵を替えても以前の鍵で読み込め、平文の値もそのまま読み込める
+	store = NewEncryptedStore(inner, fakeCipher{key: "new", previous: []string{"old"}})
+	for key, want := range map[string]string{"secret": "alice", "plain": "bob"} {
+		var got []string
+		if ok, err := store.Get(key, &got); !ok || err != nil || len(got) != 1 || got[0] != want {
+			t.Errorf("Get(%s) = %v, %v, %v, want [%s]", key, ok, err, got, want)
+		}
+	}
+
+	rewritten, err := store.Rekey("secret", "plain", "missing")
+	if err != nil || rewritten != 2 {
+		t.Fatalf("Rekey() = %d, %v, want 2 keys rewritten", rewritten, err)
+	}
+	if rewritten, err := store.Rekey("secret", "plain"); err != nil || rewritten != 0 {
+		t.Errorf("Rekey() twice = %d, %v, want nothing to rewrite", rewritten, err)
+	}
+
+	// 暗号化し直した後は以前の鍵がなくても読み込め、平文は残らない
+	store = NewEncryptedStore(inner, fakeCipher{key: "new"})
+	var got []string
+	if ok, err := store.Get("secret", &got); !ok || err != nil || got[0] != "alice" {
+		t.Errorf("Get(secret) = %v, %v, %v, want [alice]", ok, err, got)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(data), "bob") {
+		t.Errorf("state file = %s, want the plaintext value encrypted", data)
+	}
+}
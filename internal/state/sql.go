@@ -0,0 +1,197 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// drivers は STATE_BACKEND ごとに使う database/sql のドライバの名前です。
+// ドライバはバイナリに組み込んだものを、この順に探します
+var drivers = map[string][]string{
+	config.StateBackendSQLite:   {"sqlite", "sqlite3"},
+	config.StateBackendPostgres: {"pgx", "postgres"},
+}
+
+// SQLStore は状態と追記していく記録を SQLite または PostgreSQL に保存します。
+// 1つのデータベースを複数のボットで使えるよう、行にはボットの名前を付けます
+type SQLStore struct {
+	db      *sql.DB
+	backend string
+	bot     string
+	// maxLogEntries はストリームごとに残す記録の数です（0 は削除しない）
+	maxLogEntries int
+}
+
+// OpenSQLStore は backend（sqlite または postgres）のデータベースを開き、テーブルがなければ作成します。
+// bot は bots のボットの名前です（1つのボットだけを動かす場合は空）。
+// maxLogEntries を超えた古い記録は追記のたびに削除します（0 は削除しない）
+func OpenSQLStore(backend, dsn, bot string, maxLogEntries int) (*SQLStore, error) {
+	db, err := OpenDB(backend, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if backend == config.StateBackendSQLite {
+		// SQLite は書き込みを1つずつしか受け付けないため、接続を共有して database is locked を避ける
+		db.SetMaxOpenConns(1)
+	}
+	s := &SQLStore{db: db, backend: backend, bot: bot, maxLogEntries: maxLogEntries}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenDB は backend（sqlite または postgres）のデータベースを、組み込まれているドライバで開きます
+func OpenDB(backend, dsn string) (*sql.DB, error) {
+	driver, err := driverFor(backend)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("データベースのオープンに失敗しました: %w", err)
+	}
+	return db, nil
+}
+
+// driverFor は backend のドライバのうち、組み込まれているものの名前を返します
+func driverFor(backend string) (string, error) {
+	names, ok := drivers[backend]
+	if !ok {
+		return "", fmt.Errorf("不明な STATE_BACKEND です: %s", backend)
+	}
+	registered := sql.Drivers()
+	for _, name := range names {
+		if slices.Contains(registered, name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("STATE_BACKEND=%s のデータベースドライバ（%s）が組み込まれていません", backend, strings.Join(names, " または "))
+}
+
+// migrate は状態と記録のテーブルを作成します
+func (s *SQLStore) migrate() error {
+	logID := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if s.backend == config.StateBackendPostgres {
+		logID = "id BIGSERIAL PRIMARY KEY"
+	}
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS quotebot_state (bot TEXT NOT NULL, key TEXT NOT NULL, value TEXT NOT NULL, PRIMARY KEY (bot, key))`,
+		`CREATE TABLE IF NOT EXISTS quotebot_log (` + logID + `, bot TEXT NOT NULL, stream TEXT NOT NULL, value TEXT NOT NULL)`,
+		`CREATE INDEX IF NOT EXISTS quotebot_log_stream ON quotebot_log (bot, stream, id)`,
+	} {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("データベースのテーブルの作成に失敗しました: %w", err)
+		}
+	}
+	return nil
+}
+
+// Get は key の値を v に読み込みます
+func (s *SQLStore) Get(key string, v interface{}) (bool, error) {
+	var raw string
+	err := s.db.QueryRow(s.bind(`SELECT value FROM quotebot_state WHERE bot = ? AND key = ?`), s.bot, key).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("状態 %s の読み込みに失敗しました: %w", key, err)
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return false, fmt.Errorf("状態 %s の解析に失敗しました: %w", key, err)
+	}
+	return true, nil
+}
+
+// Put は key の値を v で置き換えます
+func (s *SQLStore) Put(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("状態 %s のエンコードに失敗しました: %w", key, err)
+	}
+	// ON CONFLICT は SQLite 3.24 以降と PostgreSQL 9.5 以降で使える
+	_, err = s.db.Exec(s.bind(`INSERT INTO quotebot_state (bot, key, value) VALUES (?, ?, ?) ON CONFLICT (bot, key) DO UPDATE SET value = excluded.value`),
+		s.bot, key, string(raw))
+	if err != nil {
+		return fmt.Errorf("状態 %s の書き込みに失敗しました: %w", key, err)
+	}
+	return nil
+}
+
+// Append は stream の末尾に v を追記し、maxLogEntries を超えた古い記録を削除します
+func (s *SQLStore) Append(stream string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s のエンコードに失敗しました: %w", stream, err)
+	}
+	if _, err := s.db.Exec(s.bind(`INSERT INTO quotebot_log (bot, stream, value) VALUES (?, ?, ?)`), s.bot, stream, string(raw)); err != nil {
+		return fmt.Errorf("%s の書き込みに失敗しました: %w", stream, err)
+	}
+	return s.prune(stream)
+}
+
+// prune は stream の記録を新しい順に maxLogEntries 件だけ残して削除します
+func (s *SQLStore) prune(stream string) error {
+	if s.maxLogEntries <= 0 {
+		return nil
+	}
+	// 残す件数より少なければ副問い合わせが NULL になり、何も削除しない
+	_, err := s.db.Exec(s.bind(`DELETE FROM quotebot_log WHERE bot = ? AND stream = ? AND id <= (SELECT id FROM quotebot_log WHERE bot = ? AND stream = ? ORDER BY id DESC LIMIT 1 OFFSET ?)`),
+		s.bot, stream, s.bot, stream, s.maxLogEntries)
+	if err != nil {
+		return fmt.Errorf("%s の古い記録の削除に失敗しました: %w", stream, err)
+	}
+	return nil
+}
+
+// Read は stream の記録を古い順に返します
+func (s *SQLStore) Read(stream string) ([]json.RawMessage, error) {
+	rows, err := s.db.Query(s.bind(`SELECT value FROM quotebot_log WHERE bot = ? AND stream = ? ORDER BY id`), s.bot, stream)
+	if err != nil {
+		return nil, fmt.Errorf("%s の読み込みに失敗しました: %w", stream, err)
+	}
+	defer rows.Close()
+	var values []json.RawMessage
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("%s の読み込みに失敗しました: %w", stream, err)
+		}
+		values = append(values, json.RawMessage(raw))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s の読み込みに失敗しました: %w", stream, err)
+	}
+	return values, nil
+}
+
+// Close はデータベースの接続を閉じます
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// bind は ? のプレースホルダーを、PostgreSQL では $1, $2, ... に置き換えます
+func (s *SQLStore) bind(query string) string {
+	if s.backend != config.StateBackendPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
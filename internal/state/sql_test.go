@@ -0,0 +1,215 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+func TestSQLStore(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "quotebot.db")
+	store, err := OpenSQLStore(config.StateBackendSQLite, dsn, "", 0)
+	if err != nil {
+		t.Fatalf("OpenSQLStore() error = %v", err)
+	}
+	defer store.Close()
+
+	var got []string
+	if ok, err := store.Get("items", &got); ok || err != nil {
+		t.Fatalf("Get() = %v, %v, want false before Put", ok, err)
+	}
+	for _, items := range [][]string{{"a"}, {"a", "b"}} {
+		if err := store.Put("items", items); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	for _, line := range []string{"1", "2"} {
+		if err := store.Append("history", map[string]string{"line": line}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	// 開き直しても値が残り、ほかのボットの値とは混ざらない
+	reopened, err := OpenSQLStore(config.StateBackendSQLite, dsn, "", 0)
+	if err != nil {
+		t.Fatalf("OpenSQLStore() error = %v", err)
+	}
+	defer reopened.Close()
+	if ok, err := reopened.Get("items", &got); !ok || err != nil {
+		t.Fatalf("Get() = %v, %v, want true", ok, err)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("Get() = %v, want [a b]", got)
+	}
+	lines, err := reopened.Read("history")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(lines) != 2 || string(lines[0]) != `{"line":"1"}` || string(lines[1]) != `{"line":"2"}` {
+		t.Errorf("Read() = %s, want the appended lines in order", lines)
+	}
+
+	other, err := OpenSQLStore(config.StateBackendSQLite, dsn, "other", 0)
+	if err != nil {
+		t.Fatalf("OpenSQLStore() error = %v", err)
+	}
+	defer other.Close()
+	if ok, err := other.Get("items", &got); ok || err != nil {
+		t.Errorf("Get() of another bot = %v, %v, want false", ok, err)
+	}
+	if lines, err := other.Read("history"); len(lines) != 0 || err != nil {
+		t.Errorf("Read() of another bot = %s, %v, want nothing", lines, err)
+	}
+}
+
+func TestSQLStore_Retention(t *testing.T) {
+	store, err := OpenSQLStore(config.StateBackendSQLite, filepath.Join(t.TempDir(), "quotebot.db"), "", 2)
+	if err != nil {
+		t.Fatalf("OpenSQLStore() error = %v", err)
+	}
+	defer store.Close()
+
+	for _, line := range []string{"1", "2", "3"} {
+		if err := store.Append("history", line); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if err := store.Append("other", "1"); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	// 新しい2件だけが残り、ほかのストリームの記録は消さない
+	lines, err := store.Read("history")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(lines) != 2 || string(lines[0]) != `"2"` || string(lines[1]) != `"3"` {
+		t.Errorf("Read() = %s, want the newest 2 lines", lines)
+	}
+	if lines, err := store.Read("other"); len(lines) != 1 || err != nil {
+		t.Errorf("Read(other) = %s, %v, want 1 line", lines, err)
+	}
+}
+
+// TestSQLStore_Postgres は QUOTEBOT_TEST_POSTGRES_DSN の PostgreSQL データベースで実行します
+func TestSQLStore_Postgres(t *testing.T) {
+	dsn := os.Getenv("QUOTEBOT_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("QUOTEBOT_TEST_POSTGRES_DSN is not set")
+	}
+	store, err := OpenSQLStore(config.StateBackendPostgres, dsn, t.Name(), 2)
+	if err != nil {
+		t.Fatalf("OpenSQLStore() error = %v", err)
+	}
+	defer store.Close()
+	for _, query := range []string{`DELETE FROM quotebot_state WHERE bot = $1`, `DELETE FROM quotebot_log WHERE bot = $1`} {
+		if _, err := store.db.Exec(query, t.Name()); err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+	}
+
+	for _, value := range []string{"a", "b"} {
+		if err := store.Put("key", value); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	var got string
+	if ok, err := store.Get("key", &got); !ok || err != nil || got != "b" {
+		t.Errorf("Get() = %v, %v, %q, want b", ok, err, got)
+	}
+	for _, line := range []string{"1", "2", "3"} {
+		if err := store.Append("history", line); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	lines, err := store.Read("history")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(lines) != 2 || string(lines[0]) != `"2"` || string(lines[1]) != `"3"` {
+		t.Errorf("Read() = %s, want the newest 2 lines", lines)
+	}
+}
+
+func TestSQLStore_Bind(t *testing.T) {
+	query := `INSERT INTO quotebot_state (bot, key, value) VALUES (?, ?, ?)`
+	if got := (&SQLStore{backend: config.StateBackendSQLite}).bind(query); got != query {
+		t.Errorf("bind() for sqlite = %s, want the query unchanged", got)
+	}
+	// PostgreSQL では番号付きのプレースホルダーを使う
+	if got := (&SQLStore{backend: config.StateBackendPostgres}).bind(query); !strings.Contains(got, "VALUES ($1, $2, $3)") {
+		t.Errorf("bind() for postgres = %s, want numbered placeholders", got)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want string
+	}{
+		{
+			name: "正常系: STATE_FILE がなければ保存しない",
+			cfg:  &config.Config{StateBackend: config.StateBackendFile},
+		},
+		{
+			name: "正常系: STATE_FILE に保存する",
+			cfg:  &config.Config{StateBackend: config.StateBackendFile, StateFile: "state.json"},
+			want: "*state.FileStore",
+		},
+		{
+			name: "正常系: データベースに保存する",
+			cfg:  &config.Config{StateBackend: config.StateBackendSQLite, StateDSN: "quotebot.db"},
+			want: "*state.SQLStore",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.StateFile != "" {
+				tt.cfg.StateFile = filepath.Join(t.TempDir(), tt.cfg.StateFile)
+			}
+			if tt.cfg.StateDSN != "" {
+				tt.cfg.StateDSN = filepath.Join(t.TempDir(), tt.cfg.StateDSN)
+			}
+			store, err := Open(tt.cfg)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if store == nil {
+				if tt.want != "" {
+					t.Errorf("Open() = nil, want %s", tt.want)
+				}
+				return
+			}
+			if closer, ok := store.(interface{ Close() error }); ok {
+				defer closer.Close()
+			}
+			if got := fmt.Sprintf("%T", store); got != tt.want {
+				t.Errorf("Open() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriverFor(t *testing.T) {
+	if got, err := driverFor(config.StateBackendSQLite); err != nil || got != "sqlite" {
+		t.Errorf("driverFor(sqlite) = %q, %v, want sqlite", got, err)
+	}
+	if got, err := driverFor(config.StateBackendPostgres); err != nil || got != "pgx" {
+		t.Errorf("driverFor(postgres) = %q, %v, want pgx", got, err)
+	}
+
+	drivers["missing"] = []string{"missing-driver"}
+	defer delete(drivers, "missing")
+	if _, err := driverFor("missing"); err == nil || !strings.Contains(err.Error(), "missing-driver") {
+		t.Errorf("driverFor() error = %v, want the missing driver", err)
+	}
+	if _, err := driverFor("redis"); err == nil {
+		t.Error("driverFor(redis) error = nil, want error")
+	}
+}
@@ -0,0 +1,150 @@
+// Package state は再起動しても残す必要のあるボットの状態（承認待ちの投稿など）を、
+// STATE_BACKEND で選んだファイルまたはデータベースに保存します
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// Store はキーごとに値をJSONで保存します
+type Store interface {
+	// Get は key の値を v に読み込みます。値がない場合は false を返します
+	Get(key string, v interface{}) (bool, error)
+	// Put は key の値を v で置き換えます
+	Put(key string, v interface{}) error
+}
+
+// Log は追記していく記録（投稿履歴など）も保存できる Store です
+type Log interface {
+	Store
+	// Append は stream の末尾に v を追記します
+	Append(stream string, v interface{}) error
+	// Read は stream の記録を古い順に返します
+	Read(stream string) ([]json.RawMessage, error)
+}
+
+// Open は STATE_BACKEND の Store を開きます。
+// file で STATE_FILE が未設定の場合は nil を返します
+func Open(cfg *config.Config) (Store, error) {
+	if cfg.DatabaseState() {
+		return OpenSQLStore(cfg.StateBackend, cfg.StateDSN, cfg.Bot(), cfg.HistoryMaxEntries)
+	}
+	store, err := NewFileStore(cfg)
+	if store == nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// FileStore はすべてのキーを1つのJSONファイルに保存します。
+// 書き込みは一時ファイルに書いてから置き換えるため、途中で停止しても壊れたファイルは残りません
+type FileStore struct {
+	path string
+
+	mu     sync.Mutex
+	values map[string]json.RawMessage
+}
+
+// NewFileStore は STATE_FILE に保存する FileStore を作成します。
+// STATE_FILE が未設定の場合は nil を返します
+func NewFileStore(cfg *config.Config) (*FileStore, error) {
+	if cfg.StateFile == "" {
+		return nil, nil
+	}
+	return OpenFileStore(cfg.StateFile)
+}
+
+// OpenFileStore は path の FileStore を開きます。ファイルがない場合は空の状態から始めます
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, values: map[string]json.RawMessage{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("状態ファイルの読み込みに失敗しました: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.values); err != nil {
+		return nil, fmt.Errorf("状態ファイルの解析に失敗しました: %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Get は key の値を v に読み込みます
+func (s *FileStore) Get(key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	raw, ok := s.values[key]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("状態 %s の解析に失敗しました: %w", key, err)
+	}
+	return true, nil
+}
+
+// Put は key の値を v で置き換え、ファイルに書き込みます
+func (s *FileStore) Put(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("状態 %s のエンコードに失敗しました: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, existed := s.values[key]
+	s.values[key] = raw
+	if err := s.write(); err != nil {
+		// 書き込めなかった値はメモリにも残さない
+		if existed {
+			s.values[key] = prev
+		} else {
+			delete(s.values, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// write はすべての値を一時ファイルに書いてから置き換えます
+func (s *FileStore) write() error {
+	data, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("状態のエンコードに失敗しました: %w", err)
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("状態ディレクトリの作成に失敗しました: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("状態ファイルの作成に失敗しました: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		return fmt.Errorf("状態ファイルの置き換えに失敗しました: %w", err)
+	}
+	return nil
+}
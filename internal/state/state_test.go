@@ -0,0 +1,52 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+
+	var got []string
+	if ok, err := store.Get("items", &got); ok || err != nil {
+		t.Fatalf("Get() = %v, %v, want false before Put", ok, err)
+	}
+	if err := store.Put("items", []string{"a", "b"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("os.Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+
+	// 開き直しても値が残る
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	if ok, err := store.Get("items", &got); !ok || err != nil {
+		t.Fatalf("Get() = %v, %v, want true", ok, err)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("Get() = %v, want [a b]", got)
+	}
+}
+
+func TestOpenFileStore_Error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(path); err == nil {
+		t.Error("OpenFileStore() error = nil, want a parse error")
+	}
+}
@@ -0,0 +1,353 @@
+// Package suggestion はボットの投稿への返信で提案された名言を、確認待ちの受付箱にためます。
+// 「suggest: 名言 — 著者」のように返信されると受付箱に入り、管理APIで採用すると名言ファイルに追加されます
+package suggestion
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/safego"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+const (
+	// stateKey は確認待ちの提案を保存する状態のキーです
+	stateKey = "suggestions"
+	// cursorKey は確認済みの最新の返信の時刻を保存する状態のキーです
+	cursorKey = "suggestion_inbox"
+)
+
+// StateKeys は Inbox が状態に保存するキーです
+var StateKeys = []string{stateKey, cursorKey}
+
+// maxPending は確認待ちにできる提案の数です。上限に達している間は新しい提案を受け付けません
+const maxPending = 100
+
+// ErrNotFound は指定したIDの確認待ちの提案がない場合のエラーです
+var ErrNotFound = errors.New("確認待ちの提案が見つかりません")
+
+// authorSeparators は提案の本文と著者を区切る記号です。最後に現れた区切りで分けます
+var authorSeparators = []string{"—", "―", " -- ", " - ", " ~ ", "〜"}
+
+// Mentions はアカウントへのメンションと返信を取得します
+type Mentions interface {
+	Mentions(ctx context.Context, since time.Time) ([]domain.Mention, error)
+}
+
+// QuoteAdder は名言を検証して名言ファイルに追加します
+type QuoteAdder interface {
+	AddQuote(quote domain.Quote) (*domain.Quote, error)
+}
+
+// Item は確認待ちの提案です
+type Item struct {
+	ID           string       `json:"id"`
+	Quote        domain.Quote `json:"quote"`
+	AuthorDID    string       `json:"authorDid"`
+	AuthorHandle string       `json:"authorHandle,omitempty"`
+	PostURI      string       `json:"postUri"` // 提案した返信の投稿
+	CreatedAt    time.Time    `json:"createdAt"`
+}
+
+// cursor は状態ファイルに保存する確認済みの位置です
+type cursor struct {
+	Since time.Time `json:"since"`
+}
+
+// Inbox は定期的にボットの投稿への返信を確認し、提案された名言を確認待ちにためます。
+// app.Server として App の実行中だけ動きます
+type Inbox struct {
+	mentions     Mentions
+	quotes       QuoteAdder
+	accounts     domain.AccountFilter
+	store        state.Store
+	did          string
+	prefix       string
+	pollInterval time.Duration
+	timeout      time.Duration
+	logger       *slog.Logger
+	now          func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	cursor cursor // poll のゴルーチンだけが使います
+
+	mu sync.Mutex // 確認待ちの提案の読み書きを保護します
+}
+
+// New は cfg の SUGGESTION_* の設定で Inbox を作成します。
+// 確認待ちの提案を再起動しても残すため、store は必須です
+func New(cfg *config.Config, mentions Mentions, quotes QuoteAdder, store state.Store) (*Inbox, error) {
+	in := &Inbox{
+		mentions:     mentions,
+		quotes:       quotes,
+		accounts:     domain.NewAccountFilter(cfg.DenyDIDs),
+		store:        store,
+		did:          cfg.DID,
+		prefix:       cfg.SuggestionPrefix,
+		pollInterval: cfg.SuggestionPollInterval,
+		timeout:      cfg.PostTimeout,
+		logger:       logging.ModuleFor(cfg, "suggestions"),
+		now:          time.Now,
+	}
+	if _, err := store.Get(cursorKey, &in.cursor); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// Start はバックグラウンドで返信の確認を始めます
+func (in *Inbox) Start() error {
+	in.ctx, in.cancel = context.WithCancel(context.Background())
+	in.wg.Add(1)
+	safego.Go(in.logger, "suggestions", func() {
+		defer in.wg.Done()
+		safego.Loop(in.logger, "suggestions", in.ctx.Done(), in.pollLoop)
+	})
+	return nil
+}
+
+// Shutdown は返信の確認を停止します
+func (in *Inbox) Shutdown(ctx context.Context) error {
+	if in.cancel == nil {
+		return nil
+	}
+	in.cancel()
+	done := make(chan struct{})
+	go func() {
+		in.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Suggestions は確認待ちの提案を古い順に返します
+func (in *Inbox) Suggestions() ([]Item, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.load()
+}
+
+// Accept は確認待ちの提案を名言ファイルに追加し、確認待ちから取り除きます。
+// 追加できなかった場合（既存の名言と重複しているなど）は、確認待ちのまま残します
+func (in *Inbox) Accept(id string) (*domain.Quote, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	items, i, err := in.find(id)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := in.quotes.AddQuote(items[i].Quote)
+	if err != nil {
+		return nil, err
+	}
+	if err := in.store.Put(stateKey, append(items[:i:i], items[i+1:]...)); err != nil {
+		return nil, err
+	}
+	in.logger.Info("提案された名言を採用しました", "suggestion_id", id, "quote_id", quote.ID, "author", items[i].AuthorHandle)
+	return quote, nil
+}
+
+// Reject は確認待ちの提案を採用せずに取り除きます
+func (in *Inbox) Reject(id string) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	items, i, err := in.find(id)
+	if err != nil {
+		return err
+	}
+	if err := in.store.Put(stateKey, append(items[:i:i], items[i+1:]...)); err != nil {
+		return err
+	}
+	in.logger.Info("提案された名言を却下しました", "suggestion_id", id, "author", items[i].AuthorHandle)
+	return nil
+}
+
+// pollLoop は停止するまで pollInterval ごとに poll を呼び出します
+func (in *Inbox) pollLoop() {
+	ticker := time.NewTicker(in.pollInterval)
+	defer ticker.Stop()
+	for {
+		in.poll()
+		select {
+		case <-in.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll は前回から届いた返信を確認し、提案を確認待ちに加えます
+func (in *Inbox) poll() {
+	// 初めて起動したときは、有効にする前の返信を提案として扱わない
+	if in.cursor.Since.IsZero() {
+		in.cursor.Since = in.now()
+		in.saveCursor()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(in.ctx, in.timeout)
+	defer cancel()
+	mentions, err := in.mentions.Mentions(ctx, in.cursor.Since)
+	if err != nil {
+		in.logger.Warn("メンションの取得に失敗しました", "error", redact.Error(err))
+		return
+	}
+	for _, mention := range mentions {
+		in.handle(mention)
+		if mention.IndexedAt.After(in.cursor.Since) {
+			in.cursor.Since = mention.IndexedAt
+		}
+	}
+	in.saveCursor()
+}
+
+// handle はボットの投稿のスレッドへの返信から提案を取り出し、確認待ちに加えます
+func (in *Inbox) handle(mention domain.Mention) {
+	if mention.AuthorDID == in.did || !strings.HasPrefix(mention.Root.URI, "at://"+in.did+"/") {
+		return
+	}
+	quote, ok := ParseSuggestion(mention.Text, in.prefix)
+	if !ok {
+		return
+	}
+	logger := in.logger.With("author", mention.AuthorHandle, "uri", mention.Post.URI)
+	if reason := in.accounts.Ignores(mention); reason != "" {
+		logger.Info("関わらないユーザーのメンションを無視しました", "reason", reason)
+		return
+	}
+	if err := quote.Validate(); err != nil {
+		logger.Info("投稿できない名言の提案を無視しました", "error", err)
+		return
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	items, err := in.load()
+	if err != nil {
+		logger.Warn("名言の提案の保存に失敗しました", "error", err)
+		return
+	}
+	if len(items) >= maxPending {
+		logger.Warn("確認待ちの提案が上限に達しているため、名言の提案を無視しました", "max", maxPending)
+		return
+	}
+	key := domain.NormalizeText(quote.Text)
+	for _, item := range items {
+		if item.PostURI == mention.Post.URI || domain.NormalizeText(item.Quote.Text) == key {
+			logger.Info("確認待ちの提案と重複する名言の提案を無視しました", "suggestion_id", item.ID)
+			return
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		logger.Warn("名言の提案の保存に失敗しました", "error", err)
+		return
+	}
+	item := Item{
+		ID:           id,
+		Quote:        quote,
+		AuthorDID:    mention.AuthorDID,
+		AuthorHandle: mention.AuthorHandle,
+		PostURI:      mention.Post.URI,
+		CreatedAt:    in.now(),
+	}
+	if err := in.store.Put(stateKey, append(items, item)); err != nil {
+		logger.Warn("名言の提案の保存に失敗しました", "error", err)
+		return
+	}
+	logger.Info("名言の提案を受け付けました", "suggestion_id", id)
+}
+
+// load は確認待ちの提案を読み込みます。in.mu を持って呼び出します
+func (in *Inbox) load() ([]Item, error) {
+	var items []Item
+	if _, err := in.store.Get(stateKey, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// find は確認待ちの提案と、id の提案の位置を返します。in.mu を持って呼び出します
+func (in *Inbox) find(id string) ([]Item, int, error) {
+	items, err := in.load()
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, item := range items {
+		if item.ID == id {
+			return items, i, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+func (in *Inbox) saveCursor() {
+	if err := in.store.Put(cursorKey, in.cursor); err != nil {
+		in.logger.Warn("名言の提案の確認位置の保存に失敗しました", "error", err)
+	}
+}
+
+// ParseSuggestion は返信の本文から「<prefix> 名言 — 著者」の名言を取り出します。
+// 先頭のメンションは読み飛ばし、prefix は大文字と小文字を区別しません。著者のない提案は提案とみなしません
+func ParseSuggestion(text, prefix string) (domain.Quote, bool) {
+	body := strings.TrimSpace(text)
+	for strings.HasPrefix(body, "@") {
+		i := strings.IndexFunc(body, unicode.IsSpace)
+		if i < 0 {
+			return domain.Quote{}, false
+		}
+		body = strings.TrimSpace(body[i:])
+	}
+	if len(body) < len(prefix) || !strings.EqualFold(body[:len(prefix)], prefix) {
+		return domain.Quote{}, false
+	}
+	body = body[len(prefix):]
+
+	split := -1
+	var sep string
+	for _, s := range authorSeparators {
+		if i := strings.LastIndex(body, s); i > split {
+			split, sep = i, s
+		}
+	}
+	if split < 0 {
+		return domain.Quote{}, false
+	}
+	quote := domain.Quote{
+		Text:   strings.Trim(body[:split], " \t\n\"'“”「」『』"),
+		Author: strings.TrimSpace(body[split+len(sep):]),
+	}
+	if quote.Text == "" || quote.Author == "" {
+		return domain.Quote{}, false
+	}
+	return quote, true
+}
+
+// newID は確認待ちの提案のIDを作成します（CLIで入力しやすい短い16進数）
+func newID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("IDの作成に失敗しました: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,162 @@
+package suggestion
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/state"
+)
+
+func TestParseSuggestion(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   domain.Quote
+		wantOK bool
+	}{
+		{name: "正常系: 全角ダッシュで著者を区切る", text: "suggest: 知は力なり — ベーコン", want: domain.Quote{Text: "知は力なり", Author: "ベーコン"}, wantOK: true},
+		{name: "正常系: 先頭のメンションと大文字のprefix", text: "@bot.bsky.social Suggest: \"Stay hungry, stay foolish\" - Steve Jobs", want: domain.Quote{Text: "Stay hungry, stay foolish", Author: "Steve Jobs"}, wantOK: true},
+		{name: "正常系: 本文のハイフンは区切りにしない", text: "suggest: 「一期一会 - 一生に一度」 ~ 千利休", want: domain.Quote{Text: "一期一会 - 一生に一度", Author: "千利休"}, wantOK: true},
+		{name: "異常系: 著者がない", text: "suggest: 知は力なり", wantOK: false},
+		{name: "異常系: prefixがない", text: "知は力なり — ベーコン", wantOK: false},
+		{name: "異常系: 本文が空", text: "suggest: — ベーコン", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseSuggestion(tt.text, "suggest:")
+			if ok != tt.wantOK || got.Text != tt.want.Text || got.Author != tt.want.Author {
+				t.Errorf("ParseSuggestion() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// mockMentions は since より後のメンションを返します
+type mockMentions []domain.Mention
+
+func (m mockMentions) Mentions(ctx context.Context, since time.Time) ([]domain.Mention, error) {
+	var out []domain.Mention
+	for _, mention := range m {
+		if mention.IndexedAt.After(since) {
+			out = append(out, mention)
+		}
+	}
+	return out, nil
+}
+
+// mockQuotes は追加された名言を記録します。既存の本文と重複する名言は追加できません
+type mockQuotes struct {
+	added []domain.Quote
+}
+
+func (m *mockQuotes) AddQuote(quote domain.Quote) (*domain.Quote, error) {
+	for _, q := range m.added {
+		if q.Text == quote.Text {
+			return nil, errors.New("名言を追加できません: 本文が重複しています")
+		}
+	}
+	m.added = append(m.added, quote)
+	quote.ID = quote.StableID()
+	return &quote, nil
+}
+
+func TestInbox(t *testing.T) {
+	store, err := state.OpenFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	cfg := &config.Config{DID: "did:plc:bot", SuggestionPrefix: "suggest:", PostTimeout: time.Minute}
+	quotes := &mockQuotes{}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	botPost := domain.PostRef{URI: "at://did:plc:bot/app.bsky.feed.post/1"}
+	reply := func(n, author, text string, root domain.PostRef, at time.Time) domain.Mention {
+		return domain.Mention{
+			Post:         domain.PostRef{URI: "at://" + author + "/app.bsky.feed.post/" + n},
+			Root:         root,
+			AuthorDID:    author,
+			AuthorHandle: author,
+			Text:         text,
+			IndexedAt:    at,
+		}
+	}
+	var mentions mockMentions
+	newInbox := func() *Inbox {
+		in, err := New(cfg, &mentions, quotes, store)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		in.now = func() time.Time { return now }
+		in.ctx = context.Background()
+		return in
+	}
+
+	// 有効にする前の返信は提案として扱わない
+	mentions = mockMentions{reply("old", "did:plc:alice", "suggest: 古い名言 — 著者", botPost, now.Add(-time.Minute))}
+	in := newInbox()
+	in.poll()
+	in.poll()
+	if items, _ := in.Suggestions(); len(items) != 0 {
+		t.Fatalf("Suggestions() = %+v, want none for replies before the first start", items)
+	}
+
+	now = now.Add(10 * time.Minute)
+	otherPost := domain.PostRef{URI: "at://did:plc:carol/app.bsky.feed.post/9"}
+	mentions = append(mentions,
+		reply("1", "did:plc:alice", "suggest: 知は力なり — ベーコン", botPost, now.Add(-5*time.Minute)),
+		reply("2", "did:plc:bob", "suggest: 知は力なり！ — ベーコン", botPost, now.Add(-4*time.Minute)),  // 確認待ちと重複
+		reply("3", "did:plc:bob", "suggest: 他人のスレッド — 著者", otherPost, now.Add(-3*time.Minute)), // ボットの投稿への返信でない
+		reply("4", "did:plc:bot", "suggest: 自分の返信 — 著者", botPost, now.Add(-2*time.Minute)),
+		reply("5", "did:plc:carol", "いい言葉ですね", botPost, now.Add(-time.Minute)),
+		reply("6", "did:plc:carol", "suggest: 一期一会 — 千利休", botPost, now.Add(-time.Minute)),
+	)
+	blocked := reply("b", "did:plc:erin", "suggest: ブロック中 — 著者", botPost, now.Add(-time.Minute))
+	blocked.AuthorBlocked = true
+	mentions = append(mentions, blocked)
+	in.poll()
+
+	// 再起動しても確認待ちの提案と確認済みの返信を覚えている
+	in = newInbox()
+	in.poll()
+	items, err := in.Suggestions()
+	if err != nil || len(items) != 2 || items[0].Quote.Text != "知は力なり" || items[1].Quote.Author != "千利休" {
+		t.Fatalf("Suggestions() = %+v, %v, want the two suggestions oldest first", items, err)
+	}
+	if items[0].AuthorDID != "did:plc:alice" || items[0].PostURI != "at://did:plc:alice/app.bsky.feed.post/1" {
+		t.Errorf("Suggestions()[0] = %+v, want the reply's author and URI", items[0])
+	}
+
+	quote, err := in.Accept(items[0].ID)
+	if err != nil || quote.ID == "" || len(quotes.added) != 1 {
+		t.Fatalf("Accept() = %+v, %v, want the quote added", quote, err)
+	}
+	if err := in.Reject(items[1].ID); err != nil {
+		t.Fatalf("Reject() error = %v", err)
+	}
+	if _, err := in.Accept(items[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Accept() twice error = %v, want ErrNotFound", err)
+	}
+	if items, _ := in.Suggestions(); len(items) != 0 {
+		t.Errorf("Suggestions() = %+v, want none after accepting and rejecting", items)
+	}
+
+	// 名言ファイルに追加できなかった提案は確認待ちに残る
+	now = now.Add(time.Minute)
+	mentions = append(mentions, reply("7", "did:plc:dave", "suggest: 知は力なり — ベーコン", botPost, now))
+	in.poll()
+	items, _ = in.Suggestions()
+	if len(items) != 1 {
+		t.Fatalf("Suggestions() = %+v, want the new suggestion", items)
+	}
+	if _, err := in.Accept(items[0].ID); err == nil {
+		t.Fatalf("Accept(duplicate) error = nil, want an error")
+	}
+	if items, _ := in.Suggestions(); len(items) != 1 {
+		t.Errorf("Suggestions() = %+v, want the suggestion kept after a failed accept", items)
+	}
+}
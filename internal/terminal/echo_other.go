@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || windows)
+
+package terminal
+
+import "os"
+
+// DisableEcho はこのプラットフォームでは対応していないため、常に ErrNotTerminal を返します
+func DisableEcho(f *os.File) (restore func(), err error) {
+	return nil, ErrNotTerminal
+}
@@ -0,0 +1,25 @@
+package terminal
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// DisableEcho はコンソールのエコーを無効にし、元に戻す関数を返します。
+// f がコンソールではない場合は ErrNotTerminal を返します
+func DisableEcho(f *os.File) (restore func(), err error) {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return nil, ErrNotTerminal
+	}
+
+	newMode := mode&^windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT
+	if err := windows.SetConsoleMode(handle, newMode); err != nil {
+		return nil, err
+	}
+	return func() {
+		windows.SetConsoleMode(handle, mode)
+	}, nil
+}
@@ -0,0 +1,7 @@
+// Package terminal は端末からパスワードなどを画面に表示せずに読み込むための処理です
+package terminal
+
+import "errors"
+
+// ErrNotTerminal は入力が端末ではない（パイプやファイルからの入力など）ことを表します
+var ErrNotTerminal = errors.New("入力が端末ではありません")
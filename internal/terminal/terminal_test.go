@@ -0,0 +1,21 @@
+package terminal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDisableEcho_NotTerminal(t *testing.T) {
+	// 通常のファイルは端末ではない
+	f, err := os.Create(filepath.Join(t.TempDir(), "input"))
+	if err != nil {
+		t.Fatalf("os.Create() error = %v", err)
+	}
+	defer f.Close()
+
+	if _, err := DisableEcho(f); !errors.Is(err, ErrNotTerminal) {
+		t.Errorf("DisableEcho() error = %v, want %v", err, ErrNotTerminal)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
 	"github.com/littleironwaltz/quotebot/internal/interface/repository"
 	"github.com/littleironwaltz/quotebot/internal/usecase"
 )
@@ -61,7 +62,7 @@ type MockBlueskyRepository struct {
 	RefreshTokenCalled bool
 	RefreshTokenError  error
 	Message            string
-	Done               chan struct{}
+	ShutdownCalled     bool
 }
 
 func NewMockBlueskyRepository() *MockBlueskyRepository {
@@ -70,14 +71,21 @@ func NewMockBlueskyRepository() *MockBlueskyRepository {
 		PostMessageError:   nil,
 		RefreshTokenCalled: false,
 		RefreshTokenError:  nil,
-		Done:               make(chan struct{}, 1),
 	}
 }
 
-func (m *MockBlueskyRepository) PostMessage(ctx context.Context, message string) error {
+func (m *MockBlueskyRepository) Shutdown() {
+	m.ShutdownCalled = true
+}
+
+// Publish は usecase.PostRepository を実装します
+func (m *MockBlueskyRepository) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
 	m.PostMessageCalled = true
 	m.Message = message
-	return m.PostMessageError
+	if m.PostMessageError != nil {
+		return nil, m.PostMessageError
+	}
+	return &domain.PostRef{Platform: domain.PlatformBluesky, URI: "at://test.user/app.bsky.feed.post/1"}, nil
 }
 
 func (m *MockBlueskyRepository) RefreshToken(ctx context.Context) error {
@@ -116,27 +124,27 @@ func TestIntegrationFlow(t *testing.T) {
 		t.Error("トークンリフレッシュが呼び出されていません")
 	}
 
-	// 3. ランダムな引用を取得
-	quote, err := quoteUseCase.PostRandomQuote(ctx)
+	// 3. ランダムな引用を選び、フォーマットしてBlueskyリポジトリに投稿
+	output, err := quoteUseCase.PostQuote(ctx, mockBlueskyRepo, usecase.PostQuoteInput{})
 	if err != nil {
-		t.Fatalf("ランダムな引用の取得に失敗しました: %v", err)
+		t.Fatalf("メッセージの投稿に失敗しました: %v", err)
 	}
 
 	// 4. 引用の内容を検証
+	quote := output.Quote
 	if quote.Text == "" || quote.Author == "" {
 		t.Errorf("引用の内容が空です: %+v", quote)
 	}
 
-	// 5. 引用をフォーマットしてBlueskyリポジトリに投稿
-	message := fmt.Sprintf("%s\n- %s", quote.Text, quote.Author)
-	err = mockBlueskyRepo.PostMessage(ctx, message)
-	if err != nil {
-		t.Fatalf("メッセージの投稿に失敗しました: %v", err)
+	// 5. 投稿への参照が返される
+	if output.Ref == nil || output.Ref.URI == "" {
+		t.Errorf("投稿への参照がありません: %+v", output.Ref)
 	}
+	message := fmt.Sprintf("%s\n- %s", quote.Text, quote.Author)
 
 	// 6. モックBlueskyリポジトリが正しく呼び出されたか検証
 	if !mockBlueskyRepo.PostMessageCalled {
-		t.Error("Blueskyリポジトリの PostMessage が呼び出されませんでした")
+		t.Error("Blueskyリポジトリの Publish が呼び出されませんでした")
 	}
 
 	// 7. 投稿されたメッセージが正しいか検証
@@ -202,18 +210,14 @@ func TestIntegrationFlow_BlueskyError(t *testing.T) {
 		t.Error("トークンリフレッシュが呼び出されていません")
 	}
 
-	// ランダムな引用を取得
-	quote, err := quoteUseCase.PostRandomQuote(ctx)
-	if err != nil {
-		t.Fatalf("ランダムな引用の取得に失敗しました: %v", err)
-	}
-
-	// メッセージをフォーマットして投稿（エラーが発生するはず）
-	message := fmt.Sprintf("%s\n- %s", quote.Text, quote.Author)
-	err = mockBlueskyRepo.PostMessage(ctx, message)
+	// ランダムな引用を選び、フォーマットして投稿（エラーが発生するはず）
+	output, err := quoteUseCase.PostQuote(ctx, mockBlueskyRepo, usecase.PostQuoteInput{})
 	if err == nil {
 		t.Error("Blueskyリポジトリでエラーが発生するはずですが、成功してしまいました")
 	}
+	if output.Quote == nil || output.Ref != nil {
+		t.Errorf("失敗した投稿の結果が正しくありません: %+v", output)
+	}
 }
 
 // 統合テスト：実際のリポジトリと部分的なモックの組み合わせ
@@ -226,9 +230,7 @@ func TestIntegrationWithPartialMock(t *testing.T) {
 	quoteRepo := repository.NewQuoteRepository(cfg)
 
 	// カスタムモックBlueskyRepositoryを作成
-	mockBlueskyRepo := &MockBlueskyRepository{
-		Done: make(chan struct{}, 1),
-	}
+	mockBlueskyRepo := &MockBlueskyRepository{}
 
 	// ユースケースの初期化
 	quoteUseCase := usecase.NewQuoteUseCase(quoteRepo)
@@ -247,18 +249,12 @@ func TestIntegrationWithPartialMock(t *testing.T) {
 		t.Fatalf("トークンリフレッシュに失敗しました: %v", err)
 	}
 
-	// 2. 引用を取得
-	quote, err := quoteUseCase.PostRandomQuote(ctx)
-	if err != nil {
-		t.Fatalf("引用の取得に失敗しました: %v", err)
-	}
-
-	// 3. 引用をフォーマットしてBlueskyに投稿
-	message := fmt.Sprintf("%s\n- %s", quote.Text, quote.Author)
-	err = mockBlueskyRepo.PostMessage(ctx, message)
+	// 2. 引用を選び、フォーマットしてBlueskyに投稿
+	output, err := quoteUseCase.PostQuote(ctx, mockBlueskyRepo, usecase.PostQuoteInput{})
 	if err != nil {
 		t.Fatalf("メッセージの投稿に失敗しました: %v", err)
 	}
+	quote := output.Quote
 
 	// 4. 引用のフォーマットが正しいか検証
 	expectedPrefix := quote.Text + "\n- " + quote.Author
@@ -272,6 +268,9 @@ func TestIntegrationWithPartialMock(t *testing.T) {
 		t.Error("トークンリフレッシュが呼び出されていません")
 	}
 
-	// 6. シャットダウンシグナルのシミュレーション
-	mockBlueskyRepo.Done <- struct{}{}
+	// 6. シャットダウンのシミュレーション
+	mockBlueskyRepo.Shutdown()
+	if !mockBlueskyRepo.ShutdownCalled {
+		t.Error("シャットダウンが呼び出されていません")
+	}
 }
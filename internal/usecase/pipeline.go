@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// Stage は投稿のパイプラインの段階です
+type Stage string
+
+// 投稿のパイプラインの段階（実行される順）
+const (
+	StageSelect   Stage = "select"   // 名言を選ぶ
+	StageFormat   Stage = "format"   // テンプレートで整形する
+	StageValidate Stage = "validate" // 投稿先の上限に収まるかを確認する
+	StagePublish  Stage = "publish"  // 投稿する（DryRun では実行しない）
+	StageRecord   Stage = "record"   // 結果を記録する（失敗した場合も実行する）
+)
+
+// PostContext は1回の投稿でパイプラインの段階とフックが受け渡す値です。
+// フックは値を書き換えて、後の段階の動作を変えられます（例: Text を書き換える）
+type PostContext struct {
+	RequestID    string
+	Trigger      string
+	Formatter    *domain.Formatter   // nil の場合は domain.DefaultFormatter
+	Capabilities domain.Capabilities // 投稿先の制約。ゼロ値の場合はBluesky
+	DryRun       bool
+
+	Quote *domain.Quote // select の後に設定されます
+	// Text は format の後に設定されます。Run の前に設定した場合は整形せずにそのまま投稿します（承認された本文など）
+	Text string
+	// Langs は本文の言語です。publish で WithLangs により投稿先に伝えます
+	Langs []string
+	Ref   *domain.PostRef // publish の後に設定されます
+	// Held は Pipeline.Hold で投稿を保留した場合に true になります
+	Held bool
+	// Err は投稿を中止したエラーです。record の段階では、失敗した投稿を記録するために参照できます
+	Err error
+	// HookErrors は投稿を中止しなかったフックのエラー（publish より後の段階のフック）です
+	HookErrors []error
+	// Flags はフックが投稿に付けた印です（例: 禁止語を含む名言を投稿した）。投稿履歴に記録されます
+	Flags []string
+	// Durations は実行した段階ごとにかかった時間です（前後のフックを含む）。失敗した段階も含みます
+	Durations map[Stage]time.Duration
+}
+
+// observe は stage を start から今までにかかった時間として Durations に記録します
+func (pc *PostContext) observe(stage Stage, start time.Time) {
+	if pc.Durations == nil {
+		pc.Durations = map[Stage]time.Duration{}
+	}
+	pc.Durations[stage] = time.Since(start)
+}
+
+// Hook は段階の前後に呼ばれる処理です
+type Hook func(ctx context.Context, pc *PostContext) error
+
+// Hooks は段階ごとに登録したフックです。
+// publish までの段階のフックがエラーを返すと投稿を中止し、publish の後と record のフックのエラーは
+// 投稿を中止せずに PostContext.HookErrors に記録します
+type Hooks struct {
+	before map[Stage][]Hook
+	after  map[Stage][]Hook
+}
+
+// NewHooks は空の Hooks を作成します
+func NewHooks() *Hooks {
+	return &Hooks{before: map[Stage][]Hook{}, after: map[Stage][]Hook{}}
+}
+
+// Before は stage の前に呼ぶフックを登録します（例: 投稿の前に内容を検査する）
+func (h *Hooks) Before(stage Stage, hook Hook) {
+	h.before[stage] = append(h.before[stage], hook)
+}
+
+// After は stage が成功した後に呼ぶフックを登録します（例: 投稿の後にWebhookで知らせる）
+func (h *Hooks) After(stage Stage, hook Hook) {
+	h.after[stage] = append(h.after[stage], hook)
+}
+
+// runBefore は stage の前のフックを順に呼び、最初のエラーを返します
+func (h *Hooks) runBefore(ctx context.Context, stage Stage, pc *PostContext) error {
+	if h == nil {
+		return nil
+	}
+	return runHooks(ctx, h.before[stage], stage, pc)
+}
+
+// runAfter は stage の後のフックを順に呼び、最初のエラーを返します
+func (h *Hooks) runAfter(ctx context.Context, stage Stage, pc *PostContext) error {
+	if h == nil {
+		return nil
+	}
+	return runHooks(ctx, h.after[stage], stage, pc)
+}
+
+func runHooks(ctx context.Context, hooks []Hook, stage Stage, pc *PostContext) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, pc); err != nil {
+			return fmt.Errorf("%s のフックでエラーが発生しました: %w", stage, err)
+		}
+	}
+	return nil
+}
+
+// Pipeline は名言の投稿を select → format → validate → publish → record の順に実行し、
+// 各段階の前後で Hooks を呼びます
+type Pipeline struct {
+	Select func(ctx context.Context) (*domain.Quote, error)
+	Repo   PostRepository // DryRun の場合は nil でもかまいません
+	// Record は任意です。投稿に失敗した場合も、PostContext.Err を設定して呼ばれます
+	Record func(ctx context.Context, pc *PostContext)
+	// Hold は任意です。設定した場合は publish の代わりに呼ばれ、投稿を保留します（承認待ちに入れるなど）。
+	// DryRun と同じく publish のフックは呼ばれません
+	Hold  func(ctx context.Context, pc *PostContext) error
+	Hooks *Hooks // 任意
+}
+
+// Run はパイプラインを実行します。publish までの段階が失敗した場合はそのエラーを返します
+func (p Pipeline) Run(ctx context.Context, pc *PostContext) error {
+	if pc.Formatter == nil {
+		pc.Formatter = domain.DefaultFormatter()
+	}
+	if pc.Capabilities.Platform == "" {
+		pc.Capabilities = domain.BlueskyCapabilities
+	}
+
+	pc.Err = p.post(ctx, pc)
+	if p.Record != nil {
+		defer pc.observe(StageRecord, time.Now())
+		if err := p.Hooks.runBefore(ctx, StageRecord, pc); err != nil {
+			pc.HookErrors = append(pc.HookErrors, err)
+		}
+		p.Record(ctx, pc)
+		if err := p.Hooks.runAfter(ctx, StageRecord, pc); err != nil {
+			pc.HookErrors = append(pc.HookErrors, err)
+		}
+	}
+	return pc.Err
+}
+
+// post は record より前の段階を実行します
+func (p Pipeline) post(ctx context.Context, pc *PostContext) error {
+	stages := []struct {
+		stage Stage
+		run   func() error
+	}{
+		{StageSelect, func() (err error) {
+			pc.Quote, err = p.Select(ctx)
+			return err
+		}},
+		{StageFormat, func() (err error) {
+			if pc.Text != "" {
+				return nil
+			}
+			pc.Text, err = pc.Formatter.Render(pc.Quote, pc.Capabilities)
+			return err
+		}},
+		{StageValidate, func() error {
+			return pc.Capabilities.Validate(pc.Text)
+		}},
+	}
+	for _, s := range stages {
+		if err := p.runStage(ctx, pc, s.stage, s.run); err != nil {
+			return err
+		}
+	}
+
+	if pc.DryRun {
+		return nil
+	}
+	if p.Hold != nil {
+		if err := p.Hold(ctx, pc); err != nil {
+			return err
+		}
+		pc.Held = true
+		return nil
+	}
+	defer pc.observe(StagePublish, time.Now())
+	if err := p.Hooks.runBefore(ctx, StagePublish, pc); err != nil {
+		return err
+	}
+	ref, err := p.Repo.Publish(WithLangs(ctx, pc.Langs), pc.Text)
+	if err != nil {
+		return err
+	}
+	pc.Ref = ref
+	// 投稿した後なので、フックのエラーで投稿を失敗にはしない
+	if err := p.Hooks.runAfter(ctx, StagePublish, pc); err != nil {
+		pc.HookErrors = append(pc.HookErrors, err)
+	}
+	return nil
+}
+
+// runStage は stage の前のフック、stage、後のフックを順に実行し、かかった時間を記録します
+func (p Pipeline) runStage(ctx context.Context, pc *PostContext, stage Stage, run func() error) error {
+	defer pc.observe(stage, time.Now())
+	if err := p.Hooks.runBefore(ctx, stage, pc); err != nil {
+		return err
+	}
+	if err := run(); err != nil {
+		return err
+	}
+	return p.Hooks.runAfter(ctx, stage, pc)
+}
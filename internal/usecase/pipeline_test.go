@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+func TestPipeline_Run(t *testing.T) {
+	quote := &domain.Quote{Text: "テスト名言", Author: "著者"}
+	tests := []struct {
+		name         string
+		register     func(h *Hooks, calls *[]string)
+		dryRun       bool
+		hold         bool
+		text         string
+		postErr      error
+		wantCalls    string
+		wantText     string
+		wantPosts    int
+		wantErr      bool
+		wantHookErrs int
+	}{
+		{
+			name: "正常系: 段階の順にフックが呼ばれる",
+			register: func(h *Hooks, calls *[]string) {
+				for _, stage := range []Stage{StageSelect, StageFormat, StageValidate, StagePublish, StageRecord} {
+					stage := stage
+					h.Before(stage, func(ctx context.Context, pc *PostContext) error {
+						*calls = append(*calls, "before:"+string(stage))
+						return nil
+					})
+					h.After(stage, func(ctx context.Context, pc *PostContext) error {
+						*calls = append(*calls, "after:"+string(stage))
+						return nil
+					})
+				}
+			},
+			wantCalls: "before:select,after:select,before:format,after:format,before:validate,after:validate," +
+				"before:publish,after:publish,before:record,record,after:record",
+			wantText:  "テスト名言\n- 著者",
+			wantPosts: 1,
+		},
+		{
+			name: "正常系: フックで本文を書き換える",
+			register: func(h *Hooks, calls *[]string) {
+				h.After(StageFormat, func(ctx context.Context, pc *PostContext) error {
+					pc.Text += " #quote"
+					return nil
+				})
+			},
+			wantCalls: "record",
+			wantText:  "テスト名言\n- 著者 #quote",
+			wantPosts: 1,
+		},
+		{
+			name: "異常系: 投稿の前のフックのエラーで投稿を中止し、失敗を記録する",
+			register: func(h *Hooks, calls *[]string) {
+				h.Before(StagePublish, func(ctx context.Context, pc *PostContext) error {
+					return errors.New("禁止語が含まれています")
+				})
+			},
+			wantCalls: "record",
+			wantText:  "テスト名言\n- 著者",
+			wantErr:   true,
+		},
+		{
+			name: "正常系: 投稿の後のフックのエラーでは投稿を失敗にしない",
+			register: func(h *Hooks, calls *[]string) {
+				h.After(StagePublish, func(ctx context.Context, pc *PostContext) error {
+					return errors.New("webhook unavailable")
+				})
+			},
+			wantCalls:    "record",
+			wantText:     "テスト名言\n- 著者",
+			wantPosts:    1,
+			wantHookErrs: 1,
+		},
+		{
+			name: "正常系: DryRun では投稿の段階とそのフックを実行しない",
+			register: func(h *Hooks, calls *[]string) {
+				h.Before(StagePublish, func(ctx context.Context, pc *PostContext) error {
+					*calls = append(*calls, "before:publish")
+					return nil
+				})
+			},
+			dryRun:    true,
+			wantCalls: "record",
+			wantText:  "テスト名言\n- 著者",
+		},
+		{
+			name: "正常系: Hold では投稿せずに保留する",
+			register: func(h *Hooks, calls *[]string) {
+				h.Before(StagePublish, func(ctx context.Context, pc *PostContext) error {
+					*calls = append(*calls, "before:publish")
+					return nil
+				})
+			},
+			hold:      true,
+			wantCalls: "hold,record",
+			wantText:  "テスト名言\n- 著者",
+		},
+		{
+			name:      "正常系: 本文を指定した場合は整形しない",
+			register:  func(h *Hooks, calls *[]string) {},
+			text:      "承認された本文",
+			wantCalls: "record",
+			wantText:  "承認された本文",
+			wantPosts: 1,
+		},
+		{
+			name:      "異常系: 投稿に失敗",
+			register:  func(h *Hooks, calls *[]string) {},
+			postErr:   errors.New("Bluesky APIエラー"),
+			wantCalls: "record",
+			wantText:  "テスト名言\n- 著者",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			hooks := NewHooks()
+			tt.register(hooks, &calls)
+			repo := &mockPostRepository{err: tt.postErr}
+			var recorded error
+			pipeline := Pipeline{
+				Select: func(context.Context) (*domain.Quote, error) { return quote, nil },
+				Repo:   repo,
+				Hooks:  hooks,
+				Record: func(ctx context.Context, pc *PostContext) {
+					calls = append(calls, "record")
+					recorded = pc.Err
+				},
+			}
+
+			if tt.hold {
+				pipeline.Hold = func(ctx context.Context, pc *PostContext) error {
+					calls = append(calls, "hold")
+					return nil
+				}
+			}
+
+			pc := &PostContext{DryRun: tt.dryRun, Text: tt.text}
+			err := pipeline.Run(context.Background(), pc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if recorded != err {
+				t.Errorf("recorded error = %v, want %v", recorded, err)
+			}
+			if got := strings.Join(calls, ","); got != tt.wantCalls {
+				t.Errorf("calls = %s, want %s", got, tt.wantCalls)
+			}
+			if pc.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", pc.Text, tt.wantText)
+			}
+			if len(repo.messages) != tt.wantPosts {
+				t.Errorf("posts = %d, want %d", len(repo.messages), tt.wantPosts)
+			}
+			if tt.wantPosts > 0 && repo.messages[0] != tt.wantText {
+				t.Errorf("posted %q, want %q", repo.messages[0], tt.wantText)
+			}
+			if len(pc.HookErrors) != tt.wantHookErrs {
+				t.Errorf("HookErrors = %v, want %d", pc.HookErrors, tt.wantHookErrs)
+			}
+		})
+	}
+}
+
+func TestPipeline_Durations(t *testing.T) {
+	quote := &domain.Quote{Text: "テスト名言", Author: "著者"}
+	tests := []struct {
+		name       string
+		dryRun     bool
+		postErr    error
+		wantStages []Stage
+	}{
+		{name: "正常系: 実行したすべての段階", wantStages: []Stage{StageSelect, StageFormat, StageValidate, StagePublish, StageRecord}},
+		{name: "正常系: 失敗した段階も含む", postErr: errors.New("Bluesky APIエラー"), wantStages: []Stage{StageSelect, StageFormat, StageValidate, StagePublish, StageRecord}},
+		{name: "正常系: DryRun では投稿の段階を含まない", dryRun: true, wantStages: []Stage{StageSelect, StageFormat, StageValidate, StageRecord}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks := NewHooks()
+			// フックの時間も段階の時間に含める
+			hooks.Before(StageFormat, func(ctx context.Context, pc *PostContext) error {
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			})
+			pipeline := Pipeline{
+				Select: func(context.Context) (*domain.Quote, error) { return quote, nil },
+				Repo:   &mockPostRepository{err: tt.postErr},
+				Hooks:  hooks,
+				Record: func(ctx context.Context, pc *PostContext) {},
+			}
+			pc := &PostContext{DryRun: tt.dryRun}
+			pipeline.Run(context.Background(), pc)
+
+			if len(pc.Durations) != len(tt.wantStages) {
+				t.Errorf("Durations = %v, want %v", pc.Durations, tt.wantStages)
+			}
+			for _, stage := range tt.wantStages {
+				if _, ok := pc.Durations[stage]; !ok {
+					t.Errorf("Durations has no %s", stage)
+				}
+			}
+			if pc.Durations[StageFormat] < 5*time.Millisecond {
+				t.Errorf("Durations[format] = %s, want the hook included", pc.Durations[StageFormat])
+			}
+		})
+	}
+}
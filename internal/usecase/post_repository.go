@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// PostRepository は投稿先への投稿用インターフェースです
+type PostRepository interface {
+	// Publish は指定されたメッセージを投稿し、投稿への参照を返します
+	Publish(ctx context.Context, message string) (*domain.PostRef, error)
+}
+
+// IdempotentPostRepository は冪等キーを指定して投稿できる投稿先です。
+// 同じキーで何度投稿しても投稿は1件になるため、投稿できたか分からない場合に安全に確かめられます
+type IdempotentPostRepository interface {
+	PostRepository
+	// PublishOnce は key（domain.NewPostKey）で投稿します。同じ key の投稿がすでにあればそれを返します
+	PublishOnce(ctx context.Context, message, key string) (*domain.PostRef, error)
+	// FindPost は key で投稿した投稿を返します。投稿されていない場合は nil を返します
+	FindPost(ctx context.Context, key string) (*domain.PostRef, error)
+}
+
+// ReplyPostRepository はスレッドに返信できる投稿先です
+type ReplyPostRepository interface {
+	// PublishReply は parent への返信として投稿します。root は parent が属するスレッドの最初の投稿です
+	PublishReply(ctx context.Context, message string, root, parent domain.PostRef) (*domain.PostRef, error)
+}
+
+// ThreadPost はスレッドの1件の投稿です
+type ThreadPost struct {
+	Text  string
+	Langs []string // 本文の言語（BCP 47）
+}
+
+// ThreadPostRepository はスレッドを1回のリクエストでまとめて投稿できる投稿先です。
+// すべての投稿が作られるか1件も作られないかのどちらかになるため、スレッドが途中で切れることはありません
+type ThreadPostRepository interface {
+	// PublishThread は posts を、最初の投稿に順に返信していくスレッドとして投稿し、投稿への参照を同じ順に返します。
+	// 最初の投稿は key（domain.NewPostKey）で投稿し、同じ key の投稿がすでにあればその投稿だけを返します
+	PublishThread(ctx context.Context, key string, posts []ThreadPost) ([]*domain.PostRef, error)
+}
+
+type langsKey struct{}
+
+// WithLangs は投稿の本文の言語（BCP 47）を伝える context を返します。言語を付けられる投稿先は投稿に付けます
+func WithLangs(ctx context.Context, langs []string) context.Context {
+	if len(langs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, langsKey{}, langs)
+}
+
+// LangsFromContext は WithLangs で伝えた投稿の言語を返します
+func LangsFromContext(ctx context.Context) []string {
+	langs, _ := ctx.Value(langsKey{}).([]string)
+	return langs
+}
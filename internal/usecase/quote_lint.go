@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// LintRule は名言の表記の確認の種類です
+type LintRule string
+
+// 名言の表記の確認
+const (
+	LintTrailingSpace LintRule = "trailing_space" // 行末や本文の前後の空白
+	LintDoubleSpace   LintRule = "double_space"   // 続けて2つ以上ある空白
+	LintQuoteMarks    LintRule = "quote_marks"    // 対になっていない括弧や引用符
+	LintMissingAuthor LintRule = "missing_author" // 著者がない
+	LintSimilar       LintRule = "similar"        // ほかの名言とよく似ている（書き間違えた重複の疑い）
+)
+
+// similarThreshold は名言を似ているとみなす、表記の揺れを除いた本文の2文字ずつの組の一致の割合（Dice係数）です
+const similarThreshold = 0.85
+
+// LintIssue は名言の表記の1件の指摘です。Index は0始まりの位置です
+type LintIssue struct {
+	Index   int
+	Rule    LintRule
+	Message string
+	// Fixable は FixQuote で直せる指摘です
+	Fixable bool
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%d件目: [%s] %s", i.Index+1, i.Rule, i.Message)
+}
+
+// quotePairs は対になる括弧と引用符です
+var quotePairs = [][2]string{{"「", "」"}, {"『", "』"}, {"“", "”"}, {"‘", "’"}, {"（", "）"}, {"(", ")"}}
+
+// doubleSpace は続く空白です
+var doubleSpace = regexp.MustCompile(`[ \t　]{2,}`)
+
+// spaces は行末として取り除く空白です
+const spaces = " \t　"
+
+// LintQuotes は名言の本文と著者の表記を確認し、公開する前に直したほうがよい点を指摘します。
+// ValidateQuotes と違い、指摘があっても投稿はできます
+func LintQuotes(quotes []domain.Quote) []LintIssue {
+	var issues []LintIssue
+	for i, quote := range quotes {
+		for _, field := range []struct{ name, value string }{{"本文", quote.Text}, {"著者", quote.Author}} {
+			if hasTrailingSpace(field.value) {
+				issues = append(issues, LintIssue{Index: i, Rule: LintTrailingSpace, Message: field.name + "の行末か前後に空白があります", Fixable: true})
+			}
+			if hasDoubleSpace(field.value) {
+				issues = append(issues, LintIssue{Index: i, Rule: LintDoubleSpace, Message: field.name + "に空白が続いています", Fixable: true})
+			}
+		}
+		if mark, ok := unmatchedQuoteMark(quote.Text); !ok {
+			issues = append(issues, LintIssue{Index: i, Rule: LintQuoteMarks, Message: fmt.Sprintf("本文の %s が対になっていません", mark)})
+		}
+		if strings.TrimSpace(quote.Author) == "" {
+			issues = append(issues, LintIssue{Index: i, Rule: LintMissingAuthor, Message: "著者がありません"})
+		}
+	}
+	return append(issues, similarQuotes(quotes)...)
+}
+
+// FixQuote は LintQuotes で Fixable な指摘を直した名言と、書き換えたかどうかを返します。
+// IDを省略した名言は本文から作ったIDが変わらないよう、直す前のIDを付けます
+func FixQuote(quote domain.Quote) (domain.Quote, bool) {
+	fixed := quote
+	fixed.Text = fixSpaces(quote.Text)
+	fixed.Author = fixSpaces(quote.Author)
+	if fixed.Text == quote.Text && fixed.Author == quote.Author {
+		return quote, false
+	}
+	fixed.ID = quote.StableID()
+	return fixed, true
+}
+
+func hasTrailingSpace(value string) bool {
+	if value != strings.TrimSpace(value) {
+		return true
+	}
+	for _, line := range strings.Split(value, "\n") {
+		if line != strings.TrimRight(line, spaces) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDoubleSpace は行の中（行頭の字下げと行末を除く）に空白が続いているかを返します
+func hasDoubleSpace(value string) bool {
+	for _, line := range strings.Split(value, "\n") {
+		if doubleSpace.MatchString(strings.Trim(line, spaces)) {
+			return true
+		}
+	}
+	return false
+}
+
+// fixSpaces は行末と前後の空白を取り除き、行の中で続く空白を1つにします。行頭の字下げは残します
+func fixSpaces(value string) string {
+	lines := strings.Split(strings.TrimSpace(value), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, spaces)
+		body := strings.TrimLeft(line, spaces)
+		lines[i] = line[:len(line)-len(body)] + doubleSpace.ReplaceAllStringFunc(body, func(match string) string {
+			// 全角の空白を含む場合は全角の空白1つにする
+			if strings.Contains(match, "　") {
+				return "　"
+			}
+			return " "
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unmatchedQuoteMark は対になっていない括弧か引用符を返します。すべて対になっていれば ok は true です
+func unmatchedQuoteMark(text string) (mark string, ok bool) {
+	for _, pair := range quotePairs {
+		depth := 0
+		for _, r := range text {
+			switch string(r) {
+			case pair[0]:
+				depth++
+			case pair[1]:
+				depth--
+			}
+			if depth < 0 {
+				return pair[1], false
+			}
+		}
+		if depth != 0 {
+			return pair[0], false
+		}
+	}
+	// 向きのない引用符は数が偶数であれば対になっているとみなす
+	if strings.Count(text, `"`)%2 != 0 {
+		return `"`, false
+	}
+	return "", true
+}
+
+// similarQuotes は表記の揺れを除いた本文がよく似ている名言を、後に出てくる方について指摘します
+func similarQuotes(quotes []domain.Quote) []LintIssue {
+	type entry struct {
+		length  int
+		bigrams map[string]int
+	}
+	entries := make([]entry, len(quotes))
+	for i, quote := range quotes {
+		runes := []rune(domain.NormalizeText(quote.Text))
+		bigrams := make(map[string]int, len(runes))
+		for j := 0; j+1 < len(runes); j++ {
+			bigrams[string(runes[j:j+2])]++
+		}
+		entries[i] = entry{length: len(runes) - 1, bigrams: bigrams}
+	}
+
+	var issues []LintIssue
+	for i := range entries {
+		for j := 0; j < i; j++ {
+			a, b := entries[j], entries[i]
+			if a.length <= 0 || b.length <= 0 {
+				continue
+			}
+			// 長さが大きく違えば一致の割合はしきい値に届かない
+			if float64(2*min(a.length, b.length)) < similarThreshold*float64(a.length+b.length) {
+				continue
+			}
+			shared := 0
+			for bigram, n := range b.bigrams {
+				shared += min(n, a.bigrams[bigram])
+			}
+			score := float64(2*shared) / float64(a.length+b.length)
+			if score >= similarThreshold {
+				issues = append(issues, LintIssue{Index: i, Rule: LintSimilar, Message: fmt.Sprintf("%d件目とよく似ています（%.0f%%）", j+1, score*100)})
+				break
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+func TestLintQuotes(t *testing.T) {
+	tests := []struct {
+		name   string
+		quotes []domain.Quote
+		want   []string
+	}{
+		{
+			name: "正常系: 指摘なし",
+			quotes: []domain.Quote{
+				{Text: "「知は力なり」と彼は言った。", Author: "著者1"},
+				{Text: "一行目\n  字下げした行は指摘しない", Author: "著者2"},
+			},
+			want: nil,
+		},
+		{
+			name: "異常系: 行末と前後の空白",
+			quotes: []domain.Quote{
+				{Text: "名言1 \n続き", Author: " 著者1"},
+			},
+			want: []string{"1件目: [trailing_space] 本文の行末か前後に空白があります", "1件目: [trailing_space] 著者の行末か前後に空白があります"},
+		},
+		{
+			name: "異常系: 続く空白",
+			quotes: []domain.Quote{
+				{Text: "名言の　　途中", Author: "First  Last"},
+			},
+			want: []string{"1件目: [double_space] 本文に空白が続いています", "1件目: [double_space] 著者に空白が続いています"},
+		},
+		{
+			name: "異常系: 対になっていない括弧と引用符",
+			quotes: []domain.Quote{
+				{Text: "「知は力なり", Author: "著者1"},
+				{Text: "時は金なり」", Author: "著者2"},
+				{Text: `"Knowledge is power`, Author: "著者3"},
+			},
+			want: []string{"1件目: [quote_marks] 本文の 「 が対になっていません", "2件目: [quote_marks] 本文の 」 が対になっていません", `3件目: [quote_marks] 本文の " が対になっていません`},
+		},
+		{
+			name: "異常系: 著者がない",
+			quotes: []domain.Quote{
+				{Text: "名言1", Author: ""},
+			},
+			want: []string{"1件目: [missing_author] 著者がありません"},
+		},
+		{
+			name: "異常系: よく似た名言",
+			quotes: []domain.Quote{
+				{Text: "学ぶことをやめた者は、二十歳であろうと八十歳であろうと老人である。", Author: "ヘンリー・フォード"},
+				{Text: "まったく別の名言です。", Author: "著者2"},
+				{Text: "学ぶことをやめた者は、二十歳であろうと八十歳であろうと老人だ。", Author: "ヘンリー・フォード"},
+			},
+			want: []string{"3件目: [similar] 1件目とよく似ています（93%）"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, issue := range LintQuotes(tt.quotes) {
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LintQuotes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixQuote(t *testing.T) {
+	tests := []struct {
+		name      string
+		quote     domain.Quote
+		want      domain.Quote
+		wantFixed bool
+	}{
+		{
+			name:  "正常系: 直すところがない",
+			quote: domain.Quote{Text: "名言1", Author: "著者1"},
+			want:  domain.Quote{Text: "名言1", Author: "著者1"},
+		},
+		{
+			name:      "正常系: 空白を直して直す前のIDを付ける",
+			quote:     domain.Quote{Text: " 名言の　　途中 \n  字下げ  した行", Author: "First  Last "},
+			want:      domain.Quote{ID: domain.ContentID(" 名言の　　途中 \n  字下げ  した行", "First  Last "), Text: "名言の　途中\n  字下げ した行", Author: "First Last"},
+			wantFixed: true,
+		},
+		{
+			name:      "正常系: 指定したIDはそのまま",
+			quote:     domain.Quote{ID: "q1", Text: "名言1 ", Author: "著者1"},
+			want:      domain.Quote{ID: "q1", Text: "名言1", Author: "著者1"},
+			wantFixed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fixed := FixQuote(tt.quote)
+			if fixed != tt.wantFixed {
+				t.Errorf("FixQuote() fixed = %v, want %v", fixed, tt.wantFixed)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FixQuote() = %+v, want %+v", got, tt.want)
+			}
+			if fixed && len(LintQuotes([]domain.Quote{got})) != 0 {
+				t.Errorf("LintQuotes(FixQuote()) = %v, want no issues", LintQuotes([]domain.Quote{got}))
+			}
+		})
+	}
+}
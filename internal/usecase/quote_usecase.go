@@ -3,8 +3,11 @@ package usecase
 import (
 	"context"
 	"fmt"
-	"math/rand"
-	"time"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/littleironwaltz/quotebot/internal/domain"
 )
@@ -14,37 +17,255 @@ type QuoteRepository interface {
 	LoadQuotes() ([]domain.Quote, error)
 }
 
-// QuoteUseCase は名言の取得と投稿を制御します
+// QuoteHasher は内容のハッシュを返せる QuoteRepository です。
+// 内容が変わっていなければ、再読み込みで名言を読み込み直さず、選ぶ順番もそのまま続けます
+type QuoteHasher interface {
+	QuotesHash() (string, error)
+}
+
+// QuoteWriter は名言を追加できる QuoteRepository です
+type QuoteWriter interface {
+	AppendQuote(quote domain.Quote) error
+}
+
+// QuoteUseCase は名言の取得と投稿を制御します。
+// 再読み込み、定期投稿、即時投稿など複数のゴルーチンから同時に使えます
 type QuoteUseCase struct {
 	quoteRepo QuoteRepository
-	quotes    []domain.Quote
+	// quotes は名言リストのスナップショットです。再読み込みでは新しいスライスに差し替え、
+	// 差し替えたスライスは変更しないため、読み出しにはロックが要りません
+	quotes   atomic.Pointer[[]domain.Quote]
+	reloadMu sync.Mutex // 再読み込みを1つずつ行い、古い読み込み結果で上書きしないようにします
+	hash     string     // 最後に読み込んだ内容のハッシュ。reloadMu で保護します
+	addMu    sync.Mutex // 名言の追加を1つずつ行い、同時に追加した名言の重複を見逃さないようにします
+
+	rng      *rand.Rand
+	selector QuoteSelector
+	rngMu    sync.Mutex // rand.Rand と selector の状態は並行して使えないため保護します
+}
+
+// Option は QuoteUseCase の任意の設定です
+type Option func(*QuoteUseCase)
+
+// WithRand は名言を選ぶ乱数生成器を設定します。シードを固定すると、選ばれる順番を再現できます
+func WithRand(r *rand.Rand) Option {
+	return func(uc *QuoteUseCase) {
+		uc.rng = r
+	}
+}
+
+// WithSelector は定期投稿で名言を選ぶ方法を設定します。デフォルトは RandomSelector です
+func WithSelector(selector QuoteSelector) Option {
+	return func(uc *QuoteUseCase) {
+		uc.selector = selector
+	}
+}
+
+// NewRand は seed から乱数生成器を作成します。seed が0の場合は実行ごとに異なる順番になります
+func NewRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return rand.New(rand.NewPCG(uint64(seed), 0))
 }
 
 // NewQuoteUseCase は新しいQuoteUseCaseインスタンスを作成します
-func NewQuoteUseCase(qr QuoteRepository) *QuoteUseCase {
-	return &QuoteUseCase{
+func NewQuoteUseCase(qr QuoteRepository, opts ...Option) *QuoteUseCase {
+	uc := &QuoteUseCase{
 		quoteRepo: qr,
 	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	if uc.rng == nil {
+		uc.rng = NewRand(0)
+	}
+	if uc.selector == nil {
+		uc.selector = RandomSelector{}
+	}
+	return uc
 }
 
 // Initialize は名言リストを読み込み、初期化を実行します
 func (uc *QuoteUseCase) Initialize() error {
+	return uc.Reload()
+}
+
+// Reload は名言リストを読み込み直します。
+// 読み込みに失敗した場合は現在の名言リストをそのまま使い続けます。
+// 読み込み中も、それまでの名言リストから選べます。
+// 取得元が QuoteHasher で内容が変わっていない場合は、何もしません
+func (uc *QuoteUseCase) Reload() error {
+	uc.reloadMu.Lock()
+	defer uc.reloadMu.Unlock()
+
+	var hash string
+	if hasher, ok := uc.quoteRepo.(QuoteHasher); ok {
+		var err error
+		if hash, err = hasher.QuotesHash(); err != nil {
+			return fmt.Errorf("名言の読み込みに失敗しました: %w", err)
+		}
+		// 更新日時だけが変わった場合などに、shuffle の順番を最初からやり直さない
+		if hash == uc.hash && uc.quotes.Load() != nil {
+			return nil
+		}
+	}
 	quotes, err := uc.quoteRepo.LoadQuotes()
 	if err != nil {
 		return fmt.Errorf("名言の読み込みに失敗しました: %w", err)
 	}
+	// IDを省略した名言には本文から作ったIDを付け、投稿履歴や承認待ちの投稿から同じ名言を指せるようにする
+	for i := range quotes {
+		quotes[i].ID = quotes[i].StableID()
+	}
 
-	uc.quotes = quotes
-	rand.Seed(time.Now().UnixNano())
+	// selector の状態は名言リストの位置を指すため、差し替えと同時に捨てる
+	uc.rngMu.Lock()
+	uc.quotes.Store(&quotes)
+	uc.selector.Reset()
+	uc.rngMu.Unlock()
+	uc.hash = hash
 	return nil
 }
 
-// PostRandomQuote はランダムな名言を選択して返します
-func (uc *QuoteUseCase) PostRandomQuote(ctx context.Context) (*domain.Quote, error) {
-	if len(uc.quotes) == 0 {
-		return nil, fmt.Errorf("利用可能な名言がありません")
+// AddQuote は名言を検証してから名言ファイルに追加し、読み込み直します。
+// 追加した名言を、IDを省略した場合は本文から作ったIDを付けて返します
+func (uc *QuoteUseCase) AddQuote(quote domain.Quote) (*domain.Quote, error) {
+	writer, ok := uc.quoteRepo.(QuoteWriter)
+	if !ok {
+		return nil, fmt.Errorf("名言の取得元に名言を追加できません")
 	}
+	uc.addMu.Lock()
+	defer uc.addMu.Unlock()
 
-	quote := uc.quotes[rand.Intn(len(uc.quotes))]
+	// 既存の名言と本文やIDが重複しないかも確かめる
+	quotes := append(slices.Clone(uc.snapshot()), quote)
+	for _, problem := range ValidateQuotes(quotes) {
+		if problem.Index == len(quotes)-1 {
+			return nil, fmt.Errorf("名言を追加できません: %w", problem.Err)
+		}
+	}
+	if err := writer.AppendQuote(quote); err != nil {
+		return nil, err
+	}
+	if err := uc.Reload(); err != nil {
+		return nil, err
+	}
+	quote.ID = quote.StableID()
 	return &quote, nil
 }
+
+// snapshot は現在の名言リストを返します。返したスライスは変更しないでください
+func (uc *QuoteUseCase) snapshot() []domain.Quote {
+	if quotes := uc.quotes.Load(); quotes != nil {
+		return *quotes
+	}
+	return nil
+}
+
+// Quotes は現在の名言リストのコピーを返します
+func (uc *QuoteUseCase) Quotes() []domain.Quote {
+	quotes := slices.Clone(uc.snapshot())
+	for i := range quotes {
+		quotes[i].Tags = slices.Clone(quotes[i].Tags)
+	}
+	return quotes
+}
+
+// Count は現在の名言リストの件数を返します
+func (uc *QuoteUseCase) Count() int {
+	return len(uc.snapshot())
+}
+
+// PostRandomQuote は設定した選び方（QuoteSelector）で名言を選択して返します
+func (uc *QuoteUseCase) PostRandomQuote(ctx context.Context) (*domain.Quote, error) {
+	uc.rngMu.Lock()
+	quotes := uc.snapshot()
+	if len(quotes) == 0 {
+		uc.rngMu.Unlock()
+		return nil, domain.ErrPoolEmpty
+	}
+	i := uc.selector.Select(quotes, uc.rng)
+	uc.rngMu.Unlock()
+	return copyQuote(quotes[i]), nil
+}
+
+// PostQuoteInput は名言を投稿するときの整形と投稿の設定です
+type PostQuoteInput struct {
+	Formatter    *domain.Formatter   // nil の場合は domain.DefaultFormatter
+	Capabilities domain.Capabilities // 投稿先の制約。ゼロ値の場合はBluesky
+	DryRun       bool                // true の場合は整形だけして投稿しません
+	Hooks        *Hooks              // 任意。パイプラインの段階の前後に呼ぶフック
+}
+
+// PostQuoteOutput は名言の投稿の結果です。失敗した場合も、分かった範囲の値を返します
+type PostQuoteOutput struct {
+	Quote *domain.Quote
+	Text  string          // 整形した投稿本文
+	Ref   *domain.PostRef // DryRun の場合は nil
+}
+
+// PostQuote は名言を選び、整形して repo に投稿します
+func (uc *QuoteUseCase) PostQuote(ctx context.Context, repo PostRepository, input PostQuoteInput) (*PostQuoteOutput, error) {
+	return publish(ctx, repo, uc.PostRandomQuote, input)
+}
+
+// PublishQuote は選んだ名言を整形し、投稿先の上限に収まることを確認して repo に投稿します。
+// DryRun の場合は repo を使わないため、nil でもかまいません
+func PublishQuote(ctx context.Context, repo PostRepository, quote *domain.Quote, input PostQuoteInput) (*PostQuoteOutput, error) {
+	return publish(ctx, repo, func(context.Context) (*domain.Quote, error) { return quote, nil }, input)
+}
+
+// publish は記録の段階のないパイプラインで投稿します
+func publish(ctx context.Context, repo PostRepository, selectQuote func(context.Context) (*domain.Quote, error), input PostQuoteInput) (*PostQuoteOutput, error) {
+	pc := &PostContext{Formatter: input.Formatter, Capabilities: input.Capabilities, DryRun: input.DryRun}
+	err := Pipeline{Select: selectQuote, Repo: repo, Hooks: input.Hooks}.Run(ctx, pc)
+	return &PostQuoteOutput{Quote: pc.Quote, Text: pc.Text, Ref: pc.Ref}, err
+}
+
+// QuoteByIndex は名言ファイルの index 番目（1始まり）の名言を返します
+func (uc *QuoteUseCase) QuoteByIndex(index int) (*domain.Quote, error) {
+	quotes := uc.snapshot()
+	if index < 1 || index > len(quotes) {
+		return nil, fmt.Errorf("名言の番号は1から%dの範囲で指定してください: %d", len(quotes), index)
+	}
+	return copyQuote(quotes[index-1]), nil
+}
+
+// QuoteByID は指定したIDの名言を返します
+func (uc *QuoteUseCase) QuoteByID(id string) (*domain.Quote, error) {
+	for _, quote := range uc.snapshot() {
+		if quote.ID == id {
+			return copyQuote(quote), nil
+		}
+	}
+	return nil, fmt.Errorf("IDが %s の名言が見つかりません", id)
+}
+
+// RandomQuoteWithTag は指定したタグが付いた名言からランダムに1件を返します
+func (uc *QuoteUseCase) RandomQuoteWithTag(tag string) (*domain.Quote, error) {
+	var candidates []domain.Quote
+	for _, quote := range uc.snapshot() {
+		if quote.HasTag(tag) {
+			candidates = append(candidates, quote)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("タグ %s が付いた名言が見つかりません", tag)
+	}
+	return copyQuote(candidates[uc.intn(len(candidates))]), nil
+}
+
+// intn は [0, n) の乱数を返します
+func (uc *QuoteUseCase) intn(n int) int {
+	uc.rngMu.Lock()
+	defer uc.rngMu.Unlock()
+	return uc.rng.IntN(n)
+}
+
+// copyQuote は呼び出し側が変更してもスナップショットに影響しないよう、名言をコピーします
+func copyQuote(quote domain.Quote) *domain.Quote {
+	quote.Tags = slices.Clone(quote.Tags)
+	quote.Translations = maps.Clone(quote.Translations)
+	return &quote
+}
@@ -3,9 +3,11 @@ package usecase
 import (
 	"context"
 	"errors"
-	"math/rand"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
-	"time"
 
 	"github.com/littleironwaltz/quotebot/internal/domain"
 )
@@ -20,6 +22,33 @@ func (m *mockQuoteRepository) LoadQuotes() ([]domain.Quote, error) {
 	return m.quotes, m.err
 }
 
+// writableQuoteRepository は追加した名言を次の読み込みから返します
+type writableQuoteRepository struct {
+	mockQuoteRepository
+}
+
+func (m *writableQuoteRepository) AppendQuote(quote domain.Quote) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.quotes = append(m.quotes, quote)
+	return nil
+}
+
+// モック投稿先の実装
+type mockPostRepository struct {
+	err      error
+	messages []string
+}
+
+func (m *mockPostRepository) Publish(ctx context.Context, message string) (*domain.PostRef, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.messages = append(m.messages, message)
+	return &domain.PostRef{Platform: domain.PlatformBluesky, URI: "at://did:plc:test/app.bsky.feed.post/1"}, nil
+}
+
 func TestQuoteUseCase_Initialize(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -68,8 +97,8 @@ func TestQuoteUseCase_Initialize(t *testing.T) {
 
 			// 正常系の場合は名言が読み込まれているか確認
 			if !tt.wantErr {
-				if len(uc.quotes) != len(tt.mockRepo.quotes) {
-					t.Errorf("QuoteUseCase.Initialize() loaded %d quotes, want %d", len(uc.quotes), len(tt.mockRepo.quotes))
+				if uc.Count() != len(tt.mockRepo.quotes) {
+					t.Errorf("QuoteUseCase.Initialize() loaded %d quotes, want %d", uc.Count(), len(tt.mockRepo.quotes))
 				}
 			}
 		})
@@ -77,15 +106,6 @@ func TestQuoteUseCase_Initialize(t *testing.T) {
 }
 
 func TestQuoteUseCase_PostRandomQuote(t *testing.T) {
-	// 乱数の再現性のためにシード値固定
-	seed := time.Now().UnixNano()
-	rand.Seed(seed)
-
-	// テスト終了後に乱数生成器をリセット
-	defer func() {
-		rand.Seed(time.Now().UnixNano())
-	}()
-
 	tests := []struct {
 		name        string
 		quotes      []domain.Quote
@@ -120,8 +140,8 @@ func TestQuoteUseCase_PostRandomQuote(t *testing.T) {
 				err:    nil,
 			}
 
-			// ユースケースの初期化
-			uc := NewQuoteUseCase(mockRepo)
+			// ユースケースの初期化（乱数の再現性のためにシード値固定）
+			uc := NewQuoteUseCase(mockRepo, WithRand(NewRand(1)))
 
 			// テスト用に初期化
 			if !tt.emptyQuotes {
@@ -145,6 +165,9 @@ func TestQuoteUseCase_PostRandomQuote(t *testing.T) {
 				t.Errorf("QuoteUseCase.PostRandomQuote() error message = %v, want %v", err.Error(), tt.wantErrMsg)
 				return
 			}
+			if tt.wantErr && !errors.Is(err, domain.ErrPoolEmpty) {
+				t.Errorf("QuoteUseCase.PostRandomQuote() error = %v, want ErrPoolEmpty", err)
+			}
 
 			// 正常系の場合は返却された名言が元の名言リストに含まれているか確認
 			if !tt.wantErr {
@@ -162,3 +185,428 @@ func TestQuoteUseCase_PostRandomQuote(t *testing.T) {
 		})
 	}
 }
+
+func TestQuoteUseCase_Reload(t *testing.T) {
+	tests := []struct {
+		name      string
+		reloaded  *mockQuoteRepository
+		wantErr   bool
+		wantCount int
+	}{
+		{
+			name: "正常系: 新しい名言リストに置き換わる",
+			reloaded: &mockQuoteRepository{
+				quotes: []domain.Quote{
+					{Text: "新しい名言1", Author: "著者1"},
+					{Text: "新しい名言2", Author: "著者2"},
+					{Text: "新しい名言3", Author: "著者3"},
+				},
+			},
+			wantErr:   false,
+			wantCount: 3,
+		},
+		{
+			name: "異常系: 読み込みに失敗した場合は現在のリストを維持",
+			reloaded: &mockQuoteRepository{
+				err: errors.New("ファイル読み込みエラー"),
+			},
+			wantErr:   true,
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockQuoteRepository{
+				quotes: []domain.Quote{{Text: "テスト名言1", Author: "著者1"}},
+			}
+			uc := NewQuoteUseCase(mockRepo)
+			if err := uc.Initialize(); err != nil {
+				t.Fatalf("QuoteUseCase.Initialize() failed: %v", err)
+			}
+
+			// 名言ファイルが更新された想定
+			*mockRepo = *tt.reloaded
+
+			err := uc.Reload()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("QuoteUseCase.Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := uc.Count(); got != tt.wantCount {
+				t.Errorf("QuoteUseCase.Count() = %d, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+// hashedQuoteRepository は内容のハッシュを返し、読み込んだ回数を数えます
+type hashedQuoteRepository struct {
+	mockQuoteRepository
+	hash  string
+	loads int
+}
+
+func (m *hashedQuoteRepository) QuotesHash() (string, error) {
+	return m.hash, m.err
+}
+
+func (m *hashedQuoteRepository) LoadQuotes() ([]domain.Quote, error) {
+	m.loads++
+	return m.mockQuoteRepository.LoadQuotes()
+}
+
+func TestQuoteUseCase_ReloadUnchanged(t *testing.T) {
+	tests := []struct {
+		name      string
+		hash      string
+		wantLoads int
+		// wantSame は再読み込みの前後で shuffle の順番が続いているか
+		wantSame bool
+	}{
+		{
+			name:      "正常系: 内容が変わっていなければ読み込まない",
+			hash:      "a",
+			wantLoads: 1,
+			wantSame:  true,
+		},
+		{
+			name:      "正常系: 内容が変われば読み込み直して順番をやり直す",
+			hash:      "b",
+			wantLoads: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes := make([]domain.Quote, 20)
+			for i := range quotes {
+				quotes[i] = domain.Quote{ID: fmt.Sprintf("q%d", i), Text: fmt.Sprintf("テスト名言%d", i)}
+			}
+			repo := &hashedQuoteRepository{mockQuoteRepository: mockQuoteRepository{quotes: quotes}, hash: "a"}
+			uc := NewQuoteUseCase(repo, WithRand(NewRand(42)), WithSelector(&ShuffleSelector{}))
+			if err := uc.Initialize(); err != nil {
+				t.Fatalf("QuoteUseCase.Initialize() failed: %v", err)
+			}
+			// 1周の半分まで投稿してから読み込み直す
+			seen := make(map[string]bool)
+			for i := 0; i < 10; i++ {
+				quote, err := uc.PostRandomQuote(context.Background())
+				if err != nil {
+					t.Fatalf("QuoteUseCase.PostRandomQuote() error = %v", err)
+				}
+				seen[quote.ID] = true
+			}
+
+			repo.hash = tt.hash
+			if err := uc.Reload(); err != nil {
+				t.Fatalf("QuoteUseCase.Reload() error = %v", err)
+			}
+			if repo.loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", repo.loads, tt.wantLoads)
+			}
+
+			// 順番が続いていれば、残りの10件はまだ選ばれていない名言になる
+			repeated := false
+			for i := 0; i < 10; i++ {
+				quote, err := uc.PostRandomQuote(context.Background())
+				if err != nil {
+					t.Fatalf("QuoteUseCase.PostRandomQuote() error = %v", err)
+				}
+				repeated = repeated || seen[quote.ID]
+			}
+			if repeated == tt.wantSame {
+				t.Errorf("repeated a quote = %v, want %v", repeated, !tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestQuoteUseCase_SelectQuote(t *testing.T) {
+	mockRepo := &mockQuoteRepository{
+		quotes: []domain.Quote{
+			{ID: "q1", Text: "テスト名言1", Author: "著者1", Tags: []string{"Birthday"}},
+			{ID: "q2", Text: "テスト名言2", Author: "著者2"},
+			{Text: "テスト名言3", Author: "著者3"},
+		},
+	}
+	uc := NewQuoteUseCase(mockRepo)
+	if err := uc.Initialize(); err != nil {
+		t.Fatalf("QuoteUseCase.Initialize() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		selectFn func() (*domain.Quote, error)
+		wantID   string
+		wantErr  bool
+	}{
+		{
+			name:     "正常系: 番号で選択（1始まり）",
+			selectFn: func() (*domain.Quote, error) { return uc.QuoteByIndex(2) },
+			wantID:   "q2",
+		},
+		{
+			name:     "異常系: 範囲外の番号",
+			selectFn: func() (*domain.Quote, error) { return uc.QuoteByIndex(0) },
+			wantErr:  true,
+		},
+		{
+			name:     "正常系: IDで選択",
+			selectFn: func() (*domain.Quote, error) { return uc.QuoteByID("q1") },
+			wantID:   "q1",
+		},
+		{
+			name:     "正常系: IDを省略した名言は本文から作ったIDで選択",
+			selectFn: func() (*domain.Quote, error) { return uc.QuoteByID(domain.ContentID("テスト名言3", "著者3")) },
+			wantID:   domain.ContentID("テスト名言3", "著者3"),
+		},
+		{
+			name:     "異常系: 存在しないID",
+			selectFn: func() (*domain.Quote, error) { return uc.QuoteByID("q3") },
+			wantErr:  true,
+		},
+		{
+			name:     "正常系: タグで選択（大文字と小文字を区別しない）",
+			selectFn: func() (*domain.Quote, error) { return uc.RandomQuoteWithTag("birthday") },
+			wantID:   "q1",
+		},
+		{
+			name:     "異常系: タグが付いた名言がない",
+			selectFn: func() (*domain.Quote, error) { return uc.RandomQuoteWithTag("newyear") },
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := tt.selectFn()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("select error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && quote.ID != tt.wantID {
+				t.Errorf("select = %+v, want ID %s", quote, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestQuoteUseCase_PostQuote(t *testing.T) {
+	longText := strings.Repeat("あ", 301)
+	tests := []struct {
+		name      string
+		quotes    []domain.Quote
+		input     PostQuoteInput
+		postErr   error
+		wantText  string
+		wantPosts int
+		wantErr   bool
+	}{
+		{
+			name:      "正常系: デフォルトの形式で整形して投稿する",
+			quotes:    []domain.Quote{{Text: "テスト名言", Author: "著者"}},
+			wantText:  "テスト名言\n- 著者",
+			wantPosts: 1,
+		},
+		{
+			name:   "正常系: DryRun では整形だけして投稿しない",
+			quotes: []domain.Quote{{Text: "テスト名言", Author: "著者"}},
+			input:  PostQuoteInput{DryRun: true},
+			// 投稿しないため Ref は nil
+			wantText:  "テスト名言\n- 著者",
+			wantPosts: 0,
+		},
+		{
+			name:    "異常系: 名言がない",
+			wantErr: true,
+		},
+		{
+			name:   "異常系: 上限を超える本文は投稿しない",
+			quotes: []domain.Quote{{Text: longText}},
+			// 整形した本文は、上限を超えていても返す
+			wantText: longText,
+			wantErr:  true,
+		},
+		{
+			name:     "異常系: 投稿に失敗",
+			quotes:   []domain.Quote{{Text: "テスト名言", Author: "著者"}},
+			postErr:  errors.New("Bluesky APIエラー"),
+			wantText: "テスト名言\n- 著者",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := NewQuoteUseCase(&mockQuoteRepository{quotes: tt.quotes})
+			if err := uc.Reload(); err != nil {
+				t.Fatalf("Reload() error = %v", err)
+			}
+			repo := &mockPostRepository{err: tt.postErr}
+
+			output, err := uc.PostQuote(context.Background(), repo, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("QuoteUseCase.PostQuote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if output.Text != tt.wantText {
+				t.Errorf("QuoteUseCase.PostQuote().Text = %q, want %q", output.Text, tt.wantText)
+			}
+			if len(repo.messages) != tt.wantPosts {
+				t.Errorf("posts = %d, want %d", len(repo.messages), tt.wantPosts)
+			}
+			if (output.Ref != nil) != (tt.wantPosts > 0) {
+				t.Errorf("QuoteUseCase.PostQuote().Ref = %+v", output.Ref)
+			}
+		})
+	}
+}
+
+func TestQuoteUseCase_RandSeed(t *testing.T) {
+	quotes := make([]domain.Quote, 20)
+	for i := range quotes {
+		quotes[i] = domain.Quote{ID: fmt.Sprintf("q%d", i), Text: fmt.Sprintf("テスト名言%d", i)}
+	}
+	sequence := func(seed int64) []string {
+		uc := NewQuoteUseCase(&mockQuoteRepository{quotes: quotes}, WithRand(NewRand(seed)))
+		if err := uc.Initialize(); err != nil {
+			t.Fatalf("QuoteUseCase.Initialize() failed: %v", err)
+		}
+		var ids []string
+		for i := 0; i < 10; i++ {
+			quote, err := uc.PostRandomQuote(context.Background())
+			if err != nil {
+				t.Fatalf("QuoteUseCase.PostRandomQuote() error = %v", err)
+			}
+			ids = append(ids, quote.ID)
+		}
+		return ids
+	}
+
+	// 同じシードでは同じ順番で選ばれる
+	first, second := sequence(42), sequence(42)
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Errorf("seed 42: %v != %v", first, second)
+	}
+	if other := sequence(43); strings.Join(first, ",") == strings.Join(other, ",") {
+		t.Errorf("seed 43 returned the same sequence as seed 42: %v", other)
+	}
+}
+
+func TestQuoteUseCase_ConcurrentReload(t *testing.T) {
+	// 再読み込みのたびに件数の異なる名言リストを返すリポジトリ
+	var loads atomic.Int32
+	repo := quoteRepositoryFunc(func() ([]domain.Quote, error) {
+		n := int(loads.Add(1))%5 + 1
+		quotes := make([]domain.Quote, n)
+		for i := range quotes {
+			quotes[i] = domain.Quote{ID: fmt.Sprintf("q%d", i), Text: fmt.Sprintf("テスト名言%d", i), Author: "著者", Tags: []string{"tag"}}
+		}
+		return quotes, nil
+	})
+	uc := NewQuoteUseCase(repo, WithSelector(&ShuffleSelector{}))
+	if err := uc.Initialize(); err != nil {
+		t.Fatalf("QuoteUseCase.Initialize() failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if err := uc.Reload(); err != nil {
+					t.Errorf("QuoteUseCase.Reload() error = %v", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				quote, err := uc.PostRandomQuote(context.Background())
+				if err != nil {
+					t.Errorf("QuoteUseCase.PostRandomQuote() error = %v", err)
+					return
+				}
+				// 返された名言を変更してもスナップショットは変わらない
+				quote.Tags[0] = "changed"
+				if _, err := uc.RandomQuoteWithTag("tag"); err != nil {
+					t.Errorf("QuoteUseCase.RandomQuoteWithTag() error = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, quote := range uc.Quotes() {
+		if !quote.HasTag("tag") {
+			t.Errorf("snapshot was modified: %+v", quote)
+		}
+	}
+}
+
+// quoteRepositoryFunc は関数を QuoteRepository として使います
+type quoteRepositoryFunc func() ([]domain.Quote, error)
+
+func (f quoteRepositoryFunc) LoadQuotes() ([]domain.Quote, error) {
+	return f()
+}
+
+func TestQuoteUseCase_AddQuote(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    QuoteRepository
+		quote   domain.Quote
+		wantID  string
+		wantErr bool
+	}{
+		{
+			name:   "正常系: 追加した名言が読み込まれ、本文から作ったIDが付く",
+			repo:   &writableQuoteRepository{mockQuoteRepository{quotes: []domain.Quote{{ID: "q1", Text: "テスト名言1", Author: "著者1"}}}},
+			quote:  domain.Quote{Text: "テスト名言2", Author: "著者2"},
+			wantID: domain.ContentID("テスト名言2", "著者2"),
+		},
+		{
+			name:    "異常系: 既存の名言とIDが重複",
+			repo:    &writableQuoteRepository{mockQuoteRepository{quotes: []domain.Quote{{ID: "q1", Text: "テスト名言1", Author: "著者1"}}}},
+			quote:   domain.Quote{ID: "q1", Text: "テスト名言2", Author: "著者2"},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 著者が空",
+			repo:    &writableQuoteRepository{mockQuoteRepository{quotes: []domain.Quote{{ID: "q1", Text: "テスト名言1", Author: "著者1"}}}},
+			quote:   domain.Quote{Text: "テスト名言2"},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: 追加できない取得元",
+			repo:    &mockQuoteRepository{quotes: []domain.Quote{{ID: "q1", Text: "テスト名言1", Author: "著者1"}}},
+			quote:   domain.Quote{Text: "テスト名言2", Author: "著者2"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := NewQuoteUseCase(tt.repo)
+			if err := uc.Initialize(); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			got, err := uc.AddQuote(tt.quote)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddQuote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if uc.Count() != 1 {
+					t.Errorf("Count() = %d, want 1", uc.Count())
+				}
+				return
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("AddQuote().ID = %q, want %q", got.ID, tt.wantID)
+			}
+			if _, err := uc.QuoteByID(tt.wantID); err != nil {
+				t.Errorf("QuoteByID() error = %v", err)
+			}
+		})
+	}
+}
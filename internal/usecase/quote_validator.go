@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// QuoteProblem は名言ファイルの1件の問題です。Index は0始まりの位置です
+type QuoteProblem struct {
+	Index int
+	Err   error
+}
+
+func (p QuoteProblem) Error() string {
+	if p.Index < 0 {
+		return p.Err.Error()
+	}
+	return fmt.Sprintf("%d件目: %v", p.Index+1, p.Err)
+}
+
+// ValidateQuotes は名言を1件ずつ検証し、空の項目、Blueskyの上限を超える長さ、本文やIDの重複を報告します
+func ValidateQuotes(quotes []domain.Quote) []QuoteProblem {
+	if len(quotes) == 0 {
+		return []QuoteProblem{{Index: -1, Err: fmt.Errorf("名言が1件もありません")}}
+	}
+
+	var problems []QuoteProblem
+	seen := make(map[string]int, len(quotes))
+	seenIDs := make(map[string]int)
+	for i := range quotes {
+		if err := quotes[i].Validate(); err != nil {
+			problems = append(problems, QuoteProblem{Index: i, Err: err})
+		}
+
+		key := strings.TrimSpace(quotes[i].Text)
+		if key == "" {
+			continue
+		}
+		if first, ok := seen[key]; ok {
+			problems = append(problems, QuoteProblem{Index: i, Err: fmt.Errorf("%d件目と本文が重複しています", first+1)})
+			continue
+		}
+		seen[key] = i
+
+		// IDを省略した名言は本文から作ったIDで、指定したIDと重ならないかも確かめる
+		id := quotes[i].StableID()
+		if first, ok := seenIDs[id]; ok {
+			problems = append(problems, QuoteProblem{Index: i, Err: fmt.Errorf("%d件目とIDが重複しています: %s", first+1, id)})
+			continue
+		}
+		seenIDs[id] = i
+	}
+	return problems
+}
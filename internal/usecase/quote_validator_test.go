@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+func TestValidateQuotes(t *testing.T) {
+	tests := []struct {
+		name   string
+		quotes []domain.Quote
+		want   []string
+	}{
+		{
+			name: "正常系: 問題なし",
+			quotes: []domain.Quote{
+				{Text: "名言1", Author: "著者1"},
+				{Text: "名言2", Author: "著者1"},
+			},
+			want: nil,
+		},
+		{
+			name:   "異常系: 名言が1件もない",
+			quotes: nil,
+			want:   []string{"名言が1件もありません"},
+		},
+		{
+			name: "異常系: 空の項目",
+			quotes: []domain.Quote{
+				{Text: " ", Author: "著者1"},
+				{Text: "名言2", Author: ""},
+			},
+			want: []string{"1件目: 名言の本文が空です", "2件目: 著者が空です"},
+		},
+		{
+			name: "異常系: 本文の重複",
+			quotes: []domain.Quote{
+				{Text: "名言1", Author: "著者1"},
+				{Text: "名言2", Author: "著者2"},
+				{Text: "名言1 ", Author: "著者3"},
+			},
+			want: []string{"3件目: 1件目と本文が重複しています"},
+		},
+		{
+			name: "異常系: IDの重複",
+			quotes: []domain.Quote{
+				{ID: "q1", Text: "名言1", Author: "著者1"},
+				{ID: "q1", Text: "名言2", Author: "著者2"},
+			},
+			want: []string{"2件目: 1件目とIDが重複しています: q1"},
+		},
+		{
+			name: "異常系: 指定したIDが本文から作ったIDと重複",
+			quotes: []domain.Quote{
+				{Text: "名言1", Author: "著者1"},
+				{ID: domain.ContentID("名言1", "著者1"), Text: "名言2", Author: "著者2"},
+			},
+			want: []string{"2件目: 1件目とIDが重複しています: " + domain.ContentID("名言1", "著者1")},
+		},
+		{
+			name: "異常系: 負の重み",
+			quotes: []domain.Quote{
+				{Text: "名言1", Author: "著者1", Weight: 2.5},
+				{Text: "名言2", Author: "著者2", Weight: -1},
+			},
+			want: []string{"2件目: 重みは0以上で指定してください: -1"},
+		},
+		{
+			name: "異常系: 投稿の上限を超える長さ",
+			quotes: []domain.Quote{
+				{Text: strings.Repeat("あ", domain.MaxPostLength), Author: "著者1"},
+			},
+			want: []string{"1件目: 投稿の本文が長すぎます（306文字、上限は300文字）"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := ValidateQuotes(tt.quotes)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("ValidateQuotes() = %v, want %v", problems, tt.want)
+			}
+			for i, p := range problems {
+				if p.Error() != tt.want[i] {
+					t.Errorf("ValidateQuotes()[%d] = %q, want %q", i, p.Error(), tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// 名言の選び方（QUOTE_SELECTOR）
+const (
+	SelectorRandom   = "random"   // 毎回すべての名言から均等に選ぶ
+	SelectorShuffle  = "shuffle"  // すべての名言を1回ずつ投稿するまで同じ名言を選ばない
+	SelectorWeighted = "weighted" // 名言の weight に比例して選ぶ
+)
+
+// QuoteSelector は名言リストから投稿する名言を選ぶ方法です。
+// QuoteUseCase は Select と Reset を同時に呼び出さないため、実装は状態を持てます
+type QuoteSelector interface {
+	// Select は空でない quotes から選んだ名言の位置を返します
+	Select(quotes []domain.Quote, rng *rand.Rand) int
+	// Reset は名言リストが読み込み直されたときに呼ばれ、選んだ順番などの状態を捨てます
+	Reset()
+}
+
+// NewSelector は QUOTE_SELECTOR の名前から QuoteSelector を作成します。空の場合は random です
+func NewSelector(name string) (QuoteSelector, error) {
+	switch name {
+	case "", SelectorRandom:
+		return RandomSelector{}, nil
+	case SelectorShuffle:
+		return &ShuffleSelector{}, nil
+	case SelectorWeighted:
+		return WeightedSelector{}, nil
+	default:
+		return nil, fmt.Errorf("不明な名言の選び方です: %s（random, shuffle, weighted のいずれか）", name)
+	}
+}
+
+// RandomSelector は毎回すべての名言から均等に選びます。続けて同じ名言が選ばれることがあります
+type RandomSelector struct{}
+
+// Select は名言を1件選びます
+func (RandomSelector) Select(quotes []domain.Quote, rng *rand.Rand) int {
+	return rng.IntN(len(quotes))
+}
+
+// Reset は何もしません
+func (RandomSelector) Reset() {}
+
+// ShuffleSelector は名言リストをシャッフルした順番に選び、すべて選び終えるとシャッフルし直します。
+// シャッフルし直したときも、直前に選んだ名言を続けては選びません
+type ShuffleSelector struct {
+	order []int
+	next  int
+	last  int
+}
+
+// Select は名言を1件選びます
+func (s *ShuffleSelector) Select(quotes []domain.Quote, rng *rand.Rand) int {
+	if len(s.order) != len(quotes) || s.next >= len(s.order) {
+		first := len(s.order) == 0
+		s.order = rng.Perm(len(quotes))
+		s.next = 0
+		// 新しい周回の最初が前の周回の最後と同じにならないようにする
+		if !first && len(s.order) > 1 && s.order[0] == s.last {
+			j := 1 + rng.IntN(len(s.order)-1)
+			s.order[0], s.order[j] = s.order[j], s.order[0]
+		}
+	}
+	s.last = s.order[s.next]
+	s.next++
+	return s.last
+}
+
+// Reset は選んだ順番を捨て、次の Select でシャッフルし直します
+func (s *ShuffleSelector) Reset() {
+	s.order = nil
+	s.next = 0
+}
+
+// WeightedSelector は名言の weight に比例した確率で選びます
+type WeightedSelector struct{}
+
+// Select は名言を1件選びます
+func (WeightedSelector) Select(quotes []domain.Quote, rng *rand.Rand) int {
+	total := 0.0
+	for i := range quotes {
+		total += quotes[i].SelectionWeight()
+	}
+	n := rng.Float64() * total
+	for i := range quotes {
+		n -= quotes[i].SelectionWeight()
+		if n < 0 {
+			return i
+		}
+	}
+	return len(quotes) - 1
+}
+
+// Reset は何もしません
+func (WeightedSelector) Reset() {}
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/littleironwaltz/quotebot/internal/domain"
+)
+
+// testQuotes は n 件のテスト用の名言を返します
+func testQuotes(n int) []domain.Quote {
+	quotes := make([]domain.Quote, n)
+	for i := range quotes {
+		quotes[i] = domain.Quote{ID: fmt.Sprintf("q%d", i), Text: fmt.Sprintf("テスト名言%d", i), Author: "著者"}
+	}
+	return quotes
+}
+
+func TestNewSelector(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    QuoteSelector
+		wantErr bool
+	}{
+		{name: "正常系: 空の場合は random", input: "", want: RandomSelector{}},
+		{name: "正常系: random", input: SelectorRandom, want: RandomSelector{}},
+		{name: "正常系: shuffle", input: SelectorShuffle, want: &ShuffleSelector{}},
+		{name: "正常系: weighted", input: SelectorWeighted, want: WeightedSelector{}},
+		{name: "異常系: 不明な選び方", input: "round-robin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSelector(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && fmt.Sprintf("%T", got) != fmt.Sprintf("%T", tt.want) {
+				t.Errorf("NewSelector() = %T, want %T", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShuffleSelector(t *testing.T) {
+	quotes := testQuotes(5)
+	rng := NewRand(1)
+	s := &ShuffleSelector{}
+
+	last := -1
+	for cycle := 0; cycle < 20; cycle++ {
+		// 1周のあいだに同じ名言は選ばれない
+		seen := map[int]bool{}
+		for i := 0; i < len(quotes); i++ {
+			n := s.Select(quotes, rng)
+			if seen[n] {
+				t.Fatalf("cycle %d: %d was selected twice", cycle, n)
+			}
+			// 周回の境目でも続けて同じ名言は選ばれない
+			if n == last {
+				t.Fatalf("cycle %d: %d was selected twice in a row", cycle, n)
+			}
+			seen[n] = true
+			last = n
+		}
+	}
+
+	// 名言リストが変わると新しい周回を始める
+	s.Reset()
+	seen := map[int]bool{}
+	for i := 0; i < 3; i++ {
+		seen[s.Select(quotes[:3], rng)] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("after Reset() selected %v, want all 3 quotes", seen)
+	}
+}
+
+func TestWeightedSelector(t *testing.T) {
+	quotes := testQuotes(3)
+	quotes[1].Weight = 8 // 省略した名言の重みは1
+	rng := NewRand(1)
+
+	counts := make([]int, len(quotes))
+	for i := 0; i < 10000; i++ {
+		counts[WeightedSelector{}.Select(quotes, rng)]++
+	}
+	// 期待値は 1000, 8000, 1000
+	if counts[1] < 7500 || counts[1] > 8500 || counts[0] < 700 || counts[2] < 700 {
+		t.Errorf("counts = %v, want about [1000 8000 1000]", counts)
+	}
+}
+
+func TestQuoteUseCase_Selector(t *testing.T) {
+	repo := &mockQuoteRepository{quotes: testQuotes(4)}
+	uc := NewQuoteUseCase(repo, WithRand(NewRand(1)), WithSelector(&ShuffleSelector{}))
+	if err := uc.Initialize(); err != nil {
+		t.Fatalf("QuoteUseCase.Initialize() failed: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		quote, err := uc.PostRandomQuote(context.Background())
+		if err != nil {
+			t.Fatalf("QuoteUseCase.PostRandomQuote() error = %v", err)
+		}
+		seen[quote.ID] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("selected %v, want each quote once", seen)
+	}
+
+	// 読み込み直した名言リストから選ぶ
+	repo.quotes = testQuotes(2)
+	if err := uc.Reload(); err != nil {
+		t.Fatalf("QuoteUseCase.Reload() error = %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		quote, _ := uc.PostRandomQuote(context.Background())
+		if quote.ID != "q0" && quote.ID != "q1" {
+			t.Errorf("PostRandomQuote() = %s after reload", quote.ID)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ReleasesURL は最新リリースを取得するGitHub APIのURLです
+const ReleasesURL = "https://api.github.com/repos/littleironwaltz/quotebot/releases/latest"
+
+// maxReleaseBytes はリリース情報のレスポンスを読み込む上限です
+const maxReleaseBytes = 1 << 20
+
+// Release はGitHubで公開されているリリースです
+type Release struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// CheckUpdate は url から最新リリースを取得し、実行中のバージョンより新しければ返します。
+// 新しいリリースがない場合や、開発版などバージョンを比較できない場合は nil を返します
+func CheckUpdate(ctx context.Context, client *http.Client, url string) (*Release, error) {
+	if _, ok := parseVersion(Version); !ok {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("リクエストの作成に失敗しました: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", UserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("最新リリースの取得に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("最新リリースの取得に失敗しました: status %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReleaseBytes)).Decode(&release); err != nil {
+		return nil, fmt.Errorf("リリース情報の解析に失敗しました: %w", err)
+	}
+	if !IsNewer(release.TagName, Version) {
+		return nil, nil
+	}
+	return &release, nil
+}
+
+// IsNewer は latest が current より新しいバージョンかを返します。
+// バージョンは v1.2.3 形式で比較し、解析できない場合は false を返します
+func IsNewer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion は v1.2.3 形式のバージョンを数値に分解します。
+// プレリリースやビルドメタデータ（-rc.1, +build）は無視します
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
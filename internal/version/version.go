@@ -0,0 +1,66 @@
+// Package version はビルド時に埋め込まれるバージョン情報を提供します
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// ビルド情報はビルド時に -ldflags で設定します。例:
+//
+//	go build -ldflags "-X github.com/littleironwaltz/quotebot/internal/version.Version=v1.2.3 \
+//	  -X github.com/littleironwaltz/quotebot/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/littleironwaltz/quotebot/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	// Version はアプリケーションのバージョンです
+	Version = "dev"
+	// Commit はビルドしたコミットです。未設定の場合はGoが埋め込んだVCS情報を使います
+	Commit = ""
+	// Date はビルド日時です。未設定の場合はコミット日時を使います
+	Date = ""
+)
+
+func init() {
+	if Commit != "" && Date != "" {
+		return
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == "" {
+				Commit = setting.Value
+				if len(Commit) > 12 {
+					Commit = Commit[:12]
+				}
+			}
+		case "vcs.time":
+			if Date == "" {
+				Date = setting.Value
+			}
+		}
+	}
+}
+
+// UserAgent はHTTPリクエストに付与するデフォルトのUser-Agentを返します。
+// PDSの運用者がボットのトラフィックを識別できるよう、名前・バージョン・連絡先URLを含めます
+func UserAgent() string {
+	return "QuoteBot/" + Version + " (+https://github.com/littleironwaltz/quotebot)"
+}
+
+// String は `quotebot version` で表示するバージョンとビルド情報を返します
+func String() string {
+	commit, date := Commit, Date
+	if commit == "" {
+		commit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("quotebot %s (commit %s, built %s, %s %s/%s)",
+		Version, commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,114 @@
+package version
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		name    string
+		latest  string
+		current string
+		want    bool
+	}{
+		{name: "正常系: パッチバージョンが新しい", latest: "v1.2.4", current: "v1.2.3", want: true},
+		{name: "正常系: マイナーバージョンが新しい", latest: "v1.10.0", current: "v1.9.9", want: true},
+		{name: "正常系: 同じバージョン", latest: "v1.2.3", current: "v1.2.3", want: false},
+		{name: "正常系: 古いバージョン", latest: "v1.2.3", current: "v2.0.0", want: false},
+		{name: "正常系: vなしとプレリリース", latest: "1.3.0", current: "v1.3.0-rc.1", want: false},
+		{name: "異常系: 開発版", latest: "v1.2.3", current: "dev", want: false},
+		{name: "異常系: 解析できないタグ", latest: "nightly", current: "v1.2.3", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNewer(tt.latest, tt.current); got != tt.want {
+				t.Errorf("IsNewer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		status  int
+		body    string
+		wantTag string
+		wantErr bool
+	}{
+		{
+			name:    "正常系: 新しいリリースがある",
+			version: "v1.0.0",
+			status:  http.StatusOK,
+			body:    `{"tag_name":"v1.1.0","html_url":"https://github.com/littleironwaltz/quotebot/releases/tag/v1.1.0"}`,
+			wantTag: "v1.1.0",
+		},
+		{
+			name:    "正常系: 最新版を実行中",
+			version: "v1.1.0",
+			status:  http.StatusOK,
+			body:    `{"tag_name":"v1.1.0"}`,
+		},
+		{
+			name:    "正常系: 開発版は確認しない",
+			version: "dev",
+			status:  http.StatusInternalServerError,
+		},
+		{
+			name:    "異常系: APIのエラー",
+			version: "v1.0.0",
+			status:  http.StatusForbidden,
+			body:    `{"message":"API rate limit exceeded"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasPrefix(r.Header.Get("User-Agent"), "QuoteBot/") {
+					t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			original := Version
+			Version = tt.version
+			defer func() { Version = original }()
+
+			got, err := CheckUpdate(context.Background(), server.Client(), server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			gotTag := ""
+			if got != nil {
+				gotTag = got.TagName
+			}
+			if gotTag != tt.wantTag {
+				t.Errorf("CheckUpdate() tag = %q, want %q", gotTag, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	original := [3]string{Version, Commit, Date}
+	Version, Commit, Date = "v1.2.3", "abc1234", "2024-01-02T03:04:05Z"
+	defer func() { Version, Commit, Date = original[0], original[1], original[2] }()
+
+	got := String()
+	for _, want := range []string{"v1.2.3", "abc1234", "2024-01-02T03:04:05Z"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("String() = %q, want it to contain %q", got, want)
+		}
+	}
+}
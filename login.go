@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/terminal"
+)
+
+// runLogin はハンドルとアプリパスワードを入力してセッションを作成し、
+// 暗号化したトークンをボットが使うトークンストア（TOKEN_FILE またはキーリング/Vault）に保存します
+func runLogin(cfg *config.Config, in *os.File, out io.Writer) error {
+	if cfg.AuthMode == config.AuthModeOAuth {
+		return fmt.Errorf("AUTH_MODE=oauth の場合は `quotebot oauth-login` を使用してください")
+	}
+
+	reader := bufio.NewReader(in)
+
+	handle := cfg.Handle
+	if handle != "" {
+		fmt.Fprintf(out, "ハンドル [%s]: ", handle)
+	} else {
+		fmt.Fprint(out, "ハンドル: ")
+	}
+	input, err := readLine(reader)
+	if err != nil {
+		return err
+	}
+	if input != "" {
+		handle = input
+	}
+	if handle == "" {
+		return fmt.Errorf("ハンドルを入力してください")
+	}
+
+	// 環境変数やファイルで指定されていなければ、入力を表示せずに読み込む
+	password := cfg.AppPassword
+	if password == "" {
+		fmt.Fprint(out, "アプリパスワード: ")
+		restore, err := terminal.DisableEcho(in)
+		if err != nil && !errors.Is(err, terminal.ErrNotTerminal) {
+			return fmt.Errorf("入力の非表示の設定に失敗しました: %w", err)
+		}
+		password, err = readLine(reader)
+		if restore != nil {
+			restore()
+			fmt.Fprintln(out)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if password == "" {
+		return fmt.Errorf("アプリパスワードを入力してください")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+	defer cancel()
+
+	session, err := repository.Login(ctx, cfg, handle, password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "ログインしました: %s (%s)\n", session.Handle, session.DID)
+	if cfg.TokenFile != "" {
+		fmt.Fprintf(out, "トークンを %s に保存しました\n", cfg.TokenFile)
+	} else {
+		fmt.Fprintf(out, "トークンを %s に保存しました\n", cfg.CredentialsBackend)
+	}
+	return nil
+}
+
+// readLine は1行読み込み、前後の空白を取り除いて返します
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("入力の読み込みに失敗しました: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -2,102 +2,560 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/approval"
+	"github.com/littleironwaltz/quotebot/internal/deadletter"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/heartbeat"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/interface/admin"
+	"github.com/littleironwaltz/quotebot/internal/interface/grpcapi"
+	"github.com/littleironwaltz/quotebot/internal/interface/publicstats"
 	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/leader"
+	"github.com/littleironwaltz/quotebot/internal/logging"
+	"github.com/littleironwaltz/quotebot/internal/metrics"
+	"github.com/littleironwaltz/quotebot/internal/notify"
+	"github.com/littleironwaltz/quotebot/internal/outbox"
+	"github.com/littleironwaltz/quotebot/internal/profile"
+	"github.com/littleironwaltz/quotebot/internal/quoterequest"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/state"
+	"github.com/littleironwaltz/quotebot/internal/suggestion"
 	"github.com/littleironwaltz/quotebot/internal/usecase"
+	"github.com/littleironwaltz/quotebot/internal/version"
 )
 
 func main() {
-	cfg, err := config.New()
+	// 共通のフラグはサブコマンドの前に指定します（例: quotebot --config config.yaml post-now --id q1）
+	flags := flag.NewFlagSet("quotebot", flag.ExitOnError)
+	configFile := flags.String("config", "", "設定ファイル（YAMLまたはTOML）のパス。環境変数の値が優先されます")
+	profileName := flags.String("profile", "", "設定ファイルの profiles から使用するプロファイル（dev, staging, prod など）")
+	botName := flags.String("bot", "", "設定ファイルの bots から使用するボット。指定しない場合は bots のすべてのボットを起動します")
+	flags.Parse(os.Args[1:])
+	args := flags.Args()
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	// `quotebot version` は設定を読み込まずにバージョンを表示します
+	if command == "version" {
+		fmt.Println(version.String())
+		return
+	}
+
+	var opts []config.Option
+	if *configFile != "" {
+		opts = append(opts, config.WithFile(*configFile))
+	}
+	if *profileName != "" {
+		opts = append(opts, config.WithProfile(*profileName))
+	}
+	if *botName != "" {
+		opts = append(opts, config.WithBot(*botName))
+	}
+	// 設定ファイルに bots がある場合は、すべてのボットを1つのプロセスで動かす
+	if command == "" && *botName == "" {
+		names, err := config.BotNames(opts...)
+		if err != nil {
+			log.Fatalf("設定の読み込みに失敗しました: %v", redact.Error(err))
+		}
+		if len(names) > 0 {
+			runBots(names, opts)
+			return
+		}
+	}
+	// `quotebot login` はトークンを取得するためのコマンドなので、トークンが未設定でも起動する
+	loadConfig := config.New
+	if command == "login" {
+		loadConfig = config.NewForLogin
+	}
+	cfg, err := loadConfig(opts...)
 	if err != nil {
-		log.Fatalf("設定の読み込みに失敗しました: %v", err)
+		log.Fatalf("設定の読み込みに失敗しました: %v", redact.Error(err))
+	}
+	if err := logging.Setup(cfg); err != nil {
+		log.Fatalf("ログ設定の読み込みに失敗しました: %v", err)
+	}
+	logger := logging.Module("main")
+	logger.Debug("ビルド情報", "version", version.Version, "commit", version.Commit, "date", version.Date)
+	// 誤って本番のアカウントに投稿しないよう、どのアカウントに投稿するかを起動時に表示する
+	if cfg.Profile() != "" || cfg.DryRun {
+		logger.Info("プロファイルを使用します", "profile", cfg.Profile(), "pds_url", cfg.PDSURL, "did", cfg.DID, "dry_run", cfg.DryRun)
 	}
 
-	quoteRepo := repository.NewQuoteRepository(cfg)
-	blueskyRepo := repository.NewBlueskyRepository(cfg)
-	quoteUseCase := usecase.NewQuoteUseCase(quoteRepo)
+	// サブコマンドは処理を終えると終了します
+	if command != "" {
+		switch command {
+		case "login":
+			// `quotebot login` はアプリパスワードでログインし、トークンを保存して終了します
+			if err := runLogin(cfg, os.Stdin, os.Stdout); err != nil {
+				fatal(logger, "ログインに失敗しました", err)
+			}
+			return
+		case "oauth-login":
+			// `quotebot oauth-login` はOAuthセッションを取得して終了します
+			if err := runOAuthLogin(cfg); err != nil {
+				fatal(logger, "OAuthログインに失敗しました", err)
+			}
+			return
+		case "status":
+			// `quotebot status` は実行中のボットの状態を表示します
+			if err := runStatus(cfg, os.Stdout); err != nil {
+				fatal(logger, "状態の取得に失敗しました", err)
+			}
+			return
+		case "post-now":
+			// `quotebot post-now` は指定した名言をすぐに1件投稿します
+			if err := runPostNow(cfg, args[1:], os.Stdout); err != nil {
+				fatal(logger, "即時投稿に失敗しました", err)
+			}
+			return
+		case "config":
+			// `quotebot config show` は実際に使われる設定値と取得元を表示します
+			if err := runConfig(cfg, args[1:], os.Stdout); err != nil {
+				fatal(logger, "設定の表示に失敗しました", err)
+			}
+			return
+		case "quotes":
+			// `quotebot quotes [--tag TAG]` は名言をIDとともに一覧で表示します。
+			// `quotebot quotes lint [--fix]` は名言の表記を確認します
+			if err := runQuotes(cfg, args[1:], os.Stdout); err != nil {
+				if len(args) > 1 && args[1] == "lint" {
+					fatal(logger, "名言の表記の確認に失敗しました", err)
+				}
+				fatal(logger, "名言の一覧の表示に失敗しました", err)
+			}
+			return
+		case "report":
+			// `quotebot report variants|quotes` は投稿形式ごと、名言ごとの反応を集計して表示します
+			if err := runReport(cfg, args[1:], os.Stdout); err != nil {
+				fatal(logger, "レポートの作成に失敗しました", err)
+			}
+			return
+		case "history":
+			// `quotebot history sync` はアカウントの過去の投稿から投稿履歴を復元します
+			if err := runHistory(cfg, args[1:], os.Stdout); err != nil {
+				fatal(logger, "投稿履歴の同期に失敗しました", err)
+			}
+			return
+		case "backfill":
+			// `quotebot backfill --count N --gap 10m` は新しいアカウントに名言を間隔を空けて投稿します
+			if err := runBackfill(cfg, args[1:], os.Stdout); err != nil {
+				fatal(logger, "バックフィルに失敗しました", err)
+			}
+			return
+		case "approve":
+			// `quotebot approve [--reject] [ID]` は承認待ちの投稿を表示・承認・却下します
+			if err := runApprove(cfg, args[1:], os.Stdout); err != nil {
+				fatal(logger, "承認に失敗しました", err)
+			}
+			return
+		case "dlq":
+			// `quotebot dlq [list | retry [--all] [ID...] | discard ID...]` は投稿先に送って失敗した投稿を表示・送り直し・破棄します
+			if err := runDLQ(cfg, args[1:], os.Stdout); err != nil {
+				fatal(logger, "失敗した投稿の処理に失敗しました", err)
+			}
+			return
+		case "suggestions":
+			// `quotebot suggestions [--reject] [ID]` は返信で提案された名言を表示・採用・却下します
+			if err := runSuggestions(cfg, args[1:], os.Stdout); err != nil {
+				fatal(logger, "名言の提案の確認に失敗しました", err)
+			}
+			return
+		case "state":
+			// `quotebot state rekey` は暗号化した状態を現在の鍵で暗号化し直します
+			err := runState(cfg, args[1:], os.Stdout)
+			if errors.Is(err, errStateUsage) {
+				fatal(logger, "state のサブコマンドの指定が正しくありません", err)
+			}
+			if err != nil {
+				fatal(logger, "状態の暗号化し直しに失敗しました", err)
+			}
+			return
+		case "validate":
+			// `quotebot validate` はデプロイ前に名言ファイルと認証情報を確認します
+			if err := runValidate(cfg, os.Stdout); err != nil {
+				fatal(logger, "検証に失敗しました", err)
+			}
+			return
+		}
+	}
 
-	if err := quoteUseCase.Initialize(); err != nil {
-		log.Fatalf("ユースケースの初期化に失敗しました: %v", err)
+	// 起動してから失敗しないよう、名言ファイルなどの存在もここで確認する
+	if err := cfg.Validate(); err != nil {
+		fatal(logger, "設定に問題があります", err)
 	}
 
-	// シグナル処理の設定
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// UPDATE_CHECK が有効な場合のみ、新しいリリースがないかバックグラウンドで確認する
+	if cfg.UpdateCheck {
+		go checkForUpdate(logger, cfg)
+	}
 
-	// タイマーの設定
-	ticker := time.NewTicker(cfg.PostInterval)
-	defer ticker.Stop()
+	application, err := newApplication(cfg, opts)
+	if err != nil {
+		fatal(logger, "アプリケーションの初期化に失敗しました", err)
+	}
 
-	// アプリケーション全体のコンテキストを作成
+	// シグナルを受信したらアプリケーション全体のコンテキストをキャンセルする
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	cancelOnSignal(logger, cfg.ShutdownTimeout, cancel)
+	stopReload := reloadOnHangup(ctx, func() {
+		if _, err := application.ReloadConfig(); err != nil {
+			logger.Error("設定の再読み込みに失敗しました", "error", redact.Error(err))
+		}
+	})
+	defer stopReload()
+	defer reopenLogOnSignal(ctx, logger)()
 
-	fmt.Printf("QuoteBotが起動しました（投稿間隔: %v）...\n", cfg.PostInterval)
+	if err := application.Run(ctx); err != nil {
+		fatal(logger, "アプリケーションの起動に失敗しました", err)
+	}
 
-	// 初回投稿
-	reqCtx, reqCancel := context.WithTimeout(ctx, cfg.HTTPTimeout)
+	// 実行中の投稿の完了を SHUTDOWN_TIMEOUT まで待つ
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer shutdownCancel()
+	if err := application.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("シャットダウン中にエラーが発生しました", "error", redact.Error(err))
+	}
+	logger.Info("シャットダウンが完了しました")
+}
 
-	// 投稿前に明示的にトークンをリフレッシュ
-	log.Println("初回投稿前にトークンをリフレッシュします...")
-	if err := blueskyRepo.RefreshToken(reqCtx); err != nil {
-		log.Printf("トークンリフレッシュに失敗しました: %v", err)
-	} else {
-		log.Println("トークンリフレッシュに成功しました")
+// cancelOnSignal は SIGINT または SIGTERM を受信したら cancel を呼び出します
+func cancelOnSignal(logger *slog.Logger, timeout time.Duration, cancel context.CancelFunc) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigChan
+		logger.Info("シグナルを受信しました。シャットダウンします", "signal", sig.String(), "timeout", timeout)
+		// 2回目のシグナルではシャットダウンを待たずに終了する
+		signal.Stop(sigChan)
+		cancel()
+	}()
+}
+
+// reloadOnHangup は ctx がキャンセルされるまで、SIGHUP を受信するたびに reload を呼び出します
+// （再起動せずに設定を読み込み直す）。戻り値の関数で SIGHUP の受信をやめます
+func reloadOnHangup(ctx context.Context, reload func()) (stop func()) {
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-hupChan:
+				reload()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() { signal.Stop(hupChan) }
+}
+
+// reopenLogOnSignal は ctx がキャンセルされるまで、SIGUSR1 を受信するたびに LOG_FILE を開き直します
+// （logrotate でファイルを移した後に新しいファイルへ書き込む）。戻り値の関数で受信をやめます
+func reopenLogOnSignal(ctx context.Context, logger *slog.Logger) (stop func()) {
+	if len(reopenSignals) == 0 {
+		return func() {}
 	}
+	usrChan := make(chan os.Signal, 1)
+	signal.Notify(usrChan, reopenSignals...)
+	go func() {
+		for {
+			select {
+			case <-usrChan:
+				if err := logging.Reopen(); err != nil {
+					logger.Error("ログファイルを開き直せませんでした", "error", redact.Error(err))
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() { signal.Stop(usrChan) }
+}
 
-	quote, err := quoteUseCase.PostRandomQuote(reqCtx)
+// newApplication は cfg のボットを組み立てます。opts は設定の再読み込みに使います
+func newApplication(cfg *config.Config, opts []config.Option) (_ *app.App, err error) {
+	logger := logging.ModuleFor(cfg, "main")
+	quoteRepo := repository.NewQuoteRepository(cfg)
+
+	// statsd や DogStatsD へのメトリクスの送信（METRICS_BACKEND が none 以外の場合のみ）。
+	// 投稿先の HTTP クライアントとボットで同じ送信先を使う
+	sink, err := metrics.NewSink(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("メトリクスの送信先の設定に失敗しました: %w", err)
+	}
+	defer func() {
+		if closer, ok := sink.(io.Closer); ok && err != nil {
+			closer.Close()
+		}
+	}()
+
+	poster, err := newPoster(cfg, sink)
+	if err != nil {
+		return nil, fmt.Errorf("投稿先の初期化に失敗しました: %w", err)
+	}
+	// 複数のボットを動かしている場合は、起動できなかったボットのトークンのリフレッシュを止める
+	defer func() {
+		if err != nil {
+			poster.Shutdown()
+		}
+	}()
+	crossPosters, err := newCrossPosters(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("ほかの投稿先の初期化に失敗しました: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			shutdownCrossPosters(crossPosters)
+		}
+	}()
+	selector, err := usecase.NewSelector(cfg.QuoteSelector)
 	if err != nil {
-		log.Printf("初回投稿の実行に失敗しました: %v", err)
-	} else {
-		message := fmt.Sprintf("%s\n- %s", quote.Text, quote.Author)
-		if err := blueskyRepo.PostMessage(reqCtx, message); err != nil {
-			log.Printf("初回投稿の実行に失敗しました: %v", err)
-		} else {
-			log.Println("初回投稿に成功しました")
+		return nil, fmt.Errorf("設定に問題があります: %w", err)
+	}
+	quoteUseCase := usecase.NewQuoteUseCase(quoteRepo, usecase.WithRand(usecase.NewRand(cfg.RandomSeed)), usecase.WithSelector(selector))
+
+	// 起動時にセッションが有効か確認する（投稿プラグインはハンドシェイクで確認済み）
+	if blueskyRepo, ok := poster.(*repository.BlueskyRepository); ok {
+		validateCtx, validateCancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+		session, err := blueskyRepo.ValidateSession(validateCtx)
+		validateCancel()
+		switch {
+		case errors.Is(err, domain.ErrAuthExpired):
+			logger.Warn("セッションの有効期限が切れています。quotebot login でログインし直してください", "error", redact.Error(err))
+		case err != nil:
+			logger.Warn("セッションの検証に失敗しました。投稿に失敗する可能性があります", "error", redact.Error(err))
+		default:
+			logger.Info("セッションを確認しました", "handle", session.Handle, "did", session.DID)
+		}
+		// TOKEN_REFRESH_INTERVAL ごとにリフレッシュするとき、次のリフレッシュより前にトークンが切れないようにする
+		if err := blueskyRepo.CheckRefreshInterval(); err != nil {
+			return nil, fmt.Errorf("設定に問題があります: %w", err)
 		}
 	}
-	reqCancel()
 
-	// メインループ
-	for {
-		select {
-		case <-ticker.C:
-			reqCtx, reqCancel := context.WithTimeout(ctx, cfg.HTTPTimeout)
+	if err := quoteUseCase.Initialize(); err != nil {
+		return nil, fmt.Errorf("ユースケースの初期化に失敗しました: %w", err)
+	}
+
+	// 複数のレプリカで動かす場合は、リーダーに選ばれたレプリカだけが投稿する（LEADER_ELECTION が有効な場合のみ）
+	elector, err := leader.NewFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("リーダー選出の設定に失敗しました: %w", err)
+	}
+
+	deps := app.Dependencies{
+		Quotes:       quoteUseCase,
+		Poster:       poster,
+		CrossPosters: crossPosters,
+		// SIGHUP や管理APIで設定を再読み込みする
+		LoadConfig: func() (*config.Config, error) { return config.New(opts...) },
+		QuotesFile: quoteRepo,
+	}
+	if elector != nil {
+		deps.Leader = elector
+	}
+
+	// 送信中の投稿と承認待ちの投稿（STATE_FILE または STATE_BACKEND のデータベースがある場合のみ）
+	stateStore, err := state.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("状態の読み込みに失敗しました: %w", err)
+	}
+
+	// 投稿履歴（HISTORY_FILE または STATE_BACKEND のデータベースがある場合のみ）
+	historyRecorder, err := history.NewRecorder(cfg, stateStore)
+	if err != nil {
+		return nil, fmt.Errorf("投稿履歴の初期化に失敗しました: %w", err)
+	}
+	if historyRecorder != nil {
+		deps.History = historyRecorder
+	}
 
-			// 定期的な投稿前にもトークンをリフレッシュ
-			log.Println("定期投稿前にトークンをリフレッシュします...")
-			if err := blueskyRepo.RefreshToken(reqCtx); err != nil {
-				log.Printf("トークンリフレッシュに失敗しました: %v", err)
-			} else {
-				log.Println("トークンリフレッシュに成功しました")
+	if stateStore != nil {
+		deps.Outbox = outbox.New(stateStore)
+	}
+	if cfg.ApprovalRequired {
+		deps.Approvals = approval.NewQueue(stateStore, cfg.ApprovalTTL)
+	}
+	if cfg.DeadLetterEnabled {
+		deps.DeadLetters = deadletter.NewQueue(stateStore, cfg.DeadLetterMax)
+	}
+
+	if sink != metrics.Discard {
+		deps.Metrics = sink
+	}
+
+	// 投稿やトークンのリフレッシュが続けて失敗したときの通知
+	deps.Notifier, err = notify.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("アラートの送信先の設定に失敗しました: %w", err)
+	}
+
+	// 名言の提案と名言のリクエストはユーザーの情報を含むため、STATE_ENCRYPTION_ENABLED の場合は暗号化して保存する
+	privateStore, err := sensitiveStore(cfg, stateStore)
+	if err != nil {
+		return nil, err
+	}
+
+	// ボットの投稿への返信で提案された名言の受付（Bluesky に投稿する場合のみ。STATE_FILE は検証済み）
+	var inbox *suggestion.Inbox
+	if blueskyRepo, ok := poster.(*repository.BlueskyRepository); ok && cfg.SuggestionsEnabled {
+		inbox, err = suggestion.New(cfg, blueskyRepo, quoteUseCase, privateStore)
+		if err != nil {
+			return nil, fmt.Errorf("名言の提案の受付の初期化に失敗しました: %w", err)
+		}
+		deps.NewServices = append(deps.NewServices, func(a *app.App) (app.Server, error) {
+			return inbox, nil
+		})
+	}
+
+	if cfg.AdminEnabled {
+		deps.NewAdminServer = func(a *app.App) app.Server {
+			adminOpts := []admin.Option{admin.WithConfigReloader(a), admin.WithEvents(a.Bot().Events())}
+			if cfg.ApprovalRequired {
+				adminOpts = append(adminOpts, admin.WithApprover(a.Bot()))
 			}
+			if cfg.DeadLetterEnabled {
+				adminOpts = append(adminOpts, admin.WithDeadLetters(a.Bot()))
+			}
+			if inbox != nil {
+				adminOpts = append(adminOpts, admin.WithSuggestions(inbox))
+			}
+			return admin.NewServer(cfg, a.Bot(), adminOpts...)
+		}
+	}
 
-			quote, err := quoteUseCase.PostRandomQuote(reqCtx)
-			if err != nil {
-				log.Printf("メッセージの投稿に失敗しました: %v", err)
-				reqCancel()
-				continue
-			}
-			message := fmt.Sprintf("%s\n- %s", quote.Text, quote.Author)
-			if err := blueskyRepo.PostMessage(reqCtx, message); err != nil {
-				log.Printf("メッセージの投稿に失敗しました: %v", err)
-			} else {
-				log.Println("メッセージの投稿に成功しました")
-			}
-			reqCancel()
-		case sig := <-sigChan:
-			fmt.Printf("\nシグナル %v を受信しました。シャットダウンします...\n", sig)
-			// バックグラウンドのトークン更新プロセスをクリーンアップ
-			blueskyRepo.Done <- struct{}{}
-			return
+	if cfg.GRPCEnabled {
+		deps.NewGRPCServer = func(a *app.App) app.Server {
+			return grpcapi.NewServer(cfg, a.Bot(), quoteUseCase, a.Bot().Events())
+		}
+	}
+
+	// 認証なしの統計ページ（投稿履歴の保存先は検証済み。今月の名言は Bluesky に投稿する場合のみ）
+	if cfg.PublicStatsEnabled {
+		statsOpts := []publicstats.Option{publicstats.WithHistory(func() ([]history.Entry, error) { return history.Read(cfg, stateStore) })}
+		if blueskyRepo, ok := poster.(*repository.BlueskyRepository); ok {
+			statsOpts = append(statsOpts, publicstats.WithEngagement(blueskyRepo))
+		}
+		deps.NewServices = append(deps.NewServices, func(a *app.App) (app.Server, error) {
+			return publicstats.NewServer(cfg, a.Bot(), statsOpts...), nil
+		})
+	}
+
+	// 自己紹介と固定する投稿の自動更新（Bluesky に投稿する場合のみ）
+	if blueskyRepo, ok := poster.(*repository.BlueskyRepository); ok && cfg.ProfileUpdateEnabled {
+		deps.NewServices = append(deps.NewServices, func(a *app.App) (app.Server, error) {
+			return profile.New(cfg, blueskyRepo, a.Bot(), quoteUseCase, a.Bot().Events(), stateStore)
+		})
+	}
+
+	// 名言を投稿しなかった日の稼働の知らせ
+	if cfg.HeartbeatEnabled {
+		deps.NewServices = append(deps.NewServices, func(a *app.App) (app.Server, error) {
+			return heartbeat.New(cfg, poster, a.Bot(), a.Bot().Events(), stateStore)
+		})
+	}
+
+	// メンションで届いた名言のリクエストへの返信（Bluesky に投稿する場合のみ。STATE_FILE は検証済み）
+	if blueskyRepo, ok := poster.(*repository.BlueskyRepository); ok && cfg.QuoteRequestsEnabled {
+		formatter, err := app.NewFormatter(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("投稿本文のテンプレートの解析に失敗しました: %w", err)
 		}
+		deps.NewServices = append(deps.NewServices, func(a *app.App) (app.Server, error) {
+			return quoterequest.New(cfg, blueskyRepo, quoteUseCase, formatter, privateStore)
+		})
+	}
+
+	return app.New(cfg, deps)
+}
+
+// checkForUpdate はGitHubのリリースを確認し、新しいバージョンがあればログに出力します。
+// 確認に失敗しても起動には影響しません
+func checkForUpdate(logger *slog.Logger, cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+	defer cancel()
+
+	release, err := version.CheckUpdate(ctx, &http.Client{}, version.ReleasesURL)
+	if err != nil {
+		logger.Debug("更新の確認に失敗しました", "error", redact.Error(err))
+		return
+	}
+	if release != nil {
+		logger.Info("新しいバージョンが公開されています", "current", version.Version, "latest", release.TagName, "url", release.HTMLURL)
 	}
 }
+
+// poster は投稿先です。使い終わったら Shutdown を呼び出します
+type poster interface {
+	app.Poster
+	Shutdown()
+}
+
+// newPoster は投稿先を作成します。PUBLISHER_PLUGIN が設定されている場合は Bluesky の代わりに投稿プラグインで投稿します。
+// Bluesky へのリクエストの時間は sink に送ります
+func newPoster(cfg *config.Config, sink metrics.Sink) (poster, error) {
+	if cfg.PublisherPlugin != "" {
+		repo, err := repository.NewPluginRepository(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("投稿プラグインの初期化に失敗しました: %w", err)
+		}
+		return repo, nil
+	}
+	var middlewares []repository.Middleware
+	if sink != metrics.Discard {
+		middlewares = append(middlewares, repository.MetricsMiddleware(sink))
+	}
+	repo, err := repository.NewBlueskyRepository(cfg, middlewares...)
+	if err != nil {
+		return nil, fmt.Errorf("Blueskyリポジトリの初期化に失敗しました: %w", err)
+	}
+	return repo, nil
+}
+
+// newCrossPosters は CROSS_POST_PLUGINS の投稿プラグインを起動します。
+// 起動できない投稿プラグインがあれば、起動済みの投稿プラグインを停止してエラーを返します
+func newCrossPosters(cfg *config.Config) ([]app.CrossPoster, error) {
+	var posters []app.CrossPoster
+	for _, command := range cfg.CrossPostCommands() {
+		repo, err := repository.NewPluginRepositoryWithCommand(cfg, command[0], command[1:])
+		if err != nil {
+			shutdownCrossPosters(posters)
+			return nil, fmt.Errorf("%s: %w", command[0], err)
+		}
+		posters = append(posters, repo)
+	}
+	return posters, nil
+}
+
+// shutdownCrossPosters は起動したほかの投稿先の投稿プラグインを停止します
+func shutdownCrossPosters(posters []app.CrossPoster) {
+	for _, poster := range posters {
+		if shutdowner, ok := poster.(interface{ Shutdown() }); ok {
+			shutdowner.Shutdown()
+		}
+	}
+}
+
+// fatal はエラーをログに出力してプロセスを終了します
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", redact.Error(err))
+	os.Exit(1)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+)
+
+// oauthLoginTimeout はブラウザでの認可を待つ最大時間です
+const oauthLoginTimeout = 5 * time.Minute
+
+// oauthCallback はリダイレクトURIで受け取った認可レスポンスです
+type oauthCallback struct {
+	code   string
+	issuer string
+	err    error
+}
+
+// runOAuthLogin はブラウザでatproto OAuthの認可を行い、取得したセッションをトークンストアに保存します
+func runOAuthLogin(cfg *config.Config) error {
+	store, err := repository.NewTokenStore(cfg)
+	if err != nil {
+		return fmt.Errorf("トークンストアの作成に失敗しました: %w", err)
+	}
+	if store == nil {
+		return fmt.Errorf("OAuthセッションを保存するには TOKEN_FILE または CREDENTIALS_BACKEND の設定が必要です")
+	}
+
+	redirectURI, err := url.Parse(cfg.OAuthRedirectURI)
+	if err != nil {
+		return fmt.Errorf("OAUTH_REDIRECT_URI が不正です: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oauthLoginTimeout)
+	defer cancel()
+
+	httpClient, err := repository.NewHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	oauthClient := repository.NewOAuthClient(cfg, httpClient)
+
+	loginHint := cfg.Handle
+	if loginHint == "" {
+		loginHint = cfg.DID
+	}
+	auth, err := oauthClient.StartAuthorization(ctx, cfg.PDSURL, loginHint)
+	if err != nil {
+		return fmt.Errorf("認可リクエストの開始に失敗しました: %w", err)
+	}
+
+	// リダイレクトを受け取るためのローカルサーバーを起動
+	listener, err := net.Listen("tcp", redirectURI.Host)
+	if err != nil {
+		return fmt.Errorf("リダイレクト用のサーバーを起動できません（%s）: %w", redirectURI.Host, err)
+	}
+
+	callbacks := make(chan oauthCallback, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != redirectURI.Path {
+				http.NotFound(w, r)
+				return
+			}
+
+			query := r.URL.Query()
+			var callback oauthCallback
+			switch {
+			case query.Get("state") != auth.State:
+				callback.err = fmt.Errorf("state が一致しません")
+			case query.Get("error") != "":
+				callback.err = fmt.Errorf("認可が拒否されました: %s %s", query.Get("error"), query.Get("error_description"))
+			default:
+				callback.code = query.Get("code")
+				callback.issuer = query.Get("iss")
+			}
+
+			if callback.err != nil {
+				http.Error(w, callback.err.Error(), http.StatusBadRequest)
+			} else {
+				fmt.Fprintln(w, "認可が完了しました。このウィンドウを閉じてください。")
+			}
+
+			select {
+			case callbacks <- callback:
+			default:
+			}
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Printf("次のURLをブラウザで開き、アカウントへのアクセスを許可してください:\n\n%s\n\n", auth.AuthorizationURL)
+
+	var callback oauthCallback
+	select {
+	case callback = <-callbacks:
+	case <-ctx.Done():
+		return fmt.Errorf("認可がタイムアウトしました: %w", ctx.Err())
+	}
+	if callback.err != nil {
+		return callback.err
+	}
+
+	session, accessToken, refreshToken, err := oauthClient.ExchangeCode(ctx, auth, callback.code, callback.issuer, cfg.DID)
+	if err != nil {
+		return fmt.Errorf("トークンの取得に失敗しました: %w", err)
+	}
+
+	err = store.Save(repository.StoredTokens{
+		AccessJWT:  accessToken,
+		RefreshJWT: refreshToken,
+		OAuth:      session,
+		SavedAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("OAuthセッションの保存に失敗しました: %w", err)
+	}
+
+	fmt.Printf("OAuthセッションを保存しました（DID: %s）\n", session.DID)
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package publisher defines the protocol between quotebot and publisher plugins.
+//
+// A publisher plugin is an executable that posts to a platform quotebot does not support itself.
+// quotebot starts it once (PUBLISHER_PLUGIN) and exchanges one JSON object per line:
+// requests on the plugin's stdin, responses on its stdout. Anything the plugin writes to
+// stderr is copied to quotebot's log. The plugin should exit when its stdin is closed.
+//
+// The first request is always a handshake:
+//
+//	{"id":1,"method":"handshake","protocolVersion":1}
+//	{"id":1,"protocolVersion":1,"platform":"mastodon","maxLength":500,"unit":"characters"}
+//
+// followed by one publish request per post:
+//
+//	{"id":2,"method":"publish","text":"...","requestId":"0123456789abcdef"}
+//	{"id":2,"uri":"https://example.social/@quotes/1","postId":"1","url":"https://example.social/@quotes/1"}
+//
+// "postId" and "url" are optional; they let quotebot link the post to the same quote on other
+// platforms and show where to view it.
+//
+// A request fails when its response has a non-empty "error". Requests are sent one at a time,
+// so a plugin can handle them sequentially. Plugins written in Go can use Serve instead of
+// implementing the protocol.
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ProtocolVersion is the version of the protocol described in the package documentation
+const ProtocolVersion = 1
+
+// Methods of a request
+const (
+	MethodHandshake = "handshake"
+	MethodPublish   = "publish"
+)
+
+// Length units a plugin can report in its handshake
+const (
+	UnitGraphemes  = "graphemes"
+	UnitCharacters = "characters"
+	UnitWeighted   = "weighted"
+)
+
+// Request is a message from quotebot to the plugin
+type Request struct {
+	ID     uint64 `json:"id"`
+	Method string `json:"method"`
+	// ProtocolVersion is set in the handshake
+	ProtocolVersion int `json:"protocolVersion,omitempty"`
+	// Text is the formatted post to publish
+	Text string `json:"text,omitempty"`
+	// RequestID identifies the post in quotebot's logs and history
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Response is the plugin's reply to the request with the same ID
+type Response struct {
+	ID    uint64 `json:"id"`
+	Error string `json:"error,omitempty"`
+
+	// Handshake
+	ProtocolVersion int    `json:"protocolVersion,omitempty"`
+	Platform        string `json:"platform,omitempty"`
+	MaxLength       int    `json:"maxLength,omitempty"`
+	Unit            string `json:"unit,omitempty"`
+
+	// Publish
+	URI string `json:"uri,omitempty"`
+	CID string `json:"cid,omitempty"`
+	// PostID is the platform's own ID of the post, recorded in the history next to the IDs
+	// of the same quote on other platforms
+	PostID string `json:"postId,omitempty"`
+	// URL is the web page of the post
+	URL string `json:"url,omitempty"`
+}
+
+// Info describes the platform a plugin posts to. quotebot formats and checks the length of
+// posts with it, so MaxLength and Unit should match the platform's own limit
+type Info struct {
+	Platform  string
+	MaxLength int
+	Unit      string
+}
+
+// Post is a reference to a published post
+type Post struct {
+	URI string
+	CID string
+	ID  string // The platform's own ID of the post, if it has one
+	URL string // The web page of the post, if it has one
+}
+
+// Plugin is implemented by publisher plugins written in Go
+type Plugin interface {
+	Info() Info
+	Publish(ctx context.Context, text, requestID string) (*Post, error)
+}
+
+// Serve runs p as a plugin on stdin and stdout until stdin is closed
+func Serve(p Plugin) error {
+	return ServeIO(context.Background(), os.Stdin, os.Stdout, p)
+}
+
+// ServeIO runs p as a plugin, reading requests from in and writing responses to out,
+// until in is closed or ctx is done
+func ServeIO(ctx context.Context, in io.Reader, out io.Writer, p Plugin) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	encoder := json.NewEncoder(out)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		if err := encoder.Encode(handle(ctx, p, req)); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func handle(ctx context.Context, p Plugin, req Request) Response {
+	resp := Response{ID: req.ID}
+	switch req.Method {
+	case MethodHandshake:
+		if req.ProtocolVersion != ProtocolVersion {
+			resp.Error = fmt.Sprintf("unsupported protocol version %d (want %d)", req.ProtocolVersion, ProtocolVersion)
+			return resp
+		}
+		info := p.Info()
+		resp.ProtocolVersion = ProtocolVersion
+		resp.Platform, resp.MaxLength, resp.Unit = info.Platform, info.MaxLength, info.Unit
+	case MethodPublish:
+		post, err := p.Publish(ctx, req.Text, req.RequestID)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.URI, resp.CID = post.URI, post.CID
+		resp.PostID, resp.URL = post.ID, post.URL
+	default:
+		resp.Error = fmt.Sprintf("unknown method %q", req.Method)
+	}
+	return resp
+}
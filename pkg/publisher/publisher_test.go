@@ -0,0 +1,60 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakePlugin struct{}
+
+func (fakePlugin) Info() Info {
+	return Info{Platform: "mastodon", MaxLength: 500, Unit: UnitCharacters}
+}
+
+func (fakePlugin) Publish(ctx context.Context, text, requestID string) (*Post, error) {
+	if text == "" {
+		return nil, errors.New("empty status")
+	}
+	return &Post{URI: "https://example.social/@quotes/1", ID: "1", URL: "https://example.social/@quotes/1"}, nil
+}
+
+func TestServeIO(t *testing.T) {
+	in := strings.Join([]string{
+		`{"id":1,"method":"handshake","protocolVersion":1}`,
+		`{"id":2,"method":"publish","text":"テスト名言","requestId":"0123456789abcdef"}`,
+		`{"id":3,"method":"publish"}`,
+		`{"id":4,"method":"delete"}`,
+		`{"id":5,"method":"handshake","protocolVersion":2}`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := ServeIO(context.Background(), strings.NewReader(in), &out, fakePlugin{}); err != nil {
+		t.Fatalf("ServeIO() error = %v", err)
+	}
+
+	want := []string{
+		`{"id":1,"protocolVersion":1,"platform":"mastodon","maxLength":500,"unit":"characters"}`,
+		`{"id":2,"uri":"https://example.social/@quotes/1","postId":"1","url":"https://example.social/@quotes/1"}`,
+		`{"id":3,"error":"empty status"}`,
+		`{"id":4,"error":"unknown method \"delete\""}`,
+		`{"id":5,"error":"unsupported protocol version 2 (want 1)"}`,
+	}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("ServeIO() wrote %d responses, want %d:\n%s", len(got), len(want), out.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("response %d = %s, want %s", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestServeIO_InvalidRequest(t *testing.T) {
+	var out bytes.Buffer
+	if err := ServeIO(context.Background(), strings.NewReader("not json\n"), &out, fakePlugin{}); err == nil {
+		t.Error("ServeIO() error = nil, want an error for an invalid request")
+	}
+}
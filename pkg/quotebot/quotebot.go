@@ -0,0 +1,276 @@
+// Package quotebot embeds quotebot's posting engine in other Go programs.
+//
+// A Bot takes quotes from a QuoteSource, formats them, and posts them through a Publisher at
+// the times chosen by a Scheduler, the same way the quotebot command does:
+//
+//	quotes, err := quotebot.LoadQuotes("quotes.json")
+//	if err != nil {
+//		return err
+//	}
+//	bot, err := quotebot.New(quotes, myPublisher, quotebot.WithScheduler(quotebot.Every(30*time.Minute)))
+//	if err != nil {
+//		return err
+//	}
+//	bot.Run(ctx)
+//	return bot.Shutdown(context.Background())
+//
+// To post to Bluesky, use NewBlueskyPublisher with a config.Config loaded from the environment.
+// The types aliased here are the ones the engine uses internally, so values can be passed
+// between this package and config without conversion.
+package quotebot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/events"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// Default schedule and timeout, matching the POST_INTERVAL and POST_TIMEOUT defaults
+const (
+	DefaultInterval    = time.Hour
+	DefaultPostTimeout = 2 * time.Minute
+)
+
+type (
+	// Quote is a quote to post
+	Quote = domain.Quote
+	// PostRef is a reference to a published post
+	PostRef = domain.PostRef
+	// Capabilities are the limits of the platform a Publisher posts to
+	Capabilities = domain.Capabilities
+	// PostResult is the outcome of one post
+	PostResult = app.PostResult
+	// Status is the bot's current state
+	Status = app.Status
+	// Event is something that happened in the bot, such as a post succeeding or failing
+	Event = events.Event
+	// EventType is the kind of an Event
+	EventType = events.Type
+	// Scheduler chooses when scheduled posts are made
+	Scheduler = app.Scheduler
+	// SchedulerFunc uses a function as a Scheduler
+	SchedulerFunc = app.SchedulerFunc
+	// BlueskyPublisher posts to Bluesky, refreshing its session in the background
+	BlueskyPublisher = repository.BlueskyRepository
+)
+
+// Event types
+const (
+	EventQuoteSelected      = events.QuoteSelected
+	EventPostSucceeded      = events.PostSucceeded
+	EventPostFailed         = events.PostFailed
+	EventPostHeld           = events.PostHeld
+	EventPostDryRun         = events.PostDryRun
+	EventTokenRefreshed     = events.TokenRefreshed
+	EventTokenRefreshFailed = events.TokenRefreshFailed
+
+	EventSessionStateChanged = events.SessionStateChanged
+)
+
+// Publisher posts formatted text to a platform. If it also has a
+// Capabilities() Capabilities method, posts are checked against those limits instead of Bluesky's
+type Publisher interface {
+	Publish(ctx context.Context, text string) (*PostRef, error)
+}
+
+// QuoteSource chooses the quote for each post
+type QuoteSource interface {
+	Next(ctx context.Context) (*Quote, error)
+}
+
+// Reloader is implemented by a QuoteSource that can reload its quotes (Bot.ReloadQuotes)
+type Reloader interface {
+	Reload() error
+	Count() int
+}
+
+// Every returns a Scheduler that posts at a fixed interval
+func Every(interval time.Duration) Scheduler {
+	return SchedulerFunc(func(last time.Time) time.Time {
+		return last.Add(interval)
+	})
+}
+
+// NewBlueskyPublisher creates a Publisher that posts to Bluesky with the credentials in cfg.
+// Call Shutdown on it when done to stop the background token refresh
+func NewBlueskyPublisher(cfg *config.Config) (*BlueskyPublisher, error) {
+	return repository.NewBlueskyRepository(cfg)
+}
+
+// Quotes is a QuoteSource that picks a random quote from a list
+type Quotes struct {
+	uc *usecase.QuoteUseCase
+}
+
+// NewQuotes returns a QuoteSource that picks a random quote from quotes.
+// Quotes without an ID are given one made from their text, as with a quotes file
+func NewQuotes(quotes []Quote) (*Quotes, error) {
+	return newQuotes(staticQuotes(quotes))
+}
+
+// LoadQuotes returns a QuoteSource that picks a random quote from a quotes file.
+// Reload reads the file again
+func LoadQuotes(path string) (*Quotes, error) {
+	return newQuotes(repository.NewQuoteRepository(&config.Config{QuotesFile: path}))
+}
+
+func newQuotes(repo usecase.QuoteRepository) (*Quotes, error) {
+	uc := usecase.NewQuoteUseCase(repo)
+	if err := uc.Initialize(); err != nil {
+		return nil, err
+	}
+	return &Quotes{uc: uc}, nil
+}
+
+// Next returns a random quote
+func (q *Quotes) Next(ctx context.Context) (*Quote, error) {
+	return q.uc.PostRandomQuote(ctx)
+}
+
+// Reload loads the quotes again, keeping the current ones if they are invalid
+func (q *Quotes) Reload() error {
+	return q.uc.Reload()
+}
+
+// Count returns the number of quotes
+func (q *Quotes) Count() int {
+	return q.uc.Count()
+}
+
+// All returns the quotes with their stable IDs
+func (q *Quotes) All() []Quote {
+	return q.uc.Quotes()
+}
+
+type staticQuotes []Quote
+
+func (s staticQuotes) LoadQuotes() ([]Quote, error) {
+	return append([]Quote(nil), s...), nil
+}
+
+// Option configures a Bot
+type Option func(*options)
+
+type options struct {
+	cfg       *config.Config
+	scheduler Scheduler
+}
+
+// WithConfig applies the posting settings of cfg, as loaded by config.New: POST_INTERVAL,
+// POST_TIMEOUT, DRY_RUN, the post templates and typography, hashtags, format variants, translations (POST_LANG) and the deny-list
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) {
+		o.cfg = cfg
+	}
+}
+
+// WithScheduler chooses when scheduled posts are made, instead of POST_INTERVAL
+func WithScheduler(scheduler Scheduler) Option {
+	return func(o *options) {
+		o.scheduler = scheduler
+	}
+}
+
+// Bot posts quotes on a schedule and can be controlled while it runs
+type Bot struct {
+	bot *app.Bot
+}
+
+// New creates a Bot. Without WithConfig it posts every DefaultInterval with the default template
+func New(source QuoteSource, publisher Publisher, opts ...Option) (*Bot, error) {
+	if source == nil || publisher == nil {
+		return nil, errors.New("quotebot: a QuoteSource and a Publisher are required")
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg := o.cfg
+	var botOpts []app.Option
+	if cfg == nil {
+		cfg = &config.Config{PostInterval: DefaultInterval, PostTimeout: DefaultPostTimeout}
+	} else {
+		contentOpts, err := app.ContentOptions(cfg)
+		if err != nil {
+			return nil, err
+		}
+		botOpts = append(botOpts, contentOpts...)
+	}
+	if o.scheduler != nil {
+		botOpts = append(botOpts, app.WithScheduler(o.scheduler))
+	}
+	return &Bot{bot: app.NewBot(cfg, quoteSource{source}, publisher, botOpts...)}, nil
+}
+
+// Run posts once, then posts on the schedule until ctx is cancelled.
+// Call Shutdown afterwards to wait for an in-flight post
+func (b *Bot) Run(ctx context.Context) {
+	b.bot.Run(ctx)
+}
+
+// PostNow posts a quote immediately, even while paused
+func (b *Bot) PostNow(ctx context.Context) (*PostResult, error) {
+	return b.bot.PostNow(ctx)
+}
+
+// Pause skips scheduled posts until Resume is called
+func (b *Bot) Pause() {
+	b.bot.Pause()
+}
+
+// Resume resumes scheduled posts
+func (b *Bot) Resume() {
+	b.bot.Resume()
+}
+
+// ReloadQuotes reloads the quotes if the QuoteSource implements Reloader, and returns their number
+func (b *Bot) ReloadQuotes() (int, error) {
+	return b.bot.ReloadQuotes()
+}
+
+// Status returns the bot's current state
+func (b *Bot) Status() Status {
+	return b.bot.Status()
+}
+
+// Subscribe returns a channel of the bot's events and a function to stop receiving them.
+// Events are dropped for a subscriber that falls behind, so a slow reader never delays posting
+func (b *Bot) Subscribe() (<-chan Event, func()) {
+	return b.bot.Events().Subscribe()
+}
+
+// Shutdown stops accepting posts and waits for an in-flight post until ctx is done
+func (b *Bot) Shutdown(ctx context.Context) error {
+	return b.bot.Shutdown(ctx)
+}
+
+// quoteSource adapts a QuoteSource to the engine's
+type quoteSource struct {
+	QuoteSource
+}
+
+func (s quoteSource) PostRandomQuote(ctx context.Context) (*Quote, error) {
+	return s.Next(ctx)
+}
+
+func (s quoteSource) Reload() error {
+	if r, ok := s.QuoteSource.(Reloader); ok {
+		return r.Reload()
+	}
+	return nil
+}
+
+func (s quoteSource) Count() int {
+	if r, ok := s.QuoteSource.(Reloader); ok {
+		return r.Count()
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package quotebot
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+)
+
+// recordingPublisher は投稿した本文を記録します
+type recordingPublisher struct {
+	mu    sync.Mutex
+	texts []string
+	err   error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, text string) (*PostRef, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.texts = append(p.texts, text)
+	return &PostRef{Platform: "test", URI: "test://post/" + text}, nil
+}
+
+func (p *recordingPublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.texts)
+}
+
+func TestBot_PostNow(t *testing.T) {
+	quotes, err := NewQuotes([]Quote{{Text: "テスト名言", Author: "著者"}})
+	if err != nil {
+		t.Fatalf("NewQuotes() error = %v", err)
+	}
+	publisher := &recordingPublisher{}
+	bot, err := New(quotes, publisher)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	events, unsubscribe := bot.Subscribe()
+	defer unsubscribe()
+
+	result, err := bot.PostNow(context.Background())
+	if err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+	if !strings.Contains(result.Text, "テスト名言") || result.QuoteID == "" {
+		t.Errorf("PostNow() = %+v, want the quote with its ID", result)
+	}
+	if got := <-events; got.Type != EventQuoteSelected {
+		t.Errorf("first event = %s, want %s", got.Type, EventQuoteSelected)
+	}
+	if got := <-events; got.Type != EventPostSucceeded {
+		t.Errorf("second event = %s, want %s", got.Type, EventPostSucceeded)
+	}
+
+	publisher.err = errors.New("unavailable")
+	if _, err := bot.PostNow(context.Background()); err == nil {
+		t.Error("PostNow() error = nil, want the publisher's error")
+	}
+	if st := bot.Status(); st.PoolSize != 1 || len(st.RecentErrors) != 1 {
+		t.Errorf("Status() = %+v, want 1 quote and 1 error", st)
+	}
+}
+
+func TestBot_Run(t *testing.T) {
+	quotes, err := NewQuotes([]Quote{{Text: "テスト名言", Author: "著者"}})
+	if err != nil {
+		t.Fatalf("NewQuotes() error = %v", err)
+	}
+	publisher := &recordingPublisher{}
+	bot, err := New(quotes, publisher, WithScheduler(Every(10*time.Millisecond)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		bot.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for publisher.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if err := bot.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if publisher.count() < 3 {
+		t.Errorf("posts = %d, want at least 3", publisher.count())
+	}
+}
+
+func TestNew_WithConfig(t *testing.T) {
+	quotes, err := NewQuotes([]Quote{{Text: "テスト名言", Author: "著者"}})
+	if err != nil {
+		t.Fatalf("NewQuotes() error = %v", err)
+	}
+	cfg := &config.Config{
+		PostInterval:         time.Hour,
+		PostTimeout:          time.Second,
+		PostTemplate:         "{{.Text}} / {{.Author}}",
+		AttributionSeparator: "hyphen",
+		SmartQuotes:          "keep",
+		Ellipsis:             "keep",
+		HashtagMode:          "fixed",
+	}
+	publisher := &recordingPublisher{}
+	bot, err := New(quotes, publisher, WithConfig(cfg))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := bot.PostNow(context.Background()); err != nil {
+		t.Fatalf("PostNow() error = %v", err)
+	}
+	if publisher.texts[0] != "テスト名言 / 著者" {
+		t.Errorf("posted %q, want the configured template", publisher.texts[0])
+	}
+
+	cfg.PostTemplate = "{{.Text"
+	if _, err := New(quotes, publisher, WithConfig(cfg)); err == nil {
+		t.Error("New() error = nil, want an error for an invalid template")
+	}
+}
+
+func TestLoadQuotes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.json")
+	if err := os.WriteFile(path, []byte(`[{"text":"テスト名言","author":"著者"}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	quotes, err := LoadQuotes(path)
+	if err != nil {
+		t.Fatalf("LoadQuotes() error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`[{"text":"テスト名言","author":"著者"},{"text":"テスト名言2","author":"著者"}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	bot, err := New(quotes, &recordingPublisher{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if n, err := bot.ReloadQuotes(); err != nil || n != 2 {
+		t.Errorf("ReloadQuotes() = %d, %v, want 2", n, err)
+	}
+	if all := quotes.All(); len(all) != 2 || all[1].ID == "" {
+		t.Errorf("All() = %+v, want 2 quotes with IDs", all)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/history"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/metrics"
+	"github.com/littleironwaltz/quotebot/internal/state"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// selectedQuote は post-now で選んだ1件の名言だけを返す名言の取得元です
+type selectedQuote struct {
+	quote *domain.Quote
+}
+
+func (s selectedQuote) PostRandomQuote(ctx context.Context) (*domain.Quote, error) {
+	return s.quote, nil
+}
+
+func (s selectedQuote) Reload() error { return nil }
+
+func (s selectedQuote) Count() int { return 1 }
+
+// runPostNow は指定した名言をすぐに1件投稿します。
+// 定期投稿と同じ整形、投稿履歴の記録を経由して投稿します
+func runPostNow(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("post-now", flag.ContinueOnError)
+	flags.SetOutput(out)
+	index := flags.Int("index", 0, "名言ファイルの番号（1始まり）で選ぶ")
+	id := flags.String("id", "", "名言のIDで選ぶ")
+	tag := flags.String("tag", "", "タグが付いた名言からランダムに選ぶ")
+	text := flags.String("text", "", "名言ファイルにない本文を投稿する")
+	author := flags.String("author", "", "--text と一緒に指定する著者")
+	dryRun := flags.Bool("dry-run", false, "投稿せずに本文を表示する")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	quote, err := selectQuote(cfg, *index, *id, *tag, *text, *author)
+	if err != nil {
+		return err
+	}
+	// 定期投稿と同じテンプレートで整形する
+	formatter, err := app.NewFormatter(cfg)
+	if err != nil {
+		return err
+	}
+
+	// DRY_RUN（QUOTEBOT_PROFILE=dev のデフォルト）でも投稿しない
+	if *dryRun || cfg.DryRun {
+		output, err := usecase.PublishQuote(context.Background(), nil, quote, usecase.PostQuoteInput{Formatter: formatter, DryRun: true})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s\n", output.Text)
+		return nil
+	}
+
+	poster, err := newPoster(cfg, metrics.Discard)
+	if err != nil {
+		return err
+	}
+	defer poster.Shutdown()
+
+	opts := []app.Option{app.WithFormatter(formatter)}
+	stateStore, err := state.Open(cfg)
+	if err != nil {
+		return err
+	}
+	if closer, ok := stateStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+	historyRecorder, err := history.NewRecorder(cfg, stateStore)
+	if err != nil {
+		return err
+	}
+	if historyRecorder != nil {
+		if closer, ok := historyRecorder.(io.Closer); ok {
+			defer closer.Close()
+		}
+		opts = append(opts, app.WithHistory(historyRecorder))
+	}
+
+	bot := app.NewBot(cfg, selectedQuote{quote: quote}, poster, opts...)
+	result, err := bot.PostNow(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "投稿しました: %s（リクエストID: %s）\n", result.URI, result.RequestID)
+	return nil
+}
+
+// selectQuote はフラグに従って投稿する名言を選びます。選び方はいずれか1つだけ指定できます
+func selectQuote(cfg *config.Config, index int, id, tag, text, author string) (*domain.Quote, error) {
+	selectors := 0
+	for _, set := range []bool{index != 0, id != "", tag != "", text != ""} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return nil, fmt.Errorf("--index, --id, --tag, --text のいずれか1つを指定してください")
+	}
+	if author != "" && text == "" {
+		return nil, fmt.Errorf("--author は --text と一緒に指定してください")
+	}
+	if text != "" {
+		return &domain.Quote{Text: text, Author: author}, nil
+	}
+
+	quotes := usecase.NewQuoteUseCase(repository.NewQuoteRepository(cfg), usecase.WithRand(usecase.NewRand(cfg.RandomSeed)))
+	if err := quotes.Reload(); err != nil {
+		return nil, err
+	}
+	switch {
+	case index != 0:
+		return quotes.QuoteByIndex(index)
+	case id != "":
+		return quotes.QuoteByID(id)
+	default:
+		return quotes.RandomQuoteWithTag(tag)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// runQuotes は名言ファイルの名言をIDとともに一覧で表示します。
+// 表示したIDは post-now --id や投稿履歴、report quotes で同じ名言を指すのに使えます
+func runQuotes(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) > 0 && args[0] == "lint" {
+		return runQuotesLint(cfg, args[1:], out)
+	}
+	flags := flag.NewFlagSet("quotes", flag.ContinueOnError)
+	flags.SetOutput(out)
+	tag := flags.String("tag", "", "タグが付いた名言だけを表示する")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	quotes := usecase.NewQuoteUseCase(repository.NewQuoteRepository(cfg))
+	if err := quotes.Reload(); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tAUTHOR\tQUOTE")
+	for _, quote := range quotes.Quotes() {
+		if *tag != "" && !quote.HasTag(*tag) {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", quote.ID, quote.Author, excerpt(quote.Text, 40))
+	}
+	return w.Flush()
+}
+
+// runQuotesLint は名言の表記を確認して指摘を表示します。--fix を付けると空白の指摘を直して
+// 名言ファイルを書き換えます。直していない指摘が残っていればエラーを返すため、CIでも使えます
+func runQuotesLint(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("quotes lint", flag.ContinueOnError)
+	flags.SetOutput(out)
+	fix := flags.Bool("fix", false, "直せる指摘を直して名言ファイルを書き換える")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	repo := repository.NewQuoteRepository(cfg)
+	quotes, err := repo.LoadQuotes()
+	if err != nil {
+		return err
+	}
+	if *fix {
+		fixed := make(map[int]domain.Quote)
+		for i, quote := range quotes {
+			if q, ok := usecase.FixQuote(quote); ok {
+				fixed[i] = q
+				quotes[i] = q
+			}
+		}
+		if len(fixed) > 0 {
+			if err := repo.RewriteQuotes(fixed); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(out, "%d件の名言を直しました\n", len(fixed))
+	}
+
+	issues := usecase.LintQuotes(quotes)
+	fixable := 0
+	for _, issue := range issues {
+		fmt.Fprintln(out, issue)
+		if issue.Fixable {
+			fixable++
+		}
+	}
+	if len(issues) == 0 {
+		fmt.Fprintf(out, "%d件の名言に指摘はありません\n", len(quotes))
+		return nil
+	}
+	if fixable > 0 {
+		fmt.Fprintf(out, "%d件は --fix で直せます\n", fixable)
+	}
+	return fmt.Errorf("%d件の名言に%d件の指摘があります", len(quotes), len(issues))
+}
+
+// excerpt は本文を1行にし、max 文字を超える部分を省略します
+func excerpt(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
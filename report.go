@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/analytics"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+)
+
+// runReport は `quotebot report` のサブコマンドを実行します
+func runReport(cfg *config.Config, args []string, out io.Writer) error {
+	const usage = "使い方: quotebot report variants|quotes [--since 168h|2024-01-01]"
+	if len(args) == 0 || (args[0] != "variants" && args[0] != "quotes") {
+		return fmt.Errorf(usage)
+	}
+	flags := flag.NewFlagSet("report "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	sinceFlag := flags.String("since", "", "集計する期間（168h のような期間、または 2024-01-01 のような日付）")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	since, err := parseSince(*sinceFlag, time.Now().In(cfg.DisplayLocation()))
+	if err != nil {
+		return err
+	}
+	if !cfg.HasHistory() {
+		return fmt.Errorf("反応の集計には HISTORY_FILE が必要です")
+	}
+
+	entries, err := readHistory(cfg)
+	if err != nil {
+		return err
+	}
+	blueskyRepo, err := repository.NewBlueskyRepository(cfg)
+	if err != nil {
+		return fmt.Errorf("Blueskyリポジトリの初期化に失敗しました: %w", err)
+	}
+	defer blueskyRepo.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*cfg.HTTPTimeout)
+	defer cancel()
+	if args[0] == "quotes" {
+		summaries, err := analytics.SummarizeQuotes(ctx, entries, since, blueskyRepo)
+		if err != nil {
+			return err
+		}
+		return printQuoteReport(out, summaries)
+	}
+	summaries, err := analytics.SummarizeVariants(ctx, entries, since, blueskyRepo)
+	if err != nil {
+		return err
+	}
+	return printVariantReport(out, summaries)
+}
+
+// parseSince は --since の値を、集計を始める時刻にします。日付は now のタイムゾーンで解釈します。空の場合はゼロ値（すべて）です
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("--since には 168h のような期間か 2024-01-01 のような日付を指定してください: %s", value)
+}
+
+// printVariantReport は投稿形式ごとの集計を表で出力します
+func printVariantReport(out io.Writer, summaries []analytics.VariantSummary) error {
+	if len(summaries) == 0 {
+		fmt.Fprintln(out, "投稿形式を記録した投稿がありません")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VARIANT\tPOSTS\tLIKES\tREPOSTS\tREPLIES\tQUOTES\tAVG")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%.1f\n", s.Variant, s.Posts, s.Likes, s.Reposts, s.Replies, s.Quotes, s.Average())
+	}
+	return w.Flush()
+}
+
+// printQuoteReport は名言ごとの集計を表で出力します
+func printQuoteReport(out io.Writer, summaries []analytics.QuoteSummary) error {
+	if len(summaries) == 0 {
+		fmt.Fprintln(out, "名言のIDを記録した投稿がありません")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPOSTS\tLIKES\tREPOSTS\tREPLIES\tQUOTES\tAVG\tQUOTE")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%.1f\t%s\n", s.QuoteID, s.Posts, s.Likes, s.Reposts, s.Replies, s.Quotes, s.Average(), excerpt(s.Text, 30))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignals はログファイルを開き直すシグナルです
+var reopenSignals = []os.Signal{syscall.SIGUSR1}
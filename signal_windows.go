@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// reopenSignals はログファイルを開き直すシグナルです。Windows には SIGUSR1 がないため開き直しません
+var reopenSignals []os.Signal
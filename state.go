@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/quoterequest"
+	"github.com/littleironwaltz/quotebot/internal/state"
+	"github.com/littleironwaltz/quotebot/internal/suggestion"
+)
+
+// errStateUsage は `quotebot state` のサブコマンドの指定が正しくない場合のエラーです
+var errStateUsage = errors.New("使い方: quotebot state rekey")
+
+// sensitiveStateKeys は STATE_ENCRYPTION_ENABLED で暗号化する、個人情報を含む状態のキーです
+func sensitiveStateKeys() []string {
+	return append(append([]string{}, suggestion.StateKeys...), quoterequest.StateKeys...)
+}
+
+// sensitiveStore は個人情報を含む状態を保存する Store を返します。
+// STATE_ENCRYPTION_ENABLED の場合は TOKEN_ENCRYPTION_KEY の鍵で暗号化します
+func sensitiveStore(cfg *config.Config, store state.Store) (state.Store, error) {
+	if !cfg.StateEncryptionEnabled || store == nil {
+		return store, nil
+	}
+	encryptor, err := repository.NewTokenEncryptorFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("状態の暗号化の鍵の読み込みに失敗しました: %w", err)
+	}
+	return state.NewEncryptedStore(store, encryptor), nil
+}
+
+// runState は `quotebot state` のサブコマンドを実行します。
+// `quotebot state rekey` は暗号化した状態を TOKEN_ENCRYPTION_KEY の鍵で暗号化し直します。
+// 以前の鍵は TOKEN_ENCRYPTION_PREVIOUS_KEYS に指定します。暗号化する前に保存した値も暗号化します
+func runState(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) != 1 || args[0] != "rekey" {
+		return errStateUsage
+	}
+	if !cfg.StateEncryptionEnabled {
+		return fmt.Errorf("状態の暗号化には STATE_ENCRYPTION_ENABLED=true が必要です")
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if !cfg.HasState() {
+		return fmt.Errorf("暗号化し直す状態がありません。STATE_FILE または STATE_BACKEND を設定してください")
+	}
+
+	stateStore, err := state.Open(cfg)
+	if err != nil {
+		return err
+	}
+	if closer, ok := stateStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+	store, err := sensitiveStore(cfg, stateStore)
+	if err != nil {
+		return err
+	}
+	rewritten, err := store.(*state.EncryptedStore).Rekey(sensitiveStateKeys()...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d件の状態を現在の鍵で暗号化し直しました\n", rewritten)
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/interface/admin"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/metrics"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// runStatus は実行中のボットの管理APIに問い合わせ、状態を表示します
+func runStatus(cfg *config.Config, out io.Writer) error {
+	if cfg.AdminToken == "" {
+		return fmt.Errorf("状態を取得するには ADMIN_TOKEN が必要です")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+	defer cancel()
+
+	status, err := admin.NewClient(cfg).Status(ctx)
+	if err != nil {
+		return err
+	}
+	printStatus(out, status, time.Now().In(cfg.DisplayLocation()))
+	return nil
+}
+
+// printStatus は状態を人が読みやすい形式で出力します。時刻は now のタイムゾーンで表示します
+func printStatus(out io.Writer, status *app.Status, now time.Time) {
+	state := "稼働中"
+	if status.Paused {
+		state = "一時停止中"
+	}
+	if status.Halted != "" {
+		state = "停止中（投稿先のアカウントが停止されています。復旧したら再開してください）"
+	}
+	if status.Standby {
+		state += "（待機中: リーダーではないため投稿しません）"
+	}
+	if status.DryRun {
+		state += "（DRY_RUN: 投稿しません）"
+	}
+	if status.Bot != "" {
+		fmt.Fprintf(out, "ボット:           %s\n", status.Bot)
+	}
+	fmt.Fprintf(out, "状態:             %s\n", state)
+	fmt.Fprintf(out, "稼働時間:         %v\n", now.Sub(status.StartedAt).Round(time.Second))
+	fmt.Fprintf(out, "名言の件数:       %d\n", status.PoolSize)
+
+	if status.LastPost == nil {
+		fmt.Fprintf(out, "最後の投稿:       なし\n")
+	} else {
+		result := "成功"
+		if status.LastPost.DryRun {
+			result = "DRY_RUN"
+		}
+		if status.LastPost.ApprovalID != "" {
+			result = "承認待ち: " + status.LastPost.ApprovalID
+		}
+		if status.LastPost.Error != "" {
+			result = "失敗: " + status.LastPost.Error
+		}
+		fmt.Fprintf(out, "最後の投稿:       %s（%s、リクエストID: %s）\n",
+			formatTime(status.LastPost.At, now), result, status.LastPost.RequestID)
+	}
+
+	if status.NextPostAt.IsZero() {
+		fmt.Fprintf(out, "次回の投稿:       未定\n")
+	} else {
+		fmt.Fprintf(out, "次回の投稿:       %s\n", formatTime(status.NextPostAt, now))
+	}
+	if status.Failures > 0 {
+		fmt.Fprintf(out, "連続した失敗:     %d回\n", status.Failures)
+	}
+
+	if status.SessionState != "" {
+		fmt.Fprintf(out, "セッション:       %s\n", status.SessionState)
+	}
+	if status.TokenExpiresAt == nil {
+		fmt.Fprintf(out, "トークンの有効期限: 不明\n")
+	} else {
+		fmt.Fprintf(out, "トークンの有効期限: %s\n", formatTime(*status.TokenExpiresAt, now))
+	}
+
+	printStages(out, status.Stages)
+	printRateLimits(out, status.RateLimits, now)
+
+	if len(status.RecentErrors) == 0 {
+		fmt.Fprintf(out, "直近のエラー:     なし\n")
+		return
+	}
+	fmt.Fprintf(out, "直近のエラー:\n")
+	for _, entry := range status.RecentErrors {
+		if entry.RequestID != "" {
+			fmt.Fprintf(out, "  - %s [%s] %s\n", entry.At.In(now.Location()).Format(time.RFC3339), entry.RequestID, entry.Message)
+		} else {
+			fmt.Fprintf(out, "  - %s %s\n", entry.At.In(now.Location()).Format(time.RFC3339), entry.Message)
+		}
+	}
+}
+
+// printStages は投稿のパイプラインの段階ごとの時間の p50 と p95 を、段階の順に出力します
+func printStages(out io.Writer, stages map[string]metrics.HistogramSnapshot) {
+	if len(stages) == 0 {
+		return
+	}
+	labels := make([]string, 0, len(stages))
+	for label := range stages {
+		labels = append(labels, label)
+	}
+	order := func(label string) int {
+		stage, _, _ := strings.Cut(label, ":")
+		return slices.Index(stageOrder, usecase.Stage(stage))
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if oi, oj := order(labels[i]), order(labels[j]); oi != oj {
+			return oi < oj
+		}
+		return labels[i] < labels[j]
+	})
+	fmt.Fprintf(out, "段階ごとの所要時間（p50 / p95）:\n")
+	for _, label := range labels {
+		s := stages[label]
+		fmt.Fprintf(out, "  %-18s %v / %v（%d回）\n", label, roundDuration(s.P50), roundDuration(s.P95), s.Count)
+	}
+}
+
+// printRateLimits は投稿先が返したエンドポイントごとのレート制限の残りを、パスの順に出力します
+func printRateLimits(out io.Writer, limits map[string]repository.RateLimit, now time.Time) {
+	if len(limits) == 0 {
+		return
+	}
+	keys := slices.Sorted(maps.Keys(limits))
+	fmt.Fprintf(out, "レート制限の残り:\n")
+	for _, key := range keys {
+		limit := limits[key]
+		fmt.Fprintf(out, "  %s %d / %d  リセット: %s\n", key, limit.Remaining, limit.Limit, formatTime(limit.Reset, now))
+	}
+}
+
+// roundDuration は表示する時間を丸めます。1ミリ秒未満の段階（名言の選択など）はマイクロ秒まで表示します
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// stageOrder は投稿のパイプラインの段階を実行される順に並べたものです
+var stageOrder = []usecase.Stage{usecase.StageSelect, usecase.StageFormat, usecase.StageValidate, usecase.StagePublish, usecase.StageRecord}
+
+// formatTime は時刻を now のタイムゾーンで、現在時刻からの相対時間とともに表示します
+func formatTime(t, now time.Time) string {
+	d := t.Sub(now).Round(time.Second)
+	t = t.In(now.Location())
+	if d >= 0 {
+		return fmt.Sprintf("%s（%v後）", t.Format(time.RFC3339), d)
+	}
+	return fmt.Sprintf("%s（%v前）", t.Format(time.RFC3339), -d)
+}
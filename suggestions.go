@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/interface/admin"
+)
+
+// runSuggestions は実行中のボットの管理APIで、返信で提案された名言を表示・採用・却下します
+func runSuggestions(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("suggestions", flag.ContinueOnError)
+	flags.SetOutput(out)
+	reject := flags.Bool("reject", false, "名言ファイルに追加せずに破棄する")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if cfg.AdminToken == "" {
+		return fmt.Errorf("提案の確認には ADMIN_TOKEN が必要です")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+	defer cancel()
+	client := admin.NewClient(cfg)
+
+	switch {
+	case flags.NArg() == 0 && !*reject:
+		items, err := client.Suggestions(ctx)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Fprintln(out, "確認待ちの提案はありません")
+			return nil
+		}
+		now := time.Now().In(cfg.DisplayLocation())
+		for _, item := range items {
+			fmt.Fprintf(out, "%s 提案: @%s %s\n", item.ID, item.AuthorHandle, formatTime(item.CreatedAt, now))
+			fmt.Fprintf(out, "  %s\n  - %s\n", item.Quote.Text, item.Quote.Author)
+		}
+		return nil
+	case flags.NArg() != 1:
+		return fmt.Errorf("使い方: quotebot suggestions [--reject] [ID]")
+	case *reject:
+		if err := client.RejectSuggestion(ctx, flags.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "却下しました: %s\n", flags.Arg(0))
+		return nil
+	default:
+		quote, err := client.AcceptSuggestion(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "名言ファイルに追加しました: %s\n", quote.ID)
+		return nil
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/littleironwaltz/quotebot/config"
+	"github.com/littleironwaltz/quotebot/internal/app"
+	"github.com/littleironwaltz/quotebot/internal/domain"
+	"github.com/littleironwaltz/quotebot/internal/interface/repository"
+	"github.com/littleironwaltz/quotebot/internal/redact"
+	"github.com/littleironwaltz/quotebot/internal/usecase"
+)
+
+// runValidate は名言ファイル、認証情報、投稿レコードの組み立てを確認し、結果を表示します。
+// 設定は呼び出し前に読み込まれています。問題が見つかった場合はエラーを返します（CIでのデプロイ前の確認用）
+func runValidate(cfg *config.Config, out io.Writer) error {
+	failedChecks := 0
+	var validationErr *config.ValidationError
+	if err := cfg.Validate(); errors.As(err, &validationErr) {
+		failedChecks++
+		fmt.Fprintf(out, "[NG] 設定: %d件の問題\n", len(validationErr.Problems))
+		for _, p := range validationErr.Problems {
+			fmt.Fprintf(out, "  - %v\n", p)
+		}
+	} else {
+		fmt.Fprintf(out, "[OK] 設定: DID %s, PDS %s\n", cfg.DID, cfg.PDSURL)
+	}
+
+	// 名言ファイルの形式と内容
+	quotes, err := repository.NewQuoteRepository(cfg).LoadQuotesStrict()
+	if err != nil {
+		failedChecks++
+		fmt.Fprintf(out, "[NG] 名言ファイル %s: %v\n", cfg.QuotesFile, err)
+	} else if quoteProblems := usecase.ValidateQuotes(quotes); len(quoteProblems) > 0 {
+		failedChecks++
+		fmt.Fprintf(out, "[NG] 名言ファイル %s: %d件中%d件の問題\n", cfg.QuotesFile, len(quotes), len(quoteProblems))
+		for _, p := range quoteProblems {
+			fmt.Fprintf(out, "  - %v\n", p)
+		}
+	} else {
+		fmt.Fprintf(out, "[OK] 名言ファイル %s: %d件\n", cfg.QuotesFile, len(quotes))
+	}
+
+	// 認証情報
+	blueskyRepo, err := repository.NewBlueskyRepository(cfg)
+	if err != nil {
+		fmt.Fprintf(out, "[NG] 認証情報: %v\n", redact.Error(err))
+		return fmt.Errorf("%d項目の確認に失敗しました", failedChecks+1)
+	}
+	defer blueskyRepo.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+	defer cancel()
+	if session, err := blueskyRepo.ValidateSession(ctx); err != nil {
+		failedChecks++
+		fmt.Fprintf(out, "[NG] 認証情報: %v\n", redact.Error(err))
+	} else {
+		fmt.Fprintf(out, "[OK] 認証情報: %s (%s)\n", session.Handle, session.DID)
+	}
+
+	// 投稿レコードの組み立て（送信はしない）
+	formatter, err := app.NewFormatter(cfg)
+	if err != nil {
+		return err
+	}
+	built, failed := dryRunRecords(blueskyRepo, formatter, quotes, time.Now())
+	if failed > 0 {
+		failedChecks++
+		fmt.Fprintf(out, "[NG] 投稿レコードの組み立て: %d件中%d件に失敗\n", len(quotes), failed)
+	} else {
+		fmt.Fprintf(out, "[OK] 投稿レコードの組み立て: %d件\n", built)
+	}
+
+	// A/Bテストの投稿形式も、選ばれたときに失敗しないよう同じように確認する
+	variants, err := app.NewVariants(cfg)
+	if err != nil {
+		return err
+	}
+	if variants != nil {
+		for _, v := range variants.All() {
+			built, failed := dryRunRecords(blueskyRepo, v.Formatter, quotes, time.Now())
+			if failed > 0 {
+				failedChecks++
+				fmt.Fprintf(out, "[NG] 投稿形式 %s: %d件中%d件に失敗\n", v.Name, len(quotes), failed)
+			} else {
+				fmt.Fprintf(out, "[OK] 投稿形式 %s: %d件\n", v.Name, built)
+			}
+		}
+	}
+
+	if failedChecks > 0 {
+		return fmt.Errorf("%d項目の確認に失敗しました", failedChecks)
+	}
+	return nil
+}
+
+// dryRunRecords はすべての名言について投稿本文をテンプレートで整形し、投稿レコードを組み立ててエンコードし、
+// 成功・失敗の件数を返します
+func dryRunRecords(blueskyRepo *repository.BlueskyRepository, formatter *domain.Formatter, quotes []domain.Quote, now time.Time) (built, failed int) {
+	for i := range quotes {
+		message, err := formatter.Format(&quotes[i], blueskyRepo.Capabilities())
+		var input *repository.CreateRecordInput
+		if err == nil {
+			input, err = blueskyRepo.BuildRecord(message, now)
+		}
+		if err == nil {
+			_, err = json.Marshal(input)
+		}
+		if err != nil {
+			failed++
+			continue
+		}
+		built++
+	}
+	return built, failed
+}